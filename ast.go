@@ -0,0 +1,650 @@
+package gosql
+
+import "strconv"
+
+type Ast struct {
+	Statements []*Statement
+}
+
+type AstKind uint
+
+const (
+	SelectKind AstKind = iota
+	InsertKind
+	CreateTableKind
+	UpdateKind
+	DeleteKind
+	DropTableKind
+	CreateIndexKind
+	// BeginKind, CommitKind and RollbackKind control transactions and have
+	// no statement of their own.
+	BeginKind
+	CommitKind
+	RollbackKind
+	ExplainKind
+	AlterTableKind
+	CopyKind
+	ShowTablesKind
+	DescribeKind
+	SetKind
+	CreateViewKind
+	DropViewKind
+	ShowKind
+	CreateDatabaseKind
+	DropDatabaseKind
+	UseKind
+	CreateUserKind
+	DropUserKind
+	// GrantKind and RevokeKind share the GrantStatement.
+	GrantKind
+	RevokeKind
+	// SavepointKind, ReleaseSavepointKind and RollbackToSavepointKind
+	// share the SavepointStatement.
+	SavepointKind
+	ReleaseSavepointKind
+	RollbackToSavepointKind
+)
+
+type Statement struct {
+	SelectStatement         *SelectStatement
+	InsertStatement         *InsertStatement
+	CreateTableStatement    *CreateTableStatement
+	UpdateStatement         *UpdateStatement
+	DeleteStatement         *DeleteStatement
+	DropTableStatement      *DropTableStatement
+	CreateIndexStatement    *CreateIndexStatement
+	ExplainStatement        *ExplainStatement
+	AlterTableStatement     *AlterTableStatement
+	CopyStatement           *CopyStatement
+	DescribeStatement       *DescribeStatement
+	SetStatement            *SetStatement
+	CreateViewStatement     *CreateViewStatement
+	DropViewStatement       *DropViewStatement
+	ShowStatement           *ShowStatement
+	CreateDatabaseStatement *CreateDatabaseStatement
+	DropDatabaseStatement   *DropDatabaseStatement
+	UseStatement            *UseStatement
+	CreateUserStatement     *CreateUserStatement
+	DropUserStatement       *DropUserStatement
+	GrantStatement          *GrantStatement
+	SavepointStatement      *SavepointStatement
+	Kind                    AstKind
+	// Loc is the location of the first token of the statement, and End,
+	// Offset and EndOffset give the extent of its source text like those
+	// of a Token.
+	Loc               Location
+	End               Location
+	Offset, EndOffset uint
+}
+
+type ExpressionKind uint
+
+const (
+	LiteralKind ExpressionKind = iota
+	BinaryKind
+	InKind
+	BetweenKind
+	FunctionKind
+	ColumnReferenceKind
+	UnaryKind
+	IsNullKind
+	CastKind
+	CaseKind
+)
+
+type BinaryExpression struct {
+	Left  *Expression
+	Right *Expression
+	Op    *Token
+}
+
+// UnaryExpression is a prefix operator applied to a single operand, like
+// NOT active.
+type UnaryExpression struct {
+	Operand *Expression
+	Op      *Token
+}
+
+// IsNullExpression is an IS NULL test, or IS NOT NULL when Not is set.
+type IsNullExpression struct {
+	Operand *Expression
+	Not     bool
+}
+
+// InExpression tests Left against either a List of values or, for
+// IN (SELECT ...), the single column a subquery returns.
+type InExpression struct {
+	Left   *Expression
+	List   []*Expression
+	Select *SelectStatement
+}
+
+type BetweenExpression struct {
+	Left *Expression
+	Low  *Expression
+	High *Expression
+}
+
+// FunctionExpression is a call like count(*) or sum(price). Asterisk is set
+// instead of Args for the count(*) form. Over is the OVER clause that makes
+// it a window function call, or nil.
+type FunctionExpression struct {
+	Name     *Token
+	Args     []*Expression
+	Asterisk bool
+	Over     *Window
+}
+
+// Window is an OVER clause. The rows with the same PartitionBy values as a
+// row form its partition, taken in OrderBy order. Either may be empty.
+type Window struct {
+	PartitionBy []*Expression
+	OrderBy     []*OrderByClause
+}
+
+// CastExpression converts Operand to the column type named by Type, written
+// either CAST(x AS int) or x::int.
+type CastExpression struct {
+	Operand *Expression
+	Type    *Token
+}
+
+// CaseExpression is CASE ... END, whose value is the Then of the first
+// WhenClause that matches. With an Operand, the simple form, a When matches
+// when it equals the Operand; without one each When is a condition. Else is
+// nil when the clause is absent, which makes the value NULL when nothing
+// matches. Case is the CASE keyword, kept for error locations.
+type CaseExpression struct {
+	Case    *Token
+	Operand *Expression
+	Whens   []*WhenClause
+	Else    *Expression
+}
+
+// results returns the THEN results of c followed by its ELSE result.
+func (c *CaseExpression) results() []*Expression {
+	var results []*Expression
+	for _, when := range c.Whens {
+		results = append(results, when.Then)
+	}
+	if c.Else != nil {
+		results = append(results, c.Else)
+	}
+	return results
+}
+
+// WhenClause is a single WHEN ... THEN ... of a CASE expression.
+type WhenClause struct {
+	When *Expression
+	Then *Expression
+}
+
+// ColumnReference is a column qualified by its table, like users.id. Bare
+// column names are parsed as identifier literals.
+type ColumnReference struct {
+	Table  *Token
+	Column *Token
+}
+
+type Expression struct {
+	Literal  *Token
+	Column   *ColumnReference
+	Binary   *BinaryExpression
+	Unary    *UnaryExpression
+	IsNull   *IsNullExpression
+	In       *InExpression
+	Between  *BetweenExpression
+	Function *FunctionExpression
+	Cast     *CastExpression
+	Case     *CaseExpression
+	Kind     ExpressionKind
+}
+
+type SelectItem struct {
+	Exp      *Expression
+	Asterisk bool
+	As       *Token
+}
+
+// NullsOrder says where an ORDER BY key puts NULLs.
+type NullsOrder uint
+
+const (
+	// DefaultNulls puts NULLs after the other values, whichever way the
+	// key is sorted.
+	DefaultNulls NullsOrder = iota
+	NullsFirst
+	NullsLast
+)
+
+// OrderByClause is a single sort key of an ORDER BY. Keys sort ascending
+// unless Desc is set, with NULLs where Nulls says.
+type OrderByClause struct {
+	Exp   *Expression
+	Desc  bool
+	Nulls NullsOrder
+}
+
+type JoinKind uint
+
+const (
+	InnerJoin JoinKind = iota
+	// LeftJoin keeps every row of the left side, padding it with NULLs
+	// where nothing on the right matched.
+	LeftJoin
+	// RightJoin keeps every row of the right side in the same way.
+	RightJoin
+)
+
+// JoinClause joins Table, or its alias As when one is given, on the On
+// condition. When Table names a common table expression, Select is its
+// query.
+type JoinClause struct {
+	Kind   JoinKind
+	Table  *Token
+	As     *Token
+	On     *Expression
+	Select *SelectStatement
+}
+
+// CommonTableExpression names the rows Select returns for the query its
+// WITH clause comes before.
+type CommonTableExpression struct {
+	Name   *Token
+	Select *SelectStatement
+}
+
+// SelectStatement reads from the From table, named FromAs in the query when
+// an alias is given. For a subquery in FROM, From is nil and the rows come
+// from FromSelect, which must have an alias. Limit and Offset are nil when
+// the clause is absent.
+//
+// With holds the common table expressions of a WITH clause. Those the
+// query reads from keep their names in From and the Table of joins, and
+// have their queries in FromSelect and the Select of joins.
+//
+// Hints are the optimizer hints of a /*+ */ comment right after SELECT.
+//
+// A query combining two others with UNION, INTERSECT or EXCEPT has only Set
+// and the OrderBy, Limit and Offset that apply to the combined rows, whose
+// columns ORDER BY names as the left query does.
+type SelectStatement struct {
+	With       []*CommonTableExpression
+	Hints      []*Hint
+	Distinct   bool
+	Item       []*SelectItem
+	From       *Token
+	FromSelect *SelectStatement
+	FromAs     *Token
+	Join       []*JoinClause
+	Where      *Expression
+	GroupBy    []*Expression
+	Having     *Expression
+	Set        *SetOperation
+	OrderBy    []*OrderByClause
+	Limit      *Expression
+	Offset     *Expression
+}
+
+// Hint tells the planner how to run a query, as INDEX(users users_email)
+// does. Args is empty for a hint without arguments.
+type Hint struct {
+	Name *Token
+	Args []*Token
+}
+
+// SetOperation combines the rows of Left and Right as its Op keyword,
+// UNION, INTERSECT or EXCEPT, says. Duplicate rows are removed unless All
+// is set.
+type SetOperation struct {
+	Op    *Token
+	All   bool
+	Left  *SelectStatement
+	Right *SelectStatement
+}
+
+// ExplainStatement describes how Select would run, without running it
+// unless Analyze is set, for EXPLAIN ANALYZE.
+type ExplainStatement struct {
+	Analyze bool
+	Select  *SelectStatement
+}
+
+// InsertStatement inserts each row of Values, or each row Select returns
+// when Values is nil. Columns is nil when the statement has no column list
+// and the values are in schema order. OnConflict is nil without an ON
+// CONFLICT clause and Returning without a RETURNING clause.
+type InsertStatement struct {
+	Table      *Token
+	Columns    []*Token
+	Values     [][]*Expression
+	Select     *SelectStatement
+	OnConflict *OnConflict
+	Returning  []*SelectItem
+}
+
+// OnConflict is ON CONFLICT (Target) DO NOTHING, or DO UPDATE SET ...
+// WHERE ... when DoUpdate is set. A row conflicts when a unique column, or
+// Target when it is given, already holds its value. DO NOTHING skips the
+// row, while DO UPDATE applies Set to the row already there where Where
+// holds for it, reading the row that was to be inserted as excluded.
+// Target is required for DO UPDATE.
+type OnConflict struct {
+	Target   *Token
+	DoUpdate bool
+	Set      []*Assignment
+	Where    *Expression
+}
+
+type ConstraintKind uint
+
+const (
+	NotNullConstraint ConstraintKind = iota
+	PrimaryKeyConstraint
+	UniqueConstraint
+	// DefaultConstraint gives the value of the column when an INSERT
+	// leaves it out.
+	DefaultConstraint
+	// AutoIncrementConstraint fills the column with the next value of the
+	// table's sequence when an INSERT leaves it NULL.
+	AutoIncrementConstraint
+	// ReferencesConstraint makes the column a foreign key, whose values
+	// must be found in a column of another table.
+	ReferencesConstraint
+	// CheckConstraint rejects rows for which its condition is false.
+	CheckConstraint
+	// CollateConstraint makes the text of the column compare, sort and
+	// group by a collation other than its bytes.
+	CollateConstraint
+)
+
+type ColumnConstraint struct {
+	Kind ConstraintKind
+	Loc  Location
+	// Default is the expression of a DEFAULT constraint.
+	Default *Expression
+	// References is the foreign key of a REFERENCES constraint.
+	References *ForeignKey
+	// Check is the condition of a CHECK constraint.
+	Check *Expression
+	// Collation is the name of a COLLATE constraint.
+	Collation *Token
+}
+
+// ReferentialAction is what deleting a row does to the rows whose foreign
+// keys reference it.
+type ReferentialAction uint
+
+const (
+	// RestrictAction refuses to delete a row that is referenced.
+	RestrictAction ReferentialAction = iota
+	// CascadeAction deletes the rows that reference it along with it.
+	CascadeAction
+)
+
+// ForeignKey is REFERENCES table (column) ON DELETE action. Column is nil
+// when the primary key of Table is meant.
+type ForeignKey struct {
+	Table    *Token
+	Column   *Token
+	OnDelete ReferentialAction
+}
+
+// ColumnDefinition is a column of CREATE TABLE or ALTER TABLE ADD COLUMN.
+// Length is the n of VARCHAR(n), or nil when the type has no limit.
+type ColumnDefinition struct {
+	Name        *Token
+	Datatype    *Token
+	Length      *Token
+	Constraints []*ColumnConstraint
+}
+
+func (cd *ColumnDefinition) hasConstraint(kind ConstraintKind) bool {
+	for _, c := range cd.Constraints {
+		if c.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// foreignKey returns the REFERENCES constraint of the column, or nil.
+func (cd *ColumnDefinition) foreignKey() *ForeignKey {
+	for _, c := range cd.Constraints {
+		if c.Kind == ReferencesConstraint {
+			return c.References
+		}
+	}
+	return nil
+}
+
+// defaultValue returns the expression of the column's DEFAULT, or nil.
+func (cd *ColumnDefinition) defaultValue() *Expression {
+	for _, c := range cd.Constraints {
+		if c.Kind == DefaultConstraint {
+			return c.Default
+		}
+	}
+	return nil
+}
+
+// checks returns the conditions of the column's CHECK constraints.
+func (cd *ColumnDefinition) checks() []*Expression {
+	var checks []*Expression
+	for _, c := range cd.Constraints {
+		if c.Kind == CheckConstraint {
+			checks = append(checks, c.Check)
+		}
+	}
+	return checks
+}
+
+// collation returns the name of the column's COLLATE, or nil.
+func (cd *ColumnDefinition) collation() *Token {
+	for _, c := range cd.Constraints {
+		if c.Kind == CollateConstraint {
+			return c.Collation
+		}
+	}
+	return nil
+}
+
+// maxLength is the most characters a value of the column may have, or 0
+// when there is no limit.
+func (cd *ColumnDefinition) maxLength() int {
+	if cd.Length == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(cd.Length.Value)
+	return n
+}
+
+// CreateTableStatement defines a table. Checks holds the conditions of the
+// CHECK constraints written among the columns rather than on one of them.
+// Layout is the string of WITH (layout = 'row' | 'column'), which says how
+// the rows are stored, or nil for the default row layout. The entries of a
+// Schema that describe views also set View to the query the view runs,
+// and their columns are those it returns.
+//
+// A Temporary table belongs to the session that creates it and is dropped
+// when the session closes, or when its transaction commits with
+// OnCommitDrop.
+type CreateTableStatement struct {
+	Name         *Token
+	Cols         []*ColumnDefinition
+	Checks       []*Expression
+	IfNotExists  bool
+	Layout       *Token
+	View         *SelectStatement
+	Temporary    bool
+	OnCommitDrop bool
+}
+
+const (
+	// RowLayout stores the cells of each row together, which suits
+	// reading and writing whole rows.
+	RowLayout = "row"
+	// ColumnLayout also stores each column on its own as a slice of
+	// values of its type, which suits scanning a few columns of many
+	// rows, as aggregates do.
+	ColumnLayout = "column"
+)
+
+// checks returns the conditions of every CHECK constraint of crt, those of
+// its columns first.
+func (crt *CreateTableStatement) checks() []*Expression {
+	var checks []*Expression
+	for _, col := range crt.Cols {
+		checks = append(checks, col.checks()...)
+	}
+	return append(checks, crt.Checks...)
+}
+
+type CreateIndexStatement struct {
+	Name   *Token
+	Table  *Token
+	Column *Token
+}
+
+type DropTableStatement struct {
+	Name     *Token
+	IfExists bool
+}
+
+// CreateViewStatement stores Select under Name, so that queries can read
+// from Name as if it were a table holding the rows Select returns.
+type CreateViewStatement struct {
+	Name   *Token
+	Select *SelectStatement
+}
+
+type DropViewStatement struct {
+	Name     *Token
+	IfExists bool
+}
+
+// CreateDatabaseStatement adds a database, whose tables and views are
+// apart from those of the others.
+type CreateDatabaseStatement struct {
+	Name        *Token
+	IfNotExists bool
+}
+
+// DropDatabaseStatement drops a database with its tables and views.
+type DropDatabaseStatement struct {
+	Name     *Token
+	IfExists bool
+}
+
+// UseStatement makes Name the database that the names of tables and views
+// are looked up in.
+type UseStatement struct {
+	Name *Token
+}
+
+// CreateUserStatement adds a user that sessions can log in as. Password is
+// nil for a user that cannot log in with one, and Superuser lets the user
+// run any statement without being granted privileges.
+type CreateUserStatement struct {
+	Name      *Token
+	Password  *Token
+	Superuser bool
+}
+
+// DropUserStatement drops a user with the privileges granted to it.
+type DropUserStatement struct {
+	Name     *Token
+	IfExists bool
+}
+
+// GrantStatement grants Privileges on Table to User, or revokes them for
+// REVOKE. Privileges holds SELECT, INSERT, UPDATE and DELETE keywords, and
+// is nil for ALL.
+type GrantStatement struct {
+	Privileges []*Token
+	Table      *Token
+	User       *Token
+}
+
+// SavepointStatement marks a point of the transaction called Name with
+// SAVEPOINT, undoes what the transaction did after it with ROLLBACK TO
+// SAVEPOINT, or forgets it with RELEASE SAVEPOINT.
+type SavepointStatement struct {
+	Name *Token
+}
+
+type AlterTableAction uint
+
+const (
+	AddColumnAction AlterTableAction = iota
+	DropColumnAction
+	RenameColumnAction
+)
+
+// AlterTableStatement changes a column of Table. ADD COLUMN sets Add to the
+// new column; DROP COLUMN and RENAME COLUMN set Column to the column they
+// change, and RENAME COLUMN sets To to its new name.
+type AlterTableStatement struct {
+	Table  *Token
+	Action AlterTableAction
+	Add    *ColumnDefinition
+	Column *Token
+	To     *Token
+}
+
+// Assignment is a single column = value pair of an UPDATE's SET list.
+type Assignment struct {
+	Column *Token
+	Value  *Expression
+}
+
+// UpdateStatement changes the rows Where matches. Returning is nil
+// without a RETURNING clause, whose items are of the rows as changed.
+type UpdateStatement struct {
+	Table     *Token
+	Set       []*Assignment
+	Where     *Expression
+	Returning []*SelectItem
+}
+
+// DeleteStatement removes the rows Where matches. Returning is nil without
+// a RETURNING clause, whose items are of the rows as they were.
+type DeleteStatement struct {
+	From      *Token
+	Where     *Expression
+	Returning []*SelectItem
+}
+
+// DescribeStatement lists the columns of Table with their types and
+// constraints.
+type DescribeStatement struct {
+	Table *Token
+}
+
+// SetStatement changes the setting Name of the session to Value, a
+// number, a string or DEFAULT.
+type SetStatement struct {
+	Name  *Token
+	Value *Token
+}
+
+// ShowStatement reads the setting Name of the session, or every setting
+// when Name is nil, for SHOW ALL.
+type ShowStatement struct {
+	Name *Token
+}
+
+// CopyStatement moves rows between Table and the CSV file named by File.
+// COPY ... FROM reads the file into the table and COPY ... TO, which sets
+// To, writes the table out to it. Columns is nil to copy every column in
+// schema order. Delimiter and Null are nil unless their options are given,
+// leaving fields separated by commas and NULL written as an empty field.
+type CopyStatement struct {
+	Table     *Token
+	Columns   []*Token
+	To        bool
+	File      *Token
+	Delimiter *Token
+	Header    bool
+	Null      *Token
+}
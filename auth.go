@@ -0,0 +1,365 @@
+package gosql
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Privilege is a set of the kinds of statements a user may run on a
+// table.
+type Privilege uint8
+
+const (
+	SelectPrivilege Privilege = 1 << iota
+	InsertPrivilege
+	UpdatePrivilege
+	DeletePrivilege
+
+	AllPrivileges = SelectPrivilege | InsertPrivilege | UpdatePrivilege | DeletePrivilege
+)
+
+func (p Privilege) String() string {
+	var names []string
+	for _, name := range []struct {
+		p    Privilege
+		name string
+	}{
+		{SelectPrivilege, "SELECT"},
+		{InsertPrivilege, "INSERT"},
+		{UpdatePrivilege, "UPDATE"},
+		{DeletePrivilege, "DELETE"},
+	} {
+		if p&name.p != 0 {
+			names = append(names, name.name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// user is a user CREATE USER made. Its password is kept as a salted hash
+// from scrypt, which is slow to compute so that a leaked hash is slow to
+// guess passwords against.
+type user struct {
+	salt, hash []byte
+	superuser  bool
+	// grants holds the privileges granted on each table and view, by the
+	// name it is stored under.
+	grants map[string]Privilege
+}
+
+// users holds the users of a backend. Sessions made with NewSession run as
+// the owner of the backend, who is no user and may do anything, as do the
+// statements run on the backend itself. Only sessions from Authenticate
+// have their statements checked.
+//
+// Users and their privileges take effect at once, whatever transaction
+// made them, and are kept in memory only, so a DiskBackend forgets them
+// when it is closed.
+type users struct {
+	mu    sync.RWMutex
+	users map[string]*user
+}
+
+// userManager is an executor whose statements can manage users and are
+// checked against the privileges of the user running them.
+type userManager interface {
+	memoryBackend() *MemoryBackend
+	// sessionUser returns the user statements run as, or "" for the
+	// owner.
+	sessionUser() string
+}
+
+func (mb *MemoryBackend) memoryBackend() *MemoryBackend {
+	return mb
+}
+
+func (mb *MemoryBackend) sessionUser() string {
+	return ""
+}
+
+func (s *Session) memoryBackend() *MemoryBackend {
+	return s.mb
+}
+
+func (s *Session) sessionUser() string {
+	return s.user
+}
+
+// User returns the user the session logged in as with Authenticate, or ""
+// for a session of the owner of the backend.
+func (s *Session) User() string {
+	return s.user
+}
+
+// HasUsers reports whether CREATE USER has made any users, which servers
+// take to mean that clients must log in.
+func (mb *MemoryBackend) HasUsers() bool {
+	mb.users.mu.RLock()
+	defer mb.users.mu.RUnlock()
+	return len(mb.users.users) > 0
+}
+
+// Authenticate returns a new session for the user called name, when
+// password is the one CREATE USER gave it. It fails with
+// ErrInvalidPassword otherwise, including for a user that does not exist
+// or has no password.
+func (mb *MemoryBackend) Authenticate(name, password string) (*Session, error) {
+	mb.users.mu.RLock()
+	u, ok := mb.users.users[name]
+	mb.users.mu.RUnlock()
+	if !ok || u.hash == nil {
+		return nil, ErrInvalidPassword
+	}
+	hash, err := hashPassword(u.salt, password)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(hash, u.hash) != 1 {
+		return nil, ErrInvalidPassword
+	}
+
+	s := mb.NewSession()
+	s.user = name
+	return s, nil
+}
+
+// The cost parameters of scrypt, as its documentation recommends for
+// interactive logins.
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+func hashPassword(salt []byte, password string) ([]byte, error) {
+	return scrypt.Key([]byte(password), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+}
+
+func (us *users) createUser(crt *CreateUserStatement) error {
+	u := &user{superuser: crt.Superuser, grants: map[string]Privilege{}}
+	if crt.Password != nil {
+		u.salt = make([]byte, 16)
+		if _, err := rand.Read(u.salt); err != nil {
+			return err
+		}
+		hash, err := hashPassword(u.salt, crt.Password.Value)
+		if err != nil {
+			return err
+		}
+		u.hash = hash
+	}
+
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	if _, ok := us.users[crt.Name.Value]; ok {
+		return ErrUserAlreadyExists
+	}
+	if us.users == nil {
+		us.users = map[string]*user{}
+	}
+	us.users[crt.Name.Value] = u
+	return nil
+}
+
+func (us *users) dropUser(ctx context.Context, drp *DropUserStatement) error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	if _, ok := us.users[drp.Name.Value]; !ok {
+		if drp.IfExists {
+			warn(ctx, "user %s does not exist, skipping", drp.Name.Value)
+			return nil
+		}
+		return ErrUserDoesNotExist
+	}
+	delete(us.users, drp.Name.Value)
+	return nil
+}
+
+// grant grants or revokes the privileges of grant on a table or view of
+// mb.
+func (us *users) grant(mb *MemoryBackend, grant *GrantStatement, revoke bool) error {
+	table := grant.Table.Value
+	mb.mu.RLock()
+	exists := mb.exists(table)
+	mb.mu.RUnlock()
+	if !exists {
+		return validationError(ErrTableDoesNotExist, grant.Table)
+	}
+
+	privileges := AllPrivileges
+	if grant.Privileges != nil {
+		privileges = 0
+		for _, p := range grant.Privileges {
+			privileges |= privilegeOf(keyword(p.Value))
+		}
+	}
+
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	u, ok := us.users[grant.User.Value]
+	if !ok {
+		return validationError(ErrUserDoesNotExist, grant.User)
+	}
+	if revoke {
+		u.grants[table] &^= privileges
+	} else {
+		u.grants[table] |= privileges
+	}
+	return nil
+}
+
+func privilegeOf(k keyword) Privilege {
+	switch k {
+	case SelectKeyword:
+		return SelectPrivilege
+	case InsertKeyword:
+		return InsertPrivilege
+	case UpdateKeyword:
+		return UpdatePrivilege
+	case DeleteKeyword:
+		return DeletePrivilege
+	}
+	return 0
+}
+
+// executeUserStatement runs CREATE USER, DROP USER, GRANT and REVOKE on ex.
+func executeUserStatement(ctx context.Context, ex Executor, stmt *Statement) error {
+	um, ok := ex.(userManager)
+	if !ok {
+		return ErrInvalidOperator
+	}
+	mb := um.memoryBackend()
+	switch stmt.Kind {
+	case CreateUserKind:
+		return mb.users.createUser(stmt.CreateUserStatement)
+	case DropUserKind:
+		return mb.users.dropUser(ctx, stmt.DropUserStatement)
+	}
+	return mb.users.grant(mb, stmt.GrantStatement, stmt.Kind == RevokeKind)
+}
+
+// access is a privilege a statement needs on a table.
+type access struct {
+	table     *Token
+	privilege Privilege
+}
+
+// authorize checks that the user ex runs statements as may run stmt. A
+// user that is not a superuser may read and change the rows of the tables
+// and views it was granted privileges on, and has every privilege on its
+// session's temporary tables, which it may also create and drop. Other
+// statements that change the schema or the users are for superusers, as
+// is COPY, which reads and writes files on the server.
+// Reading a view takes no privileges on the tables it reads.
+func authorize(ex Executor, stmt *Statement) error {
+	um, ok := ex.(userManager)
+	if !ok || um.sessionUser() == "" {
+		return nil
+	}
+	us := &um.memoryBackend().users
+	us.mu.RLock()
+	defer us.mu.RUnlock()
+
+	u, ok := us.users[um.sessionUser()]
+	if !ok {
+		return fmt.Errorf("%w: user %s was dropped", ErrPermissionDenied, um.sessionUser())
+	}
+	if u.superuser {
+		return nil
+	}
+
+	temp := tempSchemaOf(ex)
+	own := func(name *Token) bool {
+		return temp != "" && strings.HasPrefix(name.Value, temp+".")
+	}
+	accesses, ok := requiredAccess(stmt, own)
+	if !ok {
+		return fmt.Errorf("%w: must be superuser", ErrPermissionDenied)
+	}
+	for _, a := range accesses {
+		if missing := a.privilege &^ u.grants[a.table.Value]; missing != 0 {
+			return tokenError(PermissionError, ErrPermissionDenied, a.table,
+				fmt.Sprintf("%s: %s on %s", ErrPermissionDenied, missing, a.table.Value))
+		}
+	}
+	return nil
+}
+
+// requiredAccess returns the privileges stmt needs, leaving out those on
+// the tables own reports belong to the session. It reports false for
+// statements that only superusers may run.
+func requiredAccess(stmt *Statement, own func(*Token) bool) ([]access, bool) {
+	switch stmt.Kind {
+	case SelectKind, InsertKind, UpdateKind, DeleteKind, ExplainKind:
+	case ShowTablesKind, DescribeKind, SetKind, ShowKind, UseKind, BeginKind, CommitKind, RollbackKind,
+		SavepointKind, ReleaseSavepointKind, RollbackToSavepointKind:
+		return nil, true
+	case CopyKind:
+		// COPY reads or writes a file as the server process, so as in
+		// PostgreSQL it is for superusers, whatever the table.
+		return nil, false
+	case CreateTableKind:
+		return nil, own(stmt.CreateTableStatement.Name)
+	case DropTableKind:
+		return nil, own(stmt.DropTableStatement.Name)
+	default:
+		return nil, false
+	}
+
+	// Common table expressions are read like tables, but need no
+	// privileges.
+	ctes := map[string]bool{}
+	Inspect(stmt, func(n Node) bool {
+		if cte, ok := n.(*CommonTableExpression); ok {
+			ctes[cte.Name.Value] = true
+		}
+		return true
+	})
+
+	var accesses []access
+	need := func(table *Token, p Privilege) {
+		if ctes[table.Value] || own(table) || strings.HasPrefix(table.Value, "information_schema.") {
+			return
+		}
+		accesses = append(accesses, access{table, p})
+	}
+	Inspect(stmt, func(n Node) bool {
+		switch n := n.(type) {
+		case *SelectStatement:
+			if n.From != nil && n.FromSelect == nil {
+				need(n.From, SelectPrivilege)
+			}
+		case *JoinClause:
+			if n.Select == nil {
+				need(n.Table, SelectPrivilege)
+			}
+		case *InsertStatement:
+			need(n.Table, InsertPrivilege)
+			if n.OnConflict != nil && n.OnConflict.DoUpdate {
+				need(n.Table, UpdatePrivilege)
+			}
+			if n.Returning != nil {
+				need(n.Table, SelectPrivilege)
+			}
+		case *UpdateStatement:
+			need(n.Table, UpdatePrivilege)
+			if n.Returning != nil {
+				need(n.Table, SelectPrivilege)
+			}
+		case *DeleteStatement:
+			need(n.From, DeletePrivilege)
+			if n.Returning != nil {
+				need(n.From, SelectPrivilege)
+			}
+		}
+		return true
+	})
+	return accesses, true
+}
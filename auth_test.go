@@ -0,0 +1,104 @@
+package gosql
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticate(t *testing.T) {
+	mb := NewMemoryBackend()
+	assert.False(t, mb.HasUsers())
+	_, err := ExecuteScript(mb, "create user alice password 'secret'; create user bob", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.True(t, mb.HasUsers())
+
+	s, err := mb.Authenticate("alice", "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", s.User())
+	assert.Nil(t, s.Close())
+
+	for _, login := range [][2]string{{"alice", "wrong"}, {"carol", "secret"}, {"bob", ""}} {
+		_, err := mb.Authenticate(login[0], login[1])
+		assert.ErrorIs(t, err, ErrInvalidPassword, login[0])
+		assert.Equal(t, "28P01", SQLState(err))
+	}
+
+	_, err = ExecuteScript(mb, "create user alice", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrUserAlreadyExists)
+	_, err = ExecuteScript(mb, "drop user alice; drop user if exists alice", ScriptOptions{})
+	assert.Nil(t, err)
+	_, err = ExecuteScript(mb, "drop user alice", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrUserDoesNotExist)
+	_, err = mb.Authenticate("alice", "secret")
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+}
+
+func TestAuthorize(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, `create table items (id int primary key, name text);
+create table prices (id int, price float);
+insert into items values (1, 'pen');
+create user clerk password 'clerk';
+create user admin password 'admin' superuser;
+grant select, insert on items to clerk;
+grant all on prices to clerk;
+revoke delete on prices from clerk`, ScriptOptions{})
+	assert.Nil(t, err)
+
+	s, err := mb.Authenticate("clerk", "clerk")
+	assert.Nil(t, err)
+	defer s.Close()
+	out := filepath.Join(t.TempDir(), "items.csv")
+	for _, test := range []struct {
+		source string
+		err    error
+	}{
+		{"select name from items", nil},
+		{"insert into items values (2, 'ink')", nil},
+		{"update items set name = 'pencil'", ErrPermissionDenied},
+		{"delete from items", ErrPermissionDenied},
+		{"select * from items join prices on items.id = prices.id", nil},
+		{"update prices set price = 2", nil},
+		{"update prices set price = 3 returning price", nil},
+		{"insert into items values (3, 'cap') returning id", nil},
+		{"delete from prices", ErrPermissionDenied},
+		{"with cheap as (select id from prices) select * from cheap", nil},
+		{"show tables", nil},
+		{"create table other (id int)", ErrPermissionDenied},
+		{"drop table items", ErrPermissionDenied},
+		{"create user eve", ErrPermissionDenied},
+		{"grant all on items to clerk", ErrPermissionDenied},
+		// COPY reads and writes files as the server, whatever the grants.
+		{"copy items to '" + out + "'", ErrPermissionDenied},
+		{"copy items from '" + out + "'", ErrPermissionDenied},
+		// The session's own temporary tables need no grants.
+		{"create temporary table scratch (id int); insert into scratch values (1); drop table scratch", nil},
+	} {
+		_, err := ExecuteScript(s, test.source, ScriptOptions{})
+		if test.err == nil {
+			assert.Nil(t, err, test.source)
+		} else {
+			assert.ErrorIs(t, err, test.err, test.source)
+			assert.Equal(t, "42501", SQLState(err), test.source)
+		}
+	}
+
+	_, err = ExecuteScript(s, "select * from items where id in (select id from items); delete from items", ScriptOptions{})
+	assert.EqualError(t, err, "Permission denied: DELETE on items at 0:68")
+
+	admin, err := mb.Authenticate("admin", "admin")
+	assert.Nil(t, err)
+	defer admin.Close()
+	_, err = ExecuteScript(admin, "create table other (id int); grant update on items to clerk; grant select on missing to clerk", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrTableDoesNotExist)
+	_, err = ExecuteScript(s, "update items set name = 'pencil'", ScriptOptions{})
+	assert.Nil(t, err)
+
+	_, err = os.Stat(out)
+	assert.True(t, os.IsNotExist(err))
+	_, err = ExecuteScript(admin, "copy items to '"+out+"'", ScriptOptions{})
+	assert.Nil(t, err)
+}
@@ -0,0 +1,282 @@
+package gosql
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type ColumnType uint
+
+const (
+	TextType ColumnType = iota
+	IntType
+	BoolType
+	// NullType is the type of a bare NULL literal, which fits anywhere a
+	// value of another type does.
+	NullType
+	BigIntType
+	FloatType
+	DateType
+	TimestampType
+	// JSONType holds JSON documents, which read as their text.
+	JSONType
+	// ByteaType holds binary strings, which read as their bytes.
+	ByteaType
+)
+
+// compatible reports whether values of types a and b can be compared,
+// which takes converting them to their commonType.
+func compatible(a, b ColumnType) bool {
+	_, ok := commonType(a, b)
+	return ok
+}
+
+func (c ColumnType) String() string {
+	switch c {
+	case TextType:
+		return "TextType"
+	case IntType:
+		return "IntType"
+	case BoolType:
+		return "BoolType"
+	case NullType:
+		return "NullType"
+	case BigIntType:
+		return "BigIntType"
+	case FloatType:
+		return "FloatType"
+	case DateType:
+		return "DateType"
+	case TimestampType:
+		return "TimestampType"
+	case JSONType:
+		return "JSONType"
+	case ByteaType:
+		return "ByteaType"
+	default:
+		return "Error"
+	}
+}
+
+// Cell is a value of a result column. Which of the methods reads it
+// depends on the column's type: AsInt for IntType and BigIntType, AsFloat
+// for FloatType, AsBool for BoolType, AsTime for DateType and
+// TimestampType, and AsText for TextType and JSONType, and for ByteaType,
+// whose bytes it returns as they are.
+type Cell interface {
+	AsText() string
+	AsInt() int64
+	AsFloat() float64
+	AsBool() bool
+	AsTime() time.Time
+	IsNull() bool
+}
+
+type ResultColumn struct {
+	Type ColumnType
+	Name string
+}
+
+type Results struct {
+	Columns []ResultColumn
+	Rows    [][]Cell
+}
+
+var (
+	ErrTableDoesNotExist         = errors.New("Table does not exist")
+	ErrTableAlreadyExists        = errors.New("Table already exists")
+	ErrColumnDoesNotExist        = errors.New("Column does not exist")
+	ErrAmbiguousColumn           = errors.New("Column reference is ambiguous")
+	ErrDuplicateAlias            = errors.New("Table name specified more than once")
+	ErrInvalidSelectItem         = errors.New("Select item is not valid")
+	ErrInvalidDatatype           = errors.New("Invalid datatype")
+	ErrMissingValues             = errors.New("Missing values")
+	ErrDuplicateColumn           = errors.New("Column specified more than once")
+	ErrTypeMismatch              = errors.New("Type mismatch")
+	ErrInvalidCondition          = errors.New("Condition must be a boolean")
+	ErrInvalidOperator           = errors.New("Invalid operator")
+	ErrFunctionNotFound          = errors.New("Function does not exist")
+	ErrFunctionAlreadyExists     = errors.New("Function already exists")
+	ErrInvalidArguments          = errors.New("Invalid function arguments")
+	ErrAggregateNotAllowed       = errors.New("Aggregate functions are not allowed here")
+	ErrViolatesNotNull           = errors.New("Null value violates not-null constraint")
+	ErrViolatesPrimaryKey        = errors.New("Duplicate key value violates primary key constraint")
+	ErrViolatesUnique            = errors.New("Duplicate key value violates unique constraint")
+	ErrUnboundParameter          = errors.New("Parameter has no bound value")
+	ErrIndexAlreadyExists        = errors.New("Index already exists")
+	ErrInvalidLimit              = errors.New("LIMIT and OFFSET must be non-negative integers")
+	ErrOrderByPosition           = errors.New("ORDER BY position is not in select list")
+	ErrSubqueryColumns           = errors.New("Subquery must return exactly one column")
+	ErrMultiplePrimaryKeys       = errors.New("Multiple primary keys are not allowed")
+	ErrMultipleAutoIncrement     = errors.New("Multiple auto-increment columns are not allowed")
+	ErrViolatesForeignKey        = errors.New("Insert, update or delete violates foreign key constraint")
+	ErrViolatesCheck             = errors.New("New row violates check constraint")
+	ErrDivisionByZero            = errors.New("Division by zero")
+	ErrIntegerOutOfRange         = errors.New("Integer out of range")
+	ErrValueOutOfRange           = errors.New("Value out of range")
+	ErrValueTooLong              = errors.New("Value too long")
+	ErrInvalidCast               = errors.New("Cannot cast")
+	ErrInvalidTextRepresentation = errors.New("Invalid input syntax")
+	ErrBadCopyFileFormat         = errors.New("Bad COPY file format")
+	ErrTransactionActive         = errors.New("A transaction is already in progress")
+	ErrNoTransaction             = errors.New("No transaction in progress")
+	// ErrSerializationFailure is returned when a transaction changes a row
+	// that another transaction changed after its snapshot was taken.
+	ErrSerializationFailure = errors.New("Could not serialize access due to concurrent update")
+	// ErrLockTimeout is returned when a statement waits longer than the
+	// lock timeout for the tables it uses.
+	ErrLockTimeout = errors.New("Canceling statement due to lock timeout")
+	// ErrReadOnlySession is returned for a statement that changes the
+	// schema or the rows of a table in a session of a DiskBackend, whose
+	// log only holds the statements run on the backend itself.
+	ErrReadOnlySession = errors.New("Cannot change data in a read-only session")
+	// ErrStatementTimeout is returned when a statement runs longer than
+	// the statement_timeout of its session.
+	ErrStatementTimeout    = errors.New("Canceling statement due to statement timeout")
+	ErrUnknownSetting      = errors.New("Unrecognized configuration parameter")
+	ErrInvalidSettingValue = errors.New("Invalid value for parameter")
+	ErrInvalidScan         = errors.New("Cannot scan row")
+	ErrMultipleStatements  = errors.New("Expected exactly one statement")
+	// ErrWrongObjectType is returned when a statement names a view where
+	// it needs a table, or the other way around.
+	ErrWrongObjectType = errors.New("Wrong object type")
+	// ErrDependentObjects is returned when dropping or changing a table or
+	// view that a view reads from.
+	ErrDependentObjects = errors.New("Other objects depend on it")
+	// ErrSetOperationColumns is returned when the queries UNION,
+	// INTERSECT or EXCEPT combine return different numbers of columns.
+	ErrSetOperationColumns = errors.New("Each query of a set operation must return the same number of columns")
+	// ErrInvalidForeignKey is returned when a foreign key references a
+	// column that is neither the primary key nor unique.
+	ErrInvalidForeignKey = errors.New("Referenced column is not unique")
+	// ErrInvalidCheck is returned when a CHECK constraint reads more than
+	// the row it checks, through a subquery.
+	ErrInvalidCheck = errors.New("Check constraint may only read the row it checks")
+	// ErrWorkMemExceeded is returned when a hash join needs more memory
+	// for the rows it hashes than the work_mem of its session.
+	ErrWorkMemExceeded = errors.New("Hash join exceeds work_mem")
+	// ErrCorruptSnapshot is returned when a snapshot does not match its
+	// checksum or cannot be decoded.
+	ErrCorruptSnapshot = errors.New("Snapshot is corrupt")
+	// ErrUnsupportedSnapshot is returned when a snapshot was written in a
+	// later version of the format than this one reads.
+	ErrUnsupportedSnapshot = errors.New("Unsupported snapshot version")
+	// ErrDatabaseDoesNotExist and ErrDatabaseAlreadyExists are returned
+	// when a statement names a database that is not there, or creates one
+	// that is.
+	ErrDatabaseDoesNotExist  = errors.New("Database does not exist")
+	ErrDatabaseAlreadyExists = errors.New("Database already exists")
+	// ErrDropDefaultDatabase is returned when dropping the database that
+	// sessions start in.
+	ErrDropDefaultDatabase = errors.New("Cannot drop the default database")
+	// ErrTemporarySchema is returned when creating a temporary table in a
+	// database, and ErrTemporaryReference when a table or view that is not
+	// temporary would use a temporary table.
+	ErrTemporarySchema    = errors.New("Temporary tables cannot be created in a database")
+	ErrTemporaryReference = errors.New("Permanent objects cannot use temporary tables")
+	// ErrWindowNotAllowed is returned for window function calls anywhere
+	// but the select items and ORDER BY of a query without GROUP BY,
+	// ErrOverRequired for window functions called without OVER, and
+	// ErrNotWindowFunction for OVER after any other function than a window
+	// or aggregate function.
+	ErrWindowNotAllowed  = errors.New("Window functions are not allowed here")
+	ErrOverRequired      = errors.New("Window function requires an OVER clause")
+	ErrNotWindowFunction = errors.New("OVER requires a window or aggregate function")
+	// ErrTriggerAlreadyExists and ErrTriggerDoesNotExist are returned by
+	// CreateTrigger and DropTrigger for the names of the triggers of a
+	// table.
+	ErrTriggerAlreadyExists = errors.New("Trigger already exists")
+	ErrTriggerDoesNotExist  = errors.New("Trigger does not exist")
+	// ErrSubscriptionLagged is returned by a subscription to changes that
+	// was closed because its subscriber fell behind.
+	ErrSubscriptionLagged = errors.New("Subscription fell behind the changes")
+	// ErrEngineUnsupported is returned for statements whose objects a
+	// StorageEngine has no way of keeping.
+	ErrEngineUnsupported = errors.New("Storage engine does not support this statement")
+	// ErrNestedTooDeeply is returned by ParseStatements for expressions
+	// nested deeper than it allows, and ErrParserFailure when the parser
+	// fails in a way it does not expect.
+	ErrNestedTooDeeply = errors.New("Expression nested too deeply")
+	ErrParserFailure   = errors.New("Parser failed")
+	// ErrUserAlreadyExists and ErrUserDoesNotExist are returned when a
+	// statement creates a user that is there, or names one that is not.
+	ErrUserAlreadyExists = errors.New("User already exists")
+	ErrUserDoesNotExist  = errors.New("User does not exist")
+	// ErrPermissionDenied is returned for a statement the user of its
+	// session lacks the privileges for, and ErrInvalidPassword by
+	// Authenticate.
+	ErrPermissionDenied = errors.New("Permission denied")
+	ErrInvalidPassword  = errors.New("Password authentication failed")
+	// ErrNoConflictTarget is returned for an ON CONFLICT clause whose
+	// column has no unique or primary key constraint.
+	ErrNoConflictTarget = errors.New("No unique constraint matching the ON CONFLICT specification")
+	// ErrConflictAffectedTwice is returned when two rows of an INSERT ...
+	// ON CONFLICT DO UPDATE conflict with the same row.
+	ErrConflictAffectedTwice = errors.New("ON CONFLICT DO UPDATE cannot affect a row a second time")
+	// ErrNoSavepoint is returned for a savepoint the transaction does not
+	// have.
+	ErrNoSavepoint = errors.New("Savepoint does not exist")
+	// ErrCollationDoesNotExist is returned for a COLLATE naming neither a
+	// built-in collation nor a locale.
+	ErrCollationDoesNotExist = errors.New("Collation does not exist")
+	// ErrInvalidHint is returned for an optimizer hint the planner does
+	// not know, or whose arguments name no table or index of the query.
+	ErrInvalidHint = errors.New("Invalid optimizer hint")
+)
+
+// Backend runs statements. Those that read or change rows give up with
+// the error of their context once it is done, leaving nothing behind.
+type Backend interface {
+	CreateTable(context.Context, *CreateTableStatement) error
+	DropTable(context.Context, *DropTableStatement) error
+	AlterTable(context.Context, *AlterTableStatement) error
+	CreateIndex(context.Context, *CreateIndexStatement) error
+	// CreateView stores a view, which queries then read as they would a
+	// table. DropView removes one.
+	CreateView(context.Context, *CreateViewStatement) error
+	DropView(context.Context, *DropViewStatement) error
+	// Insert adds the rows and returns how many it added, along with the
+	// RETURNING items for them if the statement has any.
+	Insert(context.Context, *InsertStatement) (int, *Results, error)
+	// Query returns a cursor over the result of slct, which works out
+	// each row as it is read. Select is the same with every row read
+	// into Results.
+	Query(context.Context, *SelectStatement) (*Rows, error)
+	Select(context.Context, *SelectStatement) (*Results, error)
+	// Update modifies the matching rows in place and returns how many
+	// rows it changed, along with the RETURNING items for them if the
+	// statement has any.
+	Update(context.Context, *UpdateStatement) (int, *Results, error)
+	// Delete removes the matching rows and returns how many it removed,
+	// along with the RETURNING items for them if the statement has any.
+	Delete(context.Context, *DeleteStatement) (int, *Results, error)
+	// Begin starts a transaction. Until Commit, every change including
+	// creating and dropping tables can be undone with Rollback.
+	Begin() error
+	Commit() error
+	Rollback() error
+	// Savepoint names the point a transaction has reached, which
+	// RollbackToSavepoint undoes the changes made since and
+	// ReleaseSavepoint forgets.
+	Savepoint(name string) error
+	RollbackToSavepoint(name string) error
+	ReleaseSavepoint(name string) error
+	// Explain returns the plan for a query as rows of text.
+	Explain(context.Context, *ExplainStatement) (*Results, error)
+	// Set changes a setting of the session statements run in.
+	Set(*SetStatement) error
+	// Show returns the value of a setting as text.
+	Show(name string) (string, error)
+	// CreateDatabase and DropDatabase add and drop the databases that
+	// tables and views can be made in, besides the default one.
+	CreateDatabase(context.Context, *CreateDatabaseStatement) error
+	DropDatabase(context.Context, *DropDatabaseStatement) error
+}
+
+// BatchInserter is a backend that can add many rows already of the types
+// of their columns faster than an INSERT of them would. COPY uses it when
+// the backend has it.
+type BatchInserter interface {
+	InsertRows(ctx context.Context, table string, columns []string, rows [][]Cell) (int, error)
+}
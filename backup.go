@@ -0,0 +1,259 @@
+package gosql
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"sort"
+)
+
+// dumpBatch is how many rows each INSERT of a dump adds.
+const dumpBatch = 100
+
+// DumpSQL writes a script to w that recreates every database of mb, and
+// every table with its committed rows, its indexes and its views, for
+// backups and for moving a database elsewhere. RestoreSQL runs such a
+// script. Tables come before the tables whose foreign keys reference
+// them, and views after what they read. Auto-increment sequences go on
+// from the largest value restored.
+//
+// Rows the running transaction of the backend's own session has changed
+// are dumped as they were before it.
+func (mb *MemoryBackend) DumpSQL(ctx context.Context, w io.Writer) error {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+
+	names := make([]string, 0, len(mb.tables))
+	for name := range mb.tables {
+		if !isTemporary(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	release, err := mb.locks.acquire(ctx, names, nil)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	bw := bufio.NewWriter(w)
+	write := func(stmt *Statement) {
+		bw.WriteString(Format(stmt) + ";\n\n")
+	}
+
+	databases := make([]string, 0, len(mb.databases))
+	for name := range mb.databases {
+		databases = append(databases, name)
+	}
+	sort.Strings(databases)
+	for _, name := range databases {
+		write(&Statement{Kind: CreateDatabaseKind, CreateDatabaseStatement: &CreateDatabaseStatement{
+			Name: &Token{Value: name, Kind: IdentifierKind},
+		}})
+	}
+
+	schema := mb.schema()
+	committed := mb.snapshot()
+	for _, name := range mb.tableOrder(names) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		t := mb.tables[name]
+		write(&Statement{Kind: CreateTableKind, CreateTableStatement: schema[name]})
+
+		rows := t.referenceOrder(t.visibleTo(committed).rows)
+		for start := 0; start < len(rows); start += dumpBatch {
+			end := start + dumpBatch
+			if end > len(rows) {
+				end = len(rows)
+			}
+			inst := &InsertStatement{Table: &Token{Value: name, Kind: IdentifierKind}}
+			for _, row := range rows[start:end] {
+				values := make([]*Expression, len(row))
+				for i, cell := range row {
+					values[i] = cellLiteral(cell, t.columnTypes[i])
+				}
+				inst.Values = append(inst.Values, values)
+			}
+			write(&Statement{Kind: InsertKind, InsertStatement: inst})
+		}
+
+		for _, idx := range t.indexes {
+			if t.implicitIndex(idx) {
+				continue
+			}
+			write(&Statement{Kind: CreateIndexKind, CreateIndexStatement: &CreateIndexStatement{
+				Name:   &Token{Value: idx.name, Kind: IdentifierKind},
+				Table:  &Token{Value: name, Kind: IdentifierKind},
+				Column: &Token{Value: t.columns[idx.column], Kind: IdentifierKind},
+			}})
+		}
+	}
+
+	for _, name := range mb.viewOrder() {
+		if isTemporary(name) {
+			continue
+		}
+		write(&Statement{Kind: CreateViewKind, CreateViewStatement: &CreateViewStatement{
+			Name:   &Token{Value: name, Kind: IdentifierKind},
+			Select: mb.views[name],
+		}})
+	}
+	return bw.Flush()
+}
+
+// RestoreSQL runs the script read from r, as written by DumpSQL, against
+// ex. It stops at the first statement that fails, leaving what ran before
+// it in place.
+func RestoreSQL(ctx context.Context, ex Executor, r io.Reader) error {
+	source, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	_, err = ExecuteScriptContext(ctx, ex, string(source), ScriptOptions{})
+	return err
+}
+
+// tableOrder orders the tables called names so that each comes after the
+// other tables its foreign keys reference.
+func (mb *MemoryBackend) tableOrder(names []string) []string {
+	var order []string
+	added := map[string]bool{}
+	var add func(name string)
+	add = func(name string) {
+		if added[name] {
+			return
+		}
+		added[name] = true
+		for _, ref := range mb.tables[name].references {
+			if ref != nil {
+				add(ref.table)
+			}
+		}
+		order = append(order, name)
+	}
+	for _, name := range names {
+		add(name)
+	}
+	return order
+}
+
+// viewOrder orders the views by name, except that each comes after the
+// views it reads.
+func (mb *MemoryBackend) viewOrder() []string {
+	names := make([]string, 0, len(mb.views))
+	for name := range mb.views {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var order []string
+	added := map[string]bool{}
+	var add func(name string)
+	add = func(name string) {
+		if added[name] {
+			return
+		}
+		added[name] = true
+		for _, read := range readTables(mb.views[name], nil) {
+			if _, ok := mb.views[read]; ok {
+				add(read)
+			}
+		}
+		order = append(order, name)
+	}
+	for _, name := range names {
+		add(name)
+	}
+	return order
+}
+
+// referenceOrder orders rows, the rows of t, so that a row comes after
+// those it references by a foreign key of t to itself, and otherwise keeps
+// their order.
+func (t *table) referenceOrder(rows [][]MemoryCell) [][]MemoryCell {
+	var self []int
+	for i, ref := range t.references {
+		if ref != nil && ref.table == t.name {
+			self = append(self, i)
+		}
+	}
+	if len(self) == 0 {
+		return rows
+	}
+
+	// present holds the values, as text, that each referenced column has
+	// in the rows placed so far. The text is the same for equal values of
+	// the different types a key and the column it references may have.
+	present := map[int]map[string]bool{}
+	for _, i := range self {
+		present[t.columnIndex(t.references[i].column)] = map[string]bool{}
+	}
+	placed := func(row []MemoryCell) bool {
+		for _, i := range self {
+			if row[i].IsNull() {
+				continue
+			}
+			key, to := cellText(row[i], t.columnTypes[i]), t.columnIndex(t.references[i].column)
+			if !present[to][key] && cellText(row[to], t.columnTypes[to]) != key {
+				return false
+			}
+		}
+		return true
+	}
+
+	ordered := make([][]MemoryCell, 0, len(rows))
+	for len(rows) > 0 {
+		var waiting [][]MemoryCell
+		for _, row := range rows {
+			if !placed(row) {
+				waiting = append(waiting, row)
+				continue
+			}
+			ordered = append(ordered, row)
+			for to, values := range present {
+				if !row[to].IsNull() {
+					values[cellText(row[to], t.columnTypes[to])] = true
+				}
+			}
+		}
+		// Rows that can never be placed, which valid foreign keys rule
+		// out, go last as they are.
+		if len(waiting) == len(rows) {
+			return append(ordered, waiting...)
+		}
+		rows = waiting
+	}
+	return ordered
+}
+
+// implicitIndex reports whether creating t made idx itself, for a unique
+// column or a foreign key.
+func (t *table) implicitIndex(idx *index) bool {
+	i := idx.column
+	return t.unique[i] && idx.name == t.uniqueIndexName(i) ||
+		t.references[i] != nil && idx.name == t.name+"_"+t.columns[i]+"_fkey"
+}
+
+// cellLiteral is a literal that inserts cell into a column of type ct.
+// Dates, timestamps and JSON are strings cast to their type, and binary
+// strings X'...' literals.
+func cellLiteral(cell MemoryCell, ct ColumnType) *Expression {
+	literal := &Token{Value: cellText(cell, ct), Kind: StringKind}
+	switch {
+	case cell.IsNull():
+		literal = &Token{Value: string(NullKeyword), Kind: KeywordKind}
+	case ct == ByteaType:
+		literal = &Token{Value: cell.AsText(), Kind: BytesKind}
+	case ct == BoolType:
+		literal.Kind = BoolKind
+	case isNumeric(ct):
+		literal.Kind = NumericKind
+	case ct == DateType || ct == TimestampType || ct == JSONType:
+		return &Expression{Kind: CastKind, Cast: &CastExpression{
+			Operand: &Expression{Kind: LiteralKind, Literal: literal},
+			Type:    &Token{Value: string(columnTypeKeyword(ct)), Kind: KeywordKind},
+		}}
+	}
+	return &Expression{Kind: LiteralKind, Literal: literal}
+}
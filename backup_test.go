@@ -0,0 +1,67 @@
+package gosql
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBackend_DumpSQL(t *testing.T) {
+	mb := NewMemoryBackend()
+	script := `
+create table people (id int primary key auto_increment, name varchar(20) not null, boss int references people (id), born date, seen timestamp, score float default 1.5, active boolean, doc json, check (score > -10));
+create table pets (id bigint primary key, owner int references people (id) on delete cascade, name text unique) with (layout = 'column');
+create index pets_name on pets (name);
+create view bosses as select name from people where boss is null;
+create view big_bosses as select name from bosses where name > 'b';`
+	// The bosses are inserted after those who report to them, which the
+	// dump has to turn around.
+	script += "insert into people (id, name, born, seen, score, active, doc) values (1, 'it''s', date '2001-02-03', timestamp '2001-02-03 04:05:06', -2.25, true, json '{\"a\": [1, null]}');"
+	for i := 2; i <= 250; i++ {
+		script += fmt.Sprintf("insert into people (id, name, active) values (%d, 'p%d', false);", i, i)
+	}
+	script += "update people set boss = id + 1 where id < 250 and id > 1; update people set score = 1e20 where id = 2;"
+	for i := 0; i < 20; i++ {
+		script += fmt.Sprintf("insert into pets values (%d, %d, 'pet\n%d');", i, i*7%250+1, i)
+	}
+	script += "insert into pets values (9000000000, null, null); delete from people where id = 1;"
+	_, err := ExecuteScript(mb, script, ScriptOptions{})
+	assert.Nil(t, err)
+
+	var dump bytes.Buffer
+	assert.Nil(t, mb.DumpSQL(context.Background(), &dump))
+	assert.True(t, strings.HasPrefix(dump.String(), "CREATE TABLE people"), dump.String()[:100])
+	// Indexes the tables make themselves are left to them.
+	assert.Contains(t, dump.String(), "CREATE INDEX pets_name ON pets (name)")
+	assert.NotContains(t, dump.String(), "pets_pkey")
+
+	restored := NewMemoryBackend()
+	assert.Nil(t, RestoreSQL(context.Background(), restored, &dump))
+	for _, query := range []string{
+		"select * from people order by id",
+		"select * from pets order by id",
+		"select * from big_bosses",
+	} {
+		want, err := ExecuteScript(mb, query, ScriptOptions{})
+		assert.Nil(t, err)
+		got, err := ExecuteScript(restored, query, ScriptOptions{})
+		assert.Nil(t, err, query)
+		assert.Equal(t, want[0].Results, got[0].Results, query)
+	}
+
+	// The restored database dumps the same.
+	var again bytes.Buffer
+	assert.Nil(t, restored.DumpSQL(context.Background(), &again))
+	var original bytes.Buffer
+	assert.Nil(t, mb.DumpSQL(context.Background(), &original))
+	assert.Equal(t, original.String(), again.String())
+
+	// The restored sequence goes on from the largest value.
+	results, err := ExecuteScript(restored, "insert into people (name) values ('new') returning id", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(251), results[0].Results.Rows[0][0].AsInt())
+}
@@ -0,0 +1,155 @@
+package gosql
+
+import "context"
+
+// InsertRows adds rows to the table called table in one go. Each row holds
+// a cell for each of columns, or for every column of the table in order
+// when columns is nil, already of the column's type, as the rows of a
+// query on the table are. Other columns get their defaults. The rows are
+// checked against the constraints of the table like those of an INSERT,
+// and either all of them are added or, when one fails, none are.
+func (mb *MemoryBackend) InsertRows(ctx context.Context, table string, columns []string, rows [][]Cell) (int, error) {
+	return mb.session.InsertRows(ctx, table, columns, rows)
+}
+
+func (s *Session) InsertRows(ctx context.Context, table string, columns []string, rows [][]Cell) (int, error) {
+	inst := &InsertStatement{Table: &Token{Kind: IdentifierKind, Value: table}}
+	for _, col := range columns {
+		inst.Columns = append(inst.Columns, &Token{Kind: IdentifierKind, Value: col})
+	}
+
+	ctx = s.withSettings(ctx)
+	var n int
+	err := s.write(ctx, inst.Table, inst, func(tx *transaction) (err error) {
+		var id int64
+		n, id, err = s.mb.insertRows(ctx, tx, inst, rows)
+		if err == nil && id != 0 {
+			s.lastInsertID = id
+		}
+		return err
+	})
+	return n, err
+}
+
+// insertRows is insert for rows of cells instead of expressions. Rather
+// than adding each row in turn, it checks them all, then appends them to
+// the versions of the table and updates each index with them at once, and
+// only then checks their foreign keys, which may reference one another.
+// The id returned is as for insert.
+func (mb *MemoryBackend) insertRows(ctx context.Context, tx *transaction, inst *InsertStatement, cells [][]Cell) (int, int64, error) {
+	t, ok := mb.tables[inst.Table.Value]
+	if !ok {
+		return 0, 0, ErrTableDoesNotExist
+	}
+	indexes, err := t.insertColumns(inst)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	defaults := make([]MemoryCell, len(t.columns))
+	for i := range defaults {
+		if defaults[i], err = t.defaultCell(ctx, i); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	// Values that must be unique are checked against the stored rows and
+	// against the earlier rows of the batch, which are not stored yet.
+	batch := make([]map[string]bool, len(t.columns))
+	for i, unique := range t.unique {
+		if unique {
+			batch[i] = map[string]bool{}
+		}
+	}
+
+	rows := make([][]MemoryCell, len(cells))
+	var lastID int64
+	for r, values := range cells {
+		if err := canceled(ctx, r); err != nil {
+			return 0, 0, err
+		}
+		if len(values) != len(indexes) {
+			return 0, 0, ErrMissingValues
+		}
+
+		row := make([]MemoryCell, len(t.columns))
+		copy(row, defaults)
+		for i, value := range values {
+			cell, ok := value.(MemoryCell)
+			if !ok {
+				return 0, 0, ErrInvalidDatatype
+			}
+			// Assigning a cell of the column's own type only checks its
+			// length.
+			if row[indexes[i]], err = t.assign(indexes[i], cell, t.columnTypes[indexes[i]]); err != nil {
+				return 0, 0, err
+			}
+		}
+		id, err := t.fillAutoIncrement(row)
+		if err != nil {
+			return 0, 0, err
+		}
+		if id != 0 {
+			lastID = id
+		}
+
+		if err := t.fireTriggers(BeforeTrigger, InsertEvent, nil, row); err != nil {
+			return 0, 0, err
+		}
+		for i, cell := range row {
+			if t.notNull[i] && cell.IsNull() {
+				return 0, 0, ErrViolatesNotNull
+			}
+		}
+		if err := t.checkRow(ctx, row); err != nil {
+			return 0, 0, err
+		}
+		for i, seen := range batch {
+			if seen == nil || row[i].IsNull() {
+				continue
+			}
+			if seen[string(row[i])] || mb.containsValue(tx, t, i, row[i], nil) {
+				return 0, 0, t.uniqueViolation(i)
+			}
+			seen[string(row[i])] = true
+		}
+		rows[r] = row
+	}
+
+	t.insertVersions(tx, rows)
+	for _, row := range rows {
+		if err := mb.checkReferences(tx, t, row); err != nil {
+			return 0, 0, err
+		}
+	}
+	for _, row := range rows {
+		if err := mb.changed(tx, t, InsertEvent, nil, row); err != nil {
+			return 0, 0, err
+		}
+	}
+	return len(rows), lastID, nil
+}
+
+// insertVersions adds rows as new versions by tx, as insertVersion would
+// one at a time, with one pass over each index and one step to undo.
+func (t *table) insertVersions(tx *transaction, rows [][]MemoryCell) {
+	start := len(t.versions)
+	for _, row := range rows {
+		t.versions = append(t.versions, &rowVersion{xmin: tx.id, cells: row})
+	}
+	added := t.versions[start:]
+	for _, idx := range t.indexes {
+		for i, v := range added {
+			idx.add(v.cells, start+i)
+		}
+	}
+	for _, v := range added {
+		t.appendVectors(v.cells)
+	}
+	tx.undo = append(tx.undo, func() {
+		for _, v := range added {
+			v.xmin = abortedXID
+		}
+		t.dead += len(added)
+	})
+}
@@ -0,0 +1,97 @@
+package gosql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertRows(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table users (id serial primary key, name varchar(5) not null, active bool default true);"+
+		"create table follows (follower int references users, followed int references users)", ScriptOptions{})
+	assert.Nil(t, err)
+
+	n, err := mb.InsertRows(context.Background(), "users", []string{"name"}, [][]Cell{
+		{MemoryCell("alice")},
+		{MemoryCell("bob")},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, int64(2), mb.session.LastInsertID())
+
+	tests := []struct {
+		columns []string
+		rows    [][]Cell
+		err     error
+	}{
+		{nil, [][]Cell{{intCell(3), MemoryCell("carol"), boolCell(true)}, {intCell(3), MemoryCell("dave"), boolCell(true)}}, ErrViolatesPrimaryKey},
+		{nil, [][]Cell{{intCell(1), MemoryCell("carol"), boolCell(true)}}, ErrViolatesPrimaryKey},
+		{[]string{"name"}, [][]Cell{{MemoryCell("carol")}, {nullCell}}, ErrViolatesNotNull},
+		{[]string{"name"}, [][]Cell{{MemoryCell("caroline")}}, ErrValueTooLong},
+		{[]string{"name"}, [][]Cell{{MemoryCell("carol"), boolCell(false)}}, ErrMissingValues},
+		{[]string{"nickname"}, [][]Cell{{MemoryCell("carol")}}, ErrColumnDoesNotExist},
+	}
+	for _, test := range tests {
+		_, err := mb.InsertRows(context.Background(), "users", test.columns, test.rows)
+		assert.ErrorIs(t, err, test.err)
+	}
+
+	// A failed batch adds none of its rows, and foreign keys may reference
+	// rows of the same batch.
+	_, err = mb.InsertRows(context.Background(), "follows", nil, [][]Cell{{intCell(1), intCell(2)}, {intCell(2), intCell(5)}})
+	assert.ErrorIs(t, err, ErrViolatesForeignKey)
+	_, err = ExecuteScript(mb, "create table nodes (id int primary key, parent int references nodes)", ScriptOptions{})
+	assert.Nil(t, err)
+	_, err = mb.InsertRows(context.Background(), "nodes", nil, [][]Cell{{intCell(2), intCell(1)}, {intCell(1), nullCell}})
+	assert.Nil(t, err)
+
+	results, err := ExecuteScript(mb, "select id, name, active from users where id > 1;"+
+		"select count(*) from follows", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(2), MemoryCell("bob"), boolCell(true)}}, results[0].Results.Rows)
+	assert.Equal(t, [][]Cell{{intCell(0)}}, results[1].Results.Rows)
+
+	// Rolling back takes the rows out again.
+	assert.Nil(t, mb.Begin())
+	_, err = mb.InsertRows(context.Background(), "users", []string{"name"}, [][]Cell{{MemoryCell("eve")}})
+	assert.Nil(t, err)
+	assert.Nil(t, mb.Rollback())
+	results, err = ExecuteScript(mb, "select count(*) from users where name = 'eve'", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(0)}}, results[0].Results.Rows)
+}
+
+func BenchmarkInsertRows(b *testing.B) {
+	rows := make([][]Cell, 10000)
+	for i := range rows {
+		rows[i] = []Cell{intCell(int32(i)), MemoryCell(fmt.Sprintf("user%d", i))}
+	}
+
+	b.Run("InsertRows", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			mb := NewMemoryBackend()
+			_, _ = ExecuteScript(mb, "create table users (id int primary key, name text)", ScriptOptions{})
+			if _, err := mb.InsertRows(context.Background(), "users", nil, rows); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Insert", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			mb := NewMemoryBackend()
+			_, _ = ExecuteScript(mb, "create table users (id int primary key, name text)", ScriptOptions{})
+			for _, row := range rows {
+				inst := &InsertStatement{
+					Table:  &Token{Kind: IdentifierKind, Value: "users"},
+					Values: [][]*Expression{{cellExpression(row[0].(MemoryCell), IntType), cellExpression(row[1].(MemoryCell), TextType)}},
+				}
+				if _, _, err := mb.Insert(context.Background(), inst); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,225 @@
+// Package bench measures how fast a gosql backend runs a workload in the
+// style of TPC-B: accounts spread over branches and tellers, with clients
+// moving money in short write transactions while others look up balances
+// and add up branches. Setup creates the tables and fills them with as
+// much generated data as asked, and Run runs a mix of the operations from
+// several workers at once and reports the throughput and the latency
+// percentiles of each, so that changes to the storage or the executor can
+// be compared on the same workload.
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/piaoranyc/gosql"
+)
+
+// Config says how much data to generate and what workload to run on it.
+type Config struct {
+	// Branches scales the data: each branch has TellersPerBranch tellers
+	// and AccountsPerBranch accounts.
+	Branches          int
+	TellersPerBranch  int
+	AccountsPerBranch int
+	// Workers is how many clients run operations at the same time.
+	Workers int
+	// Duration is how long the workload runs and Operations how many
+	// operations it runs in all. It stops at whichever limit it reaches
+	// first, and a zero one is no limit.
+	Duration   time.Duration
+	Operations int
+	// Mix weighs how often each operation runs.
+	Mix Mix
+	// Seed makes the generated data and the operations chosen the same
+	// from one run to the next.
+	Seed int64
+}
+
+// Mix weighs the operations of the workload against each other. Transfer
+// is the write transaction of TPC-B, which changes the balance of an
+// account, a teller and a branch and records it in the history. Lookup
+// reads the balance of an account and Report adds up those of a branch.
+type Mix struct {
+	Transfer, Lookup, Report int
+}
+
+// DefaultConfig returns a workload of one branch with ten tellers and a
+// thousand accounts, run for ten seconds by four workers that mostly
+// transfer and look up balances.
+func DefaultConfig() Config {
+	return Config{
+		Branches:          1,
+		TellersPerBranch:  10,
+		AccountsPerBranch: 1000,
+		Workers:           4,
+		Duration:          10 * time.Second,
+		Mix:               Mix{Transfer: 45, Lookup: 45, Report: 10},
+		Seed:              1,
+	}
+}
+
+func (cfg Config) validate() error {
+	switch {
+	case cfg.Branches < 1 || cfg.TellersPerBranch < 1 || cfg.AccountsPerBranch < 1:
+		return errors.New("bench: the data needs at least one branch, teller and account")
+	case cfg.Workers < 1:
+		return errors.New("bench: the workload needs at least one worker")
+	case cfg.Duration <= 0 && cfg.Operations <= 0:
+		return errors.New("bench: the workload needs a duration or a number of operations")
+	case cfg.Mix.Transfer < 0 || cfg.Mix.Lookup < 0 || cfg.Mix.Report < 0 || cfg.Mix.Transfer+cfg.Mix.Lookup+cfg.Mix.Report == 0:
+		return errors.New("bench: the mix needs a positive weight")
+	}
+	return nil
+}
+
+// Report is how a workload ran.
+type Report struct {
+	// Duration is how long the workload took from its first operation
+	// to its last.
+	Duration time.Duration
+	// Operations holds each operation of the mix, in the order of Mix.
+	Operations []OperationReport
+}
+
+// OperationReport is how one operation of the workload ran. Count is how
+// many times it succeeded, and the latencies are percentiles of how long
+// those took. Failures counts the times it lost to a concurrent
+// transaction, whose changes it undid.
+type OperationReport struct {
+	Name               string
+	Count, Failures    int
+	P50, P90, P99, Max time.Duration
+}
+
+// Throughput returns how many operations succeeded per second.
+func (r *Report) Throughput() float64 {
+	count := 0
+	for _, op := range r.Operations {
+		count += op.Count
+	}
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(count) / r.Duration.Seconds()
+}
+
+// String renders the report as a table with a line for each operation.
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-10s %10s %10s %12s %12s %12s %12s\n", "operation", "count", "failures", "p50", "p90", "p99", "max")
+	for _, op := range r.Operations {
+		fmt.Fprintf(&b, "%-10s %10d %10d %12s %12s %12s %12s\n", op.Name, op.Count, op.Failures, op.P50, op.P90, op.P99, op.Max)
+	}
+	fmt.Fprintf(&b, "%.1f operations per second over %s\n", r.Throughput(), r.Duration.Round(time.Millisecond))
+	return b.String()
+}
+
+// Run runs the workload cfg describes on ex, which Setup must have filled
+// with the same data. Each worker of a MemoryBackend runs in a session of
+// its own, while those of any other backend share ex and take turns
+// running their operations. Run fails with the first error of an
+// operation that is not a conflict with another transaction, or with the
+// error of ctx if it is done before the workload is.
+func Run(ctx context.Context, ex gosql.Executor, cfg Config) (*Report, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	st, err := prepareStatements(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx := ctx
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+	var started int64
+	next := func() bool {
+		return cfg.Operations <= 0 || atomic.AddInt64(&started, 1) <= int64(cfg.Operations)
+	}
+
+	mb, sessions := ex.(*gosql.MemoryBackend)
+	var shared *sync.Mutex
+	if !sessions {
+		shared = &sync.Mutex{}
+	}
+	workers := make([]*worker, cfg.Workers)
+	for i := range workers {
+		workers[i] = &worker{cfg: cfg, st: st, ex: ex, shared: shared, rng: rand.New(rand.NewSource(cfg.Seed + int64(i)))}
+		if sessions {
+			workers[i].ex = mb.NewSession()
+		}
+	}
+
+	start := time.Now()
+	errs := make([]error, len(workers))
+	var wg sync.WaitGroup
+	for i, w := range workers {
+		wg.Add(1)
+		go func(i int, w *worker) {
+			defer wg.Done()
+			errs[i] = w.work(runCtx, next)
+		}(i, w)
+	}
+	wg.Wait()
+	duration := time.Since(start)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report := &Report{Duration: duration}
+	for op := range operationNames {
+		var latencies []time.Duration
+		failures := 0
+		for _, w := range workers {
+			latencies = append(latencies, w.latencies[op]...)
+			failures += w.failures[op]
+		}
+		report.Operations = append(report.Operations, summarize(operationNames[op], latencies, failures))
+	}
+	return report, nil
+}
+
+// summarize reports the operation called name from the latencies of the
+// times it succeeded.
+func summarize(name string, latencies []time.Duration, failures int) OperationReport {
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return OperationReport{
+		Name:     name,
+		Count:    len(latencies),
+		Failures: failures,
+		P50:      percentile(latencies, 50),
+		P90:      percentile(latencies, 90),
+		P99:      percentile(latencies, 99),
+		Max:      percentile(latencies, 100),
+	}
+}
+
+// percentile returns the smallest of the sorted latencies that at least p
+// percent of them are no greater than, or 0 when there are none.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := (len(sorted)*p + 99) / 100
+	if i < 1 {
+		i = 1
+	}
+	return sorted[i-1]
+}
@@ -0,0 +1,116 @@
+package bench
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/piaoranyc/gosql"
+	"github.com/stretchr/testify/assert"
+)
+
+func query(t *testing.T, ex gosql.Executor, source string) [][]gosql.Cell {
+	results, err := gosql.ExecuteScript(ex, source, gosql.ScriptOptions{})
+	if !assert.Nil(t, err, source) {
+		return nil
+	}
+	return results[0].Results.Rows
+}
+
+func TestRun(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Branches = 2
+	cfg.AccountsPerBranch = 600
+	cfg.Duration = 0
+	cfg.Operations = 300
+
+	mb := gosql.NewMemoryBackend()
+	assert.Nil(t, Setup(context.Background(), mb, cfg))
+	assert.Equal(t, int64(2), query(t, mb, "select count(*) from branches")[0][0].AsInt())
+	assert.Equal(t, int64(20), query(t, mb, "select count(*) from tellers")[0][0].AsInt())
+	assert.Equal(t, int64(1200), query(t, mb, "select count(*) from accounts")[0][0].AsInt())
+	assert.Equal(t, int64(600), query(t, mb, "select count(*) from accounts where branch = 2")[0][0].AsInt())
+
+	report, err := Run(context.Background(), mb, cfg)
+	assert.Nil(t, err)
+	if !assert.Len(t, report.Operations, 3) {
+		return
+	}
+	done := 0
+	for i, op := range report.Operations {
+		assert.Equal(t, operationNames[i], op.Name)
+		done += op.Count + op.Failures
+		assert.True(t, op.P50 <= op.P90 && op.P90 <= op.P99 && op.P99 <= op.Max, op.Name)
+	}
+	assert.Equal(t, cfg.Operations, done)
+	assert.True(t, report.Operations[transfer].Count > 0)
+	assert.Zero(t, report.Operations[lookup].Failures)
+	assert.True(t, report.Throughput() > 0)
+	assert.Contains(t, report.String(), "transfer")
+
+	// Every transfer that committed moved the same amount through its
+	// account, teller and branch and recorded it once.
+	transfers := query(t, mb, "select count(*), sum(delta) from history")[0]
+	assert.Equal(t, int64(report.Operations[transfer].Count), transfers[0].AsInt())
+	delta := transfers[1].AsInt()
+	assert.Equal(t, delta, query(t, mb, "select sum(balance) from branches")[0][0].AsInt())
+	assert.Equal(t, delta, query(t, mb, "select sum(balance) from tellers")[0][0].AsInt())
+}
+
+func TestRun_shared(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AccountsPerBranch = 50
+	cfg.Duration = 50 * time.Millisecond
+
+	db, err := gosql.NewDiskBackend(t.TempDir())
+	assert.Nil(t, err)
+	defer db.Close()
+	assert.Nil(t, Setup(context.Background(), db, cfg))
+
+	// Workers sharing a backend take turns, so none of them conflict.
+	report, err := Run(context.Background(), db, cfg)
+	assert.Nil(t, err)
+	for _, op := range report.Operations {
+		assert.Zero(t, op.Failures, op.Name)
+	}
+	assert.True(t, report.Duration >= cfg.Duration)
+}
+
+func TestRun_invalid(t *testing.T) {
+	mb := gosql.NewMemoryBackend()
+	for _, change := range []func(*Config){
+		func(cfg *Config) { cfg.Branches = 0 },
+		func(cfg *Config) { cfg.Workers = 0 },
+		func(cfg *Config) { cfg.Duration, cfg.Operations = 0, 0 },
+		func(cfg *Config) { cfg.Mix = Mix{} },
+		func(cfg *Config) { cfg.Mix.Lookup = -1 },
+	} {
+		cfg := DefaultConfig()
+		change(&cfg)
+		_, err := Run(context.Background(), mb, cfg)
+		assert.NotNil(t, err)
+	}
+
+	// Running without the tables fails rather than counting failures.
+	cfg := DefaultConfig()
+	cfg.Operations = 10
+	_, err := Run(context.Background(), mb, cfg)
+	assert.ErrorIs(t, err, gosql.ErrTableDoesNotExist)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Run(ctx, mb, cfg)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 200; i++ {
+		latencies = append(latencies, time.Duration(i))
+	}
+	assert.Equal(t, time.Duration(100), percentile(latencies, 50))
+	assert.Equal(t, time.Duration(198), percentile(latencies, 99))
+	assert.Equal(t, time.Duration(200), percentile(latencies, 100))
+	assert.Equal(t, time.Duration(1), percentile(latencies[:1], 50))
+	assert.Zero(t, percentile(nil, 50))
+}
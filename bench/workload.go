@@ -0,0 +1,214 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/piaoranyc/gosql"
+)
+
+// The operations of the workload, in the order of Mix.
+const (
+	transfer = iota
+	lookup
+	report
+)
+
+var operationNames = [...]string{"transfer", "lookup", "report"}
+
+const schema = `CREATE TABLE branches (id INT PRIMARY KEY, balance INT);
+CREATE TABLE tellers (id INT PRIMARY KEY, branch INT, balance INT);
+CREATE TABLE accounts (id INT PRIMARY KEY, branch INT, balance INT, name TEXT);
+CREATE INDEX accounts_branch ON accounts (branch);
+CREATE TABLE history (account INT, teller INT, branch INT, delta INT)`
+
+// insertBatch is how many rows Setup inserts with each statement.
+const insertBatch = 500
+
+// Setup creates the tables of the workload on ex and fills them with the
+// branches, tellers and accounts of cfg. Accounts start out with random
+// balances chosen from cfg.Seed.
+func Setup(ctx context.Context, ex gosql.Executor, cfg Config) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	if _, err := gosql.ExecuteScriptContext(ctx, ex, schema, gosql.ScriptOptions{}); err != nil {
+		return err
+	}
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	tables := []struct {
+		name string
+		rows int
+		row  func(id int) string
+	}{
+		{"branches", cfg.Branches, func(id int) string {
+			return fmt.Sprintf("(%d, 0)", id)
+		}},
+		{"tellers", cfg.tellers(), func(id int) string {
+			return fmt.Sprintf("(%d, %d, 0)", id, (id-1)/cfg.TellersPerBranch+1)
+		}},
+		{"accounts", cfg.accounts(), func(id int) string {
+			return fmt.Sprintf("(%d, %d, %d, 'account %d')", id, (id-1)/cfg.AccountsPerBranch+1, rng.Intn(100000), id)
+		}},
+	}
+	for _, t := range tables {
+		for first := 1; first <= t.rows; first += insertBatch {
+			var b strings.Builder
+			fmt.Fprintf(&b, "INSERT INTO %s VALUES ", t.name)
+			for id := first; id <= t.rows && id < first+insertBatch; id++ {
+				if id > first {
+					b.WriteString(", ")
+				}
+				b.WriteString(t.row(id))
+			}
+			if _, err := gosql.ExecuteScriptContext(ctx, ex, b.String(), gosql.ScriptOptions{}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (cfg Config) tellers() int {
+	return cfg.Branches * cfg.TellersPerBranch
+}
+
+func (cfg Config) accounts() int {
+	return cfg.Branches * cfg.AccountsPerBranch
+}
+
+// statements are the statements of the operations, prepared once for
+// every worker.
+type statements struct {
+	updateAccount, selectAccount, updateTeller, updateBranch, insertHistory *gosql.Prepared
+	lookup, report                                                          *gosql.Prepared
+}
+
+func prepareStatements(ctx context.Context) (*statements, error) {
+	st := &statements{}
+	for _, s := range []struct {
+		p      **gosql.Prepared
+		source string
+	}{
+		{&st.updateAccount, "UPDATE accounts SET balance = balance + $1 WHERE id = $2"},
+		{&st.selectAccount, "SELECT balance FROM accounts WHERE id = $1"},
+		{&st.updateTeller, "UPDATE tellers SET balance = balance + $1 WHERE id = $2"},
+		{&st.updateBranch, "UPDATE branches SET balance = balance + $1 WHERE id = $2"},
+		{&st.insertHistory, "INSERT INTO history VALUES ($1, $2, $3, $4)"},
+		{&st.lookup, "SELECT name, balance FROM accounts WHERE id = $1"},
+		{&st.report, "SELECT count(*), sum(balance) FROM accounts WHERE branch = $1"},
+	} {
+		p, err := gosql.Prepare(ctx, s.source)
+		if err != nil {
+			return nil, err
+		}
+		*s.p = p
+	}
+	return st, nil
+}
+
+// worker is one client of the workload.
+type worker struct {
+	cfg Config
+	st  *statements
+	ex  gosql.Executor
+	// shared, when not nil, is held while running an operation on an
+	// executor the workers share.
+	shared *sync.Mutex
+	rng    *rand.Rand
+	// latencies are how long each operation took the times it succeeded,
+	// and failures how many times it conflicted with another transaction.
+	latencies [len(operationNames)][]time.Duration
+	failures  [len(operationNames)]int
+}
+
+// work runs operations until ctx is done or next says there are no more
+// to run.
+func (w *worker) work(ctx context.Context, next func() bool) error {
+	for ctx.Err() == nil && next() {
+		op := w.choose()
+		if w.shared != nil {
+			w.shared.Lock()
+		}
+		start := time.Now()
+		err := w.run(ctx, op)
+		elapsed := time.Since(start)
+		if w.shared != nil {
+			w.shared.Unlock()
+		}
+
+		switch {
+		case err == nil:
+			w.latencies[op] = append(w.latencies[op], elapsed)
+		case ctx.Err() != nil:
+			// The operation was cut short by the end of the run.
+			return nil
+		case errors.Is(err, gosql.ErrSerializationFailure), errors.Is(err, gosql.ErrLockTimeout):
+			w.failures[op]++
+		default:
+			return fmt.Errorf("bench: %s: %w", operationNames[op], err)
+		}
+	}
+	return nil
+}
+
+// choose picks an operation at random by the weights of the mix.
+func (w *worker) choose() int {
+	mix := w.cfg.Mix
+	n := w.rng.Intn(mix.Transfer + mix.Lookup + mix.Report)
+	switch {
+	case n < mix.Transfer:
+		return transfer
+	case n < mix.Transfer+mix.Lookup:
+		return lookup
+	}
+	return report
+}
+
+func (w *worker) run(ctx context.Context, op int) error {
+	switch op {
+	case transfer:
+		return w.transfer(ctx)
+	case lookup:
+		_, err := w.st.lookup.Execute(ctx, w.ex, 1+w.rng.Intn(w.cfg.accounts()))
+		return err
+	}
+	_, err := w.st.report.Execute(ctx, w.ex, 1+w.rng.Intn(w.cfg.Branches))
+	return err
+}
+
+// transfer moves a random amount into or out of a random account through
+// a random teller, as the transaction of TPC-B does, undoing it all when
+// a statement fails.
+func (w *worker) transfer(ctx context.Context) error {
+	account := 1 + w.rng.Intn(w.cfg.accounts())
+	teller := 1 + w.rng.Intn(w.cfg.tellers())
+	branch := (teller-1)/w.cfg.TellersPerBranch + 1
+	delta := w.rng.Intn(10001) - 5000
+
+	if err := w.ex.Begin(); err != nil {
+		return err
+	}
+	for _, s := range []struct {
+		p    *gosql.Prepared
+		args []interface{}
+	}{
+		{w.st.updateAccount, []interface{}{delta, account}},
+		{w.st.selectAccount, []interface{}{account}},
+		{w.st.updateTeller, []interface{}{delta, teller}},
+		{w.st.updateBranch, []interface{}{delta, branch}},
+		{w.st.insertHistory, []interface{}{account, teller, branch, delta}},
+	} {
+		if _, err := s.p.Execute(ctx, w.ex, s.args...); err != nil {
+			w.ex.Rollback()
+			return err
+		}
+	}
+	return w.ex.Commit()
+}
@@ -0,0 +1,297 @@
+package gosql
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Bind returns a copy of stmt with every parameter replaced by the
+// matching argument, leaving stmt itself untouched so it can be bound again
+// with different arguments. A ? takes the next argument in order and $N
+// takes the Nth, counting from 1. Arguments may be int, int32, int64,
+// float64, string, []byte for a binary string, bool, time.Time or nil for
+// NULL and are substituted as values, never re-lexed as SQL.
+func Bind(stmt *Statement, args ...interface{}) (*Statement, error) {
+	b := binder{args: args}
+	bound := b.statement(stmt)
+	if b.err != nil {
+		return nil, b.err
+	}
+	return bound, nil
+}
+
+func (b *binder) statement(stmt *Statement) *Statement {
+	bound := *stmt
+
+	switch stmt.Kind {
+	case SelectKind:
+		bound.SelectStatement = b.selectStatement(stmt.SelectStatement)
+	case InsertKind:
+		inst := *stmt.InsertStatement
+		inst.Values = nil
+		for _, values := range stmt.InsertStatement.Values {
+			inst.Values = append(inst.Values, b.expressions(values))
+		}
+		inst.Select = b.selectStatement(inst.Select)
+		if c := inst.OnConflict; c != nil {
+			inst.OnConflict = &OnConflict{Target: c.Target, DoUpdate: c.DoUpdate, Set: b.assignments(c.Set), Where: b.expression(c.Where)}
+		}
+		inst.Returning = b.selectItems(inst.Returning)
+		bound.InsertStatement = &inst
+	case UpdateKind:
+		updt := *stmt.UpdateStatement
+		updt.Set = b.assignments(updt.Set)
+		updt.Where = b.expression(updt.Where)
+		updt.Returning = b.selectItems(updt.Returning)
+		bound.UpdateStatement = &updt
+	case DeleteKind:
+		dlt := *stmt.DeleteStatement
+		dlt.Where = b.expression(dlt.Where)
+		dlt.Returning = b.selectItems(dlt.Returning)
+		bound.DeleteStatement = &dlt
+	case ExplainKind:
+		bound.ExplainStatement = &ExplainStatement{Select: b.selectStatement(stmt.ExplainStatement.Select)}
+	}
+	return &bound
+}
+
+type binder struct {
+	args []interface{}
+	// next is the index of the argument the next ? takes.
+	next int
+	err  error
+	// with maps the queries of common table expressions to their bound
+	// copies, which those reading from them share.
+	with map[*SelectStatement]*SelectStatement
+	// now, when set, is the time fixTime replaces the calls to the time
+	// functions with, leaving the parameters as they are.
+	now *time.Time
+}
+
+func (b *binder) selectStatement(slct *SelectStatement) *SelectStatement {
+	if slct == nil {
+		return nil
+	}
+
+	bound := *slct
+	bound.With = nil
+	for _, cte := range slct.With {
+		sub := b.selectStatement(cte.Select)
+		if b.with == nil {
+			b.with = map[*SelectStatement]*SelectStatement{}
+		}
+		b.with[cte.Select] = sub
+		bound.With = append(bound.With, &CommonTableExpression{Name: cte.Name, Select: sub})
+	}
+	bound.Item = b.selectItems(slct.Item)
+	if slct.From != nil {
+		bound.FromSelect = b.with[slct.FromSelect]
+	} else {
+		bound.FromSelect = b.selectStatement(slct.FromSelect)
+	}
+	bound.Join = nil
+	for _, j := range slct.Join {
+		bound.Join = append(bound.Join, &JoinClause{Kind: j.Kind, Table: j.Table, As: j.As, On: b.expression(j.On), Select: b.with[j.Select]})
+	}
+	bound.Where = b.expression(slct.Where)
+	bound.GroupBy = b.expressions(slct.GroupBy)
+	bound.Having = b.expression(slct.Having)
+	if slct.Set != nil {
+		bound.Set = &SetOperation{
+			Op:    slct.Set.Op,
+			All:   slct.Set.All,
+			Left:  b.selectStatement(slct.Set.Left),
+			Right: b.selectStatement(slct.Set.Right),
+		}
+	}
+	bound.OrderBy = nil
+	for _, clause := range slct.OrderBy {
+		bound.OrderBy = append(bound.OrderBy, &OrderByClause{Exp: b.expression(clause.Exp), Desc: clause.Desc, Nulls: clause.Nulls})
+	}
+	bound.Limit = b.expression(slct.Limit)
+	bound.Offset = b.expression(slct.Offset)
+	return &bound
+}
+
+func (b *binder) selectItems(items []*SelectItem) []*SelectItem {
+	if items == nil {
+		return nil
+	}
+	bound := make([]*SelectItem, len(items))
+	for i, item := range items {
+		bound[i] = &SelectItem{Exp: b.expression(item.Exp), Asterisk: item.Asterisk, As: item.As}
+		if b.now != nil && item.As == nil && item.Exp != nil && item.Exp.Kind == FunctionKind && isTimeCall(item.Exp.Function) {
+			// The time keeps the name of the call it replaces.
+			bound[i].As = item.Exp.Function.Name
+		}
+	}
+	return bound
+}
+
+func (b *binder) assignments(set []*Assignment) []*Assignment {
+	if set == nil {
+		return nil
+	}
+	bound := make([]*Assignment, len(set))
+	for i, assignment := range set {
+		bound[i] = &Assignment{Column: assignment.Column, Value: b.expression(assignment.Value)}
+	}
+	return bound
+}
+
+func (b *binder) expressions(exps []*Expression) []*Expression {
+	if exps == nil {
+		return nil
+	}
+	bound := make([]*Expression, len(exps))
+	for i, exp := range exps {
+		bound[i] = b.expression(exp)
+	}
+	return bound
+}
+
+func (b *binder) expression(exp *Expression) *Expression {
+	if exp == nil {
+		return nil
+	}
+
+	bound := *exp
+	switch exp.Kind {
+	case LiteralKind:
+		if exp.Literal.Kind == ParameterKind && b.now == nil {
+			return b.parameter(exp.Literal)
+		}
+	case BinaryKind:
+		bound.Binary = &BinaryExpression{
+			Left:  b.expression(exp.Binary.Left),
+			Right: b.expression(exp.Binary.Right),
+			Op:    exp.Binary.Op,
+		}
+	case UnaryKind:
+		bound.Unary = &UnaryExpression{
+			Operand: b.expression(exp.Unary.Operand),
+			Op:      exp.Unary.Op,
+		}
+	case IsNullKind:
+		bound.IsNull = &IsNullExpression{
+			Operand: b.expression(exp.IsNull.Operand),
+			Not:     exp.IsNull.Not,
+		}
+	case InKind:
+		bound.In = &InExpression{
+			Left:   b.expression(exp.In.Left),
+			List:   b.expressions(exp.In.List),
+			Select: b.selectStatement(exp.In.Select),
+		}
+	case BetweenKind:
+		bound.Between = &BetweenExpression{
+			Left: b.expression(exp.Between.Left),
+			Low:  b.expression(exp.Between.Low),
+			High: b.expression(exp.Between.High),
+		}
+	case FunctionKind:
+		if b.now != nil && isTimeCall(exp.Function) {
+			return timeExpression(*b.now, timeFunctions[exp.Function.Name.Value])
+		}
+		bound.Function = &FunctionExpression{
+			Name:     exp.Function.Name,
+			Args:     b.expressions(exp.Function.Args),
+			Asterisk: exp.Function.Asterisk,
+		}
+		if over := exp.Function.Over; over != nil {
+			bound.Function.Over = &Window{PartitionBy: b.expressions(over.PartitionBy)}
+			for _, clause := range over.OrderBy {
+				bound.Function.Over.OrderBy = append(bound.Function.Over.OrderBy, &OrderByClause{Exp: b.expression(clause.Exp), Desc: clause.Desc, Nulls: clause.Nulls})
+			}
+		}
+	case CastKind:
+		bound.Cast = &CastExpression{
+			Operand: b.expression(exp.Cast.Operand),
+			Type:    exp.Cast.Type,
+		}
+	case CaseKind:
+		bound.Case = &CaseExpression{
+			Case:    exp.Case.Case,
+			Operand: b.expression(exp.Case.Operand),
+			Else:    b.expression(exp.Case.Else),
+		}
+		for _, when := range exp.Case.Whens {
+			bound.Case.Whens = append(bound.Case.Whens, &WhenClause{
+				When: b.expression(when.When),
+				Then: b.expression(when.Then),
+			})
+		}
+	}
+	return &bound
+}
+
+// parameter returns a literal holding the argument for the placeholder t,
+// at the placeholder's location. Times are bound as timestamp literals.
+func (b *binder) parameter(t *Token) *Expression {
+	unbound := &Expression{Literal: t, Kind: LiteralKind}
+	i := b.next
+	if t.Value == "?" {
+		b.next++
+	} else {
+		n, err := strconv.Atoi(t.Value[1:])
+		if err != nil || n < 1 {
+			b.fail(ErrUnboundParameter, t)
+			return unbound
+		}
+		i = n - 1
+	}
+
+	if i >= len(b.args) {
+		b.fail(ErrUnboundParameter, t)
+		return unbound
+	}
+
+	bound := &Token{Loc: t.Loc}
+	switch arg := b.args[i].(type) {
+	case int:
+		bound.Kind, bound.Value = NumericKind, strconv.Itoa(arg)
+	case int32:
+		bound.Kind, bound.Value = NumericKind, strconv.Itoa(int(arg))
+	case int64:
+		bound.Kind, bound.Value = NumericKind, strconv.FormatInt(arg, 10)
+	case float64:
+		if math.IsInf(arg, 0) || math.IsNaN(arg) {
+			b.fail(fmt.Errorf("%w: cannot bind %v", ErrValueOutOfRange, arg), t)
+			return unbound
+		}
+		bound.Kind, bound.Value = NumericKind, floatLiteral(arg)
+	case string:
+		bound.Kind, bound.Value = StringKind, arg
+	case []byte:
+		bound.Kind, bound.Value = BytesKind, string(arg)
+	case bool:
+		bound.Kind, bound.Value = BoolKind, strconv.FormatBool(arg)
+	case time.Time:
+		if !timeInRange(arg) {
+			b.fail(fmt.Errorf("%w: cannot bind %v", ErrValueOutOfRange, arg), t)
+			return unbound
+		}
+		bound.Kind, bound.Value = StringKind, FormatTimestamp(arg.UTC())
+		return &Expression{
+			Cast: &CastExpression{
+				Operand: &Expression{Literal: bound, Kind: LiteralKind},
+				Type:    &Token{Kind: KeywordKind, Value: string(TimestampKeyword), Loc: t.Loc},
+			},
+			Kind: CastKind,
+		}
+	case nil:
+		bound.Kind, bound.Value = KeywordKind, string(NullKeyword)
+	default:
+		b.fail(fmt.Errorf("%w: cannot bind %T", ErrInvalidDatatype, arg), t)
+		return unbound
+	}
+	return &Expression{Literal: bound, Kind: LiteralKind}
+}
+
+func (b *binder) fail(err error, t *Token) {
+	if b.err == nil {
+		b.err = validationError(err, t)
+	}
+}
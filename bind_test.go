@@ -0,0 +1,81 @@
+package gosql
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBind(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table users (id int, name text, admin bool)")
+	assert.Nil(t, err)
+
+	ast, err := Parse("insert into users values (?, ?, ?)")
+	assert.Nil(t, err)
+	insert := ast.Statements[0]
+
+	for i, name := range []string{"alice", "bob's", "carol"} {
+		stmt, err := Bind(insert, i+1, name, i == 0)
+		assert.Nil(t, err)
+		_, _, err = mb.Insert(context.Background(), stmt.InsertStatement)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, ParameterKind, insert.InsertStatement.Values[0][0].Literal.Kind)
+
+	ast, err = Parse("select name from users where id > $1 and name <> $2 or admin = $3")
+	assert.Nil(t, err)
+	stmt, err := Bind(ast.Statements[0], 1, "carol", true)
+	assert.Nil(t, err)
+	results, err := mb.Select(context.Background(), stmt.SelectStatement)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results.Rows))
+	assert.Equal(t, "alice", results.Rows[0][0].AsText())
+	assert.Equal(t, "bob's", results.Rows[1][0].AsText())
+
+	ast, err = Parse("update users set name = ? where id = ?")
+	assert.Nil(t, err)
+	stmt, err = Bind(ast.Statements[0], "dave", 3)
+	assert.Nil(t, err)
+	n, _, err := mb.Update(context.Background(), stmt.UpdateStatement)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	stmt, err = Bind(ast.Statements[0], nil, 2)
+	assert.Nil(t, err)
+	_, _, err = mb.Update(context.Background(), stmt.UpdateStatement)
+	assert.Nil(t, err)
+	results, err = execute(t, mb, "select id from users where name is null")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, int64(2), results.Rows[0][0].AsInt())
+
+	_, err = Bind(ast.Statements[0], "dave")
+	assert.ErrorIs(t, err, ErrUnboundParameter)
+	assert.EqualError(t, err, "Parameter has no bound value: ? at 0:37")
+
+	_, err = Bind(ast.Statements[0], "dave", struct{}{})
+	assert.ErrorIs(t, err, ErrInvalidDatatype)
+
+	_, err = Bind(ast.Statements[0], "dave", math.NaN())
+	assert.ErrorIs(t, err, ErrValueOutOfRange)
+
+	_, _, err = mb.Update(context.Background(), ast.Statements[0].UpdateStatement)
+	assert.Equal(t, ErrUnboundParameter, err)
+
+	_, err = execute(t, mb, "alter table users add column seen timestamp")
+	assert.Nil(t, err)
+	seen := time.Date(2024, 1, 31, 9, 30, 0, 0, time.FixedZone("CET", 3600))
+	ast, err = Parse("update users set seen = ? where id = 1")
+	assert.Nil(t, err)
+	stmt, err = Bind(ast.Statements[0], seen)
+	assert.Nil(t, err)
+	_, _, err = mb.Update(context.Background(), stmt.UpdateStatement)
+	assert.Nil(t, err)
+	results, err = execute(t, mb, "select seen from users where seen < timestamp '2024-01-31 09:00'")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{timestampCell(seen)}}, results.Rows)
+}
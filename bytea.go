@@ -0,0 +1,65 @@
+package gosql
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// FormatBytes writes a binary string the way PostgreSQL outputs bytea: \x
+// followed by two hex digits for each byte.
+func FormatBytes(b []byte) string {
+	return `\x` + hex.EncodeToString(b)
+}
+
+// parseBytes reads a binary string from text in the form FormatBytes
+// writes. Text without the \x prefix stands for its own bytes.
+func parseBytes(s string) (MemoryCell, bool) {
+	if !strings.HasPrefix(s, `\x`) {
+		return MemoryCell(s), true
+	}
+	b, err := hex.DecodeString(s[2:])
+	if err != nil {
+		return nil, false
+	}
+	return MemoryCell(b), true
+}
+
+// concatType is the type of left || right: binary strings concatenate to
+// a binary string, while anything else is concatenated as its text.
+func concatType(lt, rt ColumnType) ColumnType {
+	if ct, ok := commonType(lt, rt); ok && ct == ByteaType {
+		return ByteaType
+	}
+	return TextType
+}
+
+// encodeBytes writes b as text in format, which is hex or base64.
+func encodeBytes(b []byte, format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "hex":
+		return hex.EncodeToString(b), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString(b), nil
+	}
+	return "", fmt.Errorf("%w: unrecognized encoding %q", ErrInvalidArguments, format)
+}
+
+// decodeBytes reads the binary string encodeBytes writes as s in format.
+func decodeBytes(s, format string) ([]byte, error) {
+	var b []byte
+	var err error
+	switch strings.ToLower(format) {
+	case "hex":
+		b, err = hex.DecodeString(s)
+	case "base64":
+		b, err = base64.StdEncoding.DecodeString(s)
+	default:
+		return nil, fmt.Errorf("%w: unrecognized encoding %q", ErrInvalidArguments, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w for %s: %q", ErrInvalidTextRepresentation, format, s)
+	}
+	return b, nil
+}
@@ -0,0 +1,86 @@
+package gosql
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBytea(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table files (id int primary key, data bytea, thumb blob);"+
+		"insert into files values (1, X'DEADBEEF', x''), (2, x'00ff', null), (3, cast('\\x0a0b' as bytea), cast('raw' as blob))", ScriptOptions{})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		source string
+		rows   [][]Cell
+	}{
+		{"select data from files where id = 1", [][]Cell{{MemoryCell{0xde, 0xad, 0xbe, 0xef}}}},
+		{"select id from files where data = x'00FF'", [][]Cell{{intCell(2)}}},
+		{"select id from files order by data", [][]Cell{{intCell(2)}, {intCell(3)}, {intCell(1)}}},
+		{"select octet_length(data), octet_length(thumb), octet_length('é') from files where id = 1", [][]Cell{{intCell(4), intCell(0), intCell(2)}}},
+		{"select data || x'01', cast(data as text), thumb from files where id = 3", [][]Cell{{MemoryCell{0x0a, 0x0b, 0x01}, MemoryCell(`\x0a0b`), MemoryCell("raw")}}},
+		{"select encode(data, 'hex'), encode(data, 'base64'), decode('AP8=', 'base64') = data from files where id = 2", [][]Cell{{MemoryCell("00ff"), MemoryCell("AP8="), boolCell(true)}}},
+		{"select 'id ' || data from files where id = 2", [][]Cell{{MemoryCell(`id \x00ff`)}}},
+	}
+	for _, test := range tests {
+		results, err := ExecuteScript(mb, test.source, ScriptOptions{})
+		if assert.Nil(t, err, test.source) {
+			assert.Equal(t, test.rows, results[0].Results.Rows, test.source)
+		}
+	}
+
+	failures := []struct {
+		source string
+		err    error
+	}{
+		{"insert into files values (4, 'text', null)", ErrInvalidDatatype},
+		{"select data + 1 from files", ErrTypeMismatch},
+		{"select data like 'a%' from files", ErrTypeMismatch},
+		{"select octet_length(id) from files", ErrTypeMismatch},
+		{"select decode('zz', 'hex') from files", ErrInvalidTextRepresentation},
+		{"select cast('\\xabc' as bytea) from files", ErrInvalidTextRepresentation},
+	}
+	for _, test := range failures {
+		_, err := ExecuteScript(mb, test.source, ScriptOptions{})
+		assert.ErrorIs(t, err, test.err, test.source)
+	}
+
+	// Bound []byte arguments are binary strings, which scan into []byte.
+	stmt, err := Parse("select id, data from files where data = ?")
+	assert.Nil(t, err)
+	bound, err := Bind(stmt.Statements[0], []byte{0, 0xff})
+	assert.Nil(t, err)
+	rows, err := mb.Query(context.Background(), bound.SelectStatement)
+	assert.Nil(t, err)
+	var id int
+	var data []byte
+	assert.True(t, rows.Next())
+	assert.Nil(t, rows.Scan(&id, &data))
+	assert.Equal(t, []byte{0, 0xff}, data)
+
+	var buf bytes.Buffer
+	assert.Nil(t, mb.DumpSQL(context.Background(), &buf))
+	assert.Contains(t, buf.String(), "(1, X'deadbeef', X'')")
+}
+
+func TestParse_bytes(t *testing.T) {
+	tokens, err := lex("select X'0aFF', x''")
+	assert.Nil(t, err)
+	assert.Equal(t, BytesKind, tokens[1].Kind)
+	assert.Equal(t, "\n\xff", tokens[1].Value)
+	assert.Equal(t, BytesKind, tokens[3].Kind)
+	assert.Equal(t, "", tokens[3].Value)
+
+	ast, err := Parse("create table t (a bytea, b blob default x'00')")
+	assert.Nil(t, err)
+	assert.Equal(t, "CREATE TABLE t (\n  a BYTEA,\n  b BLOB DEFAULT X'00'\n)", Format(ast.Statements[0]))
+
+	for _, source := range []string{"select x'abc'", "select x'zz'"} {
+		_, err := Parse(source)
+		assert.NotNil(t, err, source)
+	}
+}
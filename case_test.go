@@ -0,0 +1,81 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCase(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table users (id int, name text, age int);"+
+		"insert into users values (1, 'alice', 30);"+
+		"insert into users values (2, 'bob', 17);"+
+		"insert into users values (3, 'carol', null)", ScriptOptions{})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		query string
+		rows  [][]Cell
+	}{
+		{
+			"select case when age >= 18 then 'adult' when age < 18 then 'minor' else 'unknown' end from users order by id",
+			[][]Cell{{MemoryCell("adult")}, {MemoryCell("minor")}, {MemoryCell("unknown")}},
+		},
+		{
+			"select case id when 1 then 'one' when 2 then 'two' end from users order by id",
+			[][]Cell{{MemoryCell("one")}, {MemoryCell("two")}, {nullCell}},
+		},
+		{
+			// Results take the type they have in common.
+			"select case when id = 1 then 1 else 2.5 end from users order by id limit 2",
+			[][]Cell{{floatCell(1)}, {floatCell(2.5)}},
+		},
+		{
+			"select name from users where case when age is null then true else age > 20 end order by id",
+			[][]Cell{{MemoryCell("alice")}, {MemoryCell("carol")}},
+		},
+		{
+			"select sum(case when age >= 18 then 1 else 0 end) from users",
+			[][]Cell{{intCell(1)}},
+		},
+	}
+
+	for _, test := range tests {
+		results, err := ExecuteScript(mb, test.query, ScriptOptions{})
+		assert.Nil(t, err, test.query)
+		if err == nil {
+			assert.Equal(t, test.rows, results[0].Results.Rows, test.query)
+		}
+	}
+
+	results, err := ExecuteScript(mb, "select case when true then 1 end from users limit 1", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "case", results[0].Results.Columns[0].Name)
+
+	_, err = ExecuteScript(mb, "select case when age then 1 end from users", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+	_, err = ExecuteScript(mb, "select case when true then 1 else 'one' end from users", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+}
+
+func TestParse_case(t *testing.T) {
+	ast, err := Parse("select case x when 1 then 'a' when 2 then 'b' else 'c' end from t")
+	assert.Nil(t, err)
+
+	cexp := ast.Statements[0].SelectStatement.Item[0].Exp.Case
+	assert.Equal(t, "x", cexp.Operand.Literal.Value)
+	assert.Equal(t, 2, len(cexp.Whens))
+	assert.Equal(t, "c", cexp.Else.Literal.Value)
+
+	assert.Equal(t, "SELECT CASE x WHEN 1 THEN 'a' WHEN 2 THEN 'b' ELSE 'c' END\nFROM t", Format(ast.Statements[0]))
+
+	for _, source := range []string{
+		"select case end from t",
+		"select case when x then 1 from t",
+		"select case when x 1 end from t",
+	} {
+		_, err = Parse(source)
+		assert.NotNil(t, err, source)
+	}
+}
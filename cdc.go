@@ -0,0 +1,135 @@
+package gosql
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Change is a row change made by a committed transaction, as a
+// subscription receives it.
+type Change struct {
+	// XID is the transaction that made the change. The changes of a
+	// transaction arrive together, in the order it made them.
+	XID uint64
+	RowChange
+}
+
+// Subscription streams the committed row changes of a backend, as made by
+// INSERT, UPDATE, DELETE and COPY and by cascading foreign keys. Changes to
+// temporary tables and to the schema are not sent.
+type Subscription struct {
+	// C receives the changes of each transaction that begins after the
+	// subscription was made, once it commits. It is closed by Close, or
+	// when the subscriber falls behind.
+	C <-chan Change
+
+	c    chan Change
+	from uint64
+	err  error
+	cdc  *changeFeed
+}
+
+// changeFeed holds the subscriptions of a backend.
+type changeFeed struct {
+	mu   sync.Mutex
+	subs []*Subscription
+	// count is len(subs), which transactions read to skip recording
+	// their changes when no one would receive them.
+	count atomic.Int32
+}
+
+// Subscribe starts streaming committed changes. Up to buffer changes wait
+// in C to be received. A transaction that commits more changes than there
+// is room for closes C instead, and Err then returns
+// ErrSubscriptionLagged, as the subscriber must catch up some other way.
+func (mb *MemoryBackend) Subscribe(buffer int) *Subscription {
+	c := make(chan Change, buffer)
+	sub := &Subscription{C: c, c: c, cdc: &mb.changes}
+
+	mb.changes.mu.Lock()
+	defer mb.changes.mu.Unlock()
+	mb.xidMu.Lock()
+	sub.from = mb.nextXID + 1
+	mb.xidMu.Unlock()
+	mb.changes.subs = append(mb.changes.subs, sub)
+	mb.changes.count.Add(1)
+	return sub
+}
+
+// Close stops the subscription and closes C, if it is not closed yet.
+func (sub *Subscription) Close() {
+	sub.cdc.mu.Lock()
+	defer sub.cdc.mu.Unlock()
+	sub.cdc.remove(sub)
+}
+
+// Err returns ErrSubscriptionLagged once C has been closed because the
+// subscriber fell behind, and nil otherwise.
+func (sub *Subscription) Err() error {
+	sub.cdc.mu.Lock()
+	defer sub.cdc.mu.Unlock()
+	return sub.err
+}
+
+// remove drops sub and closes its channel. The caller holds f.mu.
+func (f *changeFeed) remove(sub *Subscription) {
+	for i, s := range f.subs {
+		if s == sub {
+			f.subs = append(f.subs[:i:i], f.subs[i+1:]...)
+			f.count.Add(-1)
+			close(sub.c)
+			return
+		}
+	}
+}
+
+// capture records a change tx made to t for the subscriptions and for
+// persist, if there are any. Rolling the change back drops it again.
+func (mb *MemoryBackend) capture(tx *transaction, t *table, event TriggerEvent, old, new []MemoryCell) {
+	if mb.changes.count.Load() == 0 && mb.persist == nil || isTemporary(t.name) {
+		return
+	}
+	n := len(tx.changes)
+	tx.changes = append(tx.changes, Change{
+		XID:       tx.id,
+		RowChange: RowChange{Table: t.name, Event: event, Columns: t.columns, Old: rowCells(old), New: rowCells(new)},
+	})
+	tx.undo = append(tx.undo, func() {
+		tx.changes = tx.changes[:n]
+	})
+}
+
+// changed fires the after triggers of t for a row tx has changed and
+// captures the change.
+func (mb *MemoryBackend) changed(tx *transaction, t *table, event TriggerEvent, old, new []MemoryCell) error {
+	if err := t.fireTriggers(AfterTrigger, event, old, new); err != nil {
+		return err
+	}
+	mb.capture(tx, t, event, old, new)
+	return nil
+}
+
+// publish sends the changes of tx, which has committed, to the
+// subscriptions made before it began.
+func (mb *MemoryBackend) publish(tx *transaction) {
+	if len(tx.changes) == 0 {
+		return
+	}
+	f := &mb.changes
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, sub := range append([]*Subscription(nil), f.subs...) {
+		if tx.id < sub.from {
+			continue
+		}
+		if cap(sub.c)-len(sub.c) < len(tx.changes) {
+			sub.err = ErrSubscriptionLagged
+			f.remove(sub)
+			continue
+		}
+		for _, change := range tx.changes {
+			sub.c <- change
+		}
+	}
+}
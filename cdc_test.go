@@ -0,0 +1,62 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBackend_Subscribe(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table items (id int primary key, name text); insert into items values (1, 'pen')", ScriptOptions{})
+	assert.Nil(t, err)
+
+	sub := mb.Subscribe(10)
+	_, err = ExecuteScript(mb, `insert into items values (2, 'ink');
+begin;
+update items set name = 'quill' where id = 1;
+insert into items values (2, 'copy');
+delete from items where id = 2;
+commit;
+begin;
+delete from items;
+rollback`, ScriptOptions{ContinueOnError: true})
+	assert.NotNil(t, err)
+
+	// The failed statement and the rolled back transaction send nothing.
+	var changes []Change
+	for len(sub.C) > 0 {
+		changes = append(changes, <-sub.C)
+	}
+	assert.Len(t, changes, 3)
+	assert.Equal(t, InsertEvent, changes[0].Event)
+	assert.Equal(t, "items", changes[0].Table)
+	assert.Equal(t, []string{"id", "name"}, changes[0].Columns)
+	assert.Nil(t, changes[0].Old)
+	assert.Equal(t, "ink", changes[0].New[1].AsText())
+
+	assert.Equal(t, UpdateEvent, changes[1].Event)
+	assert.Equal(t, "pen", changes[1].Old[1].AsText())
+	assert.Equal(t, "quill", changes[1].New[1].AsText())
+	assert.Equal(t, DeleteEvent, changes[2].Event)
+	assert.Equal(t, int64(2), changes[2].Old[0].AsInt())
+	assert.Equal(t, changes[1].XID, changes[2].XID)
+	assert.Greater(t, changes[1].XID, changes[0].XID)
+
+	// A transaction that does not fit closes the subscription.
+	_, err = ExecuteScript(mb, "create table many (n int); insert into many values (1), (2), (3), (4), (5), (6), (7), (8), (9), (10), (11)", ScriptOptions{})
+	assert.Nil(t, err)
+	_, open := <-sub.C
+	assert.False(t, open)
+	assert.ErrorIs(t, sub.Err(), ErrSubscriptionLagged)
+	sub.Close()
+
+	// Temporary tables are left out.
+	sub = mb.Subscribe(10)
+	defer sub.Close()
+	_, err = ExecuteScript(mb, "create temporary table scratch (n int); insert into scratch values (1); insert into many values (12)", ScriptOptions{})
+	assert.Nil(t, err)
+	change := <-sub.C
+	assert.Equal(t, "many", change.Table)
+	assert.Len(t, sub.C, 0)
+}
@@ -0,0 +1,78 @@
+package gosql
+
+import "context"
+
+// validateCheck fails unless exp, a CHECK constraint of t, is a condition
+// on the columns of the row it checks. Subqueries and aggregates would
+// read other rows, and are not allowed.
+func (t *table) validateCheck(ctx context.Context, exp *Expression) error {
+	var err error
+	Inspect(exp, func(node Node) bool {
+		e, ok := node.(*Expression)
+		switch {
+		case err != nil:
+			return false
+		case !ok:
+			return true
+		case e.Kind == InKind && e.In.Select != nil:
+			err = ErrInvalidCheck
+		case e.Kind == FunctionKind && e.Function.Over != nil:
+			err = ErrWindowNotAllowed
+		case e.Kind == FunctionKind && aggregateFunctions[e.Function.Name.Value]:
+			err = ErrAggregateNotAllowed
+		}
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Evaluating against a row of NULLs finds the columns that do not
+	// exist and the operands of the wrong type.
+	_, ct, err := t.evaluateExpression(make([]MemoryCell, len(t.columns)), fixExpressionTime(ctx, exp))
+	if err != nil {
+		return err
+	}
+	if !compatible(ct, BoolType) {
+		return ErrInvalidCondition
+	}
+	return nil
+}
+
+// checkRow fails with ErrViolatesCheck when a CHECK constraint of t is
+// false for row. NULL, being unknown, lets the row through. A constraint
+// that tells the time sees that of the statement ctx belongs to.
+func (t *table) checkRow(ctx context.Context, row []MemoryCell) error {
+	for _, exp := range t.checks {
+		cell, _, err := t.evaluateExpression(row, fixExpressionTime(ctx, exp))
+		if err != nil {
+			return err
+		}
+		if isFalse(cell) {
+			return ErrViolatesCheck
+		}
+	}
+	return nil
+}
+
+// checksRead reports whether a CHECK constraint of t reads the column
+// called name.
+func (t *table) checksRead(name string) bool {
+	found := false
+	for _, exp := range t.checks {
+		Inspect(exp, func(node Node) bool {
+			e, ok := node.(*Expression)
+			if !ok || found {
+				return !found
+			}
+			switch {
+			case e.Kind == LiteralKind && e.Literal.Kind == IdentifierKind:
+				found = e.Literal.Value == name
+			case e.Kind == ColumnReferenceKind:
+				found = e.Column.Column.Value == name
+			}
+			return !found
+		})
+	}
+	return found
+}
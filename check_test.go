@@ -0,0 +1,64 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table products (id int primary key, price float check (price > 0), discount float, check (discount < price));"+
+		"insert into products values (1, 10.0, 2.0), (2, 5.0, null)", ScriptOptions{})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		source string
+		err    error
+	}{
+		{"insert into products values (3, 0.0, null)", ErrViolatesCheck},
+		{"insert into products values (3, 4.0, 5.0)", ErrViolatesCheck},
+		{"update products set price = -1.0 where id = 2", ErrViolatesCheck},
+		{"update products set discount = price where id = 1", ErrViolatesCheck},
+		{"alter table products drop column discount", ErrDependentObjects},
+		{"alter table products rename column price to cost", ErrDependentObjects},
+		{"alter table products add column stock int default -1 check (stock >= 0)", ErrViolatesCheck},
+		{"create table bad (a int check (b > 0))", ErrColumnDoesNotExist},
+		{"create table bad (a int check (a + 1))", ErrInvalidCondition},
+		{"create table bad (a int check (a in (select id from products)))", ErrInvalidCheck},
+		{"create table bad (a int, check (count(*) > 0))", ErrAggregateNotAllowed},
+	}
+	for _, test := range tests {
+		_, err := ExecuteScript(mb, test.source, ScriptOptions{})
+		assert.ErrorIs(t, err, test.err, test.source)
+	}
+
+	// A failed statement leaves the rows as they were, and NULL passes.
+	results, err := ExecuteScript(mb, "alter table products add column stock int default 0 check (stock >= 0);"+
+		"insert into products values (3, 1.0, null, null);"+
+		"select id, price, stock from products order by id", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{
+		{intCell(1), floatCell(10), intCell(0)},
+		{intCell(2), floatCell(5), intCell(0)},
+		{intCell(3), floatCell(1), nullCell},
+	}, results[2].Results.Rows)
+}
+
+func TestParse_check(t *testing.T) {
+	ast, err := Parse("create table t (a int check (a > 0), b int, check (a < b))")
+	assert.Nil(t, err)
+	crt := ast.Statements[0].CreateTableStatement
+	assert.Len(t, crt.Checks, 1)
+	assert.Len(t, crt.checks(), 2)
+	assert.Equal(t, "CREATE TABLE t (\n  a INT CHECK (a > 0),\n  b INT,\n  CHECK (a < b)\n)", Format(ast.Statements[0]))
+
+	for _, source := range []string{
+		"create table t (a int check a > 0)",
+		"create table t (check (true))",
+		"create table t (a int, check (a > 0)",
+	} {
+		_, err := Parse(source)
+		assert.NotNil(t, err, source)
+	}
+}
@@ -0,0 +1,125 @@
+package gosql
+
+import (
+	"context"
+	"time"
+)
+
+// Clock tells a backend the time, which now(), CURRENT_TIMESTAMP and
+// CURRENT_DATE return and the defaults that call them fill in.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SetClock makes mb tell the time from clock instead of the system's, so
+// that a test can stop it. It must not be called while statements run.
+func (mb *MemoryBackend) SetClock(clock Clock) {
+	mb.clock = clock
+}
+
+// now is the time of the clock of mb. The tables of mb read it through
+// this method, so that they follow SetClock.
+func (mb *MemoryBackend) now() time.Time {
+	return mb.clock.Now()
+}
+
+// now is the time a call to a function of timeFunctions that no statement
+// fixed returns when t evaluates it: that of the clock of the backend t
+// belongs to, or the system's for a table of none.
+func (t *table) now() time.Time {
+	if t.clock == nil {
+		return time.Now()
+	}
+	return t.clock()
+}
+
+// timeFunctions are the functions that tell the time, and the type of the
+// value each returns.
+var timeFunctions = map[string]ColumnType{
+	"now":               TimestampType,
+	"current_timestamp": TimestampType,
+	"current_date":      DateType,
+}
+
+type nowKey struct{}
+
+// withTime returns ctx carrying now as the time of the statement run with
+// it.
+func withTime(ctx context.Context, now time.Time) context.Context {
+	return context.WithValue(ctx, nowKey{}, now)
+}
+
+// now is the time a statement the session runs next sees: the time its
+// transaction began, as in Postgres, or the time of the clock outside a
+// transaction.
+func (s *Session) now() time.Time {
+	if s.tx != nil {
+		return s.tx.now
+	}
+	return s.mb.clock.Now()
+}
+
+// statementTime returns the time ctx carries, which a statement replayed
+// from a log was given, or else the time the session tells.
+func (s *Session) statementTime(ctx context.Context) time.Time {
+	if now, ok := ctx.Value(nowKey{}).(time.Time); ok {
+		return now
+	}
+	return s.now()
+}
+
+// fixTime returns a copy of stmt with each call to a function of
+// timeFunctions replaced by the time of the statement ctx belongs to, so
+// that every row it reads or writes sees the same time. It returns stmt
+// itself when it calls none or ctx carries no time.
+func fixTime(ctx context.Context, stmt *Statement) *Statement {
+	now, ok := ctx.Value(nowKey{}).(time.Time)
+	if !ok || !callsTime(stmt) {
+		return stmt
+	}
+	b := binder{now: &now}
+	return b.statement(stmt)
+}
+
+// fixExpressionTime is fixTime for an expression, like the default of a
+// column.
+func fixExpressionTime(ctx context.Context, exp *Expression) *Expression {
+	now, ok := ctx.Value(nowKey{}).(time.Time)
+	if !ok || exp == nil || !callsTime(exp) {
+		return exp
+	}
+	b := binder{now: &now}
+	return b.expression(exp)
+}
+
+func callsTime(node Node) bool {
+	found := false
+	Inspect(node, func(n Node) bool {
+		if fn, ok := n.(*FunctionExpression); ok && isTimeCall(fn) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func isTimeCall(fn *FunctionExpression) bool {
+	_, ok := timeFunctions[fn.Name.Value]
+	return ok && len(fn.Args) == 0 && !fn.Asterisk && fn.Over == nil
+}
+
+// timeExpression is a literal of now as a value of type ct, a timestamp
+// or a date.
+func timeExpression(now time.Time, ct ColumnType) *Expression {
+	cell := timestampCell(now)
+	if ct == DateType {
+		cell = dateCell(now.UTC())
+	}
+	return cellExpression(cell, ct)
+}
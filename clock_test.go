@@ -0,0 +1,69 @@
+package gosql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func TestClock(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 2, 29, 12, 30, 15, 123456000, time.UTC)}
+	mb := NewMemoryBackend()
+	mb.SetClock(clock)
+
+	results, err := ExecuteScript(mb, "create table events (id int, at timestamp default now());"+
+		"insert into events (id) values (1);"+
+		"select current_timestamp, now(), current_date, at from events", ScriptOptions{})
+	assert.Nil(t, err)
+	last := results[len(results)-1].Results
+	assert.Equal(t, []ResultColumn{
+		{Type: TimestampType, Name: "current_timestamp"},
+		{Type: TimestampType, Name: "now"},
+		{Type: DateType, Name: "current_date"},
+		{Type: TimestampType, Name: "at"},
+	}, last.Columns)
+	for _, i := range []int{0, 1, 3} {
+		assert.Equal(t, clock.now, last.Rows[0][i].AsTime())
+	}
+	assert.Equal(t, "2024-02-29", FormatDate(last.Rows[0][2].AsTime()))
+
+	// A transaction sees the time it began at throughout.
+	assert.Nil(t, mb.Begin())
+	began := clock.now
+	clock.now = clock.now.Add(time.Hour)
+	_, err = ExecuteScript(mb, "insert into events (id) values (2); insert into events values (3, current_timestamp)", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Nil(t, mb.Commit())
+	results, err = ExecuteScript(mb, "select at from events where id > 1; select now() from events limit 1", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{timestampCell(began)}, {timestampCell(began)}}, results[0].Results.Rows)
+	assert.Equal(t, clock.now, results[1].Results.Rows[0][0].AsTime())
+}
+
+func TestClock_check(t *testing.T) {
+	mb := NewMemoryBackend()
+	mb.SetClock(&testClock{now: time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)})
+
+	_, err := ExecuteScript(mb, "create table deadlines (id int, at timestamp check (at > now()));"+
+		"insert into deadlines values (1, timestamp '2010-01-01 00:00');"+
+		"update deadlines set at = timestamp '2005-06-01 00:00' where id = 1", ScriptOptions{})
+	assert.Nil(t, err)
+	_, err = ExecuteScript(mb, "insert into deadlines values (2, timestamp '2000-06-01 00:00')", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrViolatesCheck)
+
+	// A call no statement fixed reads the clock of the table's backend.
+	now := &Expression{Kind: FunctionKind, Function: &FunctionExpression{Name: &Token{Value: "current_date"}}}
+	cell, ct, err := mb.tables["deadlines"].evaluateExpression(nil, now)
+	assert.Nil(t, err)
+	assert.Equal(t, DateType, ct)
+	assert.Equal(t, "2001-01-01", FormatDate(cell.AsTime()))
+}
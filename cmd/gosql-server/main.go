@@ -0,0 +1,55 @@
+// Command gosql-server serves an in-memory gosql database to PostgreSQL
+// clients, for example:
+//
+//	psql -h localhost -p 5432
+//
+// With -http it also answers queries sent as JSON over HTTP:
+//
+//	curl -d '{"query": "select * from t where id = $1", "params": [1]}' localhost:8080/query
+//
+// and with -grpc it serves the Gosql service of package rpc.
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/piaoranyc/gosql"
+	"github.com/piaoranyc/gosql/rpc"
+	"github.com/piaoranyc/gosql/server"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:5432", "address to listen on")
+	httpAddr := flag.String("http", "", "address to serve the HTTP query endpoint on, if any")
+	grpcAddr := flag.String("grpc", "", "address to serve the gRPC service on, if any")
+	flag.Parse()
+
+	backend := gosql.NewMemoryBackend()
+	s := server.New(backend)
+	s.Logger = log.New(os.Stderr, "", log.LstdFlags)
+	if *httpAddr != "" {
+		go func() {
+			log.Printf("serving HTTP on %s", *httpAddr)
+			log.Fatal(http.ListenAndServe(*httpAddr, s.HTTPHandler()))
+		}()
+	}
+	if *grpcAddr != "" {
+		l, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		gs := grpc.NewServer()
+		rpc.RegisterGosqlServer(gs, rpc.NewServer(backend))
+		go func() {
+			log.Printf("serving gRPC on %s", *grpcAddr)
+			log.Fatal(gs.Serve(l))
+		}()
+	}
+	log.Printf("listening on %s", *addr)
+	log.Fatal(s.ListenAndServe(*addr))
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/piaoranyc/gosql"
+	"github.com/piaoranyc/gosql/bench"
+)
+
+// parseMix reads the weights of the operations of a benchmark written as
+// transfer=45,lookup=45,report=10. Operations it leaves out weigh nothing.
+func parseMix(s string) (bench.Mix, error) {
+	var mix bench.Mix
+	for _, part := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		weight, err := strconv.Atoi(value)
+		if !ok || err != nil || weight < 0 {
+			return mix, fmt.Errorf("invalid mix %q: expected operation=weight", part)
+		}
+		switch name {
+		case "transfer":
+			mix.Transfer = weight
+		case "lookup":
+			mix.Lookup = weight
+		case "report":
+			mix.Report = weight
+		default:
+			return mix, fmt.Errorf("unknown operation %q: expected transfer, lookup or report", name)
+		}
+	}
+	return mix, nil
+}
+
+// runBenchmark sets up the workload of cfg on a new in-memory database, or
+// on a new database in the directory dir when it is not empty, runs it and
+// writes the report to w.
+func runBenchmark(dir string, cfg bench.Config, w io.Writer) error {
+	var ex gosql.Executor = gosql.NewMemoryBackend()
+	if dir != "" {
+		db, err := gosql.NewDiskBackend(dir)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		ex = db
+	}
+
+	ctx := context.Background()
+	if err := bench.Setup(ctx, ex, cfg); err != nil {
+		return err
+	}
+	report, err := bench.Run(ctx, ex, cfg)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, report.String())
+	return err
+}
+
+// benchCommand runs gosql bench, which runs a benchmark with the data and
+// workload its flags describe and prints the throughput and latencies.
+func benchCommand(args []string) int {
+	cfg := bench.DefaultConfig()
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	flags.IntVar(&cfg.Branches, "branches", cfg.Branches, "number of branches, which scales the data")
+	flags.IntVar(&cfg.TellersPerBranch, "tellers", cfg.TellersPerBranch, "tellers per branch")
+	flags.IntVar(&cfg.AccountsPerBranch, "accounts", cfg.AccountsPerBranch, "accounts per branch")
+	flags.IntVar(&cfg.Workers, "workers", cfg.Workers, "number of clients running operations at once")
+	flags.DurationVar(&cfg.Duration, "duration", cfg.Duration, "how long to run, or 0 for no limit")
+	flags.IntVar(&cfg.Operations, "operations", cfg.Operations, "how many operations to run, or 0 for no limit")
+	flags.Int64Var(&cfg.Seed, "seed", cfg.Seed, "seed for the generated data and the operations")
+	mix := flags.String("mix", "transfer=45,lookup=45,report=10", "weights of the operations")
+	dir := flags.String("dir", "", "directory of a new disk database to run on instead of an in-memory one")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "usage: gosql bench [flags]")
+		return 2
+	}
+
+	var err error
+	if cfg.Mix, err = parseMix(*mix); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := runBenchmark(*dir, cfg, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
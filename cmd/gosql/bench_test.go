@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/piaoranyc/gosql/bench"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMix(t *testing.T) {
+	mix, err := parseMix("transfer=5, report=1")
+	assert.Nil(t, err)
+	assert.Equal(t, bench.Mix{Transfer: 5, Report: 1}, mix)
+
+	for _, s := range []string{"", "transfer", "transfer=x", "lookup=-1", "scan=1"} {
+		_, err := parseMix(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestRunBenchmark(t *testing.T) {
+	cfg := bench.DefaultConfig()
+	cfg.AccountsPerBranch = 20
+	cfg.Duration = 0
+	cfg.Operations = 20
+
+	for _, dir := range []string{"", filepath.Join(t.TempDir(), "db")} {
+		var out bytes.Buffer
+		assert.Nil(t, runBenchmark(dir, cfg, &out), dir)
+		assert.Contains(t, out.String(), "operations per second", dir)
+	}
+}
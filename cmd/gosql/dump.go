@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/piaoranyc/gosql"
+)
+
+// dumpDatabase writes the database in the directory dir to w as a SQL
+// script.
+func dumpDatabase(dir string, w io.Writer) error {
+	// Opening a directory that is not there would create it.
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+	db, err := gosql.NewDiskBackend(dir)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.DumpSQL(context.Background(), w)
+}
+
+// restoreDatabase runs the script read from r against the database in the
+// directory dir, creating it if need be, and checkpoints it so that the
+// restored rows start out in its snapshot rather than its log.
+func restoreDatabase(dir string, r io.Reader) error {
+	db, err := gosql.NewDiskBackend(dir)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := gosql.RestoreSQL(context.Background(), db, r); err != nil {
+		return err
+	}
+	return db.Checkpoint()
+}
+
+// dumpCommand runs gosql dump, which writes the database in the directory
+// named by its first argument to the file named by its second, or to
+// standard output without one.
+func dumpCommand(args []string) int {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Fprintln(os.Stderr, "usage: gosql dump dir [file]")
+		return 2
+	}
+
+	if len(args) == 1 {
+		if err := dumpDatabase(args[0], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	f, err := os.Create(args[1])
+	if err == nil {
+		err = dumpDatabase(args[0], f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// restoreCommand runs gosql restore, which loads the script in the file
+// named by its second argument, or standard input without one, into the
+// database in the directory named by its first.
+func restoreCommand(args []string) int {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Fprintln(os.Stderr, "usage: gosql restore dir [file]")
+		return 2
+	}
+
+	in := os.Stdin
+	if len(args) == 2 {
+		f, err := os.Open(args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	if err := restoreDatabase(args[0], in); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/piaoranyc/gosql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDumpDatabase(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "db")
+	db, err := gosql.NewDiskBackend(dir)
+	assert.Nil(t, err)
+	_, err = gosql.ExecuteScript(db, "create table t (id int primary key, name text); insert into t values (1, 'a'), (2, null)", gosql.ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Nil(t, db.Close())
+
+	var dump bytes.Buffer
+	assert.Nil(t, dumpDatabase(dir, &dump))
+	assert.Equal(t, `CREATE TABLE t (
+  id INT PRIMARY KEY,
+  name TEXT
+);
+
+INSERT INTO t
+VALUES
+  (1, 'a'),
+  (2, NULL);
+
+`, dump.String())
+
+	copied := filepath.Join(t.TempDir(), "copy")
+	assert.Nil(t, restoreDatabase(copied, bytes.NewReader(dump.Bytes())))
+	var again bytes.Buffer
+	assert.Nil(t, dumpDatabase(copied, &again))
+	assert.Equal(t, dump.String(), again.String())
+
+	assert.NotNil(t, dumpDatabase(filepath.Join(t.TempDir(), "missing"), &again))
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/piaoranyc/gosql"
+)
+
+// formatScript parses the statements read from r and writes them to w in
+// the layout of gosql.Format, each ended by a semicolon and separated by a
+// blank line. Comments are dropped.
+func formatScript(r io.Reader, w io.Writer) error {
+	source, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	ast, err := gosql.Parse(string(source))
+	if err != nil {
+		return err
+	}
+
+	var formatted []string
+	for _, stmt := range ast.Statements {
+		formatted = append(formatted, gosql.Format(stmt)+";\n")
+	}
+	_, err = io.WriteString(w, strings.Join(formatted, "\n"))
+	return err
+}
+
+// formatCommand runs gosql fmt, which formats the file named by its
+// argument, or standard input without one, to standard output.
+func formatCommand(args []string) int {
+	in := os.Stdin
+	switch len(args) {
+	case 0:
+	case 1:
+		f, err := os.Open(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	default:
+		fmt.Fprintln(os.Stderr, "usage: gosql fmt [file]")
+		return 2
+	}
+
+	if err := formatScript(in, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatScript(t *testing.T) {
+	var out bytes.Buffer
+	err := formatScript(strings.NewReader("create table t (id int); -- rows\nselect id from t where id>1;"), &out)
+	assert.Nil(t, err)
+	assert.Equal(t, `CREATE TABLE t (
+  id INT
+);
+
+SELECT id
+FROM t
+WHERE id > 1;
+`, out.String())
+
+	err = formatScript(strings.NewReader("selct 1"), &out)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/piaoranyc/gosql"
+)
+
+// format prints the results of a query.
+type format func(out io.Writer, results *gosql.Results)
+
+var formats = map[string]format{
+	"table":    printTable,
+	"csv":      printCSV,
+	"json":     printJSON,
+	"vertical": printVertical,
+}
+
+func formatNames() string {
+	var names []string
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// printTable lines the results up in columns like psql does.
+func printTable(out io.Writer, results *gosql.Results) {
+	var headers []string
+	rightAlign := make([]bool, len(results.Columns))
+	for i, col := range results.Columns {
+		headers = append(headers, col.Name)
+		rightAlign[i] = col.Type == gosql.IntType || col.Type == gosql.BigIntType || col.Type == gosql.FloatType
+	}
+
+	var rows [][]string
+	for _, result := range results.Rows {
+		row := make([]string, len(result))
+		for i, cell := range result {
+			row[i] = formatCell(cell, results.Columns[i].Type)
+		}
+		rows = append(rows, row)
+	}
+
+	printColumns(out, headers, rightAlign, rows)
+	printRowCount(out, len(rows))
+}
+
+// printCSV writes a header line and then one line per row. NULL is left
+// empty.
+func printCSV(out io.Writer, results *gosql.Results) {
+	w := csv.NewWriter(out)
+	var headers []string
+	for _, col := range results.Columns {
+		headers = append(headers, col.Name)
+	}
+	_ = w.Write(headers)
+
+	for _, result := range results.Rows {
+		row := make([]string, len(result))
+		for i, cell := range result {
+			row[i] = formatCell(cell, results.Columns[i].Type)
+		}
+		_ = w.Write(row)
+	}
+	w.Flush()
+}
+
+// printJSON writes an array holding an object for each row, with the keys
+// in column order.
+func printJSON(out io.Writer, results *gosql.Results) {
+	if len(results.Rows) == 0 {
+		fmt.Fprintln(out, "[]")
+		return
+	}
+
+	fmt.Fprintln(out, "[")
+	for i, result := range results.Rows {
+		var fields []string
+		for j, cell := range result {
+			name, _ := json.Marshal(results.Columns[j].Name)
+			fields = append(fields, string(name)+": "+jsonValue(cell, results.Columns[j].Type))
+		}
+		separator := ","
+		if i == len(results.Rows)-1 {
+			separator = ""
+		}
+		fmt.Fprintf(out, "  {%s}%s\n", strings.Join(fields, ", "), separator)
+	}
+	fmt.Fprintln(out, "]")
+}
+
+func jsonValue(cell gosql.Cell, ct gosql.ColumnType) string {
+	if cell.IsNull() {
+		return "null"
+	}
+
+	switch ct {
+	case gosql.IntType, gosql.BigIntType:
+		return strconv.FormatInt(cell.AsInt(), 10)
+	case gosql.FloatType:
+		return gosql.FormatFloat(cell.AsFloat())
+	case gosql.BoolType:
+		return strconv.FormatBool(cell.AsBool())
+	case gosql.DateType, gosql.TimestampType, gosql.ByteaType:
+		text, _ := json.Marshal(formatCell(cell, ct))
+		return string(text)
+	case gosql.JSONType:
+		return cell.AsText()
+	default:
+		text, _ := json.Marshal(cell.AsText())
+		return string(text)
+	}
+}
+
+// printVertical writes each row as a record with one line per column,
+// which suits rows too wide for a table.
+func printVertical(out io.Writer, results *gosql.Results) {
+	width := 0
+	for _, col := range results.Columns {
+		if len(col.Name) > width {
+			width = len(col.Name)
+		}
+	}
+
+	for i, result := range results.Rows {
+		fmt.Fprintf(out, "-[ RECORD %d ]-\n", i+1)
+		for j, cell := range result {
+			name := results.Columns[j].Name
+			line := name + strings.Repeat(" ", width-len(name)) + " | " + formatCell(cell, results.Columns[j].Type)
+			fmt.Fprintln(out, strings.TrimRight(line, " "))
+		}
+	}
+	printRowCount(out, len(results.Rows))
+}
+
+func printRowCount(out io.Writer, n int) {
+	if n == 1 {
+		fmt.Fprintln(out, "(1 row)")
+	} else {
+		fmt.Fprintf(out, "(%d rows)\n", n)
+	}
+}
+
+// formatCell writes cell the way psql does, leaving NULL empty.
+func formatCell(cell gosql.Cell, ct gosql.ColumnType) string {
+	if cell.IsNull() {
+		return ""
+	}
+
+	switch ct {
+	case gosql.IntType, gosql.BigIntType:
+		return strconv.FormatInt(cell.AsInt(), 10)
+	case gosql.FloatType:
+		return gosql.FormatFloat(cell.AsFloat())
+	case gosql.BoolType:
+		if cell.AsBool() {
+			return "t"
+		}
+		return "f"
+	case gosql.DateType:
+		return gosql.FormatDate(cell.AsTime())
+	case gosql.TimestampType:
+		return gosql.FormatTimestamp(cell.AsTime())
+	case gosql.ByteaType:
+		return gosql.FormatBytes([]byte(cell.AsText()))
+	default:
+		return cell.AsText()
+	}
+}
+
+// printColumns lays rows out in columns under headers, with the columns
+// marked in rightAlign aligned to the right.
+func printColumns(out io.Writer, headers []string, rightAlign []bool, rows [][]string) {
+	widths := make([]int, len(headers))
+	for i, header := range headers {
+		widths[i] = len(header)
+	}
+	for _, row := range rows {
+		for i, value := range row {
+			if len(value) > widths[i] {
+				widths[i] = len(value)
+			}
+		}
+	}
+
+	line := func(values []string, right func(i int) bool) {
+		var b strings.Builder
+		for i, value := range values {
+			if i > 0 {
+				b.WriteString("|")
+			}
+			padding := strings.Repeat(" ", widths[i]-len(value))
+			if right(i) {
+				b.WriteString(" " + padding + value + " ")
+			} else {
+				b.WriteString(" " + value + padding + " ")
+			}
+		}
+		fmt.Fprintln(out, strings.TrimRight(b.String(), " "))
+	}
+
+	line(headers, func(int) bool { return false })
+	var rule []string
+	for _, width := range widths {
+		rule = append(rule, strings.Repeat("-", width+2))
+	}
+	fmt.Fprintln(out, strings.Join(rule, "+"))
+	for _, row := range rows {
+		line(row, func(i int) bool { return rightAlign != nil && rightAlign[i] })
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/piaoranyc/gosql"
+	"github.com/stretchr/testify/assert"
+)
+
+func formatResults(t *testing.T) *gosql.Results {
+	mb := gosql.NewMemoryBackend()
+	r := newRepl(mb, &bytes.Buffer{})
+	r.handle("create table users (id int, name text, admin boolean);")
+	r.handle("insert into users values (1, 'alice, \"al\"', true);")
+	r.handle("insert into users values (2, NULL, false);")
+
+	ast, err := gosql.Parse("select id, name, admin from users order by id")
+	assert.Nil(t, err)
+	results, err := mb.Select(context.Background(), ast.Statements[0].SelectStatement)
+	assert.Nil(t, err)
+	return results
+}
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: "table",
+			expected: ` id | name        | admin
+----+-------------+-------
+  1 | alice, "al" | t
+  2 |             | f
+(2 rows)
+`,
+		},
+		{
+			format: "csv",
+			expected: `id,name,admin
+1,"alice, ""al""",t
+2,,f
+`,
+		},
+		{
+			format: "json",
+			expected: `[
+  {"id": 1, "name": "alice, \"al\"", "admin": true},
+  {"id": 2, "name": null, "admin": false}
+]
+`,
+		},
+		{
+			format: "vertical",
+			expected: `-[ RECORD 1 ]-
+id    | 1
+name  | alice, "al"
+admin | t
+-[ RECORD 2 ]-
+id    | 2
+name  |
+admin | f
+(2 rows)
+`,
+		},
+	}
+
+	results := formatResults(t)
+	for _, test := range tests {
+		var out bytes.Buffer
+		formats[test.format](&out, results)
+		assert.Equal(t, test.expected, out.String(), test.format)
+	}
+
+	var out bytes.Buffer
+	printJSON(&out, &gosql.Results{})
+	assert.Equal(t, "[]\n", out.String())
+}
+
+func TestFormatCell_bytea(t *testing.T) {
+	cell := gosql.MemoryCell{0xde, 0xad, 0x00}
+	assert.Equal(t, `\xdead00`, formatCell(cell, gosql.ByteaType))
+	assert.Equal(t, `"\\xdead00"`, jsonValue(cell, gosql.ByteaType))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/piaoranyc/gosql"
+	"github.com/piaoranyc/gosql/sqllogictest"
+)
+
+// runLogicTests runs each of the sqllogictest scripts at paths on a new
+// in-memory database and writes its failures and a line of counts for it
+// to w, followed by the counts of all of them. It returns those counts.
+func runLogicTests(paths []string, w io.Writer) (*sqllogictest.Result, error) {
+	total := &sqllogictest.Result{}
+	for _, path := range paths {
+		result, err := sqllogictest.RunFile(context.Background(), gosql.NewMemoryBackend(), path)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range result.Failures {
+			fmt.Fprintf(w, "%s\n%s\n\n", f, f.SQL)
+		}
+		fmt.Fprintf(w, "%s: %s\n", path, result)
+		total.Add(result)
+	}
+	if len(paths) > 1 {
+		fmt.Fprintf(w, "total: %s\n", total)
+	}
+	return total, nil
+}
+
+// logicTestCommand runs gosql logictest, which runs the sqllogictest
+// scripts named by its arguments and reports how many of their records
+// pass. It fails when any of them does not.
+func logicTestCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: gosql logictest file...")
+		return 2
+	}
+
+	result, err := runLogicTests(args, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(result.Failures) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunLogicTests(t *testing.T) {
+	dir := t.TempDir()
+	pass := filepath.Join(dir, "pass.test")
+	fail := filepath.Join(dir, "fail.test")
+	assert.Nil(t, os.WriteFile(pass, []byte("statement ok\ncreate table t (a int)\n\nquery I nosort\nselect count(*) from t\n----\n0\n"), 0o644))
+	assert.Nil(t, os.WriteFile(fail, []byte("statement ok\nselect a from missing\n"), 0o644))
+
+	var out bytes.Buffer
+	result, err := runLogicTests([]string{pass, fail}, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result.Passed)
+	assert.Len(t, result.Failures, 1)
+	assert.Contains(t, out.String(), fail+":1: unexpected error")
+	assert.Contains(t, out.String(), pass+": 2 passed, 0 failed, 0 skipped (100.0%)")
+	assert.Contains(t, out.String(), "total: 2 passed, 1 failed, 0 skipped (66.7%)")
+
+	_, err = runLogicTests([]string{filepath.Join(dir, "missing.test")}, &out)
+	assert.NotNil(t, err)
+}
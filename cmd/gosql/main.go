@@ -0,0 +1,119 @@
+// Command gosql is an interactive shell for an in-memory gosql database.
+// Statements may span several lines and run once a line ends with a
+// semicolon. Lines starting with a backslash are meta-commands; \? lists
+// them. The -format flag, the \format meta-command and SET output_format
+// choose how results are printed: as a table, CSV, JSON or one record per
+// row. The -dialect flag reads statements as PostgreSQL or MySQL write
+// them. Tab completes keywords and the names of tables and columns.
+//
+// gosql fmt [file] instead prints the statements of a file, or of standard
+// input, in a canonical layout. gosql dump dir [file] writes the database
+// stored in the directory dir out as a SQL script, and gosql restore dir
+// [file] runs such a script against it. gosql bench generates data and
+// runs a mixed workload on it, printing its throughput and latencies, and
+// gosql logictest file... runs sqllogictest scripts and reports how many
+// of their records pass.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"github.com/piaoranyc/gosql"
+)
+
+const (
+	prompt         = "gosql> "
+	continuePrompt = "   ...> "
+)
+
+func historyFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".gosql_history")
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fmt":
+			os.Exit(formatCommand(os.Args[2:]))
+		case "dump":
+			os.Exit(dumpCommand(os.Args[2:]))
+		case "restore":
+			os.Exit(restoreCommand(os.Args[2:]))
+		case "bench":
+			os.Exit(benchCommand(os.Args[2:]))
+		case "logictest":
+			os.Exit(logicTestCommand(os.Args[2:]))
+		}
+	}
+
+	formatName := flag.String("format", "table", "print results as "+formatNames())
+	dialectName := flag.String("dialect", "", "read SQL as "+strings.Join(gosql.DialectNames(), " or "))
+	flag.Parse()
+	r := newRepl(gosql.NewMemoryBackend(), os.Stdout)
+	if !r.setFormat(*formatName) {
+		fmt.Fprintf(os.Stderr, "unknown format %s\n", *formatName)
+		os.Exit(2)
+	}
+	var dialect *gosql.Dialect
+	if *dialectName != "" {
+		d, ok := gosql.LookupDialect(*dialectName)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown dialect %s\n", *dialectName)
+			os.Exit(2)
+		}
+		dialect = &d
+	}
+
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:      prompt,
+		HistoryFile: historyFile(),
+		// Multi-line statements are saved whole once they have run.
+		DisableAutoSaveHistory: true,
+		InterruptPrompt:        "^C",
+		EOFPrompt:              `\q`,
+		AutoComplete:           r,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer rl.Close()
+
+	r.dialect = dialect
+	fmt.Println(`Welcome to gosql. Type \? for help.`)
+	for {
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt {
+			// ^C discards the statement being typed.
+			r.buffer = nil
+			rl.SetPrompt(prompt)
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		ran, quit := r.handle(line)
+		if ran != "" {
+			_ = rl.SaveHistory(ran)
+		}
+		if quit {
+			return
+		}
+
+		if r.continuing() {
+			rl.SetPrompt(continuePrompt)
+		} else {
+			rl.SetPrompt(prompt)
+		}
+	}
+}
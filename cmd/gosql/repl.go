@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/piaoranyc/gosql"
+)
+
+const help = `\d          list tables
+\d TABLE    describe a table
+\format F   print results as table, csv, json or vertical
+\onerror A  stop or continue running statements after an error
+\q          quit
+\?          show this help
+`
+
+type repl struct {
+	backend *gosql.MemoryBackend
+	out     io.Writer
+	// vertical prints the results of the statements running in the
+	// vertical format, whatever output_format is.
+	vertical bool
+	// buffer holds the lines of a statement that has not yet been ended by
+	// a semicolon.
+	buffer []string
+	// continueOnError runs the rest of the statements on a line after one
+	// fails.
+	continueOnError bool
+	// dialect is the flavor of SQL statements are read in, or nil for the
+	// default.
+	dialect *gosql.Dialect
+}
+
+func newRepl(backend *gosql.MemoryBackend, out io.Writer) *repl {
+	return &repl{backend: backend, out: out}
+}
+
+// setFormat sets output_format, which chooses how results are printed, to
+// the format called name, and reports whether there is one.
+func (r *repl) setFormat(name string) bool {
+	if _, ok := formats[name]; !ok {
+		return false
+	}
+	return r.backend.Set(&gosql.SetStatement{
+		Name:  &gosql.Token{Value: "output_format", Kind: gosql.IdentifierKind},
+		Value: &gosql.Token{Value: name, Kind: gosql.StringKind},
+	}) == nil
+}
+
+// format returns the format results are printed in, which SET
+// output_format and \format choose.
+func (r *repl) format() format {
+	if r.vertical {
+		return printVertical
+	}
+	name, _ := r.backend.Show("output_format")
+	if f, ok := formats[name]; ok {
+		return f
+	}
+	return printTable
+}
+
+// continuing reports whether the next line continues a statement.
+func (r *repl) continuing() bool {
+	return len(r.buffer) > 0
+}
+
+// handle takes one line of input. A line starting with a backslash is a
+// meta-command. Other lines are collected until one ends with a semicolon,
+// and then the statements they hold run. Ending them with \G instead
+// prints their results in the vertical format. handle returns the input it
+// ran, to be saved in the history, and whether the user asked to quit.
+func (r *repl) handle(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !r.continuing() && strings.HasPrefix(trimmed, `\`) {
+		return trimmed, r.meta(trimmed)
+	}
+	if trimmed == "" && !r.continuing() {
+		return "", false
+	}
+
+	r.buffer = append(r.buffer, line)
+	vertical := strings.HasSuffix(trimmed, `\G`)
+	if !vertical && !strings.HasSuffix(trimmed, ";") {
+		return "", false
+	}
+
+	source := strings.Join(r.buffer, "\n")
+	r.buffer = nil
+	if vertical {
+		r.vertical = true
+		r.execute(strings.TrimSuffix(strings.TrimSpace(source), `\G`))
+		r.vertical = false
+	} else {
+		r.execute(source)
+	}
+	return source, false
+}
+
+// Do completes the word before pos in line for tab completion, with the
+// lines of the statement typed so far before it. It returns what each
+// suggestion would add after the word and the length of the word.
+func (r *repl) Do(line []rune, pos int) ([][]rune, int) {
+	typed := string(line[:pos])
+	source := strings.Join(append(append([]string{}, r.buffer...), typed), "\n")
+	suggestions, start := gosql.Complete(source, len(source), r.backend.Schema())
+
+	var rest [][]rune
+	for _, s := range suggestions {
+		rest = append(rest, []rune(s[len(source)-start:]))
+	}
+	return rest, len([]rune(source[start:]))
+}
+
+// meta runs a meta-command and reports whether it was \q.
+func (r *repl) meta(command string) bool {
+	fields := strings.Fields(command)
+	switch {
+	case fields[0] == `\q`:
+		return true
+	case fields[0] == `\?`:
+		fmt.Fprint(r.out, help)
+	case fields[0] == `\d` && len(fields) == 1:
+		r.listTables()
+	case fields[0] == `\d` && len(fields) == 2:
+		r.describeTable(fields[1])
+	case fields[0] == `\format` && len(fields) == 2:
+		if !r.setFormat(fields[1]) {
+			fmt.Fprintf(r.out, "Unknown format %s. Choose one of %s.\n", fields[1], formatNames())
+		}
+	case fields[0] == `\onerror` && len(fields) == 2 && (fields[1] == "stop" || fields[1] == "continue"):
+		r.continueOnError = fields[1] == "continue"
+	default:
+		fmt.Fprintf(r.out, "Invalid command %s. Try \\? for help.\n", command)
+	}
+	return false
+}
+
+func (r *repl) listTables() {
+	schema := r.backend.Schema()
+	if len(schema) == 0 {
+		fmt.Fprintln(r.out, "No tables.")
+		return
+	}
+
+	var names []string
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var rows [][]string
+	for _, name := range names {
+		rows = append(rows, []string{name})
+	}
+	printColumns(r.out, []string{"name"}, nil, rows)
+}
+
+func (r *repl) describeTable(name string) {
+	crt, ok := r.backend.Schema()[name]
+	if !ok {
+		fmt.Fprintf(r.out, "Did not find any table named %q.\n", name)
+		return
+	}
+
+	var rows [][]string
+	for _, col := range crt.Cols {
+		var modifiers []string
+		for _, constraint := range col.Constraints {
+			switch constraint.Kind {
+			case gosql.PrimaryKeyConstraint:
+				modifiers = append(modifiers, "primary key")
+			case gosql.NotNullConstraint:
+				modifiers = append(modifiers, "not null")
+			case gosql.UniqueConstraint:
+				modifiers = append(modifiers, "unique")
+			case gosql.DefaultConstraint:
+				modifiers = append(modifiers, "default "+constraint.Default.String())
+			}
+		}
+		datatype := col.Datatype.Value
+		if col.Length != nil {
+			datatype += "(" + col.Length.Value + ")"
+		}
+		rows = append(rows, []string{col.Name.Value, datatype, strings.Join(modifiers, ", ")})
+	}
+	printColumns(r.out, []string{"column", "type", "modifiers"}, nil, rows)
+
+	if len(crt.Checks) > 0 {
+		fmt.Fprintln(r.out, "Check constraints:")
+		for _, check := range crt.Checks {
+			fmt.Fprintf(r.out, "  CHECK (%s)\n", check)
+		}
+	}
+}
+
+// execute runs each statement in source, stopping at the first error
+// unless \onerror continue is set. Pressing Ctrl-C cancels the statement
+// that is running instead of quitting.
+func (r *repl) execute(source string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	results, err := gosql.ExecuteScriptContext(ctx, r.backend, source, gosql.ScriptOptions{ContinueOnError: r.continueOnError, Dialect: r.dialect})
+	if len(results) == 0 && err != nil {
+		r.error(source, err)
+		return
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			r.error(source, result.Err)
+			continue
+		}
+		for _, warning := range result.Warnings {
+			fmt.Fprintln(r.out, "NOTICE:", warning)
+		}
+		if result.Results != nil {
+			r.format()(r.out, result.Results)
+		}
+		// Queries print their row count instead of a tag.
+		switch result.Statement.Kind {
+		case gosql.SelectKind, gosql.ExplainKind, gosql.ShowTablesKind, gosql.DescribeKind, gosql.ShowKind:
+		default:
+			fmt.Fprintln(r.out, result.Tag)
+		}
+	}
+}
+
+// error prints err followed, when it points into source, by the line it
+// is on with a caret under the offending token and any hint it has.
+func (r *repl) error(source string, err error) {
+	fmt.Fprintln(r.out, "ERROR:", err)
+	var e *gosql.Error
+	if !errors.As(err, &e) {
+		return
+	}
+	if caret := e.Caret(source); caret != "" {
+		fmt.Fprintln(r.out, caret)
+	}
+	if e.Hint != "" {
+		fmt.Fprintln(r.out, "HINT:", e.Hint)
+	}
+}
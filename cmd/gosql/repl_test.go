@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/piaoranyc/gosql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepl_handle(t *testing.T) {
+	var out bytes.Buffer
+	r := newRepl(gosql.NewMemoryBackend(), &out)
+
+	ran, quit := r.handle("create table users (id int primary key, name text, admin boolean);")
+	assert.Equal(t, "create table users (id int primary key, name text, admin boolean);", ran)
+	assert.False(t, quit)
+	assert.Equal(t, "CREATE TABLE\n", out.String())
+
+	out.Reset()
+	ran, _ = r.handle("insert into users")
+	assert.Equal(t, "", ran)
+	assert.True(t, r.continuing())
+	ran, _ = r.handle("values (1, 'alice', true);")
+	assert.Equal(t, "insert into users\nvalues (1, 'alice', true);", ran)
+	assert.False(t, r.continuing())
+	r.handle("insert into users values (10, NULL, false);")
+	assert.Equal(t, "INSERT 0 1\nINSERT 0 1\n", out.String())
+
+	out.Reset()
+	r.handle("select id, name, admin from users order by id;")
+	assert.Equal(t, ` id | name  | admin
+----+-------+-------
+  1 | alice | t
+ 10 |       | f
+(2 rows)
+`, out.String())
+
+	out.Reset()
+	r.handle("select nope from users;")
+	assert.Contains(t, out.String(), "ERROR: Column does not exist")
+
+	out.Reset()
+	r.handle("insert into users values (1, 'again', false); insert into users (id) values (2);")
+	assert.Equal(t, "ERROR: Duplicate key value violates primary key constraint\n", out.String())
+	r.handle(`\onerror continue`)
+	out.Reset()
+	r.handle("insert into users values (1, 'again', false); insert into users (id) values (2);")
+	assert.Equal(t, "ERROR: Duplicate key value violates primary key constraint\nINSERT 0 1\n", out.String())
+	r.handle(`\onerror stop`)
+
+	out.Reset()
+	r.handle("selct id\n  from users;")
+	assert.Equal(t, `ERROR: Expected statement, got selct at 0:0
+LINE 1: selct id
+        ^
+HINT: Did you mean SELECT?
+`, out.String())
+
+	out.Reset()
+	r.handle("select id\n\tfrom users @;")
+	assert.Equal(t, "ERROR: Unable to lex token after users, at 1:12\nLINE 2: \tfrom users @;\n        \t           ^\n", out.String())
+
+	out.Reset()
+	r.handle("insert into users (id) values (20), (30);")
+	assert.Equal(t, "INSERT 0 2\n", out.String())
+
+	out.Reset()
+	r.handle("begin; delete from users where id = 10; rollback;")
+	assert.Equal(t, "BEGIN\nDELETE 1\nROLLBACK\n", out.String())
+
+	out.Reset()
+	r.handle("show tables;")
+	assert.Equal(t, " name\n-------\n users\n(1 row)\n", out.String())
+
+	out.Reset()
+	r.handle("drop table if exists missing;")
+	assert.Equal(t, "NOTICE: table missing does not exist, skipping\nDROP TABLE\n", out.String())
+}
+
+func TestRepl_meta(t *testing.T) {
+	var out bytes.Buffer
+	r := newRepl(gosql.NewMemoryBackend(), &out)
+
+	r.handle(`\d`)
+	assert.Equal(t, "No tables.\n", out.String())
+
+	r.handle("create table users (id int primary key, name text not null);")
+	r.handle("create table groups (id int, kind varchar(10) default 'team');")
+	out.Reset()
+	r.handle(`\d`)
+	assert.Equal(t, ` name
+--------
+ groups
+ users
+`, out.String())
+
+	out.Reset()
+	r.handle(`\d users`)
+	assert.Equal(t, ` column | type | modifiers
+--------+------+-------------
+ id     | int  | primary key
+ name   | text | not null
+`, out.String())
+
+	out.Reset()
+	r.handle(`\d groups`)
+	assert.Equal(t, ` column | type        | modifiers
+--------+-------------+----------------
+ id     | int         |
+ kind   | varchar(10) | default 'team'
+`, out.String())
+
+	out.Reset()
+	r.handle(`\d nope`)
+	assert.Equal(t, "Did not find any table named \"nope\".\n", out.String())
+
+	out.Reset()
+	r.handle(`\x`)
+	assert.Equal(t, "Invalid command \\x. Try \\? for help.\n", out.String())
+
+	ran, quit := r.handle(`\q`)
+	assert.Equal(t, `\q`, ran)
+	assert.True(t, quit)
+}
+
+func TestRepl_Do(t *testing.T) {
+	var out bytes.Buffer
+	r := newRepl(gosql.NewMemoryBackend(), &out)
+	r.handle("create table users (id int, name text, nickname text);")
+
+	suggestions, length := r.Do([]rune("select * from us"), len("select * from us"))
+	assert.Equal(t, [][]rune{[]rune("ers")}, suggestions)
+	assert.Equal(t, 2, length)
+
+	// The lines of the statement typed so far name the table.
+	r.handle("select id from users")
+	line := []rune("where n = 'señor' and ni")
+	suggestions, length = r.Do(line, len(line))
+	assert.Equal(t, [][]rune{[]rune("ckname")}, suggestions)
+	assert.Equal(t, 2, length)
+}
+
+func TestRepl_format(t *testing.T) {
+	var out bytes.Buffer
+	r := newRepl(gosql.NewMemoryBackend(), &out)
+	r.handle("create table t (a int, b text);")
+	r.handle("insert into t values (1, 'x');")
+
+	out.Reset()
+	r.handle(`\format csv`)
+	r.handle("select a, b from t;")
+	assert.Equal(t, "a,b\n1,x\n", out.String())
+
+	// \G prints one statement vertically and leaves the format alone.
+	out.Reset()
+	r.handle(`select a, b from t\G`)
+	r.handle("select a from t;")
+	assert.Equal(t, "-[ RECORD 1 ]-\na | 1\nb | x\n(1 row)\na\n1\n", out.String())
+
+	out.Reset()
+	r.handle(`\format xml`)
+	assert.Equal(t, "Unknown format xml. Choose one of csv, json, table, vertical.\n", out.String())
+
+	// So does SET output_format.
+	out.Reset()
+	r.handle("set output_format = json; show output_format;")
+	assert.Equal(t, "SET\n[\n  {\"output_format\": \"json\"}\n]\n", out.String())
+}
@@ -0,0 +1,146 @@
+package gosql
+
+import (
+	"strings"
+	"sync"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// collation orders the text of a column declared with COLLATE. Its key
+// turns a value into bytes that compare, and are equal, the way the
+// collation compares the values, so that comparisons, sorting, grouping
+// and indexes all go by it by working on the keys. Columns without a
+// collation have a nil one and compare their bytes, as does the binary
+// collation.
+type collation struct {
+	name string
+	key  func(s string) []byte
+}
+
+// Collation names besides the language tags of the locales, which
+// golang.org/x/text/collate orders.
+const (
+	binaryCollation = "binary"
+	noCaseCollation = "nocase"
+)
+
+var (
+	collationsMu sync.Mutex
+	collations   = map[string]*collation{}
+	folder       = cases.Fold()
+)
+
+// lookupCollation returns the collation called name: binary, which is
+// nil, nocase, which compares text ignoring case, or the collation of a
+// locale given by a language tag such as en-US or de-u-ks-level2.
+func lookupCollation(name string) (*collation, error) {
+	switch strings.ToLower(name) {
+	case binaryCollation, "c", "posix":
+		return nil, nil
+	case noCaseCollation:
+		return &collation{name: noCaseCollation, key: func(s string) []byte {
+			return []byte(folder.String(s))
+		}}, nil
+	}
+
+	tag, err := language.Parse(name)
+	if err != nil {
+		return nil, ErrCollationDoesNotExist
+	}
+	collationsMu.Lock()
+	defer collationsMu.Unlock()
+	if c, ok := collations[tag.String()]; ok {
+		return c, nil
+	}
+
+	// A Collator keeps state between calls, so it is used by one key at a
+	// time.
+	var mu sync.Mutex
+	collator := collate.New(tag)
+	c := &collation{name: tag.String(), key: func(s string) []byte {
+		mu.Lock()
+		defer mu.Unlock()
+		return collator.KeyFromString(&collate.Buffer{}, s)
+	}}
+	collations[c.name] = c
+	return c, nil
+}
+
+// validateCollation checks that the COLLATE of col names a collation and
+// col is text.
+func validateCollation(col *ColumnDefinition) error {
+	if _, err := columnCollation(col, columnType(col)); err != nil {
+		return validationError(err, col.collation())
+	}
+	return nil
+}
+
+// cellKey returns the key cell compares by under c. A NULL stays NULL.
+func (c *collation) cellKey(cell MemoryCell) MemoryCell {
+	if c == nil || cell.IsNull() {
+		return cell
+	}
+	return MemoryCell(c.key(cell.AsText()))
+}
+
+// columnCollation returns the collation of the column defined as col, or
+// an error when it has one that does not exist or is not text.
+func columnCollation(col *ColumnDefinition, ct ColumnType) (*collation, error) {
+	name := col.collation()
+	if name == nil {
+		return nil, nil
+	}
+	if ct != TextType {
+		return nil, ErrInvalidDatatype
+	}
+	return lookupCollation(name.Value)
+}
+
+// collation returns the collation of column i of t.
+func (t *table) collation(i int) *collation {
+	if i >= len(t.collations) {
+		return nil
+	}
+	return t.collations[i]
+}
+
+// collationOf returns the collation of the column exp names, or nil when
+// exp is not a column of t or the column has none.
+func (t *table) collationOf(exp *Expression) *collation {
+	if len(t.collations) == 0 {
+		return nil
+	}
+	i, err := t.resolveColumn(exp)
+	if err != nil || i == -1 {
+		return nil
+	}
+	return t.collation(i)
+}
+
+// comparisonCollation returns the collation values of type ct from exps
+// are compared by: that of the first of them that is a column with a
+// collation. Only text has a collation.
+func (t *table) comparisonCollation(ct ColumnType, exps ...*Expression) *collation {
+	if ct != TextType {
+		return nil
+	}
+	for _, exp := range exps {
+		if c := t.collationOf(exp); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// evaluateKey evaluates exp for ordering or grouping rows by it, giving
+// the key of its collation for a column with one.
+func (t *table) evaluateKey(row []MemoryCell, exp *Expression) (MemoryCell, ColumnType, error) {
+	cell, ct, err := t.evaluateExpression(row, exp)
+	if err != nil {
+		return nil, 0, err
+	}
+	return t.comparisonCollation(ct, exp).cellKey(cell), ct, nil
+}
@@ -0,0 +1,87 @@
+package gosql
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollation(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table users (id int primary key, name text collate nocase unique, word text collate de, raw text);"+
+		"insert into users values (1, 'Bob', 'zebra', 'Bob'), (2, 'alice', 'äpple', 'alice'), (3, 'Carol', 'apa', 'Carol')", ScriptOptions{})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		source string
+		rows   [][]Cell
+	}{
+		{"select id from users where name = 'BOB'", [][]Cell{{intCell(1)}}},
+		{"select id from users where raw = 'BOB'", [][]Cell{}},
+		{"select id from users where name in ('ALICE', 'carol') order by id", [][]Cell{{intCell(2)}, {intCell(3)}}},
+		{"select id from users where name between 'B' and 'bz'", [][]Cell{{intCell(1)}}},
+		{"select name from users order by name", [][]Cell{{MemoryCell("alice")}, {MemoryCell("Bob")}, {MemoryCell("Carol")}}},
+		{"select raw from users order by raw", [][]Cell{{MemoryCell("Bob")}, {MemoryCell("Carol")}, {MemoryCell("alice")}}},
+		{"select word from users order by word", [][]Cell{{MemoryCell("apa")}, {MemoryCell("äpple")}, {MemoryCell("zebra")}}},
+		{"select min(name), max(word) from users", [][]Cell{{MemoryCell("alice"), MemoryCell("zebra")}}},
+		{"select u.id from users u join users v on u.name = v.raw where v.id = 1", [][]Cell{{intCell(1)}}},
+	}
+	for _, test := range tests {
+		results, err := ExecuteScript(mb, test.source, ScriptOptions{})
+		if assert.Nil(t, err, test.source) {
+			assert.Equal(t, test.rows, results[0].Results.Rows, test.source)
+		}
+	}
+
+	// Values that differ only in case are one group and violate UNIQUE.
+	_, err = ExecuteScript(mb, "insert into users values (4, 'BOB', 'x', 'y')", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrViolatesUnique)
+	results, err := ExecuteScript(mb, "insert into users values (4, null, 'x', 'BOB');"+
+		"create table names (name text collate nocase);"+
+		"insert into names select raw from users;"+
+		"select name, count(*) from names group by name order by name", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{MemoryCell("alice"), intCell(1)}, {MemoryCell("Bob"), intCell(2)}, {MemoryCell("Carol"), intCell(1)}}, results[3].Results.Rows)
+
+	// An index holds the keys, so lookups through it go by the collation.
+	results, err = ExecuteScript(mb, "create index names_name on names (name); select count(*) from names where name = 'bob'", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(2)}}, results[1].Results.Rows)
+
+	for _, source := range []string{
+		"create table bad (name text collate nosuchcollation)",
+		"alter table users add column bad text collate nosuchcollation",
+	} {
+		_, err := ExecuteScript(mb, source, ScriptOptions{})
+		assert.ErrorIs(t, err, ErrCollationDoesNotExist, source)
+	}
+	_, err = ExecuteScript(mb, "create table bad (n int collate nocase)", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrInvalidDatatype)
+}
+
+func TestCollation_snapshot(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table t (name text collate nocase primary key); insert into t values ('Alice')", ScriptOptions{})
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	assert.Nil(t, mb.WriteSnapshot(context.Background(), &buf))
+	loaded, err := LoadSnapshot(bytes.NewReader(buf.Bytes()))
+	assert.Nil(t, err)
+	_, err = ExecuteScript(loaded, "insert into t values ('ALICE')", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrViolatesPrimaryKey)
+}
+
+func TestParse_collate(t *testing.T) {
+	ast, err := Parse(`create table t (a text collate "en-US" not null, b text collate nocase)`)
+	assert.Nil(t, err)
+	crt := ast.Statements[0].CreateTableStatement
+	assert.Equal(t, "en-US", crt.Cols[0].collation().Value)
+	assert.Equal(t, "nocase", crt.Cols[1].collation().Value)
+	assert.Equal(t, "CREATE TABLE t (\n  a TEXT COLLATE \"en-US\" NOT NULL,\n  b TEXT COLLATE nocase\n)", Format(ast.Statements[0]))
+
+	_, err = Parse("create table t (a text collate)")
+	assert.NotNil(t, err)
+}
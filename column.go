@@ -0,0 +1,300 @@
+package gosql
+
+import (
+	"context"
+	"math"
+)
+
+// columnVector holds one column of a table in the column layout, with an
+// entry for each of the table's versions at the same position. Integers
+// and floats are kept unpacked in slices of their own so that aggregates
+// over them read contiguous memory instead of decoding a cell per row.
+type columnVector struct {
+	ct    ColumnType
+	nulls []bool
+	// Only one of ints, floats, bools and cells is used, by the type of
+	// the column: ints for INT and BIGINT, floats for FLOAT, bools for
+	// BOOLEAN and cells for the rest.
+	ints   []int64
+	floats []float64
+	bools  []bool
+	cells  []MemoryCell
+}
+
+func newColumnVector(ct ColumnType) *columnVector {
+	return &columnVector{ct: ct}
+}
+
+// makeColumnVector returns a vector of n entries of type ct, all zero
+// values that are not NULL.
+func makeColumnVector(ct ColumnType, n int) *columnVector {
+	cv := &columnVector{ct: ct, nulls: make([]bool, n)}
+	switch ct {
+	case IntType, BigIntType:
+		cv.ints = make([]int64, n)
+	case FloatType:
+		cv.floats = make([]float64, n)
+	case BoolType:
+		cv.bools = make([]bool, n)
+	default:
+		cv.cells = make([]MemoryCell, n)
+	}
+	return cv
+}
+
+func (cv *columnVector) append(cell MemoryCell) {
+	cv.nulls = append(cv.nulls, cell.IsNull())
+	switch cv.ct {
+	case IntType, BigIntType:
+		cv.ints = append(cv.ints, cell.AsInt())
+	case FloatType:
+		cv.floats = append(cv.floats, cell.AsFloat())
+	case BoolType:
+		cv.bools = append(cv.bools, cell.AsBool())
+	default:
+		cv.cells = append(cv.cells, cell)
+	}
+}
+
+// cell returns entry i as a cell of the vector's type.
+func (cv *columnVector) cell(i int) MemoryCell {
+	switch {
+	case cv.nulls[i]:
+		return nullCell
+	case cv.ct == IntType:
+		return intCell(int32(cv.ints[i]))
+	case cv.ct == BigIntType:
+		return bigIntCell(cv.ints[i])
+	case cv.ct == FloatType:
+		return floatCell(cv.floats[i])
+	case cv.ct == BoolType:
+		return boolCell(cv.bools[i])
+	}
+	return cv.cells[i]
+}
+
+// float returns entry i of a numeric vector as a float.
+func (cv *columnVector) float(i int) float64 {
+	if cv.ct == FloatType {
+		return cv.floats[i]
+	}
+	return float64(cv.ints[i])
+}
+
+// appendVectors adds cells, a new version, to the vectors of a table in
+// the column layout.
+func (t *table) appendVectors(cells []MemoryCell) {
+	for i, cv := range t.vectors {
+		cv.append(cells[i])
+	}
+}
+
+// rebuildVectors makes the vectors of a table in the column layout again
+// from its versions, after they were renumbered or their columns changed.
+func (t *table) rebuildVectors() {
+	if !t.columnar {
+		return
+	}
+	t.vectors = make([]*columnVector, len(t.columns))
+	for i, ct := range t.columnTypes {
+		t.vectors[i] = newColumnVector(ct)
+	}
+	for _, v := range t.versions {
+		t.appendVectors(v.cells)
+	}
+}
+
+// columnAggregate runs slct from the vectors of its table when the table
+// is in the column layout and slct only computes aggregates of its columns
+// over every row, such as SELECT sum(x), count(*) FROM t. It reports false
+// for other queries, which run as usual.
+func (mb *MemoryBackend) columnAggregate(ctx context.Context, snap *txSnapshot, slct *SelectStatement) (*Results, bool, error) {
+	if slct.From == nil || slct.FromSelect != nil || len(slct.Join) > 0 || slct.Where != nil ||
+		len(slct.GroupBy) > 0 || slct.Having != nil || len(slct.OrderBy) > 0 {
+		return nil, false, nil
+	}
+	t, ok := mb.tables[slct.From.Value]
+	if !ok || !t.columnar {
+		return nil, false, nil
+	}
+
+	// Each item has to be an aggregate of a bare column, or count(*),
+	// which is col -1.
+	view := t.aliased(slct.FromAs)
+	cols := make([]int, len(slct.Item))
+	for i, item := range slct.Item {
+		if item.Asterisk || item.Exp.Kind != FunctionKind || !item.Exp.Function.isAggregate() {
+			return nil, false, nil
+		}
+		fn := item.Exp.Function
+		cols[i] = -1
+		if fn.Asterisk {
+			continue
+		}
+		if len(fn.Args) != 1 {
+			return nil, false, nil
+		}
+		// The vectors compare text by its bytes, not by a collation.
+		col, err := view.resolveColumn(fn.Args[0])
+		if err != nil || col == -1 || t.collation(col) != nil {
+			return nil, false, nil
+		}
+		cols[i] = col
+	}
+
+	var columns []ResultColumn
+	for _, item := range slct.Item {
+		// groupColumn also rejects aggregates of the wrong type.
+		column, err := view.groupColumn(nil, item)
+		if err != nil {
+			return nil, true, err
+		}
+		columns = append(columns, column)
+	}
+
+	var visible []int
+	for pos, v := range t.versions {
+		if err := canceled(ctx, pos); err != nil {
+			return nil, true, err
+		}
+		if snap.visible(v) {
+			visible = append(visible, pos)
+		}
+	}
+	statementMetrics(ctx).scanned(len(visible))
+
+	row := make([]Cell, len(slct.Item))
+	for i, item := range slct.Item {
+		fn := item.Exp.Function
+		if cols[i] == -1 {
+			row[i] = intCell(int32(len(visible)))
+			continue
+		}
+		cell, err := t.vectors[cols[i]].aggregate(fn.Name.Value, visible)
+		if err != nil {
+			return nil, true, err
+		}
+		row[i] = cell
+	}
+
+	results := &Results{Columns: columns, Rows: [][]Cell{row}}
+	if slct.Distinct {
+		results.Rows = distinct(results.Rows, results.Columns)
+	}
+	var err error
+	results.Rows, err = t.limit(results.Rows, slct)
+	if err != nil {
+		return nil, true, err
+	}
+	return results, true, nil
+}
+
+// aggregate computes the aggregate function name over the entries of cv
+// at the positions rows, giving the same result evaluateAggregate gives
+// over the cells.
+func (cv *columnVector) aggregate(name string, rows []int) (MemoryCell, error) {
+	if name == "min" || name == "max" {
+		return cv.extreme(name == "min", rows), nil
+	}
+
+	var sum int64
+	var fsum float64
+	count := 0
+	for _, r := range rows {
+		if cv.nulls[r] {
+			continue
+		}
+		count++
+		switch {
+		case name == "count":
+		case cv.ct == FloatType:
+			fsum += cv.floats[r]
+		case name == "sum":
+			n := cv.ints[r]
+			if n > 0 && sum > math.MaxInt64-n || n < 0 && sum < math.MinInt64-n {
+				return nil, ErrIntegerOutOfRange
+			}
+			sum += n
+		default:
+			fsum += float64(cv.ints[r])
+		}
+	}
+	if math.IsInf(fsum, 0) {
+		return nil, ErrValueOutOfRange
+	}
+
+	switch {
+	case name == "count":
+		return intCell(int32(count)), nil
+	case count == 0:
+		return nullCell, nil
+	case name == "avg":
+		return floatCell(fsum / float64(count)), nil
+	case cv.ct == FloatType:
+		return floatCell(fsum), nil
+	}
+	return convertCell(bigIntCell(sum), BigIntType, cv.ct)
+}
+
+// extreme implements min, when min is set, and max.
+func (cv *columnVector) extreme(min bool, rows []int) MemoryCell {
+	best := -1
+	for _, r := range rows {
+		if cv.nulls[r] {
+			continue
+		}
+		if best == -1 {
+			best = r
+			continue
+		}
+		var c int
+		switch cv.ct {
+		case IntType, BigIntType:
+			c = compareInts(cv.ints[r], cv.ints[best])
+		case FloatType:
+			c = compareFloats(cv.floats[r], cv.floats[best])
+		case BoolType:
+			c = compareBools(cv.bools[r], cv.bools[best])
+		default:
+			c = compareCells(cv.cells[r], cv.cells[best], cv.ct)
+		}
+		if min && c < 0 || !min && c > 0 {
+			best = r
+		}
+	}
+
+	if best == -1 {
+		return nullCell
+	}
+	return cv.cell(best)
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case b:
+		return -1
+	}
+	return 1
+}
@@ -0,0 +1,82 @@
+package gosql
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_tableLayout(t *testing.T) {
+	ast, err := Parse("create table t (x int) with (layout = 'column')")
+	assert.Nil(t, err)
+	assert.Equal(t, ColumnLayout, ast.Statements[0].CreateTableStatement.Layout.Value)
+
+	_, err = Parse("create table t (x int) with (layout = 'diagonal')")
+	assert.ErrorContains(t, err, "Expected 'row' or 'column'")
+	_, err = Parse("create table t (x int) with (storage = 'column')")
+	assert.ErrorContains(t, err, "Expected layout")
+}
+
+func TestMemoryBackend_columnLayout(t *testing.T) {
+	mb := NewMemoryBackend()
+	script := "create table r (id int primary key, n int, b bigint, f float, s text);" +
+		"create table c (id int primary key, n int, b bigint, f float, s text) with (layout = 'column');"
+	for i := 1; i <= 100; i++ {
+		for _, name := range []string{"r", "c"} {
+			script += fmt.Sprintf("insert into %s values (%d, %d, %d, %d.5, '%03d');", name, i, i%7-3, i*1000000000, i, i%13)
+		}
+	}
+	_, err := ExecuteScript(mb, script+"insert into r (id) values (0); insert into c (id) values (0)", ScriptOptions{})
+	assert.Nil(t, err)
+
+	// The column layout answers aggregates from its vectors, which has to
+	// give what the rows of the row layout give.
+	queries := []string{
+		"select count(*), count(n), sum(n), avg(n), min(n), max(n) from %s",
+		"select sum(b), avg(b), min(b), max(b), sum(f), avg(f), min(f), max(f) from %s",
+		"select min(s), max(s), count(s) from %s as x",
+		"select sum(x.n) from %s x limit 0",
+		"select count(n), sum(n), avg(n), sum(f), avg(f), min(n) from %s where id > 1000",
+	}
+	check := func(stage string) {
+		for _, q := range queries {
+			rows, err := ExecuteScript(mb, fmt.Sprintf(q, "r"), ScriptOptions{})
+			assert.Nil(t, err, q)
+			columns, err := ExecuteScript(mb, fmt.Sprintf(q, "c"), ScriptOptions{})
+			assert.Nil(t, err, q)
+			if err == nil {
+				assert.Equal(t, rows[0].Results, columns[0].Results, stage+": "+q)
+			}
+		}
+	}
+	check("insert")
+
+	_, err = ExecuteScript(mb, "update r set n = n * 2 where id % 3 = 0; update c set n = n * 2 where id % 3 = 0;"+
+		"delete from r where id > 90; delete from c where id > 90", ScriptOptions{})
+	assert.Nil(t, err)
+	check("update")
+
+	assert.Nil(t, mb.Begin())
+	_, err = ExecuteScript(mb, "delete from c where id < 50; insert into c values (200, 1, 1, 1, 'z')", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Nil(t, mb.Rollback())
+	check("rollback")
+
+	_, err = ExecuteScript(mb, "alter table r drop column b; alter table c drop column b;"+
+		"alter table r add column m int default 4; alter table c add column m int default 4", ScriptOptions{})
+	assert.Nil(t, err)
+	queries = append(queries[:1], queries[2:]...)
+	queries = append(queries, "select sum(m), max(m) from %s")
+	check("alter")
+
+	_, err = ExecuteScript(mb, "select sum(s) from c", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+	_, err = ExecuteScript(mb, "create table o (n int) with (layout = 'column'); insert into o values (2147483647), (1);"+
+		"select sum(n) from o", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrIntegerOutOfRange)
+
+	schema := mb.Schema()
+	assert.Equal(t, ColumnLayout, schema["c"].Layout.Value)
+	assert.Nil(t, schema["r"].Layout)
+}
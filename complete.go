@@ -0,0 +1,220 @@
+package gosql
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tableContexts are the words after which a table name is expected.
+var tableContexts = map[string]bool{
+	"from": true, "join": true, "into": true, "update": true, "table": true, "describe": true,
+}
+
+// columnContexts are the words after which a column name may come, along
+// with any operator.
+var columnContexts = map[string]bool{
+	"select": true, "where": true, "and": true, "or": true, "not": true, "on": true,
+	"by": true, "set": true, "having": true, "distinct": true, "returning": true,
+}
+
+// Complete suggests how to finish the word being typed at byte offset pos
+// of source, given the tables of schema. After FROM, JOIN, INTO, UPDATE and
+// the like it suggests table names; where an expression may go it suggests
+// the columns of the tables the statement names, as well as keywords; after
+// a table name or alias and a dot, only the columns of that table. Inside
+// a string or comment there is nothing to suggest.
+//
+// start is the offset of the word being completed, so that the
+// suggestions replace source[start:pos]. Each suggestion begins with that
+// text as typed, and keywords follow its case.
+func Complete(source string, pos int, schema Schema) (suggestions []string, start int) {
+	if pos < 0 || pos > len(source) {
+		pos = len(source)
+	}
+	start = pos
+	for start > 0 && isWordByte(source[start-1]) {
+		start--
+	}
+	word := source[start:pos]
+
+	qualifier := ""
+	if start > 0 && source[start-1] == '.' {
+		from := start - 1
+		for from > 0 && isWordByte(source[from-1]) {
+			from--
+		}
+		qualifier = strings.ToLower(source[from : start-1])
+	}
+
+	before := statementWords(source, start)
+	if before == nil {
+		return nil, start
+	}
+	tables := statementTables(source, start, schema)
+
+	var candidates []string
+	if qualifier != "" {
+		if table, ok := tables[qualifier]; ok {
+			candidates = columnNames(schema[table])
+		}
+		return matching(word, candidates, nil), start
+	}
+
+	prev := ""
+	if len(before.words) > 0 {
+		prev = before.words[len(before.words)-1]
+	}
+	switch {
+	case tableContexts[prev]:
+		for name := range schema {
+			candidates = append(candidates, name)
+		}
+		return matching(word, candidates, nil), start
+	case columnContexts[prev] || before.operator:
+		seen := map[string]bool{}
+		for _, table := range tables {
+			if !seen[table] {
+				seen[table] = true
+				candidates = append(candidates, columnNames(schema[table])...)
+			}
+		}
+	}
+	return matching(word, candidates, keywordOptions), start
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= utf8.RuneSelf || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// completionContext is what comes before the word being completed in its
+// statement.
+type completionContext struct {
+	// words holds the lower-cased keywords and identifiers.
+	words []string
+	// operator is whether the last token is an operator, comma or
+	// parenthesis rather than a word.
+	operator bool
+}
+
+// statementWords returns what comes before offset end in the statement it
+// is in, or nil when end is inside a string or comment.
+func statementWords(source string, end int) *completionContext {
+	c := &completionContext{}
+	for _, span := range Highlight(source[:end]) {
+		text := source[span.Offset:span.End]
+		switch span.Class {
+		case ErrorClass:
+			if int(span.End) == end && unclosed(text) {
+				return nil
+			}
+		case CommentClass:
+			if int(span.End) == end && strings.HasPrefix(text, "--") {
+				return nil
+			}
+			continue
+		case OperatorClass:
+			if text == string(SemiColonSymbol) {
+				c.words = nil
+				c.operator = false
+				continue
+			}
+			c.operator = true
+			continue
+		case KeywordClass, IdentifierClass:
+			c.words = append(c.words, strings.ToLower(text))
+		}
+		c.operator = false
+	}
+	return c
+}
+
+// statementTables maps the names and aliases of the tables of schema that
+// the statement around offset at reads or writes to their table.
+func statementTables(source string, at int, schema Schema) map[string]string {
+	spans := Highlight(source)
+	// Keep only the spans of the statement around at.
+	first, last := 0, len(spans)
+	for i, span := range spans {
+		if span.Class != OperatorClass || source[span.Offset:span.End] != string(SemiColonSymbol) {
+			continue
+		}
+		if int(span.End) <= at {
+			first = i + 1
+		} else {
+			last = i
+			break
+		}
+	}
+	spans = spans[first:last]
+
+	word := func(i int) (string, bool) {
+		if i >= len(spans) || spans[i].Class != IdentifierClass && spans[i].Class != KeywordClass {
+			return "", false
+		}
+		return strings.ToLower(strings.Trim(source[spans[i].Offset:spans[i].End], `"`)), true
+	}
+	tables := map[string]string{}
+	for i := range spans {
+		prev, _ := word(i)
+		if !tableContexts[prev] {
+			continue
+		}
+		name, ok := word(i + 1)
+		if _, exists := schema[name]; !ok || !exists {
+			continue
+		}
+		tables[name] = name
+		next := i + 2
+		if as, _ := word(next); as == string(AsKeyword) {
+			next++
+		}
+		if alias, ok := word(next); ok && spans[next].Class == IdentifierClass {
+			tables[alias] = name
+		}
+	}
+	return tables
+}
+
+func columnNames(crt *CreateTableStatement) []string {
+	if crt == nil {
+		return nil
+	}
+	var names []string
+	for _, col := range crt.Cols {
+		names = append(names, col.Name.Value)
+	}
+	return names
+}
+
+// matching returns the names and keywords that start with word, ignoring
+// case, sorted and without duplicates.
+func matching(word string, names, keywords []string) []string {
+	lower := strings.ToLower(word)
+	upper := word == "" || strings.IndexFunc(word, unicode.IsUpper) >= 0
+
+	seen := map[string]bool{}
+	var suggestions []string
+	add := func(s string) {
+		if !strings.HasPrefix(strings.ToLower(s), lower) {
+			return
+		}
+		if s = word + s[len(word):]; !seen[s] {
+			seen[s] = true
+			suggestions = append(suggestions, s)
+		}
+	}
+	for _, name := range names {
+		add(name)
+	}
+	for _, k := range keywords {
+		if upper {
+			add(strings.ToUpper(k))
+		} else {
+			add(strings.ToLower(k))
+		}
+	}
+	sort.Strings(suggestions)
+	return suggestions
+}
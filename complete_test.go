@@ -0,0 +1,47 @@
+package gosql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplete(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, `create table users (id int, name text, nickname text);
+create table orders (id int, user_id int, note text)`, ScriptOptions{})
+	assert.Nil(t, err)
+	schema := mb.Schema()
+
+	for _, test := range []struct {
+		source      string
+		suggestions []string
+	}{
+		{"select * from ", []string{"orders", "users"}},
+		{"select * from U", []string{"Users"}},
+		{"insert into o", []string{"orders"}},
+		// The cursor, marked by |, may be before the end.
+		{"select n| from users", []string{"name", "nickname", "not", "null"}},
+		{"select * from users where ni", []string{"nickname"}},
+		{"select * from users u join orders o on u.id = o.", []string{"id", "note", "user_id"}},
+		{"select * from users as u where u.n", []string{"name", "nickname"}},
+		{"select * from users; select * from orders where u", []string{"union", "unique", "update", "user_id"}},
+		{"sel", []string{"select"}},
+		{"SEL", []string{"SELECT"}},
+		{"select * from users where name = 'n", nil},
+		{"select * from users -- n", nil},
+		{"select * from nope where x.", nil},
+	} {
+		source := strings.Replace(test.source, "|", "", 1)
+		pos := len(source)
+		if i := strings.Index(test.source, "|"); i >= 0 {
+			pos = i
+		}
+		suggestions, start := Complete(source, pos, schema)
+		assert.Equal(t, test.suggestions, suggestions, test.source)
+		for _, s := range suggestions {
+			assert.Equal(t, source[start:pos], s[:pos-start], test.source)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package gosql
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// copyColumns is the table cp reads or writes and the columns it copies,
+// in file order.
+func copyColumns(ex Executor, cp *CopyStatement) ([]*ColumnDefinition, error) {
+	t, err := ex.Schema().table(cp.Table)
+	if err != nil {
+		return nil, err
+	}
+	return columnList(t, cp.Columns)
+}
+
+func copyDelimiter(cp *CopyStatement) rune {
+	if cp.Delimiter == nil {
+		return ','
+	}
+	return []rune(cp.Delimiter.Value)[0]
+}
+
+func copyNull(cp *CopyStatement) string {
+	if cp.Null == nil {
+		return ""
+	}
+	return cp.Null.Value
+}
+
+// copyFrom loads the rows of the CSV file cp names into its table. Each
+// field is read the way a string cast to the type of its column is, and a
+// field matching the NULL string, empty by default, is NULL. The rows go
+// in as a single batch, or a single INSERT on a backend that cannot take
+// one, so either all of them are loaded or none are.
+func copyFrom(ctx context.Context, ex Executor, cp *CopyStatement) (int, error) {
+	cols, err := copyColumns(ex, cp)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.Open(cp.File.Value)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comma = copyDelimiter(cp)
+	r.FieldsPerRecord = len(cols)
+	null := copyNull(cp)
+
+	var rows [][]Cell
+	for row := 1; ; row++ {
+		if err := canceled(ctx, row); err != nil {
+			return 0, err
+		}
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return 0, fmt.Errorf("%w: COPY %s, line %d: %s", ErrBadCopyFileFormat, cp.Table.Value, parseErr.Line, parseErr.Err)
+			}
+			return 0, err
+		}
+		if row == 1 && cp.Header {
+			continue
+		}
+
+		cells := make([]Cell, len(cols))
+		for i, field := range record {
+			ct := columnType(cols[i])
+			var cell MemoryCell
+			switch {
+			case field == null:
+			case ct == TextType:
+				cell = MemoryCell(field)
+			default:
+				cell, err = parseCell(field, ct)
+				if err != nil {
+					return 0, fmt.Errorf("%w: COPY %s, row %d, column %s", err, cp.Table.Value, row, cols[i].Name.Value)
+				}
+			}
+			cells[i] = cell
+		}
+		rows = append(rows, cells)
+	}
+
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	if b, ok := ex.(BatchInserter); ok {
+		var columns []string
+		for _, col := range cp.Columns {
+			columns = append(columns, col.Value)
+		}
+		return b.InsertRows(ctx, cp.Table.Value, columns, rows)
+	}
+
+	inst := InsertStatement{Table: cp.Table, Columns: cp.Columns}
+	for _, cells := range rows {
+		values := make([]*Expression, len(cols))
+		for i, cell := range cells {
+			values[i] = cellExpression(cell.(MemoryCell), columnType(cols[i]))
+		}
+		inst.Values = append(inst.Values, values)
+	}
+	n, _, err := ex.Insert(ctx, &inst)
+	return n, err
+}
+
+// copyTo writes the rows of the table cp names to its CSV file, replacing
+// anything already there.
+func copyTo(ctx context.Context, ex Executor, cp *CopyStatement) (n int, err error) {
+	cols, err := copyColumns(ex, cp)
+	if err != nil {
+		return 0, err
+	}
+
+	slct := SelectStatement{From: cp.Table}
+	for _, col := range cols {
+		slct.Item = append(slct.Item, &SelectItem{
+			Exp: &Expression{Kind: LiteralKind, Literal: &Token{Kind: IdentifierKind, Value: col.Name.Value}},
+		})
+	}
+	results, err := ex.Select(ctx, &slct)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.Create(cp.File.Value)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	w := csv.NewWriter(f)
+	w.Comma = copyDelimiter(cp)
+	null := copyNull(cp)
+
+	record := make([]string, len(results.Columns))
+	if cp.Header {
+		for i, col := range results.Columns {
+			record[i] = col.Name
+		}
+		if err := w.Write(record); err != nil {
+			return 0, err
+		}
+	}
+	for _, row := range results.Rows {
+		for i, cell := range row {
+			if cell.IsNull() {
+				record[i] = null
+			} else {
+				record[i] = copyText(cell, results.Columns[i].Type)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return 0, err
+		}
+	}
+	w.Flush()
+	return len(results.Rows), w.Error()
+}
+
+// copyText is the text COPY writes for cell, which reads back as the same
+// value.
+func copyText(cell Cell, ct ColumnType) string {
+	switch ct {
+	case IntType, BigIntType:
+		return strconv.FormatInt(cell.AsInt(), 10)
+	case FloatType:
+		return FormatFloat(cell.AsFloat())
+	case BoolType:
+		if cell.AsBool() {
+			return "t"
+		}
+		return "f"
+	case DateType:
+		return FormatDate(cell.AsTime())
+	case TimestampType:
+		return FormatTimestamp(cell.AsTime())
+	case ByteaType:
+		return FormatBytes([]byte(cell.AsText()))
+	}
+	return cell.AsText()
+}
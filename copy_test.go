@@ -0,0 +1,86 @@
+package gosql
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopy(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.csv")
+	err := os.WriteFile(in, []byte(`name;id;joined;admin
+"Smith; Alice";1;2024-01-02;t
+bob;2;NA;false
+`), 0600)
+	assert.Nil(t, err)
+
+	mb := NewMemoryBackend()
+	_, err = ExecuteScript(mb, "create table users (id int primary key, name text, joined date, admin boolean)", ScriptOptions{})
+	assert.Nil(t, err)
+
+	results, err := ExecuteScript(mb, "copy users (name, id, joined, admin) from '"+in+"' with (delimiter ';', header, null 'NA')", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "COPY 2", results[0].Tag)
+
+	results, err = ExecuteScript(mb, "select name, joined from users order by id", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "Smith; Alice", results[0].Results.Rows[0][0].AsText())
+	assert.Equal(t, "2024-01-02", FormatDate(results[0].Results.Rows[0][1].AsTime()))
+	assert.True(t, results[0].Results.Rows[1][1].IsNull())
+
+	out := filepath.Join(dir, "out.csv")
+	results, err = ExecuteScript(mb, "copy users to '"+out+"' header", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "COPY 2", results[0].Tag)
+	written, err := os.ReadFile(out)
+	assert.Nil(t, err)
+	assert.Equal(t, "id,name,joined,admin\n1,Smith; Alice,2024-01-02,t\n2,bob,,f\n", string(written))
+
+	// The written file loads back into an identical table.
+	_, err = ExecuteScript(mb, "create table copied (id int primary key, name text, joined date, admin boolean); copy copied from '"+out+"' csv header", ScriptOptions{})
+	assert.Nil(t, err)
+	results, err = ExecuteScript(mb, "select * from copied where joined is null and not admin", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results[0].Results.Rows))
+}
+
+func TestCopy_errors(t *testing.T) {
+	dir := t.TempDir()
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table users (id int primary key, name text)", ScriptOptions{})
+	assert.Nil(t, err)
+
+	write := func(content string) string {
+		path := filepath.Join(dir, "users.csv")
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	// A bad row loads nothing.
+	path := write("1,alice\ntwo,bob\n")
+	_, err = ExecuteScript(mb, "copy users from '"+path+"'", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrInvalidTextRepresentation)
+	assert.Contains(t, err.Error(), "COPY users, row 2, column id")
+
+	path = write("1,alice\n2\n")
+	_, err = ExecuteScript(mb, "copy users from '"+path+"'", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrBadCopyFileFormat)
+	assert.Equal(t, "22P04", SQLState(err))
+
+	path = write("1,alice\n1,bob\n")
+	_, err = ExecuteScript(mb, "copy users from '"+path+"'", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrViolatesPrimaryKey)
+
+	results, err := ExecuteScript(mb, "select * from users", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results[0].Results.Rows))
+
+	_, err = ExecuteScript(mb, "copy users (id, id) from '"+path+"'", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrDuplicateColumn)
+
+	_, err = ExecuteScript(mb, "copy users from '"+filepath.Join(dir, "missing.csv")+"'", ScriptOptions{})
+	assert.True(t, os.IsNotExist(err))
+}
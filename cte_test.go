@@ -0,0 +1,95 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommonTableExpression(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table orders (id int, customer text, total int);"+
+		"create table big (id int);"+
+		"insert into orders values (1, 'ann', 50);"+
+		"insert into orders values (2, 'bob', 5);"+
+		"insert into orders values (3, 'ann', 20);"+
+		"insert into big values (2)", ScriptOptions{})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		query string
+		rows  [][]Cell
+	}{
+		{
+			// The name hides the table called big.
+			"with big as (select id, total from orders where total > 10) select id from big order by id",
+			[][]Cell{{intCell(1)}, {intCell(3)}},
+		},
+		{
+			// Each sees those before it, and may be read more than once.
+			`with spent as (select customer, sum(total) as amount from orders group by customer),
+top as (select customer from spent where amount > 10)
+select top.customer, s.amount from top join spent as s on s.customer = top.customer`,
+			[][]Cell{{MemoryCell("ann"), intCell(70)}},
+		},
+		{
+			"select id from orders where id in (with small as (select id from orders where total < 10) select id from small)",
+			[][]Cell{{intCell(2)}},
+		},
+		{
+			"select x.id from (with o as (select id from orders) select id from o where id > 2) as x",
+			[][]Cell{{intCell(3)}},
+		},
+	}
+	for _, test := range tests {
+		results, err := ExecuteScript(mb, test.query, ScriptOptions{})
+		assert.Nil(t, err, test.query)
+		if err == nil {
+			assert.Equal(t, test.rows, results[0].Results.Rows, test.query)
+		}
+	}
+
+	// Inserting reads through the WITH clause too.
+	results, err := ExecuteScript(mb, "insert into big with ann as (select id from orders where customer = 'ann') select id from ann; select count(*) from big", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), results[1].Results.Rows[0][0].AsInt())
+
+	for _, test := range []struct {
+		query string
+		err   error
+	}{
+		{"with a as (select id from orders), a as (select id from orders) select id from a", ErrDuplicateAlias},
+		{"with a as (select nope from orders) select id from orders", ErrColumnDoesNotExist},
+		{"with a as (select id from b), b as (select id from orders) select id from a", ErrTableDoesNotExist},
+	} {
+		_, err := ExecuteScript(mb, test.query, ScriptOptions{})
+		assert.ErrorIs(t, err, test.err, test.query)
+	}
+}
+
+func TestParse_commonTableExpression(t *testing.T) {
+	ast, err := Parse("with a as (select x from t), b as (select x from a) select b.x from b join a on a.x = b.x")
+	assert.Nil(t, err)
+
+	slct := ast.Statements[0].SelectStatement
+	assert.Len(t, slct.With, 2)
+	assert.Equal(t, "b", slct.From.Value)
+	assert.Same(t, slct.With[1].Select, slct.FromSelect)
+	assert.Same(t, slct.With[0].Select, slct.Join[0].Select)
+	assert.Same(t, slct.With[0].Select, slct.With[1].Select.FromSelect)
+	assert.Equal(t, `WITH a AS (
+  SELECT x
+  FROM t
+), b AS (
+  SELECT x
+  FROM a
+)
+SELECT b.x
+FROM b
+JOIN a ON a.x = b.x`, Format(ast.Statements[0]))
+
+	_, err = Parse("with a (select x from t) select x from a")
+	assert.EqualError(t, err, "Expected AS, got ( at 0:7")
+	_, err = Parse("with a as (select x from t) with b as (select x from a) select x from b")
+	assert.EqualError(t, err, "Expected SELECT, got with at 0:28")
+}
@@ -0,0 +1,218 @@
+package gosql
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Rows is a cursor over the result of a query. Next moves it to each row
+// in turn, and rows are only produced as Next asks for them. Scan and Row
+// read the row it is on. Once Next returns false, Err tells whether the
+// rows ran out or the query failed partway.
+type Rows struct {
+	columns []ResultColumn
+	// next produces the following row, or false once there are none.
+	next func() ([]Cell, bool, error)
+	row  []Cell
+	err  error
+}
+
+func (r *Rows) Columns() []ResultColumn {
+	return r.columns
+}
+
+// Next moves to the following row and reports whether there is one. It
+// closes the cursor when there is not.
+func (r *Rows) Next() bool {
+	if r.next == nil {
+		return false
+	}
+
+	row, ok, err := r.next()
+	if err != nil || !ok {
+		r.err = err
+		r.Close()
+		return false
+	}
+	r.row = row
+	return true
+}
+
+// Row returns the cells of the row the cursor is on.
+func (r *Rows) Row() []Cell {
+	return r.row
+}
+
+func (r *Rows) Err() error {
+	return r.err
+}
+
+// Close stops the cursor before it runs out of rows. Closing it again
+// does nothing.
+func (r *Rows) Close() error {
+	r.next = nil
+	r.row = nil
+	return nil
+}
+
+// Scan copies the cells of the row the cursor is on into dest, which
+// holds one pointer per column. *Cell and *interface{} take any value,
+// and the latter gets nil for NULL. *int64 and *int take integers,
+// *float64 takes any number, *bool and *time.Time take booleans and dates
+// or timestamps, *[]byte takes binary strings, and *string takes any value
+// as text. Those cannot hold NULL.
+func (r *Rows) Scan(dest ...interface{}) error {
+	if r.row == nil {
+		return fmt.Errorf("%w: no row", ErrInvalidScan)
+	}
+	if len(dest) != len(r.row) {
+		return fmt.Errorf("%w: expected %d destinations, got %d", ErrInvalidScan, len(r.row), len(dest))
+	}
+
+	for i, d := range dest {
+		if err := scanCell(d, r.row[i], r.columns[i].Type); err != nil {
+			return fmt.Errorf("%w: column %s %s", ErrInvalidScan, r.columns[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// All reads the rows the cursor has left into Results.
+func (r *Rows) All() (*Results, error) {
+	results := &Results{Columns: r.columns, Rows: [][]Cell{}}
+	for r.Next() {
+		results.Rows = append(results.Rows, r.row)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return results, nil
+}
+
+// Cursor returns a cursor over the rows of r.
+func (r *Results) Cursor() *Rows {
+	i := 0
+	return &Rows{
+		columns: r.Columns,
+		next: func() ([]Cell, bool, error) {
+			if i == len(r.Rows) {
+				return nil, false, nil
+			}
+			i++
+			return r.Rows[i-1], true, nil
+		},
+	}
+}
+
+// Select returns the columns of r called cols, in that order, as new
+// results sharing the cells of r. It fails with ErrColumnDoesNotExist for
+// a name no column has and ErrAmbiguousColumn for one that more than one
+// does.
+func (r *Results) Select(cols ...string) (*Results, error) {
+	indexes := make([]int, len(cols))
+	selected := &Results{Columns: make([]ResultColumn, len(cols)), Rows: make([][]Cell, len(r.Rows))}
+	for i, name := range cols {
+		indexes[i] = -1
+		for j, col := range r.Columns {
+			if col.Name != name {
+				continue
+			}
+			if indexes[i] != -1 {
+				return nil, fmt.Errorf("%w: %s", ErrAmbiguousColumn, name)
+			}
+			indexes[i] = j
+		}
+		if indexes[i] == -1 {
+			return nil, fmt.Errorf("%w: %s", ErrColumnDoesNotExist, name)
+		}
+		selected.Columns[i] = r.Columns[indexes[i]]
+	}
+
+	for i, row := range r.Rows {
+		selected.Rows[i] = make([]Cell, len(indexes))
+		for j, index := range indexes {
+			selected.Rows[i][j] = row[index]
+		}
+	}
+	return selected, nil
+}
+
+// scanCell stores cell, a value of type ct, in dest. Its errors finish a
+// sentence about the column.
+func scanCell(dest interface{}, cell Cell, ct ColumnType) error {
+	switch d := dest.(type) {
+	case *Cell:
+		*d = cell
+		return nil
+	case *interface{}:
+		*d = scanValue(cell, ct)
+		return nil
+	}
+
+	if cell.IsNull() {
+		return errors.New("is NULL")
+	}
+	mismatch := fmt.Errorf("of type %s cannot be stored in %T", ct, dest)
+	switch d := dest.(type) {
+	case *int64:
+		if !isInteger(ct) {
+			return mismatch
+		}
+		*d = cell.AsInt()
+	case *int:
+		if !isInteger(ct) {
+			return mismatch
+		}
+		*d = int(cell.AsInt())
+	case *float64:
+		switch {
+		case ct == FloatType:
+			*d = cell.AsFloat()
+		case isInteger(ct):
+			*d = float64(cell.AsInt())
+		default:
+			return mismatch
+		}
+	case *bool:
+		if ct != BoolType {
+			return mismatch
+		}
+		*d = cell.AsBool()
+	case *time.Time:
+		if ct != DateType && ct != TimestampType {
+			return mismatch
+		}
+		*d = cell.AsTime()
+	case *[]byte:
+		if ct != ByteaType {
+			return mismatch
+		}
+		*d = []byte(cell.AsText())
+	case *string:
+		*d = copyText(cell, ct)
+	default:
+		return fmt.Errorf("cannot be stored in %T", dest)
+	}
+	return nil
+}
+
+// scanValue is the Go value of cell, a value of type ct, or nil for NULL.
+func scanValue(cell Cell, ct ColumnType) interface{} {
+	if cell.IsNull() {
+		return nil
+	}
+	switch ct {
+	case IntType, BigIntType:
+		return cell.AsInt()
+	case FloatType:
+		return cell.AsFloat()
+	case BoolType:
+		return cell.AsBool()
+	case DateType, TimestampType:
+		return cell.AsTime()
+	case ByteaType:
+		return []byte(cell.AsText())
+	}
+	return cell.AsText()
+}
@@ -0,0 +1,127 @@
+package gosql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func query(t *testing.T, mb *MemoryBackend, source string) *Rows {
+	ast, err := Parse(source)
+	assert.Nil(t, err)
+	rows, err := mb.Query(context.Background(), ast.Statements[0].SelectStatement)
+	assert.Nil(t, err)
+	return rows
+}
+
+func TestRows(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table users (id int, name text, joined date, score float); insert into users values (1, 'alice', date '2024-01-02', 1.5), (2, 'bob', null, 2), (3, 'carol', null, 0)", ScriptOptions{})
+	assert.Nil(t, err)
+
+	rows := query(t, mb, "select id, name, joined, score from users order by id")
+	assert.Equal(t, []ResultColumn{{IntType, "id"}, {TextType, "name"}, {DateType, "joined"}, {FloatType, "score"}}, rows.Columns())
+
+	var (
+		id     int64
+		name   string
+		joined interface{}
+		score  float64
+	)
+	assert.True(t, rows.Next())
+	assert.Nil(t, rows.Scan(&id, &name, &joined, &score))
+	assert.Equal(t, int64(1), id)
+	assert.Equal(t, "alice", name)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), joined)
+	assert.Equal(t, 1.5, score)
+
+	assert.True(t, rows.Next())
+	assert.Nil(t, rows.Scan(&id, &name, &joined, &score))
+	assert.Nil(t, joined)
+
+	// The rest are left unread.
+	assert.Nil(t, rows.Close())
+	assert.False(t, rows.Next())
+	assert.Nil(t, rows.Err())
+
+	results, err := query(t, mb, "select distinct score > 0 from users order by id offset 1").All()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.False(t, results.Rows[0][0].AsBool())
+}
+
+func TestRows_lazy(t *testing.T) {
+	mb := NewMemoryBackend()
+	numbers(t, mb, 3)
+
+	// Rows past the LIMIT are never worked out.
+	results, err := query(t, mb, "select 6 / (3 - i) from n order by i limit 2").All()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(6), results.Rows[1][0].AsInt())
+
+	rows := query(t, mb, "select 6 / (3 - i) from n order by i")
+	assert.True(t, rows.Next())
+	assert.True(t, rows.Next())
+	assert.False(t, rows.Next())
+	assert.Equal(t, ErrDivisionByZero, rows.Err())
+}
+
+func TestRows_Scan(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table t (i int, s text, b boolean); insert into t values (1, null, true)", ScriptOptions{})
+	assert.Nil(t, err)
+
+	rows := query(t, mb, "select i, s, b from t")
+	var (
+		i    int
+		f    float64
+		s    string
+		b    bool
+		cell Cell
+	)
+	assert.ErrorIs(t, rows.Scan(&i, &cell, &b), ErrInvalidScan)
+	assert.True(t, rows.Next())
+	assert.Nil(t, rows.Scan(&f, &cell, &s))
+	assert.Equal(t, 1.0, f)
+	assert.True(t, cell.IsNull())
+	assert.Equal(t, "t", s)
+
+	for _, dest := range [][]interface{}{
+		{&i, &cell},
+		{&i, &s, &b},
+		{&b, &cell, &b},
+		{&i, &cell, &i},
+		{i, &cell, &b},
+	} {
+		assert.ErrorIs(t, rows.Scan(dest...), ErrInvalidScan)
+	}
+}
+
+func TestResults_Select(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table users (id int, name text, admin bool); insert into users values (1, 'alice', true), (2, 'bob', false)", ScriptOptions{})
+	assert.Nil(t, err)
+	results, err := query(t, mb, "select id, name, admin from users order by id").All()
+	assert.Nil(t, err)
+
+	selected, err := results.Select("admin", "id")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{BoolType, "admin"}, {IntType, "id"}}, selected.Columns)
+	assert.Equal(t, [][]Cell{{boolCell(true), intCell(1)}, {boolCell(false), intCell(2)}}, selected.Rows)
+	assert.Equal(t, 3, len(results.Columns))
+
+	selected, err = results.Select("name")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{MemoryCell("alice")}, {MemoryCell("bob")}}, selected.Rows)
+
+	_, err = results.Select("id", "email")
+	assert.ErrorIs(t, err, ErrColumnDoesNotExist)
+	assert.Contains(t, err.Error(), "email")
+
+	joined, err := query(t, mb, "select a.id, b.id from users a join users b on a.id = b.id").All()
+	assert.Nil(t, err)
+	_, err = joined.Select("id")
+	assert.ErrorIs(t, err, ErrAmbiguousColumn)
+}
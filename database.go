@@ -0,0 +1,265 @@
+package gosql
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// defaultDatabase is the database sessions start in. Its tables and views
+// are stored under their own names, while those of the databases CREATE
+// DATABASE adds are stored qualified by their database, as in shop.orders.
+const defaultDatabase = "main"
+
+func (mb *MemoryBackend) CreateDatabase(ctx context.Context, crt *CreateDatabaseStatement) error {
+	return mb.session.CreateDatabase(ctx, crt)
+}
+
+func (mb *MemoryBackend) DropDatabase(ctx context.Context, drp *DropDatabaseStatement) error {
+	return mb.session.DropDatabase(ctx, drp)
+}
+
+func (s *Session) CreateDatabase(ctx context.Context, crt *CreateDatabaseStatement) error {
+	return s.alter(ctx, func(tx *transaction) error {
+		if crt.IfNotExists && s.mb.hasDatabase(crt.Name.Value) {
+			warn(ctx, "database %s already exists, skipping", crt.Name.Value)
+		}
+		return s.mb.createDatabase(tx, crt)
+	})
+}
+
+func (s *Session) DropDatabase(ctx context.Context, drp *DropDatabaseStatement) error {
+	err := s.alter(ctx, func(tx *transaction) error {
+		if drp.IfExists && !s.mb.hasDatabase(drp.Name.Value) {
+			warn(ctx, "database %s does not exist, skipping", drp.Name.Value)
+		}
+		return s.mb.dropDatabase(tx, drp)
+	})
+	// A session left without its database goes back to the default one.
+	if err == nil && s.database == drp.Name.Value {
+		s.database = defaultDatabase
+	}
+	return err
+}
+
+// hasDatabase reports whether a database is called name. The caller holds
+// mb.mu.
+func (mb *MemoryBackend) hasDatabase(name string) bool {
+	return name == defaultDatabase || mb.databases[name]
+}
+
+// databaseOf returns the database of the table or view stored as name.
+func databaseOf(name string) string {
+	if database, _, ok := strings.Cut(name, "."); ok {
+		return database
+	}
+	return defaultDatabase
+}
+
+func (mb *MemoryBackend) createDatabase(tx *transaction, crt *CreateDatabaseStatement) error {
+	name := crt.Name.Value
+	// The schemas are taken too, since their names qualify tables the
+	// same way.
+	if mb.hasDatabase(name) || name == "public" || name == "information_schema" {
+		if crt.IfNotExists {
+			return nil
+		}
+		return ErrDatabaseAlreadyExists
+	}
+
+	mb.databases[name] = true
+	tx.undo = append(tx.undo, func() {
+		delete(mb.databases, name)
+	})
+	return nil
+}
+
+// dropDatabase drops the database drp names with its tables and views.
+// It fails with ErrDependentObjects when a view or foreign key of another
+// database uses them.
+func (mb *MemoryBackend) dropDatabase(tx *transaction, drp *DropDatabaseStatement) error {
+	name := drp.Name.Value
+	if name == defaultDatabase {
+		return ErrDropDefaultDatabase
+	}
+	if !mb.databases[name] {
+		if drp.IfExists {
+			return nil
+		}
+		return ErrDatabaseDoesNotExist
+	}
+
+	var objects []string
+	for object := range mb.tables {
+		if databaseOf(object) == name {
+			objects = append(objects, object)
+		}
+	}
+	for object := range mb.views {
+		if databaseOf(object) == name {
+			objects = append(objects, object)
+		}
+	}
+	if err := mb.dropObjects(tx, objects); err != nil {
+		return err
+	}
+
+	delete(mb.databases, name)
+	tx.undo = append(tx.undo, func() {
+		mb.databases[name] = true
+	})
+	return nil
+}
+
+// dropObjects drops the tables and views stored as objects. Each is
+// dropped once nothing depends on it any more, so those the others use go
+// after them. Those still left when a round drops none are used from
+// elsewhere, and make it fail with ErrDependentObjects.
+func (mb *MemoryBackend) dropObjects(tx *transaction, objects []string) error {
+	sort.Strings(objects)
+	for len(objects) > 0 {
+		var left []string
+		for _, object := range objects {
+			token := &Token{Value: object, Kind: IdentifierKind}
+			var err error
+			if _, ok := mb.views[object]; ok {
+				err = mb.dropView(tx, &DropViewStatement{Name: token})
+			} else {
+				err = mb.dropTable(tx, &DropTableStatement{Name: token})
+			}
+			if errors.Is(err, ErrDependentObjects) {
+				left = append(left, object)
+			} else if err != nil {
+				return err
+			}
+		}
+		if len(left) == len(objects) {
+			return ErrDependentObjects
+		}
+		objects = left
+	}
+	return nil
+}
+
+// qualifyNames rewrites the names of the tables and views stmt uses to
+// those they are stored under, for a script running in database with the
+// search path path. A name without a database is looked for along path,
+// in database for public and among the information_schema views for
+// information_schema, and is otherwise taken to be in database, as the
+// names of new tables and views are. Names qualified by the default
+// database lose it. A query reading from another database than the
+// default without an alias gets the bare name as one, so that its columns
+// can still be qualified with that.
+//
+// The temporary tables and views of the session, in the schema temp, come
+// before all others and are named pg_temp.name explicitly. Temporary
+// tables are created there, as are views reading from them.
+func qualifyNames(stmt *Statement, schema Schema, database string, path []string, temp string) {
+	qualify := func(name *Token, search bool) *Token {
+		if name == nil {
+			return nil
+		}
+		if db, rest, ok := strings.Cut(name.Value, "."); ok {
+			switch {
+			case db == defaultDatabase:
+				return &Token{Value: rest, Kind: IdentifierKind, Loc: name.Loc}
+			case db == "pg_temp" && temp != "":
+				return &Token{Value: temp + "." + rest, Kind: IdentifierKind, Loc: name.Loc}
+			}
+			return name
+		}
+
+		if search && temp != "" {
+			if _, ok := schema[temp+"."+name.Value]; ok {
+				return &Token{Value: temp + "." + name.Value, Kind: IdentifierKind, Loc: name.Loc}
+			}
+		}
+		stored := name.Value
+		if database != defaultDatabase {
+			stored = database + "." + name.Value
+		}
+		if search {
+			for _, s := range path {
+				if s == "public" {
+					if _, ok := schema[stored]; ok {
+						break
+					}
+				} else if _, ok := informationSchema[s+"."+name.Value]; ok {
+					stored = s + "." + name.Value
+					break
+				}
+			}
+		}
+		if stored == name.Value {
+			return name
+		}
+		return &Token{Value: stored, Kind: IdentifierKind, Loc: name.Loc}
+	}
+	aliased := func(name, as *Token) (*Token, *Token) {
+		name = qualify(name, true)
+		if db, rest, ok := strings.Cut(name.Value, "."); ok && as == nil && db != "information_schema" {
+			as = &Token{Value: rest, Kind: IdentifierKind, Loc: name.Loc}
+		}
+		return name, as
+	}
+
+	Inspect(stmt, func(node Node) bool {
+		switch n := node.(type) {
+		case *SelectStatement:
+			if n.From != nil && n.FromSelect == nil {
+				n.From, n.FromAs = aliased(n.From, n.FromAs)
+			}
+		case *JoinClause:
+			if n.Select == nil {
+				n.Table, n.As = aliased(n.Table, n.As)
+			}
+		case *InsertStatement:
+			n.Table = qualify(n.Table, true)
+		case *UpdateStatement:
+			n.Table = qualify(n.Table, true)
+		case *DeleteStatement:
+			n.From = qualify(n.From, true)
+		case *CreateTableStatement:
+			if n.Temporary && temp != "" && !strings.Contains(n.Name.Value, ".") {
+				n.Name = &Token{Value: temp + "." + n.Name.Value, Kind: IdentifierKind, Loc: n.Name.Loc}
+			} else {
+				n.Name = qualify(n.Name, false)
+			}
+		case *ColumnConstraint:
+			if n.References != nil {
+				n.References.Table = qualify(n.References.Table, true)
+			}
+		case *DropTableStatement:
+			n.Name = qualify(n.Name, true)
+		case *AlterTableStatement:
+			n.Table = qualify(n.Table, true)
+		case *CreateIndexStatement:
+			n.Table = qualify(n.Table, true)
+		case *CopyStatement:
+			n.Table = qualify(n.Table, true)
+		case *DescribeStatement:
+			n.Table = qualify(n.Table, true)
+		case *CreateViewStatement:
+			n.Name = qualify(n.Name, false)
+		case *DropViewStatement:
+			n.Name = qualify(n.Name, true)
+		case *GrantStatement:
+			n.Table = qualify(n.Table, true)
+		}
+		return true
+	})
+
+	if crv := stmt.CreateViewStatement; crv != nil && temp != "" && !isTemporary(crv.Name.Value) {
+		for _, name := range readTables(crv.Select, nil) {
+			if isTemporary(name) {
+				name := crv.Name.Value
+				if _, rest, ok := strings.Cut(name, "."); ok {
+					name = rest
+				}
+				crv.Name = &Token{Value: temp + "." + name, Kind: IdentifierKind, Loc: crv.Name.Loc}
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package gosql
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBackend_databases(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, `create table orders (id int primary key, total int);
+insert into orders values (1, 10);
+create database shop;
+use shop;
+create table orders (id int primary key, total int, customer int references customers (id));`, ScriptOptions{})
+	assert.ErrorIs(t, err, ErrTableDoesNotExist)
+
+	// Each database has its own tables, which may share names.
+	results, err := ExecuteScript(mb, `create table customers (id int primary key, name text);
+create table orders (id int primary key, total int, customer int references customers (id));
+insert into customers values (7, 'ann');
+insert into orders values (1, 99, 7), (2, 5, 7);
+select orders.total, name from orders join customers on customer = customers.id order by orders.id;
+select total from main.orders;
+show tables;
+show database`, ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(99), results[4].Results.Rows[0][0].AsInt())
+	assert.Equal(t, "ann", results[4].Results.Rows[0][1].AsText())
+	assert.Equal(t, int64(10), results[5].Results.Rows[0][0].AsInt())
+	assert.Equal(t, [][]Cell{{MemoryCell("customers")}, {MemoryCell("orders")}}, results[6].Results.Rows)
+	assert.Equal(t, "shop", results[7].Results.Rows[0][0].AsText())
+
+	// The default database can name them with their database.
+	results, err = ExecuteScript(mb, `use main;
+select sum(total) from shop.orders;
+select table_name from information_schema.tables where table_schema = 'shop' order by table_name;
+show tables`, ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "USE", results[0].Tag)
+	assert.Equal(t, int64(104), results[1].Results.Rows[0][0].AsInt())
+	assert.Len(t, results[2].Results.Rows, 2)
+	assert.Equal(t, [][]Cell{{MemoryCell("orders")}}, results[3].Results.Rows)
+
+	for _, test := range []struct {
+		source string
+		err    error
+	}{
+		{"use nowhere", ErrDatabaseDoesNotExist},
+		{"create table nowhere.t (id int)", ErrDatabaseDoesNotExist},
+		{"create database shop", ErrDatabaseAlreadyExists},
+		{"create database information_schema", ErrDatabaseAlreadyExists},
+		{"drop database main", ErrDropDefaultDatabase},
+		{"drop database nowhere", ErrDatabaseDoesNotExist},
+		{"create view big as select id from shop.orders where total > 50; drop database shop", ErrDependentObjects},
+	} {
+		_, err := ExecuteScript(mb, test.source, ScriptOptions{})
+		assert.ErrorIs(t, err, test.err, test.source)
+	}
+	_, err = ExecuteScript(mb, "create database if not exists shop; drop database if exists nowhere", ScriptOptions{})
+	assert.Nil(t, err)
+
+	// Dropping a database drops its tables, whatever depends on what
+	// within it.
+	_, err = ExecuteScript(mb, `drop view big;
+use shop;
+create view big as select id from orders where total > 50;
+drop database shop;`, ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, mb.Schema(), 1)
+	assert.Contains(t, mb.Schema(), "orders")
+	database, err := mb.Show("database")
+	assert.Nil(t, err)
+	assert.Equal(t, "main", database)
+}
+
+func TestMemoryBackend_databasesBackup(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, `create database shop;
+use shop;
+create table items (id int primary key, name text);
+insert into items values (1, 'pen');
+create view names as select items.name from items`, ScriptOptions{})
+	assert.Nil(t, err)
+
+	var dump bytes.Buffer
+	assert.Nil(t, mb.DumpSQL(context.Background(), &dump))
+	assert.Contains(t, dump.String(), "CREATE DATABASE shop;")
+	assert.Contains(t, dump.String(), "CREATE TABLE shop.items")
+
+	restored := NewMemoryBackend()
+	assert.Nil(t, RestoreSQL(context.Background(), restored, &dump))
+	var snapshot bytes.Buffer
+	assert.Nil(t, mb.WriteSnapshot(context.Background(), &snapshot))
+	loaded, err := LoadSnapshot(&snapshot)
+	assert.Nil(t, err)
+
+	for _, ex := range []Executor{restored, loaded} {
+		results, err := ExecuteScript(ex, "use shop; select * from names", ScriptOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, "pen", results[1].Results.Rows[0][0].AsText())
+	}
+}
+
+func TestDiskBackend_databases(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDiskBackend(dir)
+	assert.Nil(t, err)
+	_, err = ExecuteScript(db, "create database shop; use shop; create table items (id int); insert into items values (1)", ScriptOptions{})
+	assert.Nil(t, err)
+
+	// The log names the tables with their database, so it replays in the
+	// default one.
+	db, err = NewDiskBackend(dir)
+	assert.Nil(t, err)
+	results, err := ExecuteScript(db, "select * from shop.items", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, results[0].Results.Rows, 1)
+	assert.Nil(t, db.Close())
+}
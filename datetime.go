@@ -0,0 +1,166 @@
+package gosql
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	secondsPerDay      = 24 * 60 * 60
+	microsecondsPerDay = secondsPerDay * 1000000
+)
+
+// Dates and timestamps are kept to the years 1 to 9999, which is what
+// ISO 8601 text without an extended year can hold.
+var (
+	minTime = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxTime = time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func isTemporal(ct ColumnType) bool {
+	return ct == DateType || ct == TimestampType
+}
+
+// timeInRange reports whether t can be stored as a date or timestamp.
+func timeInRange(t time.Time) bool {
+	return !t.Before(minTime) && t.Before(maxTime)
+}
+
+// daysInRange reports whether the date days after 1970-01-01 can be
+// stored.
+func daysInRange(days int64) bool {
+	return days >= minTime.Unix()/secondsPerDay && days < maxTime.Unix()/secondsPerDay
+}
+
+// dateCell stores the day t falls on as the number of days since
+// 1970-01-01, in four bytes like an int.
+func dateCell(t time.Time) MemoryCell {
+	y, m, d := t.Date()
+	return intCell(int32(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / secondsPerDay))
+}
+
+// timestampCell stores t as the number of microseconds since 1970-01-01
+// 00:00 UTC, in eight bytes like a bigint.
+func timestampCell(t time.Time) MemoryCell {
+	return bigIntCell(t.Unix()*1000000 + int64(t.Nanosecond()/1000))
+}
+
+// parseTime reads an ISO 8601 date, optionally followed by a time of day
+// and a UTC offset. Times with an offset are converted to UTC and those
+// without are taken to be in UTC.
+func parseTime(s string) (time.Time, bool) {
+	layouts := []string{"2006-01-02"}
+	for _, sep := range []string{" ", "T"} {
+		for _, clock := range []string{"15:04:05", "15:04"} {
+			for _, zone := range []string{"", "Z07:00", "Z07"} {
+				layouts = append(layouts, "2006-01-02"+sep+clock+zone)
+			}
+		}
+	}
+
+	for _, layout := range layouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// FormatDate formats the day of t the way PostgreSQL prints dates.
+func FormatDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// FormatTimestamp formats t the way PostgreSQL prints timestamps, leaving
+// out fractional seconds that are zero.
+func FormatTimestamp(t time.Time) string {
+	return t.Format("2006-01-02 15:04:05.999999")
+}
+
+// truncateTime rounds t down to the start of the given unit, as
+// date_trunc does.
+func truncateTime(unit string, t time.Time) (time.Time, error) {
+	y, m, d := t.Date()
+	switch strings.ToLower(unit) {
+	case "microseconds":
+		return t.Truncate(time.Microsecond), nil
+	case "milliseconds":
+		return t.Truncate(time.Millisecond), nil
+	case "second":
+		return t.Truncate(time.Second), nil
+	case "minute":
+		return t.Truncate(time.Minute), nil
+	case "hour":
+		return t.Truncate(time.Hour), nil
+	case "day":
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), nil
+	case "week":
+		// Weeks start on Monday.
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, time.UTC), nil
+	case "month":
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC), nil
+	case "quarter":
+		return time.Date(y, (m-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC), nil
+	case "year":
+		return time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC), nil
+	case "decade":
+		return time.Date(y-y%10, 1, 1, 0, 0, 0, 0, time.UTC), nil
+	case "century":
+		// Centuries and millennia start with their year 1, so the 21st
+		// century starts in 2001.
+		return time.Date((y-1)/100*100+1, 1, 1, 0, 0, 0, 0, time.UTC), nil
+	case "millennium":
+		return time.Date((y-1)/1000*1000+1, 1, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: unit %q is not supported", ErrInvalidArguments, unit)
+}
+
+// extractField returns a field of t, as EXTRACT and date_part do. Seconds
+// include their fraction.
+func extractField(field string, t time.Time) (float64, error) {
+	y, m, _ := t.Date()
+	seconds := float64(t.Second()) + float64(t.Nanosecond()/1000)/1000000
+	switch strings.ToLower(field) {
+	case "microseconds":
+		return math.Round(seconds * 1000000), nil
+	case "milliseconds":
+		return seconds * 1000, nil
+	case "second":
+		return seconds, nil
+	case "minute":
+		return float64(t.Minute()), nil
+	case "hour":
+		return float64(t.Hour()), nil
+	case "day":
+		return float64(t.Day()), nil
+	case "dow":
+		// Sunday is 0.
+		return float64(t.Weekday()), nil
+	case "isodow":
+		// Sunday is 7.
+		return float64((int(t.Weekday())+6)%7 + 1), nil
+	case "doy":
+		return float64(t.YearDay()), nil
+	case "week":
+		_, week := t.ISOWeek()
+		return float64(week), nil
+	case "month":
+		return float64(m), nil
+	case "quarter":
+		return float64((m-1)/3 + 1), nil
+	case "year":
+		return float64(y), nil
+	case "decade":
+		return float64(y / 10), nil
+	case "century":
+		return float64((y-1)/100 + 1), nil
+	case "millennium":
+		return float64((y-1)/1000 + 1), nil
+	case "epoch":
+		return float64(t.Unix()) + float64(t.Nanosecond()/1000)/1000000, nil
+	}
+	return 0, fmt.Errorf("%w: field %q is not supported", ErrInvalidArguments, field)
+}
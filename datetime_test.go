@@ -0,0 +1,105 @@
+package gosql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		text   string
+		result time.Time
+		ok     bool
+	}{
+		{"2024-02-29", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), true},
+		{"2024-02-29 13:45", time.Date(2024, 2, 29, 13, 45, 0, 0, time.UTC), true},
+		{"2024-02-29T13:45:10.25", time.Date(2024, 2, 29, 13, 45, 10, 250000000, time.UTC), true},
+		{"2024-02-29 23:30:00+02:00", time.Date(2024, 2, 29, 21, 30, 0, 0, time.UTC), true},
+		{"2024-02-29T23:30:00Z", time.Date(2024, 2, 29, 23, 30, 0, 0, time.UTC), true},
+		{"2023-02-29", time.Time{}, false},
+		{"2024-2-1", time.Time{}, false},
+		{"yesterday", time.Time{}, false},
+	}
+	for _, test := range tests {
+		result, ok := parseTime(test.text)
+		assert.Equal(t, test.ok, ok, test.text)
+		assert.Equal(t, test.result, result, test.text)
+	}
+}
+
+func TestTimeCells(t *testing.T) {
+	before := time.Date(1969, 12, 31, 23, 59, 59, 500000000, time.UTC)
+	assert.Equal(t, before, timestampCell(before).AsTime())
+	assert.Equal(t, time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC), dateCell(before).AsTime())
+	assert.Equal(t, int64(-1), dateCell(before).AsInt())
+
+	assert.Equal(t, "1969-12-31 23:59:59.5", FormatTimestamp(before))
+	assert.Equal(t, "2024-01-02 03:04:05", FormatTimestamp(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)))
+	assert.Equal(t, "0001-01-01", FormatDate(minTime))
+
+	assert.True(t, daysInRange(0))
+	assert.False(t, daysInRange(maxTime.Unix()/secondsPerDay))
+}
+
+func TestTruncateTime(t *testing.T) {
+	// A Thursday.
+	when := time.Date(2024, 8, 15, 13, 45, 30, 123456000, time.UTC)
+	tests := []struct {
+		unit   string
+		result time.Time
+	}{
+		{"milliseconds", time.Date(2024, 8, 15, 13, 45, 30, 123000000, time.UTC)},
+		{"second", time.Date(2024, 8, 15, 13, 45, 30, 0, time.UTC)},
+		{"HOUR", time.Date(2024, 8, 15, 13, 0, 0, 0, time.UTC)},
+		{"day", time.Date(2024, 8, 15, 0, 0, 0, 0, time.UTC)},
+		{"week", time.Date(2024, 8, 12, 0, 0, 0, 0, time.UTC)},
+		{"month", time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)},
+		{"quarter", time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"year", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"decade", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"century", time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		result, err := truncateTime(test.unit, when)
+		assert.Nil(t, err, test.unit)
+		assert.Equal(t, test.result, result, test.unit)
+	}
+
+	_, err := truncateTime("fortnight", when)
+	assert.ErrorIs(t, err, ErrInvalidArguments)
+}
+
+func TestExtractField(t *testing.T) {
+	// A Sunday.
+	when := time.Date(2000, 12, 31, 6, 7, 8, 500000000, time.UTC)
+	tests := []struct {
+		field  string
+		result float64
+	}{
+		{"second", 8.5},
+		{"milliseconds", 8500},
+		{"minute", 7},
+		{"hour", 6},
+		{"day", 31},
+		{"dow", 0},
+		{"isodow", 7},
+		{"doy", 366},
+		{"week", 52},
+		{"month", 12},
+		{"quarter", 4},
+		{"Year", 2000},
+		{"century", 20},
+		{"millennium", 2},
+		{"epoch", 978242828.5},
+	}
+	for _, test := range tests {
+		result, err := extractField(test.field, when)
+		assert.Nil(t, err, test.field)
+		assert.Equal(t, test.result, result, test.field)
+	}
+
+	_, err := extractField("fortnight", when)
+	assert.ErrorIs(t, err, ErrInvalidArguments)
+}
@@ -0,0 +1,65 @@
+package gosql
+
+// showTables lists the names of the stored tables and views of schema in
+// database in order, along with the temporary ones in temp.
+func showTables(schema Schema, database, temp string) *Results {
+	if database == defaultDatabase {
+		database = "public"
+	}
+	results := &Results{Columns: []ResultColumn{{Type: TextType, Name: "name"}}}
+	for _, t := range schemaTables(schema) {
+		if t.schema == database || t.schema == temp {
+			results.Rows = append(results.Rows, []Cell{MemoryCell(t.name)})
+		}
+	}
+	return results
+}
+
+// describe lists the columns of the table desc names, one row each with
+// its name, declared type, whether it takes NULL, PRI or UNI for primary
+// key and unique columns, and its default.
+func describe(schema Schema, desc *DescribeStatement) (*Results, error) {
+	t, err := schema.source(desc.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	results := &Results{Columns: []ResultColumn{
+		{Type: TextType, Name: "column"},
+		{Type: TextType, Name: "type"},
+		{Type: TextType, Name: "nullable"},
+		{Type: TextType, Name: "key"},
+		{Type: TextType, Name: "default"},
+	}}
+	for _, col := range t.Cols {
+		datatype := col.Datatype.Value
+		if col.Length != nil {
+			datatype += "(" + col.Length.Value + ")"
+		}
+
+		nullable, key := "YES", ""
+		switch {
+		case col.hasConstraint(PrimaryKeyConstraint):
+			nullable, key = "NO", "PRI"
+		case col.hasConstraint(UniqueConstraint):
+			key = "UNI"
+		}
+		if col.hasConstraint(NotNullConstraint) {
+			nullable = "NO"
+		}
+
+		columnDefault := nullCell
+		if exp := col.defaultValue(); exp != nil {
+			columnDefault = MemoryCell(formatSQLExpression(exp))
+		}
+
+		results.Rows = append(results.Rows, []Cell{
+			MemoryCell(col.Name.Value),
+			MemoryCell(datatype),
+			MemoryCell(nullable),
+			MemoryCell(key),
+			columnDefault,
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,40 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribe(t *testing.T) {
+	mb := NewMemoryBackend()
+	results, err := ExecuteScript(mb, `create table users (id int primary key, name varchar(20) not null unique, joined date default date '2024-01-01');
+create table orders (id bigint, total float);
+show tables;
+describe users`, ScriptOptions{})
+	assert.Nil(t, err)
+
+	assert.Equal(t, "SHOW", results[2].Tag)
+	assert.Equal(t, [][]Cell{{MemoryCell("orders")}, {MemoryCell("users")}}, results[2].Results.Rows)
+
+	assert.Equal(t, "DESCRIBE", results[3].Tag)
+	assert.Equal(t, []ResultColumn{
+		{Type: TextType, Name: "column"},
+		{Type: TextType, Name: "type"},
+		{Type: TextType, Name: "nullable"},
+		{Type: TextType, Name: "key"},
+		{Type: TextType, Name: "default"},
+	}, results[3].Results.Columns)
+	assert.Equal(t, [][]Cell{
+		{MemoryCell("id"), MemoryCell("int"), MemoryCell("NO"), MemoryCell("PRI"), nullCell},
+		{MemoryCell("name"), MemoryCell("varchar(20)"), MemoryCell("NO"), MemoryCell("UNI"), nullCell},
+		{MemoryCell("joined"), MemoryCell("date"), MemoryCell("YES"), MemoryCell(""), MemoryCell("'2024-01-01'::DATE")},
+	}, results[3].Results.Rows)
+
+	results, err = ExecuteScript(mb, "desc information_schema.tables", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results[0].Results.Rows))
+
+	_, err = ExecuteScript(mb, "describe nope", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrTableDoesNotExist)
+}
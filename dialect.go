@@ -0,0 +1,105 @@
+package gosql
+
+import (
+	"sort"
+	"strings"
+)
+
+// PlaceholderStyle says which placeholders for parameters a dialect takes.
+type PlaceholderStyle uint
+
+const (
+	// AnyPlaceholders takes both ? and numbered ones like $1.
+	AnyPlaceholders PlaceholderStyle = iota
+	// QuestionPlaceholders takes only ?, as MySQL does.
+	QuestionPlaceholders
+	// DollarPlaceholders takes only numbered ones like $1, as PostgreSQL
+	// does.
+	DollarPlaceholders
+)
+
+// NullsPlacement says where a dialect puts NULLs in an ORDER BY key that
+// does not say NULLS FIRST or NULLS LAST.
+type NullsPlacement uint
+
+const (
+	// NullsAlwaysLast puts them after the other values either way.
+	NullsAlwaysLast NullsPlacement = iota
+	// NullsLargest sorts them as if larger than any value, last going up
+	// and first going down, as PostgreSQL does.
+	NullsLargest
+	// NullsSmallest sorts them as if smaller than any value, first going
+	// up and last going down, as MySQL does.
+	NullsSmallest
+)
+
+// Dialect is a flavor of SQL, named Name, whose keywords, quoting,
+// placeholders and case rules are set by its LexConfig, and which places
+// NULLs in ORDER BY as Nulls says. Statements parsed in any dialect run on
+// the same backends.
+type Dialect struct {
+	Name  string
+	Nulls NullsPlacement
+	LexConfig
+}
+
+// Parse parses a script written in d.
+func (d Dialect) Parse(source string) (*Ast, error) {
+	ast, err := ParseWithConfig(source, d.LexConfig)
+	if err != nil {
+		return nil, err
+	}
+	d.placeNulls(ast)
+	return ast, nil
+}
+
+// placeNulls sets where each ORDER BY key of ast that does not say puts
+// NULLs, when d places them other than the backends do by default.
+func (d Dialect) placeNulls(ast *Ast) {
+	if d.Nulls == NullsAlwaysLast {
+		return
+	}
+	for _, stmt := range ast.Statements {
+		Inspect(stmt, func(n Node) bool {
+			if key, ok := n.(*OrderByClause); ok && key.Nulls == DefaultNulls {
+				if key.Desc == (d.Nulls == NullsLargest) {
+					key.Nulls = NullsFirst
+				}
+			}
+			return true
+		})
+	}
+}
+
+var dialects = map[string]func() Dialect{
+	"postgres": func() Dialect {
+		cfg := DefaultLexConfig()
+		cfg.Placeholders = DollarPlaceholders
+		return Dialect{Name: "postgres", Nulls: NullsLargest, LexConfig: cfg}
+	},
+	"mysql": func() Dialect {
+		cfg := DefaultLexConfig()
+		cfg.Backticks = true
+		cfg.Placeholders = QuestionPlaceholders
+		return Dialect{Name: "mysql", Nulls: NullsSmallest, LexConfig: cfg}
+	},
+}
+
+// LookupDialect returns the preset dialect called name, ignoring case.
+func LookupDialect(name string) (Dialect, bool) {
+	preset, ok := dialects[strings.ToLower(name)]
+	if !ok {
+		return Dialect{}, false
+	}
+	return preset(), true
+}
+
+// DialectNames returns the names of the preset dialects in order.
+func DialectNames() []string {
+	var names []string
+	for name := range dialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
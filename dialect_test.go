@@ -0,0 +1,97 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupDialect(t *testing.T) {
+	d, ok := LookupDialect("MySQL")
+	assert.True(t, ok)
+	assert.Equal(t, "mysql", d.Name)
+	assert.True(t, d.Backticks)
+
+	_, ok = LookupDialect("oracle")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"mysql", "postgres"}, DialectNames())
+}
+
+func TestDialect(t *testing.T) {
+	mysql, _ := LookupDialect("mysql")
+	postgres, _ := LookupDialect("postgres")
+
+	tests := []struct {
+		dialect Dialect
+		source  string
+		ok      bool
+	}{
+		{mysql, "select `name` from t where id = ?", true},
+		{mysql, "select name from t where id = $1", false},
+		{postgres, `select "name" from t where id = $1`, true},
+		{postgres, "select name from t where id = ?", false},
+		{postgres, "select `name` from t", false},
+	}
+	for _, test := range tests {
+		_, err := test.dialect.Parse(test.source)
+		assert.Equal(t, test.ok, err == nil, test.source)
+	}
+
+	mysql.CaseSensitive = true
+	ast, err := mysql.Parse("select Name from Users")
+	assert.Nil(t, err)
+	assert.Equal(t, "Users", ast.Statements[0].SelectStatement.From.Value)
+
+	mb := NewMemoryBackend()
+	results, err := ExecuteScript(mb, "create table t (id int);"+
+		"insert into t values (1);"+
+		"select `id` from t", ScriptOptions{Dialect: &mysql})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(1)}}, results[2].Results.Rows)
+}
+
+func TestDialect_nulls(t *testing.T) {
+	mysql, _ := LookupDialect("mysql")
+	postgres, _ := LookupDialect("postgres")
+
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table t (id int, n int);"+
+		"insert into t values (1, 2), (2, null), (3, 1)", ScriptOptions{})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		dialect *Dialect
+		source  string
+		ids     []int64
+	}{
+		// Without a dialect NULLs go last either way.
+		{nil, "select id from t order by n", []int64{3, 1, 2}},
+		{nil, "select id from t order by n desc", []int64{1, 3, 2}},
+		// PostgreSQL sorts them as larger than any value.
+		{&postgres, "select id from t order by n", []int64{3, 1, 2}},
+		{&postgres, "select id from t order by n desc", []int64{2, 1, 3}},
+		{&postgres, "select id from t order by n desc nulls last", []int64{1, 3, 2}},
+		// MySQL sorts them as smaller than any value.
+		{&mysql, "select id from t order by n", []int64{2, 3, 1}},
+		{&mysql, "select id from t order by n desc", []int64{1, 3, 2}},
+		{&mysql, "select id from t order by n nulls last", []int64{3, 1, 2}},
+		{&mysql, "select id from t order by n desc nulls first", []int64{2, 1, 3}},
+	}
+	for _, test := range tests {
+		results, err := ExecuteScript(mb, test.source, ScriptOptions{Dialect: test.dialect})
+		if !assert.Nil(t, err, test.source) {
+			continue
+		}
+		var ids []int64
+		for _, row := range results[0].Results.Rows {
+			ids = append(ids, row[0].AsInt())
+		}
+		assert.Equal(t, test.ids, ids, test.source)
+	}
+
+	ast, err := postgres.Parse("select id from t order by n desc, id")
+	assert.Nil(t, err)
+	orderBy := ast.Statements[0].SelectStatement.OrderBy
+	assert.Equal(t, NullsFirst, orderBy[0].Nulls)
+	assert.Equal(t, DefaultNulls, orderBy[1].Nulls)
+}
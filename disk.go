@@ -0,0 +1,680 @@
+package gosql
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	snapshotFile = "snapshot"
+	walFile      = "wal"
+)
+
+// DiskBackend is a MemoryBackend made durable with a write-ahead log in
+// dir. Every statement that changes data is appended to the log and synced
+// before it is applied in memory. Checkpoint folds the log into a snapshot
+// of all tables, and NewDiskBackend recovers by loading the snapshot and
+// replaying the log on top of it. Reads are served from memory. A statement
+// can only be canceled until it is in the log, since replaying the log
+// runs it again. Only the statements run on the backend itself are logged,
+// so the sessions it makes, with NewSession or Authenticate, can read but
+// fail with ErrReadOnlySession to change anything.
+type DiskBackend struct {
+	*MemoryBackend
+	dir string
+	wal *os.File
+	// lsn is the log sequence number of the last record written.
+	lsn uint64
+}
+
+// snapshot is the on-disk form of every table and view as of log record
+// LSN.
+type snapshot struct {
+	LSN       uint64
+	Databases []string
+	Tables    []storedTable
+	Views     []storedView
+}
+
+type storedTable struct {
+	Name        string
+	Columns     []string
+	ColumnTypes []ColumnType
+	Lengths     []int
+	NotNull     []bool
+	PrimaryKey  int
+	Unique      []bool
+	Defaults    []storedDefault
+	// AutoIncrement marks the auto-increment column and Sequence is the
+	// last value its sequence gave out.
+	AutoIncrement []bool
+	Sequence      int64
+	References    []storedReference
+	Checks        []*Expression
+	Rows          [][]storedCell
+	Indexes       []storedIndex
+	// Columnar is set for tables in the column layout.
+	Columnar bool
+	// Collations holds the COLLATE of each column, or "" for the columns
+	// without one.
+	Collations []string
+}
+
+// storedDefault is the DEFAULT of one column. Gob cannot encode the nil
+// entries of the columns without one, so only the others are stored.
+type storedDefault struct {
+	Column  int
+	Default *Expression
+}
+
+// storedReference is the foreign key of one column, stored like the
+// defaults.
+type storedReference struct {
+	Column   int
+	Table    string
+	RefersTo string
+	OnDelete ReferentialAction
+}
+
+type storedView struct {
+	Name   string
+	Select *SelectStatement
+}
+
+type storedIndex struct {
+	Name   string
+	Column int
+}
+
+// storedCell keeps NULL apart from empty text, which gob would otherwise
+// encode the same way.
+type storedCell struct {
+	Null bool
+	Data []byte
+}
+
+// walRecord is one logged statement, the rows of one call to InsertRows,
+// or the note that the statement logged as record Failed failed. On disk
+// each record is framed by its length and a CRC-32 checksum so a torn or
+// corrupt tail can be detected.
+type walRecord struct {
+	LSN       uint64
+	Statement *Statement
+	Rows      *loggedRows
+	Failed    uint64
+	// Time is the time the statement saw, which replaying it sees again.
+	// Records logged before it was kept have none.
+	Time time.Time
+}
+
+// loggedRows are rows added by InsertRows, logged as the cells they are
+// rather than as an INSERT of literals.
+type loggedRows struct {
+	Table   string
+	Columns []string
+	Rows    [][]storedCell
+}
+
+// NewDiskBackend opens the database in dir, creating the directory if it
+// does not exist yet, and recovers any statements logged since the last
+// checkpoint.
+func NewDiskBackend(dir string) (*DiskBackend, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	db := &DiskBackend{MemoryBackend: NewMemoryBackend(), dir: dir}
+	db.readOnlySessions = true
+	if err := db.loadSnapshot(); err != nil {
+		return nil, err
+	}
+
+	wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	db.wal = wal
+
+	if err := db.replay(); err != nil {
+		wal.Close()
+		return nil, err
+	}
+	if err := db.Checkpoint(); err != nil {
+		wal.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Close rolls back any open transaction, checkpoints the database and
+// releases the log file.
+func (db *DiskBackend) Close() error {
+	if db.session.tx != nil {
+		if err := db.Rollback(); err != nil {
+			return err
+		}
+	}
+	err := db.Checkpoint()
+	if closeErr := db.wal.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (db *DiskBackend) loadSnapshot() error {
+	f, err := os.Open(filepath.Join(db.dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	snap, err := readSnapshot(f)
+	if err != nil {
+		return err
+	}
+	db.lsn = snap.LSN
+	db.restoreSnapshot(snap)
+	return nil
+}
+
+// replay applies the logged statements newer than the snapshot. Reading
+// stops at the first incomplete or corrupt record, which is what a crash
+// part way through an append leaves behind. Statements logged as failed
+// left nothing behind and are skipped. Any other statement must succeed
+// again, or recovery fails, except for the last one: a crash may have
+// come between its failure and the note of it.
+func (db *DiskBackend) replay() error {
+	if _, err := db.wal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(db.wal)
+	var records []*walRecord
+	failed := map[uint64]bool{}
+	for {
+		rec, err := readRecord(r)
+		if err != nil {
+			break
+		}
+		if rec.LSN <= db.lsn {
+			continue
+		}
+		if rec.Failed != 0 {
+			failed[rec.Failed] = true
+		}
+		records = append(records, rec)
+	}
+
+	for i, rec := range records {
+		db.lsn = rec.LSN
+		if rec.Failed != 0 || failed[rec.LSN] {
+			continue
+		}
+		ctx := context.Background()
+		if !rec.Time.IsZero() {
+			ctx = withTime(ctx, rec.Time)
+		}
+		var err error
+		if rec.Rows != nil {
+			err = db.applyRows(ctx, rec.Rows)
+		} else {
+			err = db.apply(ctx, rec.Statement)
+		}
+		if err != nil && i < len(records)-1 {
+			return fmt.Errorf("replaying log record %d: %w", rec.LSN, err)
+		}
+	}
+
+	// A transaction that never committed before the crash is undone.
+	if db.session.tx != nil {
+		return db.MemoryBackend.Rollback()
+	}
+	return nil
+}
+
+func readRecord(r io.Reader) (*walRecord, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	checksum := binary.BigEndian.Uint32(header[4:])
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return nil, errors.New("wal record checksum mismatch")
+	}
+
+	var rec walRecord
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (db *DiskBackend) apply(ctx context.Context, stmt *Statement) error {
+	mb := db.MemoryBackend
+	var err error
+	switch stmt.Kind {
+	case CreateTableKind:
+		err = mb.CreateTable(ctx, stmt.CreateTableStatement)
+	case DropTableKind:
+		err = mb.DropTable(ctx, stmt.DropTableStatement)
+	case AlterTableKind:
+		err = mb.AlterTable(ctx, stmt.AlterTableStatement)
+	case CreateIndexKind:
+		err = mb.CreateIndex(ctx, stmt.CreateIndexStatement)
+	case CreateDatabaseKind:
+		err = mb.CreateDatabase(ctx, stmt.CreateDatabaseStatement)
+	case DropDatabaseKind:
+		err = mb.DropDatabase(ctx, stmt.DropDatabaseStatement)
+	case CreateViewKind:
+		err = mb.CreateView(ctx, stmt.CreateViewStatement)
+	case DropViewKind:
+		err = mb.DropView(ctx, stmt.DropViewStatement)
+	case InsertKind:
+		_, _, err = mb.Insert(ctx, stmt.InsertStatement)
+	case UpdateKind:
+		_, _, err = mb.Update(ctx, stmt.UpdateStatement)
+	case DeleteKind:
+		_, _, err = mb.Delete(ctx, stmt.DeleteStatement)
+	case BeginKind:
+		err = mb.session.beginAt(mb.session.statementTime(ctx))
+	case CommitKind:
+		err = mb.Commit()
+	case RollbackKind:
+		err = mb.Rollback()
+	case SavepointKind:
+		err = mb.Savepoint(stmt.SavepointStatement.Name.Value)
+	case RollbackToSavepointKind:
+		err = mb.RollbackToSavepoint(stmt.SavepointStatement.Name.Value)
+	case ReleaseSavepointKind:
+		err = mb.ReleaseSavepoint(stmt.SavepointStatement.Name.Value)
+	}
+	return err
+}
+
+func (db *DiskBackend) applyRows(ctx context.Context, logged *loggedRows) error {
+	rows := make([][]Cell, len(logged.Rows))
+	for r, stored := range logged.Rows {
+		rows[r] = make([]Cell, len(stored))
+		for i, cell := range stored {
+			if cell.Null {
+				rows[r][i] = MemoryCell(nil)
+			} else {
+				rows[r][i] = MemoryCell(append([]byte{}, cell.Data...))
+			}
+		}
+	}
+	_, err := db.MemoryBackend.InsertRows(ctx, logged.Table, logged.Columns, rows)
+	return err
+}
+
+// log appends stmt, which sees the time now, to the write-ahead log and
+// syncs it to disk.
+func (db *DiskBackend) log(stmt *Statement, now time.Time) error {
+	return db.append(&walRecord{Statement: stmt, Time: now})
+}
+
+// append gives rec the next log sequence number, appends it to the
+// write-ahead log and syncs it to disk.
+func (db *DiskBackend) append(rec *walRecord) error {
+	rec.LSN = db.lsn + 1
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(rec); err != nil {
+		return err
+	}
+
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(payload.Len()))
+	binary.BigEndian.PutUint32(header[4:], crc32.ChecksumIEEE(payload.Bytes()))
+
+	if _, err := db.wal.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	if _, err := db.wal.Write(append(header[:], payload.Bytes()...)); err != nil {
+		return err
+	}
+	if err := db.wal.Sync(); err != nil {
+		return err
+	}
+
+	db.lsn++
+	return nil
+}
+
+// logStatement logs stmt unless ctx is already done, and returns the
+// context to run it with, which can no longer be canceled and carries the
+// time logged with it, and the sequence number of its record for check.
+// Statements changing only temporary tables and views are not logged, as
+// they do not outlive the session, and get 0.
+func (db *DiskBackend) logStatement(ctx context.Context, stmt *Statement) (context.Context, uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	temporary, err := db.temporary(stmt)
+	if err != nil {
+		return nil, 0, err
+	}
+	now := db.session.now()
+	var lsn uint64
+	if !temporary {
+		if err := db.log(stmt, now); err != nil {
+			return nil, 0, err
+		}
+		lsn = db.lsn
+	}
+	return withTime(context.WithoutCancel(ctx), now), lsn, nil
+}
+
+// check returns err, the outcome of the statement logged as record lsn,
+// after logging that it failed if it did, so that replaying the log skips
+// it. It does nothing for a statement that was not logged, whose lsn is 0.
+func (db *DiskBackend) check(lsn uint64, err error) error {
+	if err == nil || lsn == 0 {
+		return err
+	}
+	if logErr := db.append(&walRecord{Failed: lsn}); logErr != nil {
+		return errors.Join(err, logErr)
+	}
+	return err
+}
+
+// temporary reports whether stmt changes a temporary table or view. It
+// fails with ErrTemporaryReference for a change to a stored table that
+// reads temporary ones, since replaying it could not.
+func (db *DiskBackend) temporary(stmt *Statement) (bool, error) {
+	var name string
+	switch stmt.Kind {
+	case CreateTableKind:
+		if stmt.CreateTableStatement.Temporary {
+			return true, nil
+		}
+		name = stmt.CreateTableStatement.Name.Value
+	case DropTableKind:
+		name = stmt.DropTableStatement.Name.Value
+	case AlterTableKind:
+		name = stmt.AlterTableStatement.Table.Value
+	case CreateIndexKind:
+		name = stmt.CreateIndexStatement.Table.Value
+	case CreateViewKind:
+		name = stmt.CreateViewStatement.Name.Value
+	case DropViewKind:
+		name = stmt.DropViewStatement.Name.Value
+	case InsertKind:
+		name = stmt.InsertStatement.Table.Value
+	case UpdateKind:
+		name = stmt.UpdateStatement.Table.Value
+	case DeleteKind:
+		name = stmt.DeleteStatement.From.Value
+	}
+	if isTemporary(name) {
+		return true, nil
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, read := range readTables(stmt, db.views) {
+		if isTemporary(read) {
+			return false, ErrTemporaryReference
+		}
+	}
+	return false, nil
+}
+
+// Checkpoint writes a snapshot of every table and view, in the format of
+// WriteSnapshot, and empties the log once the snapshot is safely on disk.
+// The snapshot is renamed into place, so a crash leaves either the old or
+// the new one, and records it already covers are skipped on replay. It
+// cannot run inside a transaction, whose changes must not reach the
+// snapshot before they commit.
+func (db *DiskBackend) Checkpoint() error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if db.session.tx != nil {
+		return ErrTransactionActive
+	}
+
+	release, err := db.lockAllTables(context.Background())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	snap := db.storedSnapshot()
+	snap.LSN = db.lsn
+
+	f, err := os.CreateTemp(db.dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := writeSnapshot(f, snap); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), filepath.Join(db.dir, snapshotFile)); err != nil {
+		return err
+	}
+	// The rename has to reach the disk before the log is emptied, or a
+	// crash could leave the old snapshot and no log to replay over it.
+	if err := syncDir(db.dir); err != nil {
+		return err
+	}
+
+	if err := db.wal.Truncate(0); err != nil {
+		return err
+	}
+	return db.wal.Sync()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (db *DiskBackend) CreateTable(ctx context.Context, crt *CreateTableStatement) error {
+	ctx, lsn, err := db.logStatement(ctx, &Statement{Kind: CreateTableKind, CreateTableStatement: crt})
+	if err != nil {
+		return err
+	}
+	return db.check(lsn, db.MemoryBackend.CreateTable(ctx, crt))
+}
+
+func (db *DiskBackend) CreateIndex(ctx context.Context, crt *CreateIndexStatement) error {
+	ctx, lsn, err := db.logStatement(ctx, &Statement{Kind: CreateIndexKind, CreateIndexStatement: crt})
+	if err != nil {
+		return err
+	}
+	return db.check(lsn, db.MemoryBackend.CreateIndex(ctx, crt))
+}
+
+func (db *DiskBackend) CreateView(ctx context.Context, crt *CreateViewStatement) error {
+	ctx, lsn, err := db.logStatement(ctx, &Statement{Kind: CreateViewKind, CreateViewStatement: crt})
+	if err != nil {
+		return err
+	}
+	return db.check(lsn, db.MemoryBackend.CreateView(ctx, crt))
+}
+
+func (db *DiskBackend) DropView(ctx context.Context, drp *DropViewStatement) error {
+	ctx, lsn, err := db.logStatement(ctx, &Statement{Kind: DropViewKind, DropViewStatement: drp})
+	if err != nil {
+		return err
+	}
+	return db.check(lsn, db.MemoryBackend.DropView(ctx, drp))
+}
+
+func (db *DiskBackend) CreateDatabase(ctx context.Context, crt *CreateDatabaseStatement) error {
+	ctx, lsn, err := db.logStatement(ctx, &Statement{Kind: CreateDatabaseKind, CreateDatabaseStatement: crt})
+	if err != nil {
+		return err
+	}
+	return db.check(lsn, db.MemoryBackend.CreateDatabase(ctx, crt))
+}
+
+func (db *DiskBackend) DropDatabase(ctx context.Context, drp *DropDatabaseStatement) error {
+	ctx, lsn, err := db.logStatement(ctx, &Statement{Kind: DropDatabaseKind, DropDatabaseStatement: drp})
+	if err != nil {
+		return err
+	}
+	return db.check(lsn, db.MemoryBackend.DropDatabase(ctx, drp))
+}
+
+func (db *DiskBackend) DropTable(ctx context.Context, drp *DropTableStatement) error {
+	ctx, lsn, err := db.logStatement(ctx, &Statement{Kind: DropTableKind, DropTableStatement: drp})
+	if err != nil {
+		return err
+	}
+	return db.check(lsn, db.MemoryBackend.DropTable(ctx, drp))
+}
+
+func (db *DiskBackend) AlterTable(ctx context.Context, alt *AlterTableStatement) error {
+	ctx, lsn, err := db.logStatement(ctx, &Statement{Kind: AlterTableKind, AlterTableStatement: alt})
+	if err != nil {
+		return err
+	}
+	return db.check(lsn, db.MemoryBackend.AlterTable(ctx, alt))
+}
+
+func (db *DiskBackend) Insert(ctx context.Context, inst *InsertStatement) (int, *Results, error) {
+	ctx, lsn, err := db.logStatement(ctx, &Statement{Kind: InsertKind, InsertStatement: inst})
+	if err != nil {
+		return 0, nil, err
+	}
+	n, results, err := db.MemoryBackend.Insert(ctx, inst)
+	return n, results, db.check(lsn, err)
+}
+
+// InsertRows logs the rows before adding them, as Insert does with its
+// statement.
+func (db *DiskBackend) InsertRows(ctx context.Context, table string, columns []string, rows [][]Cell) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	now := db.session.now()
+	var lsn uint64
+	if !isTemporary(table) {
+		logged := &loggedRows{Table: table, Columns: columns, Rows: make([][]storedCell, len(rows))}
+		for r, row := range rows {
+			logged.Rows[r] = make([]storedCell, len(row))
+			for i, value := range row {
+				cell, ok := value.(MemoryCell)
+				if !ok {
+					return 0, ErrInvalidDatatype
+				}
+				logged.Rows[r][i] = storedCell{Null: cell.IsNull(), Data: cell}
+			}
+		}
+		if err := db.append(&walRecord{Rows: logged, Time: now}); err != nil {
+			return 0, err
+		}
+		lsn = db.lsn
+	}
+	n, err := db.MemoryBackend.InsertRows(withTime(context.WithoutCancel(ctx), now), table, columns, rows)
+	return n, db.check(lsn, err)
+}
+
+func (db *DiskBackend) Update(ctx context.Context, updt *UpdateStatement) (int, *Results, error) {
+	ctx, lsn, err := db.logStatement(ctx, &Statement{Kind: UpdateKind, UpdateStatement: updt})
+	if err != nil {
+		return 0, nil, err
+	}
+	n, results, err := db.MemoryBackend.Update(ctx, updt)
+	return n, results, db.check(lsn, err)
+}
+
+func (db *DiskBackend) Delete(ctx context.Context, dlt *DeleteStatement) (int, *Results, error) {
+	ctx, lsn, err := db.logStatement(ctx, &Statement{Kind: DeleteKind, DeleteStatement: dlt})
+	if err != nil {
+		return 0, nil, err
+	}
+	n, results, err := db.MemoryBackend.Delete(ctx, dlt)
+	return n, results, db.check(lsn, err)
+}
+
+func (db *DiskBackend) Begin() error {
+	if db.session.tx != nil {
+		return ErrTransactionActive
+	}
+	now := db.clock.Now()
+	if err := db.log(&Statement{Kind: BeginKind}, now); err != nil {
+		return err
+	}
+	return db.check(db.lsn, db.session.beginAt(now))
+}
+
+func (db *DiskBackend) Commit() error {
+	if err := db.log(&Statement{Kind: CommitKind}, time.Time{}); err != nil {
+		return err
+	}
+	return db.check(db.lsn, db.MemoryBackend.Commit())
+}
+
+func (db *DiskBackend) Rollback() error {
+	if err := db.log(&Statement{Kind: RollbackKind}, time.Time{}); err != nil {
+		return err
+	}
+	return db.check(db.lsn, db.MemoryBackend.Rollback())
+}
+
+func (db *DiskBackend) Savepoint(name string) error {
+	if err := db.logSavepoint(SavepointKind, name); err != nil {
+		return err
+	}
+	return db.check(db.lsn, db.MemoryBackend.Savepoint(name))
+}
+
+func (db *DiskBackend) RollbackToSavepoint(name string) error {
+	if err := db.logSavepoint(RollbackToSavepointKind, name); err != nil {
+		return err
+	}
+	return db.check(db.lsn, db.MemoryBackend.RollbackToSavepoint(name))
+}
+
+func (db *DiskBackend) ReleaseSavepoint(name string) error {
+	if err := db.logSavepoint(ReleaseSavepointKind, name); err != nil {
+		return err
+	}
+	return db.check(db.lsn, db.MemoryBackend.ReleaseSavepoint(name))
+}
+
+func (db *DiskBackend) logSavepoint(kind AstKind, name string) error {
+	return db.log(&Statement{
+		Kind:               kind,
+		SavepointStatement: &SavepointStatement{Name: &Token{Value: name, Kind: IdentifierKind}},
+	}, time.Time{})
+}
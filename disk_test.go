@@ -0,0 +1,351 @@
+package gosql
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskBackend(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := NewDiskBackend(dir)
+	assert.Nil(t, err)
+
+	_, err = execute(t, db, "create table users (id int primary key, name varchar(8), admin bool);"+
+		"insert into users values (1, 'alice', true);"+
+		"insert into users values (2, '', false);"+
+		"insert into users (id) values (3);"+
+		"update users set name = 'carol' where id = 3;"+
+		"delete from users where id = 1;"+
+		`create table "a/b" (x int);`+
+		`alter table "a/b" add column y text default 'none';`+
+		`alter table "a/b" rename x to z;`+
+		"create index users_name on users (name);"+
+		"create table gone (x int);"+
+		"drop table gone;"+
+		"create view carols as select id from users where name = 'carol';"+
+		"create table seq (id serial, x int);"+
+		"insert into seq (x) values (1), (2);"+
+		"delete from seq where x = 2")
+	assert.Nil(t, err)
+
+	db, err = NewDiskBackend(dir)
+	assert.Nil(t, err)
+
+	results, err := execute(t, db, "select id, name, admin from users order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Type: IntType, Name: "id"}, {Type: TextType, Name: "name"}, {Type: BoolType, Name: "admin"}}, results.Columns)
+	assert.Equal(t, 2, len(results.Rows))
+	assert.Equal(t, int64(2), results.Rows[0][0].AsInt())
+	assert.False(t, results.Rows[0][1].IsNull())
+	assert.Equal(t, "", results.Rows[0][1].AsText())
+	assert.Equal(t, "carol", results.Rows[1][1].AsText())
+	assert.True(t, results.Rows[1][2].IsNull())
+
+	assert.Equal(t, 2, len(db.tables["users"].indexes))
+	results, err = execute(t, db, "select id from users where name = 'carol'")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+
+	_, err = execute(t, db, "insert into users values (2, 'dup', true)")
+	assert.Equal(t, ErrViolatesPrimaryKey, err)
+	_, err = execute(t, db, "insert into users values (4, 'too long!', true)")
+	assert.ErrorIs(t, err, ErrValueTooLong)
+
+	results, err = execute(t, db, `select * from "a/b"`)
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Type: IntType, Name: "z"}, {Type: TextType, Name: "y"}}, results.Columns)
+
+	// Defaults survive a snapshot too.
+	assert.Nil(t, db.Close())
+	db, err = NewDiskBackend(dir)
+	assert.Nil(t, err)
+	_, err = execute(t, db, `insert into "a/b" (z) values (1)`)
+	assert.Nil(t, err)
+	results, err = execute(t, db, `select y from "a/b"`)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{MemoryCell("none")}}, results.Rows)
+	results, err = execute(t, db, "select id from carols")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(3)}}, results.Rows)
+
+	// So does where the sequence of an auto-increment column got to.
+	results, err = execute(t, db, "insert into seq (x) values (3) returning id")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(3)}}, results.Rows)
+
+	_, err = execute(t, db, "select x from gone")
+	assert.Equal(t, ErrTableDoesNotExist, err)
+
+	_, err = execute(t, db, "create table if not exists users (id int)")
+	assert.Nil(t, err)
+
+	_, err = execute(t, db, "drop table if exists gone")
+	assert.Nil(t, err)
+}
+
+func TestDiskBackend_recovery(t *testing.T) {
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, walFile)
+
+	db, err := NewDiskBackend(dir)
+	assert.Nil(t, err)
+	_, err = execute(t, db, "create table t (id int primary key); insert into t values (1); insert into t values (2)")
+	assert.Nil(t, err)
+
+	// Simulate a crash mid-append by leaving a torn record at the end.
+	logged, err := os.ReadFile(walPath)
+	assert.Nil(t, err)
+	f, err := os.OpenFile(walPath, os.O_APPEND|os.O_WRONLY, 0)
+	assert.Nil(t, err)
+	_, err = f.Write([]byte{0, 0, 1, 0, 9, 9})
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	db, err = NewDiskBackend(dir)
+	assert.Nil(t, err)
+	results, err := execute(t, db, "select count(*) from t")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), results.Rows[0][0].AsInt())
+
+	// Replaying records the snapshot already covers must not apply them
+	// twice.
+	assert.Nil(t, os.WriteFile(walPath, logged, 0o644))
+	db, err = NewDiskBackend(dir)
+	assert.Nil(t, err)
+	results, err = execute(t, db, "select count(*) from t")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), results.Rows[0][0].AsInt())
+
+	// A record whose checksum does not match ends the replay.
+	_, err = execute(t, db, "insert into t values (3); insert into t values (4)")
+	assert.Nil(t, err)
+	logged, err = os.ReadFile(walPath)
+	assert.Nil(t, err)
+	logged[len(logged)-1] ^= 0xff
+	assert.Nil(t, os.WriteFile(walPath, logged, 0o644))
+
+	db, err = NewDiskBackend(dir)
+	assert.Nil(t, err)
+	results, err = execute(t, db, "select id from t order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results.Rows))
+	assert.Equal(t, int64(3), results.Rows[2][0].AsInt())
+
+	assert.Nil(t, db.Close())
+	info, err := os.Stat(walPath)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), info.Size())
+}
+
+func TestDiskBackend_transaction(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := NewDiskBackend(dir)
+	assert.Nil(t, err)
+
+	_, err = execute(t, db, "create table t (id int);"+
+		"begin;"+
+		"insert into t values (1);"+
+		"commit;"+
+		"begin;"+
+		"insert into t values (2);"+
+		"rollback;"+
+		"begin;"+
+		"insert into t values (3);"+
+		"create table u (x int)")
+	assert.Nil(t, err)
+	assert.Equal(t, ErrTransactionActive, db.Checkpoint())
+
+	// Reopening without closing is a crash in the middle of the last
+	// transaction, which recovery undoes.
+	db, err = NewDiskBackend(dir)
+	assert.Nil(t, err)
+
+	results, err := execute(t, db, "select id from t")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, int64(1), results.Rows[0][0].AsInt())
+	_, err = execute(t, db, "select x from u")
+	assert.Equal(t, ErrTableDoesNotExist, err)
+
+	_, err = execute(t, db, "begin; insert into t values (4)")
+	assert.Nil(t, err)
+	assert.Nil(t, db.Close())
+
+	db, err = NewDiskBackend(dir)
+	assert.Nil(t, err)
+	results, err = execute(t, db, "select id from t")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+
+	// Savepoints are logged, so recovery undoes what was rolled back to
+	// one.
+	_, err = execute(t, db, "begin; insert into t values (5); savepoint a; insert into t values (6); rollback to a; commit")
+	assert.Nil(t, err)
+	db, err = NewDiskBackend(dir)
+	assert.Nil(t, err)
+	results, err = execute(t, db, "select id from t order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(1)}, {intCell(5)}}, results.Rows)
+	assert.Nil(t, db.Close())
+}
+
+func TestDiskBackend_copy(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(t.TempDir(), "in.csv")
+	assert.Nil(t, os.WriteFile(in, []byte("1,alice\n2,\n"), 0o600))
+
+	db, err := NewDiskBackend(dir)
+	assert.Nil(t, err)
+	_, err = ExecuteScript(db, "create table users (id int primary key, name text); copy users from '"+in+"'", ScriptOptions{})
+	assert.Nil(t, err)
+
+	// The rows of a COPY are logged, so a crash right after it keeps them.
+	db, err = NewDiskBackend(dir)
+	assert.Nil(t, err)
+	results, err := execute(t, db, "select id, name from users order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(1), MemoryCell("alice")}, {intCell(2), MemoryCell(nil)}}, results.Rows)
+	assert.Nil(t, db.Close())
+}
+
+func TestDiskBackend_clock(t *testing.T) {
+	dir := t.TempDir()
+	clock := &testClock{now: time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)}
+
+	db, err := NewDiskBackend(dir)
+	assert.Nil(t, err)
+	db.SetClock(clock)
+	_, err = execute(t, db, "create table events (id int, at timestamp default now()); insert into events (id) values (1)")
+	assert.Nil(t, err)
+	began := clock.now.Add(time.Minute)
+	clock.now = began
+	assert.Nil(t, db.Begin())
+	clock.now = clock.now.Add(time.Minute)
+	_, err = execute(t, db, "insert into events values (2, now()); alter table events add column seen timestamp default current_timestamp")
+	assert.Nil(t, err)
+	assert.Nil(t, db.Commit())
+
+	// Replaying the log after a crash gives the rows the times they got
+	// then, not the time of the replay.
+	db, err = NewDiskBackend(dir)
+	assert.Nil(t, err)
+	results, err := execute(t, db, "select at, seen from events order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{
+		{timestampCell(began.Add(-time.Minute)), timestampCell(began)},
+		{timestampCell(began), timestampCell(began)},
+	}, results.Rows)
+	assert.Nil(t, db.Close())
+}
+
+func TestDiskBackend_failures(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := NewDiskBackend(dir)
+	assert.Nil(t, err)
+	_, err = execute(t, db, "create table t (id int primary key)")
+	assert.Nil(t, err)
+	for _, source := range []string{
+		"insert into t values (1)",
+		"insert into t values (1)",
+		"begin",
+		"insert into t values (2)",
+		"insert into t values (2)",
+		"insert into t values (3)",
+		"commit",
+		"rollback",
+	} {
+		_, _ = execute(t, db, source)
+	}
+
+	// A statement that failed is noted in the log, and skipped when it is
+	// replayed, even one that would succeed then.
+	_, lsn, err := db.logStatement(context.Background(), &Statement{Kind: InsertKind, InsertStatement: &InsertStatement{
+		Table:  &Token{Kind: IdentifierKind, Value: "t"},
+		Values: [][]*Expression{{{Kind: LiteralKind, Literal: &Token{Kind: NumericKind, Value: "4"}}}},
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, ErrLockTimeout, db.check(lsn, ErrLockTimeout))
+
+	crash(t, db)
+	db, err = NewDiskBackend(dir)
+	assert.Nil(t, err)
+	results, err := execute(t, db, "select id from t order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(1)}, {intCell(2)}, {intCell(3)}}, results.Rows)
+
+	// One that fails only on replay means the log and the tables have
+	// parted ways, and recovery stops rather than lose what follows. The
+	// last record is let go, as a crash may have cut off the note.
+	_, _, err = db.logStatement(context.Background(), &Statement{Kind: InsertKind, InsertStatement: &InsertStatement{
+		Table:  &Token{Kind: IdentifierKind, Value: "t"},
+		Values: [][]*Expression{{{Kind: LiteralKind, Literal: &Token{Kind: NumericKind, Value: "1"}}}},
+	}})
+	assert.Nil(t, err)
+	crash(t, db)
+	db, err = NewDiskBackend(dir)
+	assert.Nil(t, err)
+
+	_, lsn, err = db.logStatement(context.Background(), &Statement{Kind: InsertKind, InsertStatement: &InsertStatement{
+		Table:  &Token{Kind: IdentifierKind, Value: "t"},
+		Values: [][]*Expression{{{Kind: LiteralKind, Literal: &Token{Kind: NumericKind, Value: "1"}}}},
+	}})
+	assert.Nil(t, err)
+	_, err = execute(t, db, "insert into t values (5)")
+	assert.Nil(t, err)
+	crash(t, db)
+	_, err = NewDiskBackend(dir)
+	assert.ErrorIs(t, err, ErrViolatesPrimaryKey)
+	assert.Contains(t, err.Error(), fmt.Sprintf("replaying log record %d", lsn))
+}
+
+func TestDiskBackend_session(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := NewDiskBackend(dir)
+	assert.Nil(t, err)
+	_, err = execute(t, db, "create table t (id int); insert into t values (1)")
+	assert.Nil(t, err)
+
+	// A session reads the tables, but the log would not hold what it
+	// changed, so it changes nothing.
+	s := db.NewSession()
+	results, err := execute(t, s, "select id from t")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(1)}}, results.Rows)
+	for _, source := range []string{
+		"insert into t values (2)",
+		"update t set id = 2",
+		"delete from t",
+		"create table u (x int)",
+		"drop table t",
+	} {
+		_, err = execute(t, s, source)
+		assert.ErrorIs(t, err, ErrReadOnlySession, source)
+	}
+	_, err = s.InsertRows(context.Background(), "t", []string{"id"}, [][]Cell{{intCell(3)}})
+	assert.ErrorIs(t, err, ErrReadOnlySession)
+	assert.Nil(t, s.Close())
+
+	crash(t, db)
+	db, err = NewDiskBackend(dir)
+	assert.Nil(t, err)
+	results, err = execute(t, db, "select id from t")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(1)}}, results.Rows)
+	assert.Nil(t, db.Close())
+}
+
+// crash lets go of the log of db without the checkpoint Close makes, so
+// that opening dir again replays it as after a crash.
+func crash(t *testing.T, db *DiskBackend) {
+	assert.Nil(t, db.wal.Close())
+}
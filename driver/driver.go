@@ -0,0 +1,298 @@
+// Package driver registers gosql with database/sql under the name "gosql".
+// Connections opened with the same data source name share one in-memory
+// database for the life of the process.
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/piaoranyc/gosql"
+)
+
+func init() {
+	sql.Register("gosql", &Driver{})
+}
+
+var (
+	ErrMultipleStatements = gosql.ErrMultipleStatements
+	ErrNamedParameters    = errors.New("gosql: named parameters are not supported")
+)
+
+// statementCacheSize is how many prepared statements the connections of
+// a Driver share, so that queries they prepare again skip parsing.
+const statementCacheSize = 256
+
+type Driver struct {
+	mu        sync.Mutex
+	databases map[string]*gosql.MemoryBackend
+	cache     *gosql.StatementCache
+}
+
+func (d *Driver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.databases == nil {
+		d.databases = map[string]*gosql.MemoryBackend{}
+		d.cache = gosql.NewStatementCache(statementCacheSize)
+	}
+	backend, ok := d.databases[name]
+	if !ok {
+		backend = gosql.NewMemoryBackend()
+		d.databases[name] = backend
+	}
+
+	return &Conn{session: backend.NewSession(), cache: d.cache}, nil
+}
+
+// Conn is a session of the shared backend, so each connection has its own
+// transaction.
+type Conn struct {
+	session *gosql.Session
+	cache   *gosql.StatementCache
+}
+
+func (c *Conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *Conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	prepared, err := c.cache.Prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stmt{session: c.session, stmt: prepared.Statement()}, nil
+}
+
+// Close ends the session, rolling back its transaction and dropping its
+// temporary tables.
+func (c *Conn) Close() error {
+	return c.session.Close()
+}
+
+// Begin starts a transaction on the connection. Other connections keep
+// seeing the database as it was until it commits.
+func (c *Conn) Begin() (driver.Tx, error) {
+	if err := c.session.Begin(); err != nil {
+		return nil, err
+	}
+	return &Tx{session: c.session}, nil
+}
+
+type Tx struct {
+	session *gosql.Session
+}
+
+func (tx *Tx) Commit() error {
+	return tx.session.Commit()
+}
+
+func (tx *Tx) Rollback() error {
+	return tx.session.Rollback()
+}
+
+type Stmt struct {
+	session *gosql.Session
+	stmt    *gosql.Statement
+}
+
+func (s *Stmt) Close() error {
+	return nil
+}
+
+// NumInput returns -1 since ? and $N placeholders may be mixed and reused,
+// so database/sql leaves checking the argument count to Bind.
+func (s *Stmt) NumInput() int {
+	return -1
+}
+
+func (s *Stmt) bind(args []driver.NamedValue) (*gosql.Statement, error) {
+	values := make([]interface{}, len(args))
+	for _, arg := range args {
+		if arg.Name != "" {
+			return nil, ErrNamedParameters
+		}
+		values[arg.Ordinal-1] = arg.Value
+	}
+	return gosql.Bind(s.stmt, values...)
+}
+
+// named turns the arguments of the methods without a context into those
+// of the methods with one.
+func named(args []driver.Value) []driver.NamedValue {
+	nv := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		nv[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return nv
+}
+
+// statementContext limits ctx to the statement_timeout of the session,
+// which returns the context to run a statement with and a function that
+// turns the errors it ends with into ErrStatementTimeout when the timeout
+// is why.
+func (s *Stmt) statementContext(ctx context.Context) (context.Context, func(error) error) {
+	timeout := s.session.StatementTimeout()
+	if timeout == 0 {
+		return ctx, func(err error) error { return err }
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, gosql.ErrStatementTimeout)
+	return ctx, func(err error) error {
+		defer cancel()
+		if errors.Is(err, context.DeadlineExceeded) && context.Cause(ctx) == gosql.ErrStatementTimeout {
+			return gosql.ErrStatementTimeout
+		}
+		return err
+	}
+}
+
+func (s *Stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), named(args))
+}
+
+func (s *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	stmt, err := s.bind(args)
+	if err != nil {
+		return nil, err
+	}
+	ctx, done := s.statementContext(ctx)
+	result, err := s.exec(ctx, stmt)
+	return result, done(err)
+}
+
+// exec runs stmt with ExecuteContext, so that its result has the rows
+// affected and insert id the statement itself reports.
+func (s *Stmt) exec(ctx context.Context, stmt *gosql.Statement) (driver.Result, error) {
+	// Temporary tables are found by the names scripts look up, which the
+	// driver leaves as they are.
+	if stmt.Kind == gosql.CreateTableKind && stmt.CreateTableStatement.Temporary {
+		return nil, gosql.ErrInvalidOperator
+	}
+	r, err := gosql.ExecuteContext(ctx, s.session, stmt)
+	if err != nil {
+		return nil, err
+	}
+	return result{r}, nil
+}
+
+// result is the driver.Result of a statement. Its LastInsertId is the
+// last value the statement took from the sequence of an auto-increment
+// column, or 0 when it took none.
+type result struct {
+	r *gosql.StatementResult
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return r.r.LastInsertID, nil
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return r.r.RowsAffected, nil
+}
+
+// Query runs a SELECT, an INSERT, UPDATE or DELETE with a RETURNING
+// clause, EXPLAIN, SHOW TABLES or DESCRIBE. Other statements produce no
+// rows.
+func (s *Stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), named(args))
+}
+
+func (s *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	stmt, err := s.bind(args)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, done := s.statementContext(ctx)
+	var rows *gosql.Rows
+	var results *gosql.Results
+	switch stmt.Kind {
+	case gosql.SelectKind:
+		rows, err = s.session.Query(ctx, stmt.SelectStatement)
+	case gosql.InsertKind:
+		_, results, err = s.session.Insert(ctx, stmt.InsertStatement)
+	case gosql.UpdateKind:
+		_, results, err = s.session.Update(ctx, stmt.UpdateStatement)
+	case gosql.DeleteKind:
+		_, results, err = s.session.Delete(ctx, stmt.DeleteStatement)
+	case gosql.ExplainKind:
+		results, err = s.session.Explain(ctx, stmt.ExplainStatement)
+	case gosql.ShowTablesKind, gosql.DescribeKind, gosql.ShowKind:
+		var r *gosql.StatementResult
+		if r, err = gosql.ExecuteContext(ctx, s.session, stmt); err == nil {
+			results = r.Results
+		}
+	default:
+		_, err = s.exec(ctx, stmt)
+	}
+	if err != nil {
+		return nil, done(err)
+	}
+	if rows == nil {
+		if results == nil {
+			results = &gosql.Results{}
+		}
+		rows = results.Cursor()
+	}
+
+	return &Rows{rows: rows, done: done}, nil
+}
+
+// Rows reads the result of a query as database/sql asks for each row, so
+// a SELECT is only worked out as far as it is read. The statement timeout
+// runs until the rows are closed.
+type Rows struct {
+	rows *gosql.Rows
+	done func(error) error
+}
+
+func (r *Rows) Columns() []string {
+	columns := make([]string, len(r.rows.Columns()))
+	for i, col := range r.rows.Columns() {
+		columns[i] = col.Name
+	}
+	return columns
+}
+
+func (r *Rows) Close() error {
+	r.rows.Close()
+	return r.done(nil)
+}
+
+func (r *Rows) Next(dest []driver.Value) error {
+	if !r.rows.Next() {
+		if err := r.done(r.rows.Err()); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+
+	columns := r.rows.Columns()
+	for i, cell := range r.rows.Row() {
+		if cell.IsNull() {
+			dest[i] = nil
+			continue
+		}
+		switch columns[i].Type {
+		case gosql.IntType, gosql.BigIntType:
+			dest[i] = cell.AsInt()
+		case gosql.FloatType:
+			dest[i] = cell.AsFloat()
+		case gosql.BoolType:
+			dest[i] = cell.AsBool()
+		case gosql.DateType, gosql.TimestampType:
+			dest[i] = cell.AsTime()
+		case gosql.ByteaType:
+			dest[i] = []byte(cell.AsText())
+		default:
+			dest[i] = cell.AsText()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDriver(t *testing.T) {
+	db, err := sql.Open("gosql", "TestDriver")
+	assert.Nil(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("create table users (id int, name text, admin bool)")
+	assert.Nil(t, err)
+
+	for i, name := range []string{"alice", "bob", "carol"} {
+		res, err := db.Exec("insert into users values (?, ?, ?)", i+1, name, i == 0)
+		assert.Nil(t, err)
+		n, err := res.RowsAffected()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), n)
+	}
+
+	rows, err := db.Query("select id, name, admin from users where id >= $1 order by id desc", 2)
+	assert.Nil(t, err)
+	columns, err := rows.Columns()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "name", "admin"}, columns)
+
+	var ids []int
+	var names []string
+	for rows.Next() {
+		var id int
+		var name string
+		var admin bool
+		assert.Nil(t, rows.Scan(&id, &name, &admin))
+		assert.False(t, admin)
+		ids = append(ids, id)
+		names = append(names, name)
+	}
+	assert.Nil(t, rows.Err())
+	assert.Equal(t, []int{3, 2}, ids)
+	assert.Equal(t, []string{"carol", "bob"}, names)
+
+	res, err := db.Exec("update users set name = ? where admin = ?", "root", true)
+	assert.Nil(t, err)
+	n, err := res.RowsAffected()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+
+	var name string
+	assert.Nil(t, db.QueryRow("select name from users where id = ?", 1).Scan(&name))
+	assert.Equal(t, "root", name)
+
+	var id int
+	assert.Nil(t, db.QueryRow("insert into users (id) values (?) returning id", 4).Scan(&id))
+	assert.Equal(t, 4, id)
+
+	var missing sql.NullString
+	assert.Nil(t, db.QueryRow("select name from users where id = 4").Scan(&missing))
+	assert.False(t, missing.Valid)
+
+	var renamed string
+	assert.Nil(t, db.QueryRow("update users set name = ? where id = ? returning name", "dora", 4).Scan(&renamed))
+	assert.Equal(t, "dora", renamed)
+
+	res, err = db.Exec("delete from users where id > ?", 2)
+	assert.Nil(t, err)
+	n, err = res.RowsAffected()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+
+	res, err = db.Exec("insert into users (id, name) values (?, ?), (?, ?)", 3, "dave", 4, "erin")
+	assert.Nil(t, err)
+	n, err = res.RowsAffected()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+
+	var table string
+	assert.Nil(t, db.QueryRow("show tables").Scan(&table))
+	assert.Equal(t, "users", table)
+
+	_, err = db.Exec("select * from nope")
+	assert.NotNil(t, err)
+
+	_, err = db.Exec("drop table a; drop table b")
+	assert.Equal(t, ErrMultipleStatements, err)
+}
+
+func TestDriver_sharedDatabase(t *testing.T) {
+	a, err := sql.Open("gosql", "TestDriver_sharedDatabase")
+	assert.Nil(t, err)
+	defer a.Close()
+	b, err := sql.Open("gosql", "TestDriver_sharedDatabase")
+	assert.Nil(t, err)
+	defer b.Close()
+
+	_, err = a.Exec("create table t (id int)")
+	assert.Nil(t, err)
+	_, err = b.Exec("insert into t values (1)")
+	assert.Nil(t, err)
+
+	var count int
+	assert.Nil(t, a.QueryRow("select count(*) from t").Scan(&count))
+	assert.Equal(t, 1, count)
+
+	tx, err := a.Begin()
+	assert.Nil(t, err)
+	_, err = tx.Exec("insert into t values (2)")
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Commit())
+
+	assert.Nil(t, a.QueryRow("select count(*) from t").Scan(&count))
+	assert.Equal(t, 2, count)
+
+	tx, err = a.Begin()
+	assert.Nil(t, err)
+	_, err = tx.Exec("delete from t")
+	assert.Nil(t, err)
+	// Other connections see the rows until the transaction commits.
+	assert.Nil(t, b.QueryRow("select count(*) from t").Scan(&count))
+	assert.Equal(t, 2, count)
+	assert.Nil(t, tx.QueryRow("select count(*) from t").Scan(&count))
+	assert.Equal(t, 0, count)
+	assert.Nil(t, tx.Rollback())
+
+	assert.Nil(t, a.QueryRow("select count(*) from t").Scan(&count))
+	assert.Equal(t, 2, count)
+
+	// Transactions on different connections are open at the same time.
+	ta, err := a.Begin()
+	assert.Nil(t, err)
+	tb, err := b.Begin()
+	assert.Nil(t, err)
+	_, err = ta.Exec("insert into t values (3)")
+	assert.Nil(t, err)
+	assert.Nil(t, tb.QueryRow("select count(*) from t").Scan(&count))
+	assert.Equal(t, 2, count)
+	assert.Nil(t, ta.Commit())
+	assert.Nil(t, tb.Commit())
+
+	assert.Nil(t, b.QueryRow("select count(*) from t").Scan(&count))
+	assert.Equal(t, 3, count)
+}
+
+func TestDriver_context(t *testing.T) {
+	db, err := sql.Open("gosql", "TestDriver_context")
+	assert.Nil(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "create table t (id int)")
+	assert.Nil(t, err)
+	_, err = conn.ExecContext(ctx, "set statement_timeout = '1s'")
+	assert.Nil(t, err)
+	_, err = conn.ExecContext(ctx, "insert into t values ($1)", 1)
+	assert.Nil(t, err)
+
+	var count int
+	assert.Nil(t, conn.QueryRowContext(ctx, "select count(*) from t").Scan(&count))
+	assert.Equal(t, 1, count)
+
+	_, err = conn.ExecContext(ctx, "insert into t values ($1)", sql.Named("id", 2))
+	assert.Equal(t, ErrNamedParameters, err)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = conn.QueryContext(canceled, "select * from t")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestDriver_lastInsertID(t *testing.T) {
+	db, err := sql.Open("gosql", "TestDriver_lastInsertID")
+	assert.Nil(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "create table users (id serial primary key, name text)")
+	assert.Nil(t, err)
+
+	res, err := conn.ExecContext(ctx, "insert into users (name) values (?), (?)", "alice", "bob")
+	assert.Nil(t, err)
+	id, err := res.LastInsertId()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), id)
+
+	res, err = conn.ExecContext(ctx, "insert into users (name) values (?)", "carol")
+	assert.Nil(t, err)
+	id, err = res.LastInsertId()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), id)
+
+	// An insert that takes nothing from the sequence has no id.
+	res, err = conn.ExecContext(ctx, "insert into users values (10, ?)", "dave")
+	assert.Nil(t, err)
+	id, err = res.LastInsertId()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), id)
+}
+
+func TestDriver_bytea(t *testing.T) {
+	db, err := sql.Open("gosql", "TestDriver_bytea")
+	assert.Nil(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("create table files (name text, data bytea)")
+	assert.Nil(t, err)
+	_, err = db.Exec("insert into files values (?, ?)", "a", []byte{0, 1, 0xff})
+	assert.Nil(t, err)
+
+	var data []byte
+	assert.Nil(t, db.QueryRow("select data from files where name = ?", "a").Scan(&data))
+	assert.Equal(t, []byte{0, 1, 0xff}, data)
+}
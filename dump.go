@@ -0,0 +1,685 @@
+package gosql
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case KeywordKind:
+		return "keyword"
+	case SymbolKind:
+		return "symbol"
+	case IdentifierKind:
+		return "identifier"
+	case StringKind:
+		return "string"
+	case NumericKind:
+		return "numeric"
+	case BoolKind:
+		return "bool"
+	case CommentKind:
+		return "comment"
+	case BytesKind:
+		return "bytes"
+	case ParameterKind:
+		return "parameter"
+	default:
+		return "unknown"
+	}
+}
+
+// Dump renders a parsed node as an indented outline of node types,
+// operators and token locations, one node per line. It accepts an *Ast, a
+// *Statement, any statement node or an *Expression.
+func Dump(node interface{}) string {
+	d := dumper{}
+	d.node(node)
+	return d.b.String()
+}
+
+type dumper struct {
+	b     strings.Builder
+	depth int
+}
+
+func (d *dumper) line(format string, args ...interface{}) {
+	d.b.WriteString(strings.Repeat("  ", d.depth))
+	fmt.Fprintf(&d.b, format, args...)
+	d.b.WriteByte('\n')
+}
+
+func (d *dumper) indent(f func()) {
+	d.depth++
+	f()
+	d.depth--
+}
+
+func at(t *Token) string {
+	return fmt.Sprintf("at %d:%d", t.Loc.Line, t.Loc.Col)
+}
+
+func (d *dumper) node(node interface{}) {
+	switch n := node.(type) {
+	case *Ast:
+		d.line("Ast")
+		d.indent(func() {
+			for _, stmt := range n.Statements {
+				d.node(stmt)
+			}
+		})
+	case *Statement:
+		switch n.Kind {
+		case SelectKind:
+			d.node(n.SelectStatement)
+		case InsertKind:
+			d.node(n.InsertStatement)
+		case CreateTableKind:
+			d.node(n.CreateTableStatement)
+		case UpdateKind:
+			d.node(n.UpdateStatement)
+		case DeleteKind:
+			d.node(n.DeleteStatement)
+		case DropTableKind:
+			d.node(n.DropTableStatement)
+		case CreateIndexKind:
+			d.node(n.CreateIndexStatement)
+		case AlterTableKind:
+			d.node(n.AlterTableStatement)
+		case CopyKind:
+			d.node(n.CopyStatement)
+		case ShowTablesKind:
+			d.line("ShowTables")
+		case DescribeKind:
+			d.line("Describe")
+			d.indent(func() {
+				d.line("Table %s %s", n.DescribeStatement.Table, at(n.DescribeStatement.Table))
+			})
+		case SetKind:
+			d.line("Set")
+			d.indent(func() {
+				d.line("Name %s %s", n.SetStatement.Name, at(n.SetStatement.Name))
+				d.line("Value %s %s", n.SetStatement.Value, at(n.SetStatement.Value))
+			})
+		case ShowKind:
+			if n.ShowStatement.Name == nil {
+				d.line("ShowAll")
+				break
+			}
+			d.line("Show")
+			d.indent(func() {
+				d.line("Name %s %s", n.ShowStatement.Name, at(n.ShowStatement.Name))
+			})
+		case CreateViewKind:
+			d.line("CreateViewStatement")
+			d.indent(func() {
+				d.line("Name %s %s", n.CreateViewStatement.Name, at(n.CreateViewStatement.Name))
+				d.node(n.CreateViewStatement.Select)
+			})
+		case DropViewKind:
+			if n.DropViewStatement.IfExists {
+				d.line("DropViewStatement if exists")
+			} else {
+				d.line("DropViewStatement")
+			}
+			d.indent(func() {
+				d.line("Name %s %s", n.DropViewStatement.Name, at(n.DropViewStatement.Name))
+			})
+		case CreateDatabaseKind:
+			if n.CreateDatabaseStatement.IfNotExists {
+				d.line("CreateDatabaseStatement if not exists")
+			} else {
+				d.line("CreateDatabaseStatement")
+			}
+			d.indent(func() {
+				d.line("Name %s %s", n.CreateDatabaseStatement.Name, at(n.CreateDatabaseStatement.Name))
+			})
+		case DropDatabaseKind:
+			if n.DropDatabaseStatement.IfExists {
+				d.line("DropDatabaseStatement if exists")
+			} else {
+				d.line("DropDatabaseStatement")
+			}
+			d.indent(func() {
+				d.line("Name %s %s", n.DropDatabaseStatement.Name, at(n.DropDatabaseStatement.Name))
+			})
+		case UseKind:
+			d.line("Use")
+			d.indent(func() {
+				d.line("Name %s %s", n.UseStatement.Name, at(n.UseStatement.Name))
+			})
+		case CreateUserKind:
+			crt := n.CreateUserStatement
+			if crt.Superuser {
+				d.line("CreateUserStatement superuser")
+			} else {
+				d.line("CreateUserStatement")
+			}
+			d.indent(func() {
+				d.line("Name %s %s", crt.Name, at(crt.Name))
+				if crt.Password != nil {
+					d.line("Password %s %s", crt.Password, at(crt.Password))
+				}
+			})
+		case DropUserKind:
+			if n.DropUserStatement.IfExists {
+				d.line("DropUserStatement if exists")
+			} else {
+				d.line("DropUserStatement")
+			}
+			d.indent(func() {
+				d.line("Name %s %s", n.DropUserStatement.Name, at(n.DropUserStatement.Name))
+			})
+		case GrantKind, RevokeKind:
+			if n.Kind == RevokeKind {
+				d.line("RevokeStatement")
+			} else {
+				d.line("GrantStatement")
+			}
+			d.indent(func() {
+				for _, p := range n.GrantStatement.Privileges {
+					d.line("Privilege %s %s", p, at(p))
+				}
+				d.line("Table %s %s", n.GrantStatement.Table, at(n.GrantStatement.Table))
+				d.line("User %s %s", n.GrantStatement.User, at(n.GrantStatement.User))
+			})
+		case BeginKind:
+			d.line("Begin")
+		case CommitKind:
+			d.line("Commit")
+		case RollbackKind:
+			d.line("Rollback")
+		case SavepointKind, ReleaseSavepointKind, RollbackToSavepointKind:
+			switch n.Kind {
+			case SavepointKind:
+				d.line("Savepoint")
+			case ReleaseSavepointKind:
+				d.line("ReleaseSavepoint")
+			default:
+				d.line("RollbackToSavepoint")
+			}
+			d.indent(func() {
+				d.line("Name %s %s", n.SavepointStatement.Name, at(n.SavepointStatement.Name))
+			})
+		case ExplainKind:
+			d.line("Explain")
+			d.indent(func() { d.node(n.ExplainStatement.Select) })
+		}
+	case *SelectStatement:
+		d.selectStatement(n)
+	case *InsertStatement:
+		d.insertStatement(n)
+	case *CreateTableStatement:
+		d.createTableStatement(n)
+	case *UpdateStatement:
+		d.updateStatement(n)
+	case *CreateIndexStatement:
+		d.line("CreateIndexStatement")
+		d.indent(func() {
+			d.line("Name %s %s", n.Name, at(n.Name))
+			d.line("Table %s %s", n.Table, at(n.Table))
+			d.line("Column %s %s", n.Column, at(n.Column))
+		})
+	case *DropTableStatement:
+		if n.IfExists {
+			d.line("DropTableStatement if exists")
+		} else {
+			d.line("DropTableStatement")
+		}
+		d.indent(func() {
+			d.line("Name %s %s", n.Name, at(n.Name))
+		})
+	case *AlterTableStatement:
+		d.line("AlterTableStatement")
+		d.indent(func() {
+			d.line("Table %s %s", n.Table, at(n.Table))
+			switch n.Action {
+			case AddColumnAction:
+				d.line("AddColumn")
+				d.indent(func() { d.columnDefinition(n.Add) })
+			case DropColumnAction:
+				d.line("DropColumn %s %s", n.Column, at(n.Column))
+			case RenameColumnAction:
+				d.line("RenameColumn %s %s", n.Column, at(n.Column))
+				d.line("To %s %s", n.To, at(n.To))
+			}
+		})
+	case *CopyStatement:
+		if n.To {
+			d.line("CopyStatement to")
+		} else {
+			d.line("CopyStatement from")
+		}
+		d.indent(func() {
+			d.line("Table %s %s", n.Table, at(n.Table))
+			for _, col := range n.Columns {
+				d.line("Column %s %s", col, at(col))
+			}
+			d.line("File %s %s", n.File, at(n.File))
+			if n.Delimiter != nil {
+				d.line("Delimiter %s %s", n.Delimiter, at(n.Delimiter))
+			}
+			if n.Header {
+				d.line("Header")
+			}
+			if n.Null != nil {
+				d.line("Null %s %s", n.Null, at(n.Null))
+			}
+		})
+	case *DeleteStatement:
+		d.line("DeleteStatement")
+		d.indent(func() {
+			d.line("From %s %s", n.From, at(n.From))
+			if n.Where != nil {
+				d.line("Where")
+				d.indent(func() { d.expression(n.Where) })
+			}
+			if n.Returning != nil {
+				d.selectItems("Returning", n.Returning)
+			}
+		})
+	case *Expression:
+		d.expression(n)
+	default:
+		d.line("Unknown %T", node)
+	}
+}
+
+func (d *dumper) selectItems(label string, items []*SelectItem) {
+	d.line("%s", label)
+	d.indent(func() {
+		for _, item := range items {
+			if item.Asterisk {
+				d.line("Asterisk")
+				continue
+			}
+			d.expression(item.Exp)
+			if item.As != nil {
+				d.indent(func() {
+					d.line("As %s %s", item.As, at(item.As))
+				})
+			}
+		}
+	})
+}
+
+func (d *dumper) selectStatement(slct *SelectStatement) {
+	if len(slct.With) > 0 {
+		d.line("With")
+		d.indent(func() {
+			for _, cte := range slct.With {
+				d.line("CommonTableExpression %s %s", cte.Name, at(cte.Name))
+				d.indent(func() { d.selectStatement(cte.Select) })
+			}
+		})
+	}
+	if slct.Set != nil {
+		d.setOperation(slct)
+		return
+	}
+	if slct.Distinct {
+		d.line("SelectStatement distinct")
+	} else {
+		d.line("SelectStatement")
+	}
+	d.indent(func() {
+		for _, hint := range slct.Hints {
+			d.line("Hint %s %s", strings.ToUpper(hint.Name.Value), at(hint.Name))
+			d.indent(func() {
+				for _, arg := range hint.Args {
+					d.line("Argument %s %s", arg, at(arg))
+				}
+			})
+		}
+		d.selectItems("Items", slct.Item)
+		if slct.From == nil {
+			d.line("From")
+			d.indent(func() { d.selectStatement(slct.FromSelect) })
+		} else {
+			d.line("From %s %s", slct.From, at(slct.From))
+		}
+		if slct.FromAs != nil {
+			d.indent(func() { d.line("As %s %s", slct.FromAs, at(slct.FromAs)) })
+		}
+		for _, j := range slct.Join {
+			switch j.Kind {
+			case LeftJoin:
+				d.line("LeftJoin %s %s", j.Table, at(j.Table))
+			case RightJoin:
+				d.line("RightJoin %s %s", j.Table, at(j.Table))
+			default:
+				d.line("Join %s %s", j.Table, at(j.Table))
+			}
+			d.indent(func() {
+				if j.As != nil {
+					d.line("As %s %s", j.As, at(j.As))
+				}
+				d.line("On")
+				d.indent(func() { d.expression(j.On) })
+			})
+		}
+		if slct.Where != nil {
+			d.line("Where")
+			d.indent(func() { d.expression(slct.Where) })
+		}
+		if len(slct.GroupBy) > 0 {
+			d.line("GroupBy")
+			d.indent(func() {
+				for _, exp := range slct.GroupBy {
+					d.expression(exp)
+				}
+			})
+		}
+		if slct.Having != nil {
+			d.line("Having")
+			d.indent(func() { d.expression(slct.Having) })
+		}
+		d.selectTail(slct)
+	})
+}
+
+// setOperation dumps a query that combines two others, followed by what
+// applies to the combined rows.
+func (d *dumper) setOperation(slct *SelectStatement) {
+	set := slct.Set
+	if set.All {
+		d.line("SetOperation %s all %s", set.Op.Value, at(set.Op))
+	} else {
+		d.line("SetOperation %s %s", set.Op.Value, at(set.Op))
+	}
+	d.indent(func() {
+		d.line("Left")
+		d.indent(func() { d.selectStatement(set.Left) })
+		d.line("Right")
+		d.indent(func() { d.selectStatement(set.Right) })
+		d.selectTail(slct)
+	})
+}
+
+// selectTail dumps the ORDER BY, LIMIT and OFFSET of slct.
+func (d *dumper) selectTail(slct *SelectStatement) {
+	d.orderBy(slct.OrderBy)
+	if slct.Limit != nil {
+		d.line("Limit")
+		d.indent(func() { d.expression(slct.Limit) })
+	}
+	if slct.Offset != nil {
+		d.line("Offset")
+		d.indent(func() { d.expression(slct.Offset) })
+	}
+}
+
+func (d *dumper) orderBy(orderBy []*OrderByClause) {
+	if len(orderBy) == 0 {
+		return
+	}
+	d.line("OrderBy")
+	d.indent(func() {
+		for _, clause := range orderBy {
+			order := "Asc"
+			if clause.Desc {
+				order = "Desc"
+			}
+			switch clause.Nulls {
+			case NullsFirst:
+				order += " NullsFirst"
+			case NullsLast:
+				order += " NullsLast"
+			}
+			d.line("%s", order)
+			d.indent(func() { d.expression(clause.Exp) })
+		}
+	})
+}
+
+func (d *dumper) insertStatement(inst *InsertStatement) {
+	d.line("InsertStatement")
+	d.indent(func() {
+		d.line("Table %s %s", inst.Table, at(inst.Table))
+		if inst.Columns != nil {
+			d.line("Columns")
+			d.indent(func() {
+				for _, col := range inst.Columns {
+					d.line("%s %s", col, at(col))
+				}
+			})
+		}
+		if inst.Select != nil {
+			d.line("Select")
+			d.indent(func() { d.selectStatement(inst.Select) })
+		} else {
+			d.line("Values")
+			d.indent(func() {
+				for _, values := range inst.Values {
+					d.line("Row")
+					d.indent(func() {
+						for _, value := range values {
+							d.expression(value)
+						}
+					})
+				}
+			})
+		}
+		if c := inst.OnConflict; c != nil {
+			line := "OnConflict nothing"
+			if c.DoUpdate {
+				line = "OnConflict update"
+			}
+			d.line("%s", line)
+			d.indent(func() {
+				if c.Target != nil {
+					d.line("Target %s %s", c.Target, at(c.Target))
+				}
+				if c.DoUpdate {
+					d.assignments(c.Set)
+				}
+				if c.Where != nil {
+					d.line("Where")
+					d.indent(func() { d.expression(c.Where) })
+				}
+			})
+		}
+		if inst.Returning != nil {
+			d.selectItems("Returning", inst.Returning)
+		}
+	})
+}
+
+func (d *dumper) updateStatement(updt *UpdateStatement) {
+	d.line("UpdateStatement")
+	d.indent(func() {
+		d.line("Table %s %s", updt.Table, at(updt.Table))
+		d.assignments(updt.Set)
+		if updt.Where != nil {
+			d.line("Where")
+			d.indent(func() { d.expression(updt.Where) })
+		}
+		if updt.Returning != nil {
+			d.selectItems("Returning", updt.Returning)
+		}
+	})
+}
+
+func (d *dumper) assignments(set []*Assignment) {
+	d.line("Set")
+	d.indent(func() {
+		for _, assignment := range set {
+			d.line("%s %s", assignment.Column, at(assignment.Column))
+			d.indent(func() { d.expression(assignment.Value) })
+		}
+	})
+}
+
+func (d *dumper) createTableStatement(crt *CreateTableStatement) {
+	line := "CreateTableStatement"
+	if crt.Temporary {
+		line += " temporary"
+	}
+	if crt.IfNotExists {
+		line += " if not exists"
+	}
+	if crt.OnCommitDrop {
+		line += " on commit drop"
+	}
+	d.line("%s", line)
+	d.indent(func() {
+		d.line("Name %s %s", crt.Name, at(crt.Name))
+		d.line("Columns")
+		d.indent(func() {
+			for _, col := range crt.Cols {
+				d.columnDefinition(col)
+			}
+		})
+		if len(crt.Checks) > 0 {
+			d.line("Checks")
+			d.indent(func() {
+				for _, check := range crt.Checks {
+					d.expression(check)
+				}
+			})
+		}
+		if crt.Layout != nil {
+			d.line("Layout %s %s", crt.Layout, at(crt.Layout))
+		}
+	})
+}
+
+func (d *dumper) columnDefinition(col *ColumnDefinition) {
+	datatype := col.Datatype.String()
+	if col.Length != nil {
+		datatype += "(" + col.Length.Value + ")"
+	}
+	d.line("%s %s %s", col.Name, datatype, at(col.Name))
+	d.indent(func() {
+		for _, c := range col.Constraints {
+			switch c.Kind {
+			case NotNullConstraint:
+				d.line("NotNull at %d:%d", c.Loc.Line, c.Loc.Col)
+			case PrimaryKeyConstraint:
+				d.line("PrimaryKey at %d:%d", c.Loc.Line, c.Loc.Col)
+			case UniqueConstraint:
+				d.line("Unique at %d:%d", c.Loc.Line, c.Loc.Col)
+			case DefaultConstraint:
+				d.line("Default at %d:%d", c.Loc.Line, c.Loc.Col)
+				d.indent(func() { d.expression(c.Default) })
+			case AutoIncrementConstraint:
+				d.line("AutoIncrement at %d:%d", c.Loc.Line, c.Loc.Col)
+			case ReferencesConstraint:
+				references := c.References.Table.Value
+				if c.References.Column != nil {
+					references += "(" + c.References.Column.Value + ")"
+				}
+				if c.References.OnDelete == CascadeAction {
+					references += " OnDeleteCascade"
+				}
+				d.line("References %s at %d:%d", references, c.Loc.Line, c.Loc.Col)
+			case CheckConstraint:
+				d.line("Check at %d:%d", c.Loc.Line, c.Loc.Col)
+				d.indent(func() { d.expression(c.Check) })
+			case CollateConstraint:
+				d.line("Collate %s at %d:%d", c.Collation.Value, c.Loc.Line, c.Loc.Col)
+			}
+		}
+	})
+}
+
+func (d *dumper) expression(exp *Expression) {
+	switch exp.Kind {
+	case LiteralKind:
+		d.line("Literal %s (%s) %s", exp.Literal, exp.Literal.Kind, at(exp.Literal))
+	case ColumnReferenceKind:
+		d.line("ColumnReference %s.%s %s", exp.Column.Table, exp.Column.Column, at(exp.Column.Table))
+	case BinaryKind:
+		d.line("Binary %s %s", exp.Binary.Op.Value, at(exp.Binary.Op))
+		d.indent(func() {
+			d.expression(exp.Binary.Left)
+			d.expression(exp.Binary.Right)
+		})
+	case UnaryKind:
+		d.line("Unary %s %s", exp.Unary.Op.Value, at(exp.Unary.Op))
+		d.indent(func() {
+			d.expression(exp.Unary.Operand)
+		})
+	case IsNullKind:
+		if exp.IsNull.Not {
+			d.line("IsNotNull")
+		} else {
+			d.line("IsNull")
+		}
+		d.indent(func() {
+			d.expression(exp.IsNull.Operand)
+		})
+	case InKind:
+		d.line("In")
+		d.indent(func() {
+			d.expression(exp.In.Left)
+			if exp.In.Select != nil {
+				d.selectStatement(exp.In.Select)
+				return
+			}
+			d.line("List")
+			d.indent(func() {
+				for _, item := range exp.In.List {
+					d.expression(item)
+				}
+			})
+		})
+	case BetweenKind:
+		d.line("Between")
+		d.indent(func() {
+			d.expression(exp.Between.Left)
+			d.expression(exp.Between.Low)
+			d.expression(exp.Between.High)
+		})
+	case FunctionKind:
+		d.line("Function %s %s", exp.Function.Name, at(exp.Function.Name))
+		d.indent(func() {
+			if exp.Function.Asterisk {
+				d.line("Asterisk")
+			}
+			for _, arg := range exp.Function.Args {
+				d.expression(arg)
+			}
+			if over := exp.Function.Over; over != nil {
+				d.line("Over")
+				d.indent(func() {
+					if len(over.PartitionBy) > 0 {
+						d.line("PartitionBy")
+						d.indent(func() {
+							for _, exp := range over.PartitionBy {
+								d.expression(exp)
+							}
+						})
+					}
+					d.orderBy(over.OrderBy)
+				})
+			}
+		})
+	case CastKind:
+		d.line("Cast %s %s", exp.Cast.Type.Value, at(exp.Cast.Type))
+		d.indent(func() {
+			d.expression(exp.Cast.Operand)
+		})
+	case CaseKind:
+		d.line("Case %s", at(exp.Case.Case))
+		d.indent(func() {
+			if exp.Case.Operand != nil {
+				d.expression(exp.Case.Operand)
+			}
+			for _, when := range exp.Case.Whens {
+				d.line("When")
+				d.indent(func() {
+					d.expression(when.When)
+					d.expression(when.Then)
+				})
+			}
+			if exp.Case.Else != nil {
+				d.line("Else")
+				d.indent(func() {
+					d.expression(exp.Case.Else)
+				})
+			}
+		})
+	}
+}
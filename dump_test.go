@@ -0,0 +1,50 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDump(t *testing.T) {
+	ast, err := Parse("select a from t where a = 'x'")
+	assert.Nil(t, err)
+
+	assert.Equal(t, `Ast
+  SelectStatement
+    Items
+      Literal a (identifier) at 0:7
+    From t at 0:14
+    Where
+      Binary = at 0:24
+        Literal a (identifier) at 0:22
+        Literal 'x' (string) at 0:26
+`, Dump(ast))
+
+	ast, err = Parse("insert into t (a) values ('x') returning a as b")
+	assert.Nil(t, err)
+
+	assert.Equal(t, `InsertStatement
+  Table t at 0:12
+  Columns
+    a at 0:15
+  Values
+    Row
+      Literal 'x' (string) at 0:26
+  Returning
+    Literal a (identifier) at 0:41
+      As b at 0:46
+`, Dump(ast.Statements[0]))
+
+	ast, err = Parse("insert into t select a from u")
+	assert.Nil(t, err)
+
+	assert.Equal(t, `InsertStatement
+  Table t at 0:12
+  Select
+    SelectStatement
+      Items
+        Literal a (identifier) at 0:21
+      From u at 0:28
+`, Dump(ast.Statements[0]))
+}
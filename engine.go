@@ -0,0 +1,227 @@
+package gosql
+
+import (
+	"context"
+	"sync"
+)
+
+// StorageEngine keeps the tables of an EngineBackend, so that they can
+// live in a key-value store, an object store or anywhere else without
+// changing the executor. The executor keeps the rows it works on in
+// memory, with their versions and indexes, and the engine holds the
+// committed ones: opening the backend reads every table from it, and the
+// row changes of each transaction are written to it as the transaction
+// commits.
+//
+// Rows are given as cells in the order of the columns of their table. They
+// are MemoryCells, whose bytes an engine can store and give back as a
+// MemoryCell, and NULL is a nil MemoryCell, apart from empty text. The
+// engine is called by one goroutine at a time.
+type StorageEngine interface {
+	// Tables returns the definition of every stored table, in the order
+	// they were created, and Indexes those of their indexes.
+	Tables(ctx context.Context) ([]*CreateTableStatement, error)
+	Indexes(ctx context.Context) ([]*CreateIndexStatement, error)
+	CreateTable(ctx context.Context, crt *CreateTableStatement) error
+	DropTable(ctx context.Context, name string) error
+	CreateIndex(ctx context.Context, crt *CreateIndexStatement) error
+	// Scan calls fn with every row of the table called table.
+	Scan(ctx context.Context, table string, fn func(row []Cell) error) error
+	// Insert, Update and Delete change the rows of a table. Update and
+	// Delete are given a row with the values of the one to change, and
+	// any row with those values will do.
+	Insert(ctx context.Context, table string, row []Cell) error
+	Update(ctx context.Context, table string, old, new []Cell) error
+	Delete(ctx context.Context, table string, row []Cell) error
+	// Commit is called once the changes of a transaction have been
+	// written, and Rollback instead when one of them failed, so an engine
+	// that holds back writes until Commit keeps only whole transactions.
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// EngineBackend is a MemoryBackend whose tables are kept by a
+// StorageEngine. Changing the schema writes through to the engine at once,
+// so it cannot be done inside a transaction of the backend's own session.
+// Views and databases besides the default one have nowhere to be kept, so
+// they are not supported, while temporary tables stay in memory as they
+// would anyway.
+type EngineBackend struct {
+	*MemoryBackend
+	engine StorageEngine
+	// mu keeps transactions committing on different tables from writing
+	// to the engine at the same time.
+	mu sync.Mutex
+}
+
+// NewEngineBackend opens the tables engine keeps, loading their rows.
+func NewEngineBackend(ctx context.Context, engine StorageEngine) (*EngineBackend, error) {
+	eb := &EngineBackend{MemoryBackend: NewMemoryBackend(), engine: engine}
+
+	tables, err := engine.Tables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, crt := range tables {
+		if err := eb.MemoryBackend.CreateTable(ctx, crt); err != nil {
+			return nil, err
+		}
+		var rows [][]Cell
+		err := engine.Scan(ctx, crt.Name.Value, func(row []Cell) error {
+			rows = append(rows, row)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := eb.MemoryBackend.InsertRows(ctx, crt.Name.Value, nil, rows); err != nil {
+			return nil, err
+		}
+	}
+
+	indexes, err := engine.Indexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, crt := range indexes {
+		if err := eb.MemoryBackend.CreateIndex(ctx, crt); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only changes made from here on are new to the engine.
+	eb.MemoryBackend.persist = eb.persist
+	return eb, nil
+}
+
+// persist writes the row changes of a committing transaction to the
+// engine.
+func (eb *EngineBackend) persist(changes []Change) error {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	ctx := context.Background()
+	for _, change := range changes {
+		var err error
+		switch change.Event {
+		case InsertEvent:
+			err = eb.engine.Insert(ctx, change.Table, change.New)
+		case UpdateEvent:
+			err = eb.engine.Update(ctx, change.Table, change.Old, change.New)
+		case DeleteEvent:
+			err = eb.engine.Delete(ctx, change.Table, change.Old)
+		}
+		if err != nil {
+			// The error that failed the transaction is what matters.
+			_ = eb.engine.Rollback(ctx)
+			return err
+		}
+	}
+	return eb.engine.Commit(ctx)
+}
+
+// alter runs fn, which changes the schema both in memory and in the
+// engine, as a statement of the backend's own session. It cannot run
+// inside a transaction, as the engine could not undo it. Other statements
+// wait for it, including those about to write to the engine.
+func (eb *EngineBackend) alter(ctx context.Context, fn func(tx *transaction) error) error {
+	if eb.session.InTransaction() {
+		return ErrTransactionActive
+	}
+	return eb.session.alter(ctx, fn)
+}
+
+func (eb *EngineBackend) CreateTable(ctx context.Context, crt *CreateTableStatement) error {
+	if crt.Temporary || isTemporary(crt.Name.Value) {
+		return eb.MemoryBackend.CreateTable(ctx, crt)
+	}
+	return eb.alter(ctx, func(tx *transaction) error {
+		existed := eb.exists(crt.Name.Value)
+		if err := eb.createTable(ctx, tx, crt); err != nil || existed {
+			return err
+		}
+		return eb.engine.CreateTable(ctx, crt)
+	})
+}
+
+func (eb *EngineBackend) DropTable(ctx context.Context, drp *DropTableStatement) error {
+	if isTemporary(drp.Name.Value) {
+		return eb.MemoryBackend.DropTable(ctx, drp)
+	}
+	return eb.alter(ctx, func(tx *transaction) error {
+		_, existed := eb.tables[drp.Name.Value]
+		if err := eb.dropTable(tx, drp); err != nil || !existed {
+			return err
+		}
+		return eb.engine.DropTable(ctx, drp.Name.Value)
+	})
+}
+
+func (eb *EngineBackend) CreateIndex(ctx context.Context, crt *CreateIndexStatement) error {
+	if isTemporary(crt.Table.Value) {
+		return eb.MemoryBackend.CreateIndex(ctx, crt)
+	}
+	return eb.alter(ctx, func(tx *transaction) error {
+		if err := eb.createIndex(tx, crt); err != nil {
+			return err
+		}
+		return eb.engine.CreateIndex(ctx, crt)
+	})
+}
+
+// AlterTable changes the table in memory and then stores it in the engine
+// anew, with its rows as they now are, since the engine has no way of
+// changing its columns.
+func (eb *EngineBackend) AlterTable(ctx context.Context, alt *AlterTableStatement) error {
+	name := alt.Table.Value
+	if isTemporary(name) {
+		return eb.MemoryBackend.AlterTable(ctx, alt)
+	}
+	return eb.alter(ctx, func(tx *transaction) error {
+		if err := eb.alterTable(ctx, tx, alt); err != nil {
+			return err
+		}
+
+		t := eb.tables[name]
+		if err := eb.engine.DropTable(ctx, name); err != nil {
+			return err
+		}
+		if err := eb.engine.CreateTable(ctx, eb.schema()[name]); err != nil {
+			return err
+		}
+		for _, row := range t.visibleTo(tx.snapshot).rows {
+			if err := eb.engine.Insert(ctx, name, rowCells(row)); err != nil {
+				_ = eb.engine.Rollback(ctx)
+				return err
+			}
+		}
+		if err := eb.engine.Commit(ctx); err != nil {
+			return err
+		}
+		for _, idx := range t.indexes {
+			if t.implicitIndex(idx) {
+				continue
+			}
+			err := eb.engine.CreateIndex(ctx, &CreateIndexStatement{
+				Name:   &Token{Value: idx.name, Kind: IdentifierKind},
+				Table:  alt.Table,
+				Column: &Token{Value: t.columns[idx.column], Kind: IdentifierKind},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (eb *EngineBackend) CreateView(ctx context.Context, crt *CreateViewStatement) error {
+	if !isTemporary(crt.Name.Value) {
+		return ErrEngineUnsupported
+	}
+	return eb.MemoryBackend.CreateView(ctx, crt)
+}
+
+func (eb *EngineBackend) CreateDatabase(ctx context.Context, crt *CreateDatabaseStatement) error {
+	return ErrEngineUnsupported
+}
@@ -0,0 +1,178 @@
+package gosql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mapEngine keeps tables in maps, holding back writes until Commit.
+type mapEngine struct {
+	tables  []*CreateTableStatement
+	indexes []*CreateIndexStatement
+	rows    map[string][][]Cell
+	pending []func()
+	fail    error
+}
+
+func newMapEngine() *mapEngine {
+	return &mapEngine{rows: map[string][][]Cell{}}
+}
+
+func (e *mapEngine) Tables(ctx context.Context) ([]*CreateTableStatement, error) {
+	return e.tables, nil
+}
+
+func (e *mapEngine) Indexes(ctx context.Context) ([]*CreateIndexStatement, error) {
+	return e.indexes, nil
+}
+
+func (e *mapEngine) CreateTable(ctx context.Context, crt *CreateTableStatement) error {
+	e.tables = append(e.tables, crt)
+	return nil
+}
+
+func (e *mapEngine) DropTable(ctx context.Context, name string) error {
+	for i, crt := range e.tables {
+		if crt.Name.Value == name {
+			e.tables = append(e.tables[:i], e.tables[i+1:]...)
+			break
+		}
+	}
+	var kept []*CreateIndexStatement
+	for _, crt := range e.indexes {
+		if crt.Table.Value != name {
+			kept = append(kept, crt)
+		}
+	}
+	e.indexes = kept
+	delete(e.rows, name)
+	return nil
+}
+
+func (e *mapEngine) CreateIndex(ctx context.Context, crt *CreateIndexStatement) error {
+	e.indexes = append(e.indexes, crt)
+	return nil
+}
+
+func (e *mapEngine) Scan(ctx context.Context, table string, fn func(row []Cell) error) error {
+	for _, row := range e.rows[table] {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *mapEngine) Insert(ctx context.Context, table string, row []Cell) error {
+	if e.fail != nil {
+		return e.fail
+	}
+	e.pending = append(e.pending, func() {
+		e.rows[table] = append(e.rows[table], row)
+	})
+	return nil
+}
+
+func (e *mapEngine) find(table string, row []Cell) int {
+	for i, stored := range e.rows[table] {
+		if rowKey(stored, make([]ResultColumn, len(row))) == rowKey(row, make([]ResultColumn, len(row))) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (e *mapEngine) Update(ctx context.Context, table string, old, new []Cell) error {
+	e.pending = append(e.pending, func() {
+		e.rows[table][e.find(table, old)] = new
+	})
+	return nil
+}
+
+func (e *mapEngine) Delete(ctx context.Context, table string, row []Cell) error {
+	e.pending = append(e.pending, func() {
+		i := e.find(table, row)
+		e.rows[table] = append(e.rows[table][:i], e.rows[table][i+1:]...)
+	})
+	return nil
+}
+
+func (e *mapEngine) Commit(ctx context.Context) error {
+	for _, write := range e.pending {
+		write()
+	}
+	e.pending = nil
+	return nil
+}
+
+func (e *mapEngine) Rollback(ctx context.Context) error {
+	e.pending = nil
+	return nil
+}
+
+func TestEngineBackend(t *testing.T) {
+	ctx := context.Background()
+	engine := newMapEngine()
+	eb, err := NewEngineBackend(ctx, engine)
+	assert.Nil(t, err)
+
+	_, err = ExecuteScript(eb, `create table items (id int primary key, name text);
+create index items_name on items (name);
+insert into items values (1, 'pen'), (2, 'ink'), (3, 'cap');
+update items set name = 'quill' where id = 1;
+delete from items where id = 2;
+begin;
+insert into items values (4, 'pad');
+commit;
+begin;
+insert into items values (5, 'nib');
+rollback;
+create temporary table scratch (n int);
+insert into scratch values (1)`, ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, engine.tables, 1)
+	assert.Len(t, engine.indexes, 1)
+	assert.Len(t, engine.rows["items"], 3)
+
+	// The engine fails the transaction, which leaves nothing behind.
+	engine.fail = errors.New("disk full")
+	_, err = ExecuteScript(eb, "insert into items values (6, 'box')", ScriptOptions{})
+	assert.ErrorIs(t, err, engine.fail)
+	engine.fail = nil
+
+	for _, test := range []struct {
+		source string
+		err    error
+	}{
+		{"create view names as select name from items", ErrEngineUnsupported},
+		{"create database shop", ErrEngineUnsupported},
+		{"begin; create table other (id int)", ErrTransactionActive},
+	} {
+		_, err := ExecuteScript(eb, test.source, ScriptOptions{})
+		assert.ErrorIs(t, err, test.err, test.source)
+	}
+	assert.Nil(t, eb.Rollback())
+
+	_, err = ExecuteScript(eb, "alter table items add column price int default 1", ScriptOptions{})
+	assert.Nil(t, err)
+
+	// Opening the engine again finds the committed rows.
+	eb, err = NewEngineBackend(ctx, engine)
+	assert.Nil(t, err)
+	results, err := ExecuteScript(eb, "select id, name, price from items order by id", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{
+		{intCell(1), MemoryCell("quill"), intCell(1)},
+		{intCell(3), MemoryCell("cap"), intCell(1)},
+		{intCell(4), MemoryCell("pad"), intCell(1)},
+	}, results[0].Results.Rows)
+	assert.Len(t, engine.indexes, 1)
+	assert.NotContains(t, eb.Schema(), "scratch")
+
+	_, err = ExecuteScript(eb, "drop table items", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, engine.tables)
+}
@@ -0,0 +1,300 @@
+package gosql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrorCode is the broad kind of failure an error reports, so programs
+// can branch on it without matching messages.
+type ErrorCode uint
+
+const (
+	InternalError ErrorCode = iota
+	SyntaxError
+	UndefinedTableError
+	UndefinedColumnError
+	UndefinedFunctionError
+	DuplicateObjectError
+	TypeMismatchError
+	ConstraintViolationError
+	DataError
+	TransactionError
+	// CanceledError is a statement canceled through its context or by the
+	// statement timeout before it finished.
+	CanceledError
+	// ResourceError is a statement that needs more of a resource, like
+	// memory, than its session allows.
+	ResourceError
+	// PermissionError is a statement its user lacks the privileges for,
+	// or a failed login.
+	PermissionError
+)
+
+func (c ErrorCode) String() string {
+	switch c {
+	case SyntaxError:
+		return "syntax error"
+	case UndefinedTableError:
+		return "undefined table"
+	case UndefinedColumnError:
+		return "undefined column"
+	case UndefinedFunctionError:
+		return "undefined function"
+	case DuplicateObjectError:
+		return "duplicate object"
+	case TypeMismatchError:
+		return "type mismatch"
+	case ConstraintViolationError:
+		return "constraint violation"
+	case DataError:
+		return "data error"
+	case TransactionError:
+		return "transaction error"
+	case CanceledError:
+		return "canceled"
+	case ResourceError:
+		return "insufficient resources"
+	case PermissionError:
+		return "insufficient privilege"
+	}
+	return "internal error"
+}
+
+// Error is a failure found while lexing, parsing or validating a
+// statement. It points at the token that caused it so callers can show
+// the source with a caret under it. Errors.Is still matches the backend
+// error it wraps, if any.
+type Error struct {
+	Code ErrorCode
+	Loc  Location
+	// Snippet is the source text of the offending token, or empty when
+	// the error is at a place with no token, like the end of the input.
+	Snippet string
+	// Hint suggests a fix, like the keyword a misspelled word is closest
+	// to. It is empty when there is nothing to suggest.
+	Hint string
+
+	msg     string
+	err     error
+	located bool
+}
+
+func (e *Error) Error() string {
+	return e.msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// SQLState is the code PostgreSQL reports for the same failure.
+func (e *Error) SQLState() string {
+	return SQLState(e)
+}
+
+// Caret shows the line of source that Loc is on with a caret under the
+// offending column, the way psql does. It is empty when the error does not
+// point into source.
+func (e *Error) Caret(source string) string {
+	lines := strings.Split(source, "\n")
+	if !e.located || e.Loc.Line >= uint(len(lines)) {
+		return ""
+	}
+	// Columns count characters, not bytes.
+	line := []rune(lines[e.Loc.Line])
+	if e.Loc.Col > uint(len(line)) {
+		return ""
+	}
+
+	prefix := fmt.Sprintf("LINE %d: ", e.Loc.Line+1)
+	// Tabs are kept so the caret lines up however they are displayed.
+	pad := []rune(strings.Repeat(" ", len(prefix)))
+	for _, r := range line[:e.Loc.Col] {
+		if r == '\t' {
+			pad = append(pad, '\t')
+		} else {
+			pad = append(pad, ' ')
+		}
+	}
+	return prefix + string(line) + "\n" + string(pad) + "^"
+}
+
+// tokenError builds an Error pointing at t, wrapping err when it is not
+// nil.
+func tokenError(code ErrorCode, err error, t *Token, msg string) *Error {
+	return &Error{
+		Code:    code,
+		Loc:     t.Loc,
+		Snippet: t.String(),
+		msg:     fmt.Sprintf("%s at %d:%d", msg, t.Loc.Line, t.Loc.Col),
+		err:     err,
+		located: true,
+	}
+}
+
+// closestKeyword returns the keyword word is most likely a misspelling
+// of. Short words must be within one edit of it and longer ones within
+// two, so that ordinary identifiers are rarely taken for typos.
+func closestKeyword(word string) (keyword, bool) {
+	word = strings.ToLower(word)
+	if len(word) < 3 {
+		return "", false
+	}
+	limit := 1
+	if len(word) > 5 {
+		limit = 2
+	}
+
+	var closest keyword
+	best := limit + 1
+	for _, k := range keywords {
+		if d := editDistance(word, string(k)); d < best {
+			closest, best = k, d
+		}
+	}
+	return closest, best > 0 && best <= limit
+}
+
+// editDistance counts the insertions, deletions, substitutions and
+// transpositions of adjacent characters that turn a into b.
+func editDistance(a, b string) int {
+	// d[i][j] is the distance between a[:i] and b[:j].
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = d[i-1][j-1] + cost
+			if d[i-1][j]+1 < d[i][j] {
+				d[i][j] = d[i-1][j] + 1
+			}
+			if d[i][j-1]+1 < d[i][j] {
+				d[i][j] = d[i][j-1] + 1
+			}
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && d[i-2][j-2]+1 < d[i][j] {
+				d[i][j] = d[i-2][j-2] + 1
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+var errorCodes = []struct {
+	err   error
+	code  ErrorCode
+	state string
+}{
+	{ErrMultipleStatements, SyntaxError, "42601"},
+	{ErrSetOperationColumns, SyntaxError, "42601"},
+	{ErrTableDoesNotExist, UndefinedTableError, "42P01"},
+	{ErrTableAlreadyExists, DuplicateObjectError, "42P07"},
+	{ErrDatabaseDoesNotExist, UndefinedTableError, "3D000"},
+	{ErrDatabaseAlreadyExists, DuplicateObjectError, "42P04"},
+	{ErrDropDefaultDatabase, ConstraintViolationError, "55006"},
+	{ErrTemporarySchema, ConstraintViolationError, "42P16"},
+	{ErrTemporaryReference, ConstraintViolationError, "42P16"},
+	{ErrWindowNotAllowed, SyntaxError, "42P20"},
+	{ErrOverRequired, UndefinedFunctionError, "42809"},
+	{ErrNotWindowFunction, UndefinedFunctionError, "42809"},
+	{ErrTriggerAlreadyExists, DuplicateObjectError, "42710"},
+	{ErrTriggerDoesNotExist, UndefinedTableError, "42704"},
+	{ErrSubscriptionLagged, ResourceError, "53000"},
+	{ErrEngineUnsupported, InternalError, "0A000"},
+	{ErrNestedTooDeeply, ResourceError, "54001"},
+	{ErrParserFailure, InternalError, "XX000"},
+	{ErrUserAlreadyExists, DuplicateObjectError, "42710"},
+	{ErrUserDoesNotExist, UndefinedTableError, "42704"},
+	{ErrPermissionDenied, PermissionError, "42501"},
+	{ErrInvalidPassword, PermissionError, "28P01"},
+	{ErrNoConflictTarget, UndefinedColumnError, "42P10"},
+	{ErrConflictAffectedTwice, DataError, "21000"},
+	{ErrNoSavepoint, TransactionError, "3B001"},
+	{ErrCollationDoesNotExist, UndefinedTableError, "42704"},
+	{ErrInvalidHint, SyntaxError, "42601"},
+	{ErrWrongObjectType, UndefinedTableError, "42809"},
+	{ErrDependentObjects, ConstraintViolationError, "2BP01"},
+	{ErrIndexAlreadyExists, DuplicateObjectError, "42P07"},
+	{ErrColumnDoesNotExist, UndefinedColumnError, "42703"},
+	{ErrAmbiguousColumn, UndefinedColumnError, "42702"},
+	{ErrOrderByPosition, UndefinedColumnError, "42P10"},
+	{ErrDuplicateAlias, DuplicateObjectError, "42712"},
+	{ErrDuplicateColumn, DuplicateObjectError, "42701"},
+	{ErrFunctionAlreadyExists, DuplicateObjectError, "42723"},
+	{ErrMultiplePrimaryKeys, ConstraintViolationError, "42P16"},
+	{ErrMultipleAutoIncrement, ConstraintViolationError, "42P16"},
+	{ErrInvalidForeignKey, ConstraintViolationError, "42830"},
+	{ErrInvalidCheck, ConstraintViolationError, "0A000"},
+	{ErrTypeMismatch, TypeMismatchError, "42804"},
+	{ErrInvalidDatatype, TypeMismatchError, "42804"},
+	{ErrInvalidCondition, TypeMismatchError, "42804"},
+	{ErrInvalidCast, TypeMismatchError, "42846"},
+	{ErrInvalidArguments, TypeMismatchError, "42883"},
+	{ErrFunctionNotFound, UndefinedFunctionError, "42883"},
+	{ErrDivisionByZero, DataError, "22012"},
+	{ErrIntegerOutOfRange, DataError, "22003"},
+	{ErrValueOutOfRange, DataError, "22003"},
+	{ErrValueTooLong, DataError, "22001"},
+	{ErrInvalidTextRepresentation, DataError, "22P02"},
+	{ErrBadCopyFileFormat, DataError, "22P04"},
+	{ErrUnknownSetting, DataError, "42704"},
+	{ErrInvalidSettingValue, DataError, "22023"},
+	{ErrWorkMemExceeded, ResourceError, "53200"},
+	{ErrViolatesNotNull, ConstraintViolationError, "23502"},
+	{ErrViolatesPrimaryKey, ConstraintViolationError, "23505"},
+	{ErrViolatesUnique, ConstraintViolationError, "23505"},
+	{ErrViolatesForeignKey, ConstraintViolationError, "23503"},
+	{ErrViolatesCheck, ConstraintViolationError, "23514"},
+	{ErrTransactionActive, TransactionError, "25001"},
+	{ErrNoTransaction, TransactionError, "25P01"},
+	{ErrSerializationFailure, TransactionError, "40001"},
+	{ErrLockTimeout, TransactionError, "55P03"},
+	{ErrReadOnlySession, TransactionError, "25006"},
+	{ErrStatementTimeout, CanceledError, "57014"},
+	{context.Canceled, CanceledError, "57014"},
+	{context.DeadlineExceeded, CanceledError, "57014"},
+	{ErrCorruptSnapshot, InternalError, "XX001"},
+	{ErrUnsupportedSnapshot, InternalError, "0A000"},
+}
+
+// Code returns the kind of failure err reports, looking through any
+// wrapping for an Error or one of the backend errors.
+func Code(err error) ErrorCode {
+	var e *Error
+	if errors.As(err, &e) && e.Code != InternalError {
+		return e.Code
+	}
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return InternalError
+}
+
+// SQLState returns the code PostgreSQL reports for the failure err
+// reports, or XX000 when there is none more specific.
+func SQLState(err error) string {
+	var e *Error
+	if errors.As(err, &e) && e.err == nil && e.Code == SyntaxError {
+		return "42601"
+	}
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.state
+		}
+	}
+	return "XX000"
+}
@@ -0,0 +1,102 @@
+package gosql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError(t *testing.T) {
+	tests := []struct {
+		source  string
+		code    ErrorCode
+		loc     Location
+		snippet string
+		state   string
+	}{
+		{"select id ^ id", SyntaxError, Location{Col: 10}, "^", "42601"},
+		{"select id from", SyntaxError, Location{Col: 10}, "", "42601"},
+		{"select id\nfrom users where id id", SyntaxError, Location{Line: 1, Col: 20}, "id", "42601"},
+		{"select * from nope", UndefinedTableError, Location{Col: 14}, "nope", "42P01"},
+		{"select nope from users", UndefinedColumnError, Location{Col: 7}, "nope", "42703"},
+		{"select name from users where id = 'x'", TypeMismatchError, Location{Col: 32}, "=", "42804"},
+		{"select nope(id) from users", UndefinedFunctionError, Location{Col: 7}, "nope", "42883"},
+		{"select id::date from users", TypeMismatchError, Location{Col: 11}, "date", "42846"},
+		{"create table users (id int)", DuplicateObjectError, Location{Col: 13}, "users", "42P07"},
+	}
+
+	mb := NewMemoryBackend()
+	ast, err := Parse("create table users (id int primary key, name text)")
+	assert.Nil(t, err)
+	assert.Nil(t, mb.CreateTable(context.Background(), ast.Statements[0].CreateTableStatement))
+
+	for _, test := range tests {
+		ast, err := Parse(test.source)
+		if err == nil {
+			err = Validate(ast.Statements[0], mb.Schema())
+		}
+
+		var e *Error
+		if !assert.True(t, errors.As(err, &e), test.source) {
+			continue
+		}
+		assert.Equal(t, test.code, e.Code, test.source)
+		assert.Equal(t, test.code, Code(err), test.source)
+		assert.Equal(t, test.loc, e.Loc, test.source)
+		assert.Equal(t, test.snippet, e.Snippet, test.source)
+		assert.Equal(t, test.state, e.SQLState(), test.source)
+	}
+}
+
+func TestCode(t *testing.T) {
+	err := fmt.Errorf("%w: id", ErrViolatesPrimaryKey)
+	assert.Equal(t, ConstraintViolationError, Code(err))
+	assert.Equal(t, "23505", SQLState(err))
+
+	err = errors.New("disk full")
+	assert.Equal(t, InternalError, Code(err))
+	assert.Equal(t, "XX000", SQLState(err))
+	assert.Equal(t, "internal error", Code(err).String())
+}
+
+func TestError_Caret(t *testing.T) {
+	source := "select id\nfrom users\nwhere id ~ 1"
+	_, err := Parse(source)
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "LINE 3: where id ~ 1\n                 ^", e.Caret(source))
+
+	_, err = Parse("selct * from users")
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "Did you mean SELECT?", e.Hint)
+
+	e = &Error{Code: SyntaxError}
+	assert.Equal(t, "", e.Caret(source))
+}
+
+func TestClosestKeyword(t *testing.T) {
+	tests := []struct {
+		word   string
+		result keyword
+		ok     bool
+	}{
+		{"selct", SelectKeyword, true},
+		{"FORM", FromKeyword, true},
+		{"wehre", WhereKeyword, true},
+		{"ordr", OrderKeyword, true},
+		{"distnict", DistinctKeyword, true},
+		{"select", "", false},
+		{"id", "", false},
+		{"users", "", false},
+	}
+	for _, test := range tests {
+		result, ok := closestKeyword(test.word)
+		assert.Equal(t, test.ok, ok, test.word)
+		if test.ok {
+			assert.Equal(t, test.result, result, test.word)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package gosql
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// explain describes how query would run the statement e explains against
+// the rows snap sees.
+func (mb *MemoryBackend) explain(ctx context.Context, snap *txSnapshot, e *ExplainStatement) (*Results, error) {
+	if e.Analyze {
+		return mb.explainAnalyze(ctx, snap, e.Select)
+	}
+
+	root, err := mb.plan(snap, e.Select)
+	if err != nil {
+		return nil, err
+	}
+	return planResults(root.lines("", true)), nil
+}
+
+// planResults returns the lines of a plan as the rows of EXPLAIN.
+func planResults(lines []string) *Results {
+	results := &Results{Columns: []ResultColumn{{Type: TextType, Name: "QUERY PLAN"}}}
+	for _, line := range lines {
+		results.Rows = append(results.Rows, []Cell{MemoryCell(line)})
+	}
+	return results
+}
+
+// analyzeKey is the context key under which EXPLAIN ANALYZE hands a cursor
+// the analysis to plan its query into.
+type analyzeKey struct{}
+
+// analysis receives the plan of a query EXPLAIN ANALYZE runs, measured as
+// it runs.
+type analysis struct {
+	root     *planNode
+	planTime time.Duration
+}
+
+// plan plans slct, whose subqueries have been resolved, as the query a
+// measures.
+func (a *analysis) plan(ctx context.Context, mb *MemoryBackend, snap *txSnapshot, slct *SelectStatement) (*planNode, error) {
+	planning := time.Now()
+	root, err := mb.plan(snap, slct)
+	statementMetrics(ctx).planned(planning)
+	if err != nil {
+		return nil, err
+	}
+	a.planTime = time.Since(planning)
+	root.instrument()
+	a.root = root
+	return root, nil
+}
+
+// nodeStats is what EXPLAIN ANALYZE measured an operator doing.
+type nodeStats struct {
+	loops int
+	rows  int
+	// time includes that of the operators below it.
+	time time.Duration
+}
+
+// explainAnalyze runs slct, throwing its rows away, and describes the plan
+// it ran with what each operator actually did. The subqueries slct has in
+// its conditions run first, and the plan shows the values they gave.
+func (mb *MemoryBackend) explainAnalyze(ctx context.Context, snap *txSnapshot, slct *SelectStatement) (*Results, error) {
+	executing := time.Now()
+	a := &analysis{}
+	rows, err := mb.cursor(context.WithValue(ctx, analyzeKey{}, a), snap, slct)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rows.All(); err != nil {
+		return nil, err
+	}
+	executionTime := time.Since(executing) - a.planTime
+
+	return planResults(append(a.root.lines("", true),
+		fmt.Sprintf("Planning Time: %.3f ms", milliseconds(a.planTime)),
+		fmt.Sprintf("Execution Time: %.3f ms", milliseconds(executionTime)),
+	)), nil
+}
+
+// instrument has the operators of the plan rooted at n measure what they
+// do. Those with a run function are measured around it. The ones above
+// them are run by a cursor, which measures them.
+func (n *planNode) instrument() {
+	n.actual = &nodeStats{}
+	for _, child := range n.children {
+		child.instrument()
+	}
+	if n.run == nil {
+		return
+	}
+
+	run := n.run
+	n.run = func(ctx context.Context) (*table, error) {
+		// A child without a run function is a query the operator runs
+		// with a cursor, which plans it anew once its subqueries are
+		// resolved.
+		sub := &analysis{}
+		start := time.Now()
+		t, err := run(context.WithValue(ctx, analyzeKey{}, sub))
+		if err != nil {
+			return nil, err
+		}
+		n.record(start, len(t.rows))
+		for i, child := range n.children {
+			if child.run == nil && sub.root != nil {
+				n.children[i] = sub.root
+			}
+		}
+		return t, nil
+	}
+}
+
+// record counts a run of n that began at start and produced rows, if n is
+// being measured.
+func (n *planNode) record(start time.Time, rows int) {
+	if n == nil || n.actual == nil {
+		return
+	}
+	n.actual.loops++
+	n.actual.rows += rows
+	n.actual.time += time.Since(start)
+}
+
+// stages returns the operators at the top of the plan rooted at n that a
+// cursor runs itself, by operator, and the operator below them: the first
+// with a run function, or the one combining the queries of a set
+// operation. Both are empty for a nil plan.
+func (n *planNode) stages() (map[string]*planNode, *planNode) {
+	stages := map[string]*planNode{}
+	for n != nil && n.run == nil {
+		stages[n.operator] = n
+		if len(n.children) != 1 {
+			break
+		}
+		n = n.children[0]
+	}
+	return stages, n
+}
+
+// milliseconds converts d to fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// plan builds the operators query runs for slct, in the same order.
+func (mb *MemoryBackend) plan(snap *txSnapshot, slct *SelectStatement) (*planNode, error) {
+	if slct.Set != nil {
+		node, err := mb.planSet(snap, slct.Set)
+		if err != nil {
+			return nil, err
+		}
+		if len(slct.OrderBy) > 0 {
+			node = sortNode(node, slct.OrderBy)
+		}
+		return limitNode(node, slct)
+	}
+
+	node, err := mb.planFrom(snap, slct)
+	if err != nil {
+		return nil, err
+	}
+
+	output := "Output: " + formatSelectItems(slct.Item)
+	if isAggregate(slct) {
+		rows := 1
+		var details []string
+		if len(slct.GroupBy) > 0 {
+			rows = node.rows
+			details = append(details, "Group Key: "+formatExpressions(slct.GroupBy))
+		}
+		if slct.Having != nil {
+			rows = estimate(rows, defaultSelectivity)
+			details = append(details, "Filter: "+formatExpression(slct.Having))
+		}
+		details = append(details, output)
+		node = &planNode{operator: "Aggregate", details: details, rows: rows, cost: node.cost + float64(node.rows), children: []*planNode{node}}
+		if len(slct.OrderBy) > 0 {
+			node = sortNode(node, slct.OrderBy)
+		}
+	} else {
+		if hasWindow(slct) {
+			node = &planNode{operator: "WindowAgg", details: []string{output}, rows: node.rows, cost: node.cost + float64(node.rows), children: []*planNode{node}}
+		}
+		if len(slct.OrderBy) > 0 {
+			node = sortNode(node, slct.OrderBy)
+		}
+		node = &planNode{operator: "Projection", details: []string{output}, rows: node.rows, cost: node.cost + float64(node.rows), children: []*planNode{node}}
+	}
+
+	if slct.Distinct {
+		node = &planNode{operator: "Unique", rows: node.rows, cost: node.cost + float64(node.rows), children: []*planNode{node}}
+	}
+
+	return limitNode(node, slct)
+}
+
+// limitNode applies the OFFSET and LIMIT of slct to node, if it has any.
+func limitNode(node *planNode, slct *SelectStatement) (*planNode, error) {
+	if slct.Limit != nil || slct.Offset != nil {
+		rows := node.rows
+		var details []string
+		// LIMIT and OFFSET cannot refer to any column.
+		t := &table{}
+		if slct.Offset != nil {
+			offset, ok, err := t.evaluateCount(slct.Offset)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				rows -= offset
+				if rows < 0 {
+					rows = 0
+				}
+			}
+			details = append(details, "Offset: "+formatExpression(slct.Offset))
+		}
+		if slct.Limit != nil {
+			limit, ok, err := t.evaluateCount(slct.Limit)
+			if err != nil {
+				return nil, err
+			}
+			if ok && limit < rows {
+				rows = limit
+			}
+			details = append(details, "Count: "+formatExpression(slct.Limit))
+		}
+		node = &planNode{operator: "Limit", details: details, rows: rows, cost: node.cost, children: []*planNode{node}}
+	}
+
+	return node, nil
+}
+
+// sortNode sorts the rows of child, which takes n log n comparisons.
+func sortNode(child *planNode, orderBy []*OrderByClause) *planNode {
+	var keys []string
+	for _, clause := range orderBy {
+		key := formatExpression(clause.Exp)
+		if clause.Desc {
+			key += " desc"
+		}
+		switch clause.Nulls {
+		case NullsFirst:
+			key += " nulls first"
+		case NullsLast:
+			key += " nulls last"
+		}
+		keys = append(keys, key)
+	}
+	n := float64(child.rows)
+	return &planNode{
+		operator: "Sort",
+		details:  []string{"Sort Key: " + strings.Join(keys, ", ")},
+		rows:     child.rows,
+		cost:     child.cost + n*math.Log2(n+1),
+		children: []*planNode{child},
+	}
+}
+
+// lines renders the plan the way PostgreSQL's EXPLAIN does, with each
+// child below its parent behind an arrow. A measured operator also shows
+// its time and rows per run, and how many times it ran.
+func (n *planNode) lines(prefix string, root bool) []string {
+	line := fmt.Sprintf("%s  (cost=%.2f rows=%d)", n.operator, n.cost, n.rows)
+	switch {
+	case n.actual == nil:
+	case n.actual.loops == 0:
+		line += " (never executed)"
+	default:
+		loops := n.actual.loops
+		line += fmt.Sprintf(" (actual time=%.3f ms rows=%d loops=%d)",
+			milliseconds(n.actual.time)/float64(loops), n.actual.rows/loops, loops)
+	}
+	detailPrefix := "  "
+	if !root {
+		line = prefix + "->  " + line
+		detailPrefix = prefix + "      "
+	}
+
+	lines := []string{line}
+	for _, detail := range n.details {
+		lines = append(lines, detailPrefix+detail)
+	}
+	for _, child := range n.children {
+		lines = append(lines, child.lines(detailPrefix, false)...)
+	}
+	return lines
+}
+
+// formatExpression writes exp back out as SQL, parenthesizing every
+// operator.
+func formatExpression(exp *Expression) string {
+	switch exp.Kind {
+	case LiteralKind:
+		return exp.Literal.String()
+	case ColumnReferenceKind:
+		return exp.Column.Table.String() + "." + exp.Column.Column.String()
+	case BinaryKind:
+		return fmt.Sprintf("(%s %s %s)", formatExpression(exp.Binary.Left), exp.Binary.Op.Value, formatExpression(exp.Binary.Right))
+	case UnaryKind:
+		if exp.Unary.Op.Value == string(MinusSymbol) {
+			return "(-" + formatExpression(exp.Unary.Operand) + ")"
+		}
+		return fmt.Sprintf("(%s %s)", exp.Unary.Op.Value, formatExpression(exp.Unary.Operand))
+	case IsNullKind:
+		if exp.IsNull.Not {
+			return fmt.Sprintf("(%s is not null)", formatExpression(exp.IsNull.Operand))
+		}
+		return fmt.Sprintf("(%s is null)", formatExpression(exp.IsNull.Operand))
+	case InKind:
+		if exp.In.Select != nil {
+			return fmt.Sprintf("(%s in (subquery))", formatExpression(exp.In.Left))
+		}
+		return fmt.Sprintf("(%s in (%s))", formatExpression(exp.In.Left), formatExpressions(exp.In.List))
+	case BetweenKind:
+		return fmt.Sprintf("(%s between %s and %s)", formatExpression(exp.Between.Left), formatExpression(exp.Between.Low), formatExpression(exp.Between.High))
+	case FunctionKind:
+		if exp.Function.Asterisk {
+			return exp.Function.Name.Value + "(*)"
+		}
+		return exp.Function.Name.Value + "(" + formatExpressions(exp.Function.Args) + ")"
+	case CastKind:
+		return fmt.Sprintf("(%s::%s)", formatExpression(exp.Cast.Operand), exp.Cast.Type.Value)
+	case CaseKind:
+		var b strings.Builder
+		b.WriteString("case")
+		if exp.Case.Operand != nil {
+			b.WriteString(" " + formatExpression(exp.Case.Operand))
+		}
+		for _, when := range exp.Case.Whens {
+			fmt.Fprintf(&b, " when %s then %s", formatExpression(when.When), formatExpression(when.Then))
+		}
+		if exp.Case.Else != nil {
+			b.WriteString(" else " + formatExpression(exp.Case.Else))
+		}
+		b.WriteString(" end")
+		return b.String()
+	}
+	return "?"
+}
+
+// String writes exp back out as SQL.
+func (exp *Expression) String() string {
+	return formatExpression(exp)
+}
+
+func formatExpressions(exps []*Expression) string {
+	var formatted []string
+	for _, exp := range exps {
+		formatted = append(formatted, formatExpression(exp))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func formatSelectItems(items []*SelectItem) string {
+	var formatted []string
+	for _, item := range items {
+		switch {
+		case item.Asterisk:
+			formatted = append(formatted, "*")
+		case item.As != nil:
+			formatted = append(formatted, formatExpression(item.Exp)+" as "+item.As.String())
+		default:
+			formatted = append(formatted, formatExpression(item.Exp))
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,216 @@
+package gosql
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"sort"
+)
+
+// sortKeys orders the keys of rows by the ORDER BY clauses orderBy, the
+// keys of clause j being of type types[j].
+type sortKeys struct {
+	orderBy []*OrderByClause
+	types   []ColumnType
+}
+
+// less reports whether a row with keys a goes before one with keys b.
+func (s sortKeys) less(a, b []MemoryCell) bool {
+	for j, clause := range s.orderBy {
+		ka, kb := a[j], b[j]
+		c := compareCells(ka, kb, s.types[j])
+		if c == 0 {
+			continue
+		}
+		// NULLs go last whichever way the key is sorted, unless it says
+		// NULLS FIRST.
+		if ka.IsNull() || kb.IsNull() {
+			return kb.IsNull() != (clause.Nulls == NullsFirst)
+		}
+		if clause.Desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	return false
+}
+
+// sortPositions stably sorts positions by the keys of the rows they point
+// at.
+func (s sortKeys) sortPositions(ctx context.Context, positions []int, keys [][]MemoryCell) error {
+	// Once the statement is canceled every comparison is cut short, which
+	// lets the sort finish quickly with an order that is then thrown away.
+	compared := 0
+	var err error
+	sort.SliceStable(positions, func(a, b int) bool {
+		if err != nil {
+			return false
+		}
+		if err = canceled(ctx, compared); err != nil {
+			return false
+		}
+		compared++
+		return s.less(keys[positions[a]], keys[positions[b]])
+	})
+	return err
+}
+
+// sortRun is a sorted run of the keys of consecutive rows that a sort has
+// written to a temporary file to stay within its work_mem. Each entry is
+// the position of a row followed by its keys, each written as its length
+// plus one, or 0 for NULL, and its bytes.
+type sortRun struct {
+	f *os.File
+	r *bufio.Reader
+	// position and keys are those of the entry read last.
+	position int
+	keys     []MemoryCell
+}
+
+// spillRun sorts the rows from start to end by their keys and writes them
+// out as a run, dropping their keys from memory.
+func (s sortKeys) spillRun(ctx context.Context, keys [][]MemoryCell, start, end int) (*sortRun, error) {
+	positions := make([]int, end-start)
+	for i := range positions {
+		positions[i] = start + i
+	}
+	if err := s.sortPositions(ctx, positions, keys); err != nil {
+		return nil, err
+	}
+
+	f, err := os.CreateTemp("", "gosql-sort-*")
+	if err != nil {
+		return nil, err
+	}
+	run := &sortRun{f: f}
+	w := bufio.NewWriter(f)
+	var buf []byte
+	for _, p := range positions {
+		buf = binary.AppendUvarint(buf[:0], uint64(p))
+		for _, cell := range keys[p] {
+			if cell.IsNull() {
+				buf = binary.AppendUvarint(buf, 0)
+				continue
+			}
+			buf = binary.AppendUvarint(buf, uint64(len(cell))+1)
+			buf = append(buf, cell...)
+		}
+		if _, err := w.Write(buf); err != nil {
+			run.close()
+			return nil, err
+		}
+		keys[p] = nil
+	}
+	if err := w.Flush(); err != nil {
+		run.close()
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		run.close()
+		return nil, err
+	}
+	run.r = bufio.NewReader(f)
+	return run, nil
+}
+
+// next reads the next entry of the run, of n keys, reporting false once
+// there are no more.
+func (run *sortRun) next(n int) (bool, error) {
+	p, err := binary.ReadUvarint(run.r)
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	run.position = int(p)
+	run.keys = make([]MemoryCell, n)
+	for j := range run.keys {
+		size, err := binary.ReadUvarint(run.r)
+		if err != nil {
+			return false, err
+		}
+		if size == 0 {
+			continue
+		}
+		run.keys[j] = make(MemoryCell, size-1)
+		if _, err := io.ReadFull(run.r, run.keys[j]); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (run *sortRun) close() {
+	run.f.Close()
+	os.Remove(run.f.Name())
+}
+
+// mergeRuns merges the sorted runs into the order of all their rows. The
+// runs hold consecutive rows in turn, so taking the entry of the earlier
+// run first among equal keys keeps the sort stable.
+func (s sortKeys) mergeRuns(ctx context.Context, runs []*sortRun, n int) ([]int, error) {
+	h := &runHeap{keys: s}
+	for i, run := range runs {
+		ok, err := run.next(len(s.orderBy))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			h.runs = append(h.runs, runs[i])
+		}
+	}
+	heap.Init(h)
+
+	order := make([]int, 0, n)
+	for h.Len() > 0 {
+		if err := canceled(ctx, len(order)); err != nil {
+			return nil, err
+		}
+		run := h.runs[0]
+		order = append(order, run.position)
+		ok, err := run.next(len(s.orderBy))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return order, nil
+}
+
+// runHeap holds the runs that still have entries, the one whose entry
+// goes first on top.
+type runHeap struct {
+	keys sortKeys
+	runs []*sortRun
+}
+
+func (h *runHeap) Len() int { return len(h.runs) }
+
+func (h *runHeap) Less(a, b int) bool {
+	ra, rb := h.runs[a], h.runs[b]
+	if h.keys.less(ra.keys, rb.keys) {
+		return true
+	}
+	if h.keys.less(rb.keys, ra.keys) {
+		return false
+	}
+	return ra.position < rb.position
+}
+
+func (h *runHeap) Swap(a, b int) { h.runs[a], h.runs[b] = h.runs[b], h.runs[a] }
+
+func (h *runHeap) Push(x any) { h.runs = append(h.runs, x.(*sortRun)) }
+
+func (h *runHeap) Pop() any {
+	run := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return run
+}
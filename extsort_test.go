@@ -0,0 +1,74 @@
+package gosql
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortOrder_spill(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+
+	// Few distinct keys, with NULLs, so stability matters.
+	n := 2000
+	keys := make([][]MemoryCell, n)
+	for i := range keys {
+		k := MemoryCell(nil)
+		if i%7 != 0 {
+			k = intCell(int32(i * 31 % 13))
+		}
+		keys[i] = []MemoryCell{k, MemoryCell(fmt.Sprintf("%c", 'a'+i%5))}
+	}
+	evaluate := func(i int, exp *Expression) (MemoryCell, ColumnType, error) {
+		if exp.Literal.Value == "k" {
+			return keys[i][0], IntType, nil
+		}
+		return keys[i][1], TextType, nil
+	}
+	column := func(name string) *Expression {
+		return &Expression{Kind: LiteralKind, Literal: &Token{Kind: IdentifierKind, Value: name}}
+	}
+
+	for _, orderBy := range [][]*OrderByClause{
+		{{Exp: column("k")}},
+		{{Exp: column("k"), Desc: true}},
+		{{Exp: column("s"), Desc: true}, {Exp: column("k")}},
+	} {
+		inMemory, err := sortOrder(context.Background(), n, orderBy, evaluate)
+		assert.Nil(t, err)
+		ctx := context.WithValue(context.Background(), workMemKey{}, int64(1024))
+		spilled, err := sortOrder(ctx, n, orderBy, evaluate)
+		assert.Nil(t, err)
+		assert.Equal(t, inMemory, spilled)
+	}
+
+	// The runs are removed once merged.
+	files, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Empty(t, files)
+}
+
+func TestMemoryBackend_orderBySpill(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	mb := NewMemoryBackend()
+	script := "create table t (id int, name text);"
+	for i := 0; i < 500; i++ {
+		script += fmt.Sprintf("insert into t values (%d, 'name%d');", i*7%500, i%10)
+	}
+	_, err := ExecuteScript(mb, script, ScriptOptions{})
+	assert.Nil(t, err)
+
+	query := "select id, name from t order by name desc, id"
+	want, err := ExecuteScript(mb, query, ScriptOptions{})
+	assert.Nil(t, err)
+
+	s := mb.NewSession()
+	got, err := ExecuteScript(s, "set work_mem = 1; "+query, ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, want[0].Results.Rows, got[1].Results.Rows)
+}
@@ -0,0 +1,194 @@
+package gosql
+
+// reference is the foreign key of a column of a stored table: each of its
+// values other than NULL must be held by column of the table called table.
+// The referenced column is named rather than numbered, which is why the
+// columns of a referenced table cannot be dropped or renamed.
+type reference struct {
+	table    string
+	column   string
+	onDelete ReferentialAction
+}
+
+// resolveReference checks fk, the foreign key of a column of type ct in
+// t, and returns what it references. A table may reference itself. The
+// referenced column must be unique, so that each value stands for one row,
+// and its type must be comparable with ct.
+func (mb *MemoryBackend) resolveReference(t *table, fk *ForeignKey, ct ColumnType) (*reference, error) {
+	parent := t
+	if fk.Table.Value != t.name {
+		var ok bool
+		if parent, ok = mb.tables[fk.Table.Value]; !ok {
+			if _, ok := mb.views[fk.Table.Value]; ok {
+				return nil, ErrWrongObjectType
+			}
+			return nil, ErrTableDoesNotExist
+		}
+	}
+	if isTemporary(parent.name) && !isTemporary(t.name) {
+		return nil, ErrTemporaryReference
+	}
+
+	i := parent.primaryKey
+	if fk.Column != nil {
+		if i = parent.columnIndex(fk.Column.Value); i == -1 {
+			return nil, ErrColumnDoesNotExist
+		}
+	}
+	if i == -1 || !parent.unique[i] {
+		return nil, ErrInvalidForeignKey
+	}
+	if !compatible(ct, parent.columnTypes[i]) {
+		return nil, ErrTypeMismatch
+	}
+	return &reference{table: parent.name, column: parent.columns[i], onDelete: fk.OnDelete}, nil
+}
+
+// addReferenceIndex indexes foreign key column i of t unless it already
+// is, so that finding the rows that reference a deleted one does not scan
+// the table.
+func (t *table) addReferenceIndex(i int) {
+	if t.indexOn(i) == nil {
+		t.addIndex(t.name+"_"+t.columns[i]+"_fkey", i)
+	}
+}
+
+// checkReferences fails with ErrViolatesForeignKey unless every foreign
+// key of row, a row of t, references a row tx could see.
+func (mb *MemoryBackend) checkReferences(tx *transaction, t *table, row []MemoryCell) error {
+	for i, ref := range t.references {
+		if ref == nil || row[i].IsNull() {
+			continue
+		}
+		parent := mb.tables[ref.table]
+		j := parent.columnIndex(ref.column)
+		// A value that does not fit the referenced column cannot be in it.
+		value, err := convertCell(row[i], t.columnTypes[i], parent.columnTypes[j])
+		if err != nil || !mb.containsValue(tx, parent, j, value, nil) {
+			return ErrViolatesForeignKey
+		}
+	}
+	return nil
+}
+
+// releaseReferences deals with the rows whose foreign keys reference the
+// versions of t that tx has just deleted or replaced. A value some other
+// row of t still holds is referenced as before. Otherwise, when cascade is
+// set and the foreign key says ON DELETE CASCADE, the referencing rows are
+// deleted as well, and so on down; in every other case they make the
+// statement fail.
+func (mb *MemoryBackend) releaseReferences(tx *transaction, t *table, deleted []*rowVersion, cascade bool) error {
+	for _, child := range mb.tables {
+		for i, ref := range child.references {
+			if ref == nil || ref.table != t.name {
+				continue
+			}
+			j := t.columnIndex(ref.column)
+
+			var cascaded []*rowVersion
+			for _, v := range deleted {
+				value := v.cells[j]
+				if value.IsNull() || mb.containsValue(tx, t, j, value, nil) {
+					continue
+				}
+				value, err := convertCell(value, t.columnTypes[j], child.columnTypes[i])
+				if err != nil {
+					continue
+				}
+
+				for _, w := range mb.holding(tx, child, i, value) {
+					if !cascade || ref.onDelete != CascadeAction {
+						return ErrViolatesForeignKey
+					}
+					// Rows another transaction is still adding or
+					// deleting cannot be deleted yet.
+					if !tx.snapshot.visible(w) || w.xmax != 0 {
+						return ErrSerializationFailure
+					}
+					if err := child.fireTriggers(BeforeTrigger, DeleteEvent, w.cells, nil); err != nil {
+						return err
+					}
+					child.deleteVersion(tx, w)
+					cascaded = append(cascaded, w)
+				}
+			}
+
+			if len(cascaded) > 0 {
+				if err := mb.releaseReferences(tx, child, cascaded, cascade); err != nil {
+					return err
+				}
+			}
+			for _, w := range cascaded {
+				if err := mb.changed(tx, child, DeleteEvent, w.cells, nil); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// foreignKeyTables returns the tables that changing the rows of the table
+// called name reads through foreign keys, and those it writes by
+// cascading deletes to them.
+func (mb *MemoryBackend) foreignKeyTables(name string) (reads, writes []string) {
+	seen := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		written := queue[0]
+		queue = queue[1:]
+
+		if t, ok := mb.tables[written]; ok {
+			for _, ref := range t.references {
+				if ref != nil {
+					reads = append(reads, ref.table)
+				}
+			}
+		}
+		for _, child := range mb.tables {
+			for _, ref := range child.references {
+				if ref == nil || ref.table != written {
+					continue
+				}
+				if ref.onDelete != CascadeAction {
+					reads = append(reads, child.name)
+				} else if !seen[child.name] {
+					seen[child.name] = true
+					writes = append(writes, child.name)
+					queue = append(queue, child.name)
+				}
+			}
+		}
+	}
+	return reads, writes
+}
+
+// referenced reports whether a foreign key of a table other than the one
+// called name references it.
+func (mb *MemoryBackend) referenced(name string) bool {
+	for _, t := range mb.tables {
+		if t.name == name {
+			continue
+		}
+		for _, ref := range t.references {
+			if ref != nil && ref.table == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// columnReferenced reports whether a foreign key of any table, the table
+// itself included, references the column called column of the table
+// called name.
+func (mb *MemoryBackend) columnReferenced(name, column string) bool {
+	for _, t := range mb.tables {
+		for _, ref := range t.references {
+			if ref != nil && ref.table == name && ref.column == column {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForeignKey(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table users (id int primary key, name text);"+
+		"create table posts (id int primary key, author int references users on delete cascade, title text);"+
+		"create table comments (id int primary key, post int references posts (id), body text);"+
+		"insert into users values (1, 'alice'), (2, 'bob');"+
+		"insert into posts values (10, 1, 'hello'), (11, 2, 'hi'), (12, null, 'anonymous');"+
+		"insert into comments values (100, 11, 'nice')", ScriptOptions{})
+	assert.Nil(t, err)
+
+	_, err = ExecuteScript(mb, "insert into posts values (13, 3, 'nobody')", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrViolatesForeignKey)
+	_, err = ExecuteScript(mb, "update posts set author = 3 where id = 10", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrViolatesForeignKey)
+	_, err = ExecuteScript(mb, "update users set id = 3 where id = 1", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrViolatesForeignKey)
+
+	// Deleting bob cascades to his post, which a comment restricts.
+	_, err = ExecuteScript(mb, "delete from users where id = 2", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrViolatesForeignKey)
+
+	results, err := ExecuteScript(mb, "delete from users where id = 1;"+
+		"select id from posts order by id", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(11)}, {intCell(12)}}, results[1].Results.Rows)
+
+	_, err = ExecuteScript(mb, "drop table users", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrDependentObjects)
+
+	_, err = ExecuteScript(mb, "create table bad (user_name text references users (name))", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrInvalidForeignKey)
+	_, err = ExecuteScript(mb, "create table bad (user_id text references users)", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+
+	// Only the referenced columns are kept from changing.
+	_, err = ExecuteScript(mb, "alter table users rename column id to user_id", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrDependentObjects)
+	_, err = ExecuteScript(mb, "alter table users rename column name to full_name;"+
+		"alter table posts drop column title", ScriptOptions{})
+	assert.Nil(t, err)
+}
+
+func TestForeignKey_selfReference(t *testing.T) {
+	mb := NewMemoryBackend()
+	results, err := ExecuteScript(mb, "create table nodes (id int primary key, parent int references nodes on delete cascade);"+
+		"insert into nodes values (1, null), (2, 1), (3, 2), (4, null);"+
+		"delete from nodes where id = 1;"+
+		"select id from nodes", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(4)}}, results[3].Results.Rows)
+
+	_, err = ExecuteScript(mb, "alter table nodes drop column id", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrDependentObjects)
+	_, err = ExecuteScript(mb, "alter table nodes add column note text;"+
+		"alter table nodes rename column note to label;"+
+		"alter table nodes drop column parent", ScriptOptions{})
+	assert.Nil(t, err)
+}
+
+func TestParse_foreignKey(t *testing.T) {
+	ast, err := Parse("create table posts (author int references users (id) on delete cascade)")
+	assert.Nil(t, err)
+	fk := ast.Statements[0].CreateTableStatement.Cols[0].foreignKey()
+	assert.Equal(t, "users", fk.Table.Value)
+	assert.Equal(t, "id", fk.Column.Value)
+	assert.Equal(t, CascadeAction, fk.OnDelete)
+	assert.Equal(t, "CREATE TABLE posts (\n  author INT REFERENCES users (id) ON DELETE CASCADE\n)", Format(ast.Statements[0]))
+
+	_, err = Parse("create table posts (author int references users on delete nothing)")
+	assert.NotNil(t, err)
+}
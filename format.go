@@ -0,0 +1,682 @@
+package gosql
+
+import (
+	"strings"
+)
+
+// formatIndent indents the lines of subqueries and column definitions.
+const formatIndent = "  "
+
+// Format renders stmt as canonical SQL: keywords upper-cased, each clause
+// on a line of its own and parentheses only where operator precedence
+// needs them. Parsing the output gives back an equivalent statement.
+func Format(stmt *Statement) string {
+	return strings.Join(formatStatement(stmt), "\n")
+}
+
+func formatStatement(stmt *Statement) []string {
+	switch stmt.Kind {
+	case SelectKind:
+		return formatSelect(stmt.SelectStatement)
+	case ExplainKind:
+		lines := formatSelect(stmt.ExplainStatement.Select)
+		if stmt.ExplainStatement.Analyze {
+			lines[0] = "ANALYZE " + lines[0]
+		}
+		lines[0] = "EXPLAIN " + lines[0]
+		return lines
+	case InsertKind:
+		return formatInsert(stmt.InsertStatement)
+	case UpdateKind:
+		return formatUpdate(stmt.UpdateStatement)
+	case DeleteKind:
+		lines := []string{"DELETE FROM " + formatTableName(stmt.DeleteStatement.From)}
+		if stmt.DeleteStatement.Where != nil {
+			lines = append(lines, "WHERE "+formatSQLExpression(stmt.DeleteStatement.Where))
+		}
+		if stmt.DeleteStatement.Returning != nil {
+			lines = append(lines, "RETURNING "+formatSQLSelectItems(stmt.DeleteStatement.Returning))
+		}
+		return lines
+	case CreateTableKind:
+		return formatCreateTable(stmt.CreateTableStatement)
+	case CreateIndexKind:
+		crt := stmt.CreateIndexStatement
+		return []string{"CREATE INDEX " + crt.Name.String() + " ON " + formatTableName(crt.Table) + " (" + crt.Column.String() + ")"}
+	case DropTableKind:
+		drp := stmt.DropTableStatement
+		if drp.IfExists {
+			return []string{"DROP TABLE IF EXISTS " + formatTableName(drp.Name)}
+		}
+		return []string{"DROP TABLE " + formatTableName(drp.Name)}
+	case AlterTableKind:
+		return []string{formatAlterTable(stmt.AlterTableStatement)}
+	case CopyKind:
+		return []string{formatCopy(stmt.CopyStatement)}
+	case ShowTablesKind:
+		return []string{"SHOW TABLES"}
+	case DescribeKind:
+		return []string{"DESCRIBE " + formatTableName(stmt.DescribeStatement.Table)}
+	case SetKind:
+		return []string{"SET " + stmt.SetStatement.Name.String() + " = " + stmt.SetStatement.Value.String()}
+	case CreateDatabaseKind:
+		if stmt.CreateDatabaseStatement.IfNotExists {
+			return []string{"CREATE DATABASE IF NOT EXISTS " + stmt.CreateDatabaseStatement.Name.String()}
+		}
+		return []string{"CREATE DATABASE " + stmt.CreateDatabaseStatement.Name.String()}
+	case DropDatabaseKind:
+		if stmt.DropDatabaseStatement.IfExists {
+			return []string{"DROP DATABASE IF EXISTS " + stmt.DropDatabaseStatement.Name.String()}
+		}
+		return []string{"DROP DATABASE " + stmt.DropDatabaseStatement.Name.String()}
+	case UseKind:
+		return []string{"USE " + stmt.UseStatement.Name.String()}
+	case CreateUserKind:
+		crt := stmt.CreateUserStatement
+		s := "CREATE USER " + crt.Name.String()
+		if crt.Password != nil {
+			s += " PASSWORD " + crt.Password.String()
+		}
+		if crt.Superuser {
+			s += " SUPERUSER"
+		}
+		return []string{s}
+	case DropUserKind:
+		if stmt.DropUserStatement.IfExists {
+			return []string{"DROP USER IF EXISTS " + stmt.DropUserStatement.Name.String()}
+		}
+		return []string{"DROP USER " + stmt.DropUserStatement.Name.String()}
+	case GrantKind, RevokeKind:
+		return []string{formatGrant(stmt.GrantStatement, stmt.Kind == RevokeKind)}
+	case ShowKind:
+		if stmt.ShowStatement.Name == nil {
+			return []string{"SHOW ALL"}
+		}
+		return []string{"SHOW " + stmt.ShowStatement.Name.String()}
+	case CreateViewKind:
+		crt := stmt.CreateViewStatement
+		return append([]string{"CREATE VIEW " + formatTableName(crt.Name) + " AS"}, formatSelect(crt.Select)...)
+	case DropViewKind:
+		drp := stmt.DropViewStatement
+		if drp.IfExists {
+			return []string{"DROP VIEW IF EXISTS " + formatTableName(drp.Name)}
+		}
+		return []string{"DROP VIEW " + formatTableName(drp.Name)}
+	case BeginKind:
+		return []string{"BEGIN"}
+	case CommitKind:
+		return []string{"COMMIT"}
+	case RollbackKind:
+		return []string{"ROLLBACK"}
+	case SavepointKind:
+		return []string{"SAVEPOINT " + stmt.SavepointStatement.Name.String()}
+	case ReleaseSavepointKind:
+		return []string{"RELEASE SAVEPOINT " + stmt.SavepointStatement.Name.String()}
+	case RollbackToSavepointKind:
+		return []string{"ROLLBACK TO SAVEPOINT " + stmt.SavepointStatement.Name.String()}
+	}
+	return nil
+}
+
+func indentLines(lines []string) []string {
+	indented := make([]string, len(lines))
+	for i, line := range lines {
+		indented[i] = formatIndent + line
+	}
+	return indented
+}
+
+func formatSelect(slct *SelectStatement) []string {
+	var lines []string
+	for i, cte := range slct.With {
+		if i == 0 {
+			lines = append(lines, "WITH "+cte.Name.String()+" AS (")
+		} else {
+			lines[len(lines)-1] = "), " + cte.Name.String() + " AS ("
+		}
+		lines = append(lines, indentLines(formatSelect(cte.Select))...)
+		lines = append(lines, ")")
+	}
+	return append(lines, formatQuery(slct)...)
+}
+
+// formatQuery renders slct after its WITH clause.
+func formatQuery(slct *SelectStatement) []string {
+	var lines []string
+	if slct.Set != nil {
+		lines = formatSelect(slct.Set.Left)
+		op := strings.ToUpper(slct.Set.Op.Value)
+		if slct.Set.All {
+			op += " ALL"
+		}
+		lines = append(lines, op)
+		lines = append(lines, formatSelect(slct.Set.Right)...)
+	} else {
+		lines = formatSelectCore(slct)
+	}
+
+	if len(slct.OrderBy) > 0 {
+		lines = append(lines, "ORDER BY "+formatOrderBy(slct.OrderBy))
+	}
+	if slct.Limit != nil {
+		lines = append(lines, "LIMIT "+formatSQLExpression(slct.Limit))
+	}
+	if slct.Offset != nil {
+		lines = append(lines, "OFFSET "+formatSQLExpression(slct.Offset))
+	}
+	return lines
+}
+
+// formatOrderBy renders ORDER BY keys, without ORDER BY.
+func formatOrderBy(orderBy []*OrderByClause) string {
+	var keys []string
+	for _, key := range orderBy {
+		s := formatSQLExpression(key.Exp)
+		if key.Desc {
+			s += " DESC"
+		}
+		switch key.Nulls {
+		case NullsFirst:
+			s += " NULLS FIRST"
+		case NullsLast:
+			s += " NULLS LAST"
+		}
+		keys = append(keys, s)
+	}
+	return strings.Join(keys, ", ")
+}
+
+// formatWindow renders the inside of an OVER clause.
+func formatWindow(w *Window) string {
+	var clauses []string
+	if len(w.PartitionBy) > 0 {
+		clauses = append(clauses, "PARTITION BY "+formatSQLExpressions(w.PartitionBy))
+	}
+	if len(w.OrderBy) > 0 {
+		clauses = append(clauses, "ORDER BY "+formatOrderBy(w.OrderBy))
+	}
+	return strings.Join(clauses, " ")
+}
+
+// formatSelectCore renders the clauses of slct up to HAVING.
+func formatSelectCore(slct *SelectStatement) []string {
+	first := "SELECT "
+	if len(slct.Hints) > 0 {
+		first += formatHints(slct.Hints) + " "
+	}
+	if slct.Distinct {
+		first += "DISTINCT "
+	}
+	lines := []string{first + formatSQLSelectItems(slct.Item)}
+
+	if slct.From == nil {
+		lines = append(lines, "FROM (")
+		lines = append(lines, indentLines(formatSelect(slct.FromSelect))...)
+		lines = append(lines, ")"+formatAlias(slct.FromAs))
+	} else {
+		lines = append(lines, "FROM "+formatTableName(slct.From)+formatAlias(slct.FromAs))
+	}
+
+	for _, join := range slct.Join {
+		var kind string
+		switch join.Kind {
+		case LeftJoin:
+			kind = "LEFT "
+		case RightJoin:
+			kind = "RIGHT "
+		}
+		lines = append(lines, kind+"JOIN "+formatTableName(join.Table)+formatAlias(join.As)+" ON "+formatSQLExpression(join.On))
+	}
+
+	if slct.Where != nil {
+		lines = append(lines, "WHERE "+formatSQLExpression(slct.Where))
+	}
+	if len(slct.GroupBy) > 0 {
+		lines = append(lines, "GROUP BY "+formatSQLExpressions(slct.GroupBy))
+	}
+	if slct.Having != nil {
+		lines = append(lines, "HAVING "+formatSQLExpression(slct.Having))
+	}
+	return lines
+}
+
+// formatHints writes hints as the comment they are given in, with their
+// names in upper case and their arguments separated by spaces.
+func formatHints(hints []*Hint) string {
+	var parts []string
+	for _, hint := range hints {
+		part := strings.ToUpper(hint.Name.Value)
+		if len(hint.Args) > 0 {
+			var args []string
+			for _, arg := range hint.Args {
+				args = append(args, arg.String())
+			}
+			part += "(" + strings.Join(args, " ") + ")"
+		}
+		parts = append(parts, part)
+	}
+	return "/*+ " + strings.Join(parts, " ") + " */"
+}
+
+func formatAlias(as *Token) string {
+	if as == nil {
+		return ""
+	}
+	return " AS " + as.String()
+}
+
+func formatSQLSelectItems(items []*SelectItem) string {
+	var formatted []string
+	for _, item := range items {
+		if item.Asterisk {
+			formatted = append(formatted, "*")
+			continue
+		}
+		formatted = append(formatted, formatSQLExpression(item.Exp)+formatAlias(item.As))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func formatInsert(inst *InsertStatement) []string {
+	first := "INSERT INTO " + formatTableName(inst.Table)
+	if inst.Columns != nil {
+		first += " (" + formatNames(inst.Columns) + ")"
+	}
+	lines := []string{first}
+
+	switch {
+	case inst.Select != nil:
+		lines = append(lines, formatSelect(inst.Select)...)
+	case len(inst.Values) == 1:
+		lines = append(lines, "VALUES ("+formatSQLExpressions(inst.Values[0])+")")
+	default:
+		// Several rows go on lines of their own.
+		lines = append(lines, "VALUES")
+		for i, row := range inst.Values {
+			line := formatIndent + "(" + formatSQLExpressions(row) + ")"
+			if i < len(inst.Values)-1 {
+				line += ","
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	if c := inst.OnConflict; c != nil {
+		line := "ON CONFLICT"
+		if c.Target != nil {
+			line += " (" + c.Target.String() + ")"
+		}
+		if !c.DoUpdate {
+			lines = append(lines, line+" DO NOTHING")
+		} else {
+			lines = append(lines, line+" DO UPDATE", formatIndent+"SET "+formatAssignments(c.Set))
+			if c.Where != nil {
+				lines = append(lines, formatIndent+"WHERE "+formatSQLExpression(c.Where))
+			}
+		}
+	}
+	if inst.Returning != nil {
+		lines = append(lines, "RETURNING "+formatSQLSelectItems(inst.Returning))
+	}
+	return lines
+}
+
+// formatTableName writes the name of a table or view, which
+// parseTableName joins into one token when it is qualified by a database
+// or schema.
+func formatTableName(name *Token) string {
+	var parts []string
+	for _, part := range strings.Split(name.Value, ".") {
+		parts = append(parts, (&Token{Value: part, Kind: IdentifierKind}).String())
+	}
+	return strings.Join(parts, ".")
+}
+
+func formatNames(names []*Token) string {
+	var formatted []string
+	for _, name := range names {
+		formatted = append(formatted, name.String())
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func formatUpdate(updt *UpdateStatement) []string {
+	lines := []string{"UPDATE " + formatTableName(updt.Table), "SET " + formatAssignments(updt.Set)}
+	if updt.Where != nil {
+		lines = append(lines, "WHERE "+formatSQLExpression(updt.Where))
+	}
+	if updt.Returning != nil {
+		lines = append(lines, "RETURNING "+formatSQLSelectItems(updt.Returning))
+	}
+	return lines
+}
+
+func formatAssignments(set []*Assignment) string {
+	var assignments []string
+	for _, assignment := range set {
+		assignments = append(assignments, assignment.Column.String()+" = "+formatSQLExpression(assignment.Value))
+	}
+	return strings.Join(assignments, ", ")
+}
+
+func formatCreateTable(crt *CreateTableStatement) []string {
+	first := "CREATE TABLE "
+	if crt.Temporary {
+		first = "CREATE TEMPORARY TABLE "
+	}
+	if crt.IfNotExists {
+		first += "IF NOT EXISTS "
+	}
+	var items []string
+	for _, col := range crt.Cols {
+		items = append(items, formatColumnDefinition(col))
+	}
+	for _, check := range crt.Checks {
+		items = append(items, "CHECK ("+formatSQLExpression(check)+")")
+	}
+
+	lines := []string{first + formatTableName(crt.Name) + " ("}
+	for i, item := range items {
+		line := formatIndent + item
+		if i < len(items)-1 {
+			line += ","
+		}
+		lines = append(lines, line)
+	}
+	last := ")"
+	if crt.Layout != nil {
+		last += " WITH (layout = " + crt.Layout.String() + ")"
+	}
+	if crt.OnCommitDrop {
+		last += " ON COMMIT DROP"
+	}
+	return append(lines, last)
+}
+
+func formatColumnDefinition(col *ColumnDefinition) string {
+	s := col.Name.String() + " " + strings.ToUpper(col.Datatype.Value)
+	if col.Length != nil {
+		s += "(" + col.Length.Value + ")"
+	}
+	for _, c := range col.Constraints {
+		switch c.Kind {
+		case NotNullConstraint:
+			s += " NOT NULL"
+		case PrimaryKeyConstraint:
+			s += " PRIMARY KEY"
+		case UniqueConstraint:
+			s += " UNIQUE"
+		case DefaultConstraint:
+			s += " DEFAULT " + formatSQLExpression(c.Default)
+		case AutoIncrementConstraint:
+			s += " AUTO_INCREMENT"
+		case ReferencesConstraint:
+			s += " REFERENCES " + formatTableName(c.References.Table)
+			if c.References.Column != nil {
+				s += " (" + c.References.Column.String() + ")"
+			}
+			if c.References.OnDelete == CascadeAction {
+				s += " ON DELETE CASCADE"
+			}
+		case CheckConstraint:
+			s += " CHECK (" + formatSQLExpression(c.Check) + ")"
+		case CollateConstraint:
+			s += " COLLATE " + c.Collation.String()
+		}
+	}
+	return s
+}
+
+func formatAlterTable(alt *AlterTableStatement) string {
+	s := "ALTER TABLE " + formatTableName(alt.Table)
+	switch alt.Action {
+	case AddColumnAction:
+		return s + " ADD COLUMN " + formatColumnDefinition(alt.Add)
+	case DropColumnAction:
+		return s + " DROP COLUMN " + alt.Column.String()
+	}
+	return s + " RENAME COLUMN " + alt.Column.String() + " TO " + alt.To.String()
+}
+
+func formatGrant(grant *GrantStatement, revoke bool) string {
+	privileges := "ALL"
+	if grant.Privileges != nil {
+		var names []string
+		for _, p := range grant.Privileges {
+			names = append(names, strings.ToUpper(p.Value))
+		}
+		privileges = strings.Join(names, ", ")
+	}
+	if revoke {
+		return "REVOKE " + privileges + " ON " + formatTableName(grant.Table) + " FROM " + grant.User.String()
+	}
+	return "GRANT " + privileges + " ON " + formatTableName(grant.Table) + " TO " + grant.User.String()
+}
+
+func formatCopy(cp *CopyStatement) string {
+	s := "COPY " + formatTableName(cp.Table)
+	if cp.Columns != nil {
+		s += " (" + formatNames(cp.Columns) + ")"
+	}
+	if cp.To {
+		s += " TO "
+	} else {
+		s += " FROM "
+	}
+	s += cp.File.String()
+
+	var options []string
+	if cp.Delimiter != nil {
+		options = append(options, "DELIMITER "+cp.Delimiter.String())
+	}
+	if cp.Header {
+		options = append(options, "HEADER")
+	}
+	if cp.Null != nil {
+		options = append(options, "NULL "+cp.Null.String())
+	}
+	if options != nil {
+		s += " WITH (" + strings.Join(options, ", ") + ")"
+	}
+	return s
+}
+
+// precedence is the binding power of the operator at the top of exp, as
+// parseExpression assigns it. Operands like literals and function calls
+// bind tightest.
+func precedence(exp *Expression) uint {
+	switch exp.Kind {
+	case BinaryKind:
+		return exp.Binary.Op.bindingPower()
+	case UnaryKind:
+		if isNotInfix(exp) {
+			return keywordPower(LikeKeyword)
+		}
+		if exp.Unary.Op.Value == string(MinusSymbol) {
+			return symbolPower(AsteriskSymbol)
+		}
+		// NOT takes in everything that binds tighter than AND.
+		return keywordPower(AndKeyword)
+	case InKind, BetweenKind, IsNullKind:
+		return keywordPower(InKeyword)
+	case CastKind:
+		return symbolPower(CastSymbol)
+	}
+	return symbolPower(CastSymbol) + 1
+}
+
+func keywordPower(k keyword) uint {
+	t := tokenFromKeyword(k)
+	return t.bindingPower()
+}
+
+func symbolPower(s Symbol) uint {
+	t := tokenFromSymbol(s)
+	return t.bindingPower()
+}
+
+// isNotInfix reports whether exp is NOT applied to a LIKE, IN or BETWEEN,
+// which is written x NOT LIKE y and so on.
+func isNotInfix(exp *Expression) bool {
+	if exp.Kind != UnaryKind || exp.Unary.Op.Value != string(NotKeyword) {
+		return false
+	}
+	operand := exp.Unary.Operand
+	switch operand.Kind {
+	case InKind, BetweenKind:
+		return true
+	case BinaryKind:
+		return operand.Binary.Op.Value == string(LikeKeyword)
+	}
+	return false
+}
+
+// formatOperand formats exp as an operand that is parsed with minBp,
+// parenthesizing it when its operator would not bind tightly enough.
+// Operators at the same level associate to the left, so a right operand
+// needs parentheses where a left one at the same level does not.
+func formatOperand(exp *Expression, minBp uint, right bool) string {
+	p := precedence(exp)
+	if p < minBp || (right && p == minBp) {
+		return "(" + formatSQLExpression(exp) + ")"
+	}
+	return formatSQLExpression(exp)
+}
+
+// formatSQLExpression writes exp back out as SQL with keywords upper-cased
+// and only the parentheses it needs.
+func formatSQLExpression(exp *Expression) string {
+	switch exp.Kind {
+	case LiteralKind:
+		if exp.Literal.Kind == KeywordKind || exp.Literal.Kind == BoolKind {
+			return strings.ToUpper(exp.Literal.Value)
+		}
+		return exp.Literal.String()
+	case ColumnReferenceKind:
+		return exp.Column.Table.String() + "." + exp.Column.Column.String()
+	case BinaryKind:
+		bp := exp.Binary.Op.bindingPower()
+		return formatOperand(exp.Binary.Left, bp, false) + " " + formatOperator(exp.Binary.Op) + " " + formatRightOperand(exp.Binary.Right, bp)
+	case UnaryKind:
+		if isNotInfix(exp) {
+			return formatInfix(exp.Unary.Operand, " NOT")
+		}
+		operand := exp.Unary.Operand
+		if exp.Unary.Op.Value == string(MinusSymbol) {
+			// Two minus signs in a row would start a comment.
+			if operand.Kind == UnaryKind {
+				return "-(" + formatSQLExpression(operand) + ")"
+			}
+			return "-" + formatOperand(operand, precedence(exp), true)
+		}
+		if operand.Kind == UnaryKind && !isNotInfix(operand) {
+			return "NOT " + formatSQLExpression(operand)
+		}
+		return "NOT " + formatOperand(operand, precedence(exp), true)
+	case IsNullKind:
+		left := formatOperand(exp.IsNull.Operand, precedence(exp), false)
+		if exp.IsNull.Not {
+			return left + " IS NOT NULL"
+		}
+		return left + " IS NULL"
+	case InKind, BetweenKind:
+		return formatInfix(exp, "")
+	case FunctionKind:
+		fn := exp.Function
+		if isExtractCall(fn) {
+			return "EXTRACT(" + fn.Args[0].Literal.Value + " FROM " + formatSQLExpression(fn.Args[1]) + ")"
+		}
+		if isCurrentTimeCall(fn) {
+			return strings.ToUpper(fn.Name.Value)
+		}
+		call := fn.Name.String() + "(" + formatSQLExpressions(fn.Args) + ")"
+		if fn.Asterisk {
+			call = fn.Name.String() + "(*)"
+		}
+		if fn.Over != nil {
+			call += " OVER (" + formatWindow(fn.Over) + ")"
+		}
+		return call
+	case CastKind:
+		return formatOperand(exp.Cast.Operand, precedence(exp), false) + "::" + strings.ToUpper(exp.Cast.Type.Value)
+	case CaseKind:
+		// Every part is delimited by keywords, so none needs parentheses.
+		s := "CASE"
+		if exp.Case.Operand != nil {
+			s += " " + formatSQLExpression(exp.Case.Operand)
+		}
+		for _, when := range exp.Case.Whens {
+			s += " WHEN " + formatSQLExpression(when.When) + " THEN " + formatSQLExpression(when.Then)
+		}
+		if exp.Case.Else != nil {
+			s += " ELSE " + formatSQLExpression(exp.Case.Else)
+		}
+		return s + " END"
+	}
+	return "?"
+}
+
+// formatInfix formats a LIKE, IN or BETWEEN with not, either " NOT" or
+// nothing, written before its keyword.
+func formatInfix(exp *Expression, not string) string {
+	bp := precedence(exp)
+	switch exp.Kind {
+	case InKind:
+		left := formatOperand(exp.In.Left, bp, false)
+		if exp.In.Select != nil {
+			var clauses []string
+			for _, line := range formatSelect(exp.In.Select) {
+				clauses = append(clauses, strings.TrimSpace(line))
+			}
+			return left + not + " IN (" + strings.Join(clauses, " ") + ")"
+		}
+		return left + not + " IN (" + formatSQLExpressions(exp.In.List) + ")"
+	case BetweenKind:
+		// The low bound stops at AND, which separates it from the high
+		// one.
+		low := formatOperand(exp.Between.Low, keywordPower(AndKeyword), true)
+		return formatOperand(exp.Between.Left, bp, false) + not + " BETWEEN " + low + " AND " + formatRightOperand(exp.Between.High, bp)
+	}
+	like := exp.Binary
+	return formatOperand(like.Left, bp, false) + not + " LIKE " + formatRightOperand(like.Right, bp)
+}
+
+// formatRightOperand formats the right operand of a binary operator. A
+// prefix operator there needs no parentheses of its own unless it would
+// take in the operators that follow it, as NOT does with comparisons.
+func formatRightOperand(exp *Expression, bp uint) string {
+	if exp.Kind == UnaryKind && !isNotInfix(exp) && precedence(exp) >= bp {
+		return formatSQLExpression(exp)
+	}
+	if exp.Kind == UnaryKind && exp.Unary.Op.Value == string(MinusSymbol) {
+		return formatSQLExpression(exp)
+	}
+	return formatOperand(exp, bp, true)
+}
+
+// isExtractCall reports whether fn can be written as EXTRACT(field FROM
+// source), the way parseExtractExpression reads it.
+func isExtractCall(fn *FunctionExpression) bool {
+	return fn.Name.Value == "extract" && len(fn.Args) == 2 &&
+		fn.Args[0].Kind == LiteralKind && fn.Args[0].Literal.Kind == StringKind &&
+		!needsQuoting(fn.Args[0].Literal.Value)
+}
+
+func isCurrentTimeCall(fn *FunctionExpression) bool {
+	return (fn.Name.Value == "current_timestamp" || fn.Name.Value == "current_date") && isTimeCall(fn)
+}
+
+func formatOperator(op *Token) string {
+	if op.Kind == KeywordKind {
+		return strings.ToUpper(op.Value)
+	}
+	return op.Value
+}
+
+func formatSQLExpressions(exps []*Expression) string {
+	var formatted []string
+	for _, exp := range exps {
+		formatted = append(formatted, formatSQLExpression(exp))
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,159 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		source string
+		result string
+	}{
+		{
+			"select distinct u.id, name as n from users u join orders o on u.id = o.user_id where not active and (id = 1 or id = 2) order by name desc, id limit 10 offset 5",
+			`SELECT DISTINCT u.id, name AS n
+FROM users AS u
+JOIN orders AS o ON u.id = o.user_id
+WHERE NOT active AND (id = 1 OR id = 2)
+ORDER BY name DESC, id
+LIMIT 10
+OFFSET 5`,
+		},
+		{
+			"select kind, count(*) from (select kind from items) as s left outer join t on true group by kind having count(*) > 1",
+			`SELECT kind, count(*)
+FROM (
+  SELECT kind
+  FROM items
+) AS s
+LEFT JOIN t ON TRUE
+GROUP BY kind
+HAVING count(*) > 1`,
+		},
+		{
+			"insert into users (id, name) values (1, 'it''s'), (2, null) returning id",
+			`INSERT INTO users (id, name)
+VALUES
+  (1, 'it''s'),
+  (2, NULL)
+RETURNING id`,
+		},
+		{
+			"insert into archive select * from users where id in (select id from old)",
+			`INSERT INTO archive
+SELECT *
+FROM users
+WHERE id IN (SELECT id FROM old)`,
+		},
+		{
+			"update users set name = 'x', age = age + 1 where id = $1",
+			"UPDATE users\nSET name = 'x', age = age + 1\nWHERE id = $1",
+		},
+		{"delete from users", "DELETE FROM users"},
+		{
+			"select * from information_schema.tables t join information_schema.columns c on t.table_name = c.table_name",
+			"SELECT *\nFROM information_schema.tables AS t\nJOIN information_schema.columns AS c ON t.table_name = c.table_name",
+		},
+		{
+			`create table if not exists "Users" (id int primary key, name varchar(20) not null unique, joined date default date '2024-01-01')`,
+			`CREATE TABLE IF NOT EXISTS "Users" (
+  id INT PRIMARY KEY,
+  name VARCHAR(20) NOT NULL UNIQUE,
+  joined DATE DEFAULT '2024-01-01'::DATE
+)`,
+		},
+		{"create table events (at timestamp, value float) with (layout = 'column')", "CREATE TABLE events (\n  at TIMESTAMP,\n  value FLOAT\n) WITH (layout = 'column')"},
+		{"create index users_name on users (name)", "CREATE INDEX users_name ON users (name)"},
+		{"drop table if exists users", "DROP TABLE IF EXISTS users"},
+		{"alter table users add column age integer default 0", "ALTER TABLE users ADD COLUMN age INTEGER DEFAULT 0"},
+		{"alter table users rename column age to years", "ALTER TABLE users RENAME COLUMN age TO years"},
+		{"copy users from 'users.csv'", "COPY users FROM 'users.csv'"},
+		{"copy users (id, name) to 'out.csv' csv header delimiter ';' null ''", "COPY users (id, name) TO 'out.csv' WITH (DELIMITER ';', HEADER, NULL '')"},
+		{"select id from t order by a nulls first, b desc nulls last", "SELECT id\nFROM t\nORDER BY a NULLS FIRST, b DESC NULLS LAST"},
+		{"show tables", "SHOW TABLES"},
+		{"desc information_schema.tables", "DESCRIBE information_schema.tables"},
+		{"set statement_timeout to '5s'", "SET statement_timeout = '5s'"},
+		{"begin transaction", "BEGIN"},
+		{"savepoint a", "SAVEPOINT a"},
+		{"rollback transaction to a", "ROLLBACK TO SAVEPOINT a"},
+		{"release savepoint a", "RELEASE SAVEPOINT a"},
+		{"update users set age = age + 1 where id = 1 returning id, age", "UPDATE users\nSET age = age + 1\nWHERE id = 1\nRETURNING id, age"},
+		{"delete from users returning *", "DELETE FROM users\nRETURNING *"},
+		{"insert into users values (1, 'a') on conflict do nothing", "INSERT INTO users\nVALUES (1, 'a')\nON CONFLICT DO NOTHING"},
+		{"insert into users values (1, 'a') on conflict (id) do update set name = excluded.name where users.name <> excluded.name returning id",
+			"INSERT INTO users\nVALUES (1, 'a')\nON CONFLICT (id) DO UPDATE\n  SET name = excluded.name\n  WHERE users.name <> excluded.name\nRETURNING id"},
+		{"create user alice with superuser password 'secret'", "CREATE USER alice PASSWORD 'secret' SUPERUSER"},
+		{"drop user if exists alice", "DROP USER IF EXISTS alice"},
+		{"grant select, insert on table users to alice", "GRANT SELECT, INSERT ON users TO alice"},
+		{"revoke all privileges on users from alice", "REVOKE ALL ON users FROM alice"},
+		{"explain select * from users", "EXPLAIN SELECT *\nFROM users"},
+		{"explain analyze select * from users", "EXPLAIN ANALYZE SELECT *\nFROM users"},
+		{"select current_timestamp, current_date, now() from t", "SELECT CURRENT_TIMESTAMP, CURRENT_DATE, now()\nFROM t"},
+	}
+	for _, test := range tests {
+		ast, err := Parse(test.source)
+		if !assert.Nil(t, err, test.source) {
+			continue
+		}
+		result := Format(ast.Statements[0])
+		assert.Equal(t, test.result, result, test.source)
+
+		// Formatting is idempotent.
+		again, err := Parse(result)
+		if assert.Nil(t, err, result) {
+			assert.Equal(t, result, Format(again.Statements[0]))
+		}
+	}
+}
+
+func TestFormat_expressions(t *testing.T) {
+	tests := []struct {
+		source string
+		result string
+	}{
+		{"(a + b) * c - (d - e)", "(a + b) * c - (d - e)"},
+		{"((a * b)) + c", "a * b + c"},
+		{"a - (b + c)", "a - (b + c)"},
+		{"-(a * b) + -c", "-(a * b) + -c"},
+		{"-(-a)", "-(-a)"},
+		{"(-a)::text", "(-a)::TEXT"},
+		{"cast(a + 1 as int)::text", "(a + 1)::INT::TEXT"},
+		{"not (a and b) or c", "NOT (a AND b) OR c"},
+		{"(not a) = b", "(NOT a) = b"},
+		{"a = (not b)", "a = (NOT b)"},
+		{"a and not b = c", "a AND NOT b = c"},
+		{"not not a", "NOT NOT a"},
+		{"name not like 'a%' and x is not null", "name NOT LIKE 'a%' AND x IS NOT NULL"},
+		{"(a = b) is null", "a = b IS NULL"},
+		{"a = (b is null)", "a = (b IS NULL)"},
+		{"x between (a and b) and c", "x BETWEEN (a AND b) AND c"},
+		{"x between a + 1 and (y between 1 and 2)", "x BETWEEN a + 1 AND (y BETWEEN 1 AND 2)"},
+		{"x in (1, 2 + 3)", "x IN (1, 2 + 3)"},
+		{"x not in (1) and not y not between 1 and 2", "x NOT IN (1) AND NOT y NOT BETWEEN 1 AND 2"},
+		{"y = (not x in (1))", "y = (x NOT IN (1))"},
+		{"a || (b || c)", "a || (b || c)"},
+		{"extract(year from now()) + date_part('dow', d)", "EXTRACT(year FROM now()) + date_part('dow', d)"},
+		{"timestamp '2024-01-01 10:00'", "'2024-01-01 10:00'::TIMESTAMP"},
+		{"doc->'a'->>0 = 'x' || y", "doc -> 'a' ->> 0 = 'x' || y"},
+		{"doc -> ('a' || b)", "doc -> ('a' || b)"},
+		{"json '{}' -> 'a'", "'{}'::JSON -> 'a'"},
+	}
+	for _, test := range tests {
+		ast, err := Parse("select " + test.source + " from t")
+		if !assert.Nil(t, err, test.source) {
+			continue
+		}
+		exp := ast.Statements[0].SelectStatement.Item[0].Exp
+		result := formatSQLExpression(exp)
+		assert.Equal(t, test.result, result, test.source)
+
+		// The output parses back to the same tree, which formatExpression
+		// shows with every operator parenthesized.
+		again, err := Parse("select " + result + " from t")
+		if assert.Nil(t, err, result) {
+			assert.Equal(t, formatExpression(exp), formatExpression(again.Statements[0].SelectStatement.Item[0].Exp), test.source)
+		}
+	}
+}
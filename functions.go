@@ -0,0 +1,527 @@
+package gosql
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Function is a scalar function that expressions can call by name. It is
+// called once per row with the values of its arguments, which arrive as
+// int32, int64, float64, string, []byte, bool, time.Time, or nil for
+// NULL, converted to the types in Args. Dates arrive as midnight UTC, JSON
+// documents as their text and binary strings as []byte.
+type Function struct {
+	// Args is the type of each argument. Numbers are widened to fit, so
+	// an int can be passed for a float. NullType accepts a value of any
+	// type.
+	Args []ColumnType
+	// Optional is how many of the last Args may be left out.
+	Optional int
+	// Variadic functions take any number of arguments of the last type in
+	// Args, but at least one.
+	Variadic bool
+	// Returns is the type of the result. A function that takes arguments
+	// of any type may return NullType to return the type they share.
+	Returns ColumnType
+	// CalledOnNull functions are called even when an argument is NULL.
+	// Others return NULL without being called.
+	CalledOnNull bool
+	// Call computes the result, which may be an int, int32, int64,
+	// float64, string, []byte, bool, time.Time, or nil for NULL.
+	Call func(args []interface{}) (interface{}, error)
+	// Overloads are other signatures of the function, each with its own
+	// Call, tried in order when the arguments do not fit Args.
+	Overloads []Function
+}
+
+// aggregateFunctions are computed over groups of rows by the aggregate
+// code rather than called per row.
+var aggregateFunctions = map[string]bool{
+	"count": true,
+	"sum":   true,
+	"avg":   true,
+	"min":   true,
+	"max":   true,
+}
+
+// windowFunctions are computed over the partition of each row by the
+// window code, and are only called with OVER. Aggregate functions can be
+// too.
+var windowFunctions = map[string]bool{
+	"row_number": true,
+	"rank":       true,
+	"dense_rank": true,
+}
+
+// isAggregate reports whether fn calls an aggregate function over a group
+// of rows, rather than as a window function.
+func (fn *FunctionExpression) isAggregate() bool {
+	return aggregateFunctions[fn.Name.Value] && fn.Over == nil
+}
+
+var (
+	functionsMu sync.RWMutex
+	functions   = map[string]*Function{}
+)
+
+// RegisterFunction makes fn callable from every backend under name, which
+// is case insensitive. The name must not be taken by another function.
+func RegisterFunction(name string, fn Function) error {
+	name = strings.ToLower(name)
+
+	functionsMu.Lock()
+	defer functionsMu.Unlock()
+
+	if _, ok := functions[name]; ok || aggregateFunctions[name] || windowFunctions[name] {
+		return fmt.Errorf("%w: %s", ErrFunctionAlreadyExists, name)
+	}
+	functions[name] = &fn
+	return nil
+}
+
+func lookupFunction(name string) (*Function, bool) {
+	functionsMu.RLock()
+	defer functionsMu.RUnlock()
+
+	fn, ok := functions[name]
+	return fn, ok
+}
+
+// resultType checks the types of the arguments of a call to fn and returns
+// the type of its result.
+func (fn *Function) resultType(name string, args []ColumnType) (ColumnType, error) {
+	least := len(fn.Args) - fn.Optional
+	switch {
+	case fn.Variadic:
+		if len(args) < least {
+			return 0, fmt.Errorf("%w: %s takes at least %d arguments, got %d", ErrInvalidArguments, name, least, len(args))
+		}
+	case fn.Optional > 0:
+		if len(args) < least || len(args) > len(fn.Args) {
+			return 0, fmt.Errorf("%w: %s takes %d to %d arguments, got %d", ErrInvalidArguments, name, least, len(fn.Args), len(args))
+		}
+	case len(args) != len(fn.Args):
+		return 0, fmt.Errorf("%w: %s takes %d arguments, got %d", ErrInvalidArguments, name, len(fn.Args), len(args))
+	}
+
+	shared := NullType
+	for i, ct := range args {
+		want := fn.Args[len(fn.Args)-1]
+		if i < len(fn.Args) {
+			want = fn.Args[i]
+		}
+		if !implicit(ct, want) {
+			return 0, fmt.Errorf("%w: argument %d of %s must be %s, got %s", ErrTypeMismatch, i+1, name, want, ct)
+		}
+		if want != NullType || fn.Returns != NullType {
+			continue
+		}
+		common, ok := commonType(shared, ct)
+		if !ok {
+			return 0, fmt.Errorf("%w: arguments of %s must share a type, got %s and %s", ErrTypeMismatch, name, shared, ct)
+		}
+		shared = common
+	}
+
+	if fn.Returns == NullType {
+		return shared, nil
+	}
+	return fn.Returns, nil
+}
+
+// resolve returns the signature of fn, or of one of its Overloads, that
+// takes arguments of types args, and the type of its result. The error is
+// that of fn itself when none does.
+func (fn *Function) resolve(name string, args []ColumnType) (*Function, ColumnType, error) {
+	ct, err := fn.resultType(name, args)
+	if err == nil {
+		return fn, ct, nil
+	}
+	for i := range fn.Overloads {
+		if ct, oerr := fn.Overloads[i].resultType(name, args); oerr == nil {
+			return &fn.Overloads[i], ct, nil
+		}
+	}
+	return nil, 0, err
+}
+
+// evaluateFunction calls the scalar function fexp names with its arguments
+// evaluated against row.
+func (t *table) evaluateFunction(row []MemoryCell, fexp *FunctionExpression) (MemoryCell, ColumnType, error) {
+	name := fexp.Name.Value
+	switch {
+	case fexp.Over != nil:
+		return nil, 0, ErrWindowNotAllowed
+	case aggregateFunctions[name]:
+		return nil, 0, ErrAggregateNotAllowed
+	case windowFunctions[name]:
+		return nil, 0, ErrOverRequired
+	}
+	fn, ok := lookupFunction(name)
+	if !ok {
+		return nil, 0, ErrFunctionNotFound
+	}
+	if fexp.Asterisk {
+		return nil, 0, ErrInvalidArguments
+	}
+	if isTimeCall(fexp) {
+		ct := timeFunctions[name]
+		return t.evaluateExpression(row, timeExpression(t.now(), ct))
+	}
+
+	var cells []MemoryCell
+	var types []ColumnType
+	isNull := false
+	for _, arg := range fexp.Args {
+		cell, ct, err := t.evaluateExpression(row, arg)
+		if err != nil {
+			return nil, 0, err
+		}
+		cells = append(cells, cell)
+		types = append(types, ct)
+		isNull = isNull || cell.IsNull()
+	}
+
+	fn, ct, err := fn.resolve(name, types)
+	if err != nil {
+		return nil, 0, err
+	}
+	if isNull && !fn.CalledOnNull {
+		return nullCell, ct, nil
+	}
+
+	var args []interface{}
+	for i, cell := range cells {
+		want := fn.Args[len(fn.Args)-1]
+		if i < len(fn.Args) {
+			want = fn.Args[i]
+		}
+		if want == NullType {
+			want = types[i]
+		}
+		cell, err := convertCell(cell, types[i], want)
+		if err != nil {
+			return nil, 0, err
+		}
+		args = append(args, cellValue(cell, want))
+	}
+
+	value, err := fn.Call(args)
+	if err != nil {
+		return nil, 0, err
+	}
+	cell, err := valueCell(value, ct)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return cell, ct, nil
+}
+
+// cellValue converts cell to the Go value a Function receives.
+func cellValue(cell MemoryCell, ct ColumnType) interface{} {
+	if cell.IsNull() {
+		return nil
+	}
+	switch ct {
+	case IntType:
+		return int32(cell.AsInt())
+	case BigIntType:
+		return cell.AsInt()
+	case FloatType:
+		return cell.AsFloat()
+	case BoolType:
+		return cell.AsBool()
+	case DateType, TimestampType:
+		return cell.AsTime()
+	case ByteaType:
+		return append([]byte{}, cell...)
+	default:
+		return cell.AsText()
+	}
+}
+
+// valueCell converts the Go value a Function returns to a cell of type
+// ct.
+func valueCell(value interface{}, ct ColumnType) (MemoryCell, error) {
+	switch v := value.(type) {
+	case nil:
+		return nullCell, nil
+	case int:
+		return valueCell(int64(v), ct)
+	case int32:
+		return valueCell(int64(v), ct)
+	case int64:
+		if isNumeric(ct) {
+			return convertCell(bigIntCell(v), BigIntType, ct)
+		}
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, ErrValueOutOfRange
+		}
+		if ct == FloatType {
+			return floatCell(v), nil
+		}
+	case string:
+		if ct == TextType {
+			return MemoryCell(v), nil
+		}
+		if ct == JSONType {
+			return parseJSON(v)
+		}
+	case []byte:
+		if ct == ByteaType {
+			return append(MemoryCell{}, v...), nil
+		}
+	case bool:
+		if ct == BoolType {
+			return boolCell(v), nil
+		}
+	case time.Time:
+		if !timeInRange(v) {
+			return nil, ErrValueOutOfRange
+		}
+		switch ct {
+		case DateType:
+			return dateCell(v.UTC()), nil
+		case TimestampType:
+			return timestampCell(v), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: cannot return %T as %s", ErrInvalidDatatype, value, ct)
+}
+
+var datePart = Function{
+	Args:    []ColumnType{TextType, TimestampType},
+	Returns: FloatType,
+	Call: func(args []interface{}) (interface{}, error) {
+		return extractField(args[0].(string), args[1].(time.Time))
+	},
+}
+
+func init() {
+	builtins := map[string]Function{
+		"upper": {
+			Args:    []ColumnType{TextType},
+			Returns: TextType,
+			Call: func(args []interface{}) (interface{}, error) {
+				return strings.ToUpper(args[0].(string)), nil
+			},
+		},
+		"lower": {
+			Args:    []ColumnType{TextType},
+			Returns: TextType,
+			Call: func(args []interface{}) (interface{}, error) {
+				return strings.ToLower(args[0].(string)), nil
+			},
+		},
+		"length": {
+			Args:    []ColumnType{TextType},
+			Returns: IntType,
+			Call: func(args []interface{}) (interface{}, error) {
+				return utf8.RuneCountInString(args[0].(string)), nil
+			},
+		},
+		// octet_length counts the bytes of text or of a binary string.
+		"octet_length": {
+			Args:    []ColumnType{NullType},
+			Returns: IntType,
+			Call: func(args []interface{}) (interface{}, error) {
+				switch arg := args[0].(type) {
+				case string:
+					return len(arg), nil
+				case []byte:
+					return len(arg), nil
+				}
+				return nil, fmt.Errorf("%w: octet_length takes text or bytea", ErrTypeMismatch)
+			},
+		},
+		// encode writes a binary string as text in the format 'hex' or
+		// 'base64', and decode reads it back.
+		"encode": {
+			Args:    []ColumnType{ByteaType, TextType},
+			Returns: TextType,
+			Call: func(args []interface{}) (interface{}, error) {
+				return encodeBytes(args[0].([]byte), args[1].(string))
+			},
+		},
+		"decode": {
+			Args:    []ColumnType{TextType, TextType},
+			Returns: ByteaType,
+			Call: func(args []interface{}) (interface{}, error) {
+				return decodeBytes(args[0].(string), args[1].(string))
+			},
+		},
+		// substr counts characters from 1. Positions before the first
+		// character still count towards the length, as in PostgreSQL.
+		"substr": {
+			Args:     []ColumnType{TextType, IntType, IntType},
+			Optional: 1,
+			Returns:  TextType,
+			Call: func(args []interface{}) (interface{}, error) {
+				chars := []rune(args[0].(string))
+				start := int64(args[1].(int32))
+				end := int64(len(chars)) + 1
+				if len(args) > 2 {
+					n := int64(args[2].(int32))
+					if n < 0 {
+						return nil, fmt.Errorf("%w: negative substring length", ErrInvalidArguments)
+					}
+					if start+n < end {
+						end = start + n
+					}
+				}
+				if start < 1 {
+					start = 1
+				}
+				if start >= end {
+					return "", nil
+				}
+				return string(chars[start-1 : end-1]), nil
+			},
+		},
+		// concat joins the text of its arguments, skipping NULLs.
+		"concat": {
+			Args:         []ColumnType{NullType},
+			Variadic:     true,
+			Returns:      TextType,
+			CalledOnNull: true,
+			Call: func(args []interface{}) (interface{}, error) {
+				var b strings.Builder
+				for _, arg := range args {
+					switch arg := arg.(type) {
+					case nil:
+					case float64:
+						b.WriteString(FormatFloat(arg))
+					case time.Time:
+						b.WriteString(FormatTimestamp(arg))
+					default:
+						fmt.Fprint(&b, arg)
+					}
+				}
+				return b.String(), nil
+			},
+		},
+		"abs": {
+			Args:    []ColumnType{IntType},
+			Returns: IntType,
+			Call: func(args []interface{}) (interface{}, error) {
+				n := int64(args[0].(int32))
+				if n < 0 {
+					n = -n
+				}
+				return n, nil
+			},
+			Overloads: []Function{{
+				Args:    []ColumnType{FloatType},
+				Returns: FloatType,
+				Call: func(args []interface{}) (interface{}, error) {
+					return math.Abs(args[0].(float64)), nil
+				},
+			}},
+		},
+		// round rounds to the given number of decimal digits, which for
+		// an integer only changes anything when it is negative: round(1250,
+		// -2) is 1300. Halves round away from zero, so round(2.5) is 3 and
+		// round(-2.5) is -3.
+		"round": {
+			Args:     []ColumnType{IntType, IntType},
+			Optional: 1,
+			Returns:  IntType,
+			Call: func(args []interface{}) (interface{}, error) {
+				n := int64(args[0].(int32))
+				if len(args) < 2 || args[1].(int32) >= 0 {
+					return n, nil
+				}
+				if args[1].(int32) < -9 {
+					return 0, nil
+				}
+				unit := int64(math.Pow10(int(-args[1].(int32))))
+				half := unit / 2
+				if n < 0 {
+					return -((-n + half) / unit * unit), nil
+				}
+				return (n + half) / unit * unit, nil
+			},
+			Overloads: []Function{{
+				Args:     []ColumnType{FloatType, IntType},
+				Optional: 1,
+				Returns:  FloatType,
+				Call: func(args []interface{}) (interface{}, error) {
+					f := args[0].(float64)
+					if len(args) < 2 {
+						return math.Round(f), nil
+					}
+					scale := math.Pow10(int(args[1].(int32)))
+					switch {
+					case scale == 0:
+						return 0.0, nil
+					case math.IsInf(f*scale, 0):
+						// f has no more digits than that.
+						return f, nil
+					}
+					return math.Round(f*scale) / scale, nil
+				},
+			}},
+		},
+		"coalesce": {
+			Args:         []ColumnType{NullType},
+			Variadic:     true,
+			Returns:      NullType,
+			CalledOnNull: true,
+			Call: func(args []interface{}) (interface{}, error) {
+				for _, arg := range args {
+					if arg != nil {
+						return arg, nil
+					}
+				}
+				return nil, nil
+			},
+		},
+		// now is the time of the backend's Clock, fixed when the
+		// transaction of the statement began. It has no Call, since a
+		// Function cannot reach the backend; evaluateFunction reads the
+		// clock of the table instead.
+		"now": {
+			Returns: TimestampType,
+		},
+		// current_timestamp is now, called by CURRENT_TIMESTAMP.
+		"current_timestamp": {
+			Returns: TimestampType,
+		},
+		// current_date is the date part of now, called by CURRENT_DATE.
+		"current_date": {
+			Returns: DateType,
+		},
+		// date_trunc rounds a timestamp down to the start of a unit like
+		// 'hour' or 'month'.
+		"date_trunc": {
+			Args:    []ColumnType{TextType, TimestampType},
+			Returns: TimestampType,
+			Call: func(args []interface{}) (interface{}, error) {
+				return truncateTime(args[0].(string), args[1].(time.Time))
+			},
+		},
+		// json_extract is the JSON found at a path like '$.tags[0]'
+		// in a document.
+		"json_extract": {
+			Args:    []ColumnType{JSONType, TextType},
+			Returns: JSONType,
+			Call: func(args []interface{}) (interface{}, error) {
+				return jsonExtract(args[0].(string), args[1].(string))
+			},
+		},
+		// date_part is the function form of EXTRACT(field FROM source),
+		// which is parsed into a call to extract.
+		"date_part": datePart,
+		"extract":   datePart,
+	}
+	for name, fn := range builtins {
+		if err := RegisterFunction(name, fn); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package gosql
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFunctions(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table users (name text, nick text, age int);"+
+		"insert into users values ('Zoë', NULL, -31), ('bob', 'bobby', 1250)")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select upper(name), lower(name), length(name), abs(age), round(age, -2), coalesce(nick, name) from users")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{
+		{Type: TextType, Name: "upper"},
+		{Type: TextType, Name: "lower"},
+		{Type: IntType, Name: "length"},
+		{Type: IntType, Name: "abs"},
+		{Type: IntType, Name: "round"},
+		{Type: TextType, Name: "coalesce"},
+	}, results.Columns)
+	assert.Equal(t, [][]Cell{
+		{MemoryCell("ZOË"), MemoryCell("zoë"), intCell(3), intCell(31), intCell(0), MemoryCell("Zoë")},
+		{MemoryCell("BOB"), MemoryCell("bob"), intCell(3), intCell(1250), intCell(1300), MemoryCell("bobby")},
+	}, results.Rows)
+
+	results, err = execute(t, mb, "select substr(name, 2), substr(name, 0, 2), concat(name, '/', nick, '/', age), upper(nick) from users where length(name) = 3")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{
+		{MemoryCell("oë"), MemoryCell("Z"), MemoryCell("Zoë//-31"), nullCell},
+		{MemoryCell("ob"), MemoryCell("b"), MemoryCell("bob/bobby/1250"), MemoryCell("BOBBY")},
+	}, results.Rows)
+
+	results, err = execute(t, mb, "select upper(max(name)), length(nick) + count(*) from users group by nick order by nick")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{MemoryCell("BOB"), intCell(6)}, {MemoryCell("ZOË"), nullCell}}, results.Rows)
+
+	results, err = execute(t, mb, "select now() from users")
+	assert.Nil(t, err)
+	assert.Equal(t, TimestampType, results.Columns[0].Type)
+
+	// round and abs take floats too, rounding halves away from zero.
+	script, err := ExecuteScript(mb, "select round(2.5), round(-2.5), round(1.2345, 2), round(1250.0, -2), abs(-1.5), round(age / 2.0) from users where age < 0", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{
+		{Type: FloatType, Name: "round"},
+		{Type: FloatType, Name: "round"},
+		{Type: FloatType, Name: "round"},
+		{Type: FloatType, Name: "round"},
+		{Type: FloatType, Name: "abs"},
+		{Type: FloatType, Name: "round"},
+	}, script[0].Results.Columns)
+	assert.Equal(t, [][]Cell{{floatCell(3), floatCell(-3), floatCell(1.23), floatCell(1300), floatCell(1.5), floatCell(-16)}}, script[0].Results.Rows)
+
+	tests := []struct {
+		source string
+		err    error
+	}{
+		{"select nope(name) from users", ErrFunctionNotFound},
+		{"select upper(name, name) from users", ErrInvalidArguments},
+		{"select concat() from users", ErrInvalidArguments},
+		{"select upper(*) from users", ErrInvalidArguments},
+		{"select upper(age) from users", ErrTypeMismatch},
+		{"select coalesce(nick, age) from users", ErrTypeMismatch},
+		{"select substr(name, 1, -1) from users", ErrInvalidArguments},
+		{"select * from users where count(*) > 1", ErrAggregateNotAllowed},
+	}
+	for _, test := range tests {
+		_, err := execute(t, mb, test.source)
+		assert.True(t, errors.Is(err, test.err), test.source)
+	}
+}
+
+func TestRegisterFunction(t *testing.T) {
+	err := RegisterFunction("Repeat", Function{
+		Args:    []ColumnType{TextType, IntType},
+		Returns: TextType,
+		Call: func(args []interface{}) (interface{}, error) {
+			return strings.Repeat(args[0].(string), int(args[1].(int32))), nil
+		},
+	})
+	assert.Nil(t, err)
+	defer func() {
+		functionsMu.Lock()
+		delete(functions, "repeat")
+		functionsMu.Unlock()
+	}()
+
+	mb := NewMemoryBackend()
+	results, err := execute(t, mb, "create table t (s text); insert into t values ('ab'); select repeat(s, 3), repeat(NULL, 3) from t")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{MemoryCell("ababab"), nullCell}}, results.Rows)
+
+	assert.True(t, errors.Is(RegisterFunction("repeat", Function{}), ErrFunctionAlreadyExists))
+	assert.True(t, errors.Is(RegisterFunction("COUNT", Function{}), ErrFunctionAlreadyExists))
+}
@@ -0,0 +1,176 @@
+package gosql
+
+import (
+	"context"
+	"encoding/binary"
+)
+
+// joinKey is a condition of a join, cond, that equates an expression of
+// the rows on its left with one of the rows on its right.
+type joinKey struct {
+	cond        *Expression
+	left, right *Expression
+}
+
+// joinKeys finds the conditions of on that equate an expression of the
+// relations in left with one of relation right, by which a hash join can
+// match rows. It finds none when a relation is not a stored table, whose
+// columns are not known while planning.
+func (e *estimator) joinKeys(left map[int]bool, right int, on *Expression) []joinKey {
+	for _, rel := range e.rels {
+		if rel.t == nil {
+			return nil
+		}
+	}
+
+	// within reports whether refs are all relations in rels, and there is
+	// at least one.
+	within := func(refs, rels map[int]bool) bool {
+		for r := range refs {
+			if !rels[r] {
+				return false
+			}
+		}
+		return len(refs) > 0
+	}
+	rightRels := map[int]bool{right: true}
+
+	var keys []joinKey
+	for _, exp := range conjuncts(on) {
+		if exp.Kind != BinaryKind || exp.Binary.Op.Kind != SymbolKind || Symbol(exp.Binary.Op.Value) != EqSymbol {
+			continue
+		}
+		l, lok := e.references(exp.Binary.Left)
+		r, rok := e.references(exp.Binary.Right)
+		switch {
+		case !lok || !rok:
+		case within(l, left) && within(r, rightRels):
+			keys = append(keys, joinKey{cond: exp, left: exp.Binary.Left, right: exp.Binary.Right})
+		case within(r, left) && within(l, rightRels):
+			keys = append(keys, joinKey{cond: exp, left: exp.Binary.Right, right: exp.Binary.Left})
+		}
+	}
+	return keys
+}
+
+// hashJoinTables is joinTables for a condition on that holds keys. It
+// hashes the rows of right by their values of the keys, then looks up the
+// rows of left among them, so only the pairs whose keys are equal are
+// checked against on rather than every pair. The rows come out in the
+// same order as from joinTables.
+//
+// The hashed rows may take up to the work_mem of the session. Joins whose
+// keys cannot be compared, and rows whose keys fail to evaluate, are left
+// to joinTables, which reports the error, as are joins on text with a
+// collation.
+func hashJoinTables(ctx context.Context, left, right *table, kind JoinKind, on *Expression, keys []joinKey) (*table, error) {
+	types := make([]ColumnType, len(keys))
+	leftExps, leftTypes := make([]*Expression, len(keys)), make([]ColumnType, len(keys))
+	rightExps, rightTypes := make([]*Expression, len(keys)), make([]ColumnType, len(keys))
+	for i, key := range keys {
+		_, lt, err := left.evaluateExpression(make([]MemoryCell, len(left.columns)), key.left)
+		if err != nil {
+			return joinTables(ctx, left, right, kind, on)
+		}
+		_, rt, err := right.evaluateExpression(make([]MemoryCell, len(right.columns)), key.right)
+		if err != nil {
+			return joinTables(ctx, left, right, kind, on)
+		}
+		ct, ok := commonType(lt, rt)
+		if !ok || left.comparisonCollation(ct, key.left) != nil || right.comparisonCollation(ct, key.right) != nil {
+			return joinTables(ctx, left, right, kind, on)
+		}
+		types[i] = ct
+		leftExps[i], leftTypes[i] = key.left, lt
+		rightExps[i], rightTypes[i] = key.right, rt
+	}
+
+	// hashKey encodes the values of exps, of the types from, in row
+	// converted to the common types of the keys. It reports false when one
+	// is NULL, which equals nothing.
+	hashKey := func(t *table, row []MemoryCell, exps []*Expression, from []ColumnType) (string, bool, error) {
+		var buf []byte
+		for i, exp := range exps {
+			cell, _, err := t.evaluateExpression(row, exp)
+			if err != nil {
+				return "", false, err
+			}
+			if cell.IsNull() {
+				return "", false, nil
+			}
+			if cell, err = convertCell(cell, from[i], types[i]); err != nil {
+				return "", false, err
+			}
+			buf = binary.AppendUvarint(buf, uint64(len(cell)))
+			buf = append(buf, cell...)
+		}
+		return string(buf), true, nil
+	}
+
+	budget, used := workMem(ctx), int64(0)
+	buckets := map[string][]int{}
+	for ri, r := range right.rows {
+		if err := canceled(ctx, ri); err != nil {
+			return nil, err
+		}
+		key, ok, err := hashKey(right, r, rightExps, rightTypes)
+		if err != nil {
+			return joinTables(ctx, left, right, kind, on)
+		}
+		if !ok {
+			continue
+		}
+		if used += rowBytes(r) + int64(len(key)) + 8; used > budget {
+			return nil, ErrWorkMemExceeded
+		}
+		buckets[key] = append(buckets[key], ri)
+	}
+
+	joined := joinedTable(left, right)
+	leftNulls := make([]MemoryCell, len(left.columns))
+	rightNulls := make([]MemoryCell, len(right.columns))
+	rightMatched := make([]bool, len(right.rows))
+	for li, l := range left.rows {
+		if err := canceled(ctx, li); err != nil {
+			return nil, err
+		}
+		key, ok, err := hashKey(left, l, leftExps, leftTypes)
+		if err != nil {
+			return joinTables(ctx, left, right, kind, on)
+		}
+
+		matched := false
+		if ok {
+			for _, ri := range buckets[key] {
+				row := append(append([]MemoryCell{}, l...), right.rows[ri]...)
+				cell, ct, err := joined.evaluateExpression(row, on)
+				if err != nil {
+					return nil, err
+				}
+				if !compatible(ct, BoolType) {
+					return nil, ErrInvalidCondition
+				}
+				if !cell.AsBool() {
+					continue
+				}
+				joined.rows = append(joined.rows, row)
+				matched = true
+				rightMatched[ri] = true
+			}
+		}
+
+		if !matched && kind == LeftJoin {
+			joined.rows = append(joined.rows, append(append([]MemoryCell{}, l...), rightNulls...))
+		}
+	}
+
+	if kind == RightJoin {
+		for ri, r := range right.rows {
+			if !rightMatched[ri] {
+				joined.rows = append(joined.rows, append(append([]MemoryCell{}, leftNulls...), r...))
+			}
+		}
+	}
+
+	return joined, nil
+}
@@ -0,0 +1,89 @@
+package gosql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashJoin(t *testing.T) {
+	mb := NewMemoryBackend()
+	script := "create table a (id int, k int, x text); create table b (id bigint, k float, y text);"
+	for i := 0; i < 40; i++ {
+		script += fmt.Sprintf("insert into a values (%d, %d, 'a%d');", i%12, i%5, i)
+		script += fmt.Sprintf("insert into b values (%d, %d.0, 'b%d');", i%15, i%4, i)
+	}
+	_, err := ExecuteScript(mb, script+"insert into a values (null, 1, 'null'); insert into b values (null, 1, 'null')", ScriptOptions{})
+	assert.Nil(t, err)
+	snap := mb.snapshot()
+	a := mb.tables["a"].visibleTo(snap)
+	b := mb.tables["b"].visibleTo(snap)
+
+	// Hashing gives the rows a nested loop does, in the same order.
+	for _, source := range []string{"a.id = b.id", "b.id = a.id and a.k = b.k", "a.id = b.id and a.x < b.y", "a.id + 1 = b.id * 2"} {
+		on := mustParseExpression(t, source)
+		e := &estimator{rels: []*relation{{name: "a", t: a}, {name: "b", t: b}}}
+		keys := e.joinKeys(map[int]bool{0: true}, 1, on)
+		assert.NotEmpty(t, keys, source)
+		for _, kind := range []JoinKind{InnerJoin, LeftJoin, RightJoin} {
+			nested, err := joinTables(context.Background(), a, b, kind, on)
+			assert.Nil(t, err, source)
+			hashed, err := hashJoinTables(context.Background(), a, b, kind, on, keys)
+			assert.Nil(t, err, source)
+			assert.Equal(t, nested.rows, hashed.rows, source)
+		}
+	}
+
+	// Conditions that do not compare the two sides are no keys.
+	e := &estimator{rels: []*relation{{name: "a", t: a}, {name: "b", t: b}}}
+	for _, source := range []string{"a.id < b.id", "a.id = a.k", "b.id = 1", "a.id = b.id or a.k = b.k"} {
+		assert.Empty(t, e.joinKeys(map[int]bool{0: true}, 1, mustParseExpression(t, source)), source)
+	}
+
+	results, err := ExecuteScript(mb, "explain select * from a join b on a.id = b.id and a.x <> b.y", ScriptOptions{})
+	assert.Nil(t, err)
+	var plan []string
+	for _, row := range results[0].Results.Rows {
+		plan = append(plan, strings.TrimSpace(row[0].AsText()))
+	}
+	assert.Contains(t, plan[2], "->  Hash Join")
+	assert.Equal(t, []string{"Hash Cond: (a.id = b.id)", "Join Filter: (a.x <> b.y)"}, plan[3:5])
+
+	// The rows hashed must fit in work_mem.
+	s := mb.NewSession()
+	_, err = ExecuteScript(s, "set work_mem = 1; select * from a join b on a.id = b.id", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrWorkMemExceeded)
+	assert.Equal(t, "53200", SQLState(err))
+	_, err = ExecuteScript(s, "set work_mem = '1MB'; select * from a join b on a.id = b.id", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1<<20), s.WorkMem())
+}
+
+func TestSession_SetWorkMem(t *testing.T) {
+	s := NewMemoryBackend().NewSession()
+	for _, test := range []struct {
+		source string
+		bytes  int64
+		err    error
+	}{
+		{"set work_mem = 4096", 4 << 20, nil},
+		{"set work_mem = '512kB'", 512 << 10, nil},
+		{"set work_mem = '2GB'", 2 << 30, nil},
+		{"set work_mem = '100'", 100 << 10, nil},
+		{"set work_mem = default", defaultWorkMem, nil},
+		{"set work_mem = 0", 0, ErrInvalidSettingValue},
+		{"set work_mem = '64mb'", 0, ErrInvalidSettingValue},
+		{"set work_mem = 'lots'", 0, ErrInvalidSettingValue},
+	} {
+		_, err := ExecuteScript(s, test.source, ScriptOptions{})
+		if test.err != nil {
+			assert.ErrorIs(t, err, test.err, test.source)
+			continue
+		}
+		assert.Nil(t, err, test.source)
+		assert.Equal(t, test.bytes, s.WorkMem(), test.source)
+	}
+}
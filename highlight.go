@@ -0,0 +1,107 @@
+package gosql
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// HighlightClass is how a span of source is colored.
+type HighlightClass uint
+
+const (
+	KeywordClass HighlightClass = iota
+	IdentifierClass
+	StringClass
+	NumberClass
+	OperatorClass
+	CommentClass
+	// ErrorClass marks text that does not lex, such as a stray character
+	// or a string that is never closed.
+	ErrorClass
+)
+
+// Span is a byte range of the source, source[Offset:End].
+type Span struct {
+	Class       HighlightClass
+	Offset, End uint
+}
+
+// highlightLexers are the lexers of Parse, keeping comments.
+var highlightLexers = func() []lexer {
+	cfg := DefaultLexConfig()
+	cfg.Comments = true
+	return cfg.lexers()
+}()
+
+// Highlight classifies the source for coloring it in an editor or REPL.
+// The spans are in source order and leave out only whitespace. Unlike
+// Parse it never fails: text that does not lex is given an ErrorClass span
+// and lexing goes on after it, apart from an unclosed string, quoted
+// identifier or comment, which runs to the end of the source as it would
+// while it is being typed. Booleans count as keywords, parameters as
+// identifiers and symbols as operators.
+func Highlight(source string) []Span {
+	var spans []Span
+	cur := cursor{}
+
+lex:
+	for cur.pointer < uint(len(source)) {
+		for _, l := range highlightLexers {
+			if token, newCursor, ok := l(source, cur); ok {
+				if token != nil {
+					spans = append(spans, Span{Class: highlightClass(token), Offset: cur.pointer, End: newCursor.pointer})
+				}
+				cur = newCursor
+				continue lex
+			}
+		}
+
+		rest := source[cur.pointer:]
+		r, size := utf8.DecodeRuneInString(rest)
+		switch {
+		case unicode.IsSpace(r):
+		case unclosed(rest):
+			size = len(rest)
+			spans = append(spans, Span{Class: ErrorClass, Offset: cur.pointer, End: uint(len(source))})
+		case len(spans) > 0 && spans[len(spans)-1].Class == ErrorClass && spans[len(spans)-1].End == cur.pointer:
+			spans[len(spans)-1].End += uint(size)
+		default:
+			spans = append(spans, Span{Class: ErrorClass, Offset: cur.pointer, End: cur.pointer + uint(size)})
+		}
+		cur.pointer += uint(size)
+		cur.loc.Col++
+		if r == '\n' {
+			cur.loc.Line++
+			cur.loc.Col = 0
+		}
+	}
+	return spans
+}
+
+// unclosed reports whether rest, which does not lex, starts a string,
+// quoted identifier or comment.
+func unclosed(rest string) bool {
+	for _, prefix := range []string{"'", `"`, "e'", "E'", "/*"} {
+		if strings.HasPrefix(rest, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func highlightClass(t *Token) HighlightClass {
+	switch t.Kind {
+	case KeywordKind, BoolKind:
+		return KeywordClass
+	case StringKind, BytesKind:
+		return StringClass
+	case NumericKind:
+		return NumberClass
+	case SymbolKind:
+		return OperatorClass
+	case CommentKind:
+		return CommentClass
+	}
+	return IdentifierClass
+}
@@ -0,0 +1,41 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlight(t *testing.T) {
+	source := "select \"Full name\", 1.5 -- note\nfrom t where ok = true and id > $1"
+	var classified []string
+	var classes []HighlightClass
+	for _, span := range Highlight(source) {
+		classified = append(classified, source[span.Offset:span.End])
+		classes = append(classes, span.Class)
+	}
+	assert.Equal(t, []string{
+		"select", `"Full name"`, ",", "1.5", "-- note", "from", "t", "where",
+		"ok", "=", "true", "and", "id", ">", "$1",
+	}, classified)
+	assert.Equal(t, []HighlightClass{
+		KeywordClass, IdentifierClass, OperatorClass, NumberClass, CommentClass, KeywordClass, IdentifierClass, KeywordClass,
+		IdentifierClass, OperatorClass, KeywordClass, KeywordClass, IdentifierClass, OperatorClass, IdentifierClass,
+	}, classes)
+
+	for _, test := range []struct {
+		source string
+		spans  []Span
+	}{
+		// Stray characters are errors, and lexing goes on after them.
+		{"select #, a", []Span{{KeywordClass, 0, 6}, {ErrorClass, 7, 8}, {OperatorClass, 8, 9}, {IdentifierClass, 10, 11}}},
+		{"a ## b", []Span{{IdentifierClass, 0, 1}, {ErrorClass, 2, 4}, {IdentifierClass, 5, 6}}},
+		// An unclosed string or comment runs to the end.
+		{"select 'it''s", []Span{{KeywordClass, 0, 6}, {ErrorClass, 7, 13}}},
+		{"a /* b\nc", []Span{{IdentifierClass, 0, 1}, {ErrorClass, 2, 8}}},
+		{"select\r\n'señor'", []Span{{KeywordClass, 0, 6}, {StringClass, 8, 16}}},
+		{"", nil},
+	} {
+		assert.Equal(t, test.spans, Highlight(test.source), test.source)
+	}
+}
@@ -0,0 +1,119 @@
+package gosql
+
+import "strings"
+
+// The optimizer hints, which name the relations of a query the way its
+// columns are qualified, by alias when they have one.
+const (
+	// indexHint, INDEX(table index), reads table with index whenever one
+	// of the conditions on it can use the index, whatever that costs.
+	indexHint = "index"
+	// noIndexHint, NO_INDEX(table), reads every row of table.
+	noIndexHint = "no_index"
+	// hashJoinHint and noHashJoinHint make every join that can be a hash
+	// join one, or none of them.
+	hashJoinHint   = "hash_join"
+	noHashJoinHint = "no_hash_join"
+)
+
+// hintArgs is how many arguments each hint takes.
+var hintArgs = map[string]int{
+	indexHint:      2,
+	noIndexHint:    1,
+	hashJoinHint:   0,
+	noHashJoinHint: 0,
+}
+
+// planHints are the choices the hints of a query make for the planner.
+// The zero value leaves every choice to it.
+type planHints struct {
+	// indexes maps the relations hints name to the index to read them
+	// with, or to "" to read them without one.
+	indexes map[string]string
+	// join is hashJoinHint or noHashJoinHint when either is given.
+	join string
+}
+
+// validateHints checks that hints are known, have as many arguments as
+// they take and name relations of scope.
+func validateHints(scope []*CreateTableStatement, hints []*Hint) error {
+	for _, hint := range hints {
+		n, ok := hintArgs[strings.ToLower(hint.Name.Value)]
+		if !ok || len(hint.Args) != n {
+			return validationError(ErrInvalidHint, hint.Name)
+		}
+		if n == 0 {
+			continue
+		}
+		found := false
+		for _, t := range scope {
+			found = found || t.Name.Value == hint.Args[0].Value
+		}
+		if !found {
+			return validationError(ErrInvalidHint, hint.Args[0])
+		}
+	}
+	return nil
+}
+
+// newPlanHints gathers the choices hints make for the planner reading
+// rels. An INDEX hint must name an index of its relation, which the
+// validator cannot tell, and the planner ignores hints it does not know.
+func newPlanHints(hints []*Hint, rels []*relation) (planHints, error) {
+	h := planHints{indexes: map[string]string{}}
+	for _, hint := range hints {
+		name := strings.ToLower(hint.Name.Value)
+		if n, ok := hintArgs[name]; !ok || len(hint.Args) != n {
+			continue
+		}
+		switch name {
+		case indexHint:
+			if !hasIndex(rels, hint.Args[0].Value, hint.Args[1].Value) {
+				return planHints{}, validationError(ErrInvalidHint, hint.Args[1])
+			}
+			h.indexes[hint.Args[0].Value] = hint.Args[1].Value
+		case noIndexHint:
+			h.indexes[hint.Args[0].Value] = ""
+		case hashJoinHint, noHashJoinHint:
+			h.join = name
+		}
+	}
+	return h, nil
+}
+
+// hasIndex reports whether the stored table rels call name has an index
+// called index.
+func hasIndex(rels []*relation, name, index string) bool {
+	for _, rel := range rels {
+		if rel.name != name || rel.t == nil {
+			continue
+		}
+		for _, idx := range rel.t.indexes {
+			if idx.name == index {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// table returns the table whose indexes the planner may read rel with:
+// rel.t itself unless a hint names rel, nil for NO_INDEX and one with only
+// the index of INDEX, which then is to be used whatever it costs.
+func (h planHints) table(rel *relation) (t *table, forced bool) {
+	name, ok := h.indexes[rel.name]
+	if !ok || rel.t == nil {
+		return rel.t, false
+	}
+	if name == "" {
+		return nil, false
+	}
+	hinted := *rel.t
+	hinted.indexes = nil
+	for _, idx := range rel.t.indexes {
+		if idx.name == name {
+			hinted.indexes = append(hinted.indexes, idx)
+		}
+	}
+	return &hinted, true
+}
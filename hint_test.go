@@ -0,0 +1,118 @@
+package gosql
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHints(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table countries (id int primary key, name text);"+
+		"create table users (id int primary key, email text, country int);"+
+		"create index users_email on users (email);"+
+		"insert into countries values (1, 'fr'), (2, 'de')", ScriptOptions{})
+	assert.Nil(t, err)
+	for i := 1; i <= 20; i++ {
+		_, err = ExecuteScript(mb, fmt.Sprintf("insert into users values (%d, 'user%d@example.com', %d)", i, i, i%2+1), ScriptOptions{})
+		assert.Nil(t, err)
+	}
+
+	explain := func(source string) string {
+		results, err := ExecuteScript(mb, "explain "+source, ScriptOptions{})
+		if !assert.Nil(t, err, source) {
+			return ""
+		}
+		var lines []string
+		for _, row := range results[0].Results.Rows {
+			lines = append(lines, row[0].AsText())
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	tests := []struct {
+		source string
+		plan   string
+	}{
+		{"select * from countries where id = 1", "Seq Scan on countries"},
+		{"select /*+ INDEX(countries countries_pkey) */ * from countries where id = 1", "Index Scan using countries_pkey on countries"},
+		{"select * from users where id = 1", "Index Scan using users_pkey on users"},
+		{"select /*+ no_index(users) */ * from users where id = 1", "Seq Scan on users"},
+		{"select /*+ INDEX(u users_email) */ * from users u where id = 1 and email = 'user1@example.com'", "Index Scan using users_email on users u"},
+		{"select /*+ INDEX(users users_email) */ * from users where id = 1", "Seq Scan on users"},
+		{"select * from users a join users b on a.id = b.id", "Hash Join"},
+		{"select /*+ NO_HASH_JOIN */ * from users a join users b on a.id = b.id", "Nested Loop Join"},
+		{"select * from users u join countries c on u.country = c.id", "Nested Loop Join"},
+		{"select /*+ HASH_JOIN */ * from users u join countries c on u.country = c.id", "Hash Join"},
+		{"select /*+ HASH_JOIN */ * from users u join countries c on u.country < c.id", "Nested Loop Join"},
+	}
+	for _, test := range tests {
+		assert.Contains(t, explain(test.source), test.plan, test.source)
+	}
+
+	// Hints change how a query runs and not what it returns.
+	results, err := ExecuteScript(mb, "select /*+ INDEX(users users_email) */ id from users where email = 'user3@example.com';"+
+		"select /*+ NO_HASH_JOIN NO_INDEX(u) */ count(*) from users u join countries c on u.country = c.id where c.name = 'fr'", ScriptOptions{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, [][]Cell{{intCell(3)}}, results[0].Results.Rows)
+		assert.Equal(t, [][]Cell{{intCell(10)}}, results[1].Results.Rows)
+	}
+
+	for _, source := range []string{
+		"select /*+ FULL_SCAN(users) */ * from users",
+		"select /*+ INDEX(users) */ * from users",
+		"select /*+ NO_HASH_JOIN(users) */ * from users",
+		"select /*+ NO_INDEX(orders) */ * from users",
+		"select /*+ NO_INDEX(users) */ * from users u",
+		"select /*+ INDEX(users countries_pkey) */ * from users",
+		"select /*+ INDEX(c countries_pkey) */ * from (select * from countries) c",
+	} {
+		_, err := ExecuteScript(mb, source, ScriptOptions{})
+		assert.ErrorIs(t, err, ErrInvalidHint, source)
+		assert.Equal(t, SyntaxError, Code(err), source)
+	}
+}
+
+func TestParse_hints(t *testing.T) {
+	source := "select /*+ index(users, users_email) NO_HASH_JOIN */ distinct id from users"
+	ast, err := Parse(source)
+	assert.Nil(t, err)
+	slct := ast.Statements[0].SelectStatement
+	if assert.Len(t, slct.Hints, 2) {
+		assert.Equal(t, "index", slct.Hints[0].Name.Value)
+		assert.Equal(t, Location{Line: 0, Col: 11}, slct.Hints[0].Name.Loc)
+		users := slct.Hints[0].Args[0]
+		assert.Equal(t, "users", source[users.Offset:users.EndOffset])
+		assert.Equal(t, "users_email", slct.Hints[0].Args[1].Value)
+		assert.Empty(t, slct.Hints[1].Args)
+	}
+	assert.True(t, slct.Distinct)
+	formatted := Format(ast.Statements[0])
+	assert.Equal(t, "SELECT /*+ INDEX(users users_email) NO_HASH_JOIN */ DISTINCT id\nFROM users", formatted)
+	again, err := Parse(formatted)
+	assert.Nil(t, err)
+	assert.Equal(t, formatted, Format(again.Statements[0]))
+
+	// Hints anywhere but right after SELECT are comments.
+	for _, source := range []string{
+		"/*+ NO_HASH_JOIN */ select id from users",
+		"select id /*+ NO_HASH_JOIN */ from users",
+		"select /* NO_HASH_JOIN */ id from users",
+	} {
+		ast, err := Parse(source)
+		if assert.Nil(t, err, source) {
+			assert.Empty(t, ast.Statements[0].SelectStatement.Hints, source)
+		}
+	}
+
+	for _, source := range []string{
+		"select /*+ INDEX(users */ id from users",
+		"select /*+ INDEX(, users) */ id from users",
+		"select /*+ 42 */ id from users",
+	} {
+		_, err := Parse(source)
+		assert.NotNil(t, err, source)
+	}
+}
@@ -0,0 +1,320 @@
+package gosql
+
+import (
+	"math/rand"
+	"sort"
+)
+
+const skipListMaxLevel = 16
+
+// skipList is an ordered map from cell values to the rows holding them,
+// used as the storage for an index. Keys compare with compareCells.
+type skipList struct {
+	head  *skipNode
+	level int
+	ct    ColumnType
+	rand  *rand.Rand
+}
+
+type skipNode struct {
+	key  MemoryCell
+	rows []int
+	next []*skipNode
+}
+
+func newSkipList(ct ColumnType) *skipList {
+	return &skipList{
+		head:  &skipNode{next: make([]*skipNode, skipListMaxLevel)},
+		level: 1,
+		ct:    ct,
+		rand:  rand.New(rand.NewSource(1)),
+	}
+}
+
+func (s *skipList) randomLevel() int {
+	level := 1
+	for level < skipListMaxLevel && s.rand.Intn(4) == 0 {
+		level++
+	}
+	return level
+}
+
+// insert records that row holds key.
+func (s *skipList) insert(key MemoryCell, row int) {
+	update := make([]*skipNode, skipListMaxLevel)
+	node := s.head
+	for l := s.level - 1; l >= 0; l-- {
+		for node.next[l] != nil && compareCells(node.next[l].key, key, s.ct) < 0 {
+			node = node.next[l]
+		}
+		update[l] = node
+	}
+
+	if next := node.next[0]; next != nil && compareCells(next.key, key, s.ct) == 0 {
+		next.rows = append(next.rows, row)
+		return
+	}
+
+	level := s.randomLevel()
+	for l := s.level; l < level; l++ {
+		update[l] = s.head
+	}
+	if level > s.level {
+		s.level = level
+	}
+
+	added := &skipNode{key: key, rows: []int{row}, next: make([]*skipNode, level)}
+	for l := 0; l < level; l++ {
+		added.next[l] = update[l].next[l]
+		update[l].next[l] = added
+	}
+}
+
+// indexBound is one end of a range scan. A nil key leaves that end open.
+type indexBound struct {
+	key       MemoryCell
+	inclusive bool
+}
+
+// scan returns the rows whose keys fall between low and high.
+func (s *skipList) scan(low, high indexBound) []int {
+	node := s.head
+	if low.key != nil {
+		for l := s.level - 1; l >= 0; l-- {
+			for node.next[l] != nil && compareCells(node.next[l].key, low.key, s.ct) < 0 {
+				node = node.next[l]
+			}
+		}
+	}
+
+	var rows []int
+	for node = node.next[0]; node != nil; node = node.next[0] {
+		if low.key != nil && !low.inclusive && compareCells(node.key, low.key, s.ct) == 0 {
+			continue
+		}
+		if high.key != nil {
+			c := compareCells(node.key, high.key, s.ct)
+			if c > 0 || (c == 0 && !high.inclusive) {
+				break
+			}
+		}
+		rows = append(rows, node.rows...)
+	}
+	return rows
+}
+
+type index struct {
+	name   string
+	column int
+	// collation is that of the column, whose keys the index holds in
+	// place of its values.
+	collation *collation
+	tree      *skipList
+}
+
+// add records version i of the table in the index. NULLs are left out
+// since no comparison ever matches them.
+func (idx *index) add(row []MemoryCell, i int) {
+	if cell := row[idx.column]; !cell.IsNull() {
+		idx.tree.insert(idx.collation.cellKey(cell), i)
+	}
+}
+
+func (t *table) addIndex(name string, column int) {
+	idx := &index{name: name, column: column, collation: t.collation(column)}
+	t.indexes = append(t.indexes, idx)
+	t.rebuildIndex(idx)
+}
+
+func (t *table) rebuildIndex(idx *index) {
+	idx.tree = newSkipList(t.columnTypes[idx.column])
+	for i, v := range t.versions {
+		if v.xmin != abortedXID {
+			idx.add(v.cells, i)
+		}
+	}
+}
+
+// rebuildIndexes refreshes every index after versions have been removed,
+// which shifts the version numbers the indexes hold.
+func (t *table) rebuildIndexes() {
+	for _, idx := range t.indexes {
+		t.rebuildIndex(idx)
+	}
+}
+
+func (t *table) indexOn(column int) *index {
+	for _, idx := range t.indexes {
+		if idx.column == column {
+			return idx
+		}
+	}
+	return nil
+}
+
+// indexCandidates uses an index to narrow the rows where can match. It
+// returns the candidate row numbers in table order, or false when no index
+// applies and every row has to be scanned. The candidates still need where
+// evaluated against them. The rows of a stored table are its versions.
+func (t *table) indexCandidates(where *Expression) ([]int, bool) {
+	r, ok := t.indexRange(where)
+	if !ok {
+		return nil, false
+	}
+	return r.rows(t), true
+}
+
+// indexRange is the part of an index a condition can match.
+type indexRange struct {
+	idx       *index
+	low, high indexBound
+}
+
+// rows scans the range, returning the rows of t it holds in table order.
+func (r *indexRange) rows(t *table) []int {
+	return t.indexRows(r.idx.tree.scan(r.low, r.high))
+}
+
+// indexRange finds the range of an index where can match, or returns false
+// when no index applies. Of the conditions joined by AND, the first one an
+// index applies to is used.
+func (t *table) indexRange(where *Expression) (*indexRange, bool) {
+	if where == nil || len(t.indexes) == 0 {
+		return nil, false
+	}
+
+	switch where.Kind {
+	case BinaryKind:
+		bexp := where.Binary
+		if keyword(bexp.Op.Value) == AndKeyword && bexp.Op.Kind == KeywordKind {
+			if r, ok := t.indexRange(bexp.Left); ok {
+				return r, true
+			}
+			return t.indexRange(bexp.Right)
+		}
+		if bexp.Op.Kind != SymbolKind {
+			return nil, false
+		}
+
+		op := Symbol(bexp.Op.Value)
+		idx, key, ok := t.indexedComparison(bexp.Left, bexp.Right)
+		if !ok {
+			idx, key, ok = t.indexedComparison(bexp.Right, bexp.Left)
+			if !ok {
+				return nil, false
+			}
+			// Flip the operator so the column reads on the left.
+			switch op {
+			case LtSymbol:
+				op = GtSymbol
+			case LteSymbol:
+				op = GteSymbol
+			case GtSymbol:
+				op = LtSymbol
+			case GteSymbol:
+				op = LteSymbol
+			}
+		}
+
+		var low, high indexBound
+		switch op {
+		case EqSymbol:
+			low = indexBound{key: key, inclusive: true}
+			high = low
+		case LtSymbol:
+			high = indexBound{key: key}
+		case LteSymbol:
+			high = indexBound{key: key, inclusive: true}
+		case GtSymbol:
+			low = indexBound{key: key}
+		case GteSymbol:
+			low = indexBound{key: key, inclusive: true}
+		default:
+			return nil, false
+		}
+		return &indexRange{idx: idx, low: low, high: high}, true
+	case BetweenKind:
+		idx, low, ok := t.indexedComparison(where.Between.Left, where.Between.Low)
+		if !ok {
+			return nil, false
+		}
+		_, high, ok := t.indexedComparison(where.Between.Left, where.Between.High)
+		if !ok {
+			return nil, false
+		}
+		return &indexRange{idx: idx, low: indexBound{key: low, inclusive: true}, high: indexBound{key: high, inclusive: true}}, true
+	}
+
+	return nil, false
+}
+
+// indexedComparison matches an indexed column compared with a constant of
+// the column's type, returning the index and the constant's value.
+func (t *table) indexedComparison(column, value *Expression) (*index, MemoryCell, bool) {
+	i, err := t.resolveColumn(column)
+	if err != nil || i == -1 {
+		return nil, nil, false
+	}
+	idx := t.indexOn(i)
+	if idx == nil {
+		return nil, nil, false
+	}
+
+	if !isConstant(value) {
+		return nil, nil, false
+	}
+	// Only a value that converts to the column's type without loss can be
+	// looked up, so an int finds a bigint but a float never finds an int.
+	cell, ct, err := (&table{}).evaluateExpression(nil, value)
+	if err != nil || ct == NullType || !implicit(ct, t.columnTypes[i]) {
+		return nil, nil, false
+	}
+	cell, err = convertCell(cell, ct, t.columnTypes[i])
+	if err != nil {
+		return nil, nil, false
+	}
+	return idx, idx.collation.cellKey(cell), true
+}
+
+// isConstant reports whether exp is a literal or a cast of one, like
+// date '2024-01-31'.
+func isConstant(exp *Expression) bool {
+	if exp.Kind == CastKind {
+		return isConstant(exp.Cast.Operand)
+	}
+	return exp.Kind == LiteralKind && exp.Literal.Kind != IdentifierKind
+}
+
+// candidateSet is indexCandidates as a set. It is nil when no index
+// applies.
+func (t *table) candidateSet(where *Expression) map[int]bool {
+	rows, ok := t.indexCandidates(where)
+	if !ok {
+		return nil
+	}
+	set := make(map[int]bool, len(rows))
+	for _, i := range rows {
+		set[i] = true
+	}
+	return set
+}
+
+// indexRows turns the versions an index holds into rows of t, dropping
+// those a view made by visibleTo does not see.
+func (t *table) indexRows(versions []int) []int {
+	if t.rowOf == nil {
+		return sortedRows(versions)
+	}
+	rows := []int{}
+	for _, v := range versions {
+		if row := t.rowOf[v]; row != -1 {
+			rows = append(rows, row)
+		}
+	}
+	return sortedRows(rows)
+}
+
+func sortedRows(rows []int) []int {
+	sort.Ints(rows)
+	return rows
+}
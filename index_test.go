@@ -0,0 +1,101 @@
+package gosql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkipList(t *testing.T) {
+	s := newSkipList(IntType)
+	for i, key := range []int32{5, 3, 9, 3, 1, 7, 5, 5} {
+		s.insert(intCell(key), i)
+	}
+
+	tests := []struct {
+		low, high indexBound
+		rows      []int
+	}{
+		{indexBound{key: intCell(5), inclusive: true}, indexBound{key: intCell(5), inclusive: true}, []int{0, 6, 7}},
+		{indexBound{}, indexBound{key: intCell(3)}, []int{4}},
+		{indexBound{}, indexBound{key: intCell(3), inclusive: true}, []int{4, 1, 3}},
+		{indexBound{key: intCell(7)}, indexBound{}, []int{2}},
+		{indexBound{key: intCell(3), inclusive: true}, indexBound{key: intCell(7), inclusive: true}, []int{1, 3, 0, 6, 7, 5}},
+		{indexBound{key: intCell(10)}, indexBound{}, nil},
+		{indexBound{}, indexBound{}, []int{4, 1, 3, 0, 6, 7, 5, 2}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.rows, s.scan(test.low, test.high))
+	}
+}
+
+func TestMemoryBackend_CreateIndex(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table users (id int, name text);"+
+		"insert into users values (3, 'carol');"+
+		"insert into users values (1, 'alice');"+
+		"insert into users values (2, 'bob');"+
+		"create index users_id on users (id);"+
+		"insert into users values (4, 'dave');"+
+		"insert into users (name) values ('nobody')")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		where      string
+		candidates []int
+		names      []string
+	}{
+		{"id = 2", []int{2}, []string{"bob"}},
+		{"2 = id", []int{2}, []string{"bob"}},
+		{"id > 2", []int{0, 3}, []string{"carol", "dave"}},
+		{"2 >= id", []int{1, 2}, []string{"alice", "bob"}},
+		{"id between 2 and 3", []int{0, 2}, []string{"carol", "bob"}},
+		{"name = 'dave' and id <= 4", []int{0, 1, 2, 3}, []string{"dave"}},
+		{"id = '2'::int", []int{2}, []string{"bob"}},
+		{"id = 9", nil, nil},
+		{"name = 'bob'", nil, []string{"bob"}},
+		{"id = 1 or id = 2", nil, []string{"alice", "bob"}},
+	}
+
+	for _, test := range tests {
+		ast, err := Parse("select name from users where " + test.where)
+		assert.Nil(t, err)
+		where := ast.Statements[0].SelectStatement.Where
+
+		candidates, ok := mb.tables["users"].indexCandidates(where)
+		if test.candidates != nil || ok {
+			assert.True(t, ok, test.where)
+			assert.Equal(t, test.candidates, candidates, test.where)
+		}
+
+		results, err := mb.Select(context.Background(), ast.Statements[0].SelectStatement)
+		assert.Nil(t, err, test.where)
+		var names []string
+		for _, row := range results.Rows {
+			names = append(names, row[0].AsText())
+		}
+		assert.Equal(t, test.names, names, test.where)
+	}
+
+	_, err = execute(t, mb, "update users set id = 10 where id = 1; delete from users where id = 3")
+	assert.Nil(t, err)
+	results, err := execute(t, mb, "select name from users where id = 10")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, "alice", results.Rows[0][0].AsText())
+	results, err = execute(t, mb, "select name from users where id < 3")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, "bob", results.Rows[0][0].AsText())
+
+	_, err = execute(t, mb, "create index users_id on users (name)")
+	assert.Equal(t, ErrIndexAlreadyExists, err)
+
+	_, err = execute(t, mb, "create index users_age on users (age)")
+	assert.Equal(t, ErrColumnDoesNotExist, err)
+
+	_, err = execute(t, mb, "create index t_id on t (id)")
+	assert.Equal(t, ErrTableDoesNotExist, err)
+}
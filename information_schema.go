@@ -0,0 +1,166 @@
+package gosql
+
+import (
+	"sort"
+	"strings"
+)
+
+// informationSchemaView is a read-only table of the information_schema,
+// computed from the schema of the backend whenever a query reads it.
+type informationSchemaView struct {
+	definition *CreateTableStatement
+	rows       func(schema Schema) [][]MemoryCell
+}
+
+// informationSchema holds the views queries can read under their
+// qualified names, describing the tables the way PostgreSQL's
+// information_schema does. Stored tables belong to the public schema, or
+// to one named after their database.
+var informationSchema map[string]*informationSchemaView
+
+// The views list themselves, so they are set up in init to keep the
+// variable from depending on its own value.
+func init() {
+	informationSchema = map[string]*informationSchemaView{
+		"information_schema.tables": {
+			definition: viewDefinition("create table tables (table_schema text, table_name text, table_type text)"),
+			rows:       informationSchemaTables,
+		},
+		"information_schema.columns": {
+			definition: viewDefinition(`create table columns (
+				table_schema text, table_name text, column_name text,
+				ordinal_position int, column_default text, is_nullable text,
+				data_type text, character_maximum_length int)`),
+			rows: informationSchemaColumns,
+		},
+	}
+}
+
+func viewDefinition(source string) *CreateTableStatement {
+	ast, err := Parse(source)
+	if err != nil {
+		panic(err)
+	}
+	return ast.Statements[0].CreateTableStatement
+}
+
+// table builds the rows of v from schema.
+func (v *informationSchemaView) table(schema Schema) *table {
+	t := &table{name: v.definition.Name.Value, primaryKey: -1}
+	for _, col := range v.definition.Cols {
+		t.columns = append(t.columns, col.Name.Value)
+		t.columnTypes = append(t.columnTypes, columnType(col))
+	}
+	t.rows = v.rows(schema)
+	return t
+}
+
+// schemaTable is a table listed in the information_schema. Tables of the
+// default database are in the public schema, and those of the others in
+// a schema named after their database.
+type schemaTable struct {
+	schema     string
+	name       string
+	definition *CreateTableStatement
+}
+
+// schemaTables lists the stored tables and views of schema and the
+// information_schema views, ordered by schema and name.
+func schemaTables(schema Schema) []schemaTable {
+	var tables []schemaTable
+	for _, crt := range schema {
+		schema, name := "public", crt.Name.Value
+		if database, rest, ok := strings.Cut(name, "."); ok {
+			schema, name = database, rest
+		}
+		tables = append(tables, schemaTable{schema, name, crt})
+	}
+	for _, view := range informationSchema {
+		tables = append(tables, schemaTable{"information_schema", view.definition.Name.Value, view.definition})
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		if tables[i].schema != tables[j].schema {
+			return tables[i].schema < tables[j].schema
+		}
+		return tables[i].name < tables[j].name
+	})
+	return tables
+}
+
+func informationSchemaTables(schema Schema) [][]MemoryCell {
+	var rows [][]MemoryCell
+	for _, t := range schemaTables(schema) {
+		tableType := "BASE TABLE"
+		if t.schema == "information_schema" || t.definition.View != nil {
+			tableType = "VIEW"
+		}
+		rows = append(rows, []MemoryCell{
+			MemoryCell(t.schema),
+			MemoryCell(t.name),
+			MemoryCell(tableType),
+		})
+	}
+	return rows
+}
+
+func informationSchemaColumns(schema Schema) [][]MemoryCell {
+	var rows [][]MemoryCell
+	for _, t := range schemaTables(schema) {
+		for i, col := range t.definition.Cols {
+			columnDefault := nullCell
+			if exp := col.defaultValue(); exp != nil {
+				columnDefault = MemoryCell(formatSQLExpression(exp))
+			}
+			isNullable := "YES"
+			if col.hasConstraint(NotNullConstraint) || col.hasConstraint(PrimaryKeyConstraint) {
+				isNullable = "NO"
+			}
+			maxLength := nullCell
+			if col.Length != nil {
+				length, _, err := numericLiteral(col.Length.Value)
+				if err == nil {
+					maxLength = length
+				}
+			}
+			rows = append(rows, []MemoryCell{
+				MemoryCell(t.schema),
+				MemoryCell(t.name),
+				MemoryCell(col.Name.Value),
+				intCell(int32(i + 1)),
+				columnDefault,
+				MemoryCell(isNullable),
+				MemoryCell(dataTypeName(col)),
+				maxLength,
+			})
+		}
+	}
+	return rows
+}
+
+// dataTypeName is the name information_schema.columns gives the type of
+// col, which is PostgreSQL's rather than the one it was declared with.
+func dataTypeName(col *ColumnDefinition) string {
+	switch keyword(col.Datatype.Value) {
+	case VarcharKeyword:
+		return "character varying"
+	case FloatKeyword, RealKeyword:
+		return "double precision"
+	}
+	switch columnType(col) {
+	case IntType:
+		return "integer"
+	case BigIntType:
+		return "bigint"
+	case BoolType:
+		return "boolean"
+	case DateType:
+		return "date"
+	case TimestampType:
+		return "timestamp without time zone"
+	case JSONType:
+		return "json"
+	case ByteaType:
+		return "bytea"
+	}
+	return "text"
+}
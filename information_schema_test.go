@@ -0,0 +1,57 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInformationSchema(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, `create table users (id int primary key, name varchar(20) not null, score real default 1.5);
+create table orders (id bigint, placed timestamp, data json)`, ScriptOptions{})
+	assert.Nil(t, err)
+
+	results, err := ExecuteScript(mb, "select table_schema, table_name, table_type from information_schema.tables", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{
+		{MemoryCell("information_schema"), MemoryCell("columns"), MemoryCell("VIEW")},
+		{MemoryCell("information_schema"), MemoryCell("tables"), MemoryCell("VIEW")},
+		{MemoryCell("public"), MemoryCell("orders"), MemoryCell("BASE TABLE")},
+		{MemoryCell("public"), MemoryCell("users"), MemoryCell("BASE TABLE")},
+	}, results[0].Results.Rows)
+
+	results, err = ExecuteScript(mb, `select c.column_name, c.ordinal_position, c.column_default, c.is_nullable, c.data_type, c.character_maximum_length
+from information_schema.columns c
+join information_schema.tables on c.table_name = tables.table_name
+where tables.table_schema = 'public' and c.table_name = 'users'
+order by c.ordinal_position`, ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{
+		{MemoryCell("id"), intCell(1), nullCell, MemoryCell("NO"), MemoryCell("integer"), nullCell},
+		{MemoryCell("name"), intCell(2), nullCell, MemoryCell("NO"), MemoryCell("character varying"), intCell(20)},
+		{MemoryCell("score"), intCell(3), MemoryCell("1.5"), MemoryCell("YES"), MemoryCell("double precision"), nullCell},
+	}, results[0].Results.Rows)
+
+	results, err = ExecuteScript(mb, "select data_type from information_schema.columns where table_name = 'orders' order by ordinal_position", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{
+		{MemoryCell("bigint")},
+		{MemoryCell("timestamp without time zone")},
+		{MemoryCell("json")},
+	}, results[0].Results.Rows)
+
+	// The views reflect the schema as it changes.
+	results, err = ExecuteScript(mb, "drop table orders; select count(*) from information_schema.tables where table_schema = 'public'", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, intCell(1).AsInt(), results[1].Results.Rows[0][0].AsInt())
+
+	_, err = ExecuteScript(mb, "select nope from information_schema.tables", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrColumnDoesNotExist)
+
+	_, err = ExecuteScript(mb, "select * from information_schema.schemata", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrTableDoesNotExist)
+
+	_, err = ExecuteScript(mb, "delete from information_schema.tables", ScriptOptions{})
+	assert.Error(t, err)
+}
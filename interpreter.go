@@ -0,0 +1,36 @@
+package gosql
+
+import "context"
+
+// Interpreter runs scripts against a MemoryBackend of its own, for
+// programs that embed gosql and want one call from SQL to rows. The
+// tables a script creates stay for the scripts run after it.
+type Interpreter struct {
+	mb *MemoryBackend
+}
+
+// NewInterpreter returns an Interpreter with an empty database.
+func NewInterpreter() *Interpreter {
+	return &Interpreter{mb: NewMemoryBackend()}
+}
+
+// Exec runs the statements of sql in order and returns the last result
+// set they produced, or nil when none of them returned rows. It stops at
+// the first statement that fails and returns its error.
+func (in *Interpreter) Exec(sql string) (*Results, error) {
+	return in.ExecContext(context.Background(), sql)
+}
+
+// ExecContext is Exec with each statement run under ctx.
+func (in *Interpreter) ExecContext(ctx context.Context, sql string) (*Results, error) {
+	results, err := ExecuteScriptContext(ctx, in.mb, sql, ScriptOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := len(results) - 1; i >= 0; i-- {
+		if results[i].Results != nil {
+			return results[i].Results, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,32 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpreter(t *testing.T) {
+	in := NewInterpreter()
+
+	results, err := in.Exec("create table users (id int primary key, name text);" +
+		"insert into users values (1, 'ada'), (2, 'grace');" +
+		"select name from users order by id desc")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Name: "name", Type: TextType}}, results.Columns)
+	assert.Equal(t, [][]Cell{{MemoryCell("grace")}, {MemoryCell("ada")}}, results.Rows)
+
+	// Tables stay between calls, and statements without rows return none.
+	results, err = in.Exec("insert into users values (3, 'linus')")
+	assert.Nil(t, err)
+	assert.Nil(t, results)
+
+	results, err = in.Exec("select count(*) from users; delete from users where id = 3")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), results.Rows[0][0].AsInt())
+
+	_, err = in.Exec("insert into users values (1, 'again'); select * from users")
+	assert.NotNil(t, err)
+	_, err = in.Exec("select from")
+	assert.NotNil(t, err)
+}
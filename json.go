@@ -0,0 +1,182 @@
+package gosql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// JSON documents are stored as text in a normal form, the way PostgreSQL
+// stores jsonb: whitespace between tokens is dropped, object keys are
+// sorted and only the last of several equal keys is kept. Numbers keep
+// the digits they were written with.
+
+// parseJSON reads a JSON document from text, reporting
+// ErrInvalidTextRepresentation when it does not hold exactly one.
+func parseJSON(s string) (MemoryCell, error) {
+	doc, err := decodeJSON(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w for type json: %s", ErrInvalidTextRepresentation, err)
+	}
+	return encodeJSON(doc)
+}
+
+func decodeJSON(s string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the document")
+	}
+	return doc, nil
+}
+
+func encodeJSON(doc interface{}) (MemoryCell, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return MemoryCell(bytes.TrimSuffix(b.Bytes(), []byte("\n"))), nil
+}
+
+// jsonElement looks up key in doc, which must be an object when key is
+// text and an array when it is an integer. Negative integers count back
+// from the end of an array. The second result is false when there is no
+// such element.
+func jsonElement(doc interface{}, key MemoryCell, kt ColumnType) (interface{}, bool) {
+	switch doc := doc.(type) {
+	case map[string]interface{}:
+		if kt != TextType {
+			return nil, false
+		}
+		element, ok := doc[key.AsText()]
+		return element, ok
+	case []interface{}:
+		if !isInteger(kt) {
+			return nil, false
+		}
+		i := key.AsInt()
+		if i < 0 {
+			i += int64(len(doc))
+		}
+		if i < 0 || i >= int64(len(doc)) {
+			return nil, false
+		}
+		return doc[i], true
+	}
+	return nil, false
+}
+
+// jsonOperatorType is the type of doc -> key or doc ->> key, which takes
+// a JSON document on the left and an object key or array index on the
+// right.
+func jsonOperatorType(op Symbol, lt, rt ColumnType) (ColumnType, bool) {
+	if !implicit(lt, JSONType) || !(implicit(rt, TextType) || isInteger(rt)) {
+		return 0, false
+	}
+	if op == ArrowSymbol {
+		return JSONType, true
+	}
+	return TextType, true
+}
+
+// evaluateJSONOperator applies -> or ->>. doc -> key is the element of
+// doc at key as JSON, and doc ->> key is the element as text, with
+// strings unquoted and JSON null turned into NULL. Both are NULL when doc
+// has no such element.
+func evaluateJSONOperator(op Symbol, doc MemoryCell, lt ColumnType, key MemoryCell, rt ColumnType) (MemoryCell, ColumnType, error) {
+	ct, ok := jsonOperatorType(op, lt, rt)
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: %s expects json and text or an integer, got %s and %s", ErrTypeMismatch, op, lt, rt)
+	}
+	if doc.IsNull() || key.IsNull() {
+		return nullCell, ct, nil
+	}
+
+	value, err := decodeJSON(doc.AsText())
+	if err != nil {
+		return nil, 0, err
+	}
+	element, ok := jsonElement(value, key, rt)
+	if !ok {
+		return nullCell, ct, nil
+	}
+	if op == ArrowSymbol {
+		cell, err := encodeJSON(element)
+		return cell, ct, err
+	}
+
+	switch element := element.(type) {
+	case nil:
+		return nullCell, ct, nil
+	case string:
+		return MemoryCell(element), ct, nil
+	}
+	cell, err := encodeJSON(element)
+	return cell, ct, err
+}
+
+// jsonExtract implements json_extract(doc, path). The path starts at $
+// for the whole document and goes down through .key for object members
+// and [n] for array elements, as in '$.tags[0]'. It returns nil, which
+// is NULL, when nothing is found at the path.
+func jsonExtract(doc, path string) (interface{}, error) {
+	value, err := decodeJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	invalid := fmt.Errorf("%w for JSON path: %q", ErrInvalidTextRepresentation, path)
+	if !strings.HasPrefix(path, "$") {
+		return nil, invalid
+	}
+	rest := path[1:]
+	for rest != "" {
+		var key MemoryCell
+		var kt ColumnType
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[") + 1
+			if end == 0 {
+				end = len(rest)
+			}
+			if end == 1 {
+				return nil, invalid
+			}
+			key, kt, rest = MemoryCell(rest[1:end]), TextType, rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, invalid
+			}
+			i, err := strconv.ParseInt(rest[1:end], 10, 64)
+			if err != nil {
+				return nil, invalid
+			}
+			key, kt, rest = bigIntCell(i), BigIntType, rest[end+1:]
+		default:
+			return nil, invalid
+		}
+
+		var ok bool
+		value, ok = jsonElement(value, key, kt)
+		if !ok {
+			return nil, nil
+		}
+	}
+
+	cell, err := encodeJSON(value)
+	if err != nil {
+		return nil, err
+	}
+	return cell.AsText(), nil
+}
@@ -0,0 +1,81 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJSON(t *testing.T) {
+	tests := []struct {
+		text   string
+		result string
+		ok     bool
+	}{
+		{` { "b": [1, 2.50, true], "a": null } `, `{"a":null,"b":[1,2.50,true]}`, true},
+		{`{"a": 1, "a": 2}`, `{"a":2}`, true},
+		{`"<é>"`, `"<é>"`, true},
+		{`12345678901234567890`, `12345678901234567890`, true},
+		{`{"a": 1`, "", false},
+		{`[1] [2]`, "", false},
+		{``, "", false},
+	}
+	for _, test := range tests {
+		cell, err := parseJSON(test.text)
+		if !test.ok {
+			assert.ErrorIs(t, err, ErrInvalidTextRepresentation, test.text)
+			continue
+		}
+		assert.Nil(t, err, test.text)
+		assert.Equal(t, test.result, cell.AsText(), test.text)
+	}
+}
+
+func TestMemoryBackend_JSON(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, `create table events (id int, data json);
+insert into events values
+  (1, json '{"user": {"name": "alice"}, "tags": ["a", "b"], "n": 1}'),
+  (2, '{"user": {"name": "bob", "admin": true}, "tags": [], "n": null}'::json),
+  (3, NULL)`)
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, `select data -> 'user' ->> 'name', data -> 'tags' -> -1, data ->> 'n', json_extract(data, '$.tags[0]') from events`)
+	assert.Nil(t, err)
+	assert.Equal(t, []ColumnType{TextType, JSONType, TextType, JSONType}, []ColumnType{
+		results.Columns[0].Type, results.Columns[1].Type, results.Columns[2].Type, results.Columns[3].Type,
+	})
+	assert.Equal(t, [][]Cell{
+		{MemoryCell("alice"), MemoryCell(`"b"`), MemoryCell("1"), MemoryCell(`"a"`)},
+		{MemoryCell("bob"), nullCell, nullCell, nullCell},
+		{nullCell, nullCell, nullCell, nullCell},
+	}, results.Rows)
+
+	results, err = execute(t, mb, `select id from events where data -> 'user' ->> 'admin' = 'true' or data ->> 'n' = '1'`)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results.Rows))
+
+	results, err = execute(t, mb, `select data::text from events where id = 2`)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"n":null,"tags":[],"user":{"admin":true,"name":"bob"}}`, results.Rows[0][0].AsText())
+
+	_, err = execute(t, mb, `insert into events values (4, json '{bad}')`)
+	assert.ErrorIs(t, err, ErrInvalidTextRepresentation)
+
+	_, err = execute(t, mb, `select json_extract(data, 'tags') from events`)
+	assert.ErrorIs(t, err, ErrInvalidTextRepresentation)
+
+	_, err = execute(t, mb, `select id -> 'a' from events`)
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+
+	schema := mb.Schema()
+	for _, source := range []string{
+		"select data -> true from events",
+		"select id ->> 'a' from events",
+		"insert into events values (4, 'not cast')",
+	} {
+		ast, err := Parse(source)
+		assert.Nil(t, err, source)
+		assert.Error(t, Validate(ast.Statements[0], schema), source)
+	}
+}
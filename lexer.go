@@ -1,8 +1,13 @@
 package gosql
 
 import (
+	"encoding/hex"
 	"fmt"
+	"io"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Location struct {
@@ -10,22 +15,266 @@ type Location struct {
 	Col  uint
 }
 
+// Offset returns the 1-based character position of loc, whose column
+// counts characters, in source, or 0 when loc is past its end. Clients
+// that point at an error by position, as PostgreSQL's do, take this.
+func (loc Location) Offset(source string) int {
+	var line, col uint
+	position := 1
+	for i := 0; i < len(source); position++ {
+		if line == loc.Line && col == loc.Col {
+			return position
+		}
+		r, size := utf8.DecodeRuneInString(source[i:])
+		if r == '\n' {
+			line++
+			col = 0
+		} else {
+			col++
+		}
+		i += size
+	}
+	return 0
+}
+
 type keyword string
 
 const (
-	SelectKeyword keyword = "select"
-	FromKeyword   keyword = "from"
-	AsKeyword     keyword = "as"
-	TableKeyword  keyword = "table"
-	CreateKeyword keyword = "create"
-	InsertKeyword keyword = "insert"
-	IntoKeyword   keyword = "into"
-	ValuesKeyword keyword = "values"
-	IntKeyword    keyword = "int"
-	TextKeyword   keyword = "text"
-	WhereKeyword  keyword = "where"
+	SelectKeyword    keyword = "select"
+	FromKeyword      keyword = "from"
+	AsKeyword        keyword = "as"
+	TableKeyword     keyword = "table"
+	CreateKeyword    keyword = "create"
+	InsertKeyword    keyword = "insert"
+	IntoKeyword      keyword = "into"
+	ValuesKeyword    keyword = "values"
+	IntKeyword       keyword = "int"
+	IntegerKeyword   keyword = "integer"
+	BigintKeyword    keyword = "bigint"
+	FloatKeyword     keyword = "float"
+	RealKeyword      keyword = "real"
+	TextKeyword      keyword = "text"
+	VarcharKeyword   keyword = "varchar"
+	BoolKeyword      keyword = "bool"
+	BooleanKeyword   keyword = "boolean"
+	DateKeyword      keyword = "date"
+	TimestampKeyword keyword = "timestamp"
+	JsonKeyword      keyword = "json"
+	ByteaKeyword     keyword = "bytea"
+	BlobKeyword      keyword = "blob"
+	WhereKeyword     keyword = "where"
+	AndKeyword       keyword = "and"
+	OrKeyword        keyword = "or"
+	LikeKeyword      keyword = "like"
+	InKeyword        keyword = "in"
+	BetweenKeyword   keyword = "between"
+	DistinctKeyword  keyword = "distinct"
+	OrderKeyword     keyword = "order"
+	ByKeyword        keyword = "by"
+	AscKeyword       keyword = "asc"
+	DescKeyword      keyword = "desc"
+	JoinKeyword      keyword = "join"
+	OnKeyword        keyword = "on"
+	NotKeyword       keyword = "not"
+	NullKeyword      keyword = "null"
+	PrimaryKeyword   keyword = "primary"
+	KeyKeyword       keyword = "key"
+	ReturningKeyword keyword = "returning"
+	UpdateKeyword    keyword = "update"
+	SetKeyword       keyword = "set"
+	DeleteKeyword    keyword = "delete"
+	DropKeyword      keyword = "drop"
+	IfKeyword        keyword = "if"
+	ExistsKeyword    keyword = "exists"
+	IndexKeyword     keyword = "index"
+	UniqueKeyword    keyword = "unique"
+	IsKeyword        keyword = "is"
+	LimitKeyword     keyword = "limit"
+	OffsetKeyword    keyword = "offset"
+	GroupKeyword     keyword = "group"
+	HavingKeyword    keyword = "having"
+	InnerKeyword     keyword = "inner"
+	LeftKeyword      keyword = "left"
+	RightKeyword     keyword = "right"
+	OuterKeyword     keyword = "outer"
+	ExplainKeyword   keyword = "explain"
+	AlterKeyword     keyword = "alter"
+	AddKeyword       keyword = "add"
+	ColumnKeyword    keyword = "column"
+	RenameKeyword    keyword = "rename"
+	ToKeyword        keyword = "to"
+	DefaultKeyword   keyword = "default"
+	CastKeyword      keyword = "cast"
+	CopyKeyword      keyword = "copy"
+	ShowKeyword      keyword = "show"
+	DescribeKeyword  keyword = "describe"
+	UnionKeyword     keyword = "union"
+	IntersectKeyword keyword = "intersect"
+	ExceptKeyword    keyword = "except"
+	AllKeyword       keyword = "all"
+	CaseKeyword      keyword = "case"
+	WhenKeyword      keyword = "when"
+	ThenKeyword      keyword = "then"
+	ElseKeyword      keyword = "else"
+	EndKeyword       keyword = "end"
+	// SERIAL and BIGSERIAL are INT and BIGINT columns that are NOT NULL
+	// and AUTO_INCREMENT.
+	SerialKeyword        keyword = "serial"
+	BigserialKeyword     keyword = "bigserial"
+	AutoIncrementKeyword keyword = "auto_increment"
+	ReferencesKeyword    keyword = "references"
+	RestrictKeyword      keyword = "restrict"
+	CascadeKeyword       keyword = "cascade"
+	CheckKeyword         keyword = "check"
+
+	// Transaction control.
+	BeginKeyword       keyword = "begin"
+	CommitKeyword      keyword = "commit"
+	RollbackKeyword    keyword = "rollback"
+	TransactionKeyword keyword = "transaction"
 )
 
+var keywords = []keyword{
+	SelectKeyword,
+	InsertKeyword,
+	ValuesKeyword,
+	TableKeyword,
+	CreateKeyword,
+	WhereKeyword,
+	FromKeyword,
+	IntoKeyword,
+	TextKeyword,
+	VarcharKeyword,
+	IntKeyword,
+	IntegerKeyword,
+	BigintKeyword,
+	FloatKeyword,
+	RealKeyword,
+	BoolKeyword,
+	BooleanKeyword,
+	DateKeyword,
+	TimestampKeyword,
+	JsonKeyword,
+	ByteaKeyword,
+	BlobKeyword,
+	AndKeyword,
+	OrKeyword,
+	LikeKeyword,
+	InKeyword,
+	BetweenKeyword,
+	AsKeyword,
+	DistinctKeyword,
+	OrderKeyword,
+	ByKeyword,
+	AscKeyword,
+	DescKeyword,
+	JoinKeyword,
+	OnKeyword,
+	NotKeyword,
+	NullKeyword,
+	PrimaryKeyword,
+	KeyKeyword,
+	ReturningKeyword,
+	UpdateKeyword,
+	SetKeyword,
+	DeleteKeyword,
+	DropKeyword,
+	IfKeyword,
+	ExistsKeyword,
+	IndexKeyword,
+	UniqueKeyword,
+	IsKeyword,
+	LimitKeyword,
+	OffsetKeyword,
+	GroupKeyword,
+	HavingKeyword,
+	InnerKeyword,
+	LeftKeyword,
+	RightKeyword,
+	OuterKeyword,
+	ExplainKeyword,
+	AlterKeyword,
+	AddKeyword,
+	ColumnKeyword,
+	RenameKeyword,
+	ToKeyword,
+	DefaultKeyword,
+	CastKeyword,
+	CopyKeyword,
+	ShowKeyword,
+	DescribeKeyword,
+	UnionKeyword,
+	IntersectKeyword,
+	ExceptKeyword,
+	AllKeyword,
+	CaseKeyword,
+	WhenKeyword,
+	ThenKeyword,
+	ElseKeyword,
+	EndKeyword,
+	SerialKeyword,
+	BigserialKeyword,
+	AutoIncrementKeyword,
+	ReferencesKeyword,
+	RestrictKeyword,
+	CascadeKeyword,
+	CheckKeyword,
+	BeginKeyword,
+	CommitKeyword,
+	RollbackKeyword,
+	TransactionKeyword,
+}
+
+// keywordOptions is keywords as plain strings, built once for longestMatch.
+var keywordOptions = func() []string {
+	options := make([]string, 0, len(keywords))
+	for _, k := range keywords {
+		options = append(options, string(k))
+	}
+	return options
+}()
+
+// nonReservedKeywords may also be used as names without quoting, as in
+// create table events (key text, date date). Reserved keywords, which
+// start statements, clauses and expressions, must be quoted.
+var nonReservedKeywords = map[keyword]bool{
+	TextKeyword:        true,
+	VarcharKeyword:     true,
+	IntKeyword:         true,
+	IntegerKeyword:     true,
+	BigintKeyword:      true,
+	FloatKeyword:       true,
+	RealKeyword:        true,
+	BoolKeyword:        true,
+	BooleanKeyword:     true,
+	DateKeyword:        true,
+	TimestampKeyword:   true,
+	JsonKeyword:        true,
+	ByteaKeyword:       true,
+	BlobKeyword:        true,
+	SerialKeyword:      true,
+	BigserialKeyword:   true,
+	KeyKeyword:         true,
+	IndexKeyword:       true,
+	AddKeyword:         true,
+	RenameKeyword:      true,
+	RestrictKeyword:    true,
+	CascadeKeyword:     true,
+	ExplainKeyword:     true,
+	CopyKeyword:        true,
+	ShowKeyword:        true,
+	DescribeKeyword:    true,
+	BeginKeyword:       true,
+	CommitKeyword:      true,
+	RollbackKeyword:    true,
+	TransactionKeyword: true,
+}
+
+// isNonReserved reports whether t is a keyword that may be used as a name.
+func isNonReserved(t *Token) bool {
+	return t.Kind == KeywordKind && nonReservedKeywords[keyword(t.Value)]
+}
+
 type Symbol string
 
 const (
@@ -34,8 +283,57 @@ const (
 	CommaSymbol      Symbol = ","
 	LeftparenSymbol  Symbol = "("
 	RightparenSymbol Symbol = ")"
+	EqSymbol         Symbol = "="
+	NeqSymbol        Symbol = "<>"
+	BangEqSymbol     Symbol = "!="
+	LtSymbol         Symbol = "<"
+	LteSymbol        Symbol = "<="
+	GtSymbol         Symbol = ">"
+	GteSymbol        Symbol = ">="
+	DotSymbol        Symbol = "."
+	ConcatSymbol     Symbol = "||"
+	CastSymbol       Symbol = "::"
+	PlusSymbol       Symbol = "+"
+	MinusSymbol      Symbol = "-"
+	SlashSymbol      Symbol = "/"
+	PercentSymbol    Symbol = "%"
+	ArrowSymbol      Symbol = "->"
+	ArrowTextSymbol  Symbol = "->>"
 )
 
+var symbols = []Symbol{
+	SemiColonSymbol,
+	AsteriskSymbol,
+	CommaSymbol,
+	LeftparenSymbol,
+	RightparenSymbol,
+	EqSymbol,
+	NeqSymbol,
+	BangEqSymbol,
+	LtSymbol,
+	LteSymbol,
+	GtSymbol,
+	GteSymbol,
+	DotSymbol,
+	ConcatSymbol,
+	CastSymbol,
+	PlusSymbol,
+	MinusSymbol,
+	SlashSymbol,
+	PercentSymbol,
+	ArrowSymbol,
+	ArrowTextSymbol,
+}
+
+// symbolOptions is symbols as plain strings, built once for longestMatch.
+var symbolOptions = func() []string {
+	options := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		options = append(options, string(s))
+	}
+	return options
+}()
+
 type TokenKind uint
 
 const (
@@ -44,51 +342,353 @@ const (
 	IdentifierKind
 	StringKind
 	NumericKind
+	BoolKind
+	CommentKind
+	ParameterKind
+	// BytesKind is an X'...' literal. Its Value holds the bytes its hex
+	// digits spell.
+	BytesKind
 )
 
 type Token struct {
 	Value string
 	Kind  TokenKind
 	Loc   Location
+	// End is the location just past the last character of the token's
+	// source text, and Offset and EndOffset are the byte offsets of Loc
+	// and End, so that source[Offset:EndOffset] is the text, quotes and
+	// all. Tokens the parser makes up may have no more than a Loc.
+	End               Location
+	Offset, EndOffset uint
 }
 
+// cursor is a position in the source. pointer is a byte offset, while the
+// columns of loc count characters, so that they match what an editor
+// shows.
 type cursor struct {
 	pointer uint
 	loc     Location
 }
 
+// span sets where t is in the source from the cursors at its start and
+// end, in a source whose first base bytes were dropped before them.
+func (t *Token) span(start, end cursor, base uint) {
+	t.Loc, t.End = start.loc, end.loc
+	t.Offset, t.EndOffset = base+start.pointer, base+end.pointer
+}
+
 func (t *Token) equals(other *Token) bool {
 	return t.Value == other.Value && t.Kind == other.Kind
 }
 
+// String renders the token as SQL source. String literals are wrapped in
+// single quotes with their quotes doubled, or written as E'...' strings
+// with backslash escapes when they hold a control character, and
+// identifiers that would not lex back to themselves are wrapped in double
+// quotes, doubling theirs.
+func (t *Token) String() string {
+	switch t.Kind {
+	case StringKind:
+		if needsEscapes(t.Value) {
+			return "E'" + escapeString(t.Value) + "'"
+		}
+		return "'" + strings.ReplaceAll(t.Value, "'", "''") + "'"
+	case BytesKind:
+		return "X'" + hex.EncodeToString([]byte(t.Value)) + "'"
+	case IdentifierKind:
+		if needsQuoting(t.Value) {
+			return `"` + strings.ReplaceAll(t.Value, `"`, `""`) + `"`
+		}
+	}
+	return t.Value
+}
+
+func needsQuoting(identifier string) bool {
+	if identifier == "" {
+		return true
+	}
+	if _, _, ok := lexKeyword(identifier, cursor{}); ok {
+		return true
+	}
+	if _, _, ok := lexBool(identifier, cursor{}); ok {
+		return true
+	}
+	token, cur, ok := lexIdentifier(identifier, cursor{})
+	return !ok || cur.pointer != uint(len(identifier)) || token.Value != identifier
+}
+
+// Reconstruct joins tokens back into SQL source. The output is not
+// byte-for-byte identical to the original input but lexes to an equivalent
+// token stream.
+func Reconstruct(tokens []*Token) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 && isLineComment(tokens[i-1]) {
+			b.WriteByte('\n')
+		} else if i > 0 && !noSpaceBetween(tokens[i-1], t) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.String())
+	}
+	return b.String()
+}
+
+func isLineComment(t *Token) bool {
+	return t.Kind == CommentKind && strings.HasPrefix(t.Value, "--")
+}
+
+// isHint reports whether t is a comment holding optimizer hints, /*+ like
+// this */.
+func isHint(t *Token) bool {
+	return t.Kind == CommentKind && strings.HasPrefix(t.Value, "/*+")
+}
+
+func noSpaceBetween(prev, next *Token) bool {
+	if prev.Kind == SymbolKind && prev.Value == string(LeftparenSymbol) {
+		return true
+	}
+	if next.Kind != SymbolKind {
+		return false
+	}
+	switch Symbol(next.Value) {
+	case CommaSymbol, SemiColonSymbol, RightparenSymbol:
+		return true
+	}
+	return false
+}
+
 type lexer func(string, cursor) (*Token, cursor, bool)
 
+var lexers = []lexer{skipComment, lexBool, lexKeyword, lexParameter, lexSymbol, lexNumeric, lexString, lexIdentifier}
+
+// LexConfig holds the keywords and symbols the lexer recognizes, so that
+// dialect-specific words like ilike or returning can be added without
+// changing the package.
+type LexConfig struct {
+	Keywords []string
+	Symbols  []string
+	// Comments keeps comments in the output as CommentKind tokens instead
+	// of discarding them, so tools can round-trip them.
+	Comments bool
+	// Backticks and Brackets accept identifiers quoted the way MySQL
+	// quotes them, `like this`, and the way SQL Server does, [like this],
+	// besides the standard "like this".
+	Backticks bool
+	Brackets  bool
+	// Placeholders says which placeholders for parameters are taken.
+	Placeholders PlaceholderStyle
+	// CaseSensitive keeps the case of identifiers that are not quoted
+	// instead of folding them to lower case.
+	CaseSensitive bool
+}
+
+// DefaultLexConfig returns the keywords and symbols used by Parse.
+func DefaultLexConfig() LexConfig {
+	return LexConfig{
+		Keywords: append([]string{}, keywordOptions...),
+		Symbols:  append([]string{}, symbolOptions...),
+	}
+}
+
+func (cfg LexConfig) lexers() []lexer {
+	var keywords []string
+	for _, k := range cfg.Keywords {
+		keywords = append(keywords, strings.ToLower(k))
+	}
+	symbols := cfg.Symbols
+
+	comments := skipComment
+	if cfg.Comments {
+		comments = lexComment
+	}
+	placeholders := cfg.Placeholders
+	caseSensitive := cfg.CaseSensitive
+
+	lexers := []lexer{
+		comments,
+		lexBool,
+		func(source string, ic cursor) (*Token, cursor, bool) {
+			return lexKeywordFrom(source, ic, keywords)
+		},
+		func(source string, ic cursor) (*Token, cursor, bool) {
+			return lexParameterStyle(source, ic, placeholders)
+		},
+		func(source string, ic cursor) (*Token, cursor, bool) {
+			return lexSymbolFrom(source, ic, symbols)
+		},
+		lexNumeric,
+		lexString,
+	}
+	if cfg.Backticks {
+		lexers = append(lexers, func(source string, ic cursor) (*Token, cursor, bool) {
+			return lexQuotedIdentifier(source, ic, '`', '`')
+		})
+	}
+	if cfg.Brackets {
+		lexers = append(lexers, func(source string, ic cursor) (*Token, cursor, bool) {
+			return lexQuotedIdentifier(source, ic, '[', ']')
+		})
+	}
+	return append(lexers, func(source string, ic cursor) (*Token, cursor, bool) {
+		return lexIdentifierCase(source, ic, caseSensitive)
+	})
+}
+
 func lex(source string) ([]*Token, error) {
+	return lexWith(source, lexers)
+}
+
+// LexWithConfig lexes source recognizing the keywords and symbols of cfg
+// instead of the defaults.
+func LexWithConfig(source string, cfg LexConfig) ([]*Token, error) {
+	return lexWith(source, cfg.lexers())
+}
+
+func lexWith(source string, lexers []lexer) ([]*Token, error) {
+	return lexFrom(source, cursor{}, 0, lexers)
+}
+
+// lexHint lexes the text of the optimizer hints comment t, placing the
+// tokens where they are in the source t was lexed from.
+func lexHint(t *Token) ([]*Token, error) {
+	start := cursor{pointer: uint(len("/*+")), loc: t.Loc}
+	start.loc.Col += start.pointer
+	return lexFrom(strings.TrimSuffix(t.Value, "*/"), start, t.Offset, lexers)
+}
+
+// lexFrom lexes source from cur on. base is where source starts in the
+// input it was taken from, which token offsets count from.
+func lexFrom(source string, cur cursor, base uint, lexers []lexer) ([]*Token, error) {
 	tokens := []*Token{}
-	cur := cursor{}
 
 lex:
 
 	for cur.pointer < uint(len(source)) {
-		lexers := []lexer{lexKeyword, lexSymbol, lexNumeric, lexString, lexIdentifier}
 		for _, l := range lexers {
 			if token, newCursor, ok := l(source, cur); ok {
-				cur = newCursor
 				if token != nil {
+					token.span(cur, newCursor, base)
 					tokens = append(tokens, token)
 				}
+				cur = newCursor
 				continue lex
 			}
 		}
-		hint := ""
+		var last *Token
 		if len(tokens) > 0 {
-			hint = " after " + tokens[len(tokens)-1].Value
+			last = tokens[len(tokens)-1]
 		}
-		return nil, fmt.Errorf("Unable to lex token%s, at %d:%d", hint, cur.loc.Line, cur.loc.Col)
+		return nil, lexError(source, cur, last)
 	}
 	return tokens, nil
 
 }
+
+// lexError reports that no lexer matches source at cur. last is the
+// token before it, or nil at the start of the input.
+func lexError(source string, cur cursor, last *Token) error {
+	hint := ""
+	if last != nil {
+		hint = " after " + last.Value
+	}
+	r, _ := utf8.DecodeRuneInString(source[cur.pointer:])
+	return &Error{
+		Code:    SyntaxError,
+		Loc:     cur.loc,
+		Snippet: string(r),
+		msg:     fmt.Sprintf("Unable to lex token%s, at %d:%d", hint, cur.loc.Line, cur.loc.Col),
+		located: true,
+	}
+}
+
+// Lexer lexes tokens one at a time from a reader, keeping only the part
+// of the input it has not lexed yet in memory, so that large scripts like
+// dumps can be processed without reading them whole.
+type Lexer struct {
+	r      io.Reader
+	lexers []lexer
+	// buf holds the input read but not yet lexed from cur.pointer on.
+	// Locations in cur count from the start of the input, and base is
+	// how many bytes of it were dropped before buf.
+	buf  string
+	base uint
+	cur  cursor
+	last *Token
+	eof  bool
+	err  error
+}
+
+// NewLexer returns a Lexer reading from r with the default keywords and
+// symbols.
+func NewLexer(r io.Reader) *Lexer {
+	return &Lexer{r: r, lexers: lexers}
+}
+
+// Next returns the next token, skipping whitespace and comments other
+// than optimizer hints. It returns io.EOF after the last token, and keeps
+// returning the first error it hits after that.
+func (l *Lexer) Next() (*Token, error) {
+	for l.err == nil {
+		if l.cur.pointer == uint(len(l.buf)) {
+			if l.eof {
+				l.err = io.EOF
+				break
+			}
+			l.err = l.fill()
+			continue
+		}
+
+		matched := false
+		for _, lx := range l.lexers {
+			token, newCursor, ok := lx(l.buf, l.cur)
+			if !ok {
+				continue
+			}
+			// A token running up to the end of what has been read may go
+			// on in the rest of the input, as may one that stops at a
+			// character that has not been read whole.
+			if !l.eof && !utf8.FullRuneInString(l.buf[newCursor.pointer:]) {
+				break
+			}
+			matched = true
+			start := l.cur
+			l.cur = newCursor
+			if token != nil {
+				token.span(start, newCursor, l.base)
+				l.last = token
+				return token, nil
+			}
+			break
+		}
+
+		switch {
+		case matched:
+		case l.eof:
+			l.err = lexError(l.buf, l.cur, l.last)
+		default:
+			// Nothing matches yet, but a string or quoted identifier may
+			// be closed further on.
+			l.err = l.fill()
+		}
+	}
+	return nil, l.err
+}
+
+// fill drops the lexed part of the buffer and reads more input onto it.
+func (l *Lexer) fill() error {
+	l.buf = l.buf[l.cur.pointer:]
+	l.base += l.cur.pointer
+	l.cur.pointer = 0
+
+	chunk := make([]byte, 4096)
+	n, err := l.r.Read(chunk)
+	l.buf += string(chunk[:n])
+	if err == io.EOF {
+		l.eof = true
+		return nil
+	}
+	return err
+}
 func lexNumeric(source string, ic cursor) (*Token, cursor, bool) {
 	cur := ic
 	periodFound := false
@@ -96,7 +696,6 @@ func lexNumeric(source string, ic cursor) (*Token, cursor, bool) {
 
 	for ; cur.pointer < uint(len(source)); cur.pointer++ {
 		c := source[cur.pointer]
-		cur.loc.Col++
 		isDigit := c >= '0' && c <= '9'
 		isPeriod := c == '.'
 		isExpMarker := c == 'e'
@@ -109,10 +708,12 @@ func lexNumeric(source string, ic cursor) (*Token, cursor, bool) {
 			continue
 		}
 		if isPeriod {
-			//period comes only one
+			// A number has at most one period, and none after its
+			// exponent.
 			if periodFound {
 				return nil, ic, false
 			}
+			periodFound = true
 			continue
 		}
 
@@ -129,7 +730,6 @@ func lexNumeric(source string, ic cursor) (*Token, cursor, bool) {
 			cNext := source[cur.pointer+1]
 			if cNext == '-' || cNext == '+' {
 				cur.pointer++
-				cur.loc.Col++
 			}
 			continue
 		}
@@ -140,6 +740,7 @@ func lexNumeric(source string, ic cursor) (*Token, cursor, bool) {
 	if cur.pointer == ic.pointer {
 		return nil, ic, false
 	}
+	cur.loc.Col += cur.pointer - ic.pointer
 	return &Token{
 		Value: source[ic.pointer:cur.pointer],
 		Loc:   ic.loc,
@@ -147,80 +748,292 @@ func lexNumeric(source string, ic cursor) (*Token, cursor, bool) {
 	}, cur, true
 }
 
-func lexCharacterDelimited(source string, ic cursor, delimiter byte) (*Token, cursor, bool) {
+// lexCharacterDelimited lexes text between the delimiters left and right,
+// in which a doubled right stands for itself. With escapes set a backslash
+// starts one of the escape sequences of E'...' strings: \b, \f, \n, \r and \t
+// for control characters, \uXXXX and \UXXXXXXXX for a character given by
+// its hexadecimal code point, and otherwise the character that follows, as
+// in \' or \\. Text with an invalid escape does not lex.
+func lexCharacterDelimited(source string, ic cursor, left, right byte, escapes bool) (*Token, cursor, bool) {
 	cur := ic
 	if len(source[cur.pointer:]) == 0 {
 		return nil, ic, false
 	}
-	if source[cur.pointer] != delimiter {
+	if source[cur.pointer] != left {
 		return nil, ic, false
 	}
 	cur.loc.Col++
 	cur.pointer++
 	var value []byte
-	for ; cur.pointer < uint(len(source)); cur.pointer++ {
-		c := source[cur.pointer]
-		if c == delimiter {
-			if cur.pointer+1 >= uint(len(source)) || source[cur.pointer+1] != delimiter {
+	for cur.pointer < uint(len(source)) {
+		if source[cur.pointer] == right {
+			if cur.pointer+1 >= uint(len(source)) || source[cur.pointer+1] != right {
+				cur.pointer++
+				cur.loc.Col++
 				return &Token{
 					Value: string(value),
 					Loc:   ic.loc,
 					Kind:  StringKind,
 				}, cur, true
-			} else {
-				value = append(value, delimiter)
-				cur.pointer++
-				cur.loc.Col++
 			}
+			value = append(value, right)
+			cur.pointer += 2
+			cur.loc.Col += 2
+			continue
+		}
 
+		if escapes && source[cur.pointer] == '\\' {
+			r, n, ok := unescape(source[cur.pointer+1:])
+			if !ok {
+				return nil, ic, false
+			}
+			value = utf8.AppendRune(value, r)
+			cur.pointer += uint(1 + n)
+			cur.loc.Col += uint(1 + utf8.RuneCountInString(source[cur.pointer-uint(n):cur.pointer]))
+			continue
+		}
+
+		// Characters are copied whole, each moving the column by one.
+		r, size := utf8.DecodeRuneInString(source[cur.pointer:])
+		value = append(value, source[cur.pointer:cur.pointer+uint(size)]...)
+		cur.pointer += uint(size)
+		if r == '\n' {
+			cur.loc.Line++
+			cur.loc.Col = 0
+			continue
 		}
-		value = append(value, c)
 		cur.loc.Col++
 	}
 	return nil, ic, false
 }
-func lexString(source string, ic cursor) (*Token, cursor, bool) {
-	return lexCharacterDelimited(source, ic, '\'')
+
+// lexComment lexes a -- comment running to the end of the line or a
+// /* */ block comment, which may span lines. The token value includes the
+// comment markers.
+func lexComment(source string, ic cursor) (*Token, cursor, bool) {
+	rest := source[ic.pointer:]
+	cur := ic
+
+	var end int
+	switch {
+	case strings.HasPrefix(rest, "--"):
+		end = strings.IndexByte(rest, '\n')
+		if end == -1 {
+			end = len(rest)
+		}
+	case strings.HasPrefix(rest, "/*"):
+		end = strings.Index(rest[2:], "*/")
+		if end == -1 {
+			return nil, ic, false
+		}
+		end += len("/**/")
+	default:
+		return nil, ic, false
+	}
+
+	value := rest[:end]
+	for _, r := range value {
+		if r == '\n' {
+			cur.loc.Line++
+			cur.loc.Col = 0
+			continue
+		}
+		cur.loc.Col++
+	}
+	cur.pointer += uint(end)
+
+	return &Token{
+		Value: value,
+		Loc:   ic.loc,
+		Kind:  CommentKind,
+	}, cur, true
 }
 
-func longestMatch(source string, ic cursor, options []string) string {
-	var value []byte
-	var skipList []int
-	var match string
+// skipComment consumes a comment like lexComment without emitting a
+// token, except for optimizer hints, which the parser reads.
+func skipComment(source string, ic cursor) (*Token, cursor, bool) {
+	token, cur, ok := lexComment(source, ic)
+	if ok && isHint(token) {
+		return token, cur, true
+	}
+	return nil, cur, ok
+}
+
+// lexParameter lexes a ? placeholder or a numbered one like $1.
+func lexParameter(source string, ic cursor) (*Token, cursor, bool) {
+	return lexParameterStyle(source, ic, AnyPlaceholders)
+}
 
+// lexParameterStyle lexes the placeholders style allows.
+func lexParameterStyle(source string, ic cursor, style PlaceholderStyle) (*Token, cursor, bool) {
 	cur := ic
-	for cur.pointer < uint(len(source)) {
-		value = append(value, strings.ToLower(string(source[cur.pointer]))...)
+	switch source[cur.pointer] {
+	case '?':
+		if style == DollarPlaceholders {
+			return nil, ic, false
+		}
 		cur.pointer++
-	match:
-		for i, option := range options {
-			for _, skip := range skipList {
-				if i == skip {
-					continue match
-				}
-			}
-			if option == string(value) {
-				skipList = append(skipList, i)
-				if len(option) > len(match) {
-					match = option
-				}
-				continue
-			}
-			sharePrefix := string(value) == option[:cur.pointer-ic.pointer]
-			tooLong := len(value) > len(options)
-			if tooLong || !sharePrefix {
-				skipList = append(skipList, i)
+	case '$':
+		if style == QuestionPlaceholders {
+			return nil, ic, false
+		}
+		cur.pointer++
+		for cur.pointer < uint(len(source)) && source[cur.pointer] >= '0' && source[cur.pointer] <= '9' {
+			cur.pointer++
+		}
+		if cur.pointer == ic.pointer+1 {
+			return nil, ic, false
+		}
+	default:
+		return nil, ic, false
+	}
+	cur.loc.Col += cur.pointer - ic.pointer
+
+	return &Token{
+		Value: source[ic.pointer:cur.pointer],
+		Loc:   ic.loc,
+		Kind:  ParameterKind,
+	}, cur, true
+}
+
+// lexString lexes a string literal, either a plain one or an E'...' string,
+// whose backslash escapes are replaced by the characters they stand for,
+// or an X'...' binary string of hex digits.
+func lexString(source string, ic cursor) (*Token, cursor, bool) {
+	rest := source[ic.pointer:]
+	if len(rest) >= 2 && (rest[0] == 'x' || rest[0] == 'X') && rest[1] == '\'' {
+		return lexBytes(source, ic)
+	}
+	if len(rest) < 2 || rest[0] != 'e' && rest[0] != 'E' || rest[1] != '\'' {
+		return lexCharacterDelimited(source, ic, '\'', '\'', false)
+	}
+
+	cur := ic
+	cur.pointer++
+	cur.loc.Col++
+	token, cur, ok := lexCharacterDelimited(source, cur, '\'', '\'', true)
+	if !ok {
+		return nil, ic, false
+	}
+	token.Loc = ic.loc
+	return token, cur, true
+}
+
+// lexBytes lexes an X'...' binary string. Its hex digits must come in
+// pairs, each spelling a byte.
+func lexBytes(source string, ic cursor) (*Token, cursor, bool) {
+	cur := ic
+	cur.pointer++
+	cur.loc.Col++
+	token, cur, ok := lexCharacterDelimited(source, cur, '\'', '\'', false)
+	if !ok {
+		return nil, ic, false
+	}
+	b, err := hex.DecodeString(token.Value)
+	if err != nil {
+		return nil, ic, false
+	}
+	token.Value, token.Kind, token.Loc = string(b), BytesKind, ic.loc
+	return token, cur, true
+}
+
+var escapeSequences = map[byte]rune{'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t'}
+
+// unescape decodes the escape sequence s starts with, just after its
+// backslash, and returns the character it stands for and its length.
+func unescape(s string) (rune, int, bool) {
+	if s == "" {
+		return 0, 0, false
+	}
+	if r, ok := escapeSequences[s[0]]; ok {
+		return r, 1, true
+	}
+
+	digits := 0
+	switch s[0] {
+	case 'u':
+		digits = 4
+	case 'U':
+		digits = 8
+	default:
+		r, n := utf8.DecodeRuneInString(s)
+		return r, n, true
+	}
+	if len(s) < 1+digits {
+		return 0, 0, false
+	}
+	code, err := strconv.ParseUint(s[1:1+digits], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) || code == 0 {
+		return 0, 0, false
+	}
+	return rune(code), 1 + digits, true
+}
+
+// needsEscapes reports whether a string literal holding value has to be
+// written as an E'...' string, which it does when value has a control
+// character other than a tab or a line break.
+func needsEscapes(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; c < ' ' && c != '\t' && c != '\n' {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeString writes value for the inside of an E'...' string.
+func escapeString(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch r {
+		case '\'', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < ' ' {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
 			}
 		}
+	}
+	return b.String()
+}
 
-		if len(skipList) == len(options) {
-			break
+// longestMatch returns the longest option that source starts with at ic,
+// ignoring case, or "" when none match. Options must be lower case.
+func longestMatch(source string, ic cursor, options []string) string {
+	rest := source[ic.pointer:]
+	var match string
+	for _, option := range options {
+		if len(option) <= len(match) || len(option) > len(rest) {
+			continue
+		}
+		if strings.ToLower(rest[:len(option)]) == option {
+			match = option
 		}
 	}
 	return match
 }
 
 func lexSymbol(source string, ic cursor) (*Token, cursor, bool) {
+	return lexSymbolFrom(source, ic, symbolOptions)
+}
+
+func lexSymbolFrom(source string, ic cursor, symbols []string) (*Token, cursor, bool) {
+	if ic.pointer >= uint(len(source)) {
+		return nil, ic, false
+	}
 	cur := ic
 	c := source[cur.pointer]
 	cur.loc.Col++
@@ -236,19 +1049,17 @@ func lexSymbol(source string, ic cursor) (*Token, cursor, bool) {
 		return nil, cur, true
 
 	}
-	symbols := []Symbol{
-		SemiColonSymbol,
-		AsteriskSymbol,
-		CommaSymbol,
-		LeftparenSymbol,
-		RightparenSymbol,
+	match := longestMatch(source, ic, symbols)
+	if match == "" {
+		return nil, ic, false
 	}
-	var options []string
-	for _, s := range symbols {
-		options = append(options, string(s))
+	// A period followed by a digit starts a number like .5, not a dot.
+	if match == string(DotSymbol) && cur.pointer < uint(len(source)) && source[cur.pointer] >= '0' && source[cur.pointer] <= '9' {
+		return nil, ic, false
 	}
-	match := longestMatch(source, ic, options)
-	if match == "" {
+	// A slash followed by an asterisk starts a comment, which only gets
+	// here when it is never closed.
+	if match == string(SlashSymbol) && cur.pointer < uint(len(source)) && source[cur.pointer] == '*' {
 		return nil, ic, false
 	}
 	cur.pointer = ic.pointer + uint(len(match))
@@ -262,27 +1073,21 @@ func lexSymbol(source string, ic cursor) (*Token, cursor, bool) {
 }
 
 func lexKeyword(source string, ic cursor) (*Token, cursor, bool) {
+	return lexKeywordFrom(source, ic, keywordOptions)
+}
+
+func lexKeywordFrom(source string, ic cursor, keywords []string) (*Token, cursor, bool) {
 	cur := ic
-	keywords := []keyword{
-		SelectKeyword,
-		InsertKeyword,
-		ValuesKeyword,
-		TableKeyword,
-		CreateKeyword,
-		WhereKeyword,
-		FromKeyword,
-		IntoKeyword,
-		TextKeyword,
-		IntKeyword,
-	}
-	var options []string
-	for _, k := range keywords {
-		options = append(options, string(k))
-	}
-	match := longestMatch(source, ic, options)
+	match := longestMatch(source, ic, keywords)
 	if match == "" {
 		return nil, ic, false
 	}
+	// A keyword immediately followed by more identifier characters is
+	// really the start of an identifier, e.g. fromage or selects.
+	end := ic.pointer + uint(len(match))
+	if r, _ := utf8.DecodeRuneInString(source[end:]); end < uint(len(source)) && isIdentifierContinuation(r) {
+		return nil, ic, false
+	}
 	cur.pointer = ic.pointer + uint(len(match))
 	cur.loc.Col = ic.loc.Col + uint(len(match))
 
@@ -293,37 +1098,73 @@ func lexKeyword(source string, ic cursor) (*Token, cursor, bool) {
 	}, cur, true
 }
 
+var boolOptions = []string{"true", "false"}
+
+// lexBool lexes the true and false literals. They share keyword matching
+// rules but produce BoolKind tokens so the parser can treat them as values.
+func lexBool(source string, ic cursor) (*Token, cursor, bool) {
+	token, cur, ok := lexKeywordFrom(source, ic, boolOptions)
+	if !ok {
+		return nil, ic, false
+	}
+	token.Kind = BoolKind
+	return token, cur, true
+}
+
 func lexIdentifier(source string, ic cursor) (*Token, cursor, bool) {
+	return lexIdentifierCase(source, ic, false)
+}
+
+// lexIdentifierCase lexes an identifier, folding it to lower case unless
+// it is quoted or caseSensitive is set.
+func lexIdentifierCase(source string, ic cursor, caseSensitive bool) (*Token, cursor, bool) {
 
-	if token, newCursor, ok := lexCharacterDelimited(source, ic, '"'); ok {
+	if token, newCursor, ok := lexCharacterDelimited(source, ic, '"', '"', false); ok {
+		token.Kind = IdentifierKind
 		return token, newCursor, true
 	}
+	// Identifiers start with a letter of any script, like señor or 名前.
+	if ic.pointer >= uint(len(source)) {
+		return nil, ic, false
+	}
 	cur := ic
-	c := source[cur.pointer]
-	isAlpha := (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
-	if !isAlpha {
+	r, size := utf8.DecodeRuneInString(source[cur.pointer:])
+	if !unicode.IsLetter(r) {
 		return nil, ic, false
 	}
-	cur.pointer++
-	cur.loc.Col++
-	value := []byte{c}
-	for ; cur.pointer < uint(len(source)); cur.pointer++ {
-		c = source[cur.pointer]
-		isAlpha := (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
-		isNumeric := c >= '0' && c <= '9'
-		if isAlpha || isNumeric || c == '$' || c == '_' {
-			value = append(value, c)
-			cur.loc.Col++
-			continue
+	for {
+		cur.pointer += uint(size)
+		cur.loc.Col++
+		if cur.pointer == uint(len(source)) {
+			break
+		}
+		r, size = utf8.DecodeRuneInString(source[cur.pointer:])
+		if !isIdentifierContinuation(r) {
+			break
 		}
-		break
 	}
-	if len(value) == 0 {
-		return nil, ic, false
+	value := source[ic.pointer:cur.pointer]
+	if !caseSensitive {
+		value = strings.ToLower(value)
 	}
 	return &Token{
-		Value: strings.ToLower(string(value)),
+		Value: value,
 		Loc:   ic.loc,
 		Kind:  IdentifierKind,
 	}, cur, true
 }
+
+// lexQuotedIdentifier lexes an identifier quoted between left and right,
+// which keeps its case like one in double quotes.
+func lexQuotedIdentifier(source string, ic cursor, left, right byte) (*Token, cursor, bool) {
+	token, cur, ok := lexCharacterDelimited(source, ic, left, right, false)
+	if !ok {
+		return nil, ic, false
+	}
+	token.Kind = IdentifierKind
+	return token, cur, true
+}
+
+func isIdentifierContinuation(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || r == '$' || r == '_'
+}
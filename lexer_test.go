@@ -1,8 +1,11 @@
 package gosql
 
 import (
+	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -133,7 +136,7 @@ func TestToken_lexString(t *testing.T) {
 		assert.Equal(t, test.string, ok, test.value)
 		if ok {
 			test.value = strings.TrimSpace(test.value)
-			assert.Equal(t, test.value[1:len(test.value)-1], tok.Value, test.value)
+			assert.Equal(t, strings.ReplaceAll(test.value[1:len(test.value)-1], "''", "'"), tok.Value, test.value)
 		}
 	}
 }
@@ -151,6 +154,55 @@ func TestToken_lexSymbol(t *testing.T) {
 			symbol: true,
 			value:  "||",
 		},
+		{
+			symbol: true,
+			value:  ". ",
+		},
+		{
+			symbol: true,
+			value:  "<>",
+		},
+		{
+			symbol: true,
+			value:  "<= ",
+		},
+		{
+			symbol: true,
+			value:  ">=",
+		},
+		{
+			symbol: true,
+			value:  "< ",
+		},
+		{
+			symbol: true,
+			value:  "!=",
+		},
+		{
+			symbol: true,
+			value:  "::",
+		},
+		{
+			symbol: true,
+			value:  "- ",
+		},
+		{
+			symbol: true,
+			value:  "%",
+		},
+		{
+			symbol: true,
+			value:  "/ ",
+		},
+		// false tests
+		{
+			symbol: false,
+			value:  ".5",
+		},
+		{
+			symbol: false,
+			value:  "/*",
+		},
 	}
 
 	for _, test := range tests {
@@ -204,6 +256,16 @@ func TestToken_lexIdentifier(t *testing.T) {
 			input:      `"userName"`,
 			value:      "userName",
 		},
+		{
+			Identifier: true,
+			input:      "Señor=1",
+			value:      "señor",
+		},
+		{
+			Identifier: true,
+			input:      "名前 ",
+			value:      "名前",
+		},
 		// false tests
 		{
 			Identifier: false,
@@ -266,6 +328,26 @@ func TestToken_lexKeyword(t *testing.T) {
 			keyword: false,
 			value:   "flubbrety",
 		},
+		{
+			keyword: false,
+			value:   "fromage",
+		},
+		{
+			keyword: false,
+			value:   "selects",
+		},
+		{
+			keyword: false,
+			value:   "intable",
+		},
+		{
+			keyword: false,
+			value:   "wheres",
+		},
+		{
+			keyword: false,
+			value:   "into_",
+		},
 	}
 
 	for _, test := range tests {
@@ -288,14 +370,20 @@ func TestLex(t *testing.T) {
 			input: "select a",
 			Tokens: []Token{
 				{
-					Loc:   Location{Col: 0, Line: 0},
-					Value: string(SelectKeyword),
-					Kind:  KeywordKind,
+					Loc:       Location{Col: 0, Line: 0},
+					End:       Location{Col: 6, Line: 0},
+					Offset:    0,
+					EndOffset: 6,
+					Value:     string(SelectKeyword),
+					Kind:      KeywordKind,
 				},
 				{
-					Loc:   Location{Col: 7, Line: 0},
-					Value: "a",
-					Kind:  IdentifierKind,
+					Loc:       Location{Col: 7, Line: 0},
+					End:       Location{Col: 8, Line: 0},
+					Offset:    7,
+					EndOffset: 8,
+					Value:     "a",
+					Kind:      IdentifierKind,
 				},
 			},
 		},
@@ -318,14 +406,20 @@ func TestLex(t *testing.T) {
 			input: "select 1",
 			Tokens: []Token{
 				{
-					Loc:   Location{Col: 0, Line: 0},
-					Value: string(SelectKeyword),
-					Kind:  KeywordKind,
+					Loc:       Location{Col: 0, Line: 0},
+					End:       Location{Col: 6, Line: 0},
+					Offset:    0,
+					EndOffset: 6,
+					Value:     string(SelectKeyword),
+					Kind:      KeywordKind,
 				},
 				{
-					Loc:   Location{Col: 7, Line: 0},
-					Value: "1",
-					Kind:  NumericKind,
+					Loc:       Location{Col: 7, Line: 0},
+					End:       Location{Col: 8, Line: 0},
+					Offset:    7,
+					EndOffset: 8,
+					Value:     "1",
+					Kind:      NumericKind,
 				},
 			},
 			err: nil,
@@ -365,54 +459,84 @@ func TestLex(t *testing.T) {
 			input: "CREATE TABLE u (id INT, name TEXT)",
 			Tokens: []Token{
 				{
-					Loc:   Location{Col: 0, Line: 0},
-					Value: string(CreateKeyword),
-					Kind:  KeywordKind,
+					Loc:       Location{Col: 0, Line: 0},
+					End:       Location{Col: 6, Line: 0},
+					Offset:    0,
+					EndOffset: 6,
+					Value:     string(CreateKeyword),
+					Kind:      KeywordKind,
 				},
 				{
-					Loc:   Location{Col: 7, Line: 0},
-					Value: string(TableKeyword),
-					Kind:  KeywordKind,
+					Loc:       Location{Col: 7, Line: 0},
+					End:       Location{Col: 12, Line: 0},
+					Offset:    7,
+					EndOffset: 12,
+					Value:     string(TableKeyword),
+					Kind:      KeywordKind,
 				},
 				{
-					Loc:   Location{Col: 13, Line: 0},
-					Value: "u",
-					Kind:  IdentifierKind,
+					Loc:       Location{Col: 13, Line: 0},
+					End:       Location{Col: 14, Line: 0},
+					Offset:    13,
+					EndOffset: 14,
+					Value:     "u",
+					Kind:      IdentifierKind,
 				},
 				{
-					Loc:   Location{Col: 15, Line: 0},
-					Value: "(",
-					Kind:  SymbolKind,
+					Loc:       Location{Col: 15, Line: 0},
+					End:       Location{Col: 16, Line: 0},
+					Offset:    15,
+					EndOffset: 16,
+					Value:     "(",
+					Kind:      SymbolKind,
 				},
 				{
-					Loc:   Location{Col: 16, Line: 0},
-					Value: "id",
-					Kind:  IdentifierKind,
+					Loc:       Location{Col: 16, Line: 0},
+					End:       Location{Col: 18, Line: 0},
+					Offset:    16,
+					EndOffset: 18,
+					Value:     "id",
+					Kind:      IdentifierKind,
 				},
 				{
-					Loc:   Location{Col: 19, Line: 0},
-					Value: "int",
-					Kind:  KeywordKind,
+					Loc:       Location{Col: 19, Line: 0},
+					End:       Location{Col: 22, Line: 0},
+					Offset:    19,
+					EndOffset: 22,
+					Value:     "int",
+					Kind:      KeywordKind,
 				},
 				{
-					Loc:   Location{Col: 22, Line: 0},
-					Value: ",",
-					Kind:  SymbolKind,
+					Loc:       Location{Col: 22, Line: 0},
+					End:       Location{Col: 23, Line: 0},
+					Offset:    22,
+					EndOffset: 23,
+					Value:     ",",
+					Kind:      SymbolKind,
 				},
 				{
-					Loc:   Location{Col: 24, Line: 0},
-					Value: "name",
-					Kind:  IdentifierKind,
+					Loc:       Location{Col: 24, Line: 0},
+					End:       Location{Col: 28, Line: 0},
+					Offset:    24,
+					EndOffset: 28,
+					Value:     "name",
+					Kind:      IdentifierKind,
 				},
 				{
-					Loc:   Location{Col: 29, Line: 0},
-					Value: "text",
-					Kind:  KeywordKind,
+					Loc:       Location{Col: 29, Line: 0},
+					End:       Location{Col: 33, Line: 0},
+					Offset:    29,
+					EndOffset: 33,
+					Value:     "text",
+					Kind:      KeywordKind,
 				},
 				{
-					Loc:   Location{Col: 33, Line: 0},
-					Value: ")",
-					Kind:  SymbolKind,
+					Loc:       Location{Col: 33, Line: 0},
+					End:       Location{Col: 34, Line: 0},
+					Offset:    33,
+					EndOffset: 34,
+					Value:     ")",
+					Kind:      SymbolKind,
 				},
 			},
 		},
@@ -420,49 +544,76 @@ func TestLex(t *testing.T) {
 			input: "insert into users Values (105, 233)",
 			Tokens: []Token{
 				{
-					Loc:   Location{Col: 0, Line: 0},
-					Value: string(InsertKeyword),
-					Kind:  KeywordKind,
+					Loc:       Location{Col: 0, Line: 0},
+					End:       Location{Col: 6, Line: 0},
+					Offset:    0,
+					EndOffset: 6,
+					Value:     string(InsertKeyword),
+					Kind:      KeywordKind,
 				},
 				{
-					Loc:   Location{Col: 7, Line: 0},
-					Value: string(IntoKeyword),
-					Kind:  KeywordKind,
+					Loc:       Location{Col: 7, Line: 0},
+					End:       Location{Col: 11, Line: 0},
+					Offset:    7,
+					EndOffset: 11,
+					Value:     string(IntoKeyword),
+					Kind:      KeywordKind,
 				},
 				{
-					Loc:   Location{Col: 12, Line: 0},
-					Value: "users",
-					Kind:  IdentifierKind,
+					Loc:       Location{Col: 12, Line: 0},
+					End:       Location{Col: 17, Line: 0},
+					Offset:    12,
+					EndOffset: 17,
+					Value:     "users",
+					Kind:      IdentifierKind,
 				},
 				{
-					Loc:   Location{Col: 18, Line: 0},
-					Value: string(ValuesKeyword),
-					Kind:  KeywordKind,
+					Loc:       Location{Col: 18, Line: 0},
+					End:       Location{Col: 24, Line: 0},
+					Offset:    18,
+					EndOffset: 24,
+					Value:     string(ValuesKeyword),
+					Kind:      KeywordKind,
 				},
 				{
-					Loc:   Location{Col: 25, Line: 0},
-					Value: "(",
-					Kind:  SymbolKind,
+					Loc:       Location{Col: 25, Line: 0},
+					End:       Location{Col: 26, Line: 0},
+					Offset:    25,
+					EndOffset: 26,
+					Value:     "(",
+					Kind:      SymbolKind,
 				},
 				{
-					Loc:   Location{Col: 26, Line: 0},
-					Value: "105",
-					Kind:  NumericKind,
+					Loc:       Location{Col: 26, Line: 0},
+					End:       Location{Col: 29, Line: 0},
+					Offset:    26,
+					EndOffset: 29,
+					Value:     "105",
+					Kind:      NumericKind,
 				},
 				{
-					Loc:   Location{Col: 30, Line: 0},
-					Value: ",",
-					Kind:  SymbolKind,
+					Loc:       Location{Col: 29, Line: 0},
+					End:       Location{Col: 30, Line: 0},
+					Offset:    29,
+					EndOffset: 30,
+					Value:     ",",
+					Kind:      SymbolKind,
 				},
 				{
-					Loc:   Location{Col: 32, Line: 0},
-					Value: "233",
-					Kind:  NumericKind,
+					Loc:       Location{Col: 31, Line: 0},
+					End:       Location{Col: 34, Line: 0},
+					Offset:    31,
+					EndOffset: 34,
+					Value:     "233",
+					Kind:      NumericKind,
 				},
 				{
-					Loc:   Location{Col: 36, Line: 0},
-					Value: ")",
-					Kind:  SymbolKind,
+					Loc:       Location{Col: 34, Line: 0},
+					End:       Location{Col: 35, Line: 0},
+					Offset:    34,
+					EndOffset: 35,
+					Value:     ")",
+					Kind:      SymbolKind,
 				},
 			},
 			err: nil,
@@ -471,29 +622,44 @@ func TestLex(t *testing.T) {
 			input: "SELECT id FROM users;",
 			Tokens: []Token{
 				{
-					Loc:   Location{Col: 0, Line: 0},
-					Value: string(SelectKeyword),
-					Kind:  KeywordKind,
+					Loc:       Location{Col: 0, Line: 0},
+					End:       Location{Col: 6, Line: 0},
+					Offset:    0,
+					EndOffset: 6,
+					Value:     string(SelectKeyword),
+					Kind:      KeywordKind,
 				},
 				{
-					Loc:   Location{Col: 7, Line: 0},
-					Value: "id",
-					Kind:  IdentifierKind,
+					Loc:       Location{Col: 7, Line: 0},
+					End:       Location{Col: 9, Line: 0},
+					Offset:    7,
+					EndOffset: 9,
+					Value:     "id",
+					Kind:      IdentifierKind,
 				},
 				{
-					Loc:   Location{Col: 10, Line: 0},
-					Value: string(FromKeyword),
-					Kind:  KeywordKind,
+					Loc:       Location{Col: 10, Line: 0},
+					End:       Location{Col: 14, Line: 0},
+					Offset:    10,
+					EndOffset: 14,
+					Value:     string(FromKeyword),
+					Kind:      KeywordKind,
 				},
 				{
-					Loc:   Location{Col: 15, Line: 0},
-					Value: "users",
-					Kind:  IdentifierKind,
+					Loc:       Location{Col: 15, Line: 0},
+					End:       Location{Col: 20, Line: 0},
+					Offset:    15,
+					EndOffset: 20,
+					Value:     "users",
+					Kind:      IdentifierKind,
 				},
 				{
-					Loc:   Location{Col: 20, Line: 0},
-					Value: ";",
-					Kind:  SymbolKind,
+					Loc:       Location{Col: 20, Line: 0},
+					End:       Location{Col: 21, Line: 0},
+					Offset:    20,
+					EndOffset: 21,
+					Value:     ";",
+					Kind:      SymbolKind,
 				},
 			},
 			err: nil,
@@ -510,3 +676,444 @@ func TestLex(t *testing.T) {
 		}
 	}
 }
+
+func TestLex_keywordPrefixedIdentifiers(t *testing.T) {
+	for _, input := range []string{"fromage", "selects", "intable", "wheres"} {
+		tokens, err := lex(input)
+		assert.Nil(t, err, input)
+		assert.Equal(t, []*Token{{
+			Value:     input,
+			Kind:      IdentifierKind,
+			End:       Location{Col: uint(len(input))},
+			EndOffset: uint(len(input)),
+		}}, tokens, input)
+	}
+
+	tokens, err := lex("from t")
+	assert.Nil(t, err)
+	assert.Equal(t, []*Token{
+		{Value: string(FromKeyword), Kind: KeywordKind, End: Location{Col: 4}, EndOffset: 4},
+		{Value: "t", Kind: IdentifierKind, Loc: Location{Col: 5}, End: Location{Col: 6}, Offset: 5, EndOffset: 6},
+	}, tokens)
+}
+
+func TestToken_lexBool(t *testing.T) {
+	tests := []struct {
+		bool  bool
+		value string
+	}{
+		{
+			bool:  true,
+			value: "true",
+		},
+		{
+			bool:  true,
+			value: "FALSE",
+		},
+		{
+			bool:  true,
+			value: "true)",
+		},
+		// false tests
+		{
+			bool:  false,
+			value: "truest",
+		},
+		{
+			bool:  false,
+			value: "falsey",
+		},
+		{
+			bool:  false,
+			value: "'true'",
+		},
+	}
+
+	for _, test := range tests {
+		tok, _, ok := lexBool(test.value, cursor{})
+		assert.Equal(t, test.bool, ok, test.value)
+		if ok {
+			assert.Equal(t, BoolKind, tok.Kind, test.value)
+			assert.Equal(t, strings.ToLower(strings.TrimSuffix(test.value, ")")), tok.Value, test.value)
+		}
+	}
+}
+
+func TestToken_String(t *testing.T) {
+	tests := []struct {
+		token    Token
+		expected string
+	}{
+		{
+			token:    Token{Value: "select", Kind: KeywordKind},
+			expected: "select",
+		},
+		{
+			token:    Token{Value: "a b", Kind: StringKind},
+			expected: "'a b'",
+		},
+		{
+			token:    Token{Value: "users", Kind: IdentifierKind},
+			expected: "users",
+		},
+		{
+			token:    Token{Value: "userName", Kind: IdentifierKind},
+			expected: `"userName"`,
+		},
+		{
+			token:    Token{Value: "from", Kind: IdentifierKind},
+			expected: `"from"`,
+		},
+		{
+			token:    Token{Value: "1.5", Kind: NumericKind},
+			expected: "1.5",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.token.String(), test.expected)
+	}
+}
+
+func TestReconstruct(t *testing.T) {
+	tests := []string{
+		"select a",
+		"SELECT id FROM users;",
+		"CREATE TABLE u (id INT, name TEXT)",
+		"insert into users Values (105, 'a b', 'c '' d')",
+		`select "userName", " abc " from "select"`,
+		`select "a""b" from t`,
+		"select *from(t)",
+	}
+
+	for _, test := range tests {
+		tokens, err := lex(test)
+		assert.Nil(t, err, test)
+
+		reconstructed := Reconstruct(tokens)
+		relexed, err := lex(reconstructed)
+		assert.Nil(t, err, reconstructed)
+		assert.Equal(t, len(tokens), len(relexed), reconstructed)
+		for i := range tokens {
+			if i < len(relexed) {
+				assert.True(t, tokens[i].equals(relexed[i]), reconstructed)
+			}
+		}
+	}
+
+	// A doubled quote stands for one quote, which is doubled again when
+	// the token is written back.
+	tokens, err := lex("'c '' d'")
+	assert.Nil(t, err)
+	assert.Equal(t, "c ' d", tokens[0].Value)
+	assert.Equal(t, "'c '' d'", Reconstruct(tokens))
+}
+
+func BenchmarkLex(b *testing.B) {
+	statements := []string{
+		"CREATE TABLE users (id INT, name TEXT, email TEXT);",
+		"INSERT INTO users VALUES (105, 'Alice', 'alice@example.com');",
+		"SELECT id, name, email FROM users WHERE id;",
+		"select * from users;",
+	}
+	var source strings.Builder
+	for source.Len() < 4096 {
+		for _, s := range statements {
+			source.WriteString(s)
+			source.WriteString("\n")
+		}
+	}
+	input := source.String()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := lex(input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func FuzzLex(f *testing.F) {
+	seeds := []string{
+		"select a",
+		"SELECT id, name FROM users;",
+		"CREATE TABLE u (id INT, name TEXT)",
+		"insert into users Values (105, 'a '' b')",
+		`select "userName" from "t"`,
+		"'unterminated",
+		`"unterminated`,
+		"e",
+		"1e",
+		"1e+",
+		"1.e",
+		"select 1e",
+		";;;;;,,,,,(((((*****)))))",
+		"selec",
+		"",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, source string) {
+		tokens, err := lex(source)
+		if err == nil && tokens == nil {
+			t.Errorf("lex(%q) returned neither tokens nor an error", source)
+		}
+	})
+}
+
+func TestLexWithConfig(t *testing.T) {
+	cfg := DefaultLexConfig()
+	cfg.Keywords = append(cfg.Keywords, "ILIKE")
+
+	tokens, err := LexWithConfig("select a from t where a ilike 'x%'", cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 8, len(tokens))
+	assert.Equal(t, &Token{Value: "ilike", Kind: KeywordKind, Loc: Location{Col: 24}, End: Location{Col: 29}, Offset: 24, EndOffset: 29}, tokens[6])
+
+	tokens, err = lex("select a from t where a ilike 'x%'")
+	assert.Nil(t, err)
+	assert.Equal(t, &Token{Value: "ilike", Kind: IdentifierKind, Loc: Location{Col: 24}, End: Location{Col: 29}, Offset: 24, EndOffset: 29}, tokens[6])
+
+	cfg = LexConfig{Keywords: []string{"select"}, Symbols: []string{"*"}}
+	tokens, err = LexWithConfig("select * from t", cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, KeywordKind, tokens[0].Kind)
+	assert.Equal(t, SymbolKind, tokens[1].Kind)
+	assert.Equal(t, IdentifierKind, tokens[2].Kind)
+
+	_, err = LexWithConfig("select a, b", cfg)
+	assert.EqualError(t, err, "Unable to lex token after a, at 0:8")
+}
+
+func TestLexWithConfig_quotedIdentifiers(t *testing.T) {
+	cfg := DefaultLexConfig()
+	_, err := LexWithConfig("select `a b` from [t]", cfg)
+	assert.NotNil(t, err)
+
+	cfg.Backticks, cfg.Brackets = true, true
+	tokens, err := LexWithConfig("select `Full name`, [Order] from `t`", cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, []*Token{
+		{Value: string(SelectKeyword), Kind: KeywordKind, Loc: Location{Col: 0}, End: Location{Col: 6}, Offset: 0, EndOffset: 6},
+		{Value: "Full name", Kind: IdentifierKind, Loc: Location{Col: 7}, End: Location{Col: 18}, Offset: 7, EndOffset: 18},
+		{Value: string(CommaSymbol), Kind: SymbolKind, Loc: Location{Col: 18}, End: Location{Col: 19}, Offset: 18, EndOffset: 19},
+		{Value: "Order", Kind: IdentifierKind, Loc: Location{Col: 20}, End: Location{Col: 27}, Offset: 20, EndOffset: 27},
+		{Value: string(FromKeyword), Kind: KeywordKind, Loc: Location{Col: 28}, End: Location{Col: 32}, Offset: 28, EndOffset: 32},
+		{Value: "t", Kind: IdentifierKind, Loc: Location{Col: 33}, End: Location{Col: 36}, Offset: 33, EndOffset: 36},
+	}, tokens)
+
+	ast, err := ParseWithConfig("select [id] from `users`", cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id\nFROM users", Format(ast.Statements[0]))
+}
+
+func TestLex_multiLineString(t *testing.T) {
+	tokens, err := lex("select 'line one\nline two' as x,\n 'a\n\nb' y")
+	assert.Nil(t, err)
+	assert.Equal(t, []*Token{
+		{Value: string(SelectKeyword), Kind: KeywordKind, Loc: Location{Line: 0, Col: 0}, End: Location{Line: 0, Col: 6}, Offset: 0, EndOffset: 6},
+		{Value: "line one\nline two", Kind: StringKind, Loc: Location{Line: 0, Col: 7}, End: Location{Line: 1, Col: 9}, Offset: 7, EndOffset: 26},
+		{Value: string(AsKeyword), Kind: KeywordKind, Loc: Location{Line: 1, Col: 10}, End: Location{Line: 1, Col: 12}, Offset: 27, EndOffset: 29},
+		{Value: "x", Kind: IdentifierKind, Loc: Location{Line: 1, Col: 13}, End: Location{Line: 1, Col: 14}, Offset: 30, EndOffset: 31},
+		{Value: string(CommaSymbol), Kind: SymbolKind, Loc: Location{Line: 1, Col: 14}, End: Location{Line: 1, Col: 15}, Offset: 31, EndOffset: 32},
+		{Value: "a\n\nb", Kind: StringKind, Loc: Location{Line: 2, Col: 1}, End: Location{Line: 4, Col: 2}, Offset: 34, EndOffset: 40},
+		{Value: "y", Kind: IdentifierKind, Loc: Location{Line: 4, Col: 3}, End: Location{Line: 4, Col: 4}, Offset: 41, EndOffset: 42},
+	}, tokens)
+}
+
+func TestLex_escapeStrings(t *testing.T) {
+	tests := []struct {
+		source string
+		value  string
+	}{
+		{`E'a\tb\nc'`, "a\tb\nc"},
+		{`e'it\'s'`, "it's"},
+		{`E'back\\slash'`, `back\slash`},
+		{`E'\u00e9t\U0001F600'`, "ét😀"},
+		{`E'\q'`, "q"},
+		{`'a\nb'`, `a\nb`},
+		{`'it''s'`, "it's"},
+		{`E'it''s'`, "it's"},
+		{`''''`, "'"},
+	}
+	for _, test := range tests {
+		tokens, err := lex(test.source + " x")
+		if assert.Nil(t, err, test.source) {
+			assert.Equal(t, &Token{
+				Value:     test.value,
+				Kind:      StringKind,
+				End:       Location{Col: uint(utf8.RuneCountInString(test.source))},
+				EndOffset: uint(len(test.source)),
+			}, tokens[0], test.source)
+			assert.Equal(t, uint(utf8.RuneCountInString(test.source)+1), tokens[1].Loc.Col, test.source)
+		}
+	}
+
+	for _, source := range []string{`E'\u12'`, `E'\u0000'`, `E'\'`} {
+		_, err := Parse("select " + source)
+		assert.NotNil(t, err, source)
+	}
+
+	// Quotes are doubled, and values a plain string cannot hold are
+	// written back as E'' strings.
+	for _, value := range []string{"it's", "a\tb\nc", "bell\a", `back\slash`, "a '' b"} {
+		token := Token{Value: value, Kind: StringKind}
+		tokens, err := lex(token.String())
+		if assert.Nil(t, err, value) {
+			assert.Equal(t, value, tokens[0].Value, token.String())
+		}
+	}
+	assert.Equal(t, `'it''s'`, (&Token{Value: "it's", Kind: StringKind}).String())
+	assert.Equal(t, `E'it\'s\r'`, (&Token{Value: "it's\r", Kind: StringKind}).String())
+
+	results, err := ExecuteScript(NewMemoryBackend(), `create table t (s text); insert into t values (E'tab\there'); select s from t`, ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{MemoryCell("tab\there")}}, results[2].Results.Rows)
+}
+
+func TestLex_unicode(t *testing.T) {
+	tokens, err := lex("select 名前, 'señor' /* ünï */ from Café")
+	assert.Nil(t, err)
+	assert.Equal(t, []*Token{
+		{Value: string(SelectKeyword), Kind: KeywordKind, Loc: Location{Line: 0, Col: 0}, End: Location{Line: 0, Col: 6}, Offset: 0, EndOffset: 6},
+		{Value: "名前", Kind: IdentifierKind, Loc: Location{Line: 0, Col: 7}, End: Location{Line: 0, Col: 9}, Offset: 7, EndOffset: 13},
+		{Value: string(CommaSymbol), Kind: SymbolKind, Loc: Location{Line: 0, Col: 9}, End: Location{Line: 0, Col: 10}, Offset: 13, EndOffset: 14},
+		{Value: "señor", Kind: StringKind, Loc: Location{Line: 0, Col: 11}, End: Location{Line: 0, Col: 18}, Offset: 15, EndOffset: 23},
+		{Value: string(FromKeyword), Kind: KeywordKind, Loc: Location{Line: 0, Col: 29}, End: Location{Line: 0, Col: 33}, Offset: 36, EndOffset: 40},
+		{Value: "café", Kind: IdentifierKind, Loc: Location{Line: 0, Col: 34}, End: Location{Line: 0, Col: 38}, Offset: 41, EndOffset: 46},
+	}, tokens)
+
+	source := "select 名前 from café t 1"
+	_, err = Parse(source)
+	var e *Error
+	assert.ErrorAs(t, err, &e)
+	assert.Equal(t, Location{Line: 0, Col: 22}, e.Loc)
+	assert.Equal(t, "LINE 1: "+source+"\n"+strings.Repeat(" ", len("LINE 1: ")+22)+"^", e.Caret(source))
+}
+
+func TestLex_comments(t *testing.T) {
+	source := "select a -- the id\nfrom t /* multi\nline */ where a"
+	tokens, err := lex(source)
+	assert.Nil(t, err)
+	assert.Equal(t, []*Token{
+		{Value: string(SelectKeyword), Kind: KeywordKind, Loc: Location{Line: 0, Col: 0}, End: Location{Line: 0, Col: 6}, Offset: 0, EndOffset: 6},
+		{Value: "a", Kind: IdentifierKind, Loc: Location{Line: 0, Col: 7}, End: Location{Line: 0, Col: 8}, Offset: 7, EndOffset: 8},
+		{Value: string(FromKeyword), Kind: KeywordKind, Loc: Location{Line: 1, Col: 0}, End: Location{Line: 1, Col: 4}, Offset: 19, EndOffset: 23},
+		{Value: "t", Kind: IdentifierKind, Loc: Location{Line: 1, Col: 5}, End: Location{Line: 1, Col: 6}, Offset: 24, EndOffset: 25},
+		{Value: string(WhereKeyword), Kind: KeywordKind, Loc: Location{Line: 2, Col: 8}, End: Location{Line: 2, Col: 13}, Offset: 43, EndOffset: 48},
+		{Value: "a", Kind: IdentifierKind, Loc: Location{Line: 2, Col: 14}, End: Location{Line: 2, Col: 15}, Offset: 49, EndOffset: 50},
+	}, tokens)
+
+	cfg := DefaultLexConfig()
+	cfg.Comments = true
+	tokens, err = LexWithConfig(source, cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 8, len(tokens))
+	assert.Equal(t, &Token{Value: "-- the id", Kind: CommentKind, Loc: Location{Line: 0, Col: 9}, End: Location{Line: 0, Col: 18}, Offset: 9, EndOffset: 18}, tokens[2])
+	assert.Equal(t, &Token{Value: "/* multi\nline */", Kind: CommentKind, Loc: Location{Line: 1, Col: 7}, End: Location{Line: 2, Col: 7}, Offset: 26, EndOffset: 42}, tokens[5])
+
+	relexed, err := LexWithConfig(Reconstruct(tokens), cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, len(tokens), len(relexed))
+
+	tokens, err = lex("select 1 -- trailing")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(tokens))
+
+	_, err = lex("select 1 /* unterminated")
+	assert.NotNil(t, err)
+
+	tokens, err = lex("select '-- not a comment'")
+	assert.Nil(t, err)
+	assert.Equal(t, "-- not a comment", tokens[1].Value)
+}
+
+func TestLongestMatch(t *testing.T) {
+	options := []string{"<", "<=", "<>", "|", "||", "select", "selection"}
+	tests := []struct {
+		source string
+		match  string
+	}{
+		{"<", "<"},
+		{"<=1", "<="},
+		{"<>", "<>"},
+		{"< =", "<"},
+		{"|||", "||"},
+		{"SELECT", "select"},
+		{"selectio", "select"},
+		{"selections", "selection"},
+		{"sel", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.match, longestMatch(test.source, cursor{}, options), test.source)
+	}
+}
+
+func TestLex_parameters(t *testing.T) {
+	tokens, err := lex("where a = ? and b = $12")
+	assert.Nil(t, err)
+	assert.Equal(t, &Token{Value: "?", Kind: ParameterKind, Loc: Location{Col: 10}, End: Location{Col: 11}, Offset: 10, EndOffset: 11}, tokens[3])
+	assert.Equal(t, &Token{Value: "$12", Kind: ParameterKind, Loc: Location{Col: 20}, End: Location{Col: 23}, Offset: 20, EndOffset: 23}, tokens[7])
+
+	_, err = lex("select $")
+	assert.NotNil(t, err)
+}
+
+func TestLexer(t *testing.T) {
+	source := `create table users (id int, "Full name" text, señor text);
+-- a comment
+insert into users values (1, 'it''s
+two lines'), (2.5e-3, null);
+/* a block
+comment */ select * from users where id <> 10 and name like 'a%';`
+	expected, err := lex(source)
+	assert.Nil(t, err)
+
+	// Reading one byte at a time splits every token across reads.
+	for _, r := range []io.Reader{strings.NewReader(source), iotest.OneByteReader(strings.NewReader(source))} {
+		l := NewLexer(r)
+		var tokens []*Token
+		for {
+			token, err := l.Next()
+			if err == io.EOF {
+				break
+			}
+			if !assert.Nil(t, err) {
+				break
+			}
+			tokens = append(tokens, token)
+		}
+		assert.Equal(t, expected, tokens)
+
+		_, err = l.Next()
+		assert.Equal(t, io.EOF, err)
+	}
+
+	l := NewLexer(iotest.OneByteReader(strings.NewReader("select 'open")))
+	token, err := l.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, "select", token.Value)
+	_, err = l.Next()
+	assert.Equal(t, "Unable to lex token after select, at 0:7", err.Error())
+
+	l = NewLexer(iotest.TimeoutReader(iotest.OneByteReader(strings.NewReader("select 1"))))
+	_, err = l.Next()
+	assert.Equal(t, iotest.ErrTimeout, err)
+}
+
+func TestLocation_Offset(t *testing.T) {
+	source := "select 'ééé',\n  nosuch from t"
+	assert.Equal(t, 1, Location{}.Offset(source))
+	assert.Equal(t, 13, Location{Col: 12}.Offset(source))
+	assert.Equal(t, 17, Location{Line: 1, Col: 2}.Offset(source))
+	assert.Equal(t, 0, Location{Line: 2}.Offset(source))
+
+	source = "insert into t values (1); select 'ééé', nosuch from t"
+	assert.Equal(t, 39, Location{Col: 38}.Offset(source))
+}
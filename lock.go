@@ -0,0 +1,186 @@
+package gosql
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// lockManager hands out table locks to statements: shared locks on the
+// tables a statement reads and exclusive locks on the table it writes. A
+// statement asks for all of its locks at once and gets either all of them
+// or none, so no statement holds a lock while waiting for another and
+// statements cannot deadlock. Locks last for one statement; conflicting
+// changes to the same rows by open transactions are caught by
+// ErrSerializationFailure instead.
+type lockManager struct {
+	mu      sync.Mutex
+	readers map[string]int
+	writers map[string]bool
+	// waiting counts the requests for exclusive locks waiting on each
+	// table, which keep new shared locks from being granted so that a
+	// steady stream of readers cannot starve a writer.
+	waiting map[string]int
+	// released is closed and replaced whenever locks are released, waking
+	// the requests waiting for them.
+	released chan struct{}
+	// timeout is how long a request waits before failing with
+	// ErrLockTimeout, or 0 to wait as long as it takes.
+	timeout time.Duration
+}
+
+func newLockManager() *lockManager {
+	return &lockManager{
+		readers:  map[string]int{},
+		writers:  map[string]bool{},
+		waiting:  map[string]int{},
+		released: make(chan struct{}),
+	}
+}
+
+// SetLockTimeout limits how long a statement waits for the tables it uses
+// to be free. Statements that wait longer fail with ErrLockTimeout. The
+// default of 0 waits as long as it takes.
+func (mb *MemoryBackend) SetLockTimeout(timeout time.Duration) {
+	mb.locks.mu.Lock()
+	defer mb.locks.mu.Unlock()
+	mb.locks.timeout = timeout
+}
+
+// acquire waits until it can lock the tables named by reads for reading
+// and those named by writes for writing, and returns a function that
+// releases them. A table in both is locked for writing. It gives up with
+// the error of ctx if ctx is done first.
+func (lm *lockManager) acquire(ctx context.Context, reads, writes []string) (func(), error) {
+	exclusive := map[string]bool{}
+	for _, name := range writes {
+		exclusive[name] = true
+	}
+	shared := map[string]bool{}
+	for _, name := range reads {
+		if !exclusive[name] {
+			shared[name] = true
+		}
+	}
+
+	lm.mu.Lock()
+	var deadline <-chan time.Time
+	if lm.timeout > 0 {
+		timer := time.NewTimer(lm.timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	for name := range exclusive {
+		lm.waiting[name]++
+	}
+	defer func() {
+		for name := range exclusive {
+			lm.waiting[name]--
+			if lm.waiting[name] == 0 {
+				delete(lm.waiting, name)
+			}
+		}
+		lm.mu.Unlock()
+	}()
+
+	for !lm.grantable(shared, exclusive) {
+		released := lm.released
+		lm.mu.Unlock()
+		select {
+		case <-released:
+			lm.mu.Lock()
+		case <-deadline:
+			lm.mu.Lock()
+			return nil, ErrLockTimeout
+		case <-ctx.Done():
+			lm.mu.Lock()
+			return nil, ctx.Err()
+		}
+	}
+
+	for name := range shared {
+		lm.readers[name]++
+	}
+	for name := range exclusive {
+		lm.writers[name] = true
+	}
+	return func() { lm.release(shared, exclusive) }, nil
+}
+
+// grantable reports whether none of the locks asked for conflict with
+// those already granted. Shared locks also wait for the requests for
+// exclusive locks on the same table.
+func (lm *lockManager) grantable(shared, exclusive map[string]bool) bool {
+	for name := range shared {
+		if lm.writers[name] || lm.waiting[name] > 0 {
+			return false
+		}
+	}
+	for name := range exclusive {
+		if lm.writers[name] || lm.readers[name] > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (lm *lockManager) release(shared, exclusive map[string]bool) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	for name := range shared {
+		lm.readers[name]--
+		if lm.readers[name] == 0 {
+			delete(lm.readers, name)
+		}
+	}
+	for name := range exclusive {
+		delete(lm.writers, name)
+	}
+	close(lm.released)
+	lm.released = make(chan struct{})
+}
+
+// readTables lists the tables node reads from in FROM clauses, joins and
+// subqueries, in order and without repeats. Those that name one of views
+// also add what the query of the view reads, so that a query through a
+// view locks the tables behind it.
+func readTables(node Node, views map[string]*SelectStatement) []string {
+	seen := map[string]bool{}
+	var read func(node Node)
+	read = func(node Node) {
+		Inspect(node, func(n Node) bool {
+			var name string
+			switch n := n.(type) {
+			case *SelectStatement:
+				if n.From == nil || n.FromSelect != nil {
+					return true
+				}
+				name = n.From.Value
+			case *JoinClause:
+				if n.Select != nil {
+					return true
+				}
+				name = n.Table.Value
+			default:
+				return true
+			}
+			if !seen[name] {
+				seen[name] = true
+				if view, ok := views[name]; ok {
+					read(view)
+				}
+			}
+			return true
+		})
+	}
+	read(node)
+
+	var names []string
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,119 @@
+package gosql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockManager(t *testing.T) {
+	lm := newLockManager()
+	lm.timeout = 20 * time.Millisecond
+
+	// Readers share a table, and locks on other tables do not conflict.
+	r1, err := lm.acquire(context.Background(), []string{"a"}, nil)
+	assert.Nil(t, err)
+	r2, err := lm.acquire(context.Background(), []string{"a"}, []string{"b"})
+	assert.Nil(t, err)
+
+	_, err = lm.acquire(context.Background(), nil, []string{"a"})
+	assert.Equal(t, ErrLockTimeout, err)
+	_, err = lm.acquire(context.Background(), []string{"b"}, nil)
+	assert.Equal(t, ErrLockTimeout, err)
+
+	// A waiting writer gets the table once the readers are done, and
+	// readers that come after it wait their turn.
+	lm.timeout = 0
+	granted := make(chan func())
+	go func() {
+		w, err := lm.acquire(context.Background(), []string{"b"}, []string{"a"})
+		assert.Nil(t, err)
+		granted <- w
+	}()
+	for {
+		lm.mu.Lock()
+		waiting := lm.waiting["a"]
+		lm.mu.Unlock()
+		if waiting > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	lm.timeout = 20 * time.Millisecond
+	_, err = lm.acquire(context.Background(), []string{"a"}, nil)
+	assert.Equal(t, ErrLockTimeout, err)
+
+	r1()
+	r2()
+	w := <-granted
+	assert.True(t, lm.writers["a"])
+	w()
+	assert.Empty(t, lm.readers)
+	assert.Empty(t, lm.writers)
+	assert.Empty(t, lm.waiting)
+
+	// A table both read and written is locked for writing.
+	w, err = lm.acquire(context.Background(), []string{"a", "c"}, []string{"a"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"c": 1}, lm.readers)
+	w()
+}
+
+func TestMemoryBackend_ConcurrentWrites(t *testing.T) {
+	mb := NewMemoryBackend()
+	for i := 0; i < 4; i++ {
+		_, err := execute(t, mb, fmt.Sprintf("create table t%d (id int primary key, n int)", i))
+		assert.Nil(t, err)
+	}
+
+	// Sessions write to their own tables and read all of them at once.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s := mb.NewSession()
+			for j := 0; j < 50; j++ {
+				_, err := execute(t, s, fmt.Sprintf("insert into t%d values (%d, %d)", i, j, j))
+				assert.Nil(t, err)
+				_, err = execute(t, s, fmt.Sprintf("update t%d set n = n + 1 where id = %d", i, j))
+				assert.Nil(t, err)
+				_, err = execute(t, s, "select * from t0 join t1 on t0.id = t1.id where t0.id in (select id from t2)")
+				assert.Nil(t, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 4; i++ {
+		results, err := execute(t, mb, fmt.Sprintf("select sum(n) from t%d", i))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(50*51/2), results.Rows[0][0].AsInt())
+	}
+}
+
+func TestMemoryBackend_LockTimeout(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table users (id int); create table teams (id int)")
+	assert.Nil(t, err)
+	mb.SetLockTimeout(20 * time.Millisecond)
+
+	release, err := mb.locks.acquire(context.Background(), []string{"users"}, nil)
+	assert.Nil(t, err)
+
+	_, err = execute(t, mb, "insert into users values (1)")
+	assert.Equal(t, ErrLockTimeout, err)
+	assert.Equal(t, "55P03", SQLState(err))
+	_, err = execute(t, mb, "insert into teams select id from users")
+	assert.Nil(t, err)
+	_, err = execute(t, mb, "select * from users")
+	assert.Nil(t, err)
+
+	release()
+	_, err = execute(t, mb, "insert into users values (1)")
+	assert.Nil(t, err)
+}
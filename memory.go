@@ -0,0 +1,2931 @@
+package gosql
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MemoryCell holds a value in its binary form. A nil MemoryCell is NULL.
+type MemoryCell []byte
+
+// AsInt reads an int, which takes four bytes, or a bigint, which takes
+// eight.
+func (mc MemoryCell) AsInt() int64 {
+	switch len(mc) {
+	case 4:
+		return int64(int32(binary.BigEndian.Uint32(mc)))
+	case 8:
+		return int64(binary.BigEndian.Uint64(mc))
+	}
+	return 0
+}
+
+func (mc MemoryCell) AsFloat() float64 {
+	if len(mc) != 8 {
+		return 0
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(mc))
+}
+
+// AsTime reads a date, which takes four bytes and counts days since
+// 1970-01-01, or a timestamp, which takes eight and counts microseconds.
+// Both are in UTC.
+func (mc MemoryCell) AsTime() time.Time {
+	switch len(mc) {
+	case 4:
+		return time.Unix(mc.AsInt()*secondsPerDay, 0).UTC()
+	case 8:
+		micros := mc.AsInt()
+		return time.Unix(micros/1000000, micros%1000000*1000).UTC()
+	}
+	return time.Time{}
+}
+
+func (mc MemoryCell) AsText() string {
+	return string(mc)
+}
+
+func (mc MemoryCell) AsBool() bool {
+	return len(mc) != 0 && mc[0] == 1
+}
+
+func (mc MemoryCell) IsNull() bool {
+	return mc == nil
+}
+
+func intCell(i int32) MemoryCell {
+	cell := make(MemoryCell, 4)
+	binary.BigEndian.PutUint32(cell, uint32(i))
+	return cell
+}
+
+func bigIntCell(i int64) MemoryCell {
+	cell := make(MemoryCell, 8)
+	binary.BigEndian.PutUint64(cell, uint64(i))
+	return cell
+}
+
+// floatCell stores f, with negative zero stored as zero so that equal
+// floats are equal bytes.
+func floatCell(f float64) MemoryCell {
+	if f == 0 {
+		f = 0
+	}
+	cell := make(MemoryCell, 8)
+	binary.BigEndian.PutUint64(cell, math.Float64bits(f))
+	return cell
+}
+
+var (
+	trueCell  = MemoryCell{1}
+	falseCell = MemoryCell{0}
+	nullCell  = MemoryCell(nil)
+)
+
+func boolCell(b bool) MemoryCell {
+	if b {
+		return trueCell
+	}
+	return falseCell
+}
+
+type table struct {
+	name        string
+	columns     []string
+	columnTypes []ColumnType
+	rows        [][]MemoryCell
+	notNull     []bool
+	// lengths holds the most characters each column of a stored table
+	// takes, or 0 for the columns without a limit.
+	lengths []int
+	// primaryKey is the index of the primary key column, or -1.
+	primaryKey int
+	// unique marks the columns whose non-NULL values must be distinct,
+	// including the primary key.
+	unique []bool
+	// defaults holds the DEFAULT expression of each column of a stored
+	// table, or nil for the columns without one.
+	defaults []*Expression
+	// autoIncrement marks the column, at most one, that takes the next
+	// value of sequence when a row is inserted with it NULL. sequence is
+	// the last value given out, which rolling back does not take back.
+	autoIncrement []bool
+	sequence      int64
+	// references holds the foreign key of each column of a stored table,
+	// or nil for the columns without one.
+	references []*reference
+	// checks holds the conditions of the CHECK constraints of a stored
+	// table, whether written on a column or on the table.
+	checks []*Expression
+	// collations holds the collation of each column, nil for those that
+	// compare their bytes. It may be shorter than columns, or nil, for
+	// tables that are not stored.
+	collations []*collation
+	// triggers holds the triggers of a stored table in the order they
+	// fire.
+	triggers []*namedTrigger
+	// columnTables holds the table each column came from when this is the
+	// result of a join. It is nil for stored tables.
+	columnTables []string
+	indexes      []*index
+	// versions holds every version of the rows of a stored table, which
+	// reads turn into rows with visibleTo. dead counts the versions that
+	// are deleted or rolled back.
+	versions []*rowVersion
+	dead     int
+	// rowOf maps the versions of the stored table to the rows of a view
+	// made by visibleTo, or to -1 for versions the view does not see.
+	rowOf []int
+	// stats is what the planner knows of a stored table, or nil until it
+	// is first analyzed.
+	stats *tableStats
+	// columnar is set for stored tables in the column layout, which also
+	// keep their versions in vectors, one for each column.
+	columnar bool
+	vectors  []*columnVector
+	// clock tells the time of the backend a stored table belongs to, or of
+	// the tables it was joined from. It is nil for other tables.
+	clock func() time.Time
+}
+
+func (t *table) columnIndex(name string) int {
+	for i, c := range t.columns {
+		if c == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *table) columnTable(i int) string {
+	if t.columnTables == nil {
+		return t.name
+	}
+	return t.columnTables[i]
+}
+
+// aliased returns t with its columns qualified by the alias as instead of
+// the table name, sharing t's rows. It returns t itself when as is nil.
+func (t *table) aliased(as *Token) *table {
+	if as == nil {
+		return t
+	}
+
+	view := *t
+	view.columnTables = make([]string, len(t.columns))
+	for i := range view.columnTables {
+		view.columnTables[i] = as.Value
+	}
+	return &view
+}
+
+func (t *table) qualifiedColumnIndex(ref *ColumnReference) int {
+	for i, c := range t.columns {
+		if c == ref.Column.Value && t.columnTable(i) == ref.Table.Value {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolveColumn returns the index of the column exp refers to, or -1 if exp
+// is not a column reference. A name without a table that more than one of
+// the joined tables has is ambiguous.
+func (t *table) resolveColumn(exp *Expression) (int, error) {
+	var i int
+	switch {
+	case exp.Kind == LiteralKind && exp.Literal.Kind == IdentifierKind:
+		i = t.columnIndex(exp.Literal.Value)
+		for j := i + 1; i != -1 && j < len(t.columns); j++ {
+			if t.columns[j] == exp.Literal.Value {
+				return -1, ErrAmbiguousColumn
+			}
+		}
+	case exp.Kind == ColumnReferenceKind:
+		i = t.qualifiedColumnIndex(exp.Column)
+	default:
+		return -1, nil
+	}
+
+	if i == -1 {
+		return -1, ErrColumnDoesNotExist
+	}
+	return i, nil
+}
+
+type MemoryBackend struct {
+	// mu is held for writing while a statement changes the schema or a
+	// transaction ends, and for reading while any other statement runs,
+	// never for a whole transaction. Statements holding it for reading
+	// take locks on the tables they use.
+	mu     sync.RWMutex
+	tables map[string]*table
+	// views holds the query of each view by name. Views and tables share
+	// names, so no name is in both.
+	views map[string]*SelectStatement
+	// databases holds the databases besides the default one, whose tables
+	// and views are named after them.
+	databases map[string]bool
+	locks     *lockManager
+	// xidMu guards nextXID and active, which statements on different
+	// tables change at the same time.
+	xidMu   sync.Mutex
+	nextXID uint64
+	// active holds the transactions in progress.
+	active map[uint64]bool
+	// session runs the statements called on the backend itself.
+	session *Session
+	// readOnlySessions makes the sessions NewSession returns fail every
+	// statement that changes anything, as a DiskBackend cannot log them.
+	readOnlySessions bool
+	// sessions counts the sessions made, which name their temporary
+	// schemas after it.
+	sessions atomic.Uint64
+	// schemaVersion changes whenever the schema may have, so that plans
+	// made against an older one are not used. plans caches the plans.
+	schemaVersion uint64
+	plans         *planCache
+	// changes streams committed row changes to subscriptions.
+	changes changeFeed
+	// persist, when set, stores the row changes of each transaction as it
+	// commits, before other transactions can see them. A transaction it
+	// fails for is rolled back.
+	persist func(changes []Change) error
+	// statements keeps the metrics of the statements run on the backend.
+	statements statementLog
+	// users holds the users CREATE USER made and their privileges.
+	users users
+	// clock tells the time statements see.
+	clock Clock
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	mb := &MemoryBackend{
+		tables:    map[string]*table{},
+		views:     map[string]*SelectStatement{},
+		databases: map[string]bool{},
+		locks:     newLockManager(),
+		active:    map[uint64]bool{},
+		plans:     newPlanCache(defaultPlanCacheSize),
+		clock:     systemClock{},
+	}
+	mb.session = mb.NewSession()
+	return mb
+}
+
+func (mb *MemoryBackend) Begin() error {
+	return mb.session.Begin()
+}
+
+func (mb *MemoryBackend) Commit() error {
+	return mb.session.Commit()
+}
+
+func (mb *MemoryBackend) Rollback() error {
+	return mb.session.Rollback()
+}
+
+func (mb *MemoryBackend) Savepoint(name string) error {
+	return mb.session.Savepoint(name)
+}
+
+func (mb *MemoryBackend) RollbackToSavepoint(name string) error {
+	return mb.session.RollbackToSavepoint(name)
+}
+
+func (mb *MemoryBackend) ReleaseSavepoint(name string) error {
+	return mb.session.ReleaseSavepoint(name)
+}
+
+func (mb *MemoryBackend) CreateTable(ctx context.Context, crt *CreateTableStatement) error {
+	return mb.session.CreateTable(ctx, crt)
+}
+
+func (mb *MemoryBackend) DropTable(ctx context.Context, drp *DropTableStatement) error {
+	return mb.session.DropTable(ctx, drp)
+}
+
+func (mb *MemoryBackend) AlterTable(ctx context.Context, alt *AlterTableStatement) error {
+	return mb.session.AlterTable(ctx, alt)
+}
+
+func (mb *MemoryBackend) CreateIndex(ctx context.Context, crt *CreateIndexStatement) error {
+	return mb.session.CreateIndex(ctx, crt)
+}
+
+func (mb *MemoryBackend) Insert(ctx context.Context, inst *InsertStatement) (int, *Results, error) {
+	return mb.session.Insert(ctx, inst)
+}
+
+func (mb *MemoryBackend) LastInsertID() int64 {
+	return mb.session.LastInsertID()
+}
+
+func (mb *MemoryBackend) Query(ctx context.Context, slct *SelectStatement) (*Rows, error) {
+	return mb.session.Query(ctx, slct)
+}
+
+func (mb *MemoryBackend) Select(ctx context.Context, slct *SelectStatement) (*Results, error) {
+	return mb.session.Select(ctx, slct)
+}
+
+func (mb *MemoryBackend) Update(ctx context.Context, updt *UpdateStatement) (int, *Results, error) {
+	return mb.session.Update(ctx, updt)
+}
+
+func (mb *MemoryBackend) Explain(ctx context.Context, e *ExplainStatement) (*Results, error) {
+	return mb.session.Explain(ctx, e)
+}
+
+func (mb *MemoryBackend) Delete(ctx context.Context, dlt *DeleteStatement) (int, *Results, error) {
+	return mb.session.Delete(ctx, dlt)
+}
+
+func literalToCell(t *Token) (MemoryCell, ColumnType, error) {
+	switch t.Kind {
+	case NumericKind:
+		return numericLiteral(t.Value)
+	case StringKind:
+		return MemoryCell(t.Value), TextType, nil
+	case BytesKind:
+		return MemoryCell(t.Value), ByteaType, nil
+	case BoolKind:
+		return boolCell(t.Value == "true"), BoolType, nil
+	case ParameterKind:
+		return nil, 0, ErrUnboundParameter
+	case KeywordKind:
+		if keyword(t.Value) == NullKeyword {
+			return nullCell, NullType, nil
+		}
+	}
+
+	return nil, 0, ErrInvalidDatatype
+}
+
+// evaluateExpression computes the value of exp against row, which may be nil
+// when the expression does not reference any columns.
+func (t *table) evaluateExpression(row []MemoryCell, exp *Expression) (MemoryCell, ColumnType, error) {
+	switch exp.Kind {
+	case LiteralKind, ColumnReferenceKind:
+		i, err := t.resolveColumn(exp)
+		if err != nil {
+			return nil, 0, err
+		}
+		if i == -1 {
+			return literalToCell(exp.Literal)
+		}
+		if row == nil {
+			return nil, 0, ErrColumnDoesNotExist
+		}
+		return row[i], t.columnTypes[i], nil
+	case BinaryKind:
+		return t.evaluateBinaryExpression(row, exp.Binary)
+	case UnaryKind:
+		return t.evaluateUnaryExpression(row, exp.Unary)
+	case IsNullKind:
+		return t.evaluateIsNullExpression(row, exp.IsNull)
+	case InKind:
+		return t.evaluateInExpression(row, exp.In)
+	case BetweenKind:
+		return t.evaluateBetweenExpression(row, exp.Between)
+	case FunctionKind:
+		return t.evaluateFunction(row, exp.Function)
+	case CastKind:
+		return t.evaluateCastExpression(row, exp.Cast)
+	case CaseKind:
+		return t.evaluateCaseExpression(row, exp.Case)
+	}
+
+	return nil, 0, ErrInvalidDatatype
+}
+
+func (t *table) evaluateBinaryExpression(row []MemoryCell, bexp *BinaryExpression) (MemoryCell, ColumnType, error) {
+	left, lt, err := t.evaluateExpression(row, bexp.Left)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	right, rt, err := t.evaluateExpression(row, bexp.Right)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	op := bexp.Op
+	switch op.Kind {
+	case KeywordKind:
+		switch keyword(op.Value) {
+		case AndKeyword, OrKeyword:
+			if !compatible(lt, BoolType) || !compatible(rt, BoolType) {
+				return nil, 0, fmt.Errorf("%w: %s expects booleans, got %s and %s", ErrTypeMismatch, op.Value, lt, rt)
+			}
+			// Three-valued logic: NULL is unknown, so it only decides the
+			// result when the other side does not.
+			if keyword(op.Value) == AndKeyword {
+				if isFalse(left) || isFalse(right) {
+					return falseCell, BoolType, nil
+				}
+				if left.IsNull() || right.IsNull() {
+					return nullCell, BoolType, nil
+				}
+				return trueCell, BoolType, nil
+			}
+			if left.AsBool() || right.AsBool() {
+				return trueCell, BoolType, nil
+			}
+			if left.IsNull() || right.IsNull() {
+				return nullCell, BoolType, nil
+			}
+			return falseCell, BoolType, nil
+		case LikeKeyword:
+			if !compatible(lt, TextType) || !compatible(rt, TextType) {
+				return nil, 0, fmt.Errorf("%w: like expects text, got %s and %s", ErrTypeMismatch, lt, rt)
+			}
+			if left.IsNull() || right.IsNull() {
+				return nullCell, BoolType, nil
+			}
+			return boolCell(compileLike(right.AsText()).match(left.AsText())), BoolType, nil
+		}
+	case SymbolKind:
+		switch Symbol(op.Value) {
+		case PlusSymbol, MinusSymbol, AsteriskSymbol, SlashSymbol, PercentSymbol:
+			return arithmetic(Symbol(op.Value), left, lt, right, rt)
+		case ConcatSymbol:
+			// Values of other types are concatenated as their text.
+			ct := concatType(lt, rt)
+			if left.IsNull() || right.IsNull() {
+				return nullCell, ct, nil
+			}
+			if ct == ByteaType {
+				return append(append(MemoryCell{}, left...), right...), ct, nil
+			}
+			return MemoryCell(cellText(left, lt) + cellText(right, rt)), ct, nil
+		case ArrowSymbol, ArrowTextSymbol:
+			return evaluateJSONOperator(Symbol(op.Value), left, lt, right, rt)
+		}
+
+		left, right, lt, err = unify(left, lt, right, rt)
+		if err != nil {
+			return nil, 0, err
+		}
+		// Comparing anything with NULL gives NULL, which no filter matches.
+		if left.IsNull() || right.IsNull() {
+			return nullCell, BoolType, nil
+		}
+		if c := t.comparisonCollation(lt, bexp.Left, bexp.Right); c != nil {
+			left, right = c.cellKey(left), c.cellKey(right)
+		}
+
+		switch Symbol(op.Value) {
+		case EqSymbol:
+			return boolCell(bytes.Equal(left, right)), BoolType, nil
+		case NeqSymbol, BangEqSymbol:
+			return boolCell(!bytes.Equal(left, right)), BoolType, nil
+		case LtSymbol:
+			return boolCell(compareCells(left, right, lt) < 0), BoolType, nil
+		case LteSymbol:
+			return boolCell(compareCells(left, right, lt) <= 0), BoolType, nil
+		case GtSymbol:
+			return boolCell(compareCells(left, right, lt) > 0), BoolType, nil
+		case GteSymbol:
+			return boolCell(compareCells(left, right, lt) >= 0), BoolType, nil
+		}
+	}
+
+	return nil, 0, ErrInvalidOperator
+}
+
+// unify converts two values to their commonType so that they can be
+// compared.
+func unify(left MemoryCell, lt ColumnType, right MemoryCell, rt ColumnType) (MemoryCell, MemoryCell, ColumnType, error) {
+	ct, ok := commonType(lt, rt)
+	if !ok {
+		return nil, nil, 0, fmt.Errorf("%w: cannot compare %s to %s", ErrTypeMismatch, lt, rt)
+	}
+	left, err := convertCell(left, lt, ct)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	right, err = convertCell(right, rt, ct)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return left, right, ct, nil
+}
+
+// arithmetic applies op to two numbers after converting them to their
+// common type, or moves a date by a number of days. NULL on either side
+// gives NULL, and a result that does not fit in that type is an error
+// rather than wrapping around.
+func arithmetic(op Symbol, left MemoryCell, lt ColumnType, right MemoryCell, rt ColumnType) (MemoryCell, ColumnType, error) {
+	ct, ok := arithmeticType(op, lt, rt)
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: %s expects numbers, got %s and %s", ErrTypeMismatch, op, lt, rt)
+	}
+	if left.IsNull() || right.IsNull() {
+		return nullCell, ct, nil
+	}
+
+	if ct == FloatType {
+		l, _ := convertCell(left, lt, ct)
+		r, _ := convertCell(right, rt, ct)
+		result, err := floatArithmetic(op, l.AsFloat(), r.AsFloat())
+		if err != nil {
+			return nil, 0, err
+		}
+		return floatCell(result), ct, nil
+	}
+
+	result, ok, err := integerArithmetic(op, left.AsInt(), right.AsInt())
+	if err != nil {
+		return nil, 0, err
+	}
+	if !ok {
+		return nil, 0, ErrIntegerOutOfRange
+	}
+	if ct == DateType {
+		if !daysInRange(result) {
+			return nil, 0, ErrValueOutOfRange
+		}
+		return intCell(int32(result)), ct, nil
+	}
+	cell, err := convertCell(bigIntCell(result), BigIntType, ct)
+	if err != nil {
+		return nil, 0, err
+	}
+	return cell, ct, nil
+}
+
+func (t *table) evaluateUnaryExpression(row []MemoryCell, uexp *UnaryExpression) (MemoryCell, ColumnType, error) {
+	operand, ct, err := t.evaluateExpression(row, uexp.Operand)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch uexp.Op.Value {
+	case string(NotKeyword):
+		if !compatible(ct, BoolType) {
+			return nil, 0, fmt.Errorf("%w: not expects a boolean, got %s", ErrTypeMismatch, ct)
+		}
+		if operand.IsNull() {
+			return nullCell, BoolType, nil
+		}
+		return boolCell(!operand.AsBool()), BoolType, nil
+	case string(MinusSymbol):
+		return arithmetic(MinusSymbol, intCell(0), IntType, operand, ct)
+	}
+
+	return nil, 0, ErrInvalidOperator
+}
+
+func (t *table) evaluateCastExpression(row []MemoryCell, cexp *CastExpression) (MemoryCell, ColumnType, error) {
+	operand, from, err := t.evaluateExpression(row, cexp.Operand)
+	if err != nil {
+		return nil, 0, err
+	}
+	to, err := datatype(cexp.Type)
+	if err != nil {
+		return nil, 0, err
+	}
+	cell, err := castCell(operand, from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+	return cell, to, nil
+}
+
+// evaluateCaseExpression gives the result of the first WHEN that matches,
+// or else the ELSE result or NULL. A condition that is NULL does not match,
+// and neither does a NULL operand in the simple form. Only the result that
+// is picked is evaluated against row, so its type has to be found by
+// evaluating all of them against NULLs as projection does.
+func (t *table) evaluateCaseExpression(row []MemoryCell, cexp *CaseExpression) (MemoryCell, ColumnType, error) {
+	nulls := row
+	if row != nil {
+		nulls = make([]MemoryCell, len(t.columns))
+	}
+	ct := NullType
+	for _, result := range cexp.results() {
+		_, rt, err := t.evaluateExpression(nulls, result)
+		if err != nil {
+			return nil, 0, err
+		}
+		var ok bool
+		if ct, ok = commonType(ct, rt); !ok {
+			return nil, 0, fmt.Errorf("%w: case results %s and %s", ErrTypeMismatch, ct, rt)
+		}
+	}
+
+	var operand MemoryCell
+	var ot ColumnType
+	if cexp.Operand != nil {
+		var err error
+		operand, ot, err = t.evaluateExpression(row, cexp.Operand)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	chosen := cexp.Else
+	for _, when := range cexp.Whens {
+		cell, wt, err := t.evaluateExpression(row, when.When)
+		if err != nil {
+			return nil, 0, err
+		}
+		if cexp.Operand != nil {
+			left, right, _, err := unify(operand, ot, cell, wt)
+			if err != nil {
+				return nil, 0, err
+			}
+			if left.IsNull() || right.IsNull() || !bytes.Equal(left, right) {
+				continue
+			}
+		} else {
+			if !compatible(wt, BoolType) {
+				return nil, 0, ErrInvalidCondition
+			}
+			if !cell.AsBool() {
+				continue
+			}
+		}
+		chosen = when.Then
+		break
+	}
+	if chosen == nil {
+		return nullCell, ct, nil
+	}
+
+	cell, rt, err := t.evaluateExpression(row, chosen)
+	if err != nil {
+		return nil, 0, err
+	}
+	cell, err = convertCell(cell, rt, ct)
+	if err != nil {
+		return nil, 0, err
+	}
+	return cell, ct, nil
+}
+
+func isFalse(cell MemoryCell) bool {
+	return !cell.IsNull() && !cell.AsBool()
+}
+
+func (t *table) evaluateIsNullExpression(row []MemoryCell, iexp *IsNullExpression) (MemoryCell, ColumnType, error) {
+	operand, _, err := t.evaluateExpression(row, iexp.Operand)
+	if err != nil {
+		return nil, 0, err
+	}
+	return boolCell(operand.IsNull() != iexp.Not), BoolType, nil
+}
+
+func (mb *MemoryBackend) createIndex(tx *transaction, crt *CreateIndexStatement) error {
+	t, ok := mb.tables[crt.Table.Value]
+	if !ok {
+		return ErrTableDoesNotExist
+	}
+
+	for _, other := range mb.tables {
+		for _, idx := range other.indexes {
+			if idx.name == crt.Name.Value {
+				return ErrIndexAlreadyExists
+			}
+		}
+	}
+
+	column := t.columnIndex(crt.Column.Value)
+	if column == -1 {
+		return ErrColumnDoesNotExist
+	}
+
+	t.addIndex(crt.Name.Value, column)
+	added := t.indexes[len(t.indexes)-1]
+	tx.undo = append(tx.undo, func() {
+		for i, idx := range t.indexes {
+			if idx == added {
+				t.indexes = append(t.indexes[:i:i], t.indexes[i+1:]...)
+				return
+			}
+		}
+	})
+	return nil
+}
+
+func (mb *MemoryBackend) dropTable(tx *transaction, drp *DropTableStatement) error {
+	t, ok := mb.tables[drp.Name.Value]
+	if !ok {
+		if _, ok := mb.views[drp.Name.Value]; ok {
+			return ErrWrongObjectType
+		}
+		if drp.IfExists {
+			return nil
+		}
+		return ErrTableDoesNotExist
+	}
+	if err := mb.checkDependents(drp.Name.Value); err != nil {
+		return err
+	}
+
+	delete(mb.tables, drp.Name.Value)
+	tx.undo = append(tx.undo, func() {
+		mb.tables[drp.Name.Value] = t
+	})
+	return nil
+}
+
+func (mb *MemoryBackend) createTable(ctx context.Context, tx *transaction, crt *CreateTableStatement) error {
+	if mb.exists(crt.Name.Value) {
+		if crt.IfNotExists {
+			return nil
+		}
+		return ErrTableAlreadyExists
+	}
+	if !mb.hasDatabase(databaseOf(crt.Name.Value)) && !isTemporary(crt.Name.Value) {
+		return ErrDatabaseDoesNotExist
+	}
+
+	t := table{name: crt.Name.Value, primaryKey: -1, clock: mb.now}
+	for i, col := range crt.Cols {
+		t.columns = append(t.columns, col.Name.Value)
+
+		isPrimaryKey := col.hasConstraint(PrimaryKeyConstraint)
+		if isPrimaryKey {
+			t.primaryKey = i
+		}
+		t.notNull = append(t.notNull, isPrimaryKey || col.hasConstraint(NotNullConstraint))
+		t.unique = append(t.unique, isPrimaryKey || col.hasConstraint(UniqueConstraint))
+		t.autoIncrement = append(t.autoIncrement, col.hasConstraint(AutoIncrementConstraint))
+
+		dt, err := datatype(col.Datatype)
+		if err != nil {
+			return err
+		}
+		t.columnTypes = append(t.columnTypes, dt)
+		t.lengths = append(t.lengths, col.maxLength())
+		c, err := columnCollation(col, dt)
+		if err != nil {
+			return err
+		}
+		t.collations = append(t.collations, c)
+
+		t.defaults = append(t.defaults, col.defaultValue())
+		if _, err := t.defaultCell(context.Background(), i); err != nil {
+			return err
+		}
+	}
+
+	// Unique columns get an index so checking a new value does not scan
+	// the table.
+	for i, unique := range t.unique {
+		if unique {
+			t.addIndex(t.uniqueIndexName(i), i)
+		}
+	}
+
+	// Foreign keys are resolved once every column is there, since they
+	// may reference the table itself.
+	t.references = make([]*reference, len(crt.Cols))
+	for i, col := range crt.Cols {
+		fk := col.foreignKey()
+		if fk == nil {
+			continue
+		}
+		ref, err := mb.resolveReference(&t, fk, t.columnTypes[i])
+		if err != nil {
+			return err
+		}
+		t.references[i] = ref
+		t.addReferenceIndex(i)
+	}
+
+	for _, check := range crt.checks() {
+		if err := t.validateCheck(ctx, check); err != nil {
+			return err
+		}
+		t.checks = append(t.checks, check)
+	}
+
+	if crt.Layout != nil && crt.Layout.Value == ColumnLayout {
+		t.columnar = true
+		t.rebuildVectors()
+	}
+
+	mb.tables[crt.Name.Value] = &t
+	tx.undo = append(tx.undo, func() {
+		delete(mb.tables, crt.Name.Value)
+	})
+	return nil
+}
+
+func datatype(t *Token) (ColumnType, error) {
+	switch keyword(t.Value) {
+	case IntKeyword, IntegerKeyword:
+		return IntType, nil
+	case BigintKeyword:
+		return BigIntType, nil
+	case FloatKeyword, RealKeyword:
+		return FloatType, nil
+	case TextKeyword, VarcharKeyword:
+		return TextType, nil
+	case BoolKeyword, BooleanKeyword:
+		return BoolType, nil
+	case DateKeyword:
+		return DateType, nil
+	case TimestampKeyword:
+		return TimestampType, nil
+	case JsonKeyword:
+		return JSONType, nil
+	case ByteaKeyword, BlobKeyword:
+		return ByteaType, nil
+	}
+	return 0, ErrInvalidDatatype
+}
+
+// defaultCell evaluates the DEFAULT of column i of t, or returns NULL if
+// it has none. Defaults cannot refer to columns, so they are evaluated
+// anew for each row they fill, at the time of the statement of ctx.
+func (t *table) defaultCell(ctx context.Context, i int) (MemoryCell, error) {
+	if t.defaults[i] == nil {
+		return nullCell, nil
+	}
+	cell, ct, err := (&table{}).evaluateExpression(nil, fixExpressionTime(ctx, t.defaults[i]))
+	if err != nil {
+		return nil, err
+	}
+	return t.assign(i, cell, ct)
+}
+
+// autoIncrementColumn returns the index of the auto-increment column of t,
+// or -1.
+func (t *table) autoIncrementColumn() int {
+	for i, auto := range t.autoIncrement {
+		if auto {
+			return i
+		}
+	}
+	return -1
+}
+
+// nextSequenceValue takes the next value of the sequence of t for column
+// i, which runs out where the type of the column does.
+func (t *table) nextSequenceValue(i int) (MemoryCell, error) {
+	if t.columnTypes[i] == BigIntType {
+		if t.sequence == math.MaxInt64 {
+			return nil, ErrIntegerOutOfRange
+		}
+		t.sequence++
+		return bigIntCell(t.sequence), nil
+	}
+	if t.sequence >= math.MaxInt32 {
+		return nil, ErrIntegerOutOfRange
+	}
+	t.sequence++
+	return intCell(int32(t.sequence)), nil
+}
+
+// fillAutoIncrement gives row the next value of the sequence of t if it
+// leaves the auto-increment column NULL, and returns that value or 0. A
+// value given explicitly moves the sequence past it, so later rows do not
+// take it again.
+func (t *table) fillAutoIncrement(row []MemoryCell) (int64, error) {
+	i := t.autoIncrementColumn()
+	if i == -1 {
+		return 0, nil
+	}
+	if !row[i].IsNull() {
+		if v := row[i].AsInt(); v > t.sequence {
+			t.sequence = v
+		}
+		return 0, nil
+	}
+
+	cell, err := t.nextSequenceValue(i)
+	if err != nil {
+		return 0, err
+	}
+	row[i] = cell
+	return t.sequence, nil
+}
+
+// assign converts a value of type ct for storing in column i of t. Text
+// longer than the column allows is an error unless only spaces are cut
+// off, as in PostgreSQL.
+func (t *table) assign(i int, cell MemoryCell, ct ColumnType) (MemoryCell, error) {
+	if !assignable(ct, t.columnTypes[i]) {
+		return nil, ErrInvalidDatatype
+	}
+	cell, err := convertCell(cell, ct, t.columnTypes[i])
+	if err != nil || t.lengths[i] == 0 || cell.IsNull() {
+		return cell, err
+	}
+
+	chars := []rune(cell.AsText())
+	if len(chars) <= t.lengths[i] {
+		return cell, nil
+	}
+	if strings.TrimRight(string(chars[t.lengths[i]:]), " ") != "" {
+		return nil, fmt.Errorf("%w for type varchar(%d)", ErrValueTooLong, t.lengths[i])
+	}
+	return MemoryCell(string(chars[:t.lengths[i]])), nil
+}
+
+// uniqueIndexName names the index of unique column i the way PostgreSQL
+// names them.
+func (t *table) uniqueIndexName(i int) string {
+	if i == t.primaryKey {
+		return t.name + "_pkey"
+	}
+	return t.name + "_" + t.columns[i] + "_key"
+}
+
+// alterTable changes the columns of a table, rewriting every version of
+// its rows to match. Like other schema changes it takes effect for every
+// session at once.
+func (mb *MemoryBackend) alterTable(ctx context.Context, tx *transaction, alt *AlterTableStatement) error {
+	t, ok := mb.tables[alt.Table.Value]
+	if !ok {
+		return ErrTableDoesNotExist
+	}
+
+	// Views, foreign keys and checks name the columns they read, so those
+	// may not be dropped or renamed.
+	if alt.Action != AddColumnAction {
+		if err := mb.checkColumnDependents(alt.Table.Value, alt.Column.Value); err != nil {
+			return err
+		}
+		if t.checksRead(alt.Column.Value) {
+			return ErrDependentObjects
+		}
+	}
+
+	switch alt.Action {
+	case AddColumnAction:
+		return mb.addColumn(ctx, tx, t, alt.Add)
+	case DropColumnAction:
+		return t.dropColumn(tx, alt.Column.Value)
+	case RenameColumnAction:
+		return t.renameColumn(tx, alt.Column.Value, alt.To.Value)
+	}
+	return nil
+}
+
+// tableSchema is the part of a table that altering its columns changes.
+type tableSchema struct {
+	columns       []string
+	columnTypes   []ColumnType
+	lengths       []int
+	notNull       []bool
+	unique        []bool
+	defaults      []*Expression
+	autoIncrement []bool
+	references    []*reference
+	checks        []*Expression
+	collations    []*collation
+	primaryKey    int
+	indexes       []*index
+}
+
+func (t *table) schema() tableSchema {
+	return tableSchema{t.columns, t.columnTypes, t.lengths, t.notNull, t.unique, t.defaults, t.autoIncrement, t.references, t.checks, t.collations, t.primaryKey, t.indexes}
+}
+
+// restoreSchema puts back a schema saved before an ALTER TABLE that is
+// being rolled back.
+func (t *table) restoreSchema(s tableSchema) {
+	t.columns, t.columnTypes, t.lengths, t.notNull, t.unique, t.defaults = s.columns, s.columnTypes, s.lengths, s.notNull, s.unique, s.defaults
+	t.autoIncrement, t.references, t.checks, t.collations = s.autoIncrement, s.references, s.checks, s.collations
+	t.primaryKey, t.indexes = s.primaryKey, s.indexes
+	t.stats = nil
+	t.rebuildIndexes()
+	t.rebuildVectors()
+}
+
+// addColumn appends col to t, filling it in every existing row with its
+// default, or NULL. A column that cannot be NULL can only be added without
+// a default while no row needs a value. An auto-increment column instead
+// numbers the existing rows from the table's sequence. The rows must meet
+// the CHECK constraints of the column as they are filled.
+func (mb *MemoryBackend) addColumn(ctx context.Context, tx *transaction, t *table, col *ColumnDefinition) error {
+	if t.columnIndex(col.Name.Value) != -1 {
+		return ErrDuplicateColumn
+	}
+	dt, err := datatype(col.Datatype)
+	if err != nil {
+		return err
+	}
+	c, err := columnCollation(col, dt)
+	if err != nil {
+		return err
+	}
+	isPrimaryKey := col.hasConstraint(PrimaryKeyConstraint)
+	if isPrimaryKey && t.primaryKey != -1 {
+		return ErrMultiplePrimaryKeys
+	}
+	isAutoIncrement := col.hasConstraint(AutoIncrementConstraint)
+	if isAutoIncrement && t.autoIncrementColumn() != -1 {
+		return ErrMultipleAutoIncrement
+	}
+	var ref *reference
+	if fk := col.foreignKey(); fk != nil {
+		if ref, err = mb.resolveReference(t, fk, dt); err != nil {
+			return err
+		}
+	}
+
+	saved := t.schema()
+	i := len(t.columns)
+	// The slices are copied so views of the old schema keep it.
+	t.columns = append(t.columns[:i:i], col.Name.Value)
+	t.columnTypes = append(t.columnTypes[:i:i], dt)
+	t.lengths = append(t.lengths[:i:i], col.maxLength())
+	t.notNull = append(t.notNull[:i:i], isPrimaryKey || col.hasConstraint(NotNullConstraint))
+	t.unique = append(t.unique[:i:i], isPrimaryKey || col.hasConstraint(UniqueConstraint))
+	t.defaults = append(t.defaults[:i:i], col.defaultValue())
+	t.autoIncrement = append(t.autoIncrement[:i:i], isAutoIncrement)
+	t.references = append(t.references[:i:i], ref)
+	t.collations = append(t.collations[:i:i], c)
+	if isPrimaryKey {
+		t.primaryKey = i
+	}
+	for _, check := range col.checks() {
+		if err := t.validateCheck(ctx, check); err != nil {
+			t.restoreSchema(saved)
+			return err
+		}
+		t.checks = append(t.checks[:len(t.checks):len(t.checks)], check)
+	}
+
+	value, err := t.defaultCell(ctx, i)
+	if err == nil && !value.IsNull() && ref != nil {
+		// The rows there already take the default, which has to be
+		// referenced if there are any.
+		for _, v := range t.versions {
+			if mb.live(tx, v) {
+				row := make([]MemoryCell, len(t.columns))
+				row[i] = value
+				err = mb.checkReferences(tx, t, row)
+				break
+			}
+		}
+	}
+	if err == nil && t.notNull[i] && value.IsNull() && !isAutoIncrement {
+		for _, v := range t.versions {
+			if mb.live(tx, v) {
+				err = ErrViolatesNotNull
+				break
+			}
+		}
+	}
+	if err != nil {
+		t.restoreSchema(saved)
+		return err
+	}
+
+	// The rows share the one value, as cells are never changed in place.
+	for n, v := range t.versions {
+		cell := value
+		if isAutoIncrement && cell.IsNull() {
+			cell, err = t.nextSequenceValue(i)
+		}
+		if err == nil && len(t.checks) > len(saved.checks) && mb.live(tx, v) {
+			err = t.checkRow(ctx, append(v.cells[:i:i], cell))
+		}
+		if err != nil {
+			for _, v := range t.versions[:n] {
+				v.cells = v.cells[:i:i]
+			}
+			t.restoreSchema(saved)
+			return err
+		}
+		v.cells = append(v.cells[:i:i], cell)
+	}
+	if t.unique[i] {
+		t.indexes = t.indexes[:len(t.indexes):len(t.indexes)]
+		t.addIndex(t.uniqueIndexName(i), i)
+	}
+	if ref != nil {
+		t.indexes = t.indexes[:len(t.indexes):len(t.indexes)]
+		t.addReferenceIndex(i)
+	}
+	t.stats = nil
+	t.rebuildVectors()
+
+	tx.undo = append(tx.undo, func() {
+		// Versions other sessions added since have the column too.
+		for _, v := range t.versions {
+			v.cells = v.cells[:i:i]
+		}
+		t.restoreSchema(saved)
+	})
+	return nil
+}
+
+// dropColumn removes the column named name from t along with its indexes.
+func (t *table) dropColumn(tx *transaction, name string) error {
+	i := t.columnIndex(name)
+	if i == -1 {
+		return ErrColumnDoesNotExist
+	}
+
+	saved := t.schema()
+	// The slices are copied so views of the old schema keep it.
+	t.columns = append(t.columns[:i:i], t.columns[i+1:]...)
+	t.columnTypes = append(t.columnTypes[:i:i], t.columnTypes[i+1:]...)
+	t.lengths = append(t.lengths[:i:i], t.lengths[i+1:]...)
+	t.notNull = append(t.notNull[:i:i], t.notNull[i+1:]...)
+	t.unique = append(t.unique[:i:i], t.unique[i+1:]...)
+	t.defaults = append(t.defaults[:i:i], t.defaults[i+1:]...)
+	t.autoIncrement = append(t.autoIncrement[:i:i], t.autoIncrement[i+1:]...)
+	t.references = append(t.references[:i:i], t.references[i+1:]...)
+	t.collations = append(t.collations[:i:i], t.collations[i+1:]...)
+	switch {
+	case t.primaryKey == i:
+		t.primaryKey = -1
+	case t.primaryKey > i:
+		t.primaryKey--
+	}
+	t.indexes = nil
+	for _, idx := range saved.indexes {
+		switch {
+		case idx.column < i:
+			t.indexes = append(t.indexes, idx)
+		case idx.column > i:
+			t.indexes = append(t.indexes, &index{name: idx.name, column: idx.column - 1, collation: idx.collation, tree: idx.tree})
+		}
+	}
+
+	dropped := make(map[*rowVersion]MemoryCell, len(t.versions))
+	for _, v := range t.versions {
+		dropped[v] = v.cells[i]
+		v.cells = append(v.cells[:i:i], v.cells[i+1:]...)
+	}
+	t.stats = nil
+	t.rebuildVectors()
+
+	tx.undo = append(tx.undo, func() {
+		// Versions other sessions added since get NULL.
+		for _, v := range t.versions {
+			v.cells = append(append(v.cells[:i:i], dropped[v]), v.cells[i:]...)
+		}
+		t.restoreSchema(saved)
+	})
+	return nil
+}
+
+func (t *table) renameColumn(tx *transaction, name, to string) error {
+	i := t.columnIndex(name)
+	if i == -1 {
+		return ErrColumnDoesNotExist
+	}
+	if t.columnIndex(to) != -1 {
+		return ErrDuplicateColumn
+	}
+
+	saved := t.schema()
+	t.columns = append([]string{}, t.columns...)
+	t.columns[i] = to
+	tx.undo = append(tx.undo, func() {
+		t.restoreSchema(saved)
+	})
+	return nil
+}
+
+// insertColumns returns the index in t of each value of a row of inst.
+// Without an explicit column list values are assigned in schema order.
+func (t *table) insertColumns(inst *InsertStatement) ([]int, error) {
+	if inst.Columns == nil {
+		indexes := make([]int, len(t.columns))
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes, nil
+	}
+
+	var indexes []int
+	seen := map[int]bool{}
+	for _, col := range inst.Columns {
+		i := t.columnIndex(col.Value)
+		if i == -1 {
+			return nil, ErrColumnDoesNotExist
+		}
+		if seen[i] {
+			return nil, ErrDuplicateColumn
+		}
+		seen[i] = true
+		indexes = append(indexes, i)
+	}
+	return indexes, nil
+}
+
+// insert adds each row of inst to the table, stopping at the first that
+// fails, and returns how many it added. The returned results hold the
+// RETURNING items for the new rows and are nil when the statement has no
+// RETURNING clause. The id returned is the last value the rows took from
+// the sequence of the table, or 0 if they took none.
+func (mb *MemoryBackend) insert(ctx context.Context, tx *transaction, inst *InsertStatement) (int, *Results, int64, error) {
+	inst = fixTime(ctx, &Statement{Kind: InsertKind, InsertStatement: inst}).InsertStatement
+	t, ok := mb.tables[inst.Table.Value]
+	if !ok {
+		return 0, nil, 0, ErrTableDoesNotExist
+	}
+
+	indexes, err := t.insertColumns(inst)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+
+	values, err := mb.insertValues(ctx, tx, t, inst, indexes)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+
+	var lastID int64
+	inserted := make([][]MemoryCell, 0, len(values))
+	// returned holds the rows inserted or updated, in order, and written
+	// the versions they were stored as, which a later row of an upsert
+	// may not update again.
+	var returned [][]MemoryCell
+	written := map[*rowVersion]bool{}
+	for _, cells := range values {
+		// Columns missing from an explicit column list get their defaults.
+		row := make([]MemoryCell, len(t.columns))
+		for i := range row {
+			if row[i], err = t.defaultCell(ctx, i); err != nil {
+				return 0, nil, 0, err
+			}
+		}
+		for i, cell := range cells {
+			row[indexes[i]] = cell
+		}
+		id, err := t.fillAutoIncrement(row)
+		if err != nil {
+			return 0, nil, 0, err
+		}
+
+		if err := t.fireTriggers(BeforeTrigger, InsertEvent, nil, row); err != nil {
+			return 0, nil, 0, err
+		}
+		if inst.OnConflict != nil {
+			existing, err := mb.conflicting(tx, t, inst.OnConflict, row)
+			if err != nil {
+				return 0, nil, 0, err
+			}
+			if existing != nil {
+				updated, err := mb.upsert(ctx, tx, t, inst.OnConflict, existing, row, written)
+				if err != nil {
+					return 0, nil, 0, err
+				}
+				if updated != nil {
+					returned = append(returned, updated)
+				}
+				continue
+			}
+		}
+		if id != 0 {
+			lastID = id
+		}
+		// Earlier rows of the statement count towards the constraints.
+		if err := mb.checkConstraints(ctx, tx, t, row); err != nil {
+			return 0, nil, 0, err
+		}
+
+		t.insertVersion(tx, row)
+		written[t.versions[len(t.versions)-1]] = true
+		if err := mb.checkReferences(tx, t, row); err != nil {
+			return 0, nil, 0, err
+		}
+		inserted = append(inserted, row)
+		returned = append(returned, row)
+	}
+
+	for _, row := range inserted {
+		if err := mb.changed(tx, t, InsertEvent, nil, row); err != nil {
+			return 0, nil, 0, err
+		}
+	}
+
+	results, err := t.returning(inst.Returning, returned)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	return len(returned), results, lastID, nil
+}
+
+// insertValues computes the rows inst inserts into the columns of t at
+// indexes, either from its VALUES or by running its SELECT, which sees the
+// table as it was before the statement.
+func (mb *MemoryBackend) insertValues(ctx context.Context, tx *transaction, t *table, inst *InsertStatement, indexes []int) ([][]MemoryCell, error) {
+	if inst.Select != nil {
+		results, err := mb.query(ctx, tx.snapshot, inst.Select)
+		if err != nil {
+			return nil, err
+		}
+		if len(results.Columns) != len(indexes) {
+			return nil, ErrMissingValues
+		}
+		for i, col := range results.Columns {
+			if !assignable(col.Type, t.columnTypes[indexes[i]]) {
+				return nil, ErrInvalidDatatype
+			}
+		}
+
+		var rows [][]MemoryCell
+		for _, result := range results.Rows {
+			row := make([]MemoryCell, len(result))
+			for i, cell := range result {
+				row[i], err = t.assign(indexes[i], cell.(MemoryCell), results.Columns[i].Type)
+				if err != nil {
+					return nil, err
+				}
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	}
+
+	var rows [][]MemoryCell
+	for _, values := range inst.Values {
+		if len(values) != len(indexes) {
+			return nil, ErrMissingValues
+		}
+
+		var row []MemoryCell
+		for i, value := range values {
+			cell, ct, err := t.evaluateExpression(nil, value)
+			if err != nil {
+				return nil, err
+			}
+			cell, err = t.assign(indexes[i], cell, ct)
+			if err != nil {
+				return nil, err
+			}
+			row = append(row, cell)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (mb *MemoryBackend) checkConstraints(ctx context.Context, tx *transaction, t *table, row []MemoryCell) error {
+	for i, cell := range row {
+		if t.notNull[i] && cell.IsNull() {
+			return ErrViolatesNotNull
+		}
+	}
+
+	if err := t.checkRow(ctx, row); err != nil {
+		return err
+	}
+
+	for i, cell := range row {
+		if !t.unique[i] || cell.IsNull() {
+			continue
+		}
+		if mb.containsValue(tx, t, i, cell, nil) {
+			return t.uniqueViolation(i)
+		}
+	}
+
+	return nil
+}
+
+// containsValue reports whether any live version of t other than those in
+// replaced holds value in column i.
+func (mb *MemoryBackend) containsValue(tx *transaction, t *table, i int, value MemoryCell, replaced map[*rowVersion]bool) bool {
+	for _, v := range mb.holding(tx, t, i, value) {
+		if !replaced[v] {
+			return true
+		}
+	}
+	return false
+}
+
+// holding returns the live versions of t that hold value in column i, or
+// a value its collation takes as equal.
+func (mb *MemoryBackend) holding(tx *transaction, t *table, i int, value MemoryCell) []*rowVersion {
+	var versions []*rowVersion
+	c := t.collation(i)
+	key := c.cellKey(value)
+	holds := func(v *rowVersion) bool {
+		return mb.live(tx, v) && bytes.Equal(c.cellKey(v.cells[i]), key)
+	}
+
+	if idx := t.indexOn(i); idx != nil {
+		bound := indexBound{key: key, inclusive: true}
+		for _, pos := range idx.tree.scan(bound, bound) {
+			if holds(t.versions[pos]) {
+				versions = append(versions, t.versions[pos])
+			}
+		}
+		return versions
+	}
+	for _, v := range t.versions {
+		if holds(v) {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+func (t *table) uniqueViolation(i int) error {
+	if i == t.primaryKey {
+		return ErrViolatesPrimaryKey
+	}
+	return ErrViolatesUnique
+}
+
+// filter returns the rows of t for which where holds. The plan decides
+// whether an index narrows the rows first, so filter checks every row it
+// is given, in batches when where compiles to a vectorExpression.
+func (t *table) filter(ctx context.Context, where *Expression) ([][]MemoryCell, error) {
+	if where == nil {
+		return t.rows, nil
+	}
+	if cond := t.compileVector(where); cond != nil && compatible(cond.ct, BoolType) {
+		return t.filterBatches(ctx, where, cond)
+	}
+	return t.filterRows(ctx, t.rows, where)
+}
+
+// filterRows returns the rows for which where holds, evaluating it a row
+// at a time.
+func (t *table) filterRows(ctx context.Context, rows [][]MemoryCell, where *Expression) ([][]MemoryCell, error) {
+	var kept [][]MemoryCell
+	for i, row := range rows {
+		if err := canceled(ctx, i); err != nil {
+			return nil, err
+		}
+		cell, ct, err := t.evaluateExpression(row, where)
+		if err != nil {
+			return nil, err
+		}
+		if !compatible(ct, BoolType) {
+			return nil, ErrInvalidCondition
+		}
+		if cell.AsBool() {
+			kept = append(kept, row)
+		}
+	}
+
+	return kept, nil
+}
+
+// isAggregate reports whether slct groups its rows, either with GROUP BY
+// and HAVING or by selecting aggregate functions over the whole table.
+func isAggregate(slct *SelectStatement) bool {
+	if len(slct.GroupBy) > 0 || slct.Having != nil {
+		return true
+	}
+	for _, item := range slct.Item {
+		if !item.Asterisk && hasAggregate(item.Exp) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAggregate reports whether exp calls an aggregate function.
+func hasAggregate(exp *Expression) bool {
+	found := false
+	_, _ = rewriteExpression(exp, func(exp *Expression) (*Expression, error) {
+		if exp.Kind == FunctionKind && exp.Function.isAggregate() {
+			found = true
+			return exp, nil
+		}
+		return nil, nil
+	})
+	return found
+}
+
+// aggregate computes one result row for each group of rows. Every column
+// a select item reads outside an aggregate function must be listed in
+// GROUP BY. Without GROUP BY all rows form a single group.
+func (t *table) aggregate(ctx context.Context, slct *SelectStatement, rows [][]MemoryCell) (*Results, error) {
+	groups, err := t.groupRows(ctx, slct.GroupBy, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []ResultColumn
+	for _, item := range slct.Item {
+		column, err := t.groupColumn(slct.GroupBy, item)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+
+	var kept [][][]MemoryCell
+	results := [][]Cell{}
+	for _, group := range groups {
+		if slct.Having != nil {
+			cell, ct, err := t.evaluateGroupExpression(slct.GroupBy, group, slct.Having)
+			if err != nil {
+				return nil, err
+			}
+			if !compatible(ct, BoolType) {
+				return nil, ErrInvalidCondition
+			}
+			if !cell.AsBool() {
+				continue
+			}
+		}
+
+		var result []Cell
+		for _, item := range slct.Item {
+			cell, _, err := t.evaluateGroupExpression(slct.GroupBy, group, item.Exp)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, cell)
+		}
+		kept = append(kept, group)
+		results = append(results, result)
+	}
+
+	if len(slct.OrderBy) > 0 {
+		order, err := sortOrder(ctx, len(kept), slct.OrderBy, func(i int, exp *Expression) (MemoryCell, ColumnType, error) {
+			cell, ct, err := t.evaluateGroupExpression(slct.GroupBy, kept[i], exp)
+			if err != nil {
+				return nil, 0, err
+			}
+			return t.comparisonCollation(ct, exp).cellKey(cell), ct, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sorted := make([][]Cell, len(results))
+		for i, o := range order {
+			sorted[i] = results[o]
+		}
+		results = sorted
+	}
+
+	return &Results{
+		Columns: columns,
+		Rows:    results,
+	}, nil
+}
+
+// groupColumn describes the result column for a select item of a grouped
+// query. Other expressions than a bare aggregate are evaluated over a group
+// of one row of NULLs to find their type.
+func (t *table) groupColumn(groupBy []*Expression, item *SelectItem) (ResultColumn, error) {
+	if item.Asterisk {
+		return ResultColumn{}, ErrInvalidSelectItem
+	}
+
+	var ct ColumnType
+	var err error
+	if item.Exp.Kind == FunctionKind && item.Exp.Function.isAggregate() {
+		_, ct, err = t.evaluateAggregate(item.Exp.Function, nil)
+	} else {
+		nulls := make([]MemoryCell, len(t.columns))
+		_, ct, err = t.evaluateGroupExpression(groupBy, [][]MemoryCell{nulls}, item.Exp)
+	}
+	if err != nil {
+		return ResultColumn{}, err
+	}
+	return ResultColumn{Type: ct, Name: itemName(item)}, nil
+}
+
+// groupRows splits rows by their GROUP BY values, keeping the groups in
+// the order they first appear. Without GROUP BY there is exactly one group,
+// even when there are no rows.
+func (t *table) groupRows(ctx context.Context, groupBy []*Expression, rows [][]MemoryCell) ([][][]MemoryCell, error) {
+	if len(groupBy) == 0 {
+		return [][][]MemoryCell{rows}, nil
+	}
+
+	var groups [][][]MemoryCell
+	seen := map[string]int{}
+	for n, row := range rows {
+		if err := canceled(ctx, n); err != nil {
+			return nil, err
+		}
+		values := make([]Cell, len(groupBy))
+		types := make([]ResultColumn, len(groupBy))
+		for i, exp := range groupBy {
+			cell, ct, err := t.evaluateKey(row, exp)
+			if err != nil {
+				return nil, err
+			}
+			values[i], types[i].Type = cell, ct
+		}
+
+		key := rowKey(values, types)
+		if i, ok := seen[key]; ok {
+			groups[i] = append(groups[i], row)
+			continue
+		}
+		seen[key] = len(groups)
+		groups = append(groups, [][]MemoryCell{row})
+	}
+	return groups, nil
+}
+
+// grouped reports whether column i is a GROUP BY expression, so that it has
+// a single value within each group.
+func (t *table) grouped(groupBy []*Expression, i int) bool {
+	for _, exp := range groupBy {
+		if j, err := t.resolveColumn(exp); err == nil && j == i {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateGroupExpression evaluates exp once for a group of rows. Aggregate
+// functions in exp are computed over the whole group, and columns must be
+// grouped so that any row of the group gives the same value.
+func (t *table) evaluateGroupExpression(groupBy []*Expression, rows [][]MemoryCell, exp *Expression) (MemoryCell, ColumnType, error) {
+	resolved, err := t.resolveAggregates(groupBy, rows, exp)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var row []MemoryCell
+	if len(rows) > 0 {
+		row = rows[0]
+	}
+	return t.evaluateExpression(row, resolved)
+}
+
+// resolveAggregates returns a copy of exp with each aggregate function
+// replaced by a literal holding its value over rows.
+func (t *table) resolveAggregates(groupBy []*Expression, rows [][]MemoryCell, exp *Expression) (*Expression, error) {
+	return rewriteExpression(exp, func(exp *Expression) (*Expression, error) {
+		switch exp.Kind {
+		case LiteralKind, ColumnReferenceKind:
+			i, err := t.resolveColumn(exp)
+			if err != nil {
+				return nil, err
+			}
+			if i != -1 && !t.grouped(groupBy, i) {
+				return nil, ErrInvalidSelectItem
+			}
+		case FunctionKind:
+			if !exp.Function.isAggregate() {
+				break
+			}
+			cell, ct, err := t.evaluateAggregate(exp.Function, rows)
+			if err != nil {
+				return nil, err
+			}
+			return cellExpression(cell, ct), nil
+		}
+		return nil, nil
+	})
+}
+
+// rewriteExpression returns a copy of exp in which every node that replace
+// returns an expression for is swapped for that expression. When replace
+// returns nil the node is kept and its operands are rewritten in turn.
+func rewriteExpression(exp *Expression, replace func(*Expression) (*Expression, error)) (*Expression, error) {
+	if exp == nil {
+		return nil, nil
+	}
+	if replaced, err := replace(exp); err != nil || replaced != nil {
+		return replaced, err
+	}
+
+	rewritten := *exp
+	var err error
+	rewrite := func(exp *Expression) *Expression {
+		if err != nil {
+			return nil
+		}
+		var r *Expression
+		r, err = rewriteExpression(exp, replace)
+		return r
+	}
+	rewriteAll := func(exps []*Expression) []*Expression {
+		if exps == nil {
+			return nil
+		}
+		rewritten := make([]*Expression, len(exps))
+		for i, exp := range exps {
+			rewritten[i] = rewrite(exp)
+		}
+		return rewritten
+	}
+
+	switch exp.Kind {
+	case BinaryKind:
+		rewritten.Binary = &BinaryExpression{
+			Left:  rewrite(exp.Binary.Left),
+			Right: rewrite(exp.Binary.Right),
+			Op:    exp.Binary.Op,
+		}
+	case UnaryKind:
+		rewritten.Unary = &UnaryExpression{
+			Operand: rewrite(exp.Unary.Operand),
+			Op:      exp.Unary.Op,
+		}
+	case IsNullKind:
+		rewritten.IsNull = &IsNullExpression{
+			Operand: rewrite(exp.IsNull.Operand),
+			Not:     exp.IsNull.Not,
+		}
+	case InKind:
+		rewritten.In = &InExpression{
+			Left:   rewrite(exp.In.Left),
+			List:   rewriteAll(exp.In.List),
+			Select: exp.In.Select,
+		}
+	case BetweenKind:
+		rewritten.Between = &BetweenExpression{
+			Left: rewrite(exp.Between.Left),
+			Low:  rewrite(exp.Between.Low),
+			High: rewrite(exp.Between.High),
+		}
+	case FunctionKind:
+		rewritten.Function = &FunctionExpression{
+			Name:     exp.Function.Name,
+			Args:     rewriteAll(exp.Function.Args),
+			Asterisk: exp.Function.Asterisk,
+			Over:     exp.Function.Over,
+		}
+	case CastKind:
+		rewritten.Cast = &CastExpression{
+			Operand: rewrite(exp.Cast.Operand),
+			Type:    exp.Cast.Type,
+		}
+	case CaseKind:
+		rewritten.Case = &CaseExpression{
+			Case:    exp.Case.Case,
+			Operand: rewrite(exp.Case.Operand),
+			Else:    rewrite(exp.Case.Else),
+		}
+		for _, when := range exp.Case.Whens {
+			rewritten.Case.Whens = append(rewritten.Case.Whens, &WhenClause{
+				When: rewrite(when.When),
+				Then: rewrite(when.Then),
+			})
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &rewritten, nil
+}
+
+// resolveSubqueries returns a copy of exp with each IN (SELECT ...) turned
+// into a list of the values the subquery returns. Subqueries cannot refer
+// to the outer query, so each one runs just once.
+func (mb *MemoryBackend) resolveSubqueries(ctx context.Context, snap *txSnapshot, exp *Expression) (*Expression, error) {
+	return rewriteExpression(exp, func(exp *Expression) (*Expression, error) {
+		if exp.Kind != InKind || exp.In.Select == nil {
+			return nil, nil
+		}
+
+		results, err := mb.query(ctx, snap, exp.In.Select)
+		if err != nil {
+			return nil, err
+		}
+		if len(results.Columns) != 1 {
+			return nil, ErrSubqueryColumns
+		}
+
+		left, err := mb.resolveSubqueries(ctx, snap, exp.In.Left)
+		if err != nil {
+			return nil, err
+		}
+		in := &InExpression{Left: left, List: []*Expression{}}
+		for _, row := range results.Rows {
+			in.List = append(in.List, cellExpression(row[0].(MemoryCell), results.Columns[0].Type))
+		}
+		return &Expression{Kind: InKind, In: in}, nil
+	})
+}
+
+// resolveSelectSubqueries returns a copy of slct with the subqueries in its
+// conditions and sort keys resolved.
+func (mb *MemoryBackend) resolveSelectSubqueries(ctx context.Context, snap *txSnapshot, slct *SelectStatement) (*SelectStatement, error) {
+	resolved := *slct
+	var err error
+	if resolved.Where, err = mb.resolveSubqueries(ctx, snap, slct.Where); err != nil {
+		return nil, err
+	}
+	if resolved.Having, err = mb.resolveSubqueries(ctx, snap, slct.Having); err != nil {
+		return nil, err
+	}
+	resolved.Join = nil
+	for _, j := range slct.Join {
+		on, err := mb.resolveSubqueries(ctx, snap, j.On)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Join = append(resolved.Join, &JoinClause{Kind: j.Kind, Table: j.Table, As: j.As, On: on, Select: j.Select})
+	}
+	resolved.OrderBy = nil
+	for _, clause := range slct.OrderBy {
+		exp, err := mb.resolveSubqueries(ctx, snap, clause.Exp)
+		if err != nil {
+			return nil, err
+		}
+		resolved.OrderBy = append(resolved.OrderBy, &OrderByClause{Exp: exp, Desc: clause.Desc, Nulls: clause.Nulls})
+	}
+	return &resolved, nil
+}
+
+// subquery runs slct and returns its results as a table named as, for a
+// subquery in FROM.
+func (mb *MemoryBackend) subquery(ctx context.Context, snap *txSnapshot, slct *SelectStatement, as *Token) (*table, error) {
+	results, err := mb.query(ctx, snap, slct)
+	if err != nil {
+		return nil, err
+	}
+	return resultsTable(results, as.Value), nil
+}
+
+// resultsTable holds the rows of results in a table called name.
+func resultsTable(results *Results, name string) *table {
+	t := &table{name: name, primaryKey: -1}
+	for _, col := range results.Columns {
+		t.columns = append(t.columns, col.Name)
+		t.columnTypes = append(t.columnTypes, col.Type)
+	}
+	for _, result := range results.Rows {
+		row := make([]MemoryCell, len(result))
+		for i, cell := range result {
+			row[i] = cell.(MemoryCell)
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t
+}
+
+// cellExpression is an expression that evaluates to cell with type ct. A
+// literal is cast when it would otherwise read back as another type, as
+// NULL and bigints that fit in an int do.
+func cellExpression(cell MemoryCell, ct ColumnType) *Expression {
+	literal := &Token{}
+	readsAs := ct
+	switch {
+	case cell.IsNull():
+		literal.Kind, literal.Value = KeywordKind, string(NullKeyword)
+		readsAs = NullType
+	case isInteger(ct):
+		literal.Kind, literal.Value = NumericKind, strconv.FormatInt(cell.AsInt(), 10)
+		_, readsAs, _ = numericLiteral(literal.Value)
+	case ct == FloatType:
+		literal.Kind, literal.Value = NumericKind, floatLiteral(cell.AsFloat())
+	case ct == BoolType:
+		literal.Kind, literal.Value = BoolKind, strconv.FormatBool(cell.AsBool())
+	case isTemporal(ct):
+		literal.Kind, literal.Value = StringKind, cellText(cell, ct)
+		readsAs = TextType
+	case ct == ByteaType:
+		literal.Kind, literal.Value = BytesKind, cell.AsText()
+	default:
+		literal.Kind, literal.Value = StringKind, cell.AsText()
+		readsAs = TextType
+	}
+
+	exp := &Expression{Kind: LiteralKind, Literal: literal}
+	if readsAs == ct || ct == NullType {
+		return exp
+	}
+	return &Expression{
+		Cast: &CastExpression{
+			Operand: exp,
+			Type:    &Token{Kind: KeywordKind, Value: string(columnTypeKeyword(ct))},
+		},
+		Kind: CastKind,
+	}
+}
+
+// evaluateAggregate implements count, sum, avg, min and max, skipping
+// NULLs. sum adds up numbers in their own type and avg is always a float.
+// sum and avg of no rows are 0, while min and max of no rows are NULL.
+func (t *table) evaluateAggregate(fn *FunctionExpression, rows [][]MemoryCell) (MemoryCell, ColumnType, error) {
+	name := fn.Name.Value
+	if !aggregateFunctions[name] {
+		return nil, 0, ErrFunctionNotFound
+	}
+
+	if fn.Asterisk {
+		if name != "count" {
+			return nil, 0, ErrInvalidArguments
+		}
+		return intCell(int32(len(rows))), IntType, nil
+	}
+	if len(fn.Args) != 1 {
+		return nil, 0, ErrInvalidArguments
+	}
+
+	if name == "min" || name == "max" {
+		return t.evaluateExtreme(fn, rows)
+	}
+
+	// Like evaluateExtreme, find the type of the argument from a row of
+	// NULLs so that it is known even without rows.
+	_, ct, err := t.evaluateExpression(make([]MemoryCell, len(t.columns)), fn.Args[0])
+	if err != nil {
+		return nil, 0, err
+	}
+	if name != "count" && !isNumeric(ct) {
+		return nil, 0, fmt.Errorf("%w: %s expects a number, got %s", ErrTypeMismatch, name, ct)
+	}
+
+	var sum int64
+	var fsum float64
+	count := 0
+	for _, row := range rows {
+		cell, _, err := t.evaluateExpression(row, fn.Args[0])
+		if err != nil {
+			return nil, 0, err
+		}
+		if cell.IsNull() {
+			continue
+		}
+		count++
+		switch {
+		case name == "count":
+		case ct == FloatType:
+			fsum += cell.AsFloat()
+		case name == "sum":
+			var ok bool
+			sum, ok, _ = integerArithmetic(PlusSymbol, sum, cell.AsInt())
+			if !ok {
+				return nil, 0, ErrIntegerOutOfRange
+			}
+		default:
+			fsum += float64(cell.AsInt())
+		}
+	}
+	if math.IsInf(fsum, 0) {
+		return nil, 0, ErrValueOutOfRange
+	}
+
+	switch {
+	case name == "count":
+		return intCell(int32(count)), IntType, nil
+	case name == "avg":
+		if count == 0 {
+			return nullCell, FloatType, nil
+		}
+		return floatCell(fsum / float64(count)), FloatType, nil
+	case count == 0:
+		// Like min and max, sum has no value without any to add up.
+		return nullCell, ct, nil
+	case ct == FloatType:
+		return floatCell(fsum), FloatType, nil
+	}
+	cell, err := convertCell(bigIntCell(sum), BigIntType, ct)
+	if err != nil {
+		return nil, 0, err
+	}
+	return cell, ct, nil
+}
+
+// evaluateExtreme implements min and max, which work on any type. The
+// argument is evaluated against a row of NULLs to find its type when there
+// are no rows.
+func (t *table) evaluateExtreme(fn *FunctionExpression, rows [][]MemoryCell) (MemoryCell, ColumnType, error) {
+	if len(rows) == 0 {
+		_, ct, err := t.evaluateExpression(make([]MemoryCell, len(t.columns)), fn.Args[0])
+		return nullCell, ct, err
+	}
+
+	c := t.collationOf(fn.Args[0])
+	extreme, et := nullCell, NullType
+	for _, row := range rows {
+		cell, ct, err := t.evaluateExpression(row, fn.Args[0])
+		if err != nil {
+			return nil, 0, err
+		}
+		if et == NullType {
+			et = ct
+		}
+		if cell.IsNull() {
+			continue
+		}
+		cmp := compareCells(c.cellKey(cell), c.cellKey(extreme), et)
+		if extreme.IsNull() || (fn.Name.Value == "min" && cmp < 0) || (fn.Name.Value == "max" && cmp > 0) {
+			extreme = cell
+		}
+	}
+	return extreme, et, nil
+}
+
+// projection describes the result columns of items, with * standing for
+// every column of t. The type of an expression does not depend on the
+// values it reads, so evaluating it against a row of NULLs finds it.
+func (t *table) projection(items []*SelectItem) ([]ResultColumn, error) {
+	nulls := make([]MemoryCell, len(t.columns))
+	var columns []ResultColumn
+	for _, item := range items {
+		if item.Asterisk {
+			for i, name := range t.columns {
+				columns = append(columns, ResultColumn{
+					Type: t.columnTypes[i],
+					Name: name,
+				})
+			}
+			continue
+		}
+
+		_, ct, err := t.evaluateExpression(nulls, item.Exp)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, ResultColumn{
+			Type: ct,
+			Name: itemName(item),
+		})
+	}
+
+	return columns, nil
+}
+
+// outputs returns items with each asterisk expanded into references to
+// the columns of t, which are the columns of the result ORDER BY may
+// refer to.
+func (t *table) outputs(items []*SelectItem) []*SelectItem {
+	var outputs []*SelectItem
+	for _, item := range items {
+		if !item.Asterisk {
+			outputs = append(outputs, item)
+			continue
+		}
+		for i, name := range t.columns {
+			ref := &ColumnReference{
+				Table:  &Token{Value: t.columnTable(i), Kind: IdentifierKind},
+				Column: &Token{Value: name, Kind: IdentifierKind},
+			}
+			outputs = append(outputs, &SelectItem{Exp: &Expression{Kind: ColumnReferenceKind, Column: ref}})
+		}
+	}
+	return outputs
+}
+
+// returning projects the items of a RETURNING clause onto the rows a
+// statement wrote to t, or returns nil when there are no items.
+func (t *table) returning(items []*SelectItem, rows [][]MemoryCell) (*Results, error) {
+	if items == nil {
+		return nil, nil
+	}
+	columns, err := t.projection(items)
+	if err != nil {
+		return nil, err
+	}
+	results := &Results{Columns: columns}
+	for _, row := range rows {
+		result, err := t.project(items, row)
+		if err != nil {
+			return nil, err
+		}
+		results.Rows = append(results.Rows, result)
+	}
+	return results, nil
+}
+
+// project computes the values of items for row.
+func (t *table) project(items []*SelectItem, row []MemoryCell) ([]Cell, error) {
+	var result []Cell
+	for _, item := range items {
+		if item.Asterisk {
+			for _, cell := range row {
+				result = append(result, cell)
+			}
+			continue
+		}
+
+		cell, _, err := t.evaluateExpression(row, item.Exp)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, cell)
+	}
+	return result, nil
+}
+
+// itemName names the result column of a select item: its alias, else the
+// column or function it reads, else ?column? as in PostgreSQL.
+func itemName(item *SelectItem) string {
+	if item.As != nil {
+		return item.As.Value
+	}
+	return resultName(item.Exp).Value
+}
+
+// joinTables combines left with right by a nested loop, keeping the pairs
+// of rows for which on holds, or every pair when on is nil. Outer joins
+// then add the unmatched rows of their outer side with NULLs for the other
+// side.
+func joinTables(ctx context.Context, left, right *table, kind JoinKind, on *Expression) (*table, error) {
+	joined := joinedTable(left, right)
+	leftNulls := make([]MemoryCell, len(left.columns))
+	rightNulls := make([]MemoryCell, len(right.columns))
+	rightMatched := make([]bool, len(right.rows))
+	pairs := 0
+	for _, l := range left.rows {
+		matched := false
+		for ri, r := range right.rows {
+			if err := canceled(ctx, pairs); err != nil {
+				return nil, err
+			}
+			pairs++
+			row := append(append([]MemoryCell{}, l...), r...)
+			if on != nil {
+				cell, ct, err := joined.evaluateExpression(row, on)
+				if err != nil {
+					return nil, err
+				}
+				if !compatible(ct, BoolType) {
+					return nil, ErrInvalidCondition
+				}
+				if !cell.AsBool() {
+					continue
+				}
+			}
+			joined.rows = append(joined.rows, row)
+			matched = true
+			rightMatched[ri] = true
+		}
+
+		if !matched && kind == LeftJoin {
+			joined.rows = append(joined.rows, append(append([]MemoryCell{}, l...), rightNulls...))
+		}
+	}
+
+	if kind == RightJoin {
+		for ri, r := range right.rows {
+			if !rightMatched[ri] {
+				joined.rows = append(joined.rows, append(append([]MemoryCell{}, leftNulls...), r...))
+			}
+		}
+	}
+
+	return joined, nil
+}
+
+// joinedTable returns a table without rows with the columns of left
+// followed by those of right.
+func joinedTable(left, right *table) *table {
+	joined := &table{
+		columns:     append(append([]string{}, left.columns...), right.columns...),
+		columnTypes: append(append([]ColumnType{}, left.columnTypes...), right.columnTypes...),
+		clock:       left.clock,
+	}
+	for i := range left.columns {
+		joined.columnTables = append(joined.columnTables, left.columnTable(i))
+		joined.collations = append(joined.collations, left.collation(i))
+	}
+	for i := range right.columns {
+		joined.columnTables = append(joined.columnTables, right.columnTable(i))
+		joined.collations = append(joined.collations, right.collation(i))
+	}
+	return joined
+}
+
+// query runs slct against the rows snap sees and reads its whole result.
+func (mb *MemoryBackend) query(ctx context.Context, snap *txSnapshot, slct *SelectStatement) (*Results, error) {
+	rows, err := mb.cursor(ctx, snap, slct)
+	if err != nil {
+		return nil, err
+	}
+	return rows.All()
+}
+
+// cursor runs slct against the rows snap sees. Grouping, window functions
+// and sorting need every row up front, but projecting the items,
+// DISTINCT, OFFSET and LIMIT happen a row at a time as the cursor is
+// read. Stored rows are never changed in place, so reading it needs no
+// locks.
+//
+// Under EXPLAIN ANALYZE, the cursor plans the whole of slct once its
+// subqueries are resolved, hands the plan back and measures the operators
+// above the ones planFrom builds as it runs them.
+func (mb *MemoryBackend) cursor(ctx context.Context, snap *txSnapshot, slct *SelectStatement) (*Rows, error) {
+	began := time.Now()
+	a, _ := ctx.Value(analyzeKey{}).(*analysis)
+	if a != nil {
+		// The subqueries are not part of the plan.
+		ctx = context.WithValue(ctx, analyzeKey{}, (*analysis)(nil))
+	}
+
+	slct = fixTime(ctx, &Statement{Kind: SelectKind, SelectStatement: slct}).SelectStatement
+	slct, err := mb.resolveSelectSubqueries(ctx, snap, slct)
+	if err != nil {
+		return nil, err
+	}
+	var plan *planNode
+	if a != nil {
+		if plan, err = a.plan(ctx, mb, snap, slct); err != nil {
+			return nil, err
+		}
+	}
+	stages, source := plan.stages()
+	if slct.Set != nil {
+		return mb.setCursor(ctx, snap, slct, stages, source)
+	}
+	if results, ok, err := mb.columnAggregate(ctx, snap, slct); ok {
+		if err != nil {
+			return nil, err
+		}
+		// The column store produces the result at once, without scanning
+		// the rows.
+		for _, stage := range stages {
+			stage.record(began, len(results.Rows))
+		}
+		return results.Cursor(), nil
+	}
+
+	// The planner picks how to read the tables, join them and apply WHERE.
+	if source == nil {
+		planning := time.Now()
+		source, err = mb.planFrom(snap, slct)
+		statementMetrics(ctx).planned(planning)
+		if err != nil {
+			return nil, err
+		}
+	}
+	t, err := source.run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows := t.rows
+	if slct.OrderBy, err = outputOrderBy(slct.OrderBy, t.outputs(slct.Item)); err != nil {
+		return nil, err
+	}
+
+	if isAggregate(slct) || hasWindow(slct) {
+		var results *Results
+		if isAggregate(slct) {
+			results, err = t.aggregate(ctx, slct, rows)
+		} else {
+			results, err = t.window(ctx, slct, rows)
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Grouping or the window functions also sort and project the rows.
+		for _, operator := range []string{"Aggregate", "WindowAgg", "Sort", "Projection"} {
+			stages[operator].record(began, len(results.Rows))
+		}
+		if slct.Distinct {
+			results.Rows = distinct(results.Rows, results.Columns)
+			stages["Unique"].record(began, len(results.Rows))
+		}
+		results.Rows, err = t.limit(results.Rows, slct)
+		if err != nil {
+			return nil, err
+		}
+		stages["Limit"].record(began, len(results.Rows))
+		return results.Cursor(), nil
+	}
+
+	if len(slct.OrderBy) > 0 {
+		rows, err = t.sort(ctx, rows, slct.OrderBy)
+		if err != nil {
+			return nil, err
+		}
+		stages["Sort"].record(began, len(rows))
+	}
+
+	columns, err := t.projection(slct.Item)
+	if err != nil {
+		return nil, err
+	}
+	offset, limit, err := t.bounds(slct)
+	if err != nil {
+		return nil, err
+	}
+
+	// Items that compile are projected a batch at a time. A batch that
+	// fails is projected again a row at a time, which returns the rows
+	// before the one that fails first as a row at a time would.
+	compiled := t.compileItems(slct.Item)
+	var batch [][]Cell
+	start, end := 0, 0
+
+	seen := map[string]bool{}
+	i, kept, skipped, produced := 0, 0, 0, 0
+	measured := false
+	next := func() ([]Cell, bool, error) {
+		for produced != limit && i < len(rows) {
+			if err := canceled(ctx, i); err != nil {
+				return nil, false, err
+			}
+			if compiled != nil && i >= end {
+				start, end = i, i+batchLength(slct.Distinct, offset-skipped, limit-produced)
+				if end > len(rows) {
+					end = len(rows)
+				}
+				var err error
+				if batch, err = t.projectBatch(slct.Item, compiled, rows[start:end]); err != nil {
+					batch = nil
+				}
+			}
+			var result []Cell
+			var err error
+			if batch != nil {
+				result = batch[i-start]
+			} else {
+				result, err = t.project(slct.Item, rows[i])
+			}
+			i++
+			if err != nil {
+				return nil, false, err
+			}
+			if slct.Distinct {
+				key := rowKey(result, columns)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			kept++
+			if skipped < offset {
+				skipped++
+				continue
+			}
+			produced++
+			return result, true, nil
+		}
+		if !measured {
+			measured = true
+			stages["Projection"].record(began, i)
+			stages["Unique"].record(began, kept)
+			stages["Limit"].record(began, produced)
+		}
+		return nil, false, nil
+	}
+
+	return &Rows{columns: columns, next: next}, nil
+}
+
+// limit applies OFFSET and then LIMIT to the finished result rows.
+func (t *table) limit(rows [][]Cell, slct *SelectStatement) ([][]Cell, error) {
+	offset, limit, err := t.bounds(slct)
+	if err != nil {
+		return nil, err
+	}
+	if offset > len(rows) {
+		offset = len(rows)
+	}
+	rows = rows[offset:]
+	if limit >= 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows, nil
+}
+
+// bounds evaluates OFFSET and LIMIT, giving a limit of -1 when there is
+// none. A NULL count is the same as leaving the clause out.
+func (t *table) bounds(slct *SelectStatement) (offset, limit int, err error) {
+	limit = -1
+	if slct.Offset != nil {
+		n, ok, err := t.evaluateCount(slct.Offset)
+		if err != nil {
+			return 0, 0, err
+		}
+		if ok {
+			offset = n
+		}
+	}
+
+	if slct.Limit != nil {
+		n, ok, err := t.evaluateCount(slct.Limit)
+		if err != nil {
+			return 0, 0, err
+		}
+		if ok {
+			limit = n
+		}
+	}
+
+	return offset, limit, nil
+}
+
+// evaluateCount evaluates a LIMIT or OFFSET count, which cannot refer to
+// any row. It returns false when the count is NULL.
+func (t *table) evaluateCount(exp *Expression) (int, bool, error) {
+	cell, ct, err := t.evaluateExpression(nil, exp)
+	if err != nil {
+		return 0, false, err
+	}
+	if cell.IsNull() {
+		return 0, false, nil
+	}
+	if !isInteger(ct) || cell.AsInt() < 0 {
+		return 0, false, ErrInvalidLimit
+	}
+	return int(cell.AsInt()), true, nil
+}
+
+// sort returns rows stably ordered by the ORDER BY keys.
+func (t *table) sort(ctx context.Context, rows [][]MemoryCell, orderBy []*OrderByClause) ([][]MemoryCell, error) {
+	order, err := sortOrder(ctx, len(rows), orderBy, func(i int, exp *Expression) (MemoryCell, ColumnType, error) {
+		return t.evaluateKey(rows[i], exp)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := make([][]MemoryCell, len(rows))
+	for i, o := range order {
+		sorted[i] = rows[o]
+	}
+	return sorted, nil
+}
+
+// sortOrder returns the positions of n rows stably ordered by the ORDER BY
+// keys, where evaluate computes a key for row i. Every row must produce the
+// same type for a given key, apart from NULLs.
+//
+// The keys may take up to the work_mem of the session. Past it, the rows
+// whose keys are held so far are sorted and their keys written out to a
+// temporary file as a run, and the runs are merged once every key has
+// been computed.
+func sortOrder(ctx context.Context, n int, orderBy []*OrderByClause, evaluate func(i int, exp *Expression) (MemoryCell, ColumnType, error)) ([]int, error) {
+	keys := make([][]MemoryCell, n)
+	s := sortKeys{orderBy: orderBy, types: make([]ColumnType, len(orderBy))}
+	var runs []*sortRun
+	defer func() {
+		for _, run := range runs {
+			run.close()
+		}
+	}()
+
+	budget, used, start := workMem(ctx), int64(0), 0
+	for i := range keys {
+		if err := canceled(ctx, i); err != nil {
+			return nil, err
+		}
+		for j, clause := range orderBy {
+			cell, ct, err := evaluate(i, clause.Exp)
+			if err != nil {
+				return nil, err
+			}
+			if i == 0 || s.types[j] == NullType {
+				s.types[j] = ct
+			} else if !compatible(s.types[j], ct) {
+				return nil, fmt.Errorf("%w: cannot order %s with %s", ErrTypeMismatch, s.types[j], ct)
+			}
+			keys[i] = append(keys[i], cell)
+		}
+
+		if used += rowBytes(keys[i]) + 8; used > budget {
+			run, err := s.spillRun(ctx, keys, start, i+1)
+			if err != nil {
+				return nil, err
+			}
+			runs = append(runs, run)
+			used, start = 0, i+1
+		}
+	}
+
+	if len(runs) > 0 {
+		if start < n {
+			run, err := s.spillRun(ctx, keys, start, n)
+			if err != nil {
+				return nil, err
+			}
+			runs = append(runs, run)
+		}
+		return s.mergeRuns(ctx, runs, n)
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	if err := s.sortPositions(ctx, order, keys); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// distinct drops every row that repeats an earlier one, keeping the first
+// of each. NULLs count as equal to each other here, unlike in comparisons.
+func distinct(rows [][]Cell, columns []ResultColumn) [][]Cell {
+	seen := map[string]bool{}
+	kept := rows[:0]
+	for _, row := range rows {
+		key := rowKey(row, columns)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, row)
+	}
+	return kept
+}
+
+// rowKey encodes a row so that two rows have the same key only if every cell
+// has the same type and contents. Each cell is prefixed with its type and
+// length so that cells cannot run into each other, and NULL is written
+// without a length so that it differs from empty text.
+func rowKey(row []Cell, columns []ResultColumn) string {
+	var b strings.Builder
+	for i, cell := range row {
+		mc := cell.(MemoryCell)
+		if mc.IsNull() {
+			fmt.Fprintf(&b, "%d:null:", columns[i].Type)
+			continue
+		}
+		fmt.Fprintf(&b, "%d:%d:", columns[i].Type, len(mc))
+		b.Write(mc)
+	}
+	return b.String()
+}
+
+// evaluateInExpression checks the left operand against each item in the
+// list. Every item must be comparable with the left operand.
+func (t *table) evaluateInExpression(row []MemoryCell, iexp *InExpression) (MemoryCell, ColumnType, error) {
+	left, lt, err := t.evaluateExpression(row, iexp.Left)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	found, sawNull := false, left.IsNull()
+	for _, item := range iexp.List {
+		cell, ct, err := t.evaluateExpression(row, item)
+		if err != nil {
+			return nil, 0, err
+		}
+		l, cell, common, err := unify(left, lt, cell, ct)
+		if err != nil {
+			return nil, 0, err
+		}
+		if c := t.comparisonCollation(common, iexp.Left, item); c != nil {
+			l, cell = c.cellKey(l), c.cellKey(cell)
+		}
+		if cell.IsNull() {
+			sawNull = true
+		} else if !left.IsNull() && bytes.Equal(l, cell) {
+			found = true
+		}
+	}
+
+	// x IN (...) is NULL rather than false when x is NULL or the list
+	// holds a NULL that might have matched.
+	if !found && sawNull {
+		return nullCell, BoolType, nil
+	}
+	return boolCell(found), BoolType, nil
+}
+
+// compareCells orders two cells of the same type, returning a negative
+// number, zero or a positive number like strings.Compare. NULL sorts after
+// every other value.
+func compareCells(a, b MemoryCell, ct ColumnType) int {
+	if a.IsNull() || b.IsNull() {
+		if a.IsNull() && b.IsNull() {
+			return 0
+		} else if a.IsNull() {
+			return 1
+		}
+		return -1
+	}
+
+	switch ct {
+	case IntType, BigIntType, DateType, TimestampType:
+		ai, bi := a.AsInt(), b.AsInt()
+		if ai < bi {
+			return -1
+		} else if ai > bi {
+			return 1
+		}
+		return 0
+	case FloatType:
+		af, bf := a.AsFloat(), b.AsFloat()
+		if af < bf {
+			return -1
+		} else if af > bf {
+			return 1
+		}
+		return 0
+	case BoolType:
+		if a.AsBool() == b.AsBool() {
+			return 0
+		} else if b.AsBool() {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a.AsText(), b.AsText())
+}
+
+// evaluateBetweenExpression checks low <= left <= high, inclusive on both
+// ends.
+func (t *table) evaluateBetweenExpression(row []MemoryCell, bexp *BetweenExpression) (MemoryCell, ColumnType, error) {
+	var cells []MemoryCell
+	var types []ColumnType
+	ct := NullType
+	for _, exp := range []*Expression{bexp.Left, bexp.Low, bexp.High} {
+		cell, et, err := t.evaluateExpression(row, exp)
+		if err != nil {
+			return nil, 0, err
+		}
+		common, ok := commonType(ct, et)
+		if !ok {
+			return nil, 0, fmt.Errorf("%w: cannot compare %s to %s", ErrTypeMismatch, ct, et)
+		}
+		ct = common
+		cells = append(cells, cell)
+		types = append(types, et)
+	}
+
+	for i, cell := range cells {
+		if cell.IsNull() {
+			return nullCell, BoolType, nil
+		}
+		converted, err := convertCell(cell, types[i], ct)
+		if err != nil {
+			return nil, 0, err
+		}
+		cells[i] = converted
+	}
+	if c := t.comparisonCollation(ct, bexp.Left, bexp.Low, bexp.High); c != nil {
+		for i, cell := range cells {
+			cells[i] = c.cellKey(cell)
+		}
+	}
+
+	inRange := compareCells(cells[1], cells[0], ct) <= 0 && compareCells(cells[0], cells[2], ct) <= 0
+	return boolCell(inRange), BoolType, nil
+}
+
+// likePattern is a compiled LIKE pattern. % matches any sequence of
+// characters, _ matches any single character and a backslash makes the
+// following character match literally.
+type likePattern []likeElement
+
+type likeElement struct {
+	wildcard rune // '%', '_' or 0 for a literal
+	literal  rune
+}
+
+func compileLike(pattern string) likePattern {
+	var lp likePattern
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '%':
+			// Consecutive %s match the same as one.
+			if len(lp) == 0 || lp[len(lp)-1].wildcard != '%' {
+				lp = append(lp, likeElement{wildcard: r})
+			}
+		case '_':
+			lp = append(lp, likeElement{wildcard: r})
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				r = runes[i]
+			}
+			lp = append(lp, likeElement{literal: r})
+		default:
+			lp = append(lp, likeElement{literal: r})
+		}
+	}
+	return lp
+}
+
+// match reports whether the pattern matches all of s. When the rest of s
+// does not match after a %, the % is made to swallow one more character
+// and matching resumes from there, so no state is kept beyond the last %.
+func (lp likePattern) match(s string) bool {
+	runes := []rune(s)
+	i, j := 0, 0
+	star, mark := -1, 0
+	for i < len(runes) {
+		switch {
+		case j < len(lp) && lp[j].wildcard == '%':
+			star, mark = j, i
+			j++
+		case j < len(lp) && (lp[j].wildcard == '_' || lp[j].wildcard == 0 && lp[j].literal == runes[i]):
+			i++
+			j++
+		case star >= 0:
+			mark++
+			i, j = mark, star+1
+		default:
+			return false
+		}
+	}
+	for j < len(lp) && lp[j].wildcard == '%' {
+		j++
+	}
+	return j == len(lp)
+}
+
+// update applies the SET assignments to every row matching the WHERE
+// clause, replacing each with a new version. Values are computed from the
+// row as it was before the update, and no row changes if any of them would
+// violate a constraint. The results are the RETURNING items for the new
+// rows, and nil when the statement has no RETURNING clause.
+func (mb *MemoryBackend) update(ctx context.Context, tx *transaction, updt *UpdateStatement) (int, *Results, error) {
+	updt = fixTime(ctx, &Statement{Kind: UpdateKind, UpdateStatement: updt}).UpdateStatement
+	t, ok := mb.tables[updt.Table.Value]
+	if !ok {
+		return 0, nil, ErrTableDoesNotExist
+	}
+
+	indexes := make([]int, len(updt.Set))
+	seen := map[int]bool{}
+	for i, assignment := range updt.Set {
+		index := t.columnIndex(assignment.Column.Value)
+		if index == -1 {
+			return 0, nil, ErrColumnDoesNotExist
+		}
+		if seen[index] {
+			return 0, nil, ErrDuplicateColumn
+		}
+		seen[index] = true
+		indexes[i] = index
+	}
+
+	matched, err := mb.matching(ctx, tx, t, updt.Where)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rows, err := mb.replace(ctx, tx, t, matched, func(old []MemoryCell) ([]MemoryCell, error) {
+		return t.applyAssignments(old, updt.Set, indexes)
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	results, err := t.returning(updt.Returning, rows)
+	if err != nil {
+		return 0, nil, err
+	}
+	return len(matched), results, nil
+}
+
+// applyAssignments returns a copy of old with the SET assignments applied
+// to the columns at indexes, computing the values from old.
+func (t *table) applyAssignments(old []MemoryCell, set []*Assignment, indexes []int) ([]MemoryCell, error) {
+	row := make([]MemoryCell, len(old))
+	copy(row, old)
+	for i, assignment := range set {
+		cell, ct, err := t.evaluateExpression(old, assignment.Value)
+		if err != nil {
+			return nil, err
+		}
+		if row[indexes[i]], err = t.assign(indexes[i], cell, ct); err != nil {
+			return nil, err
+		}
+	}
+	return row, nil
+}
+
+// replace replaces each of the versions matched with a new version that
+// change makes from its cells, and returns the new rows. No row changes if
+// any of them would violate a constraint.
+func (mb *MemoryBackend) replace(ctx context.Context, tx *transaction, t *table, matched []*rowVersion, change func([]MemoryCell) ([]MemoryCell, error)) ([][]MemoryCell, error) {
+	rows := make([][]MemoryCell, len(matched))
+	replaced := make(map[*rowVersion]bool, len(matched))
+	for i, v := range matched {
+		newRow, err := change(v.cells)
+		if err != nil {
+			return nil, err
+		}
+		if err := t.fireTriggers(BeforeTrigger, UpdateEvent, v.cells, newRow); err != nil {
+			return nil, err
+		}
+		for j, cell := range newRow {
+			if t.notNull[j] && cell.IsNull() {
+				return nil, ErrViolatesNotNull
+			}
+		}
+		if err := t.checkRow(ctx, newRow); err != nil {
+			return nil, err
+		}
+		rows[i] = newRow
+		replaced[v] = true
+	}
+
+	for i, unique := range t.unique {
+		if !unique {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, row := range rows {
+			if row[i].IsNull() {
+				continue
+			}
+			key := string(t.collation(i).cellKey(row[i]))
+			if seen[key] || mb.containsValue(tx, t, i, row[i], replaced) {
+				return nil, t.uniqueViolation(i)
+			}
+			seen[key] = true
+		}
+	}
+
+	for i, v := range matched {
+		t.deleteVersion(tx, v)
+		t.insertVersion(tx, rows[i])
+	}
+
+	// Foreign keys are checked once every row has changed, as rows of a
+	// table may reference each other.
+	if err := mb.releaseReferences(tx, t, matched, false); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if err := mb.checkReferences(tx, t, row); err != nil {
+			return nil, err
+		}
+	}
+	for i, v := range matched {
+		if err := mb.changed(tx, t, UpdateEvent, v.cells, rows[i]); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// delete removes every row matching the WHERE clause, or all rows when
+// there is none, by marking their versions deleted. The results are the
+// RETURNING items for the rows removed, and nil when the statement has no
+// RETURNING clause.
+func (mb *MemoryBackend) delete(ctx context.Context, tx *transaction, dlt *DeleteStatement) (int, *Results, error) {
+	dlt = fixTime(ctx, &Statement{Kind: DeleteKind, DeleteStatement: dlt}).DeleteStatement
+	t, ok := mb.tables[dlt.From.Value]
+	if !ok {
+		return 0, nil, ErrTableDoesNotExist
+	}
+
+	matched, err := mb.matching(ctx, tx, t, dlt.Where)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, v := range matched {
+		if err := t.fireTriggers(BeforeTrigger, DeleteEvent, v.cells, nil); err != nil {
+			return 0, nil, err
+		}
+		t.deleteVersion(tx, v)
+	}
+	if err := mb.releaseReferences(tx, t, matched, true); err != nil {
+		return 0, nil, err
+	}
+	deleted := make([][]MemoryCell, len(matched))
+	for i, v := range matched {
+		if err := mb.changed(tx, t, DeleteEvent, v.cells, nil); err != nil {
+			return 0, nil, err
+		}
+		deleted[i] = v.cells
+	}
+	results, err := t.returning(dlt.Returning, deleted)
+	if err != nil {
+		return 0, nil, err
+	}
+	return len(matched), results, nil
+}
+
+// matching returns the versions of t that tx sees and where holds for. A
+// version someone else has deleted or replaced since tx's snapshot cannot
+// be changed again, as the result would depend on which of the two went
+// first.
+func (mb *MemoryBackend) matching(ctx context.Context, tx *transaction, t *table, where *Expression) ([]*rowVersion, error) {
+	where, err := mb.resolveSubqueries(ctx, tx.snapshot, where)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := t.candidateSet(where)
+	var matched []*rowVersion
+	for i, v := range t.versions {
+		if err := canceled(ctx, i); err != nil {
+			return nil, err
+		}
+		if candidates != nil && !candidates[i] {
+			continue
+		}
+		if !tx.snapshot.visible(v) {
+			continue
+		}
+		statementMetrics(ctx).scanned(1)
+		if where != nil {
+			cell, ct, err := t.evaluateExpression(v.cells, where)
+			if err != nil {
+				return nil, err
+			}
+			if !compatible(ct, BoolType) {
+				return nil, ErrInvalidCondition
+			}
+			if !cell.AsBool() {
+				continue
+			}
+		}
+		if v.xmax != 0 {
+			return nil, ErrSerializationFailure
+		}
+		matched = append(matched, v)
+	}
+	return matched, nil
+}
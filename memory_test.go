@@ -0,0 +1,2118 @@
+package gosql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// execute runs every statement in source against mb and returns the
+// results of the last statement.
+func execute(t *testing.T, mb Backend, source string) (*Results, error) {
+	ast, err := Parse(source)
+	assert.Nil(t, err, source)
+
+	var results *Results
+	for _, stmt := range ast.Statements {
+		switch stmt.Kind {
+		case CreateTableKind:
+			err = mb.CreateTable(context.Background(), stmt.CreateTableStatement)
+		case DropTableKind:
+			err = mb.DropTable(context.Background(), stmt.DropTableStatement)
+		case AlterTableKind:
+			err = mb.AlterTable(context.Background(), stmt.AlterTableStatement)
+		case CreateIndexKind:
+			err = mb.CreateIndex(context.Background(), stmt.CreateIndexStatement)
+		case CreateViewKind:
+			err = mb.CreateView(context.Background(), stmt.CreateViewStatement)
+		case DropViewKind:
+			err = mb.DropView(context.Background(), stmt.DropViewStatement)
+		case InsertKind:
+			_, results, err = mb.Insert(context.Background(), stmt.InsertStatement)
+		case SelectKind:
+			results, err = mb.Select(context.Background(), stmt.SelectStatement)
+		case UpdateKind:
+			_, results, err = mb.Update(context.Background(), stmt.UpdateStatement)
+		case DeleteKind:
+			_, results, err = mb.Delete(context.Background(), stmt.DeleteStatement)
+		case BeginKind:
+			err = mb.Begin()
+		case CommitKind:
+			err = mb.Commit()
+		case RollbackKind:
+			err = mb.Rollback()
+		case SavepointKind:
+			err = mb.Savepoint(stmt.SavepointStatement.Name.Value)
+		case RollbackToSavepointKind:
+			err = mb.RollbackToSavepoint(stmt.SavepointStatement.Name.Value)
+		case ReleaseSavepointKind:
+			err = mb.ReleaseSavepoint(stmt.SavepointStatement.Name.Value)
+		case ExplainKind:
+			results, err = mb.Explain(context.Background(), stmt.ExplainStatement)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+func TestMemoryBackend_Select(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table users (id int, name text);"+
+		"insert into users values (1, 'alice');"+
+		"insert into users values (2, 'bob');")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select * from users")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Type: IntType, Name: "id"}, {Type: TextType, Name: "name"}}, results.Columns)
+	assert.Equal(t, 2, len(results.Rows))
+	assert.Equal(t, int64(1), results.Rows[0][0].AsInt())
+	assert.Equal(t, "alice", results.Rows[0][1].AsText())
+	assert.Equal(t, int64(2), results.Rows[1][0].AsInt())
+	assert.Equal(t, "bob", results.Rows[1][1].AsText())
+
+	results, err = execute(t, mb, "select name from users")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Type: TextType, Name: "name"}}, results.Columns)
+	assert.Equal(t, 2, len(results.Rows))
+	assert.Equal(t, "alice", results.Rows[0][0].AsText())
+	assert.Equal(t, "bob", results.Rows[1][0].AsText())
+}
+
+func TestMemoryBackend_errors(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table users (id int, name text)")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		source string
+		err    error
+	}{
+		{"create table users (id int)", ErrTableAlreadyExists},
+		{"select * from nope", ErrTableDoesNotExist},
+		{"select age from users", ErrColumnDoesNotExist},
+		{"select age + 1 from users", ErrColumnDoesNotExist},
+		{"insert into nope values (1)", ErrTableDoesNotExist},
+		{"insert into users values (1)", ErrMissingValues},
+		{"insert into users values ('1', 'alice')", ErrInvalidDatatype},
+		{"insert into users values (1.5, 'alice')", ErrInvalidDatatype},
+		{"insert into users values (1, 2)", ErrInvalidDatatype},
+	}
+
+	for _, test := range tests {
+		_, err := execute(t, mb, test.source)
+		assert.Equal(t, test.err, err, test.source)
+	}
+}
+
+func TestMemoryBackend_SelectWhere(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table users (id int, name text);"+
+		"insert into users values (1, 'alice');"+
+		"insert into users values (2, 'bob');"+
+		"insert into users values (3, 'carol');")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		source string
+		names  []string
+	}{
+		{"select name from users where id = 2", []string{"bob"}},
+		{"select name from users where id = 1 or id = 3", []string{"alice", "carol"}},
+		{"select name from users where id = 1 and name = 'alice'", []string{"alice"}},
+		{"select name from users where id = 1 and name = 'bob'", nil},
+		{"select name from users where (id = 1 or id = 2) and name = 'bob'", []string{"bob"}},
+		{"select name from users where id > 1", []string{"bob", "carol"}},
+		{"select name from users where id >= 2 and id < 3", []string{"bob"}},
+		{"select name from users where id <= 2 and name <> 'alice'", []string{"bob"}},
+		{"select name from users where id != 2", []string{"alice", "carol"}},
+		{"select name from users where name > 'b'", []string{"bob", "carol"}},
+		{"select name from users where not id = 2", []string{"alice", "carol"}},
+		{"select name from users where not (id = 1 or id = 2)", []string{"carol"}},
+	}
+
+	for _, test := range tests {
+		results, err := execute(t, mb, test.source)
+		assert.Nil(t, err, test.source)
+
+		var names []string
+		for _, row := range results.Rows {
+			names = append(names, row[0].AsText())
+		}
+		assert.Equal(t, test.names, names, test.source)
+	}
+
+	results, err := execute(t, mb, "select * from users where id = 2")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, int64(2), results.Rows[0][0].AsInt())
+	assert.Equal(t, "bob", results.Rows[0][1].AsText())
+
+	_, err = execute(t, mb, "select name from users where id = 'x'")
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+
+	_, err = execute(t, mb, "select name from users where id = 1 and name")
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+
+	_, err = execute(t, mb, "select name from users where id")
+	assert.Equal(t, ErrInvalidCondition, err)
+
+	_, err = execute(t, mb, "select name from users where not id")
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+
+	_, err = execute(t, mb, "select name from users where id < 'x'")
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+
+	_, err = execute(t, mb, "select name from users where age = 1")
+	assert.Equal(t, ErrColumnDoesNotExist, err)
+}
+
+func TestMemoryBackend_SelectExpressions(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table items (name text, price int, quantity int);"+
+		"insert into items values ('apple', 3, 10), ('pear', 5, NULL), ('plum', 7, 2)")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select name, price * quantity as total, -price, price % 4 = 1, 2 + 3 * 4 from items order by price")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{
+		{Type: TextType, Name: "name"},
+		{Type: IntType, Name: "total"},
+		{Type: IntType, Name: "?column?"},
+		{Type: BoolType, Name: "?column?"},
+		{Type: IntType, Name: "?column?"},
+	}, results.Columns)
+	assert.Equal(t, [][]Cell{
+		{MemoryCell("apple"), intCell(30), intCell(-3), boolCell(false), intCell(14)},
+		{MemoryCell("pear"), nullCell, intCell(-5), boolCell(true), intCell(14)},
+		{MemoryCell("plum"), intCell(14), intCell(-7), boolCell(false), intCell(14)},
+	}, results.Rows)
+
+	results, err = execute(t, mb, "select name from items where price * 2 > 9 order by price / 2 desc")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{MemoryCell("plum")}, {MemoryCell("pear")}}, results.Rows)
+
+	// Aggregates can be part of a larger expression.
+	results, err = execute(t, mb, "select sum(price) * 10 as tens, count(*) - 1, max(name) from items")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Type: IntType, Name: "tens"}, {Type: IntType, Name: "?column?"}, {Type: TextType, Name: "max"}}, results.Columns)
+	assert.Equal(t, [][]Cell{{intCell(150), intCell(2), MemoryCell("plum")}}, results.Rows)
+
+	results, err = execute(t, mb, "select price > 4, count(*) from items group by price")
+	assert.Nil(t, err)
+	assert.Equal(t, BoolType, results.Columns[0].Type)
+
+	results, err = execute(t, mb, "insert into items values ('fig', 2, 3) returning price * quantity as total, name")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Type: IntType, Name: "total"}, {Type: TextType, Name: "name"}}, results.Columns)
+	assert.Equal(t, [][]Cell{{intCell(6), MemoryCell("fig")}}, results.Rows)
+
+	tests := []struct {
+		source string
+		err    error
+	}{
+		{"select price / 0 from items", ErrDivisionByZero},
+		{"select price % (quantity - quantity) from items where quantity = 2", ErrDivisionByZero},
+		{"select 2147483647 + price from items", ErrIntegerOutOfRange},
+		{"select price * 1000000000 from items", ErrIntegerOutOfRange},
+		{"select name, count(*) + price from items group by name", ErrInvalidSelectItem},
+	}
+	for _, test := range tests {
+		_, err := execute(t, mb, test.source)
+		assert.Equal(t, test.err, err, test.source)
+	}
+
+	_, err = execute(t, mb, "select name + 1 from items")
+	assert.True(t, errors.Is(err, ErrTypeMismatch))
+}
+
+func TestMemoryBackend_NumericTypes(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table m (id integer, big bigint, price float, ratio real);"+
+		"create index m_big on m (big);"+
+		"insert into m values (1, 3000000000, 2.5, 1), (2, 5, 1e3, -0.25), (3, NULL, .5, NULL)")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select id, big, price, big * 2, id + price, id / 2, price / 4, ratio from m order by price")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{
+		{Type: IntType, Name: "id"},
+		{Type: BigIntType, Name: "big"},
+		{Type: FloatType, Name: "price"},
+		{Type: BigIntType, Name: "?column?"},
+		{Type: FloatType, Name: "?column?"},
+		{Type: IntType, Name: "?column?"},
+		{Type: FloatType, Name: "?column?"},
+		{Type: FloatType, Name: "ratio"},
+	}, results.Columns)
+	assert.Equal(t, [][]Cell{
+		{intCell(3), nullCell, floatCell(0.5), nullCell, floatCell(3.5), intCell(1), floatCell(0.125), nullCell},
+		{intCell(1), bigIntCell(3000000000), floatCell(2.5), bigIntCell(6000000000), floatCell(3.5), intCell(0), floatCell(0.625), floatCell(1)},
+		{intCell(2), bigIntCell(5), floatCell(1000), bigIntCell(10), floatCell(1002), intCell(1), floatCell(250), floatCell(-0.25)},
+	}, results.Rows)
+
+	tests := []struct {
+		source string
+		ids    []int64
+	}{
+		{"select id from m where big = 5", []int64{2}},
+		{"select id from m where big > 2147483647", []int64{1}},
+		{"select id from m where price = 1000", []int64{2}},
+		{"select id from m where ratio < 0", []int64{2}},
+		{"select id from m where id = 1.0", []int64{1}},
+		{"select id from m where price in (1, 2.5)", []int64{1}},
+		{"select id from m where big between 1 and 10.5", []int64{2}},
+	}
+	for _, test := range tests {
+		results, err := execute(t, mb, test.source)
+		assert.Nil(t, err, test.source)
+		var ids []int64
+		for _, row := range results.Rows {
+			ids = append(ids, row[0].AsInt())
+		}
+		assert.Equal(t, test.ids, ids, test.source)
+	}
+
+	results, err = execute(t, mb, "select sum(big), sum(price), avg(id), min(price), max(big) from m")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{
+		{Type: BigIntType, Name: "sum"},
+		{Type: FloatType, Name: "sum"},
+		{Type: FloatType, Name: "avg"},
+		{Type: FloatType, Name: "min"},
+		{Type: BigIntType, Name: "max"},
+	}, results.Columns)
+	assert.Equal(t, [][]Cell{{bigIntCell(3000000005), floatCell(1003), floatCell(2), floatCell(0.5), bigIntCell(3000000000)}}, results.Rows)
+
+	results, err = execute(t, mb, "select 'p' || price, concat(big, '/', ratio) from m where id = 2")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{MemoryCell("p1000"), MemoryCell("5/-0.25")}}, results.Rows)
+
+	// Integers are stored in narrower integer columns when they fit, and
+	// in float columns, but floats need a cast to become integers.
+	_, err = execute(t, mb, "insert into m values (4, 6, 7, 8); update m set id = big where id = 2; update m set big = 9.5 where id = 3")
+	assert.Equal(t, ErrInvalidDatatype, err)
+	results, err = execute(t, mb, "select id, price from m where big = 6 or big = 5 order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(4), floatCell(7)}, {intCell(5), floatCell(1000)}}, results.Rows)
+
+	errTests := []struct {
+		source string
+		err    error
+	}{
+		{"insert into m (id) values (1.5)", ErrInvalidDatatype},
+		{"insert into m (id) values (3000000000)", ErrIntegerOutOfRange},
+		{"update m set id = big", ErrIntegerOutOfRange},
+		{"select big * 4000000000 from m", ErrIntegerOutOfRange},
+		{"select 9223372036854775808 from m", ErrIntegerOutOfRange},
+		{"select price * 1e308 from m", ErrValueOutOfRange},
+		{"select price / 0 from m", ErrDivisionByZero},
+		{"select price % 2 from m", ErrTypeMismatch},
+		{"select abs(price > 1) from m", ErrTypeMismatch},
+	}
+	for _, test := range errTests {
+		_, err := execute(t, mb, test.source)
+		assert.True(t, errors.Is(err, test.err), test.source)
+	}
+}
+
+func TestMemoryBackend_Cast(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table t (id int, code text, score float, big bigint);"+
+		"insert into t values (1, ' 42 ', 2.5, 7), (2, 'yes', -3.5, NULL)")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select id::text, cast(score as int), score::bigint, id::bool, true::int, cast(null as float), '1e3'::float from t order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{
+		{Type: TextType, Name: "id"},
+		{Type: IntType, Name: "score"},
+		{Type: BigIntType, Name: "score"},
+		{Type: BoolType, Name: "id"},
+		{Type: IntType, Name: "int"},
+		{Type: FloatType, Name: "float"},
+		{Type: FloatType, Name: "float"},
+	}, results.Columns)
+	assert.Equal(t, [][]Cell{
+		{MemoryCell("1"), intCell(2), bigIntCell(2), boolCell(true), intCell(1), nullCell, floatCell(1000)},
+		{MemoryCell("2"), intCell(-4), bigIntCell(-4), boolCell(true), intCell(1), nullCell, floatCell(1000)},
+	}, results.Rows)
+
+	results, err = execute(t, mb, "select code::int + 1 from t where id = 1")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(43)}}, results.Rows)
+
+	results, err = execute(t, mb, "select code::bool, score::text || '!' from t where id = 2")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{boolCell(true), MemoryCell("-3.5!")}}, results.Rows)
+
+	// A cast makes a float storable in an int column.
+	_, err = execute(t, mb, "update t set id = cast(score * 2 as int)")
+	assert.Nil(t, err)
+	results, err = execute(t, mb, "select id from t order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(-7)}, {intCell(5)}}, results.Rows)
+
+	// Aggregates keep their type inside a larger expression even when the
+	// value would fit a narrower one.
+	results, err = execute(t, mb, "select sum(big) + 1 from t")
+	assert.Nil(t, err)
+	assert.Equal(t, BigIntType, results.Columns[0].Type)
+	assert.Equal(t, [][]Cell{{bigIntCell(8)}}, results.Rows)
+
+	tests := []struct {
+		source string
+		err    error
+	}{
+		{"select code::int from t", ErrInvalidTextRepresentation},
+		{"select 'maybe'::bool from t", ErrInvalidTextRepresentation},
+		{"select '0x10'::float from t", ErrInvalidTextRepresentation},
+		{"select '3000000000'::int from t", ErrIntegerOutOfRange},
+		{"select cast(1e10 as int) from t", ErrIntegerOutOfRange},
+		{"select score::bool from t", ErrInvalidCast},
+	}
+	for _, test := range tests {
+		_, err := execute(t, mb, test.source)
+		assert.True(t, errors.Is(err, test.err), test.source)
+	}
+
+	_, err = execute(t, mb, "select code::int from t")
+	assert.EqualError(t, err, `Invalid input syntax for type int: "yes"`)
+	_, err = execute(t, mb, "select true::float from t")
+	assert.EqualError(t, err, "Cannot cast boolean to float")
+}
+
+func TestMemoryBackend_DateTime(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table events (id int, day date, at timestamp);"+
+		"insert into events values (1, date '2024-01-31', timestamp '2024-01-31 09:30:00'),"+
+		"(2, '2024-02-29'::date, '2024-02-29T18:00:05.25Z'::timestamp),"+
+		"(3, NULL, NULL)")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select day, at from events where id = 2")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Type: DateType, Name: "day"}, {Type: TimestampType, Name: "at"}}, results.Columns)
+	assert.Equal(t, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), results.Rows[0][0].AsTime())
+	assert.Equal(t, time.Date(2024, 2, 29, 18, 0, 5, 250000000, time.UTC), results.Rows[0][1].AsTime())
+
+	results, err = execute(t, mb, "select id from events where at >= date '2024-02-01' or day < timestamp '2024-01-31 00:00:01' order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(1)}, {intCell(2)}}, results.Rows)
+
+	results, err = execute(t, mb, "select day + 1, day - 31, date '2024-03-01' - day, at::date, day::text || ' ' || at from events where id = 1")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{
+		{Type: DateType, Name: "?column?"},
+		{Type: DateType, Name: "?column?"},
+		{Type: IntType, Name: "?column?"},
+		{Type: DateType, Name: "at"},
+		{Type: TextType, Name: "?column?"},
+	}, results.Columns)
+	assert.Equal(t, [][]Cell{{
+		dateCell(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)),
+		dateCell(time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)),
+		intCell(30),
+		dateCell(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)),
+		MemoryCell("2024-01-31 2024-01-31 09:30:00"),
+	}}, results.Rows)
+
+	results, err = execute(t, mb, "select date_trunc('month', at), extract(hour from at), extract('dow' from day), date_part('second', at) from events order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{
+		{Type: TimestampType, Name: "date_trunc"},
+		{Type: FloatType, Name: "extract"},
+		{Type: FloatType, Name: "extract"},
+		{Type: FloatType, Name: "date_part"},
+	}, results.Columns)
+	assert.Equal(t, [][]Cell{
+		{timestampCell(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)), floatCell(9), floatCell(3), floatCell(0)},
+		{timestampCell(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)), floatCell(18), floatCell(4), floatCell(5.25)},
+		{nullCell, nullCell, nullCell, nullCell},
+	}, results.Rows)
+
+	results, err = execute(t, mb, "select min(day), max(at) from events")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{
+		dateCell(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)),
+		timestampCell(time.Date(2024, 2, 29, 18, 0, 5, 250000000, time.UTC)),
+	}}, results.Rows)
+
+	// A timestamp stored in a date column keeps only its day.
+	_, err = execute(t, mb, "update events set day = now() where id = 3; update events set at = day where id = 3")
+	assert.Nil(t, err)
+	results, err = execute(t, mb, "select day is not null, at = day, extract(hour from at) from events where id = 3")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{boolCell(true), boolCell(true), floatCell(0)}}, results.Rows)
+
+	tests := []struct {
+		source string
+		err    error
+	}{
+		{"select date '2024-13-01' from events", ErrInvalidTextRepresentation},
+		{"select date '9999-12-31' + 1 from events", ErrValueOutOfRange},
+		{"select day + at from events", ErrTypeMismatch},
+		{"select 1 - day from events", ErrTypeMismatch},
+		{"select day = 'x' from events", ErrTypeMismatch},
+		{"select date_trunc('fortnight', at) from events", ErrInvalidArguments},
+		{"insert into events (day) values (1)", ErrInvalidDatatype},
+	}
+	for _, test := range tests {
+		_, err := execute(t, mb, test.source)
+		assert.True(t, errors.Is(err, test.err), test.source)
+	}
+}
+
+func TestMemoryBackend_Varchar(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table users (id int, name varchar(5), note varchar default 'none')")
+	assert.Nil(t, err)
+
+	_, err = execute(t, mb, "insert into users (id, name) values (1, 'zoë'), (2, 'carol   '), (3, NULL)")
+	assert.Nil(t, err)
+	results, err := execute(t, mb, "select name, note from users order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Type: TextType, Name: "name"}, {Type: TextType, Name: "note"}}, results.Columns)
+	assert.Equal(t, [][]Cell{
+		{MemoryCell("zoë"), MemoryCell("none")},
+		{MemoryCell("carol"), MemoryCell("none")},
+		{nullCell, MemoryCell("none")},
+	}, results.Rows)
+
+	_, err = execute(t, mb, "insert into users (id, name) values (4, 'mallory')")
+	assert.Equal(t, ErrValueTooLong, errors.Unwrap(err))
+	assert.EqualError(t, err, "Value too long for type varchar(5)")
+
+	_, err = execute(t, mb, "update users set name = name || '!' where id = 1")
+	assert.Nil(t, err)
+	_, err = execute(t, mb, "update users set name = name || '!!' where id = 1")
+	assert.ErrorIs(t, err, ErrValueTooLong)
+	_, err = execute(t, mb, "insert into users (id, name) select id, note || note from users")
+	assert.ErrorIs(t, err, ErrValueTooLong)
+
+	_, err = execute(t, mb, "alter table users add column code varchar(2) default 'abc'")
+	assert.ErrorIs(t, err, ErrValueTooLong)
+	_, err = execute(t, mb, "alter table users add column code varchar(2) default 'ab'")
+	assert.Nil(t, err)
+	_, err = execute(t, mb, "alter table users drop column name")
+	assert.Nil(t, err)
+	_, err = execute(t, mb, "update users set code = 'abc'")
+	assert.ErrorIs(t, err, ErrValueTooLong)
+
+	code := mb.Schema()["users"].Cols[2]
+	assert.Equal(t, "varchar", code.Datatype.Value)
+	assert.Equal(t, "2", code.Length.Value)
+}
+
+func TestMemoryBackend_SelectLike(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table users (id int, name text);"+
+		"insert into users values (1, 'alice');"+
+		"insert into users values (2, 'alan');"+
+		"insert into users values (3, 'malice');"+
+		"insert into users values (4, '100%');"+
+		"insert into users values (5, '1000');")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		source string
+		names  []string
+	}{
+		{"select name from users where name like 'al%'", []string{"alice", "alan"}},
+		{"select name from users where name like '_lice'", []string{"alice"}},
+		{"select name from users where name like '%lice'", []string{"alice", "malice"}},
+		{"select name from users where name like 'a.*'", nil},
+		{`select name from users where name like '100\%'`, []string{"100%"}},
+		{"select name from users where name like '100%'", []string{"100%", "1000"}},
+		{"select name from users where name like '%%l%_e'", []string{"alice", "malice"}},
+		{"select name from users where name like 'a%n%'", []string{"alan"}},
+		{"select name from users where name not like '%l%'", []string{"100%", "1000"}},
+		{"select name from users where name || id like '%3'", []string{"malice"}},
+		{"select name from users where 'x' || name || 'x' = 'xalicex'", []string{"alice"}},
+	}
+
+	for _, test := range tests {
+		results, err := execute(t, mb, test.source)
+		assert.Nil(t, err, test.source)
+
+		var names []string
+		for _, row := range results.Rows {
+			names = append(names, row[0].AsText())
+		}
+		assert.Equal(t, test.names, names, test.source)
+	}
+
+	_, err = execute(t, mb, "select name from users where id like 'x'")
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+
+	results, err := execute(t, mb, "select name || '-' || id, name || NULL from users where id = 1")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Type: TextType, Name: "?column?"}, {Type: TextType, Name: "?column?"}}, results.Columns)
+	assert.Equal(t, [][]Cell{{MemoryCell("alice-1"), nullCell}}, results.Rows)
+}
+
+func TestMemoryBackend_SelectIn(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table t (id int, name text);"+
+		"insert into t values (1, 'a');"+
+		"insert into t values (2, 'b');"+
+		"insert into t values (3, 'c');")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select name from t where id in (1, 3)")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results.Rows))
+	assert.Equal(t, "a", results.Rows[0][0].AsText())
+	assert.Equal(t, "c", results.Rows[1][0].AsText())
+
+	results, err = execute(t, mb, "select name from t where name in ('b')")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, "b", results.Rows[0][0].AsText())
+
+	results, err = execute(t, mb, "select name from t where id not in (1, 3)")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, "b", results.Rows[0][0].AsText())
+
+	// A NULL in the list might have matched, so no row passes.
+	results, err = execute(t, mb, "select name from t where id not in (1, null)")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results.Rows))
+
+	_, err = execute(t, mb, "select name from t where id in (1, 'b')")
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+}
+
+func TestMemoryBackend_SelectBetween(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table people (name text, age int);"+
+		"insert into people values ('kid', 10);"+
+		"insert into people values ('adult', 18);"+
+		"insert into people values ('senior', 65);"+
+		"insert into people values ('elder', 80);")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select name from people where age between 18 and 65")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results.Rows))
+	assert.Equal(t, "adult", results.Rows[0][0].AsText())
+	assert.Equal(t, "senior", results.Rows[1][0].AsText())
+
+	results, err = execute(t, mb, "select age from people where name between 'b' and 'f' and age = 80")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, int64(80), results.Rows[0][0].AsInt())
+
+	results, err = execute(t, mb, "select name from people where age not between 18 and 65 and name <> 'kid'")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, "elder", results.Rows[0][0].AsText())
+
+	_, err = execute(t, mb, "select name from people where age between 'a' and 65")
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+}
+
+func TestMemoryBackend_SelectAlias(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table users (id int, name text);"+
+		"insert into users values (1, 'alice');")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select id as uid, name n from users")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Type: IntType, Name: "uid"}, {Type: TextType, Name: "n"}}, results.Columns)
+	assert.Equal(t, "alice", results.Rows[0][1].AsText())
+}
+
+func TestMemoryBackend_SelectAggregate(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table t (x int, name text);"+
+		"insert into t values (1, 'a');"+
+		"insert into t values (2, 'b');"+
+		"insert into t values (4, 'c');")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select count(*), sum(x), avg(x) as mean, count(name) from t")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{
+		{Type: IntType, Name: "count"},
+		{Type: IntType, Name: "sum"},
+		{Type: FloatType, Name: "mean"},
+		{Type: IntType, Name: "count"},
+	}, results.Columns)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, int64(3), results.Rows[0][0].AsInt())
+	assert.Equal(t, int64(7), results.Rows[0][1].AsInt())
+	assert.Equal(t, 7.0/3, results.Rows[0][2].AsFloat())
+	assert.Equal(t, int64(3), results.Rows[0][3].AsInt())
+
+	results, err = execute(t, mb, "select sum(x) from t where x = 1 or x = 4")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), results.Rows[0][0].AsInt())
+
+	// Only count has a value without rows, or with only NULLs.
+	for _, source := range []string{
+		"select count(x), sum(x), avg(x), min(x), max(x) from t where false",
+		"select count(x), sum(x), avg(x), min(x), max(x) from t where x is null",
+		"select count(null::float), sum(null::float), avg(null::float), min(null::float), max(null::float) from t",
+	} {
+		results, err = execute(t, mb, source)
+		assert.Nil(t, err, source)
+		assert.Equal(t, int64(0), results.Rows[0][0].AsInt(), source)
+		for _, cell := range results.Rows[0][1:] {
+			assert.True(t, cell.IsNull(), source)
+		}
+	}
+	results, err = execute(t, mb, "select sum(x), avg(x) from t where false")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Type: IntType, Name: "sum"}, {Type: FloatType, Name: "avg"}}, results.Columns)
+
+	tests := []struct {
+		source string
+		err    error
+	}{
+		{"select x, sum(x) from t", ErrInvalidSelectItem},
+		{"select median(x) from t", ErrFunctionNotFound},
+		{"select sum(*) from t", ErrInvalidArguments},
+		{"select sum(x, x) from t", ErrInvalidArguments},
+		{"select x from t where count(*) = 1", ErrAggregateNotAllowed},
+	}
+	for _, test := range tests {
+		_, err := execute(t, mb, test.source)
+		assert.Equal(t, test.err, err, test.source)
+	}
+
+	_, err = execute(t, mb, "select sum(name) from t")
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+}
+
+func TestMemoryBackend_SelectGroupBy(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table t (x int, name text, ok bool);"+
+		"insert into t values (1, 'a', true);"+
+		"insert into t values (2, 'b', false);"+
+		"insert into t values (4, 'a', false);"+
+		"insert into t values (8, 'c', true);"+
+		"insert into t values (null, 'b', null);")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select name, count(*), sum(x), min(x), max(x) as top from t group by name")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{
+		{Type: TextType, Name: "name"},
+		{Type: IntType, Name: "count"},
+		{Type: IntType, Name: "sum"},
+		{Type: IntType, Name: "min"},
+		{Type: IntType, Name: "top"},
+	}, results.Columns)
+	assert.Equal(t, 3, len(results.Rows))
+	assert.Equal(t, "a", results.Rows[0][0].AsText())
+	assert.Equal(t, int64(2), results.Rows[0][1].AsInt())
+	assert.Equal(t, int64(5), results.Rows[0][2].AsInt())
+	assert.Equal(t, int64(1), results.Rows[0][3].AsInt())
+	assert.Equal(t, int64(4), results.Rows[0][4].AsInt())
+	assert.Equal(t, "b", results.Rows[1][0].AsText())
+	assert.Equal(t, int64(2), results.Rows[1][1].AsInt())
+	assert.Equal(t, int64(2), results.Rows[1][3].AsInt())
+	assert.Equal(t, "c", results.Rows[2][0].AsText())
+
+	tests := []struct {
+		source string
+		names  []string
+	}{
+		{"select name from t group by name having count(*) > 1", []string{"a", "b"}},
+		{"select name from t group by name having sum(x) > 2 and name <> 'c'", []string{"a"}},
+		{"select name from t group by name order by sum(x) desc", []string{"c", "a", "b"}},
+		{"select name from t group by name order by name desc limit 2", []string{"c", "b"}},
+		{"select name from t where x > 1 group by name", []string{"b", "a", "c"}},
+		{"select min(name) from t", []string{"a"}},
+		{"select max(name) from t where x > 100", []string{""}},
+		{"select name from t group by name having min(x) is null", nil},
+	}
+	for _, test := range tests {
+		results, err := execute(t, mb, test.source)
+		assert.Nil(t, err, test.source)
+
+		var names []string
+		for _, row := range results.Rows {
+			names = append(names, row[0].AsText())
+		}
+		assert.Equal(t, test.names, names, test.source)
+	}
+
+	results, err = execute(t, mb, "select max(name), min(ok) from t where x > 100")
+	assert.Nil(t, err)
+	assert.Equal(t, TextType, results.Columns[0].Type)
+	assert.Equal(t, BoolType, results.Columns[1].Type)
+	assert.True(t, results.Rows[0][0].IsNull())
+
+	results, err = execute(t, mb, "select ok, count(x) from t group by ok")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results.Rows))
+	assert.True(t, results.Rows[2][0].IsNull())
+	assert.Equal(t, int64(0), results.Rows[2][1].AsInt())
+
+	results, err = execute(t, mb, "select name from t group by name having count(*) > 10")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results.Rows))
+
+	errTests := []struct {
+		source string
+		err    error
+	}{
+		{"select x, count(*) from t group by name", ErrInvalidSelectItem},
+		{"select name from t group by name having x > 1", ErrInvalidSelectItem},
+		{"select * from t group by name", ErrInvalidSelectItem},
+		{"select name from t group by name having count(*)", ErrInvalidCondition},
+		{"select name from t group by nope", ErrColumnDoesNotExist},
+	}
+	for _, test := range errTests {
+		_, err := execute(t, mb, test.source)
+		assert.Equal(t, test.err, err, test.source)
+	}
+}
+
+func TestMemoryBackend_SelectDistinct(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table users (id int, name text);"+
+		"insert into users values (1, 'alice');"+
+		"insert into users values (2, 'bob');"+
+		"insert into users values (3, 'alice');"+
+		"insert into users values (1, 'alice');")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select distinct name from users")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results.Rows))
+	assert.Equal(t, "alice", results.Rows[0][0].AsText())
+	assert.Equal(t, "bob", results.Rows[1][0].AsText())
+
+	results, err = execute(t, mb, "select distinct id, name from users")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results.Rows))
+
+	results, err = execute(t, mb, "select name from users")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(results.Rows))
+
+	results, err = execute(t, mb, "select distinct name from users order by name desc limit 1")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, "bob", results.Rows[0][0].AsText())
+
+	results, err = execute(t, mb, "select distinct count(*) from users group by id")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results.Rows))
+
+	_, err = execute(t, mb, "create table v (s text, b bool, n int);"+
+		"insert into v values ('', true, 0);"+
+		"insert into v values (null, true, 0);"+
+		"insert into v values ('', false, null);"+
+		"insert into v values (null, true, 0);"+
+		"insert into v values ('', null, null);"+
+		"insert into v values ('', false, null);")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		source string
+		rows   int
+	}{
+		{"select distinct s from v", 2},
+		{"select distinct b from v", 3},
+		{"select distinct n from v", 2},
+		{"select distinct s, b, n from v", 4},
+	}
+	for _, test := range tests {
+		results, err := execute(t, mb, test.source)
+		assert.Nil(t, err, test.source)
+		assert.Equal(t, test.rows, len(results.Rows), test.source)
+	}
+}
+
+func TestMemoryBackend_SelectOrderBy(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table t (id int, a text, b int);"+
+		"insert into t values (1, 'x', 1);"+
+		"insert into t values (2, 'y', 5);"+
+		"insert into t values (3, 'x', 3);"+
+		"insert into t values (4, 'y', 2);"+
+		"insert into t values (5, 'x', 3);")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		source string
+		ids    []int64
+	}{
+		{"select id from t order by id desc", []int64{5, 4, 3, 2, 1}},
+		{"select id from t order by a asc, b desc", []int64{3, 5, 1, 2, 4}},
+		{"select id from t order by b", []int64{1, 4, 3, 5, 2}},
+		{"select id from t where a = 'y' order by b", []int64{4, 2}},
+		// Keys may name the columns of the result by alias or position.
+		{"select id + 1 as x from t order by x desc", []int64{6, 5, 4, 3, 2}},
+		{"select id, b as a from t order by a, id", []int64{1, 4, 3, 5, 2}},
+		{"select id, b from t order by 2, 1", []int64{1, 4, 3, 5, 2}},
+		{"select * from t order by 3 desc, 1", []int64{2, 3, 5, 4, 1}},
+		{"select count(*) as n from t group by a order by n", []int64{2, 3}},
+		{"select id from t where a = 'x' union select b from t order by 1 desc", []int64{5, 3, 2, 1}},
+	}
+
+	for _, test := range tests {
+		results, err := execute(t, mb, test.source)
+		assert.Nil(t, err, test.source)
+
+		var ids []int64
+		for _, row := range results.Rows {
+			ids = append(ids, row[0].AsInt())
+		}
+		assert.Equal(t, test.ids, ids, test.source)
+	}
+
+	_, err = execute(t, mb, "select id from t order by nope")
+	assert.Equal(t, ErrColumnDoesNotExist, err)
+	_, err = execute(t, mb, "select id from t order by 2")
+	assert.ErrorIs(t, err, ErrOrderByPosition)
+	_, err = execute(t, mb, "select id as x, b as x from t order by x")
+	assert.ErrorIs(t, err, ErrAmbiguousColumn)
+
+	// Validation resolves the keys the same way.
+	results, err := ExecuteScript(mb, "select a as x, id from t order by x desc, 2", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), results[0].Results.Rows[0][1].AsInt())
+	_, err = ExecuteScript(mb, "select id from t order by 0", ScriptOptions{})
+	assert.Equal(t, "42P10", SQLState(err))
+
+	_, err = execute(t, mb, "create table u (id int, n int, s text, f bool);"+
+		"insert into u values (1, 10, '10', true);"+
+		"insert into u values (2, 9, '9', false);"+
+		"insert into u values (3, null, null, null);"+
+		"insert into u values (4, 100, '100', true)")
+	assert.Nil(t, err)
+
+	tests = []struct {
+		source string
+		ids    []int64
+	}{
+		{"select id from u order by n", []int64{2, 1, 4, 3}},
+		{"select id from u order by s", []int64{1, 4, 2, 3}},
+		{"select id from u order by n desc", []int64{4, 1, 2, 3}},
+		{"select id from u order by f, id desc", []int64{2, 4, 1, 3}},
+		{"select id from u order by n nulls first", []int64{3, 2, 1, 4}},
+		{"select id from u order by n desc nulls first", []int64{3, 4, 1, 2}},
+		{"select id from u order by n desc NULLS LAST", []int64{4, 1, 2, 3}},
+		{"select id, row_number() over (order by n nulls first) as r from u order by r", []int64{3, 2, 1, 4}},
+	}
+
+	for _, test := range tests {
+		results, err := execute(t, mb, test.source)
+		assert.Nil(t, err, test.source)
+
+		var ids []int64
+		for _, row := range results.Rows {
+			ids = append(ids, row[0].AsInt())
+		}
+		assert.Equal(t, test.ids, ids, test.source)
+	}
+}
+
+func TestMemoryBackend_SelectLimit(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table t (id int, a text);"+
+		"insert into t values (1, 'x');"+
+		"insert into t values (2, 'y');"+
+		"insert into t values (3, 'x');"+
+		"insert into t values (4, 'y');"+
+		"insert into t values (5, 'x');")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		source string
+		ids    []int64
+	}{
+		{"select id from t order by id limit 2", []int64{1, 2}},
+		{"select id from t order by id limit 2 offset 1", []int64{2, 3}},
+		{"select id from t order by id desc offset 3", []int64{2, 1}},
+		{"select id from t where a = 'x' order by id desc limit 2", []int64{5, 3}},
+		{"select id from t order by id limit 10 offset 4", []int64{5}},
+		{"select id from t limit 2 offset 10", nil},
+		{"select id from t limit 0", nil},
+		{"select id from t order by id limit null offset 3", []int64{4, 5}},
+		{"select count(*) from t limit 1", []int64{5}},
+		{"select count(*) from t offset 1", nil},
+	}
+
+	for _, test := range tests {
+		results, err := execute(t, mb, test.source)
+		assert.Nil(t, err, test.source)
+
+		var ids []int64
+		for _, row := range results.Rows {
+			ids = append(ids, row[0].AsInt())
+		}
+		assert.Equal(t, test.ids, ids, test.source)
+	}
+
+	results, err := execute(t, mb, "select distinct a from t order by a limit 1 offset 1")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, "y", results.Rows[0][0].AsText())
+
+	_, err = execute(t, mb, "select id from t limit 'a'")
+	assert.Equal(t, ErrInvalidLimit, err)
+
+	_, err = execute(t, mb, "select id from t offset id")
+	assert.Equal(t, ErrColumnDoesNotExist, err)
+}
+
+func TestMemoryBackend_SelectJoin(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table users (id int, name text);"+
+		"create table orders (id int, user_id int, total int);"+
+		"insert into users values (1, 'alice');"+
+		"insert into users values (2, 'bob');"+
+		"insert into orders values (10, 1, 5);"+
+		"insert into orders values (11, 1, 7);"+
+		"insert into orders values (12, 3, 9);")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select users.id, name, orders.id, orders.total from users join orders on users.id = orders.user_id")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{
+		{Type: IntType, Name: "id"},
+		{Type: TextType, Name: "name"},
+		{Type: IntType, Name: "id"},
+		{Type: IntType, Name: "total"},
+	}, results.Columns)
+	assert.Equal(t, 2, len(results.Rows))
+	assert.Equal(t, int64(1), results.Rows[0][0].AsInt())
+	assert.Equal(t, int64(10), results.Rows[0][2].AsInt())
+	assert.Equal(t, int64(11), results.Rows[1][2].AsInt())
+
+	results, err = execute(t, mb, "select orders.total from users join orders on users.id = orders.user_id where orders.total = 7")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, int64(7), results.Rows[0][0].AsInt())
+
+	results, err = execute(t, mb, "select * from users join orders on users.id = orders.user_id")
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(results.Columns))
+
+	_, err = execute(t, mb, "select users.total from users join orders on users.id = orders.user_id")
+	assert.Equal(t, ErrColumnDoesNotExist, err)
+
+	_, err = execute(t, mb, "select users.id from users join nope on users.id = nope.id")
+	assert.Equal(t, ErrTableDoesNotExist, err)
+
+	results, err = execute(t, mb, "select u.name, o.id from users u join orders as o on u.id = o.user_id where o.total > 5")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, "alice", results.Rows[0][0].AsText())
+	assert.Equal(t, int64(11), results.Rows[0][1].AsInt())
+
+	// A table can be joined with itself under two aliases.
+	results, err = execute(t, mb, "select a.id, b.id from orders a join orders b on a.user_id = b.user_id where a.id < b.id")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, int64(10), results.Rows[0][0].AsInt())
+	assert.Equal(t, int64(11), results.Rows[0][1].AsInt())
+
+	results, err = execute(t, mb, "select u.id from users u where u.name = 'bob'")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+
+	_, err = execute(t, mb, "select users.id from users u")
+	assert.Equal(t, ErrColumnDoesNotExist, err)
+}
+
+func TestMemoryBackend_SelectOuterJoin(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table users (id int, name text);"+
+		"create table orders (id int, user_id int, total int);"+
+		"insert into users values (1, 'alice');"+
+		"insert into users values (2, 'bob');"+
+		"insert into orders values (10, 1, 5);"+
+		"insert into orders values (11, 1, 7);"+
+		"insert into orders values (12, 3, 9);")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select u.name, o.id from users u left join orders o on u.id = o.user_id")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results.Rows))
+	assert.Equal(t, "alice", results.Rows[0][0].AsText())
+	assert.Equal(t, int64(10), results.Rows[0][1].AsInt())
+	assert.Equal(t, int64(11), results.Rows[1][1].AsInt())
+	assert.Equal(t, "bob", results.Rows[2][0].AsText())
+	assert.True(t, results.Rows[2][1].IsNull())
+
+	results, err = execute(t, mb, "select u.name, o.id from users u right outer join orders o on u.id = o.user_id")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results.Rows))
+	assert.Equal(t, int64(10), results.Rows[0][1].AsInt())
+	assert.Equal(t, int64(11), results.Rows[1][1].AsInt())
+	assert.True(t, results.Rows[2][0].IsNull())
+	assert.Equal(t, int64(12), results.Rows[2][1].AsInt())
+
+	// Filtering on the padded side finds the unmatched rows.
+	results, err = execute(t, mb, "select u.name from users u left outer join orders o on u.id = o.user_id where o.id is null")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, "bob", results.Rows[0][0].AsText())
+
+	results, err = execute(t, mb, "select u.name, count(o.id) from users u left join orders o on u.id = o.user_id group by u.name")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results.Rows))
+	assert.Equal(t, int64(2), results.Rows[0][1].AsInt())
+	assert.Equal(t, int64(0), results.Rows[1][1].AsInt())
+
+	results, err = execute(t, mb, "select u.name from users u inner join orders o on u.id = o.user_id")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results.Rows))
+}
+
+func TestMemoryBackend_SelectSubquery(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table users (id int, name text);"+
+		"create table orders (id int, user_id int, total int);"+
+		"insert into users values (1, 'alice');"+
+		"insert into users values (2, 'bob');"+
+		"insert into users values (3, 'carol');"+
+		"insert into orders values (10, 1, 5);"+
+		"insert into orders values (11, 1, 7);"+
+		"insert into orders values (12, 3, 9);")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		source string
+		names  []string
+	}{
+		{"select name from users where id in (select user_id from orders)", []string{"alice", "carol"}},
+		{"select name from users where not id in (select user_id from orders)", []string{"bob"}},
+		{"select name from users where id in (select user_id from orders where total > 100)", nil},
+		{"select name from users where id in (select user_id from orders where id in (select id from orders where total = 9))", []string{"carol"}},
+		{"select b.name from (select id, name from users where id > 1) as b", []string{"bob", "carol"}},
+		{"select big.name from (select name from users where id <> 2) big where big.name like '%o%'", []string{"carol"}},
+		{"select s.name from (select name from users order by name desc limit 2) s order by s.name", []string{"bob", "carol"}},
+		{"select u.name from (select id, name from users) u join orders o on u.id = o.user_id where o.total > 6", []string{"alice", "carol"}},
+	}
+	for _, test := range tests {
+		results, err := execute(t, mb, test.source)
+		assert.Nil(t, err, test.source)
+
+		var names []string
+		for _, row := range results.Rows {
+			names = append(names, row[0].AsText())
+		}
+		assert.Equal(t, test.names, names, test.source)
+	}
+
+	results, err := execute(t, mb, "select s.user_id, s.count from (select user_id, count(*) from orders group by user_id) s where s.count > 1")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, int64(1), results.Rows[0][0].AsInt())
+
+	_, err = execute(t, mb, "delete from orders where user_id in (select id from users where name = 'alice')")
+	assert.Nil(t, err)
+	results, err = execute(t, mb, "select id from orders")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+
+	_, err = execute(t, mb, "update users set name = 'c' where id in (select user_id from orders)")
+	assert.Nil(t, err)
+	results, err = execute(t, mb, "select id from users where name = 'c'")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, int64(3), results.Rows[0][0].AsInt())
+
+	_, err = execute(t, mb, "select name from users where id in (select id, name from users)")
+	assert.Equal(t, ErrSubqueryColumns, err)
+
+	_, err = execute(t, mb, "select name from (select id from users) s")
+	assert.Equal(t, ErrColumnDoesNotExist, err)
+}
+
+func TestMemoryBackend_InsertColumns(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table t (id int, name text, age int);"+
+		"insert into t (name, id, age) values ('x', 1, 30);"+
+		"insert into t (id) values (2);"+
+		"insert into t (age, name) values (40, 'z');")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select id, name, age from t")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results.Rows))
+
+	assert.Equal(t, int64(1), results.Rows[0][0].AsInt())
+	assert.Equal(t, "x", results.Rows[0][1].AsText())
+	assert.Equal(t, int64(30), results.Rows[0][2].AsInt())
+
+	assert.Equal(t, int64(2), results.Rows[1][0].AsInt())
+	assert.True(t, results.Rows[1][1].IsNull())
+	assert.True(t, results.Rows[1][2].IsNull())
+
+	assert.True(t, results.Rows[2][0].IsNull())
+	assert.Equal(t, "z", results.Rows[2][1].AsText())
+	assert.Equal(t, int64(40), results.Rows[2][2].AsInt())
+
+	results, err = execute(t, mb, "select name from t where age = 40")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+
+	results, err = execute(t, mb, "select count(age), sum(age), count(*) from t")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), results.Rows[0][0].AsInt())
+	assert.Equal(t, int64(70), results.Rows[0][1].AsInt())
+	assert.Equal(t, int64(3), results.Rows[0][2].AsInt())
+
+	results, err = execute(t, mb, "select id from t order by age desc")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), results.Rows[2][0].AsInt())
+
+	tests := []struct {
+		source string
+		err    error
+	}{
+		{"insert into t (id, id) values (1, 2)", ErrDuplicateColumn},
+		{"insert into t (nope) values (1)", ErrColumnDoesNotExist},
+		{"insert into t (id, name) values (1)", ErrMissingValues},
+		{"insert into t (name) values (1)", ErrInvalidDatatype},
+	}
+	for _, test := range tests {
+		_, err := execute(t, mb, test.source)
+		assert.Equal(t, test.err, err, test.source)
+	}
+}
+
+func TestMemoryBackend_InsertDefaults(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table t (id int, created text default 'unknown', n int default 3, flag bool default 1 = 2, other bool default null);"+
+		"insert into t (id) values (1);"+
+		"insert into t (id, created, n) values (2, 'today', NULL);"+
+		"insert into t values (3, 'x', 4, true, NULL)")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select * from t order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{
+		{intCell(1), MemoryCell("unknown"), intCell(3), boolCell(false), nullCell},
+		{intCell(2), MemoryCell("today"), nullCell, boolCell(false), nullCell},
+		{intCell(3), MemoryCell("x"), intCell(4), boolCell(true), nullCell},
+	}, results.Rows)
+
+	// Adding a column with a default fills it in the rows already there,
+	// so it can be NOT NULL.
+	_, err = execute(t, mb, "alter table t add column score int not null default 10")
+	assert.Nil(t, err)
+	results, err = execute(t, mb, "select score from t where id = 3")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(10)}}, results.Rows)
+	_, err = execute(t, mb, "alter table t add column missing int not null default null")
+	assert.Equal(t, ErrViolatesNotNull, err)
+
+	_, err = execute(t, mb, "alter table t drop column created;"+
+		"insert into t (id) values (4)")
+	assert.Nil(t, err)
+	results, err = execute(t, mb, "select * from t where id = 4")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(4), intCell(3), boolCell(false), nullCell, intCell(10)}}, results.Rows)
+
+	tests := []string{
+		"create table u (id int default 'x')",
+		"create table u (id int default id)",
+		"alter table t add column bad bool default 1",
+	}
+	for _, source := range tests {
+		_, err := execute(t, mb, source)
+		assert.NotNil(t, err, source)
+	}
+	_, err = execute(t, mb, "select * from u")
+	assert.Equal(t, ErrTableDoesNotExist, err)
+	assert.Equal(t, 5, len(mb.tables["t"].columns))
+}
+
+func TestMemoryBackend_InsertRows(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table t (id int primary key, name text default 'none', age int);"+
+		"insert into t (id, name) values (1, 'x'), (2, 'y');"+
+		"insert into t (age, id) values (30, 3), (40, 4), (NULL, 5)")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select * from t order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{
+		{intCell(1), MemoryCell("x"), nullCell},
+		{intCell(2), MemoryCell("y"), nullCell},
+		{intCell(3), MemoryCell("none"), intCell(30)},
+		{intCell(4), MemoryCell("none"), intCell(40)},
+		{intCell(5), MemoryCell("none"), nullCell},
+	}, results.Rows)
+
+	results, err = execute(t, mb, "insert into t (id) values (6), (7) returning id, name")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(6), MemoryCell("none")}, {intCell(7), MemoryCell("none")}}, results.Rows)
+
+	// A row that fails leaves none of the others behind, even when it
+	// conflicts with an earlier row of the same statement.
+	tests := []struct {
+		source string
+		err    error
+	}{
+		{"insert into t (id) values (8), (8)", ErrViolatesPrimaryKey},
+		{"insert into t (id) values (8), (1)", ErrViolatesPrimaryKey},
+		{"insert into t (id, name) values (8, 'a'), (9)", ErrMissingValues},
+		{"insert into t values (8, 'a', 1), (9, 2, 2)", ErrInvalidDatatype},
+	}
+	for _, test := range tests {
+		_, err := execute(t, mb, test.source)
+		assert.Equal(t, test.err, err, test.source)
+	}
+
+	_, err = execute(t, mb, "begin; insert into t (id) values (8), (9), (1)")
+	assert.Equal(t, ErrViolatesPrimaryKey, err)
+	_, err = execute(t, mb, "commit")
+	assert.Nil(t, err)
+
+	results, err = execute(t, mb, "select count(*) from t")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), results.Rows[0][0].AsInt())
+}
+
+func TestMemoryBackend_InsertSelect(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table users (id int primary key, name text, active bool);"+
+		"create table archive (id int primary key, name text, note text default 'archived');"+
+		"insert into users values (1, 'alice', true), (2, 'bob', false), (3, 'carol', false)")
+	assert.Nil(t, err)
+
+	insert := func(source string) int {
+		ast, err := Parse(source)
+		assert.Nil(t, err, source)
+		n, _, err := mb.Insert(context.Background(), ast.Statements[0].InsertStatement)
+		assert.Nil(t, err, source)
+		return n
+	}
+
+	assert.Equal(t, 2, insert("insert into archive (name, id) select name, id from users where not active"))
+	results, err := execute(t, mb, "select * from archive order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{
+		{intCell(2), MemoryCell("bob"), MemoryCell("archived")},
+		{intCell(3), MemoryCell("carol"), MemoryCell("archived")},
+	}, results.Rows)
+
+	// Selecting nothing inserts nothing.
+	assert.Equal(t, 0, insert("insert into archive (id) select id from users where id > 10"))
+
+	// The SELECT does not see the rows the statement inserts.
+	_, err = execute(t, mb, "create table counts (n int);"+
+		"insert into counts values (1);"+
+		"insert into counts select count(*) from counts;"+
+		"insert into counts select n from counts")
+	assert.Nil(t, err)
+	results, err = execute(t, mb, "select n from counts order by n")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(1)}, {intCell(1)}, {intCell(1)}, {intCell(1)}}, results.Rows)
+
+	results, err = execute(t, mb, "insert into archive (id, name) select id, name from users where id = 1 returning id, note")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(1), MemoryCell("archived")}}, results.Rows)
+
+	tests := []struct {
+		source string
+		err    error
+	}{
+		{"insert into archive select id from users", ErrMissingValues},
+		{"insert into archive (id, name) select name, id from users", ErrInvalidDatatype},
+		{"insert into archive (id) select id from users", ErrViolatesPrimaryKey},
+		{"insert into archive (id) select id from nope", ErrTableDoesNotExist},
+	}
+	for _, test := range tests {
+		_, err := execute(t, mb, test.source)
+		assert.Equal(t, test.err, err, test.source)
+	}
+	results, err = execute(t, mb, "select count(*) from archive")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), results.Rows[0][0].AsInt())
+}
+
+func TestMemoryBackend_InsertConstraints(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table t (id int primary key, name text not null, age int);"+
+		"insert into t values (1, 'a', 10);"+
+		"insert into t (id, name) values (2, 'b');")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		source string
+		err    error
+	}{
+		{"insert into t values (1, 'c', 30)", ErrViolatesPrimaryKey},
+		{"insert into t (id, age) values (3, 30)", ErrViolatesNotNull},
+		{"insert into t (name, age) values ('d', 30)", ErrViolatesNotNull},
+	}
+	for _, test := range tests {
+		_, err := execute(t, mb, test.source)
+		assert.Equal(t, test.err, err, test.source)
+	}
+
+	results, err := execute(t, mb, "select count(*) from t")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), results.Rows[0][0].AsInt())
+}
+
+func TestMemoryBackend_InsertReturning(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table t (id int, name text)")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "insert into t values (1, 'x') returning *")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Type: IntType, Name: "id"}, {Type: TextType, Name: "name"}}, results.Columns)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, int64(1), results.Rows[0][0].AsInt())
+	assert.Equal(t, "x", results.Rows[0][1].AsText())
+
+	results, err = execute(t, mb, "insert into t (id) values (2) returning name, id as new_id")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Type: TextType, Name: "name"}, {Type: IntType, Name: "new_id"}}, results.Columns)
+	assert.True(t, results.Rows[0][0].IsNull())
+	assert.Equal(t, int64(2), results.Rows[0][1].AsInt())
+
+	results, err = execute(t, mb, "insert into t values (3, 'z')")
+	assert.Nil(t, err)
+	assert.Nil(t, results)
+
+	_, err = execute(t, mb, "insert into t values (4, 'w') returning nope")
+	assert.Equal(t, ErrColumnDoesNotExist, err)
+
+	results, err = execute(t, mb, "select count(*) from t")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), results.Rows[0][0].AsInt())
+}
+
+func TestMemoryBackend_UpdateDeleteReturning(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table t (id serial primary key, name text, hits int default 0);"+
+		"insert into t (name) values ('a'), ('b'), ('c')")
+	assert.Nil(t, err)
+
+	// UPDATE returns the rows as changed.
+	results, err := execute(t, mb, "update t set hits = hits + 1 where id >= 2 returning id, hits, upper(name) as name")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Type: IntType, Name: "id"}, {Type: IntType, Name: "hits"}, {Type: TextType, Name: "name"}}, results.Columns)
+	assert.Equal(t, [][]Cell{{intCell(2), intCell(1), MemoryCell("B")}, {intCell(3), intCell(1), MemoryCell("C")}}, results.Rows)
+
+	// DELETE returns the rows as they were.
+	results, err = execute(t, mb, "delete from t where hits = 1 returning *")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results.Columns))
+	assert.Equal(t, [][]Cell{{intCell(2), MemoryCell("b"), intCell(1)}, {intCell(3), MemoryCell("c"), intCell(1)}}, results.Rows)
+
+	results, err = execute(t, mb, "delete from t where id = 10 returning id")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Type: IntType, Name: "id"}}, results.Columns)
+	assert.Empty(t, results.Rows)
+
+	results, err = execute(t, mb, "update t set hits = 5")
+	assert.Nil(t, err)
+	assert.Nil(t, results)
+
+	// A RETURNING item that fails leaves the rows as they were.
+	_, err = execute(t, mb, "update t set hits = 6 returning nope")
+	assert.Equal(t, ErrColumnDoesNotExist, err)
+	results, err = execute(t, mb, "select hits from t")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(5)}}, results.Rows)
+}
+
+func TestMemoryBackend_InsertOnConflict(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table t (id int primary key, name text unique, hits int);"+
+		"insert into t values (1, 'a', 1), (2, 'b', 1)")
+	assert.Nil(t, err)
+
+	// DO NOTHING skips the rows that conflict on any unique column.
+	results, err := execute(t, mb, "insert into t values (1, 'x', 0), (3, 'b', 0), (4, 'd', 0) on conflict do nothing returning id")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(4)}}, results.Rows)
+
+	// DO UPDATE reads the row that was to be inserted as excluded, and
+	// skips the rows its WHERE does not hold for.
+	results, err = execute(t, mb, "insert into t values (1, 'a', 5), (2, 'b', 5), (5, 'e', 5) "+
+		"on conflict (id) do update set hits = t.hits + excluded.hits where name = 'a' returning id, hits")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(1), intCell(6)}, {intCell(5), intCell(5)}}, results.Rows)
+
+	// An update that breaks another unique column fails as UPDATE does.
+	_, err = execute(t, mb, "insert into t values (1, 'x', 0) on conflict (id) do update set name = 'b'")
+	assert.ErrorIs(t, err, ErrViolatesUnique)
+
+	// A statement may not update a row twice.
+	_, err = execute(t, mb, "insert into t values (6, 'f', 0), (6, 'f', 1) on conflict (id) do update set hits = excluded.hits")
+	assert.Equal(t, ErrConflictAffectedTwice, err)
+
+	results, err = execute(t, mb, "select id, name, hits from t")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{
+		{intCell(2), MemoryCell("b"), intCell(1)},
+		{intCell(4), MemoryCell("d"), intCell(0)},
+		{intCell(1), MemoryCell("a"), intCell(6)},
+		{intCell(5), MemoryCell("e"), intCell(5)},
+	}, results.Rows)
+}
+
+func TestMemoryBackend_Bool(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table t (id int, flag boolean, done bool)")
+	assert.Nil(t, err)
+
+	_, err = execute(t, mb, "insert into t values (1, true, FALSE); insert into t values (2, false, true)")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select id, flag from t where flag = true")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Type: IntType, Name: "id"}, {Type: BoolType, Name: "flag"}}, results.Columns)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, int64(1), results.Rows[0][0].AsInt())
+	assert.True(t, results.Rows[0][1].AsBool())
+
+	results, err = execute(t, mb, "select id from t where done")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+	assert.Equal(t, int64(2), results.Rows[0][0].AsInt())
+
+	_, err = execute(t, mb, "insert into t values (3, 1, true)")
+	assert.Equal(t, ErrInvalidDatatype, err)
+}
+
+func TestMemoryBackend_Update(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table users (id int primary key, name text not null, age int);"+
+		"insert into users values (1, 'alice', 30);"+
+		"insert into users values (2, 'bob', 25);"+
+		"insert into users values (3, 'carol', 40);")
+	assert.Nil(t, err)
+
+	update := func(source string) (int, error) {
+		ast, err := Parse(source)
+		assert.Nil(t, err, source)
+		n, _, err := mb.Update(context.Background(), ast.Statements[0].UpdateStatement)
+		return n, err
+	}
+
+	n, err := update("update users set name = 'robert' where id = 2")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	results, err := execute(t, mb, "select name from users where id = 2")
+	assert.Nil(t, err)
+	assert.Equal(t, "robert", results.Rows[0][0].AsText())
+
+	n, err = update("update users set age = 50, name = 'x' where age >= 30")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	n, err = update("update users set age = 1 where id = 9")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = update("update users set age = 0")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+
+	results, err = execute(t, mb, "select count(*) from users where age = 0")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), results.Rows[0][0].AsInt())
+
+	_, err = update("update users set id = 1 where id = 2")
+	assert.Equal(t, ErrViolatesPrimaryKey, err)
+
+	_, err = update("update users set age = 'old'")
+	assert.Equal(t, ErrInvalidDatatype, err)
+
+	_, err = update("update users set nope = 1")
+	assert.Equal(t, ErrColumnDoesNotExist, err)
+
+	_, err = update("update users set age = 1, age = 2")
+	assert.Equal(t, ErrDuplicateColumn, err)
+
+	_, err = update("update users set age = 1 where id")
+	assert.Equal(t, ErrInvalidCondition, err)
+
+	_, err = update("update nope set age = 1")
+	assert.Equal(t, ErrTableDoesNotExist, err)
+
+	results, err = execute(t, mb, "select id, name from users order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), results.Rows[1][0].AsInt())
+	assert.Equal(t, "robert", results.Rows[1][1].AsText())
+}
+
+func TestMemoryBackend_Delete(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table users (id int, name text);"+
+		"insert into users values (1, 'alice');"+
+		"insert into users values (2, 'bob');"+
+		"insert into users values (3, 'carol');")
+	assert.Nil(t, err)
+
+	remove := func(source string) (int, error) {
+		ast, err := Parse(source)
+		assert.Nil(t, err, source)
+		n, _, err := mb.Delete(context.Background(), ast.Statements[0].DeleteStatement)
+		return n, err
+	}
+
+	n, err := remove("delete from users where id = 2")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	results, err := execute(t, mb, "select name from users")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results.Rows))
+	assert.Equal(t, "alice", results.Rows[0][0].AsText())
+	assert.Equal(t, "carol", results.Rows[1][0].AsText())
+
+	n, err = remove("delete from users where id = 9")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	_, err = remove("delete from users where name")
+	assert.Equal(t, ErrInvalidCondition, err)
+
+	_, err = remove("delete from nope")
+	assert.Equal(t, ErrTableDoesNotExist, err)
+
+	n, err = remove("delete from users")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	results, err = execute(t, mb, "select count(*) from users")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), results.Rows[0][0].AsInt())
+
+	_, err = execute(t, mb, "insert into users values (4, 'dave')")
+	assert.Nil(t, err)
+}
+
+func TestMemoryBackend_DropTable(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table t (id int); insert into t values (1)")
+	assert.Nil(t, err)
+
+	_, err = execute(t, mb, "create table t (id int)")
+	assert.Equal(t, ErrTableAlreadyExists, err)
+
+	_, err = execute(t, mb, "create table if not exists t (name text)")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select id from t")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+
+	_, err = execute(t, mb, "drop table t")
+	assert.Nil(t, err)
+
+	_, err = execute(t, mb, "select id from t")
+	assert.Equal(t, ErrTableDoesNotExist, err)
+
+	_, err = execute(t, mb, "drop table t")
+	assert.Equal(t, ErrTableDoesNotExist, err)
+
+	_, err = execute(t, mb, "drop table if exists t")
+	assert.Nil(t, err)
+
+	_, err = execute(t, mb, "create table if not exists t (name text); insert into t values ('x')")
+	assert.Nil(t, err)
+}
+
+func TestMemoryBackend_AlterTable(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table users (id int primary key, name text, age int);"+
+		"create index users_age on users (age);"+
+		"insert into users values (1, 'alice', 30);"+
+		"insert into users values (2, 'bob', 40)")
+	assert.Nil(t, err)
+
+	_, err = execute(t, mb, "alter table users add column email text unique")
+	assert.Nil(t, err)
+	results, err := execute(t, mb, "select * from users order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{IntType, "id"}, {TextType, "name"}, {IntType, "age"}, {TextType, "email"}}, results.Columns)
+	assert.Equal(t, [][]Cell{
+		{intCell(1), MemoryCell("alice"), intCell(30), nullCell},
+		{intCell(2), MemoryCell("bob"), intCell(40), nullCell},
+	}, results.Rows)
+
+	_, err = execute(t, mb, "insert into users values (3, 'carol', 50, 'c@x')")
+	assert.Nil(t, err)
+	_, err = execute(t, mb, "insert into users values (4, 'dave', 60, 'c@x')")
+	assert.Equal(t, ErrViolatesUnique, err)
+
+	// The rows already there would have no value.
+	_, err = execute(t, mb, "alter table users add score int not null")
+	assert.Equal(t, ErrViolatesNotNull, err)
+	_, err = execute(t, mb, "alter table users add code int primary key")
+	assert.Equal(t, ErrMultiplePrimaryKeys, err)
+	_, err = execute(t, mb, "alter table users add name text")
+	assert.Equal(t, ErrDuplicateColumn, err)
+
+	// Dropping a column drops its index and renumbers the columns after it.
+	_, err = execute(t, mb, "alter table users drop column age")
+	assert.Nil(t, err)
+	var indexes []string
+	for _, idx := range mb.tables["users"].indexes {
+		indexes = append(indexes, fmt.Sprintf("%s on %d", idx.name, idx.column))
+	}
+	assert.Equal(t, []string{"users_pkey on 0", "users_email_key on 2"}, indexes)
+	results, err = execute(t, mb, "select name, email from users where email = 'c@x'")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{MemoryCell("carol"), MemoryCell("c@x")}}, results.Rows)
+	_, err = execute(t, mb, "select age from users")
+	assert.Equal(t, ErrColumnDoesNotExist, err)
+
+	_, err = execute(t, mb, "alter table users rename column name to login")
+	assert.Nil(t, err)
+	results, err = execute(t, mb, "select login from users where id = 2")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{MemoryCell("bob")}}, results.Rows)
+	_, err = execute(t, mb, "alter table users rename login to email")
+	assert.Equal(t, ErrDuplicateColumn, err)
+	_, err = execute(t, mb, "alter table users drop nope")
+	assert.Equal(t, ErrColumnDoesNotExist, err)
+	_, err = execute(t, mb, "alter table nope drop id")
+	assert.Equal(t, ErrTableDoesNotExist, err)
+
+	// Rolling back restores the columns and their values.
+	_, err = execute(t, mb, "begin;"+
+		"alter table users drop column email;"+
+		"insert into users values (4, 'dave');"+
+		"alter table users add column age int;"+
+		"alter table users rename login to name;"+
+		"rollback")
+	assert.Nil(t, err)
+	results, err = execute(t, mb, "select * from users order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{IntType, "id"}, {TextType, "login"}, {TextType, "email"}}, results.Columns)
+	assert.Equal(t, [][]Cell{
+		{intCell(1), MemoryCell("alice"), nullCell},
+		{intCell(2), MemoryCell("bob"), nullCell},
+		{intCell(3), MemoryCell("carol"), MemoryCell("c@x")},
+	}, results.Rows)
+	_, err = execute(t, mb, "insert into users values (4, 'dave', 'c@x')")
+	assert.Equal(t, ErrViolatesUnique, err)
+}
+
+func TestMemoryBackend_UniqueConstraints(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table t (id int primary key, email text unique);"+
+		"insert into t values (1, 'a@x');"+
+		"insert into t values (2, 'b@x');"+
+		"insert into t (id) values (3);"+
+		"insert into t (id) values (4)")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		source string
+		err    error
+	}{
+		{"insert into t values (5, 'a@x')", ErrViolatesUnique},
+		{"insert into t values (2, 'c@x')", ErrViolatesPrimaryKey},
+		{"update t set email = 'b@x' where id = 1", ErrViolatesUnique},
+		{"update t set email = 'dup'", ErrViolatesUnique},
+		{"update t set id = 1 where id = 4", ErrViolatesPrimaryKey},
+	}
+	for _, test := range tests {
+		_, err := execute(t, mb, test.source)
+		assert.Equal(t, test.err, err, test.source)
+	}
+
+	_, err = execute(t, mb, "update t set email = 'c@x' where id = 1; insert into t values (5, 'a@x')")
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"t_pkey", "t_email_key"}, []string{mb.tables["t"].indexes[0].name, mb.tables["t"].indexes[1].name})
+
+	results, err := execute(t, mb, "select count(*) from t")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), results.Rows[0][0].AsInt())
+}
+
+func TestMemoryBackend_Null(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table users (id int, name text, active bool);"+
+		"insert into users values (1, 'alice', true);"+
+		"insert into users values (2, null, false);"+
+		"insert into users values (3, 'carol', null);"+
+		"insert into users values (null, null, null)")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		where string
+		ids   []int64
+	}{
+		{"name is null", []int64{2, 0}},
+		{"name is not null", []int64{1, 3}},
+		{"name = null", nil},
+		{"null = null", nil},
+		{"not (name = 'alice')", []int64{3}},
+		{"active or id = 3", []int64{1, 3}},
+		{"not active", []int64{2}},
+		{"active is null and id is not null", []int64{3}},
+		{"id in (1, null)", []int64{1}},
+		{"not (id in (1, null))", nil},
+		{"not (id in (1, 2))", []int64{3}},
+		{"id between 2 and null", nil},
+		{"name like null", nil},
+		{"null", nil},
+	}
+
+	for _, test := range tests {
+		results, err := execute(t, mb, "select id from users where "+test.where)
+		assert.Nil(t, err, test.where)
+
+		var ids []int64
+		for _, row := range results.Rows {
+			ids = append(ids, row[0].AsInt())
+		}
+		assert.Equal(t, test.ids, ids, test.where)
+	}
+
+	_, err = execute(t, mb, "update users set name = null where id = 1")
+	assert.Nil(t, err)
+	results, err := execute(t, mb, "select count(*) from users where name is null")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), results.Rows[0][0].AsInt())
+
+	_, err = execute(t, mb, "select id from users where name = 1")
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+}
+
+func TestMemoryBackend_Transaction(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table t (id int primary key, name text);"+
+		"insert into t values (1, 'a');"+
+		"insert into t values (2, 'b');")
+	assert.Nil(t, err)
+
+	_, err = execute(t, mb, "begin;"+
+		"insert into t values (3, 'c');"+
+		"update t set name = 'z' where id = 1;"+
+		"delete from t where id = 2;"+
+		"create table u (x int);"+
+		"create index t_name on t (name);"+
+		"rollback")
+	assert.Nil(t, err)
+
+	results, err := execute(t, mb, "select id, name from t order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results.Rows))
+	assert.Equal(t, "a", results.Rows[0][1].AsText())
+	assert.Equal(t, "b", results.Rows[1][1].AsText())
+	assert.Equal(t, 1, len(mb.tables["t"].indexes))
+
+	_, err = execute(t, mb, "select x from u")
+	assert.Equal(t, ErrTableDoesNotExist, err)
+
+	// The primary key index was restored along with the rows.
+	_, err = execute(t, mb, "insert into t values (2, 'dup')")
+	assert.Equal(t, ErrViolatesPrimaryKey, err)
+	results, err = execute(t, mb, "select name from t where id = 2")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results.Rows))
+
+	_, err = execute(t, mb, "begin transaction;"+
+		"drop table t;"+
+		"create table u (x int);"+
+		"insert into u values (1);"+
+		"rollback transaction")
+	assert.Nil(t, err)
+	_, err = execute(t, mb, "select id from t")
+	assert.Nil(t, err)
+	_, err = execute(t, mb, "select x from u")
+	assert.Equal(t, ErrTableDoesNotExist, err)
+
+	_, err = execute(t, mb, "begin;"+
+		"insert into t values (3, 'c');"+
+		"create table u (x int);"+
+		"commit")
+	assert.Nil(t, err)
+	results, err = execute(t, mb, "select id from t")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results.Rows))
+	_, err = execute(t, mb, "select x from u")
+	assert.Nil(t, err)
+
+	assert.Equal(t, ErrNoTransaction, mb.Commit())
+	assert.Equal(t, ErrNoTransaction, mb.Rollback())
+	assert.Nil(t, mb.Begin())
+	assert.Equal(t, ErrTransactionActive, mb.Begin())
+	assert.Nil(t, mb.Commit())
+}
+
+func TestMemoryBackend_Savepoint(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	_, err := execute(t, mb, "create table t (id int primary key);"+
+		"begin;"+
+		"insert into t values (1);"+
+		"savepoint a;"+
+		"insert into t values (2);"+
+		"create table u (x int);"+
+		"savepoint b;"+
+		"insert into t values (3);"+
+		"rollback to savepoint a")
+	assert.Nil(t, err)
+
+	// Rolling back to a forgets the savepoints after it but keeps a.
+	assert.Equal(t, ErrNoSavepoint, mb.ReleaseSavepoint("b"))
+	_, err = execute(t, mb, "select x from u")
+	assert.Equal(t, ErrTableDoesNotExist, err)
+	_, err = execute(t, mb, "insert into t values (4); rollback transaction to a; insert into t values (5); release savepoint a")
+	assert.Nil(t, err)
+	assert.Equal(t, ErrNoSavepoint, mb.RollbackToSavepoint("a"))
+
+	_, err = execute(t, mb, "commit")
+	assert.Nil(t, err)
+	results, err := execute(t, mb, "select id from t order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(1)}, {intCell(5)}}, results.Rows)
+
+	// A newer savepoint hides an older one of the same name, and a
+	// statement that fails leaves the savepoints as they were.
+	_, err = execute(t, mb, "begin; savepoint s; insert into t values (6); savepoint s; insert into t values (7)")
+	assert.Nil(t, err)
+	_, err = execute(t, mb, "insert into t values (7)")
+	assert.Equal(t, ErrViolatesPrimaryKey, err)
+	_, err = execute(t, mb, "rollback to s; release s; commit")
+	assert.Nil(t, err)
+	results, err = execute(t, mb, "select id from t order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(1)}, {intCell(5)}, {intCell(6)}}, results.Rows)
+
+	assert.Equal(t, ErrNoTransaction, mb.Savepoint("s"))
+	assert.Equal(t, ErrNoTransaction, mb.RollbackToSavepoint("s"))
+}
+
+func TestMemoryBackend_Session(t *testing.T) {
+	mb := NewMemoryBackend()
+	a := mb.NewSession()
+	b := mb.NewSession()
+
+	_, err := execute(t, a, "create table t (id int primary key, n int);"+
+		"insert into t values (1, 10);"+
+		"insert into t values (2, 20);")
+	assert.Nil(t, err)
+
+	// Uncommitted changes are only seen by their own session.
+	_, err = execute(t, a, "begin;"+
+		"insert into t values (3, 30);"+
+		"update t set n = 11 where id = 1;"+
+		"delete from t where id = 2")
+	assert.Nil(t, err)
+	results, err := execute(t, a, "select n from t order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results.Rows))
+	assert.Equal(t, int64(11), results.Rows[0][0].AsInt())
+	results, err = execute(t, b, "select n from t order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results.Rows))
+	assert.Equal(t, int64(10), results.Rows[0][0].AsInt())
+	assert.Equal(t, int64(20), results.Rows[1][0].AsInt())
+
+	// A key inserted by a transaction in progress is taken.
+	_, err = execute(t, b, "insert into t values (3, 0)")
+	assert.Equal(t, ErrViolatesPrimaryKey, err)
+
+	// A transaction keeps its snapshot while others commit.
+	_, err = execute(t, b, "begin")
+	assert.Nil(t, err)
+	_, err = execute(t, a, "commit")
+	assert.Nil(t, err)
+	results, err = execute(t, b, "select n from t where id = 1")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), results.Rows[0][0].AsInt())
+
+	// Changing a row someone else changed since fails, and leaves the
+	// rest of the transaction alone.
+	_, err = execute(t, b, "insert into t values (4, 40)")
+	assert.Nil(t, err)
+	_, err = execute(t, b, "update t set n = 12 where id = 1")
+	assert.Equal(t, ErrSerializationFailure, err)
+	_, err = execute(t, b, "commit")
+	assert.Nil(t, err)
+
+	results, err = execute(t, a, "select id, n from t order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results.Rows))
+	assert.Equal(t, int64(11), results.Rows[0][1].AsInt())
+	assert.Equal(t, int64(4), results.Rows[2][0].AsInt())
+
+	// Dead versions are dropped once no transaction needs them.
+	for i := 0; i < 10; i++ {
+		_, err = execute(t, a, "update t set n = 50")
+		assert.Nil(t, err)
+	}
+	assert.True(t, len(mb.tables["t"].versions) < 10)
+	results, err = execute(t, a, "select n from t where id = 4")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(50), results.Rows[0][0].AsInt())
+}
+
+func TestMemoryBackend_ConcurrentSessions(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table accounts (id int primary key, balance int);"+
+		"insert into accounts values (1, 50);"+
+		"insert into accounts values (2, 50);")
+	assert.Nil(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		writer := mb.NewSession()
+		for i := 1; i <= 50; i++ {
+			_, err := execute(t, writer, fmt.Sprintf("begin;"+
+				"update accounts set balance = %d where id = 1;"+
+				"update accounts set balance = %d where id = 2;"+
+				"commit", 50-i, 50+i))
+			assert.Nil(t, err)
+		}
+	}()
+
+	// Readers never see one half of a transfer without the other.
+	reader := mb.NewSession()
+	for i := 0; i < 100; i++ {
+		results, err := execute(t, reader, "select balance from accounts")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(100), results.Rows[0][0].AsInt()+results.Rows[1][0].AsInt())
+	}
+	<-done
+
+	results, err := execute(t, mb, "select balance from accounts order by id")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), results.Rows[0][0].AsInt())
+	assert.Equal(t, int64(100), results.Rows[1][0].AsInt())
+}
+
+func TestMemoryBackend_Explain(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table users (id int primary key, name text, team int);"+
+		"create table teams (id int primary key, name text);"+
+		"insert into teams values (1, 'red');"+
+		"insert into teams values (2, 'blue');")
+	assert.Nil(t, err)
+	for i := 1; i <= 9; i++ {
+		_, err = execute(t, mb, fmt.Sprintf("insert into users values (%d, 'user%d', %d)", i, i, i%2+1))
+		assert.Nil(t, err)
+	}
+
+	tests := []struct {
+		source string
+		plan   string
+	}{
+		{
+			source: "explain select * from users",
+			plan: `Projection  (cost=18.00 rows=9)
+  Output: *
+  ->  Seq Scan on users  (cost=9.00 rows=9)`,
+		},
+		{
+			source: "explain select name from users where id >= 7 and name <> 'x' order by name desc limit 2",
+			plan: `Limit  (cost=18.32 rows=2)
+  Count: 2
+  ->  Projection  (cost=18.32 rows=3)
+        Output: name
+        ->  Sort  (cost=15.32 rows=3)
+              Sort Key: name desc
+              ->  Filter  (cost=9.32 rows=3)
+                    Condition: ((id >= 7) and (name <> 'x'))
+                    ->  Index Scan using users_pkey on users  (cost=6.32 rows=3)
+                          Index Cond: (id >= 7)`,
+		},
+		{
+			source: "explain select distinct u.name from users u left join teams t on u.team = t.id where t.name = 'red'",
+			plan: `Unique  (cost=43.00 rows=5)
+  ->  Projection  (cost=38.00 rows=5)
+        Output: u.name
+        ->  Filter  (cost=33.00 rows=5)
+              Condition: (t.name = 'red')
+              ->  Hash Left Join  (cost=24.00 rows=9)
+                    Hash Cond: (u.team = t.id)
+                    ->  Seq Scan on users u  (cost=9.00 rows=9)
+                    ->  Seq Scan on teams t  (cost=2.00 rows=2)`,
+		},
+		{
+			source: "explain select team, count(*) from users group by team having count(*) > 1 order by team",
+			plan: `Sort  (cost=24.00 rows=3)
+  Sort Key: team
+  ->  Aggregate  (cost=18.00 rows=3)
+        Group Key: team
+        Filter: (count(*) > 1)
+        Output: team, count(*)
+        ->  Seq Scan on users  (cost=9.00 rows=9)`,
+		},
+		{
+			source: "explain select s.id from (select id from users where name = 'user1') s",
+			plan: `Projection  (cost=22.00 rows=2)
+  Output: s.id
+  ->  Subquery Scan on s  (cost=20.00 rows=2)
+        ->  Projection  (cost=20.00 rows=2)
+              Output: id
+              ->  Filter  (cost=18.00 rows=2)
+                    Condition: (name = 'user1')
+                    ->  Seq Scan on users  (cost=9.00 rows=9)`,
+		},
+	}
+
+	for _, test := range tests {
+		results, err := execute(t, mb, test.source)
+		assert.Nil(t, err, test.source)
+		assert.Equal(t, "QUERY PLAN", results.Columns[0].Name)
+
+		var lines []string
+		for _, row := range results.Rows {
+			lines = append(lines, row[0].AsText())
+		}
+		assert.Equal(t, test.plan, strings.Join(lines, "\n"), test.source)
+	}
+
+	_, err = execute(t, mb, "explain select * from nope")
+	assert.Equal(t, ErrTableDoesNotExist, err)
+}
@@ -0,0 +1,136 @@
+package gosql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatementMetrics measures how a statement ran.
+type StatementMetrics struct {
+	// ParseTime is how long parsing took. A script is parsed as a whole,
+	// so its first statement is given the time and the others none.
+	ParseTime time.Duration
+	// PlanTime is the part of ExecutionTime spent planning queries,
+	// including subqueries.
+	PlanTime time.Duration
+	// ExecutionTime is how long the statement took to validate and run.
+	ExecutionTime time.Duration
+	// RowsScanned counts the rows read from stored tables, and
+	// RowsReturned those of the result of a query or RETURNING clause.
+	RowsScanned  int64
+	RowsReturned int64
+}
+
+// add adds the measures of other to m.
+func (m *StatementMetrics) add(other StatementMetrics) {
+	m.ParseTime += other.ParseTime
+	m.PlanTime += other.PlanTime
+	m.ExecutionTime += other.ExecutionTime
+	m.RowsScanned += other.RowsScanned
+	m.RowsReturned += other.RowsReturned
+}
+
+// Metrics adds up the metrics of the statements run on a backend.
+type Metrics struct {
+	Statements uint64
+	// Failed counts the statements that returned an error, and Slow those
+	// that ran at least the log_min_duration_statement of their session.
+	Failed uint64
+	Slow   uint64
+	StatementMetrics
+}
+
+// metricsKey is the context key under which ExecuteContext passes the
+// metrics of the running statement to the executor.
+type metricsKey struct{}
+
+// statementMetrics returns the metrics of the statement ctx belongs to, or
+// nil when no one is measuring it.
+func statementMetrics(ctx context.Context) *StatementMetrics {
+	m, _ := ctx.Value(metricsKey{}).(*StatementMetrics)
+	return m
+}
+
+// scanned counts n rows read from a stored table.
+func (m *StatementMetrics) scanned(n int) {
+	if m != nil {
+		m.RowsScanned += int64(n)
+	}
+}
+
+// planned counts the time since start as spent planning.
+func (m *StatementMetrics) planned(start time.Time) {
+	if m != nil {
+		m.PlanTime += time.Since(start)
+	}
+}
+
+// metricsRecorder is an executor that keeps the metrics of the statements
+// run on it.
+type metricsRecorder interface {
+	recordStatement(stmt *Statement, m StatementMetrics, err error)
+}
+
+// statementLog keeps the metrics of a backend and writes its slow query
+// log.
+type statementLog struct {
+	mu      sync.Mutex
+	metrics Metrics
+	slow    io.Writer
+}
+
+// Metrics returns the metrics of every statement run on the backend so
+// far, by any session.
+func (mb *MemoryBackend) Metrics() Metrics {
+	mb.statements.mu.Lock()
+	defer mb.statements.mu.Unlock()
+	return mb.statements.metrics
+}
+
+// SetSlowQueryLog makes statements that run for at least the
+// log_min_duration_statement of their session write a line to w with
+// their duration and text. A nil w turns the log off.
+func (mb *MemoryBackend) SetSlowQueryLog(w io.Writer) {
+	mb.statements.mu.Lock()
+	defer mb.statements.mu.Unlock()
+	mb.statements.slow = w
+}
+
+func (mb *MemoryBackend) recordStatement(stmt *Statement, m StatementMetrics, err error) {
+	mb.session.recordStatement(stmt, m, err)
+}
+
+func (s *Session) recordStatement(stmt *Statement, m StatementMetrics, err error) {
+	l := &s.mb.statements
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.metrics.Statements++
+	if err != nil {
+		l.metrics.Failed++
+	}
+	l.metrics.add(m)
+
+	if s.slowQuery < 0 || m.ExecutionTime < s.slowQuery {
+		return
+	}
+	l.metrics.Slow++
+	if l.slow != nil {
+		// The log is best effort, and a statement does not fail for it.
+		_, _ = fmt.Fprintf(l.slow, "duration: %.3f ms  rows: %d  statement: %s\n",
+			milliseconds(m.ExecutionTime), m.RowsReturned, statementLine(stmt))
+	}
+}
+
+// statementLine formats stmt on a single line.
+func statementLine(stmt *Statement) string {
+	lines := strings.Split(Format(stmt), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, " ")
+}
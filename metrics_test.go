@@ -0,0 +1,61 @@
+package gosql
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBackend_Metrics(t *testing.T) {
+	mb := NewMemoryBackend()
+	results, err := ExecuteScript(mb, `create table items (id int primary key, name text);
+insert into items values (1, 'pen'), (2, 'ink'), (3, 'cap');
+select name from items where name <> 'ink';
+select name from items where id = 2;
+delete from items where name = 'cap'`, ScriptOptions{})
+	assert.Nil(t, err)
+
+	// The first statement is given the time the script took to parse.
+	assert.Greater(t, results[0].Metrics.ParseTime, int64(0))
+	assert.Zero(t, results[1].Metrics.ParseTime)
+
+	// The sequential scans read every row.
+	scan := results[2].Metrics
+	assert.Equal(t, int64(3), scan.RowsScanned)
+	assert.Equal(t, int64(2), scan.RowsReturned)
+	assert.Greater(t, scan.PlanTime, int64(0))
+	assert.GreaterOrEqual(t, scan.ExecutionTime, scan.PlanTime)
+	assert.Equal(t, int64(1), results[3].Metrics.RowsReturned)
+	assert.Equal(t, int64(3), results[4].Metrics.RowsScanned)
+
+	_, err = ExecuteScript(mb, "select nope from items", ScriptOptions{})
+	assert.NotNil(t, err)
+	metrics := mb.Metrics()
+	assert.Equal(t, uint64(6), metrics.Statements)
+	assert.Equal(t, uint64(1), metrics.Failed)
+	assert.Equal(t, int64(9), metrics.RowsScanned)
+	assert.Zero(t, metrics.Slow)
+}
+
+func TestMemoryBackend_SetSlowQueryLog(t *testing.T) {
+	mb := NewMemoryBackend()
+	var log bytes.Buffer
+	mb.SetSlowQueryLog(&log)
+
+	_, err := ExecuteScript(mb, "create table items (id int); set log_min_duration_statement = 0; select id\nfrom items", ScriptOptions{})
+	assert.Nil(t, err)
+	// The SET is the first statement to run under the new threshold.
+	assert.Regexp(t, `^duration: [0-9.]+ ms  rows: 0  statement: SET log_min_duration_statement = 0\n`+
+		`duration: [0-9.]+ ms  rows: 0  statement: SELECT id FROM items\n$`, log.String())
+
+	log.Reset()
+	_, err = ExecuteScript(mb, "set log_min_duration_statement = '1h'; select id from items; show log_min_duration_statement", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, log.String())
+
+	results, err := ExecuteScript(mb, "set log_min_duration_statement = '-1'; show log_min_duration_statement", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "-1", results[1].Results.Rows[0][0].AsText())
+	assert.Equal(t, uint64(2), mb.Metrics().Slow)
+}
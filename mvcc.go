@@ -0,0 +1,643 @@
+package gosql
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Stored rows are kept as versions. Each version records the transaction
+// that created it and the one that deleted or replaced it, so a reader sees
+// every table as of its snapshot while writers add new versions alongside
+// the ones it is looking at.
+
+const (
+	// frozenXID creates versions that every transaction sees, such as the
+	// rows loaded from disk or left behind by vacuum.
+	frozenXID uint64 = 0
+	// abortedXID replaces the creator of versions whose transaction rolled
+	// back, which hides them from everyone.
+	abortedXID = ^uint64(0)
+)
+
+type rowVersion struct {
+	// xmin is the transaction that created the version.
+	xmin uint64
+	// xmax is the transaction that deleted the version, or 0 while no
+	// transaction has.
+	xmax  uint64
+	cells []MemoryCell
+}
+
+// txSnapshot decides which versions a statement sees: those created by
+// transactions that had committed when it was taken, and those created by
+// its own transaction.
+type txSnapshot struct {
+	// xid is the transaction the snapshot belongs to, or 0 for a read
+	// outside any transaction.
+	xid uint64
+	// xmax is the first transaction id that had not been assigned when the
+	// snapshot was taken.
+	xmax uint64
+	// active holds the transactions in progress when it was taken.
+	active map[uint64]bool
+}
+
+// sees reports whether the changes of transaction xid are visible. A
+// transaction that finished before the snapshot either committed or had
+// its versions hidden with abortedXID when it rolled back.
+func (s *txSnapshot) sees(xid uint64) bool {
+	if xid == frozenXID || xid == s.xid {
+		return true
+	}
+	return xid < s.xmax && !s.active[xid]
+}
+
+func (s *txSnapshot) visible(v *rowVersion) bool {
+	return s.sees(v.xmin) && (v.xmax == 0 || !s.sees(v.xmax))
+}
+
+type transaction struct {
+	id       uint64
+	snapshot *txSnapshot
+	// undo reverses each change the transaction made, in the order they
+	// were made.
+	undo []func()
+	// changes holds the row changes to publish to subscriptions once the
+	// transaction commits.
+	changes []Change
+	// now is the time the statements of a transaction Begin started see.
+	now time.Time
+}
+
+// rollbackTo undoes the changes made since the transaction had mark undo
+// entries.
+func (tx *transaction) rollbackTo(mark int) {
+	for i := len(tx.undo) - 1; i >= mark; i-- {
+		tx.undo[i]()
+	}
+	tx.undo = tx.undo[:mark]
+}
+
+// begin starts a transaction whose snapshot is taken now.
+func (mb *MemoryBackend) begin() *transaction {
+	mb.xidMu.Lock()
+	defer mb.xidMu.Unlock()
+
+	mb.nextXID++
+	tx := &transaction{id: mb.nextXID}
+	tx.snapshot = mb.takeSnapshot()
+	tx.snapshot.xid = tx.id
+	mb.active[tx.id] = true
+	return tx
+}
+
+// end commits or rolls back tx. Its undo entries may only touch tables
+// the caller holds exclusively. A transaction whose changes cannot be
+// persisted is rolled back instead, returning why.
+func (mb *MemoryBackend) end(tx *transaction, commit bool) error {
+	var err error
+	if commit && mb.persist != nil && len(tx.changes) > 0 {
+		err = mb.persist(tx.changes)
+		commit = err == nil
+	}
+	if !commit {
+		tx.rollbackTo(0)
+	}
+	mb.xidMu.Lock()
+	delete(mb.active, tx.id)
+	mb.xidMu.Unlock()
+	if commit {
+		mb.publish(tx)
+	}
+	return err
+}
+
+// tidy vacuums and analyzes tables after a transaction ends. The caller
+// must hold them exclusively.
+func (mb *MemoryBackend) tidy(tables ...*table) {
+	mb.xidMu.Lock()
+	idle := len(mb.active) == 0
+	mb.xidMu.Unlock()
+
+	for _, t := range tables {
+		if idle {
+			t.vacuum()
+		}
+		if t.stale() {
+			t.analyze()
+		}
+	}
+}
+
+// allTables lists every stored table, for tidying them all while holding
+// mb.mu for writing.
+func (mb *MemoryBackend) allTables() []*table {
+	var tables []*table
+	for _, t := range mb.tables {
+		tables = append(tables, t)
+	}
+	return tables
+}
+
+// snapshot sees the transactions that have committed so far.
+func (mb *MemoryBackend) snapshot() *txSnapshot {
+	mb.xidMu.Lock()
+	defer mb.xidMu.Unlock()
+	return mb.takeSnapshot()
+}
+
+// takeSnapshot is snapshot for callers that hold mb.xidMu.
+func (mb *MemoryBackend) takeSnapshot() *txSnapshot {
+	active := make(map[uint64]bool, len(mb.active))
+	for xid := range mb.active {
+		active[xid] = true
+	}
+	return &txSnapshot{xmax: mb.nextXID + 1, active: active}
+}
+
+// live reports whether v holds a row tx must not duplicate in a unique
+// column. Rows created by transactions still in progress count, since
+// they may yet commit.
+func (mb *MemoryBackend) live(tx *transaction, v *rowVersion) bool {
+	if v.xmin == abortedXID {
+		return false
+	}
+	if v.xmax == 0 || v.xmax == tx.id {
+		return v.xmax == 0
+	}
+	mb.xidMu.Lock()
+	defer mb.xidMu.Unlock()
+	return mb.active[v.xmax]
+}
+
+// vacuum drops the versions no transaction can see any more. It must only
+// run when no transaction is in progress, and only does anything on tables
+// that are mostly dead versions, because removing versions renumbers the
+// rest and their indexes have to be rebuilt.
+func (t *table) vacuum() {
+	if t.dead == 0 || t.dead*2 < len(t.versions) {
+		return
+	}
+
+	var kept []*rowVersion
+	for _, v := range t.versions {
+		if v.xmin != abortedXID && v.xmax == 0 {
+			v.xmin = frozenXID
+			kept = append(kept, v)
+		}
+	}
+	t.versions = kept
+	t.dead = 0
+	t.rebuildIndexes()
+	t.rebuildVectors()
+}
+
+// insertVersion adds cells to t as a version created by tx.
+func (t *table) insertVersion(tx *transaction, cells []MemoryCell) {
+	v := &rowVersion{xmin: tx.id, cells: cells}
+	t.versions = append(t.versions, v)
+	for _, idx := range t.indexes {
+		idx.add(cells, len(t.versions)-1)
+	}
+	t.appendVectors(cells)
+	tx.undo = append(tx.undo, func() {
+		v.xmin = abortedXID
+		t.dead++
+	})
+}
+
+// deleteVersion marks v as deleted by tx.
+func (t *table) deleteVersion(tx *transaction, v *rowVersion) {
+	v.xmax = tx.id
+	t.dead++
+	tx.undo = append(tx.undo, func() {
+		v.xmax = 0
+		t.dead--
+	})
+}
+
+// visibleTo returns the rows of t that snap sees, as a table sharing t's
+// schema and indexes.
+func (t *table) visibleTo(snap *txSnapshot) *table {
+	view := *t
+	view.versions = nil
+	view.rows = nil
+	view.rowOf = make([]int, len(t.versions))
+	for i, v := range t.versions {
+		view.rowOf[i] = -1
+		if snap.visible(v) {
+			view.rowOf[i] = len(view.rows)
+			view.rows = append(view.rows, v.cells)
+		}
+	}
+	return &view
+}
+
+// Session runs statements against a MemoryBackend with a transaction of
+// its own, so every connection to a shared backend can have one open.
+// Statements lock the tables they use for as long as they run: those that
+// read share their tables with other readers, while one that writes has
+// its table to itself, so statements on different tables run side by
+// side. Reads see the snapshot of their transaction, or of the moment they
+// start outside one. Statements that change the schema have the backend to
+// themselves. A session must not be used by more than one goroutine at a
+// time.
+//
+// Changes to rows stay invisible to other sessions until they commit.
+// Creating and dropping tables and indexes takes effect for everyone at
+// once, but is still undone by a rollback.
+type Session struct {
+	mb *MemoryBackend
+	// tx is the transaction opened by Begin, or nil.
+	tx *transaction
+	// timeout is the statement_timeout set with Set, or 0 for none.
+	timeout time.Duration
+	// workMem is the work_mem set with Set, in bytes.
+	workMem int64
+	// outputFormat, caseSensitive and searchPath are the other settings,
+	// which the scripts the session runs, or its client, go by.
+	outputFormat  string
+	caseSensitive bool
+	searchPath    []string
+	// database is the database the scripts the session runs look names
+	// up in, which USE changes.
+	database string
+	// lastInsertID is the last value the session's inserts took from the
+	// sequence of an auto-increment column, or 0.
+	lastInsertID int64
+	// temp is the schema holding the session's temporary tables, and
+	// onCommit those of them the transaction drops as it commits.
+	temp     string
+	onCommit []string
+	// slowQuery is the log_min_duration_statement set with Set, or -1
+	// when no statement is slow.
+	slowQuery time.Duration
+	// user is the user the session logged in as, or "" for the owner of
+	// the backend.
+	user string
+	// savepoints holds the savepoints of the transaction, oldest first.
+	savepoints []savepoint
+	// readOnly sessions fail the statements that would change anything
+	// with ErrReadOnlySession.
+	readOnly bool
+}
+
+// savepoint is a point of a transaction that ROLLBACK TO SAVEPOINT goes
+// back to: how many undo entries and temporary tables to drop on commit
+// the transaction had then.
+type savepoint struct {
+	name     string
+	undo     int
+	onCommit int
+}
+
+func (mb *MemoryBackend) NewSession() *Session {
+	return &Session{
+		mb:           mb,
+		workMem:      defaultWorkMem,
+		outputFormat: OutputFormats[0],
+		searchPath:   defaultSearchPath,
+		database:     defaultDatabase,
+		temp:         tempSchemaPrefix + strconv.FormatUint(mb.sessions.Add(1), 10),
+		slowQuery:    -1,
+		readOnly:     mb.readOnlySessions,
+	}
+}
+
+// run runs fn inside the session's transaction, or in a transaction of
+// its own that commits when fn succeeds. Either way a failed statement
+// leaves nothing behind. It reports whether fn ran in a transaction of
+// its own, which has then ended.
+func (s *Session) run(fn func(tx *transaction) error) (bool, error) {
+	if s.tx == nil {
+		tx := s.mb.begin()
+		err := fn(tx)
+		if endErr := s.mb.end(tx, err == nil); err == nil {
+			err = endErr
+		}
+		return true, err
+	}
+
+	mark := len(s.tx.undo)
+	err := fn(s.tx)
+	if err != nil {
+		s.tx.rollbackTo(mark)
+	}
+	return false, err
+}
+
+// alter runs fn, which changes the schema, with the backend to itself.
+func (s *Session) alter(ctx context.Context, fn func(tx *transaction) error) error {
+	if s.readOnly {
+		return ErrReadOnlySession
+	}
+	s.mb.mu.Lock()
+	defer s.mb.mu.Unlock()
+
+	// Waiting for the backend cannot be interrupted, so a statement that
+	// was canceled meanwhile gives up once it has it.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mb.schemaVersion++
+	ended, err := s.run(fn)
+	if ended {
+		s.mb.tidy(s.mb.allTables()...)
+	}
+	return err
+}
+
+// write runs fn, which changes the rows of table and reads those of the
+// tables node reads from, holding the locks it needs. Foreign keys add the
+// tables on their other ends.
+func (s *Session) write(ctx context.Context, table *Token, node Node, fn func(tx *transaction) error) error {
+	if s.readOnly {
+		return ErrReadOnlySession
+	}
+	s.mb.mu.RLock()
+	defer s.mb.mu.RUnlock()
+
+	reads, writes := s.mb.foreignKeyTables(table.Value)
+	writes = append(writes, table.Value)
+	release, err := s.mb.locks.acquire(ctx, append(readTables(node, s.mb.views), reads...), writes)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	ended, err := s.run(fn)
+	if ended {
+		for _, name := range writes {
+			if t, ok := s.mb.tables[name]; ok {
+				s.mb.tidy(t)
+			}
+		}
+	}
+	return err
+}
+
+// read runs fn, which reads the tables slct reads from, holding shared
+// locks on them and with the snapshot the session sees.
+func (s *Session) read(ctx context.Context, slct *SelectStatement, fn func(snap *txSnapshot) error) error {
+	s.mb.mu.RLock()
+	defer s.mb.mu.RUnlock()
+
+	release, err := s.mb.locks.acquire(ctx, readTables(slct, s.mb.views), nil)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// The snapshot is taken once the locks are held, so that vacuum
+	// cannot have removed versions it sees.
+	snap := s.mb.snapshot()
+	if s.tx != nil {
+		snap = s.tx.snapshot
+	}
+	return fn(snap)
+}
+
+// InTransaction reports whether Begin has opened a transaction that has
+// not yet ended.
+func (s *Session) InTransaction() bool {
+	return s.tx != nil
+}
+
+// Schema describes the tables of the backend the session runs on.
+func (s *Session) Schema() Schema {
+	return s.mb.Schema()
+}
+
+func (s *Session) Begin() error {
+	return s.beginAt(s.mb.clock.Now())
+}
+
+// beginAt starts a transaction whose statements see the time now.
+func (s *Session) beginAt(now time.Time) error {
+	if s.tx != nil {
+		return ErrTransactionActive
+	}
+	s.tx = s.mb.begin()
+	s.tx.now = now
+	return nil
+}
+
+func (s *Session) Commit() error {
+	return s.end(true)
+}
+
+func (s *Session) Rollback() error {
+	return s.end(false)
+}
+
+func (s *Session) end(commit bool) error {
+	s.mb.mu.Lock()
+	defer s.mb.mu.Unlock()
+
+	if s.tx == nil {
+		return ErrNoTransaction
+	}
+	err := s.mb.end(s.tx, commit)
+	if err != nil {
+		commit = false
+	}
+	if !commit {
+		// Rolling back may undo changes to the schema.
+		s.mb.schemaVersion++
+	}
+	s.tx = nil
+	s.savepoints = nil
+	s.mb.tidy(s.mb.allTables()...)
+
+	onCommit := s.onCommit
+	s.onCommit = nil
+	if commit {
+		return s.drop(onCommit)
+	}
+	return err
+}
+
+// Savepoint marks the point the transaction has reached as name, hiding
+// any older savepoint of the same name until it is released.
+func (s *Session) Savepoint(name string) error {
+	s.mb.mu.RLock()
+	defer s.mb.mu.RUnlock()
+
+	if s.tx == nil {
+		return ErrNoTransaction
+	}
+	s.savepoints = append(s.savepoints, savepoint{name: name, undo: len(s.tx.undo), onCommit: len(s.onCommit)})
+	return nil
+}
+
+// RollbackToSavepoint undoes every change the transaction made since the
+// savepoint called name, and forgets the savepoints made after it. The
+// savepoint itself stays, so it can be rolled back to again.
+func (s *Session) RollbackToSavepoint(name string) error {
+	s.mb.mu.Lock()
+	defer s.mb.mu.Unlock()
+
+	i, err := s.findSavepoint(name)
+	if err != nil {
+		return err
+	}
+	sp := s.savepoints[i]
+	s.tx.rollbackTo(sp.undo)
+	s.onCommit = s.onCommit[:sp.onCommit]
+	s.savepoints = s.savepoints[:i+1]
+	// Rolling back may undo changes to the schema.
+	s.mb.schemaVersion++
+	return nil
+}
+
+// ReleaseSavepoint forgets the savepoint called name and those made after
+// it, keeping the changes made since.
+func (s *Session) ReleaseSavepoint(name string) error {
+	s.mb.mu.RLock()
+	defer s.mb.mu.RUnlock()
+
+	i, err := s.findSavepoint(name)
+	if err != nil {
+		return err
+	}
+	s.savepoints = s.savepoints[:i]
+	return nil
+}
+
+// findSavepoint returns the index of the newest savepoint called name.
+func (s *Session) findSavepoint(name string) (int, error) {
+	if s.tx == nil {
+		return 0, ErrNoTransaction
+	}
+	for i := len(s.savepoints) - 1; i >= 0; i-- {
+		if s.savepoints[i].name == name {
+			return i, nil
+		}
+	}
+	return 0, ErrNoSavepoint
+}
+
+// Query runs slct and returns a cursor over its result. The cursor
+// holds no locks and sees the rows as they were when Query returned, so
+// other statements may run while it is read.
+func (s *Session) Query(ctx context.Context, slct *SelectStatement) (*Rows, error) {
+	ctx = s.withSettings(ctx)
+	var rows *Rows
+	err := s.read(ctx, slct, func(snap *txSnapshot) (err error) {
+		rows, err = s.mb.cursor(ctx, snap, slct)
+		return err
+	})
+	return rows, err
+}
+
+func (s *Session) Select(ctx context.Context, slct *SelectStatement) (*Results, error) {
+	rows, err := s.Query(ctx, slct)
+	if err != nil {
+		return nil, err
+	}
+	return rows.All()
+}
+
+func (s *Session) Explain(ctx context.Context, e *ExplainStatement) (*Results, error) {
+	ctx = s.withSettings(ctx)
+	var results *Results
+	err := s.read(ctx, e.Select, func(snap *txSnapshot) (err error) {
+		results, err = s.mb.explain(ctx, snap, e)
+		return err
+	})
+	return results, err
+}
+
+func (s *Session) CreateTable(ctx context.Context, crt *CreateTableStatement) error {
+	if crt.Temporary {
+		var err error
+		if crt, err = s.temporaryTable(crt); err != nil {
+			return err
+		}
+	}
+	return s.alter(ctx, func(tx *transaction) error {
+		existed := s.mb.exists(crt.Name.Value)
+		if err := s.mb.createTable(ctx, tx, crt); err != nil || existed || !crt.OnCommitDrop {
+			if err == nil && existed {
+				warn(ctx, "table %s already exists, skipping", crt.Name.Value)
+			}
+			return err
+		}
+		// Outside a transaction the statement commits as it ends, so the
+		// table goes with it.
+		if s.tx == nil {
+			return s.mb.dropTable(tx, &DropTableStatement{Name: crt.Name})
+		}
+		s.onCommit = append(s.onCommit, crt.Name.Value)
+		return nil
+	})
+}
+
+func (s *Session) DropTable(ctx context.Context, drp *DropTableStatement) error {
+	return s.alter(ctx, func(tx *transaction) error {
+		if drp.IfExists && !s.mb.exists(drp.Name.Value) {
+			warn(ctx, "table %s does not exist, skipping", drp.Name.Value)
+		}
+		return s.mb.dropTable(tx, drp)
+	})
+}
+
+func (s *Session) AlterTable(ctx context.Context, alt *AlterTableStatement) error {
+	ctx = s.withSettings(ctx)
+	return s.alter(ctx, func(tx *transaction) error {
+		return s.mb.alterTable(ctx, tx, alt)
+	})
+}
+
+func (s *Session) CreateIndex(ctx context.Context, crt *CreateIndexStatement) error {
+	return s.alter(ctx, func(tx *transaction) error {
+		return s.mb.createIndex(tx, crt)
+	})
+}
+
+func (s *Session) Insert(ctx context.Context, inst *InsertStatement) (int, *Results, error) {
+	ctx = s.withSettings(ctx)
+	var n int
+	var results *Results
+	err := s.write(ctx, inst.Table, inst, func(tx *transaction) error {
+		var id int64
+		var err error
+		n, results, id, err = s.mb.insert(ctx, tx, inst)
+		if err == nil && id != 0 {
+			s.lastInsertID = id
+			insertedID(ctx, id)
+		}
+		return err
+	})
+	return n, results, err
+}
+
+// LastInsertID returns the last value an INSERT of the session took from
+// the sequence of an auto-increment column, or 0 if none has.
+func (s *Session) LastInsertID() int64 {
+	return s.lastInsertID
+}
+
+func (s *Session) Update(ctx context.Context, updt *UpdateStatement) (int, *Results, error) {
+	ctx = s.withSettings(ctx)
+	var n int
+	var results *Results
+	err := s.write(ctx, updt.Table, updt, func(tx *transaction) (err error) {
+		n, results, err = s.mb.update(ctx, tx, updt)
+		return err
+	})
+	return n, results, err
+}
+
+func (s *Session) Delete(ctx context.Context, dlt *DeleteStatement) (int, *Results, error) {
+	ctx = s.withSettings(ctx)
+	var n int
+	var results *Results
+	err := s.write(ctx, dlt.From, dlt, func(tx *transaction) (err error) {
+		n, results, err = s.mb.delete(ctx, tx, dlt)
+		return err
+	})
+	return n, results, err
+}
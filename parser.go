@@ -0,0 +1,3027 @@
+package gosql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+func tokenFromKeyword(k keyword) Token {
+	return Token{
+		Kind:  KeywordKind,
+		Value: string(k),
+	}
+}
+
+func tokenFromSymbol(s Symbol) Token {
+	return Token{
+		Kind:  SymbolKind,
+		Value: string(s),
+	}
+}
+
+func expectToken(tokens []*Token, cursor uint, t Token) bool {
+	if cursor >= uint(len(tokens)) {
+		return false
+	}
+	return t.equals(tokens[cursor])
+}
+
+// parseError builds a syntax error pointing at the token under the cursor,
+// or at the last token when the cursor has run past the end of the input.
+// An identifier that looks like a misspelled keyword gets a hint naming it.
+func parseError(tokens []*Token, cursor uint, msg string) error {
+	if cursor >= uint(len(tokens)) {
+		if len(tokens) == 0 {
+			return &Error{Code: SyntaxError, msg: msg + ", got end of input"}
+		}
+		last := tokens[len(tokens)-1]
+		err := tokenError(SyntaxError, nil, last, fmt.Sprintf("%s, got end of input after %s", msg, last))
+		err.Snippet = ""
+		return err
+	}
+	t := tokens[cursor]
+	err := tokenError(SyntaxError, nil, t, fmt.Sprintf("%s, got %s", msg, t))
+	if t.Kind == IdentifierKind {
+		if k, ok := closestKeyword(t.Value); ok {
+			err.Hint = fmt.Sprintf("Did you mean %s?", strings.ToUpper(string(k)))
+		}
+	}
+	return err
+}
+
+// Parse parses a script of semicolon-separated statements. The trailing
+// semicolon is optional and empty statements, as in "select 1;;", are
+// skipped rather than reported.
+func Parse(source string) (*Ast, error) {
+	return ParseContext(context.Background(), source)
+}
+
+// ParseContext is Parse for long scripts, giving up with the error of ctx
+// once it is done. It looks at ctx between statements.
+func ParseContext(ctx context.Context, source string) (*Ast, error) {
+	return parseWith(ctx, source, lexers)
+}
+
+// ParseWithConfig is Parse for source lexed with cfg, for example to take
+// identifiers quoted the way MySQL quotes them.
+func ParseWithConfig(source string, cfg LexConfig) (*Ast, error) {
+	return parseWith(context.Background(), source, cfg.lexers())
+}
+
+// maxNestingDepth is how deeply ParseStatements lets expressions nest.
+const maxNestingDepth = 1000
+
+// ParseStatements parses a script like Parse, for source that cannot be
+// trusted. It rejects expressions nested more than a thousand deep before
+// parsing them, which bounds how deep the parser recurses, and returns
+// any failure as an *Error. As a last resort it recovers from a panic of
+// the parser, which FuzzParse looks for in Parse itself.
+func ParseStatements(source string) (stmts []Statement, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			stmts = nil
+			err = &Error{Code: InternalError, msg: fmt.Sprintf("%s: %v", ErrParserFailure, r), err: ErrParserFailure}
+		}
+	}()
+
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNesting(tokens); err != nil {
+		return nil, err
+	}
+	a, err := parseTokens(context.Background(), tokens)
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range a.Statements {
+		stmts = append(stmts, *stmt)
+	}
+	return stmts, nil
+}
+
+// withoutComments drops the comments from tokens but for the optimizer
+// hints right after a SELECT keyword, which is the one place they are
+// read. Hints anywhere else are comments like any other.
+func withoutComments(tokens []*Token) []*Token {
+	kept := make([]*Token, 0, len(tokens))
+	for i, t := range tokens {
+		hint := isHint(t) && i > 0 && expectToken(tokens, uint(i-1), tokenFromKeyword(SelectKeyword))
+		if t.Kind == CommentKind && !hint {
+			continue
+		}
+		kept = append(kept, t)
+	}
+	return kept
+}
+
+// checkNesting rejects tokens whose expressions nest deeper than
+// maxNestingDepth. A parenthesis or CASE opens a level until it is closed,
+// and each operator of a run of prefix operators, as in - - 1 or not not
+// a, opens one until the run ends. The parser recurses at most a few times
+// for each level.
+func checkNesting(tokens []*Token) error {
+	depth, prefixes := 0, 0
+	for i, t := range tokens {
+		cursor := uint(i)
+		switch {
+		case expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)), expectToken(tokens, cursor, tokenFromKeyword(CaseKeyword)):
+			depth++
+		case expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)), expectToken(tokens, cursor, tokenFromKeyword(EndKeyword)):
+			depth--
+		}
+		if expectToken(tokens, cursor, tokenFromSymbol(MinusSymbol)) || expectToken(tokens, cursor, tokenFromKeyword(NotKeyword)) {
+			prefixes++
+		} else {
+			prefixes = 0
+		}
+		if depth+prefixes > maxNestingDepth {
+			return tokenError(ResourceError, ErrNestedTooDeeply, t, ErrNestedTooDeeply.Error())
+		}
+	}
+	return nil
+}
+
+func parseWith(ctx context.Context, source string, lexers []lexer) (*Ast, error) {
+	tokens, err := lexWith(source, lexers)
+	if err != nil {
+		return nil, err
+	}
+	return parseTokens(ctx, tokens)
+}
+
+// parseTokens parses the statements of a lexed script.
+func parseTokens(ctx context.Context, tokens []*Token) (*Ast, error) {
+	tokens = withoutComments(tokens)
+	a := Ast{}
+	cursor := uint(0)
+	semicolonToken := tokenFromSymbol(SemiColonSymbol)
+
+	for cursor < uint(len(tokens)) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if expectToken(tokens, cursor, semicolonToken) {
+			cursor++
+			continue
+		}
+
+		stmt, newCursor, err := parseStatement(tokens, cursor, semicolonToken)
+		if err != nil {
+			return nil, err
+		}
+		first, last := tokens[cursor], tokens[newCursor-1]
+		stmt.Loc, stmt.End = first.Loc, last.End
+		stmt.Offset, stmt.EndOffset = first.Offset, last.EndOffset
+		cursor = newCursor
+		a.Statements = append(a.Statements, stmt)
+
+		if cursor < uint(len(tokens)) && !expectToken(tokens, cursor, semicolonToken) {
+			return nil, parseError(tokens, cursor, "Expected end of statement")
+		}
+	}
+
+	return &a, nil
+}
+
+func parseStatement(tokens []*Token, initialCursor uint, delimiter Token) (*Statement, uint, error) {
+	cursor := initialCursor
+
+	if isQuery(tokens, cursor) {
+		slct, newCursor, err := parseSelectStatement(tokens, cursor, delimiter)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:            SelectKind,
+			SelectStatement: slct,
+		}, newCursor, nil
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(InsertKeyword)) {
+		inst, newCursor, err := parseInsertStatement(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:            InsertKind,
+			InsertStatement: inst,
+		}, newCursor, nil
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(UpdateKeyword)) {
+		updt, newCursor, err := parseUpdateStatement(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:            UpdateKind,
+			UpdateStatement: updt,
+		}, newCursor, nil
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(DeleteKeyword)) {
+		dlt, newCursor, err := parseDeleteStatement(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:            DeleteKind,
+			DeleteStatement: dlt,
+		}, newCursor, nil
+	}
+
+	// view is not a keyword, so that it can still name columns.
+	viewToken := Token{Kind: IdentifierKind, Value: "view"}
+	if expectToken(tokens, cursor, tokenFromKeyword(DropKeyword)) && expectToken(tokens, cursor+1, viewToken) {
+		name, ifExists, newCursor, err := parseDropTarget(tokens, cursor+2, "view")
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:              DropViewKind,
+			DropViewStatement: &DropViewStatement{Name: name, IfExists: ifExists},
+		}, newCursor, nil
+	}
+
+	// Nor are database and use.
+	databaseToken := Token{Kind: IdentifierKind, Value: "database"}
+	if expectToken(tokens, cursor, tokenFromKeyword(DropKeyword)) && expectToken(tokens, cursor+1, databaseToken) {
+		name, ifExists, newCursor, err := parseDropTarget(tokens, cursor+2, "database")
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:                  DropDatabaseKind,
+			DropDatabaseStatement: &DropDatabaseStatement{Name: name, IfExists: ifExists},
+		}, newCursor, nil
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(CreateKeyword)) && expectToken(tokens, cursor+1, databaseToken) {
+		crt, newCursor, err := parseCreateDatabaseStatement(tokens, cursor+2)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:                    CreateDatabaseKind,
+			CreateDatabaseStatement: crt,
+		}, newCursor, nil
+	}
+
+	// Nor are user, grant and revoke.
+	userToken := Token{Kind: IdentifierKind, Value: "user"}
+	if expectToken(tokens, cursor, tokenFromKeyword(CreateKeyword)) && expectToken(tokens, cursor+1, userToken) {
+		crt, newCursor, err := parseCreateUserStatement(tokens, cursor+2)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{Kind: CreateUserKind, CreateUserStatement: crt}, newCursor, nil
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(DropKeyword)) && expectToken(tokens, cursor+1, userToken) {
+		name, ifExists, newCursor, err := parseDropTarget(tokens, cursor+2, "user")
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:              DropUserKind,
+			DropUserStatement: &DropUserStatement{Name: name, IfExists: ifExists},
+		}, newCursor, nil
+	}
+
+	revokeToken := Token{Kind: IdentifierKind, Value: "revoke"}
+	if expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "grant"}) || expectToken(tokens, cursor, revokeToken) {
+		revoke := expectToken(tokens, cursor, revokeToken)
+		grant, newCursor, err := parseGrantStatement(tokens, cursor+1, revoke)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		kind := GrantKind
+		if revoke {
+			kind = RevokeKind
+		}
+		return &Statement{Kind: kind, GrantStatement: grant}, newCursor, nil
+	}
+
+	if expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "savepoint"}) {
+		return parseSavepointStatement(tokens, cursor+1, SavepointKind)
+	}
+	if expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "release"}) {
+		return parseSavepointStatement(tokens, cursor+1, ReleaseSavepointKind)
+	}
+
+	if expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "use"}) {
+		name, newCursor, ok := parseIdentifier(tokens, cursor+1)
+		if !ok {
+			return nil, initialCursor, parseError(tokens, cursor+1, "Expected database name")
+		}
+		return &Statement{Kind: UseKind, UseStatement: &UseStatement{Name: name}}, newCursor, nil
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(DropKeyword)) {
+		drp, newCursor, err := parseDropTableStatement(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:               DropTableKind,
+			DropTableStatement: drp,
+		}, newCursor, nil
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(AlterKeyword)) {
+		alt, newCursor, err := parseAlterTableStatement(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:                AlterTableKind,
+			AlterTableStatement: alt,
+		}, newCursor, nil
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(CreateKeyword)) && expectToken(tokens, cursor+1, tokenFromKeyword(IndexKeyword)) {
+		crtIdx, newCursor, err := parseCreateIndexStatement(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:                 CreateIndexKind,
+			CreateIndexStatement: crtIdx,
+		}, newCursor, nil
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(CreateKeyword)) && expectToken(tokens, cursor+1, viewToken) {
+		crtView, newCursor, err := parseCreateViewStatement(tokens, cursor+2, delimiter)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:                CreateViewKind,
+			CreateViewStatement: crtView,
+		}, newCursor, nil
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(CreateKeyword)) {
+		crtTbl, newCursor, err := parseCreateTableStatement(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:                 CreateTableKind,
+			CreateTableStatement: crtTbl,
+		}, newCursor, nil
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(CopyKeyword)) {
+		cp, newCursor, err := parseCopyStatement(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:          CopyKind,
+			CopyStatement: cp,
+		}, newCursor, nil
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(ShowKeyword)) {
+		// tables is not a keyword, so that information_schema.tables
+		// stays a name.
+		if expectToken(tokens, cursor+1, Token{Kind: IdentifierKind, Value: "tables"}) {
+			return &Statement{Kind: ShowTablesKind}, cursor + 2, nil
+		}
+		if expectToken(tokens, cursor+1, tokenFromKeyword(AllKeyword)) {
+			return &Statement{Kind: ShowKind, ShowStatement: &ShowStatement{}}, cursor + 2, nil
+		}
+		name, newCursor, ok := parseIdentifier(tokens, cursor+1)
+		if !ok {
+			return nil, initialCursor, parseError(tokens, cursor+1, "Expected TABLES, ALL or setting name")
+		}
+		return &Statement{Kind: ShowKind, ShowStatement: &ShowStatement{Name: name}}, newCursor, nil
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(DescribeKeyword)) || expectToken(tokens, cursor, tokenFromKeyword(DescKeyword)) {
+		table, newCursor, ok := parseTableName(tokens, cursor+1)
+		if !ok {
+			return nil, initialCursor, parseError(tokens, cursor+1, "Expected table name")
+		}
+		return &Statement{
+			Kind:              DescribeKind,
+			DescribeStatement: &DescribeStatement{Table: table},
+		}, newCursor, nil
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(SetKeyword)) {
+		set, newCursor, err := parseSetStatement(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:         SetKind,
+			SetStatement: set,
+		}, newCursor, nil
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(ExplainKeyword)) {
+		cursor++
+		analyze := expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "analyze"})
+		if analyze {
+			cursor++
+		}
+		if !isQuery(tokens, cursor) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected SELECT")
+		}
+		slct, newCursor, err := parseSelectStatement(tokens, cursor, delimiter)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:             ExplainKind,
+			ExplainStatement: &ExplainStatement{Analyze: analyze, Select: slct},
+		}, newCursor, nil
+	}
+
+	for k, kind := range transactionKinds {
+		if !expectToken(tokens, cursor, tokenFromKeyword(k)) {
+			continue
+		}
+		cursor++
+		if expectToken(tokens, cursor, tokenFromKeyword(TransactionKeyword)) {
+			cursor++
+		}
+		if kind == RollbackKind && expectToken(tokens, cursor, tokenFromKeyword(ToKeyword)) {
+			return parseSavepointStatement(tokens, cursor+1, RollbackToSavepointKind)
+		}
+		return &Statement{Kind: kind}, cursor, nil
+	}
+
+	return nil, initialCursor, parseError(tokens, cursor, "Expected statement")
+}
+
+// parseSavepointStatement parses the name of a savepoint, which SAVEPOINT
+// may come before after RELEASE and ROLLBACK TO.
+func parseSavepointStatement(tokens []*Token, initialCursor uint, kind AstKind) (*Statement, uint, error) {
+	cursor := initialCursor
+	if kind != SavepointKind && expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "savepoint"}) {
+		cursor++
+	}
+	name, newCursor, ok := parseIdentifier(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected savepoint name")
+	}
+	return &Statement{Kind: kind, SavepointStatement: &SavepointStatement{Name: name}}, newCursor, nil
+}
+
+// transactionKinds maps the keyword of each transaction control statement
+// to its kind. Any of them may be followed by TRANSACTION.
+var transactionKinds = map[keyword]AstKind{
+	BeginKeyword:    BeginKind,
+	CommitKeyword:   CommitKind,
+	RollbackKeyword: RollbackKind,
+}
+
+func parseToken(tokens []*Token, initialCursor uint, kind TokenKind) (*Token, uint, bool) {
+	cursor := initialCursor
+	if cursor >= uint(len(tokens)) {
+		return nil, initialCursor, false
+	}
+
+	current := tokens[cursor]
+	if current.Kind == kind {
+		return current, cursor + 1, true
+	}
+
+	return nil, initialCursor, false
+}
+
+// parseIdentifier parses a name, which is an identifier or a non-reserved
+// keyword. A keyword is returned as an identifier token.
+func parseIdentifier(tokens []*Token, initialCursor uint) (*Token, uint, bool) {
+	if initialCursor >= uint(len(tokens)) {
+		return nil, initialCursor, false
+	}
+
+	current := tokens[initialCursor]
+	switch {
+	case current.Kind == IdentifierKind:
+		return current, initialCursor + 1, true
+	case isNonReserved(current):
+		name := *current
+		name.Kind = IdentifierKind
+		return &name, initialCursor + 1, true
+	}
+
+	return nil, initialCursor, false
+}
+
+// bindingPower returns how tightly a binary operator holds its operands. A
+// zero result means the token is not a binary operator.
+func (t *Token) bindingPower() uint {
+	switch t.Kind {
+	case KeywordKind:
+		switch keyword(t.Value) {
+		case OrKeyword:
+			return 1
+		case AndKeyword:
+			return 2
+		case LikeKeyword, InKeyword, BetweenKeyword, IsKeyword:
+			return 3
+		}
+	case SymbolKind:
+		switch Symbol(t.Value) {
+		case EqSymbol, NeqSymbol, BangEqSymbol, LtSymbol, LteSymbol, GtSymbol, GteSymbol:
+			return 3
+		case ConcatSymbol, ArrowSymbol, ArrowTextSymbol:
+			return 4
+		case PlusSymbol, MinusSymbol:
+			return 5
+		case AsteriskSymbol, SlashSymbol, PercentSymbol:
+			return 6
+		case CastSymbol:
+			return 7
+		}
+	}
+	return 0
+}
+
+func parseLiteralExpression(tokens []*Token, initialCursor uint) (*Expression, uint, error) {
+	cursor := initialCursor
+
+	kinds := []TokenKind{IdentifierKind, NumericKind, StringKind, BytesKind, BoolKind, ParameterKind}
+	for _, kind := range kinds {
+		t, newCursor, ok := parseToken(tokens, cursor, kind)
+		if ok {
+			return &Expression{
+				Literal: t,
+				Kind:    LiteralKind,
+			}, newCursor, nil
+		}
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(NullKeyword)) {
+		return &Expression{
+			Literal: tokens[cursor],
+			Kind:    LiteralKind,
+		}, cursor + 1, nil
+	}
+
+	if name, newCursor, ok := parseIdentifier(tokens, cursor); ok {
+		return &Expression{
+			Literal: name,
+			Kind:    LiteralKind,
+		}, newCursor, nil
+	}
+
+	return nil, initialCursor, parseError(tokens, cursor, "Expected expression")
+}
+
+// parseExpression parses operands joined by binary operators whose binding
+// power is greater than minBp, so callers start with 0 to read a whole
+// expression.
+func parseExpression(tokens []*Token, initialCursor uint, minBp uint) (*Expression, uint, error) {
+	cursor := initialCursor
+
+	var exp *Expression
+	if expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+		cursor++
+
+		inner, newCursor, err := parseExpression(tokens, cursor, 0)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+		}
+		cursor++
+		exp = inner
+	} else if expectToken(tokens, cursor, tokenFromKeyword(NotKeyword)) {
+		op := tokens[cursor]
+		cursor++
+
+		// NOT binds looser than comparisons but tighter than AND (2), so
+		// not a = 1 and b reads as (not (a = 1)) and b.
+		operand, newCursor, err := parseExpression(tokens, cursor, 2)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		exp = &Expression{
+			Unary: &UnaryExpression{
+				Operand: operand,
+				Op:      op,
+			},
+			Kind: UnaryKind,
+		}
+	} else if expectToken(tokens, cursor, tokenFromSymbol(MinusSymbol)) {
+		op := tokens[cursor]
+		cursor++
+
+		// Negation binds tighter than any binary operator.
+		operand, newCursor, err := parseExpression(tokens, cursor, 6)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		exp = &Expression{
+			Unary: &UnaryExpression{
+				Operand: operand,
+				Op:      op,
+			},
+			Kind: UnaryKind,
+		}
+	} else if expectToken(tokens, cursor, tokenFromKeyword(CastKeyword)) {
+		cast, newCursor, err := parseCastExpression(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		exp = &Expression{
+			Cast: cast,
+			Kind: CastKind,
+		}
+	} else if expectToken(tokens, cursor, tokenFromKeyword(CaseKeyword)) {
+		cexp, newCursor, err := parseCaseExpression(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		exp = &Expression{
+			Case: cexp,
+			Kind: CaseKind,
+		}
+	} else if literal, newCursor, ok := parseToken(tokens, cursor+1, StringKind); ok && isColumnType(tokens[cursor]) {
+		// A type followed by a string, like date '2024-01-31', casts the
+		// string to the type. Without the string the type names a column.
+		exp = &Expression{
+			Cast: &CastExpression{
+				Operand: &Expression{Literal: literal, Kind: LiteralKind},
+				Type:    tokens[cursor],
+			},
+			Kind: CastKind,
+		}
+		cursor = newCursor
+	} else if isExtract(tokens, cursor) {
+		fn, newCursor, err := parseExtractExpression(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		exp = &Expression{
+			Function: fn,
+			Kind:     FunctionKind,
+		}
+	} else if isCurrentTime(tokens, cursor) {
+		exp = &Expression{
+			Function: &FunctionExpression{Name: tokens[cursor]},
+			Kind:     FunctionKind,
+		}
+		cursor++
+	} else if expectToken(tokens, cursor+1, tokenFromSymbol(DotSymbol)) {
+		col, newCursor, err := parseColumnReference(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		exp = &Expression{
+			Column: col,
+			Kind:   ColumnReferenceKind,
+		}
+	} else if expectToken(tokens, cursor+1, tokenFromSymbol(LeftparenSymbol)) {
+		fn, newCursor, err := parseFunctionExpression(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		exp = &Expression{
+			Function: fn,
+			Kind:     FunctionKind,
+		}
+	} else {
+		literal, newCursor, err := parseLiteralExpression(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		exp = literal
+	}
+
+	for cursor < uint(len(tokens)) {
+		// NOT LIKE, NOT IN and NOT BETWEEN are parsed as NOT applied to
+		// the LIKE, IN or BETWEEN.
+		var not *Token
+		if expectToken(tokens, cursor, tokenFromKeyword(NotKeyword)) && (expectToken(tokens, cursor+1, tokenFromKeyword(LikeKeyword)) ||
+			expectToken(tokens, cursor+1, tokenFromKeyword(InKeyword)) || expectToken(tokens, cursor+1, tokenFromKeyword(BetweenKeyword))) {
+			not = tokens[cursor]
+			cursor++
+		}
+
+		op := tokens[cursor]
+		bp := op.bindingPower()
+		if bp == 0 || bp <= minBp {
+			if not != nil {
+				cursor--
+			}
+			break
+		}
+		isIn := expectToken(tokens, cursor, tokenFromKeyword(InKeyword))
+		isBetween := expectToken(tokens, cursor, tokenFromKeyword(BetweenKeyword))
+		isIs := expectToken(tokens, cursor, tokenFromKeyword(IsKeyword))
+		isCast := expectToken(tokens, cursor, tokenFromSymbol(CastSymbol))
+		cursor++
+
+		if isCast {
+			ty, newCursor, ok := parseToken(tokens, cursor, KeywordKind)
+			if !ok || !isColumnType(ty) {
+				return nil, initialCursor, parseError(tokens, cursor, "Expected type")
+			}
+			cursor = newCursor
+
+			exp = &Expression{
+				Cast: &CastExpression{
+					Operand: exp,
+					Type:    ty,
+				},
+				Kind: CastKind,
+			}
+			continue
+		}
+
+		if isIs {
+			not := expectToken(tokens, cursor, tokenFromKeyword(NotKeyword))
+			if not {
+				cursor++
+			}
+			if !expectToken(tokens, cursor, tokenFromKeyword(NullKeyword)) {
+				return nil, initialCursor, parseError(tokens, cursor, "Expected NULL")
+			}
+			cursor++
+
+			exp = &Expression{
+				IsNull: &IsNullExpression{
+					Operand: exp,
+					Not:     not,
+				},
+				Kind: IsNullKind,
+			}
+			continue
+		}
+
+		if isBetween {
+			between, newCursor, err := parseBetweenBounds(tokens, cursor, bp)
+			if err != nil {
+				return nil, initialCursor, err
+			}
+			cursor = newCursor
+
+			between.Left = exp
+			exp = negate(&Expression{
+				Between: between,
+				Kind:    BetweenKind,
+			}, not)
+			continue
+		}
+
+		if isIn {
+			in, newCursor, err := parseInList(tokens, cursor)
+			if err != nil {
+				return nil, initialCursor, err
+			}
+			cursor = newCursor
+
+			in.Left = exp
+			exp = negate(&Expression{
+				In:   in,
+				Kind: InKind,
+			}, not)
+			continue
+		}
+
+		right, newCursor, err := parseExpression(tokens, cursor, bp)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+
+		exp = negate(&Expression{
+			Binary: &BinaryExpression{
+				Left:  exp,
+				Right: right,
+				Op:    op,
+			},
+			Kind: BinaryKind,
+		}, not)
+	}
+
+	return exp, cursor, nil
+}
+
+// negate applies the NOT keyword not to exp, or returns exp as it is when
+// not is nil.
+func negate(exp *Expression, not *Token) *Expression {
+	if not == nil {
+		return exp
+	}
+	return &Expression{
+		Unary: &UnaryExpression{
+			Operand: exp,
+			Op:      not,
+		},
+		Kind: UnaryKind,
+	}
+}
+
+// parseTableName parses the name of a table or view, which may be
+// qualified by its database as in shop.orders, or by its schema as in
+// information_schema.tables. The parts of a qualified name are joined into
+// one identifier token.
+func parseTableName(tokens []*Token, initialCursor uint) (*Token, uint, bool) {
+	name, cursor, ok := parseIdentifier(tokens, initialCursor)
+	if !ok {
+		return nil, initialCursor, false
+	}
+	if !expectToken(tokens, cursor, tokenFromSymbol(DotSymbol)) {
+		return name, cursor, true
+	}
+
+	table, newCursor, ok := parseIdentifier(tokens, cursor+1)
+	if !ok {
+		return nil, initialCursor, false
+	}
+	return &Token{
+		Value:     name.Value + "." + table.Value,
+		Kind:      IdentifierKind,
+		Loc:       name.Loc,
+		End:       table.End,
+		Offset:    name.Offset,
+		EndOffset: table.EndOffset,
+	}, newCursor, true
+}
+
+func parseColumnReference(tokens []*Token, initialCursor uint) (*ColumnReference, uint, error) {
+	cursor := initialCursor
+
+	table, newCursor, ok := parseIdentifier(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(DotSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected dot")
+	}
+	cursor++
+
+	column, newCursor, ok := parseIdentifier(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected column name")
+	}
+	cursor = newCursor
+
+	return &ColumnReference{
+		Table:  table,
+		Column: column,
+	}, cursor, nil
+}
+
+func parseFunctionExpression(tokens []*Token, initialCursor uint) (*FunctionExpression, uint, error) {
+	cursor := initialCursor
+
+	name, newCursor, ok := parseToken(tokens, cursor, IdentifierKind)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected function name")
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected left paren")
+	}
+	cursor++
+
+	fn := FunctionExpression{Name: name}
+	if expectToken(tokens, cursor, tokenFromSymbol(AsteriskSymbol)) {
+		fn.Asterisk = true
+		cursor++
+	} else if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+		args, newCursor, err := parseExpressions(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		fn.Args = args
+	}
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+	}
+	cursor++
+
+	if expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "over"}) {
+		over, newCursor, err := parseWindow(tokens, cursor+1)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		fn.Over = over
+	}
+
+	return &fn, cursor, nil
+}
+
+// parseWindow parses the parenthesized window following OVER. over and
+// partition are not keywords, so that they can still name columns.
+func parseWindow(tokens []*Token, initialCursor uint) (*Window, uint, error) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected left paren")
+	}
+	cursor++
+
+	var w Window
+	if expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "partition"}) {
+		cursor++
+		if !expectToken(tokens, cursor, tokenFromKeyword(ByKeyword)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected BY")
+		}
+		cursor++
+
+		partitionBy, newCursor, err := parseExpressions(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		w.PartitionBy = partitionBy
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(OrderKeyword)) {
+		orderBy, newCursor, err := parseOrderBy(tokens, cursor+1)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		w.OrderBy = orderBy
+	}
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+	}
+	cursor++
+
+	return &w, cursor, nil
+}
+
+// parseCastExpression parses CAST(<expression> AS <type>).
+func parseCastExpression(tokens []*Token, initialCursor uint) (*CastExpression, uint, error) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(CastKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected CAST")
+	}
+	cursor++
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected left paren")
+	}
+	cursor++
+
+	operand, newCursor, err := parseExpression(tokens, cursor, 0)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(AsKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected AS")
+	}
+	cursor++
+
+	ty, newCursor, ok := parseToken(tokens, cursor, KeywordKind)
+	if !ok || !isColumnType(ty) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected type")
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+	}
+	cursor++
+
+	return &CastExpression{
+		Operand: operand,
+		Type:    ty,
+	}, cursor, nil
+}
+
+// parseCaseExpression parses CASE [<operand>] WHEN <expression> THEN
+// <expression> ... [ELSE <expression>] END.
+func parseCaseExpression(tokens []*Token, initialCursor uint) (*CaseExpression, uint, error) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(CaseKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected CASE")
+	}
+	cexp := CaseExpression{Case: tokens[cursor]}
+	cursor++
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(WhenKeyword)) {
+		operand, newCursor, err := parseExpression(tokens, cursor, 0)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		cexp.Operand = operand
+	}
+
+	for expectToken(tokens, cursor, tokenFromKeyword(WhenKeyword)) {
+		cursor++
+
+		when, newCursor, err := parseExpression(tokens, cursor, 0)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+
+		if !expectToken(tokens, cursor, tokenFromKeyword(ThenKeyword)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected THEN")
+		}
+		cursor++
+
+		then, newCursor, err := parseExpression(tokens, cursor, 0)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+
+		cexp.Whens = append(cexp.Whens, &WhenClause{When: when, Then: then})
+	}
+	if len(cexp.Whens) == 0 {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected WHEN")
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(ElseKeyword)) {
+		cursor++
+
+		els, newCursor, err := parseExpression(tokens, cursor, 0)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		cexp.Else = els
+	}
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(EndKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected END")
+	}
+	cursor++
+
+	return &cexp, cursor, nil
+}
+
+// isExtract reports whether the tokens at cursor start EXTRACT(field FROM
+// source). extract is not a keyword, so extract(...) without FROM is an
+// ordinary function call.
+func isExtract(tokens []*Token, cursor uint) bool {
+	return expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "extract"}) &&
+		expectToken(tokens, cursor+1, tokenFromSymbol(LeftparenSymbol)) &&
+		expectToken(tokens, cursor+3, tokenFromKeyword(FromKeyword))
+}
+
+// isWord reports whether the token at cursor is the identifier word, in
+// any case. NULLS, FIRST and LAST are not keywords, so they are matched
+// this way where ORDER BY takes them.
+func isWord(tokens []*Token, cursor uint, word string) bool {
+	return cursor < uint(len(tokens)) && tokens[cursor].Kind == IdentifierKind &&
+		strings.EqualFold(tokens[cursor].Value, word)
+}
+
+// isCurrentTime reports whether the token at cursor is CURRENT_TIMESTAMP
+// or CURRENT_DATE, which call the functions of the same name without
+// parentheses.
+func isCurrentTime(tokens []*Token, cursor uint) bool {
+	return (expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "current_timestamp"}) ||
+		expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "current_date"})) &&
+		!expectToken(tokens, cursor+1, tokenFromSymbol(LeftparenSymbol)) &&
+		!expectToken(tokens, cursor+1, tokenFromSymbol(DotSymbol))
+}
+
+// parseExtractExpression parses EXTRACT(field FROM source) into a call to
+// the extract function with the field name as a string.
+func parseExtractExpression(tokens []*Token, initialCursor uint) (*FunctionExpression, uint, error) {
+	cursor := initialCursor
+
+	name := tokens[cursor]
+	cursor += 2
+
+	field := tokens[cursor]
+	if field.Kind != IdentifierKind && field.Kind != StringKind {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected field name")
+	}
+	cursor += 2
+
+	source, newCursor, err := parseExpression(tokens, cursor, 0)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+	}
+	cursor++
+
+	return &FunctionExpression{
+		Name: name,
+		Args: []*Expression{
+			{Literal: &Token{Kind: StringKind, Value: field.Value, Loc: field.Loc}, Kind: LiteralKind},
+			source,
+		},
+	}, cursor, nil
+}
+
+// parseInList parses the parenthesized list following IN. An empty list is
+// a syntax error, as it is in Postgres.
+// parseInList parses the parenthesized list or subquery following IN. The
+// caller fills in the left operand.
+func parseInList(tokens []*Token, initialCursor uint) (*InExpression, uint, error) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected left paren")
+	}
+	cursor++
+
+	var in InExpression
+	if isQuery(tokens, cursor) {
+		slct, newCursor, err := parseSelectStatement(tokens, cursor, tokenFromSymbol(RightparenSymbol))
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		in.Select = slct
+	} else {
+		list, newCursor, err := parseExpressions(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		in.List = list
+	}
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+	}
+	cursor++
+
+	return &in, cursor, nil
+}
+
+// parseBetweenBounds parses the "<low> and <high>" following BETWEEN. The
+// low bound stops before any AND so that AND is read as the separator rather
+// than as a boolean operator.
+func parseBetweenBounds(tokens []*Token, initialCursor uint, bp uint) (*BetweenExpression, uint, error) {
+	cursor := initialCursor
+	andToken := tokenFromKeyword(AndKeyword)
+
+	low, newCursor, err := parseExpression(tokens, cursor, andToken.bindingPower())
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, andToken) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected AND")
+	}
+	cursor++
+
+	high, newCursor, err := parseExpression(tokens, cursor, bp)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	cursor = newCursor
+
+	return &BetweenExpression{
+		Low:  low,
+		High: high,
+	}, cursor, nil
+}
+
+func parseSelectItems(tokens []*Token, initialCursor uint) ([]*SelectItem, uint, error) {
+	cursor := initialCursor
+
+	var s []*SelectItem
+	for {
+		var si SelectItem
+		if expectToken(tokens, cursor, tokenFromSymbol(AsteriskSymbol)) {
+			si.Asterisk = true
+			cursor++
+		} else {
+			exp, newCursor, err := parseExpression(tokens, cursor, 0)
+			if err != nil {
+				return nil, initialCursor, err
+			}
+			cursor = newCursor
+			si.Exp = exp
+
+			as, newCursor, err := parseAlias(tokens, cursor)
+			if err != nil {
+				return nil, initialCursor, err
+			}
+			cursor = newCursor
+			si.As = as
+		}
+		s = append(s, &si)
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+			break
+		}
+		cursor++
+	}
+
+	return s, cursor, nil
+}
+
+// parseAlias parses an optional alias, written as "as <name>" or just
+// "<name>". The alias is nil when there is none.
+func parseAlias(tokens []*Token, initialCursor uint) (*Token, uint, error) {
+	cursor := initialCursor
+	if expectToken(tokens, cursor, tokenFromKeyword(AsKeyword)) {
+		cursor++
+	}
+
+	// Only after AS may the alias be a non-reserved keyword, which would
+	// otherwise be read as the start of the next clause.
+	as, newCursor, ok := parseToken(tokens, cursor, IdentifierKind)
+	if cursor != initialCursor {
+		as, newCursor, ok = parseIdentifier(tokens, cursor)
+	}
+	if ok {
+		return as, newCursor, nil
+	}
+	if cursor != initialCursor {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected alias")
+	}
+	return nil, initialCursor, nil
+}
+
+// withToken starts a WITH clause. with is not a keyword, so that it can
+// still name columns.
+var withToken = Token{Kind: IdentifierKind, Value: "with"}
+
+// isQuery reports whether the tokens at cursor start a query, with SELECT
+// or a WITH clause.
+func isQuery(tokens []*Token, cursor uint) bool {
+	return expectToken(tokens, cursor, tokenFromKeyword(SelectKeyword)) || expectToken(tokens, cursor, withToken)
+}
+
+// parseSelectStatement parses a query, which may combine several with
+// UNION, INTERSECT and EXCEPT, followed by the ORDER BY, LIMIT and OFFSET
+// of the whole. A WITH clause may come first.
+func parseSelectStatement(tokens []*Token, initialCursor uint, delimiter Token) (*SelectStatement, uint, error) {
+	if expectToken(tokens, initialCursor, withToken) {
+		with, cursor, err := parseWithClause(tokens, initialCursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		if !expectToken(tokens, cursor, tokenFromKeyword(SelectKeyword)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected SELECT")
+		}
+		slct, newCursor, err := parseSelectStatement(tokens, cursor, delimiter)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		for i, cte := range with {
+			linkCommonTables(cte.Select, with[:i])
+		}
+		linkCommonTables(slct, with)
+		slct.With = with
+		return slct, newCursor, nil
+	}
+
+	slct, cursor, err := parseSetOperation(tokens, initialCursor, 1)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(OrderKeyword)) {
+		cursor++
+
+		orderBy, newCursor, err := parseOrderBy(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		slct.OrderBy = orderBy
+	}
+
+	// LIMIT and OFFSET may come in either order, each at most once.
+	for {
+		var clause **Expression
+		switch {
+		case slct.Limit == nil && expectToken(tokens, cursor, tokenFromKeyword(LimitKeyword)):
+			clause = &slct.Limit
+		case slct.Offset == nil && expectToken(tokens, cursor, tokenFromKeyword(OffsetKeyword)):
+			clause = &slct.Offset
+		default:
+			return slct, cursor, nil
+		}
+		cursor++
+
+		exp, newCursor, err := parseExpression(tokens, cursor, 0)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		*clause = exp
+	}
+}
+
+// parseWithClause parses WITH and the common table expressions after it,
+// each a name and a parenthesized query separated by AS.
+func parseWithClause(tokens []*Token, initialCursor uint) ([]*CommonTableExpression, uint, error) {
+	cursor := initialCursor + 1
+
+	var with []*CommonTableExpression
+	for {
+		if cursor >= uint(len(tokens)) || tokens[cursor].Kind != IdentifierKind {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected query name")
+		}
+		name := tokens[cursor]
+		cursor++
+
+		if !expectToken(tokens, cursor, tokenFromKeyword(AsKeyword)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected AS")
+		}
+		cursor++
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected left paren")
+		}
+		cursor++
+
+		slct, newCursor, err := parseSelectStatement(tokens, cursor, tokenFromSymbol(RightparenSymbol))
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+		}
+		cursor++
+		with = append(with, &CommonTableExpression{Name: name, Select: slct})
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+			return with, cursor, nil
+		}
+		cursor++
+	}
+}
+
+// linkCommonTables gives the tables node reads from that with names their
+// queries. Those a nested WITH clause names have theirs already, so its
+// names hide those of with.
+func linkCommonTables(node Node, with []*CommonTableExpression) {
+	find := func(name *Token) *SelectStatement {
+		for _, cte := range with {
+			if cte.Name.Value == name.Value {
+				return cte.Select
+			}
+		}
+		return nil
+	}
+	Inspect(node, func(n Node) bool {
+		switch n := n.(type) {
+		case *SelectStatement:
+			if n.From != nil && n.FromSelect == nil {
+				n.FromSelect = find(n.From)
+			}
+		case *JoinClause:
+			if n.Select == nil {
+				n.Select = find(n.Table)
+			}
+		}
+		return true
+	})
+}
+
+// setPrecedence returns how tightly a set operator holds the queries it
+// combines, or zero when the token is not one. INTERSECT binds tighter
+// than UNION and EXCEPT, as in PostgreSQL.
+func (t *Token) setPrecedence() uint {
+	if t.Kind != KeywordKind {
+		return 0
+	}
+	switch keyword(t.Value) {
+	case UnionKeyword, ExceptKeyword:
+		return 1
+	case IntersectKeyword:
+		return 2
+	}
+	return 0
+}
+
+// parseSetOperation parses queries joined by set operators whose
+// precedence is at least minPrec. Operators of the same precedence group
+// from the left.
+func parseSetOperation(tokens []*Token, initialCursor uint, minPrec uint) (*SelectStatement, uint, error) {
+	left, cursor, err := parseSelectCore(tokens, initialCursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+
+	for cursor < uint(len(tokens)) {
+		op := tokens[cursor]
+		prec := op.setPrecedence()
+		if prec == 0 || prec < minPrec {
+			break
+		}
+		cursor++
+
+		all := false
+		if expectToken(tokens, cursor, tokenFromKeyword(AllKeyword)) {
+			all = true
+			cursor++
+		} else if expectToken(tokens, cursor, tokenFromKeyword(DistinctKeyword)) {
+			cursor++
+		}
+
+		right, newCursor, err := parseSetOperation(tokens, cursor, prec+1)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+
+		left = &SelectStatement{
+			Set: &SetOperation{
+				Op:    op,
+				All:   all,
+				Left:  left,
+				Right: right,
+			},
+		}
+	}
+
+	return left, cursor, nil
+}
+
+// parseSelectCore parses a single query up to its HAVING clause, leaving
+// what may follow a set operation to the caller.
+func parseSelectCore(tokens []*Token, initialCursor uint) (*SelectStatement, uint, error) {
+	cursor := initialCursor
+	if !expectToken(tokens, cursor, tokenFromKeyword(SelectKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected SELECT")
+	}
+	cursor++
+
+	slct := SelectStatement{}
+
+	if cursor < uint(len(tokens)) && isHint(tokens[cursor]) {
+		hints, err := parseHints(tokens[cursor])
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		slct.Hints = hints
+		cursor++
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(DistinctKeyword)) {
+		slct.Distinct = true
+		cursor++
+	}
+
+	item, newCursor, err := parseSelectItems(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	cursor = newCursor
+	slct.Item = item
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(FromKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected FROM")
+	}
+	cursor++
+
+	if expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+		cursor++
+
+		sub, newCursor, err := parseSelectStatement(tokens, cursor, tokenFromSymbol(RightparenSymbol))
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		slct.FromSelect = sub
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+		}
+		cursor++
+	} else {
+		from, newCursor, ok := parseTableName(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+		}
+		cursor = newCursor
+		slct.From = from
+	}
+
+	fromAs, newCursor, err := parseAlias(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	if fromAs == nil && slct.FromSelect != nil {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected alias")
+	}
+	cursor = newCursor
+	slct.FromAs = fromAs
+
+	for isJoin(tokens, cursor) {
+		join, newCursor, err := parseJoinClause(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		slct.Join = append(slct.Join, join)
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(WhereKeyword)) {
+		cursor++
+
+		where, newCursor, err := parseExpression(tokens, cursor, 0)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		slct.Where = where
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(GroupKeyword)) {
+		cursor++
+
+		if !expectToken(tokens, cursor, tokenFromKeyword(ByKeyword)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected BY")
+		}
+		cursor++
+
+		groupBy, newCursor, err := parseExpressions(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		slct.GroupBy = groupBy
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(HavingKeyword)) {
+		cursor++
+
+		having, newCursor, err := parseExpression(tokens, cursor, 0)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		slct.Having = having
+	}
+
+	return &slct, cursor, nil
+}
+
+// parseHints parses the optimizer hints in the comment t, each a name
+// followed by its arguments, if any, in parentheses and separated by
+// spaces or commas, as in /*+ INDEX(users users_email) NO_HASH_JOIN */.
+func parseHints(t *Token) ([]*Hint, error) {
+	tokens, err := lexHint(t)
+	if err != nil {
+		return nil, err
+	}
+
+	var hints []*Hint
+	cursor := uint(0)
+	for cursor < uint(len(tokens)) {
+		if !isHintWord(tokens[cursor]) {
+			return nil, parseError(tokens, cursor, "Expected hint")
+		}
+		hint := &Hint{Name: tokens[cursor]}
+		hints = append(hints, hint)
+		cursor++
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+			continue
+		}
+		cursor++
+		for !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+			if len(hint.Args) > 0 && expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+				cursor++
+			}
+			if cursor >= uint(len(tokens)) || !isHintWord(tokens[cursor]) {
+				return nil, parseError(tokens, cursor, "Expected hint argument")
+			}
+			hint.Args = append(hint.Args, tokens[cursor])
+			cursor++
+		}
+		cursor++
+	}
+	return hints, nil
+}
+
+// isHintWord reports whether t can name a hint or be one of its
+// arguments, which name tables and indexes.
+func isHintWord(t *Token) bool {
+	return t.Kind == IdentifierKind || t.Kind == KeywordKind
+}
+
+func parseExpressions(tokens []*Token, initialCursor uint) ([]*Expression, uint, error) {
+	cursor := initialCursor
+
+	var exps []*Expression
+	for {
+		exp, newCursor, err := parseExpression(tokens, cursor, 0)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		exps = append(exps, exp)
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+			break
+		}
+		cursor++
+	}
+
+	return exps, cursor, nil
+}
+
+func parseInsertStatement(tokens []*Token, initialCursor uint) (*InsertStatement, uint, error) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(InsertKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected INSERT")
+	}
+	cursor++
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(IntoKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected INTO")
+	}
+	cursor++
+
+	table, newCursor, ok := parseTableName(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+	}
+	cursor = newCursor
+
+	var columns []*Token
+	if expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+		cursor++
+
+		for {
+			col, newCursor, ok := parseIdentifier(tokens, cursor)
+			if !ok {
+				return nil, initialCursor, parseError(tokens, cursor, "Expected column name")
+			}
+			cursor = newCursor
+			columns = append(columns, col)
+
+			if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+				break
+			}
+			cursor++
+		}
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+		}
+		cursor++
+	}
+
+	var values [][]*Expression
+	var slct *SelectStatement
+	switch {
+	case expectToken(tokens, cursor, tokenFromKeyword(ValuesKeyword)):
+		cursor++
+
+		rows, newCursor, err := parseValues(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		values = rows
+	case isQuery(tokens, cursor):
+		sub, newCursor, err := parseSelectStatement(tokens, cursor, tokenFromSymbol(SemiColonSymbol))
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		slct = sub
+	default:
+		return nil, initialCursor, parseError(tokens, cursor, "Expected VALUES or SELECT")
+	}
+
+	onConflict, newCursor, err := parseOnConflict(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	cursor = newCursor
+
+	returning, newCursor, err := parseReturning(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	cursor = newCursor
+
+	return &InsertStatement{
+		Table:      table,
+		Columns:    columns,
+		Values:     values,
+		Select:     slct,
+		OnConflict: onConflict,
+		Returning:  returning,
+	}, cursor, nil
+}
+
+// parseOnConflict parses the ON CONFLICT clause of an INSERT, if there is
+// one.
+func parseOnConflict(tokens []*Token, initialCursor uint) (*OnConflict, uint, error) {
+	cursor := initialCursor
+	if !expectToken(tokens, cursor, tokenFromKeyword(OnKeyword)) {
+		return nil, initialCursor, nil
+	}
+	cursor++
+	if !expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "conflict"}) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected CONFLICT")
+	}
+	cursor++
+
+	onConflict := &OnConflict{}
+	if expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+		cursor++
+		target, newCursor, ok := parseIdentifier(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected column name")
+		}
+		cursor = newCursor
+		if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+		}
+		cursor++
+		onConflict.Target = target
+	}
+
+	if !expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "do"}) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected DO")
+	}
+	cursor++
+	switch {
+	case expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "nothing"}):
+		return onConflict, cursor + 1, nil
+	case !expectToken(tokens, cursor, tokenFromKeyword(UpdateKeyword)):
+		return nil, initialCursor, parseError(tokens, cursor, "Expected NOTHING or UPDATE")
+	case onConflict.Target == nil:
+		return nil, initialCursor, parseError(tokens, cursor, "ON CONFLICT DO UPDATE needs a conflict target")
+	}
+	cursor++
+	onConflict.DoUpdate = true
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(SetKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected SET")
+	}
+	cursor++
+	set, newCursor, err := parseAssignments(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	cursor = newCursor
+	onConflict.Set = set
+
+	if expectToken(tokens, cursor, tokenFromKeyword(WhereKeyword)) {
+		cursor++
+		where, newCursor, err := parseExpression(tokens, cursor, 0)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		onConflict.Where = where
+	}
+	return onConflict, cursor, nil
+}
+
+// parseReturning parses the RETURNING clause of a write statement, if
+// there is one.
+func parseReturning(tokens []*Token, initialCursor uint) ([]*SelectItem, uint, error) {
+	cursor := initialCursor
+	if !expectToken(tokens, cursor, tokenFromKeyword(ReturningKeyword)) {
+		return nil, initialCursor, nil
+	}
+	cursor++
+
+	returning, newCursor, err := parseSelectItems(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	return returning, newCursor, nil
+}
+
+// parseValues parses the parenthesized rows of a VALUES clause.
+func parseValues(tokens []*Token, initialCursor uint) ([][]*Expression, uint, error) {
+	cursor := initialCursor
+
+	var rows [][]*Expression
+	for {
+		if !expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected left paren")
+		}
+		cursor++
+
+		row, newCursor, err := parseExpressions(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		rows = append(rows, row)
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+		}
+		cursor++
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+			break
+		}
+		cursor++
+	}
+
+	return rows, cursor, nil
+}
+
+var columnTypes = []keyword{IntKeyword, IntegerKeyword, BigintKeyword, FloatKeyword, RealKeyword, TextKeyword, VarcharKeyword, BoolKeyword, BooleanKeyword, DateKeyword, TimestampKeyword, JsonKeyword, ByteaKeyword, BlobKeyword}
+
+// parseColumnDefinitions parses the columns of CREATE TABLE along with the
+// CHECK constraints that may be written among them.
+func parseColumnDefinitions(tokens []*Token, initialCursor uint) ([]*ColumnDefinition, []*Expression, uint, error) {
+	cursor := initialCursor
+
+	var cds []*ColumnDefinition
+	var checks []*Expression
+	seen := map[string]bool{}
+	hasPrimaryKey, hasAutoIncrement := false, false
+	for {
+		if expectToken(tokens, cursor, tokenFromKeyword(CheckKeyword)) {
+			check, newCursor, err := parseCheck(tokens, cursor+1)
+			if err != nil {
+				return nil, nil, initialCursor, err
+			}
+			cursor = newCursor
+			checks = append(checks, check)
+
+			if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+				break
+			}
+			cursor++
+			continue
+		}
+
+		cd, newCursor, err := parseColumnDefinition(tokens, cursor)
+		if err != nil {
+			return nil, nil, initialCursor, err
+		}
+		if seen[cd.Name.Value] {
+			return nil, nil, initialCursor, parseError(tokens, cursor, "Duplicate column name")
+		}
+		seen[cd.Name.Value] = true
+		if cd.hasConstraint(PrimaryKeyConstraint) {
+			if hasPrimaryKey {
+				// Point at the constraints, after the name and type.
+				return nil, nil, initialCursor, parseError(tokens, cursor+2, "Multiple primary keys")
+			}
+			hasPrimaryKey = true
+		}
+		if cd.hasConstraint(AutoIncrementConstraint) {
+			if hasAutoIncrement {
+				return nil, nil, initialCursor, parseError(tokens, cursor+2, "Multiple auto-increment columns")
+			}
+			hasAutoIncrement = true
+		}
+		cursor = newCursor
+		cds = append(cds, cd)
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+			break
+		}
+		cursor++
+	}
+
+	if len(cds) == 0 {
+		return nil, nil, initialCursor, parseError(tokens, initialCursor, "Expected column name")
+	}
+	return cds, checks, cursor, nil
+}
+
+// parseCheck parses the parenthesized condition that follows CHECK.
+func parseCheck(tokens []*Token, initialCursor uint) (*Expression, uint, error) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected left paren")
+	}
+	cursor++
+
+	exp, newCursor, err := parseExpression(tokens, cursor, 0)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+	}
+	cursor++
+
+	return exp, cursor, nil
+}
+
+func parseColumnDefinition(tokens []*Token, initialCursor uint) (*ColumnDefinition, uint, error) {
+	cursor := initialCursor
+
+	id, newCursor, ok := parseIdentifier(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected column name")
+	}
+	cursor = newCursor
+
+	ty, newCursor, ok := parseToken(tokens, cursor, KeywordKind)
+	serial := ok && (keyword(ty.Value) == SerialKeyword || keyword(ty.Value) == BigserialKeyword)
+	if !ok || !isColumnType(ty) && !serial {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected column type")
+	}
+	cursor = newCursor
+
+	var constraints []*ColumnConstraint
+	if serial {
+		// SERIAL is shorthand for an integer that is NOT NULL and
+		// AUTO_INCREMENT.
+		serialType := IntKeyword
+		if keyword(ty.Value) == BigserialKeyword {
+			serialType = BigintKeyword
+		}
+		constraints = append(constraints,
+			&ColumnConstraint{Kind: NotNullConstraint, Loc: ty.Loc},
+			&ColumnConstraint{Kind: AutoIncrementConstraint, Loc: ty.Loc})
+		ty = &Token{Value: string(serialType), Kind: KeywordKind, Loc: ty.Loc}
+	}
+
+	var length *Token
+	if keyword(ty.Value) == VarcharKeyword && expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+		cursor++
+		length, newCursor, ok = parseToken(tokens, cursor, NumericKind)
+		if !ok || !isPositiveInteger(length.Value) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected positive length")
+		}
+		cursor = newCursor
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+		}
+		cursor++
+	}
+
+	more, newCursor, err := parseColumnConstraints(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	for _, c := range more {
+		if c.Kind == AutoIncrementConstraint && !isIntegerType(ty) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected integer type for AUTO_INCREMENT")
+		}
+	}
+	constraints = append(constraints, more...)
+	cursor = newCursor
+
+	return &ColumnDefinition{
+		Name:        id,
+		Datatype:    ty,
+		Length:      length,
+		Constraints: constraints,
+	}, cursor, nil
+}
+
+func parseColumnConstraints(tokens []*Token, initialCursor uint) ([]*ColumnConstraint, uint, error) {
+	cursor := initialCursor
+
+	var constraints []*ColumnConstraint
+	for cursor < uint(len(tokens)) {
+		start := tokens[cursor]
+
+		var kind ConstraintKind
+		var second keyword
+		switch {
+		case expectToken(tokens, cursor, tokenFromKeyword(NotKeyword)):
+			kind, second = NotNullConstraint, NullKeyword
+		case expectToken(tokens, cursor, tokenFromKeyword(PrimaryKeyword)):
+			kind, second = PrimaryKeyConstraint, KeyKeyword
+		case expectToken(tokens, cursor, tokenFromKeyword(UniqueKeyword)):
+			kind = UniqueConstraint
+		case expectToken(tokens, cursor, tokenFromKeyword(DefaultKeyword)):
+			kind = DefaultConstraint
+		case expectToken(tokens, cursor, tokenFromKeyword(AutoIncrementKeyword)):
+			kind = AutoIncrementConstraint
+		case expectToken(tokens, cursor, tokenFromKeyword(ReferencesKeyword)):
+			kind = ReferencesConstraint
+		case expectToken(tokens, cursor, tokenFromKeyword(CheckKeyword)):
+			kind = CheckConstraint
+		case expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "collate"}):
+			kind = CollateConstraint
+		default:
+			return constraints, cursor, nil
+		}
+		cursor++
+
+		if kind == DefaultConstraint {
+			exp, newCursor, err := parseExpression(tokens, cursor, 0)
+			if err != nil {
+				return nil, initialCursor, err
+			}
+			cursor = newCursor
+			constraints = append(constraints, &ColumnConstraint{
+				Kind:    kind,
+				Loc:     start.Loc,
+				Default: exp,
+			})
+			continue
+		}
+
+		if kind == CheckConstraint {
+			check, newCursor, err := parseCheck(tokens, cursor)
+			if err != nil {
+				return nil, initialCursor, err
+			}
+			cursor = newCursor
+			constraints = append(constraints, &ColumnConstraint{
+				Kind:  kind,
+				Loc:   start.Loc,
+				Check: check,
+			})
+			continue
+		}
+
+		if kind == CollateConstraint {
+			name, newCursor, ok := parseIdentifier(tokens, cursor)
+			if !ok {
+				return nil, initialCursor, parseError(tokens, cursor, "Expected collation name")
+			}
+			cursor = newCursor
+			constraints = append(constraints, &ColumnConstraint{
+				Kind:      kind,
+				Loc:       start.Loc,
+				Collation: name,
+			})
+			continue
+		}
+
+		if kind == ReferencesConstraint {
+			fk, newCursor, err := parseForeignKey(tokens, cursor)
+			if err != nil {
+				return nil, initialCursor, err
+			}
+			cursor = newCursor
+			constraints = append(constraints, &ColumnConstraint{
+				Kind:       kind,
+				Loc:        start.Loc,
+				References: fk,
+			})
+			continue
+		}
+
+		if second != "" {
+			if !expectToken(tokens, cursor, tokenFromKeyword(second)) {
+				return nil, initialCursor, parseError(tokens, cursor, "Expected "+strings.ToUpper(string(second)))
+			}
+			cursor++
+		}
+
+		constraints = append(constraints, &ColumnConstraint{
+			Kind: kind,
+			Loc:  start.Loc,
+		})
+	}
+
+	return constraints, cursor, nil
+}
+
+// parseForeignKey parses what follows REFERENCES: <table> [(<column>)]
+// [ON DELETE RESTRICT | ON DELETE CASCADE].
+func parseForeignKey(tokens []*Token, initialCursor uint) (*ForeignKey, uint, error) {
+	cursor := initialCursor
+
+	table, newCursor, ok := parseTableName(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+	}
+	cursor = newCursor
+	fk := ForeignKey{Table: table}
+
+	if expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+		cursor++
+		fk.Column, newCursor, ok = parseIdentifier(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected column name")
+		}
+		cursor = newCursor
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+		}
+		cursor++
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(OnKeyword)) {
+		cursor++
+		if !expectToken(tokens, cursor, tokenFromKeyword(DeleteKeyword)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected DELETE")
+		}
+		cursor++
+
+		switch {
+		case expectToken(tokens, cursor, tokenFromKeyword(RestrictKeyword)):
+			fk.OnDelete = RestrictAction
+		case expectToken(tokens, cursor, tokenFromKeyword(CascadeKeyword)):
+			fk.OnDelete = CascadeAction
+		default:
+			return nil, initialCursor, parseError(tokens, cursor, "Expected RESTRICT or CASCADE")
+		}
+		cursor++
+	}
+
+	return &fk, cursor, nil
+}
+
+func isPositiveInteger(s string) bool {
+	n, err := strconv.ParseInt(s, 10, 32)
+	return err == nil && n > 0
+}
+
+func isColumnType(t *Token) bool {
+	for _, k := range columnTypes {
+		if t.equals(&Token{Kind: KeywordKind, Value: string(k)}) {
+			return true
+		}
+	}
+	return false
+}
+
+func isIntegerType(t *Token) bool {
+	switch keyword(t.Value) {
+	case IntKeyword, IntegerKeyword, BigintKeyword:
+		return true
+	}
+	return false
+}
+
+func parseUpdateStatement(tokens []*Token, initialCursor uint) (*UpdateStatement, uint, error) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(UpdateKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected UPDATE")
+	}
+	cursor++
+
+	table, newCursor, ok := parseTableName(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(SetKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected SET")
+	}
+	cursor++
+
+	set, newCursor, err := parseAssignments(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	cursor = newCursor
+
+	var where *Expression
+	if expectToken(tokens, cursor, tokenFromKeyword(WhereKeyword)) {
+		cursor++
+
+		exp, newCursor, err := parseExpression(tokens, cursor, 0)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		where = exp
+	}
+
+	returning, newCursor, err := parseReturning(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	cursor = newCursor
+
+	return &UpdateStatement{
+		Table:     table,
+		Set:       set,
+		Where:     where,
+		Returning: returning,
+	}, cursor, nil
+}
+
+// parseAssignments parses the column = value list of a SET clause.
+func parseAssignments(tokens []*Token, initialCursor uint) ([]*Assignment, uint, error) {
+	cursor := initialCursor
+	var set []*Assignment
+	for {
+		col, newCursor, ok := parseIdentifier(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected column name")
+		}
+		cursor = newCursor
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(EqSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected =")
+		}
+		cursor++
+
+		value, newCursor, err := parseExpression(tokens, cursor, 0)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		set = append(set, &Assignment{Column: col, Value: value})
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+			break
+		}
+		cursor++
+	}
+	return set, cursor, nil
+}
+
+func parseDeleteStatement(tokens []*Token, initialCursor uint) (*DeleteStatement, uint, error) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(DeleteKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected DELETE")
+	}
+	cursor++
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(FromKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected FROM")
+	}
+	cursor++
+
+	table, newCursor, ok := parseTableName(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+	}
+	cursor = newCursor
+
+	var where *Expression
+	if expectToken(tokens, cursor, tokenFromKeyword(WhereKeyword)) {
+		cursor++
+
+		exp, newCursor, err := parseExpression(tokens, cursor, 0)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		where = exp
+	}
+
+	returning, newCursor, err := parseReturning(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	cursor = newCursor
+
+	return &DeleteStatement{
+		From:      table,
+		Where:     where,
+		Returning: returning,
+	}, cursor, nil
+}
+
+func parseCreateTableStatement(tokens []*Token, initialCursor uint) (*CreateTableStatement, uint, error) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(CreateKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected CREATE")
+	}
+	cursor++
+
+	// temporary and temp are not keywords, so that they can still name
+	// columns.
+	temporary := expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "temporary"}) ||
+		expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "temp"})
+	if temporary {
+		cursor++
+	}
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(TableKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected TABLE")
+	}
+	cursor++
+
+	ifNotExists := false
+	if expectToken(tokens, cursor, tokenFromKeyword(IfKeyword)) {
+		cursor++
+		if !expectToken(tokens, cursor, tokenFromKeyword(NotKeyword)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected NOT")
+		}
+		cursor++
+		if !expectToken(tokens, cursor, tokenFromKeyword(ExistsKeyword)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected EXISTS")
+		}
+		cursor++
+		ifNotExists = true
+	}
+
+	name, newCursor, ok := parseTableName(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected left paren")
+	}
+	cursor++
+
+	cols, checks, newCursor, err := parseColumnDefinitions(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+	}
+	cursor++
+
+	layout, newCursor, err := parseTableLayout(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	cursor = newCursor
+
+	onCommitDrop := false
+	if temporary && expectToken(tokens, cursor, tokenFromKeyword(OnKeyword)) {
+		if !expectToken(tokens, cursor+1, tokenFromKeyword(CommitKeyword)) {
+			return nil, initialCursor, parseError(tokens, cursor+1, "Expected COMMIT")
+		}
+		if !expectToken(tokens, cursor+2, tokenFromKeyword(DropKeyword)) {
+			return nil, initialCursor, parseError(tokens, cursor+2, "Expected DROP")
+		}
+		cursor += 3
+		onCommitDrop = true
+	}
+
+	return &CreateTableStatement{
+		Name:         name,
+		Cols:         cols,
+		Checks:       checks,
+		IfNotExists:  ifNotExists,
+		Layout:       layout,
+		Temporary:    temporary,
+		OnCommitDrop: onCommitDrop,
+	}, cursor, nil
+}
+
+// parseTableLayout parses the optional WITH (layout = '...') of CREATE
+// TABLE. The layout is nil when there is none.
+func parseTableLayout(tokens []*Token, initialCursor uint) (*Token, uint, error) {
+	cursor := initialCursor
+	// with is not a keyword, so that it stays usable as a name.
+	if !expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "with"}) {
+		return nil, initialCursor, nil
+	}
+	cursor++
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected left paren")
+	}
+	cursor++
+	if !expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "layout"}) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected layout")
+	}
+	cursor++
+	if !expectToken(tokens, cursor, tokenFromSymbol(EqSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected =")
+	}
+	cursor++
+
+	layout, newCursor, ok := parseToken(tokens, cursor, StringKind)
+	if !ok || layout.Value != RowLayout && layout.Value != ColumnLayout {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected 'row' or 'column'")
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+	}
+	return layout, cursor + 1, nil
+}
+
+func parseCreateIndexStatement(tokens []*Token, initialCursor uint) (*CreateIndexStatement, uint, error) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(CreateKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected CREATE")
+	}
+	cursor++
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(IndexKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected INDEX")
+	}
+	cursor++
+
+	name, newCursor, ok := parseIdentifier(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected index name")
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(OnKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected ON")
+	}
+	cursor++
+
+	table, newCursor, ok := parseTableName(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected left paren")
+	}
+	cursor++
+
+	column, newCursor, ok := parseIdentifier(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected column name")
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+	}
+	cursor++
+
+	return &CreateIndexStatement{
+		Name:   name,
+		Table:  table,
+		Column: column,
+	}, cursor, nil
+}
+
+func parseDropTableStatement(tokens []*Token, initialCursor uint) (*DropTableStatement, uint, error) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(DropKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected DROP")
+	}
+	cursor++
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(TableKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected TABLE")
+	}
+	cursor++
+
+	name, ifExists, cursor, err := parseDropTarget(tokens, cursor, "table")
+	if err != nil {
+		return nil, initialCursor, err
+	}
+
+	return &DropTableStatement{
+		Name:     name,
+		IfExists: ifExists,
+	}, cursor, nil
+}
+
+// parseDropTarget parses what follows DROP TABLE or DROP VIEW: an
+// optional IF EXISTS and the name of the object, which is a table or a
+// view as object says.
+func parseDropTarget(tokens []*Token, initialCursor uint, object string) (*Token, bool, uint, error) {
+	cursor := initialCursor
+
+	ifExists := false
+	if expectToken(tokens, cursor, tokenFromKeyword(IfKeyword)) {
+		cursor++
+		if !expectToken(tokens, cursor, tokenFromKeyword(ExistsKeyword)) {
+			return nil, false, initialCursor, parseError(tokens, cursor, "Expected EXISTS")
+		}
+		cursor++
+		ifExists = true
+	}
+
+	name, newCursor, ok := parseTableName(tokens, cursor)
+	if !ok {
+		return nil, false, initialCursor, parseError(tokens, cursor, "Expected "+object+" name")
+	}
+	return name, ifExists, newCursor, nil
+}
+
+// parseCreateDatabaseStatement parses the rest of CREATE DATABASE after the
+// DATABASE: IF NOT EXISTS, if there, and the name.
+func parseCreateDatabaseStatement(tokens []*Token, initialCursor uint) (*CreateDatabaseStatement, uint, error) {
+	cursor := initialCursor
+
+	ifNotExists := false
+	if expectToken(tokens, cursor, tokenFromKeyword(IfKeyword)) {
+		cursor++
+		if !expectToken(tokens, cursor, tokenFromKeyword(NotKeyword)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected NOT")
+		}
+		cursor++
+		if !expectToken(tokens, cursor, tokenFromKeyword(ExistsKeyword)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected EXISTS")
+		}
+		cursor++
+		ifNotExists = true
+	}
+
+	name, newCursor, ok := parseIdentifier(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected database name")
+	}
+	return &CreateDatabaseStatement{Name: name, IfNotExists: ifNotExists}, newCursor, nil
+}
+
+// parseCreateUserStatement parses the rest of CREATE USER after the USER:
+// the name, an optional WITH and the PASSWORD and SUPERUSER options in any
+// order.
+func parseCreateUserStatement(tokens []*Token, initialCursor uint) (*CreateUserStatement, uint, error) {
+	cursor := initialCursor
+
+	name, newCursor, ok := parseIdentifier(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected user name")
+	}
+	cursor = newCursor
+	crt := &CreateUserStatement{Name: name}
+
+	if expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "with"}) {
+		cursor++
+	}
+	for {
+		switch {
+		case expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "password"}) && crt.Password == nil:
+			password, newCursor, ok := parseToken(tokens, cursor+1, StringKind)
+			if !ok {
+				return nil, initialCursor, parseError(tokens, cursor+1, "Expected password")
+			}
+			crt.Password = password
+			cursor = newCursor
+		case expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "superuser"}) && !crt.Superuser:
+			crt.Superuser = true
+			cursor++
+		default:
+			return crt, cursor, nil
+		}
+	}
+}
+
+// privilegeKeywords are the privileges GRANT and REVOKE take.
+var privilegeKeywords = []keyword{SelectKeyword, InsertKeyword, UpdateKeyword, DeleteKeyword}
+
+// parseGrantStatement parses the rest of GRANT or REVOKE: the privileges,
+// or ALL [PRIVILEGES], ON [TABLE], the table and then TO the user, or FROM
+// the user for REVOKE.
+func parseGrantStatement(tokens []*Token, initialCursor uint, revoke bool) (*GrantStatement, uint, error) {
+	cursor := initialCursor
+	grant := &GrantStatement{}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(AllKeyword)) {
+		cursor++
+		if expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "privileges"}) {
+			cursor++
+		}
+	} else {
+		for {
+			var privilege *Token
+			for _, k := range privilegeKeywords {
+				if expectToken(tokens, cursor, tokenFromKeyword(k)) {
+					privilege = tokens[cursor]
+				}
+			}
+			if privilege == nil {
+				return nil, initialCursor, parseError(tokens, cursor, "Expected privilege")
+			}
+			grant.Privileges = append(grant.Privileges, privilege)
+			cursor++
+			if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+				break
+			}
+			cursor++
+		}
+	}
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(OnKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected ON")
+	}
+	cursor++
+	if expectToken(tokens, cursor, tokenFromKeyword(TableKeyword)) {
+		cursor++
+	}
+	table, newCursor, ok := parseTableName(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+	}
+	grant.Table = table
+	cursor = newCursor
+
+	to, want := tokenFromKeyword(ToKeyword), "Expected TO"
+	if revoke {
+		to, want = tokenFromKeyword(FromKeyword), "Expected FROM"
+	}
+	if !expectToken(tokens, cursor, to) {
+		return nil, initialCursor, parseError(tokens, cursor, want)
+	}
+	cursor++
+	user, newCursor, ok := parseIdentifier(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected user name")
+	}
+	grant.User = user
+	return grant, newCursor, nil
+}
+
+// parseCreateViewStatement parses the rest of CREATE VIEW after the VIEW:
+// the name, AS and the query.
+func parseCreateViewStatement(tokens []*Token, initialCursor uint, delimiter Token) (*CreateViewStatement, uint, error) {
+	cursor := initialCursor
+
+	name, newCursor, ok := parseTableName(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected view name")
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(AsKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected AS")
+	}
+	cursor++
+
+	if !isQuery(tokens, cursor) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected SELECT")
+	}
+	slct, newCursor, err := parseSelectStatement(tokens, cursor, delimiter)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+
+	return &CreateViewStatement{
+		Name:   name,
+		Select: slct,
+	}, newCursor, nil
+}
+
+// parseAlterTableStatement parses ALTER TABLE with one of ADD COLUMN, DROP
+// COLUMN and RENAME COLUMN ... TO. The COLUMN keyword may be left out.
+func parseAlterTableStatement(tokens []*Token, initialCursor uint) (*AlterTableStatement, uint, error) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(AlterKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected ALTER")
+	}
+	cursor++
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(TableKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected TABLE")
+	}
+	cursor++
+
+	name, newCursor, ok := parseTableName(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+	}
+	cursor = newCursor
+	alt := &AlterTableStatement{Table: name}
+
+	var action keyword
+	for _, k := range []keyword{AddKeyword, DropKeyword, RenameKeyword} {
+		if expectToken(tokens, cursor, tokenFromKeyword(k)) {
+			action = k
+		}
+	}
+	if action == "" {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected ADD, DROP or RENAME")
+	}
+	cursor++
+	if expectToken(tokens, cursor, tokenFromKeyword(ColumnKeyword)) {
+		cursor++
+	}
+
+	if action == AddKeyword {
+		cd, newCursor, err := parseColumnDefinition(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		alt.Action = AddColumnAction
+		alt.Add = cd
+		return alt, newCursor, nil
+	}
+
+	alt.Column, newCursor, ok = parseIdentifier(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected column name")
+	}
+	cursor = newCursor
+
+	if action == DropKeyword {
+		alt.Action = DropColumnAction
+		return alt, cursor, nil
+	}
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(ToKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected TO")
+	}
+	cursor++
+
+	alt.To, newCursor, ok = parseIdentifier(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected column name")
+	}
+	alt.Action = RenameColumnAction
+	return alt, newCursor, nil
+}
+
+// parseCopyStatement parses COPY <table> [(<columns>)] FROM|TO '<file>'
+// followed by options, which may be wrapped in WITH (...) and separated by
+// commas as in PostgreSQL.
+func parseCopyStatement(tokens []*Token, initialCursor uint) (*CopyStatement, uint, error) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(CopyKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected COPY")
+	}
+	cursor++
+
+	table, newCursor, ok := parseTableName(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+	}
+	cursor = newCursor
+	cp := &CopyStatement{Table: table}
+
+	if expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol)) {
+		cursor++
+
+		for {
+			col, newCursor, ok := parseIdentifier(tokens, cursor)
+			if !ok {
+				return nil, initialCursor, parseError(tokens, cursor, "Expected column name")
+			}
+			cursor = newCursor
+			cp.Columns = append(cp.Columns, col)
+
+			if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+				break
+			}
+			cursor++
+		}
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+		}
+		cursor++
+	}
+
+	switch {
+	case expectToken(tokens, cursor, tokenFromKeyword(FromKeyword)):
+	case expectToken(tokens, cursor, tokenFromKeyword(ToKeyword)):
+		cp.To = true
+	default:
+		return nil, initialCursor, parseError(tokens, cursor, "Expected FROM or TO")
+	}
+	cursor++
+
+	cp.File, newCursor, ok = parseToken(tokens, cursor, StringKind)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected file name")
+	}
+	cursor = newCursor
+
+	// with is not a keyword, so that it stays usable as a name.
+	if expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "with"}) {
+		cursor++
+	}
+	parenthesized := expectToken(tokens, cursor, tokenFromSymbol(LeftparenSymbol))
+	if parenthesized {
+		cursor++
+	}
+
+	for cursor < uint(len(tokens)) {
+		option := tokens[cursor]
+		switch {
+		case option.Kind == IdentifierKind && option.Value == "delimiter":
+			cp.Delimiter, newCursor, ok = parseToken(tokens, cursor+1, StringKind)
+			if !ok || utf8.RuneCountInString(cp.Delimiter.Value) != 1 {
+				return nil, initialCursor, parseError(tokens, cursor+1, "Expected a single character")
+			}
+			cursor = newCursor
+		case expectToken(tokens, cursor, tokenFromKeyword(NullKeyword)):
+			cp.Null, newCursor, ok = parseToken(tokens, cursor+1, StringKind)
+			if !ok {
+				return nil, initialCursor, parseError(tokens, cursor+1, "Expected string")
+			}
+			cursor = newCursor
+		case option.Kind == IdentifierKind && option.Value == "header":
+			cursor++
+			cp.Header = true
+			if value, newCursor, ok := parseToken(tokens, cursor, BoolKind); ok {
+				cp.Header = value.Value == "true"
+				cursor = newCursor
+			}
+		case option.Kind == IdentifierKind && option.Value == "format":
+			cursor++
+			if !expectToken(tokens, cursor, Token{Kind: IdentifierKind, Value: "csv"}) {
+				return nil, initialCursor, parseError(tokens, cursor, "Expected CSV")
+			}
+			cursor++
+		case option.Kind == IdentifierKind && option.Value == "csv":
+			cursor++
+		default:
+			if parenthesized {
+				return nil, initialCursor, parseError(tokens, cursor, "Expected option")
+			}
+			return cp, cursor, nil
+		}
+
+		if parenthesized {
+			if expectToken(tokens, cursor, tokenFromSymbol(RightparenSymbol)) {
+				return cp, cursor + 1, nil
+			}
+			if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+				return nil, initialCursor, parseError(tokens, cursor, "Expected comma or right paren")
+			}
+			cursor++
+		}
+	}
+
+	if parenthesized {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected right paren")
+	}
+	return cp, cursor, nil
+}
+
+// parseSetStatement parses SET name = value, also written SET name TO
+// value.
+func parseSetStatement(tokens []*Token, initialCursor uint) (*SetStatement, uint, error) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(SetKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected SET")
+	}
+	cursor++
+
+	name, newCursor, ok := parseIdentifier(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected setting name")
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(EqSymbol)) && !expectToken(tokens, cursor, tokenFromKeyword(ToKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected = or TO")
+	}
+	cursor++
+
+	if expectToken(tokens, cursor, tokenFromKeyword(DefaultKeyword)) {
+		return &SetStatement{Name: name, Value: tokens[cursor]}, cursor + 1, nil
+	}
+	// Words like on, json or information_schema are taken as they are.
+	var value *Token
+	for _, kind := range []TokenKind{NumericKind, StringKind, BoolKind, IdentifierKind, KeywordKind} {
+		if value, newCursor, ok = parseToken(tokens, cursor, kind); ok {
+			break
+		}
+	}
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected setting value")
+	}
+	return &SetStatement{Name: name, Value: value}, newCursor, nil
+}
+
+func parseOrderBy(tokens []*Token, initialCursor uint) ([]*OrderByClause, uint, error) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(ByKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected BY")
+	}
+	cursor++
+
+	var clauses []*OrderByClause
+	for {
+		exp, newCursor, err := parseExpression(tokens, cursor, 0)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+
+		clause := OrderByClause{Exp: exp}
+		if expectToken(tokens, cursor, tokenFromKeyword(DescKeyword)) {
+			clause.Desc = true
+			cursor++
+		} else if expectToken(tokens, cursor, tokenFromKeyword(AscKeyword)) {
+			cursor++
+		}
+		if isWord(tokens, cursor, "nulls") {
+			switch {
+			case isWord(tokens, cursor+1, "first"):
+				clause.Nulls = NullsFirst
+			case isWord(tokens, cursor+1, "last"):
+				clause.Nulls = NullsLast
+			default:
+				return nil, initialCursor, parseError(tokens, cursor+1, "Expected FIRST or LAST")
+			}
+			cursor += 2
+		}
+		clauses = append(clauses, &clause)
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+			break
+		}
+		cursor++
+	}
+
+	return clauses, cursor, nil
+}
+
+var joinKinds = map[keyword]JoinKind{
+	InnerKeyword: InnerJoin,
+	LeftKeyword:  LeftJoin,
+	RightKeyword: RightJoin,
+}
+
+// isJoin reports whether a join clause starts at cursor.
+func isJoin(tokens []*Token, cursor uint) bool {
+	if expectToken(tokens, cursor, tokenFromKeyword(JoinKeyword)) {
+		return true
+	}
+	for k := range joinKinds {
+		if expectToken(tokens, cursor, tokenFromKeyword(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseJoinClause parses "[INNER] JOIN", "LEFT [OUTER] JOIN" or
+// "RIGHT [OUTER] JOIN" followed by the table and its ON condition.
+func parseJoinClause(tokens []*Token, initialCursor uint) (*JoinClause, uint, error) {
+	cursor := initialCursor
+
+	kind := InnerJoin
+	for k, jk := range joinKinds {
+		if !expectToken(tokens, cursor, tokenFromKeyword(k)) {
+			continue
+		}
+		kind = jk
+		cursor++
+		if kind != InnerJoin && expectToken(tokens, cursor, tokenFromKeyword(OuterKeyword)) {
+			cursor++
+		}
+		break
+	}
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(JoinKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected JOIN")
+	}
+	cursor++
+
+	table, newCursor, ok := parseTableName(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+	}
+	cursor = newCursor
+
+	as, newCursor, err := parseAlias(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(OnKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected ON")
+	}
+	cursor++
+
+	on, newCursor, err := parseExpression(tokens, cursor, 0)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	cursor = newCursor
+
+	return &JoinClause{
+		Kind:  kind,
+		Table: table,
+		As:    as,
+		On:    on,
+	}, cursor, nil
+}
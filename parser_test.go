@@ -0,0 +1,1189 @@
+package gosql
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		source string
+		ast    *Ast
+	}{
+		{
+			source: "select id, name from users",
+			ast: &Ast{
+				Statements: []*Statement{
+					{
+						Kind:      SelectKind,
+						End:       Location{Col: 26},
+						EndOffset: 26,
+						SelectStatement: &SelectStatement{
+							Item: []*SelectItem{
+								{
+									Exp: &Expression{
+										Kind: LiteralKind,
+										Literal: &Token{
+											Loc:       Location{Col: 7, Line: 0},
+											End:       Location{Col: 9, Line: 0},
+											Offset:    7,
+											EndOffset: 9,
+											Kind:      IdentifierKind,
+											Value:     "id",
+										},
+									},
+								},
+								{
+									Exp: &Expression{
+										Kind: LiteralKind,
+										Literal: &Token{
+											Loc:       Location{Col: 11, Line: 0},
+											End:       Location{Col: 15, Line: 0},
+											Offset:    11,
+											EndOffset: 15,
+											Kind:      IdentifierKind,
+											Value:     "name",
+										},
+									},
+								},
+							},
+							From: &Token{
+								Loc:       Location{Col: 21, Line: 0},
+								End:       Location{Col: 26, Line: 0},
+								Offset:    21,
+								EndOffset: 26,
+								Kind:      IdentifierKind,
+								Value:     "users",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			source: "select * from t;",
+			ast: &Ast{
+				Statements: []*Statement{
+					{
+						Kind:      SelectKind,
+						End:       Location{Col: 15},
+						EndOffset: 15,
+						SelectStatement: &SelectStatement{
+							Item: []*SelectItem{
+								{
+									Asterisk: true,
+								},
+							},
+							From: &Token{
+								Loc:       Location{Col: 14, Line: 0},
+								End:       Location{Col: 15, Line: 0},
+								Offset:    14,
+								EndOffset: 15,
+								Kind:      IdentifierKind,
+								Value:     "t",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			source: "insert into users values (1, 'alice')",
+			ast: &Ast{
+				Statements: []*Statement{
+					{
+						Kind:      InsertKind,
+						End:       Location{Col: 37},
+						EndOffset: 37,
+						InsertStatement: &InsertStatement{
+							Table: &Token{
+								Loc:       Location{Col: 12, Line: 0},
+								End:       Location{Col: 17, Line: 0},
+								Offset:    12,
+								EndOffset: 17,
+								Kind:      IdentifierKind,
+								Value:     "users",
+							},
+							Values: [][]*Expression{
+								{
+									{
+										Kind: LiteralKind,
+										Literal: &Token{
+											Loc:       Location{Col: 26, Line: 0},
+											End:       Location{Col: 27, Line: 0},
+											Offset:    26,
+											EndOffset: 27,
+											Kind:      NumericKind,
+											Value:     "1",
+										},
+									},
+									{
+										Kind: LiteralKind,
+										Literal: &Token{
+											Loc:       Location{Col: 29, Line: 0},
+											End:       Location{Col: 36, Line: 0},
+											Offset:    29,
+											EndOffset: 36,
+											Kind:      StringKind,
+											Value:     "alice",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			source: "create table t (id int, name text)",
+			ast: &Ast{
+				Statements: []*Statement{
+					{
+						Kind:      CreateTableKind,
+						End:       Location{Col: 34},
+						EndOffset: 34,
+						CreateTableStatement: &CreateTableStatement{
+							Name: &Token{
+								Loc:       Location{Col: 13, Line: 0},
+								End:       Location{Col: 14, Line: 0},
+								Offset:    13,
+								EndOffset: 14,
+								Kind:      IdentifierKind,
+								Value:     "t",
+							},
+							Cols: []*ColumnDefinition{
+								{
+									Name: &Token{
+										Loc:       Location{Col: 16, Line: 0},
+										End:       Location{Col: 18, Line: 0},
+										Offset:    16,
+										EndOffset: 18,
+										Kind:      IdentifierKind,
+										Value:     "id",
+									},
+									Datatype: &Token{
+										Loc:       Location{Col: 19, Line: 0},
+										End:       Location{Col: 22, Line: 0},
+										Offset:    19,
+										EndOffset: 22,
+										Kind:      KeywordKind,
+										Value:     "int",
+									},
+								},
+								{
+									Name: &Token{
+										Loc:       Location{Col: 24, Line: 0},
+										End:       Location{Col: 28, Line: 0},
+										Offset:    24,
+										EndOffset: 28,
+										Kind:      IdentifierKind,
+										Value:     "name",
+									},
+									Datatype: &Token{
+										Loc:       Location{Col: 29, Line: 0},
+										End:       Location{Col: 33, Line: 0},
+										Offset:    29,
+										EndOffset: 33,
+										Kind:      KeywordKind,
+										Value:     "text",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ast, err := Parse(test.source)
+		assert.Nil(t, err, test.source)
+		assert.Equal(t, test.ast, ast, test.source)
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{
+			source: "select id where",
+			err:    "Expected FROM, got where at 0:10",
+		},
+		{
+			source: "select id, from users",
+			err:    "Expected expression, got from at 0:11",
+		},
+		{
+			source: "select id from",
+			err:    "Expected table name, got end of input after from at 0:10",
+		},
+		{
+			source: "select * from t t2 t3",
+			err:    "Expected end of statement, got t3 at 0:19",
+		},
+		{
+			source: "insert into users values (1,)",
+			err:    "Expected expression, got ) at 0:28",
+		},
+		{
+			source: "insert into users values 1, 2)",
+			err:    "Expected left paren, got 1 at 0:25",
+		},
+		{
+			source: "insert into users values (1, 2",
+			err:    "Expected right paren, got end of input after 2 at 0:29",
+		},
+		{
+			source: "create table t (id)",
+			err:    "Expected column type, got ) at 0:18",
+		},
+		{
+			source: "create table t (id int, id text)",
+			err:    "Duplicate column name, got id at 0:24",
+		},
+		{
+			source: "create table t (id values)",
+			err:    "Expected column type, got values at 0:19",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.source)
+		assert.EqualError(t, err, test.err, test.source)
+	}
+}
+
+// parenthesize renders an expression with every binary node wrapped in
+// parens so tests can assert the tree shape without spelling out locations.
+func parenthesize(e *Expression) string {
+	switch e.Kind {
+	case LiteralKind:
+		return e.Literal.String()
+	case ColumnReferenceKind:
+		return e.Column.Table.String() + "." + e.Column.Column.String()
+	case BinaryKind:
+		return "(" + parenthesize(e.Binary.Left) + " " + e.Binary.Op.Value + " " + parenthesize(e.Binary.Right) + ")"
+	case IsNullKind:
+		if e.IsNull.Not {
+			return "(" + parenthesize(e.IsNull.Operand) + " is not null)"
+		}
+		return "(" + parenthesize(e.IsNull.Operand) + " is null)"
+	case UnaryKind:
+		return "(" + e.Unary.Op.Value + " " + parenthesize(e.Unary.Operand) + ")"
+	case InKind:
+		var items []string
+		for _, item := range e.In.List {
+			items = append(items, parenthesize(item))
+		}
+		return "(" + parenthesize(e.In.Left) + " in [" + strings.Join(items, ", ") + "])"
+	case BetweenKind:
+		return "(" + parenthesize(e.Between.Left) + " between " + parenthesize(e.Between.Low) + " and " + parenthesize(e.Between.High) + ")"
+	case FunctionKind:
+		if e.Function.Asterisk {
+			return e.Function.Name.Value + "(*)"
+		}
+		var args []string
+		for _, arg := range e.Function.Args {
+			args = append(args, parenthesize(arg))
+		}
+		return e.Function.Name.Value + "(" + strings.Join(args, ", ") + ")"
+	case CastKind:
+		return "(" + parenthesize(e.Cast.Operand) + "::" + e.Cast.Type.Value + ")"
+	}
+	return "?"
+}
+
+func TestParse_where(t *testing.T) {
+	tests := []struct {
+		source string
+		where  string
+	}{
+		{
+			source: "select * from t where a = 1",
+			where:  "(a = 1)",
+		},
+		{
+			source: "select * from t where a = 1 and b = 2",
+			where:  "((a = 1) and (b = 2))",
+		},
+		{
+			source: "select * from t where (a = 1 or b = 2)",
+			where:  "((a = 1) or (b = 2))",
+		},
+		{
+			source: "select * from t where a = 1 or b = 2 and c = 3",
+			where:  "((a = 1) or ((b = 2) and (c = 3)))",
+		},
+		{
+			source: "select * from t where (a = 1 or b = 2) and c = 3",
+			where:  "(((a = 1) or (b = 2)) and (c = 3))",
+		},
+		{
+			source: "select * from t where a = 'x' and b = c and d = 4",
+			where:  "(((a = 'x') and (b = c)) and (d = 4))",
+		},
+		{
+			source: "select * from t where age > 30 and name = 'x'",
+			where:  "((age > 30) and (name = 'x'))",
+		},
+		{
+			source: "select * from t where a <= 1 or b >= 2 and c <> 3",
+			where:  "((a <= 1) or ((b >= 2) and (c <> 3)))",
+		},
+		{
+			source: "select * from t where not a < 1 and b",
+			where:  "((not (a < 1)) and b)",
+		},
+		{
+			source: "select * from t where not (a = 1 or b = 2)",
+			where:  "(not ((a = 1) or (b = 2)))",
+		},
+		{
+			source: "select * from t where a or not not b",
+			where:  "(a or (not (not b)))",
+		},
+		{
+			source: "select * from t where a + b * c = d - e / 2 % 3",
+			where:  "((a + (b * c)) = (d - ((e / 2) % 3)))",
+		},
+		{
+			source: "select * from t where (a - b) * -c > -1 - 2",
+			where:  "(((a - b) * (- c)) > ((- 1) - 2))",
+		},
+		{
+			source: "select * from t where not a * 2 = 4",
+			where:  "(not ((a * 2) = 4))",
+		},
+	}
+
+	for _, test := range tests {
+		ast, err := Parse(test.source)
+		assert.Nil(t, err, test.source)
+		if err == nil {
+			assert.Equal(t, test.where, parenthesize(ast.Statements[0].SelectStatement.Where), test.source)
+		}
+	}
+
+	_, err := Parse("select * from t where (a = 1")
+	assert.EqualError(t, err, "Expected right paren, got end of input after 1 at 0:27")
+}
+
+func TestParse_multipleStatements(t *testing.T) {
+	ast, err := Parse("create table t (id int); insert into t values (1);\nselect * from t;")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(ast.Statements))
+
+	kinds := []AstKind{CreateTableKind, InsertKind, SelectKind}
+	locs := []Location{{Line: 0, Col: 0}, {Line: 0, Col: 25}, {Line: 1, Col: 0}}
+	for i, stmt := range ast.Statements {
+		assert.Equal(t, kinds[i], stmt.Kind)
+		assert.Equal(t, locs[i], stmt.Loc)
+	}
+
+	ast, err = Parse(";select * from t;; ;select * from u")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(ast.Statements))
+
+	ast, err = Parse("")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(ast.Statements))
+
+	_, err = Parse("select * from t select * from u")
+	assert.EqualError(t, err, "Expected end of statement, got select at 0:16")
+}
+
+func TestParse_like(t *testing.T) {
+	ast, err := Parse("select * from t where name like 'a%' and id = 1")
+	assert.Nil(t, err)
+	assert.Equal(t, "((name like 'a%') and (id = 1))", parenthesize(ast.Statements[0].SelectStatement.Where))
+
+	ast, err = Parse("select * from t where not name not like 'a' || id + 1 or id = 2")
+	assert.Nil(t, err)
+	assert.Equal(t, "((not (not (name like ('a' || (id + 1))))) or (id = 2))", parenthesize(ast.Statements[0].SelectStatement.Where))
+
+	_, err = Parse("select * from t where name not 'a'")
+	assert.EqualError(t, err, "Expected end of statement, got not at 0:27")
+}
+
+func TestParse_cast(t *testing.T) {
+	ast, err := Parse("select * from t where cast(a + 1 as bigint) = -b::int * 2")
+	assert.Nil(t, err)
+	assert.Equal(t, "(((a + 1)::bigint) = ((- (b::int)) * 2))", parenthesize(ast.Statements[0].SelectStatement.Where))
+
+	ast, err = Parse("select * from t where a::text::bool and '1'::integer > 0")
+	assert.Nil(t, err)
+	assert.Equal(t, "(((a::text)::bool) and (('1'::integer) > 0))", parenthesize(ast.Statements[0].SelectStatement.Where))
+
+	ast, err = Parse("select * from t where a > date '2024-01-31' and extract(year from a) = 2024 and extract(a)")
+	assert.Nil(t, err)
+	assert.Equal(t, "(((a > ('2024-01-31'::date)) and (extract('year', a) = 2024)) and extract(a))", parenthesize(ast.Statements[0].SelectStatement.Where))
+
+	// Without a string, timestamp is a column.
+	_, err = Parse("select * from t where a > timestamp 1")
+	assert.EqualError(t, err, "Expected end of statement, got 1 at 0:36")
+
+	_, err = Parse("select * from t where a::b")
+	assert.EqualError(t, err, "Expected type, got b at 0:25")
+
+	_, err = Parse("select * from t where cast(a int)")
+	assert.EqualError(t, err, "Expected AS, got int at 0:29")
+}
+
+func TestParse_nonReservedKeywords(t *testing.T) {
+	ast, err := Parse("create table events (key text, date date, index int, intensity int)")
+	assert.Nil(t, err)
+	var names []string
+	for _, cd := range ast.Statements[0].CreateTableStatement.Cols {
+		assert.Equal(t, IdentifierKind, cd.Name.Kind)
+		names = append(names, cd.Name.Value)
+	}
+	assert.Equal(t, []string{"key", "date", "index", "intensity"}, names)
+
+	ast, err = Parse("select key as text, e.date from events e where date > date '2024-01-31' and selection = 1")
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT \"key\" AS \"text\", e.\"date\"\nFROM events AS e\nWHERE \"date\" > '2024-01-31'::DATE AND selection = 1", Format(ast.Statements[0]))
+
+	for _, source := range []string{
+		"create table select (a int)",
+		"create table t (from int)",
+		"select a text from t",
+	} {
+		_, err := Parse(source)
+		assert.NotNil(t, err, source)
+	}
+}
+
+func TestParse_in(t *testing.T) {
+	ast, err := Parse("select * from t where id in (1, 3) and name = 'x'")
+	assert.Nil(t, err)
+	assert.Equal(t, "((id in [1, 3]) and (name = 'x'))", parenthesize(ast.Statements[0].SelectStatement.Where))
+
+	_, err = Parse("select * from t where id in ()")
+	assert.EqualError(t, err, "Expected expression, got ) at 0:29")
+
+	_, err = Parse("select * from t where id in 1")
+	assert.EqualError(t, err, "Expected left paren, got 1 at 0:28")
+}
+
+func TestParse_between(t *testing.T) {
+	tests := []struct {
+		source string
+		where  string
+	}{
+		{
+			source: "select * from t where col between 1 and 10",
+			where:  "(col between 1 and 10)",
+		},
+		{
+			source: "select * from t where col between 1 and 10 and b = 2",
+			where:  "((col between 1 and 10) and (b = 2))",
+		},
+		{
+			source: "select * from t where a = 1 and col between lo and hi or b = 2",
+			where:  "(((a = 1) and (col between lo and hi)) or (b = 2))",
+		},
+	}
+
+	for _, test := range tests {
+		ast, err := Parse(test.source)
+		assert.Nil(t, err, test.source)
+		if err == nil {
+			assert.Equal(t, test.where, parenthesize(ast.Statements[0].SelectStatement.Where), test.source)
+		}
+	}
+
+	_, err := Parse("select * from t where col between 1 or 10")
+	assert.EqualError(t, err, "Expected AND, got or at 0:36")
+}
+
+func TestParse_alias(t *testing.T) {
+	ast, err := Parse("select id as uid, name n, age from users")
+	assert.Nil(t, err)
+
+	items := ast.Statements[0].SelectStatement.Item
+	assert.Equal(t, 3, len(items))
+	assert.Equal(t, "uid", items[0].As.Value)
+	assert.Equal(t, "n", items[1].As.Value)
+	assert.Nil(t, items[2].As)
+
+	_, err = Parse("select id as from users")
+	assert.EqualError(t, err, "Expected alias, got from at 0:13")
+}
+
+func TestParse_function(t *testing.T) {
+	ast, err := Parse("select count(*), sum(price) total, avg(age) from t")
+	assert.Nil(t, err)
+
+	items := ast.Statements[0].SelectStatement.Item
+	assert.Equal(t, 3, len(items))
+
+	count := items[0].Exp
+	assert.Equal(t, FunctionKind, count.Kind)
+	assert.Equal(t, "count", count.Function.Name.Value)
+	assert.True(t, count.Function.Asterisk)
+	assert.Nil(t, count.Function.Args)
+
+	sum := items[1].Exp
+	assert.Equal(t, FunctionKind, sum.Kind)
+	assert.Equal(t, "sum", sum.Function.Name.Value)
+	assert.Equal(t, 1, len(sum.Function.Args))
+	assert.Equal(t, "price", sum.Function.Args[0].Literal.Value)
+	assert.Equal(t, "total", items[1].As.Value)
+
+	_, err = Parse("select count(* from t")
+	assert.EqualError(t, err, "Expected right paren, got from at 0:15")
+}
+
+func TestParse_distinct(t *testing.T) {
+	ast, err := Parse("select distinct name from users")
+	assert.Nil(t, err)
+	assert.True(t, ast.Statements[0].SelectStatement.Distinct)
+	assert.Equal(t, "name", ast.Statements[0].SelectStatement.Item[0].Exp.Literal.Value)
+
+	ast, err = Parse("select name from users")
+	assert.Nil(t, err)
+	assert.False(t, ast.Statements[0].SelectStatement.Distinct)
+}
+
+func TestParse_orderBy(t *testing.T) {
+	ast, err := Parse("select * from t where a = 1 order by a asc, b desc, c")
+	assert.Nil(t, err)
+
+	orderBy := ast.Statements[0].SelectStatement.OrderBy
+	assert.Equal(t, 3, len(orderBy))
+	assert.Equal(t, "a", orderBy[0].Exp.Literal.Value)
+	assert.False(t, orderBy[0].Desc)
+	assert.Equal(t, "b", orderBy[1].Exp.Literal.Value)
+	assert.True(t, orderBy[1].Desc)
+	assert.Equal(t, "c", orderBy[2].Exp.Literal.Value)
+	assert.False(t, orderBy[2].Desc)
+	assert.Equal(t, DefaultNulls, orderBy[2].Nulls)
+
+	ast, err = Parse("select * from t order by a nulls first, b desc NULLS LAST")
+	assert.Nil(t, err)
+	orderBy = ast.Statements[0].SelectStatement.OrderBy
+	assert.Equal(t, NullsFirst, orderBy[0].Nulls)
+	assert.True(t, orderBy[1].Desc)
+	assert.Equal(t, NullsLast, orderBy[1].Nulls)
+
+	_, err = Parse("select * from t order a")
+	assert.EqualError(t, err, "Expected BY, got a at 0:22")
+	_, err = Parse("select * from t order by a nulls")
+	assert.NotNil(t, err)
+}
+
+func TestParse_groupBy(t *testing.T) {
+	ast, err := Parse("select a, count(*) from t where b = 1 group by a, c having count(*) > 1 order by a")
+	assert.Nil(t, err)
+
+	slct := ast.Statements[0].SelectStatement
+	assert.Equal(t, 2, len(slct.GroupBy))
+	assert.Equal(t, "a", slct.GroupBy[0].Literal.Value)
+	assert.Equal(t, "c", slct.GroupBy[1].Literal.Value)
+	assert.Equal(t, "(count(*) > 1)", parenthesize(slct.Having))
+	assert.Equal(t, 1, len(slct.OrderBy))
+
+	_, err = Parse("select a from t group a")
+	assert.EqualError(t, err, "Expected BY, got a at 0:22")
+}
+
+func TestParse_subquery(t *testing.T) {
+	ast, err := Parse("select s.id from (select id from t where a = 1) as s where s.id in (select id from u)")
+	assert.Nil(t, err)
+
+	slct := ast.Statements[0].SelectStatement
+	assert.Nil(t, slct.From)
+	assert.Equal(t, "s", slct.FromAs.Value)
+	assert.Equal(t, "t", slct.FromSelect.From.Value)
+	assert.Equal(t, "(a = 1)", parenthesize(slct.FromSelect.Where))
+	assert.Equal(t, InKind, slct.Where.Kind)
+	assert.Nil(t, slct.Where.In.List)
+	assert.Equal(t, "u", slct.Where.In.Select.From.Value)
+
+	_, err = Parse("select * from (select id from t)")
+	assert.EqualError(t, err, "Expected alias, got end of input after ) at 0:31")
+
+	_, err = Parse("select * from (select id from t s")
+	assert.EqualError(t, err, "Expected right paren, got end of input after s at 0:32")
+
+	_, err = Parse("select * from t where id in (select id from u")
+	assert.EqualError(t, err, "Expected right paren, got end of input after u at 0:44")
+}
+
+func TestParse_limit(t *testing.T) {
+	ast, err := Parse("select * from t order by a limit 10 offset 5")
+	assert.Nil(t, err)
+	slct := ast.Statements[0].SelectStatement
+	assert.Equal(t, "10", slct.Limit.Literal.Value)
+	assert.Equal(t, "5", slct.Offset.Literal.Value)
+
+	ast, err = Parse("select * from t offset 5 limit 10")
+	assert.Nil(t, err)
+	slct = ast.Statements[0].SelectStatement
+	assert.Equal(t, "10", slct.Limit.Literal.Value)
+	assert.Equal(t, "5", slct.Offset.Literal.Value)
+
+	ast, err = Parse("select * from t where a = 1")
+	assert.Nil(t, err)
+	assert.Nil(t, ast.Statements[0].SelectStatement.Limit)
+	assert.Nil(t, ast.Statements[0].SelectStatement.Offset)
+
+	_, err = Parse("select * from t limit")
+	assert.NotNil(t, err)
+}
+
+func TestParse_join(t *testing.T) {
+	ast, err := Parse("select users.id, orders.total from users join orders on users.id = orders.user_id where orders.total = 1")
+	assert.Nil(t, err)
+
+	slct := ast.Statements[0].SelectStatement
+	assert.Equal(t, 2, len(slct.Item))
+	assert.Equal(t, "users.id", parenthesize(slct.Item[0].Exp))
+	assert.Equal(t, "orders.total", parenthesize(slct.Item[1].Exp))
+	assert.Equal(t, "users", slct.From.Value)
+	assert.Equal(t, 1, len(slct.Join))
+	assert.Equal(t, "orders", slct.Join[0].Table.Value)
+	assert.Equal(t, "(users.id = orders.user_id)", parenthesize(slct.Join[0].On))
+	assert.Equal(t, "(orders.total = 1)", parenthesize(slct.Where))
+
+	ast, err = Parse("select u.id, o.total from users as u join orders o on u.id = o.user_id")
+	assert.Nil(t, err)
+
+	slct = ast.Statements[0].SelectStatement
+	assert.Equal(t, "users", slct.From.Value)
+	assert.Equal(t, "u", slct.FromAs.Value)
+	assert.Equal(t, "orders", slct.Join[0].Table.Value)
+	assert.Equal(t, "o", slct.Join[0].As.Value)
+	assert.Equal(t, "(u.id = o.user_id)", parenthesize(slct.Join[0].On))
+
+	ast, err = Parse("select * from users where id = 1")
+	assert.Nil(t, err)
+	assert.Nil(t, ast.Statements[0].SelectStatement.FromAs)
+
+	tests := []struct {
+		source string
+		kind   JoinKind
+	}{
+		{"select * from a join b on a.id = b.id", InnerJoin},
+		{"select * from a inner join b on a.id = b.id", InnerJoin},
+		{"select * from a left join b on a.id = b.id", LeftJoin},
+		{"select * from a left outer join b on a.id = b.id", LeftJoin},
+		{"select * from a right join b on a.id = b.id", RightJoin},
+		{"select * from a right outer join b on a.id = b.id", RightJoin},
+	}
+	for _, test := range tests {
+		ast, err := Parse(test.source)
+		assert.Nil(t, err, test.source)
+		assert.Equal(t, test.kind, ast.Statements[0].SelectStatement.Join[0].Kind, test.source)
+	}
+
+	_, err = Parse("select * from a left b on a.id = b.id")
+	assert.EqualError(t, err, "Expected JOIN, got b at 0:21")
+
+	_, err = Parse("select * from a inner outer join b on a.id = b.id")
+	assert.EqualError(t, err, "Expected JOIN, got outer at 0:22")
+
+	_, err = Parse("select * from users as where id = 1")
+	assert.EqualError(t, err, "Expected alias, got where at 0:23")
+
+	_, err = Parse("select users. from users")
+	assert.EqualError(t, err, "Expected column name, got from at 0:14")
+
+	_, err = Parse("select * from users join orders")
+	assert.EqualError(t, err, "Expected ON, got end of input after orders at 0:25")
+}
+
+func TestParse_insertColumns(t *testing.T) {
+	ast, err := Parse("insert into t (name, id) values ('x', 1)")
+	assert.Nil(t, err)
+
+	inst := ast.Statements[0].InsertStatement
+	assert.Equal(t, 2, len(inst.Columns))
+	assert.Equal(t, "name", inst.Columns[0].Value)
+	assert.Equal(t, "id", inst.Columns[1].Value)
+	assert.Equal(t, [][]*Expression{{
+		{Kind: LiteralKind, Literal: &Token{Value: "x", Kind: StringKind, Loc: Location{Col: 33}, End: Location{Col: 36}, Offset: 33, EndOffset: 36}},
+		{Kind: LiteralKind, Literal: &Token{Value: "1", Kind: NumericKind, Loc: Location{Col: 38}, End: Location{Col: 39}, Offset: 38, EndOffset: 39}},
+	}}, inst.Values)
+
+	ast, err = Parse("insert into t values (1)")
+	assert.Nil(t, err)
+	assert.Nil(t, ast.Statements[0].InsertStatement.Columns)
+
+	ast, err = Parse("insert into t (id) values (1), (2),(3)")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(ast.Statements[0].InsertStatement.Values))
+
+	ast, err = Parse("insert into t (id) select id from u where id > 1 returning id")
+	assert.Nil(t, err)
+	inst = ast.Statements[0].InsertStatement
+	assert.Nil(t, inst.Values)
+	assert.Equal(t, "u", inst.Select.From.Value)
+	assert.NotNil(t, inst.Select.Where)
+	assert.Equal(t, 1, len(inst.Returning))
+
+	_, err = Parse("insert into t (id) (1)")
+	assert.EqualError(t, err, "Expected VALUES or SELECT, got ( at 0:19")
+
+	_, err = Parse("insert into t values (1), ")
+	assert.EqualError(t, err, "Expected left paren, got end of input after , at 0:24")
+
+	_, err = Parse("insert into t (name, ) values ('x')")
+	assert.EqualError(t, err, "Expected column name, got ) at 0:21")
+}
+
+func TestParse_columnConstraints(t *testing.T) {
+	ast, err := Parse("create table t (id int primary key, name text not null, age int)")
+	assert.Nil(t, err)
+
+	cols := ast.Statements[0].CreateTableStatement.Cols
+	assert.Equal(t, []*ColumnConstraint{{Kind: PrimaryKeyConstraint, Loc: Location{Col: 23}}}, cols[0].Constraints)
+	assert.Equal(t, []*ColumnConstraint{{Kind: NotNullConstraint, Loc: Location{Col: 46}}}, cols[1].Constraints)
+	assert.Nil(t, cols[2].Constraints)
+
+	ast, err = Parse("create table t (email text unique not null)")
+	assert.Nil(t, err)
+	assert.Equal(t, []*ColumnConstraint{
+		{Kind: UniqueConstraint, Loc: Location{Col: 27}},
+		{Kind: NotNullConstraint, Loc: Location{Col: 34}},
+	}, ast.Statements[0].CreateTableStatement.Cols[0].Constraints)
+
+	ast, err = Parse("create table t (b bool not null default 1 = 2)")
+	assert.Nil(t, err)
+	constraints := ast.Statements[0].CreateTableStatement.Cols[0].Constraints
+	assert.Equal(t, 2, len(constraints))
+	assert.Equal(t, NotNullConstraint, constraints[0].Kind)
+	assert.Equal(t, DefaultConstraint, constraints[1].Kind)
+	assert.Equal(t, Location{Col: 32}, constraints[1].Loc)
+	assert.Equal(t, "(1 = 2)", constraints[1].Default.String())
+
+	_, err = Parse("create table t (id int default)")
+	assert.NotNil(t, err)
+
+	_, err = Parse("create table t (id int primary, name text)")
+	assert.EqualError(t, err, "Expected KEY, got , at 0:30")
+
+	_, err = Parse("create table t (id int not, name text)")
+	assert.EqualError(t, err, "Expected NULL, got , at 0:26")
+
+	_, err = Parse("create table t (id int primary key, name text primary key)")
+	assert.EqualError(t, err, "Multiple primary keys, got primary at 0:46")
+}
+
+func TestParse_varchar(t *testing.T) {
+	ast, err := Parse("create table t (code varchar(8) not null, note varchar)")
+	assert.Nil(t, err)
+	cols := ast.Statements[0].CreateTableStatement.Cols
+	assert.Equal(t, "varchar", cols[0].Datatype.Value)
+	assert.Equal(t, 8, cols[0].maxLength())
+	assert.Equal(t, []*ColumnConstraint{{Kind: NotNullConstraint, Loc: Location{Col: 32}}}, cols[0].Constraints)
+	assert.Nil(t, cols[1].Length)
+	assert.Equal(t, 0, cols[1].maxLength())
+
+	_, err = Parse("create table t (code varchar(0))")
+	assert.EqualError(t, err, "Expected positive length, got 0 at 0:29")
+
+	_, err = Parse("create table t (code varchar(8)")
+	assert.EqualError(t, err, "Expected right paren, got end of input after ) at 0:30")
+
+	_, err = Parse("create table t (code text(8))")
+	assert.EqualError(t, err, "Expected right paren, got ( at 0:25")
+}
+
+func TestParse_insertReturning(t *testing.T) {
+	ast, err := Parse("insert into t values (1) returning *")
+	assert.Nil(t, err)
+	assert.Equal(t, []*SelectItem{{Asterisk: true}}, ast.Statements[0].InsertStatement.Returning)
+
+	ast, err = Parse("insert into t values (1) returning id, name as n")
+	assert.Nil(t, err)
+	returning := ast.Statements[0].InsertStatement.Returning
+	assert.Equal(t, 2, len(returning))
+	assert.Equal(t, "id", returning[0].Exp.Literal.Value)
+	assert.Equal(t, "n", returning[1].As.Value)
+}
+
+func TestParse_update(t *testing.T) {
+	ast, err := Parse("update users set name = 'x', age = 3 where id = 1")
+	assert.Nil(t, err)
+	assert.Equal(t, UpdateKind, ast.Statements[0].Kind)
+
+	updt := ast.Statements[0].UpdateStatement
+	assert.Equal(t, "users", updt.Table.Value)
+	assert.Equal(t, 2, len(updt.Set))
+	assert.Equal(t, "name", updt.Set[0].Column.Value)
+	assert.Equal(t, "'x'", parenthesize(updt.Set[0].Value))
+	assert.Equal(t, "age", updt.Set[1].Column.Value)
+	assert.Equal(t, "(id = 1)", parenthesize(updt.Where))
+
+	ast, err = Parse("update users set age = 3")
+	assert.Nil(t, err)
+	assert.Nil(t, ast.Statements[0].UpdateStatement.Where)
+
+	_, err = Parse("update users age = 3")
+	assert.EqualError(t, err, "Expected SET, got age at 0:13")
+
+	_, err = Parse("update users set age 3")
+	assert.EqualError(t, err, "Expected =, got 3 at 0:21")
+
+	_, err = Parse("update set age = 3")
+	assert.EqualError(t, err, "Expected table name, got set at 0:7")
+
+	ast, err = Parse("update users set age = 3 where id = 1 returning id, age")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(ast.Statements[0].UpdateStatement.Returning))
+	ast, err = Parse("delete from users returning *")
+	assert.Nil(t, err)
+	assert.Equal(t, []*SelectItem{{Asterisk: true}}, ast.Statements[0].DeleteStatement.Returning)
+	_, err = Parse("delete from users returning")
+	assert.NotNil(t, err)
+}
+
+func TestParse_insertOnConflict(t *testing.T) {
+	ast, err := Parse("insert into users values (1, 'a') on conflict (id) do update set name = excluded.name, age = 1 where age > 0")
+	assert.Nil(t, err)
+	c := ast.Statements[0].InsertStatement.OnConflict
+	assert.Equal(t, "id", c.Target.Value)
+	assert.True(t, c.DoUpdate)
+	assert.Equal(t, 2, len(c.Set))
+	assert.Equal(t, ColumnReferenceKind, c.Set[0].Value.Kind)
+	assert.NotNil(t, c.Where)
+
+	ast, err = Parse("insert into users values (1, 'a') on conflict do nothing returning id")
+	assert.Nil(t, err)
+	assert.Equal(t, &OnConflict{}, ast.Statements[0].InsertStatement.OnConflict)
+	assert.Equal(t, 1, len(ast.Statements[0].InsertStatement.Returning))
+
+	_, err = Parse("insert into users values (1, 'a') on conflict do update set name = 'b'")
+	assert.NotNil(t, err)
+}
+
+func TestParse_delete(t *testing.T) {
+	ast, err := Parse("delete from users where id = 1")
+	assert.Nil(t, err)
+	assert.Equal(t, DeleteKind, ast.Statements[0].Kind)
+
+	dlt := ast.Statements[0].DeleteStatement
+	assert.Equal(t, "users", dlt.From.Value)
+	assert.Equal(t, "(id = 1)", parenthesize(dlt.Where))
+
+	ast, err = Parse("delete from users")
+	assert.Nil(t, err)
+	assert.Nil(t, ast.Statements[0].DeleteStatement.Where)
+
+	_, err = Parse("delete users")
+	assert.EqualError(t, err, "Expected FROM, got users at 0:7")
+}
+
+func TestParse_dropTable(t *testing.T) {
+	ast, err := Parse("drop table users")
+	assert.Nil(t, err)
+	assert.Equal(t, DropTableKind, ast.Statements[0].Kind)
+	assert.Equal(t, "users", ast.Statements[0].DropTableStatement.Name.Value)
+	assert.False(t, ast.Statements[0].DropTableStatement.IfExists)
+
+	ast, err = Parse("drop table if exists users")
+	assert.Nil(t, err)
+	assert.True(t, ast.Statements[0].DropTableStatement.IfExists)
+
+	ast, err = Parse("create table if not exists users (id int)")
+	assert.Nil(t, err)
+	assert.True(t, ast.Statements[0].CreateTableStatement.IfNotExists)
+	assert.Equal(t, "users", ast.Statements[0].CreateTableStatement.Name.Value)
+
+	_, err = Parse("drop users")
+	assert.EqualError(t, err, "Expected TABLE, got users at 0:5")
+
+	_, err = Parse("drop table if users")
+	assert.EqualError(t, err, "Expected EXISTS, got users at 0:14")
+
+	_, err = Parse("create table if exists users (id int)")
+	assert.EqualError(t, err, "Expected NOT, got exists at 0:16")
+}
+
+func TestParse_alterTable(t *testing.T) {
+	ast, err := Parse("alter table users add column email text not null")
+	assert.Nil(t, err)
+	assert.Equal(t, AlterTableKind, ast.Statements[0].Kind)
+	alt := ast.Statements[0].AlterTableStatement
+	assert.Equal(t, "users", alt.Table.Value)
+	assert.Equal(t, AddColumnAction, alt.Action)
+	assert.Equal(t, "email", alt.Add.Name.Value)
+	assert.Equal(t, "text", alt.Add.Datatype.Value)
+	assert.True(t, alt.Add.hasConstraint(NotNullConstraint))
+
+	ast, err = Parse("alter table users drop email")
+	assert.Nil(t, err)
+	alt = ast.Statements[0].AlterTableStatement
+	assert.Equal(t, DropColumnAction, alt.Action)
+	assert.Equal(t, "email", alt.Column.Value)
+
+	ast, err = Parse("alter table users rename column name to login")
+	assert.Nil(t, err)
+	alt = ast.Statements[0].AlterTableStatement
+	assert.Equal(t, RenameColumnAction, alt.Action)
+	assert.Equal(t, "name", alt.Column.Value)
+	assert.Equal(t, "login", alt.To.Value)
+
+	_, err = Parse("alter table users modify name text")
+	assert.EqualError(t, err, "Expected ADD, DROP or RENAME, got modify at 0:18")
+
+	_, err = Parse("alter table users rename name login")
+	assert.EqualError(t, err, "Expected TO, got login at 0:30")
+
+	_, err = Parse("alter table users add column email")
+	assert.Error(t, err)
+}
+
+func TestParse_copy(t *testing.T) {
+	ast, err := Parse("copy users (id, name) from 'users.csv' with (format csv, delimiter ';', header, null 'NA')")
+	assert.Nil(t, err)
+	assert.Equal(t, CopyKind, ast.Statements[0].Kind)
+	cp := ast.Statements[0].CopyStatement
+	assert.Equal(t, "users", cp.Table.Value)
+	assert.Equal(t, 2, len(cp.Columns))
+	assert.False(t, cp.To)
+	assert.Equal(t, "users.csv", cp.File.Value)
+	assert.Equal(t, ";", cp.Delimiter.Value)
+	assert.True(t, cp.Header)
+	assert.Equal(t, "NA", cp.Null.Value)
+
+	ast, err = Parse("copy users to 'out.csv' csv header false; delete from users")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(ast.Statements))
+	cp = ast.Statements[0].CopyStatement
+	assert.Nil(t, cp.Columns)
+	assert.True(t, cp.To)
+	assert.False(t, cp.Header)
+	assert.Nil(t, cp.Delimiter)
+
+	_, err = Parse("copy users into 'users.csv'")
+	assert.EqualError(t, err, "Expected FROM or TO, got into at 0:11")
+
+	_, err = Parse("copy users from 'users.csv' delimiter ';;'")
+	assert.EqualError(t, err, "Expected a single character, got ';;' at 0:38")
+
+	_, err = Parse("copy users from 'users.csv' with (header encoding 'utf8')")
+	assert.EqualError(t, err, "Expected comma or right paren, got encoding at 0:41")
+}
+
+func TestParse_showDescribe(t *testing.T) {
+	ast, err := Parse("show tables; describe users; desc information_schema.columns")
+	assert.Nil(t, err)
+	assert.Equal(t, ShowTablesKind, ast.Statements[0].Kind)
+	assert.Equal(t, DescribeKind, ast.Statements[1].Kind)
+	assert.Equal(t, "users", ast.Statements[1].DescribeStatement.Table.Value)
+	assert.Equal(t, "information_schema.columns", ast.Statements[2].DescribeStatement.Table.Value)
+
+	ast, err = Parse("show all; show work_mem")
+	assert.Nil(t, err)
+	assert.Equal(t, ShowKind, ast.Statements[0].Kind)
+	assert.Nil(t, ast.Statements[0].ShowStatement.Name)
+	assert.Equal(t, "work_mem", ast.Statements[1].ShowStatement.Name.Value)
+
+	_, err = Parse("show 1")
+	assert.EqualError(t, err, "Expected TABLES, ALL or setting name, got 1 at 0:5")
+
+	_, err = Parse("describe 1")
+	assert.EqualError(t, err, "Expected table name, got 1 at 0:9")
+}
+
+func TestParse_set(t *testing.T) {
+	ast, err := Parse("set statement_timeout = 100; set statement_timeout to '5s'; set statement_timeout = default")
+	assert.Nil(t, err)
+	assert.Equal(t, SetKind, ast.Statements[0].Kind)
+	assert.Equal(t, "statement_timeout", ast.Statements[0].SetStatement.Name.Value)
+	assert.Equal(t, "100", ast.Statements[0].SetStatement.Value.Value)
+	assert.Equal(t, StringKind, ast.Statements[1].SetStatement.Value.Kind)
+	assert.Equal(t, "5s", ast.Statements[1].SetStatement.Value.Value)
+	assert.Equal(t, KeywordKind, ast.Statements[2].SetStatement.Value.Kind)
+
+	ast, err = Parse("set case_sensitive = on; set case_sensitive = false; set search_path to information_schema")
+	assert.Nil(t, err)
+	assert.Equal(t, "on", ast.Statements[0].SetStatement.Value.Value)
+	assert.Equal(t, BoolKind, ast.Statements[1].SetStatement.Value.Kind)
+	assert.Equal(t, "information_schema", ast.Statements[2].SetStatement.Value.Value)
+
+	_, err = Parse("set statement_timeout 100")
+	assert.EqualError(t, err, "Expected = or TO, got 100 at 0:22")
+
+	_, err = Parse("set statement_timeout = ")
+	assert.EqualError(t, err, "Expected setting value, got end of input after = at 0:22")
+}
+
+func TestParse_databases(t *testing.T) {
+	ast, err := Parse("create database if not exists shop; use shop; insert into shop.orders values (1); drop database shop")
+	assert.Nil(t, err)
+	assert.Equal(t, CreateDatabaseKind, ast.Statements[0].Kind)
+	assert.True(t, ast.Statements[0].CreateDatabaseStatement.IfNotExists)
+	assert.Equal(t, "shop", ast.Statements[1].UseStatement.Name.Value)
+	assert.Equal(t, "shop.orders", ast.Statements[2].InsertStatement.Table.Value)
+	assert.Equal(t, DropDatabaseKind, ast.Statements[3].Kind)
+	assert.Equal(t, "DROP DATABASE shop", Format(ast.Statements[3]))
+
+	_, err = Parse("use 1")
+	assert.EqualError(t, err, "Expected database name, got 1 at 0:4")
+}
+
+func TestParse_temporaryTable(t *testing.T) {
+	ast, err := Parse("create temporary table staging (id int) on commit drop; create temp table scratch (id int)")
+	assert.Nil(t, err)
+	crt := ast.Statements[0].CreateTableStatement
+	assert.True(t, crt.Temporary)
+	assert.True(t, crt.OnCommitDrop)
+	assert.Equal(t, "CREATE TEMPORARY TABLE staging (\n  id INT\n) ON COMMIT DROP", Format(ast.Statements[0]))
+	assert.True(t, ast.Statements[1].CreateTableStatement.Temporary)
+	assert.False(t, ast.Statements[1].CreateTableStatement.OnCommitDrop)
+
+	_, err = Parse("create temporary table staging (id int) on drop")
+	assert.EqualError(t, err, "Expected COMMIT, got drop at 0:43")
+}
+
+func TestParseContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ParseContext(ctx, "select 1")
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestParse_createIndex(t *testing.T) {
+	ast, err := Parse("create index users_id on users (id)")
+	assert.Nil(t, err)
+	assert.Equal(t, CreateIndexKind, ast.Statements[0].Kind)
+
+	crt := ast.Statements[0].CreateIndexStatement
+	assert.Equal(t, "users_id", crt.Name.Value)
+	assert.Equal(t, "users", crt.Table.Value)
+	assert.Equal(t, "id", crt.Column.Value)
+
+	_, err = Parse("create index users_id users (id)")
+	assert.EqualError(t, err, "Expected ON, got users at 0:22")
+
+	_, err = Parse("create index on users (id)")
+	assert.EqualError(t, err, "Expected index name, got on at 0:13")
+}
+
+func TestParse_isNull(t *testing.T) {
+	tests := []struct {
+		source string
+		where  string
+	}{
+		{"select * from t where a is null", "(a is null)"},
+		{"select * from t where a is not null and b = null", "((a is not null) and (b = null))"},
+		{"select * from t where not a is null", "(not (a is null))"},
+	}
+
+	for _, test := range tests {
+		ast, err := Parse(test.source)
+		assert.Nil(t, err, test.source)
+		if err == nil {
+			assert.Equal(t, test.where, parenthesize(ast.Statements[0].SelectStatement.Where), test.source)
+		}
+	}
+
+	_, err := Parse("select * from t where a is 1")
+	assert.EqualError(t, err, "Expected NULL, got 1 at 0:27")
+}
+
+func TestParse_transaction(t *testing.T) {
+	ast, err := Parse("begin; commit; rollback; begin transaction; commit transaction; rollback transaction")
+	assert.Nil(t, err)
+
+	var kinds []AstKind
+	for _, stmt := range ast.Statements {
+		kinds = append(kinds, stmt.Kind)
+	}
+	assert.Equal(t, []AstKind{BeginKind, CommitKind, RollbackKind, BeginKind, CommitKind, RollbackKind}, kinds)
+
+	_, err = Parse("begin work")
+	assert.EqualError(t, err, "Expected end of statement, got work at 0:6")
+}
+
+func TestParse_explain(t *testing.T) {
+	ast, err := Parse("explain select id from t where id = 1")
+	assert.Nil(t, err)
+	assert.Equal(t, ExplainKind, ast.Statements[0].Kind)
+	slct := ast.Statements[0].ExplainStatement.Select
+	assert.Equal(t, "t", slct.From.Value)
+	assert.NotNil(t, slct.Where)
+	assert.False(t, ast.Statements[0].ExplainStatement.Analyze)
+
+	ast, err = Parse("explain analyze select id from t")
+	assert.Nil(t, err)
+	assert.True(t, ast.Statements[0].ExplainStatement.Analyze)
+
+	_, err = Parse("explain delete from t")
+	assert.EqualError(t, err, "Expected SELECT, got delete at 0:8")
+}
+
+func TestParseStatements(t *testing.T) {
+	stmts, err := ParseStatements("select a from t; explain select (((a))) from t")
+	assert.Nil(t, err)
+	assert.Len(t, stmts, 2)
+	assert.Equal(t, ExplainKind, stmts[1].Kind)
+
+	deep := strings.Repeat("(", 500) + "1" + strings.Repeat(")", 500)
+	_, err = ParseStatements("select " + deep + " from t")
+	assert.Nil(t, err)
+
+	for _, source := range []string{
+		"select " + strings.Repeat("(", 1001) + "1" + strings.Repeat(")", 1001),
+		"select " + strings.Repeat("- ", 5000) + "1",
+		"select 1 where " + strings.Repeat("not ", 5000) + "true",
+		"select " + strings.Repeat("case when true then ", 1001) + "1",
+	} {
+		_, err = ParseStatements(source)
+		assert.ErrorIs(t, err, ErrNestedTooDeeply)
+		assert.Equal(t, ResourceError, Code(err))
+		assert.Equal(t, "54001", SQLState(err))
+	}
+
+	_, err = ParseStatements("select 'unterminated")
+	var e *Error
+	assert.ErrorAs(t, err, &e)
+}
+
+func FuzzParse(f *testing.F) {
+	seeds := []string{
+		"select a from t where a = 1",
+		"select case when a then b else c end from t",
+		"insert into t values (1, 'a'), (2, E'\\n')",
+		"create table t (id int primary key, b text default 'x')",
+		"select * from a join b on a.x = b.y order by 1 limit 2",
+		"update t set a = -a + 1 where not b in (select c from d)",
+		"explain analyze select 1",
+		"with x as (select 1) select * from x union select 2",
+		"select row_number() over (partition by a order by b) from t",
+		"copy t from 'x' csv header",
+		"select cast(a as int), a::text, date '2020-01-01' from t",
+		"select ((((",
+		"",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, source string) {
+		// Parse does not recover from panics the way ParseStatements
+		// does, so any panic of the lexer or the parser fails the fuzzer
+		// rather than turning into an error. Nesting is checked first to
+		// keep deep input from exhausting the stack.
+		if tokens, err := lex(source); err == nil && checkNesting(tokens) == nil {
+			Parse(source)
+		}
+
+		_, err := ParseStatements(source)
+		var e *Error
+		if err != nil && !errors.As(err, &e) {
+			t.Errorf("ParseStatements(%q) returned %T, not an *Error", source, err)
+		}
+	})
+}
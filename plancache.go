@@ -0,0 +1,143 @@
+package gosql
+
+import (
+	"container/list"
+	"sync"
+)
+
+// defaultPlanCacheSize is how many queries a MemoryBackend keeps the plans
+// of.
+const defaultPlanCacheSize = 256
+
+// planChoices are the decisions the planner makes from the statistics:
+// how to read each table and the order to join them in. Making them again
+// from the cache skips weighing every index and join order.
+type planChoices struct {
+	// access holds, for each relation, the position among its conditions
+	// of the one its index scan uses, or -1 for a sequential scan.
+	access []int
+	// order is the order joinOrder joins the relations in, or nil when
+	// they are joined as written.
+	order []int
+}
+
+// PlanCacheStats counts how often queries found the plan of an earlier
+// run of the same query in the cache.
+type PlanCacheStats struct {
+	Hits   uint64
+	Misses uint64
+	// Invalidations counts the plans dropped because the schema changed
+	// or a table they read was analyzed again since they were made.
+	Invalidations uint64
+	// Size is how many plans the cache holds.
+	Size int
+}
+
+// planCache keeps the planChoices of the queries run most recently, found
+// by their text. A plan stays valid while the schema stays the same and
+// the tables it reads keep the statistics it was made from. Tables are
+// analyzed again once their rows double or halve, so plans outlive small
+// changes but not drift large enough to make them doubtful. It is safe for
+// concurrent use.
+type planCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+	stats   PlanCacheStats
+}
+
+type planCacheEntry struct {
+	key string
+	// schema is the version of the schema the plan was made against, and
+	// stats the statistics of each of its relations.
+	schema  uint64
+	stats   []*tableStats
+	choices *planChoices
+}
+
+func newPlanCache(size int) *planCache {
+	return &planCache{size: size, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// get returns the choices cached for the query key, reading rels, when
+// they are still valid against schema.
+func (c *planCache) get(key string, schema uint64, rels []*relation) (*planChoices, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	entry := e.Value.(*planCacheEntry)
+	if entry.schema != schema || !sameStats(entry.stats, relationStats(rels)) {
+		c.order.Remove(e)
+		delete(c.entries, key)
+		c.stats.Invalidations++
+		c.stats.Misses++
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	c.stats.Hits++
+	return entry.choices, true
+}
+
+// put caches the choices made for the query key, reading rels.
+func (c *planCache) put(key string, schema uint64, rels []*relation, choices *planChoices) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+	}
+	c.entries[key] = c.order.PushFront(&planCacheEntry{
+		key:     key,
+		schema:  schema,
+		stats:   relationStats(rels),
+		choices: choices,
+	})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*planCacheEntry).key)
+	}
+}
+
+func (c *planCache) counters() PlanCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.order.Len()
+	return stats
+}
+
+// relationStats lists the statistics of rels, nil for those that are not
+// stored tables or have not been analyzed.
+func relationStats(rels []*relation) []*tableStats {
+	stats := make([]*tableStats, len(rels))
+	for i, rel := range rels {
+		if rel.t != nil {
+			stats[i] = rel.t.stats
+		}
+	}
+	return stats
+}
+
+func sameStats(a, b []*tableStats) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// PlanCacheStats returns the counters of the cache of query plans.
+func (mb *MemoryBackend) PlanCacheStats() PlanCacheStats {
+	return mb.plans.counters()
+}
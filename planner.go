@@ -0,0 +1,716 @@
+package gosql
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+)
+
+// defaultSelectivity is the share of rows a condition is assumed to keep
+// when the statistics cannot tell.
+const defaultSelectivity = 1.0 / 3
+
+// tableStats describes the rows of a stored table for the planner. It is
+// gathered by analyze and may lag behind the table, so it is only ever
+// used for estimates.
+type tableStats struct {
+	// versions is how many versions the table had when it was analyzed.
+	versions int
+	rows     int
+	// distinct and nulls count the distinct non-NULL values and the NULLs
+	// of each column.
+	distinct []int
+	nulls    []int
+}
+
+// analyze gathers the statistics of t from the versions that are not
+// deleted or rolled back.
+func (t *table) analyze() {
+	stats := &tableStats{
+		versions: len(t.versions),
+		distinct: make([]int, len(t.columns)),
+		nulls:    make([]int, len(t.columns)),
+	}
+	seen := make([]map[string]bool, len(t.columns))
+	for i := range seen {
+		seen[i] = map[string]bool{}
+	}
+	for _, v := range t.versions {
+		if v.xmin == abortedXID || v.xmax != 0 {
+			continue
+		}
+		stats.rows++
+		for i, cell := range v.cells {
+			if cell.IsNull() {
+				stats.nulls[i]++
+			} else {
+				seen[i][string(cell)] = true
+			}
+		}
+	}
+	for i := range seen {
+		stats.distinct[i] = len(seen[i])
+	}
+	t.stats = stats
+}
+
+// stale reports whether t has grown or shrunk enough since it was
+// analyzed to need analyzing again. Waiting for the number of versions to
+// double or halve keeps the work in proportion to the writes.
+func (t *table) stale() bool {
+	return t.stats == nil || len(t.versions) >= 2*t.stats.versions || len(t.versions) < t.stats.versions/2
+}
+
+// planNode is one operator of the plan for a query. Rows estimates how
+// many rows it produces and cost how much work that takes in all, counted
+// in rows handled. The executor runs the operators from the leaves up.
+type planNode struct {
+	operator string
+	details  []string
+	rows     int
+	cost     float64
+	children []*planNode
+	// run produces the rows of the operators planFrom builds. It is nil
+	// for the ones above them, which query runs itself.
+	run func(ctx context.Context) (*table, error)
+	// actual is what the operator did, once EXPLAIN ANALYZE measures it.
+	actual *nodeStats
+}
+
+// relation is a table the FROM clause reads, either stored or the result
+// of a subquery.
+type relation struct {
+	// name is what its columns are qualified with, and target what the
+	// plan calls it.
+	name, target string
+	// t is the view of a stored table, or nil for a subquery.
+	t *table
+	// source reads every row.
+	source *planNode
+	// conds are the conditions that only involve this relation.
+	conds []*Expression
+}
+
+// condition is a condition of a query with the relations it involves.
+type condition struct {
+	exp  *Expression
+	refs map[int]bool
+}
+
+// planFrom plans the FROM clause, the joins and WHERE of slct, whose
+// subqueries must be resolved before it runs. WHERE is pushed down to the
+// tables it involves when every join is an inner join, which frees the
+// planner to use their indexes and to join them in any order. The choices
+// made for a query are cached and made the same way the next time it runs
+// while they are still valid. Its hints override the choices they name.
+func (mb *MemoryBackend) planFrom(snap *txSnapshot, slct *SelectStatement) (*planNode, error) {
+	rels, err := mb.relations(snap, slct)
+	if err != nil {
+		return nil, err
+	}
+
+	hints, err := newPlanHints(slct.Hints, rels)
+	if err != nil {
+		return nil, err
+	}
+
+	key := strings.Join(formatSelect(slct), "\n")
+	e := &estimator{rels: rels, hints: hints}
+	if choices, ok := mb.plans.get(key, mb.schemaVersion, rels); ok {
+		e.choices, e.replay = choices, true
+	} else {
+		e.choices = &planChoices{access: make([]int, len(rels))}
+		defer mb.plans.put(key, mb.schemaVersion, rels, e.choices)
+	}
+
+	if len(slct.Join) == 0 {
+		return e.access(0, conjuncts(slct.Where)), nil
+	}
+
+	if conds, ok := e.conditions(slct); ok {
+		return e.joinOrder(conds), nil
+	}
+
+	node := rels[0].source
+	joined := map[int]bool{0: true}
+	for i, j := range slct.Join {
+		node = e.join(node, rels[i+1].source, j.Kind, j.On, e.joinKeys(joined, i+1, j.On))
+		joined[i+1] = true
+	}
+	if slct.Where != nil {
+		node = e.filter(node, slct.Where, node.rows)
+	}
+	return node, nil
+}
+
+// relations prepares the tables slct reads, in the order it names them.
+func (mb *MemoryBackend) relations(snap *txSnapshot, slct *SelectStatement) ([]*relation, error) {
+	var rels []*relation
+	var rel *relation
+	var err error
+	switch {
+	case slct.From == nil:
+		rel, err = mb.subqueryRelation(snap, slct.FromSelect, slct.FromAs, nil)
+	case slct.FromSelect != nil:
+		rel, err = mb.subqueryRelation(snap, slct.FromSelect, slct.From, slct.FromAs)
+	default:
+		rel, err = mb.relation(snap, slct.From, slct.FromAs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rels = append(rels, rel)
+
+	for _, j := range slct.Join {
+		if j.Select != nil {
+			rel, err = mb.subqueryRelation(snap, j.Select, j.Table, j.As)
+		} else {
+			rel, err = mb.relation(snap, j.Table, j.As)
+		}
+		if err != nil {
+			return nil, err
+		}
+		rels = append(rels, rel)
+	}
+	return rels, nil
+}
+
+func (mb *MemoryBackend) relation(snap *txSnapshot, name, as *Token) (*relation, error) {
+	if slct, ok := mb.views[name.Value]; ok {
+		return mb.subqueryRelation(snap, slct, name, as)
+	}
+
+	var t *table
+	if view, ok := informationSchema[name.Value]; ok {
+		t = view.table(mb.schema())
+	} else {
+		stored, ok := mb.tables[name.Value]
+		if !ok {
+			return nil, ErrTableDoesNotExist
+		}
+		t = stored.visibleTo(snap)
+	}
+	t = t.aliased(as)
+
+	rel := &relation{name: t.name, target: name.Value, t: t}
+	if as != nil {
+		rel.name = as.Value
+		rel.target += " " + as.Value
+	}
+	rel.source = &planNode{
+		operator: "Seq Scan on " + rel.target,
+		rows:     len(t.rows),
+		cost:     float64(len(t.rows)),
+		run: func(ctx context.Context) (*table, error) {
+			statementMetrics(ctx).scanned(len(t.rows))
+			return t, nil
+		},
+	}
+	return rel, nil
+}
+
+// estimator estimates the rows the operators of a query produce, from the
+// statistics of the tables it reads.
+type estimator struct {
+	rels  []*relation
+	hints planHints
+	// choices records the choices made, or holds those to make again when
+	// replay is set.
+	choices *planChoices
+	replay  bool
+}
+
+// access plans reading relation i and keeping the rows for which all of
+// conds hold. An index scan replaces the sequential one when one of conds
+// can use an index and it is expected to cost less than reading every row,
+// or whatever it costs when a hint names the index.
+func (e *estimator) access(i int, conds []*Expression) *planNode {
+	rel := e.rels[i]
+	node := rel.source
+	chosen := -1
+	if t, forced := e.hints.table(rel); t != nil {
+		rows := float64(len(t.rows))
+		for j, cond := range conds {
+			if e.replay && j != e.choices.access[i] {
+				continue
+			}
+			r, ok := t.indexRange(cond)
+			if !ok {
+				continue
+			}
+			matches := rows * e.selectivity(cond)
+			if cost := math.Log2(rows+1) + matches; cost < node.cost || e.replay || forced && chosen == -1 {
+				node = indexScanNode(rel, r, cond, matches, cost)
+				chosen = j
+			}
+		}
+	}
+	// Cached choices are shared by the queries that replay them.
+	if !e.replay {
+		e.choices.access[i] = chosen
+	}
+
+	if len(conds) == 0 {
+		return node
+	}
+	return e.filter(node, conjunction(conds), rel.source.rows)
+}
+
+func indexScanNode(rel *relation, r *indexRange, cond *Expression, matches, cost float64) *planNode {
+	return &planNode{
+		operator: "Index Scan using " + r.idx.name + " on " + rel.target,
+		details:  []string{"Index Cond: " + formatExpression(cond)},
+		rows:     int(math.Ceil(matches)),
+		cost:     cost,
+		run: func(ctx context.Context) (*table, error) {
+			scanned := *rel.t
+			scanned.rows = nil
+			scanned.rowOf = nil
+			scanned.indexes = nil
+			for _, i := range r.rows(rel.t) {
+				scanned.rows = append(scanned.rows, rel.t.rows[i])
+			}
+			statementMetrics(ctx).scanned(len(scanned.rows))
+			return &scanned, nil
+		},
+	}
+}
+
+// filter keeps the rows of child for which where holds, estimating how
+// many from the rows where applies to before any of it was used to narrow
+// them.
+func (e *estimator) filter(child *planNode, where *Expression, rows int) *planNode {
+	return &planNode{
+		operator: "Filter",
+		details:  []string{"Condition: " + formatExpression(where)},
+		rows:     estimate(rows, e.selectivity(where)),
+		cost:     child.cost + float64(child.rows),
+		children: []*planNode{child},
+		run: func(ctx context.Context) (*table, error) {
+			t, err := child.run(ctx)
+			if err != nil {
+				return nil, err
+			}
+			filtered := *t
+			filtered.rows, err = t.filter(ctx, where)
+			return &filtered, err
+		},
+	}
+}
+
+var joinOperators = map[JoinKind]string{
+	InnerJoin: "Nested Loop Join",
+	LeftJoin:  "Nested Loop Left Join",
+	RightJoin: "Nested Loop Right Join",
+}
+
+var hashJoinOperators = map[JoinKind]string{
+	InnerJoin: "Hash Join",
+	LeftJoin:  "Hash Left Join",
+	RightJoin: "Hash Right Join",
+}
+
+// join plans a join of left and right on the condition on, which may be
+// nil to pair every row with every other. It is a hash join on keys, the
+// equalities of on that compare the two sides, when there are any and
+// hashing the rows of right costs less than checking every pair, and a
+// nested loop join otherwise. The HASH_JOIN and NO_HASH_JOIN hints make
+// that choice instead.
+func (e *estimator) join(left, right *planNode, kind JoinKind, on *Expression, keys []joinKey) *planNode {
+	hash := len(keys) > 0 && left.rows+2*right.rows < left.rows*right.rows
+	switch e.hints.join {
+	case hashJoinHint:
+		hash = len(keys) > 0
+	case noHashJoinHint:
+		hash = false
+	}
+	node := &planNode{
+		operator: joinOperators[kind],
+		rows:     left.rows * right.rows,
+		cost:     left.cost + right.cost + float64(left.rows*right.rows),
+		children: []*planNode{left, right},
+		run: func(ctx context.Context) (*table, error) {
+			l, err := left.run(ctx)
+			if err != nil {
+				return nil, err
+			}
+			r, err := right.run(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if hash {
+				return hashJoinTables(ctx, l, r, kind, on, keys)
+			}
+			return joinTables(ctx, l, r, kind, on)
+		},
+	}
+	if on != nil {
+		node.details = []string{"Join Filter: " + formatExpression(on)}
+		node.rows = estimate(node.rows, e.selectivity(on))
+	}
+	if hash {
+		node.operator = hashJoinOperators[kind]
+		node.cost = left.cost + right.cost + float64(left.rows+2*right.rows)
+		var conds, rest []*Expression
+		for _, cond := range conjuncts(on) {
+			rest = append(rest, cond)
+			for _, key := range keys {
+				if key.cond == cond {
+					conds = append(conds, cond)
+					rest = rest[:len(rest)-1]
+					break
+				}
+			}
+		}
+		node.details = []string{"Hash Cond: " + formatExpression(conjunction(conds))}
+		if len(rest) > 0 {
+			node.details = append(node.details, "Join Filter: "+formatExpression(conjunction(rest)))
+		}
+	}
+	if kind == LeftJoin && node.rows < left.rows {
+		node.rows = left.rows
+	}
+	if kind == RightJoin && node.rows < right.rows {
+		node.rows = right.rows
+	}
+	return node
+}
+
+// conditions splits the ON conditions and WHERE of slct into the
+// conditions they are made of, handing those that involve a single stored
+// table to it. It reports false when the joins have to run in the order
+// written: when one is an outer join, when a subquery is among the tables,
+// or when it is not clear which table a column belongs to.
+func (e *estimator) conditions(slct *SelectStatement) ([]*condition, bool) {
+	if slct.FromSelect != nil {
+		return nil, false
+	}
+	exps := conjuncts(slct.Where)
+	for _, j := range slct.Join {
+		if j.Kind != InnerJoin {
+			return nil, false
+		}
+		exps = append(exps, conjuncts(j.On)...)
+	}
+
+	var conds []*condition
+	for _, exp := range exps {
+		refs, ok := e.references(exp)
+		if !ok {
+			return nil, false
+		}
+		conds = append(conds, &condition{exp: exp, refs: refs})
+	}
+
+	var joinConds []*condition
+	for _, cond := range conds {
+		if len(cond.refs) != 1 {
+			joinConds = append(joinConds, cond)
+			continue
+		}
+		for i := range cond.refs {
+			e.rels[i].conds = append(e.rels[i].conds, cond.exp)
+		}
+	}
+	return joinConds, true
+}
+
+// references finds the relations whose columns exp reads. It reports false
+// when a column matches no relation or more than one.
+func (e *estimator) references(exp *Expression) (map[int]bool, bool) {
+	refs := map[int]bool{}
+	ok := true
+	_, _ = rewriteExpression(exp, func(exp *Expression) (*Expression, error) {
+		matches := -1
+		switch {
+		case exp.Kind == LiteralKind && exp.Literal.Kind == IdentifierKind:
+			for i, rel := range e.rels {
+				if rel.t.columnIndex(exp.Literal.Value) != -1 {
+					if matches != -1 {
+						ok = false
+					}
+					matches = i
+				}
+			}
+		case exp.Kind == ColumnReferenceKind:
+			for i, rel := range e.rels {
+				if rel.name == exp.Column.Table.Value {
+					if matches != -1 {
+						ok = false
+					}
+					matches = i
+				}
+			}
+		default:
+			return nil, nil
+		}
+
+		if matches == -1 {
+			ok = false
+		} else {
+			refs[matches] = true
+		}
+		return exp, nil
+	})
+	return refs, ok
+}
+
+// joinOrder plans inner joins of all the relations, applying each of conds
+// as soon as the relations it involves have been joined. It starts from the
+// relation expected to produce the fewest rows and adds the others one at
+// a time, each time the one whose join is expected to produce the fewest,
+// leaving the relations no condition connects yet until last.
+func (e *estimator) joinOrder(conds []*condition) *planNode {
+	scans := make([]*planNode, len(e.rels))
+	for i, rel := range e.rels {
+		scans[i] = e.access(i, rel.conds)
+	}
+
+	joined := map[int]bool{}
+	applied := make([]bool, len(conds))
+	// applicable returns the conditions that can be applied once rel joins.
+	applicable := func(rel int) []int {
+		var found []int
+		for i, cond := range conds {
+			if applied[i] {
+				continue
+			}
+			ready := true
+			for r := range cond.refs {
+				if !joined[r] && r != rel {
+					ready = false
+				}
+			}
+			if ready {
+				found = append(found, i)
+			}
+		}
+		return found
+	}
+
+	first := 0
+	for i, scan := range scans {
+		if scan.rows < scans[first].rows {
+			first = i
+		}
+	}
+	if e.replay {
+		first = e.choices.order[0]
+	}
+	node := scans[first]
+	joined[first] = true
+	order := []int{first}
+
+	for len(order) < len(e.rels) {
+		next, nextConds, nextRows := -1, []int(nil), 0
+		for i, scan := range scans {
+			if joined[i] || e.replay && i != e.choices.order[len(order)] {
+				continue
+			}
+			found := applicable(i)
+			sel := 1.0
+			for _, c := range found {
+				sel *= e.selectivity(conds[c].exp)
+			}
+			rows := estimate(node.rows*scan.rows, sel)
+			better := next == -1 ||
+				(len(found) > 0 && len(nextConds) == 0) ||
+				((len(found) > 0) == (len(nextConds) > 0) && rows < nextRows)
+			if better {
+				next, nextConds, nextRows = i, found, rows
+			}
+		}
+
+		var on []*Expression
+		for _, c := range nextConds {
+			applied[c] = true
+			on = append(on, conds[c].exp)
+		}
+		node = e.join(node, scans[next], InnerJoin, conjunction(on), e.joinKeys(joined, next, conjunction(on)))
+		joined[next] = true
+		order = append(order, next)
+	}
+	if !e.replay {
+		e.choices.order = order
+	}
+
+	// Conditions on no table at all are left for the end.
+	var rest []*Expression
+	for i, cond := range conds {
+		if !applied[i] {
+			rest = append(rest, cond.exp)
+		}
+	}
+	if len(rest) > 0 {
+		node = e.filter(node, conjunction(rest), node.rows)
+	}
+
+	if !sort.IntsAreSorted(order) {
+		node = e.restoreOrder(node, order)
+	}
+	return node
+}
+
+// restoreOrder puts the columns node produces back in the order the query
+// names its tables, after joinOrder has joined them in order.
+func (e *estimator) restoreOrder(node *planNode, order []int) *planNode {
+	start := make([]int, len(e.rels))
+	offset := 0
+	for _, rel := range order {
+		start[rel] = offset
+		offset += len(e.rels[rel].t.columns)
+	}
+	var columns []int
+	for rel := range e.rels {
+		for i := range e.rels[rel].t.columns {
+			columns = append(columns, start[rel]+i)
+		}
+	}
+
+	restored := *node
+	restored.run = func(ctx context.Context) (*table, error) {
+		t, err := node.run(ctx)
+		if err != nil {
+			return nil, err
+		}
+		reordered := &table{}
+		for _, i := range columns {
+			reordered.columns = append(reordered.columns, t.columns[i])
+			reordered.columnTypes = append(reordered.columnTypes, t.columnTypes[i])
+			reordered.columnTables = append(reordered.columnTables, t.columnTable(i))
+			reordered.collations = append(reordered.collations, t.collation(i))
+		}
+		for _, row := range t.rows {
+			cells := make([]MemoryCell, len(columns))
+			for j, i := range columns {
+				cells[j] = row[i]
+			}
+			reordered.rows = append(reordered.rows, cells)
+		}
+		return reordered, nil
+	}
+	return &restored
+}
+
+// selectivity estimates the share of rows for which exp holds.
+func (e *estimator) selectivity(exp *Expression) float64 {
+	switch exp.Kind {
+	case BinaryKind:
+		b := exp.Binary
+		if b.Op.Kind == KeywordKind {
+			switch keyword(b.Op.Value) {
+			case AndKeyword:
+				return e.selectivity(b.Left) * e.selectivity(b.Right)
+			case OrKeyword:
+				l, r := e.selectivity(b.Left), e.selectivity(b.Right)
+				return l + r - l*r
+			}
+		}
+		if b.Op.Kind == SymbolKind {
+			switch Symbol(b.Op.Value) {
+			case EqSymbol:
+				return e.equality(b.Left, b.Right)
+			case NeqSymbol, BangEqSymbol:
+				return 1 - e.equality(b.Left, b.Right)
+			}
+		}
+	case UnaryKind:
+		if keyword(exp.Unary.Op.Value) == NotKeyword {
+			return 1 - e.selectivity(exp.Unary.Operand)
+		}
+	case IsNullKind:
+		if stats, i, ok := e.stats(exp.IsNull.Operand); ok {
+			nulls := float64(stats.nulls[i]) / float64(stats.rows)
+			if exp.IsNull.Not {
+				return 1 - nulls
+			}
+			return nulls
+		}
+	case InKind:
+		if exp.In.Select == nil {
+			sel := float64(len(exp.In.List)) * e.equality(exp.In.Left, nil)
+			if sel > 1 {
+				sel = 1
+			}
+			return sel
+		}
+	}
+	return defaultSelectivity
+}
+
+// equality estimates the share of rows for which a equals b, assuming
+// values are spread evenly over the distinct values of the columns. When
+// both are columns, each value of the one with fewer distinct values is
+// assumed to occur in the other.
+func (e *estimator) equality(a, b *Expression) float64 {
+	distinct := 0
+	for _, exp := range []*Expression{a, b} {
+		if exp == nil {
+			continue
+		}
+		if stats, i, ok := e.stats(exp); ok && stats.distinct[i] > distinct {
+			distinct = stats.distinct[i]
+		}
+	}
+	if distinct == 0 {
+		return defaultSelectivity
+	}
+	return 1 / float64(distinct)
+}
+
+// stats finds the statistics of the column exp refers to, if it is a
+// column of an analyzed table that is not empty.
+func (e *estimator) stats(exp *Expression) (*tableStats, int, bool) {
+	for _, rel := range e.rels {
+		if rel.t == nil || rel.t.stats == nil || rel.t.stats.rows == 0 {
+			continue
+		}
+		i, err := rel.t.resolveColumn(exp)
+		if err != nil || i == -1 {
+			continue
+		}
+		if i >= len(rel.t.stats.distinct) {
+			return nil, 0, false
+		}
+		return rel.t.stats, i, true
+	}
+	return nil, 0, false
+}
+
+// estimate applies the selectivity sel to rows, rounding up so a non-empty
+// input is never estimated to come out empty.
+func estimate(rows int, sel float64) int {
+	return int(math.Ceil(float64(rows) * sel))
+}
+
+// conjuncts splits exp into the conditions joined by AND at its top.
+func conjuncts(exp *Expression) []*Expression {
+	if exp == nil {
+		return nil
+	}
+	if exp.Kind == BinaryKind && exp.Binary.Op.Kind == KeywordKind && keyword(exp.Binary.Op.Value) == AndKeyword {
+		return append(conjuncts(exp.Binary.Left), conjuncts(exp.Binary.Right)...)
+	}
+	return []*Expression{exp}
+}
+
+// conjunction joins exps with AND, or returns nil if there are none.
+func conjunction(exps []*Expression) *Expression {
+	var exp *Expression
+	for _, e := range exps {
+		if exp == nil {
+			exp = e
+			continue
+		}
+		exp = &Expression{
+			Kind: BinaryKind,
+			Binary: &BinaryExpression{
+				Left:  exp,
+				Right: e,
+				Op:    &Token{Kind: KeywordKind, Value: string(AndKeyword)},
+			},
+		}
+	}
+	return exp
+}
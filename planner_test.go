@@ -0,0 +1,213 @@
+package gosql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTable_analyze(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table users (id int, name text);"+
+		"insert into users values (1, 'alice');"+
+		"insert into users values (2, 'bob');"+
+		"insert into users values (3, NULL);"+
+		"insert into users values (4, 'alice');")
+	assert.Nil(t, err)
+
+	users := mb.tables["users"]
+	users.analyze()
+	assert.Equal(t, 4, users.stats.rows)
+	assert.Equal(t, []int{4, 2}, users.stats.distinct)
+	assert.Equal(t, []int{0, 1}, users.stats.nulls)
+
+	_, err = execute(t, mb, "delete from users where name = 'alice'")
+	assert.Nil(t, err)
+	users.analyze()
+	assert.Equal(t, 2, users.stats.rows)
+	assert.Equal(t, []int{2, 1}, users.stats.distinct)
+
+	// Committing analyzes the tables that have grown enough.
+	for i := 5; i <= 20; i++ {
+		_, err = execute(t, mb, fmt.Sprintf("insert into users values (%d, 'user%d')", i, i))
+		assert.Nil(t, err)
+	}
+	assert.False(t, users.stale())
+	assert.True(t, users.stats.rows > 2)
+}
+
+func TestMemoryBackend_planner(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table countries (id int primary key, name text);"+
+		"create table users (id int primary key, name text, country int);"+
+		"create table orders (id int primary key, buyer int, total int);"+
+		"insert into countries values (1, 'fr');"+
+		"insert into countries values (2, 'de');")
+	assert.Nil(t, err)
+	for i := 1; i <= 10; i++ {
+		_, err = execute(t, mb, fmt.Sprintf("insert into users values (%d, 'user%d', %d)", i, i, i%2+1))
+		assert.Nil(t, err)
+	}
+	for i := 1; i <= 30; i++ {
+		_, err = execute(t, mb, fmt.Sprintf("insert into orders values (%d, %d, %d)", i, i%10+1, i*10))
+		assert.Nil(t, err)
+	}
+
+	explain := func(source string) string {
+		results, err := execute(t, mb, "explain "+source)
+		assert.Nil(t, err, source)
+		var lines []string
+		for _, row := range results.Rows {
+			lines = append(lines, row[0].AsText())
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	// Reading both rows of a table is cheaper than using its index.
+	assert.Contains(t, explain("select * from countries where id = 1"), "Seq Scan on countries")
+	assert.Contains(t, explain("select * from users where id = 1"), "Index Scan using users_pkey on users")
+
+	// The joins start from the country WHERE narrows down and WHERE uses
+	// the index on orders. Joining the one country to users takes fewer
+	// steps with a nested loop than with a hash join, but joining orders
+	// does not.
+	assert.Equal(t, `Projection  (cost=78.95 rows=5)
+  Output: o.id, u.name
+  ->  Hash Join  (cost=73.95 rows=5)
+        Hash Cond: (o.buyer = u.id)
+        ->  Nested Loop Join  (cost=24.00 rows=5)
+              Join Filter: (u.country = c.id)
+              ->  Filter  (cost=4.00 rows=1)
+                    Condition: (c.name = 'fr')
+                    ->  Seq Scan on countries c  (cost=2.00 rows=2)
+              ->  Seq Scan on users u  (cost=10.00 rows=10)
+        ->  Filter  (cost=24.95 rows=10)
+              Condition: (o.id >= 25)
+              ->  Index Scan using orders_pkey on orders o  (cost=14.95 rows=10)
+                    Index Cond: (o.id >= 25)`, explain("select o.id, u.name from orders o join users u on o.buyer = u.id join countries c on u.country = c.id where c.name = 'fr' and o.id >= 25"))
+
+	// Joining in another order than written leaves the columns in place.
+	results, err := execute(t, mb, "select * from orders o join users u on o.buyer = u.id join countries c on u.country = c.id where c.name = 'fr' and o.id < 3")
+	assert.Nil(t, err)
+	var columns []string
+	for _, col := range results.Columns {
+		columns = append(columns, col.Name)
+	}
+	assert.Equal(t, []string{"id", "buyer", "total", "id", "name", "country", "id", "name"}, columns)
+	assert.Equal(t, [][]Cell{
+		{intCell(1), intCell(2), intCell(10), intCell(2), MemoryCell("user2"), intCell(1), intCell(1), MemoryCell("fr")},
+	}, results.Rows)
+
+	// Unqualified columns are pushed down to the table they belong to.
+	results, err = execute(t, mb, "select total from orders join users on buyer = country where name = 'user3' and total < 40")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(10)}}, results.Rows)
+}
+
+func TestMemoryBackend_planCache(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table users (id int primary key, name text);"+
+		"create table orders (id int primary key, buyer int)")
+	assert.Nil(t, err)
+	for i := 1; i <= 10; i++ {
+		_, err = execute(t, mb, fmt.Sprintf("insert into users values (%d, 'user%d'); insert into orders values (%d, %d)", i, i, i, i%3+1))
+		assert.Nil(t, err)
+	}
+
+	query := func(source string) [][]Cell {
+		results, err := execute(t, mb, source)
+		assert.Nil(t, err, source)
+		return results.Rows
+	}
+	// counts returns the hits, misses and invalidations since the last
+	// call.
+	last := mb.PlanCacheStats()
+	counts := func() [3]uint64 {
+		stats := mb.PlanCacheStats()
+		defer func() { last = stats }()
+		return [3]uint64{stats.Hits - last.Hits, stats.Misses - last.Misses, stats.Invalidations - last.Invalidations}
+	}
+
+	join := "select u.name from orders o join users u on o.buyer = u.id where o.id < 3 order by u.name"
+	assert.Equal(t, [][]Cell{{MemoryCell("user2")}, {MemoryCell("user3")}}, query(join))
+	assert.Equal(t, [3]uint64{0, 1, 0}, counts())
+	assert.Equal(t, [][]Cell{{MemoryCell("user2")}, {MemoryCell("user3")}}, query(join))
+	assert.Equal(t, [][]Cell{{MemoryCell("user2")}, {MemoryCell("user3")}}, query(join))
+	assert.Equal(t, [3]uint64{2, 0, 0}, counts())
+
+	// A new index may make a better plan.
+	_, err = execute(t, mb, "create index users_name on users (name)")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{MemoryCell("user2")}, {MemoryCell("user3")}}, query(join))
+	assert.Equal(t, [3]uint64{0, 1, 1}, counts())
+
+	// So may twice as many rows, but not a few more.
+	_, err = execute(t, mb, "insert into orders values (11, 1)")
+	assert.Nil(t, err)
+	query(join)
+	assert.Equal(t, [3]uint64{1, 0, 0}, counts())
+	for i := 12; i <= 25; i++ {
+		_, err = execute(t, mb, fmt.Sprintf("insert into orders values (%d, 1)", i))
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, [][]Cell{{MemoryCell("user2")}, {MemoryCell("user3")}}, query(join))
+	assert.Equal(t, [3]uint64{0, 1, 1}, counts())
+	assert.Equal(t, 1, mb.PlanCacheStats().Size)
+}
+
+func TestMemoryBackend_explainAnalyze(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, `create table items (id int primary key, name text, team int);
+create table teams (id int primary key, name text);
+insert into teams values (1, 'red'), (2, 'blue');
+insert into items values (1, 'pen', 1), (2, 'ink', 1), (3, 'cap', 2), (4, 'pen', 2)`)
+	assert.Nil(t, err)
+
+	times := regexp.MustCompile(`[0-9]+\.[0-9]{3} ms`)
+	costs := regexp.MustCompile(`cost=[0-9.]+ rows=[0-9]+`)
+	explain := func(source string) string {
+		results, err := execute(t, mb, "explain analyze "+source)
+		assert.Nil(t, err)
+		var lines []string
+		for _, row := range results.Rows {
+			lines = append(lines, times.ReplaceAllString(row[0].AsText(), "T"))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	assert.Equal(t, `Limit  (cost=12.83 rows=2) (actual time=T rows=2 loops=1)
+  Count: 2
+  ->  Unique  (cost=12.83 rows=2) (actual time=T rows=2 loops=1)
+        ->  Projection  (cost=10.83 rows=2) (actual time=T rows=2 loops=1)
+              Output: name
+              ->  Sort  (cost=8.83 rows=2) (actual time=T rows=3 loops=1)
+                    Sort Key: name
+                    ->  Filter  (cost=5.66 rows=2) (actual time=T rows=3 loops=1)
+                          Condition: (id > 1)
+                          ->  Index Scan using items_pkey on items  (cost=3.66 rows=2) (actual time=T rows=3 loops=1)
+                                Index Cond: (id > 1)
+Planning Time: T
+Execution Time: T`, explain("select distinct name from items where id > 1 order by name limit 2"))
+
+	// The subquery runs first and the plan shows its values.
+	assert.Equal(t, `Unique  (cost=X) (actual time=T rows=3 loops=1)
+  ->  Append  (cost=X) (actual time=T rows=4 loops=1)
+        ->  Projection  (cost=X) (actual time=T rows=2 loops=1)
+              Output: name
+              ->  Filter  (cost=X) (actual time=T rows=2 loops=1)
+                    Condition: (team in (2))
+                    ->  Seq Scan on items  (cost=X) (actual time=T rows=4 loops=1)
+        ->  Projection  (cost=X) (actual time=T rows=2 loops=1)
+              Output: name
+              ->  Filter  (cost=X) (actual time=T rows=2 loops=1)
+                    Condition: (team = 1)
+                    ->  Seq Scan on items  (cost=X) (actual time=T rows=4 loops=1)
+Planning Time: T
+Execution Time: T`, costs.ReplaceAllString(explain("select name from items where team in (select id from teams where name = 'blue') union select name from items where team = 1"), "cost=X"))
+
+	assert.Contains(t, explain("select s.n from (select name as n from items where team = 2) s"), `  ->  Subquery Scan on s  (cost=10.00 rows=2) (actual time=T rows=2 loops=1)
+        ->  Projection  (cost=10.00 rows=2) (actual time=T rows=2 loops=1)`)
+}
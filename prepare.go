@@ -0,0 +1,146 @@
+package gosql
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Prepared is a statement parsed once to be run any number of times with
+// different arguments for its parameters. It is validated and planned each
+// time it runs, since the tables and the rows in them may have changed.
+type Prepared struct {
+	stmt *Statement
+}
+
+// Prepare parses source, which must hold exactly one statement.
+func Prepare(ctx context.Context, source string) (*Prepared, error) {
+	ast, err := ParseContext(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	if len(ast.Statements) != 1 {
+		return nil, ErrMultipleStatements
+	}
+	return &Prepared{stmt: ast.Statements[0]}, nil
+}
+
+// Statement returns the statement as parsed, with its parameters unbound.
+// It must not be changed.
+func (p *Prepared) Statement() *Statement {
+	return p.stmt
+}
+
+// Execute binds args to the parameters of the statement as Bind does and
+// runs it on ex as ExecuteContext does.
+func (p *Prepared) Execute(ctx context.Context, ex Executor, args ...interface{}) (*StatementResult, error) {
+	stmt, err := Bind(p.stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	return ExecuteContext(ctx, ex, stmt)
+}
+
+// StatementCache prepares statements, keeping the most recently used ones
+// so that preparing the same text again skips lexing and parsing. Texts
+// that differ only in the case of keywords and identifiers or in
+// whitespace share an entry. It is safe for concurrent use.
+type StatementCache struct {
+	mu   sync.Mutex
+	size int
+	// order holds the entries from most to least recently used, and
+	// entries finds them by their normalized text.
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key      string
+	prepared *Prepared
+}
+
+// NewStatementCache returns a cache holding up to size statements.
+func NewStatementCache(size int) *StatementCache {
+	return &StatementCache{size: size, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// Prepare returns the cached statement for source, preparing and caching
+// it when there is none. Failures are not cached.
+func (c *StatementCache) Prepare(ctx context.Context, source string) (*Prepared, error) {
+	key := normalize(source)
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		c.mu.Unlock()
+		return e.Value.(*cacheEntry).prepared, nil
+	}
+	c.mu.Unlock()
+
+	// Parsing happens outside the lock, so two callers may both parse
+	// the same text and the second one's result is kept.
+	prepared, err := Prepare(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, prepared: prepared})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+	return prepared, nil
+}
+
+// Len returns how many statements the cache holds.
+func (c *StatementCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// normalize returns source with keywords and identifiers in lower case,
+// every run of whitespace turned into one space and any trailing
+// semicolon removed, leaving quoted strings and identifiers alone. Since
+// the lexer does the same, texts with the same normalized form parse the
+// same. Comments could end at a line break that would be lost, so source
+// holding one is returned unchanged.
+func normalize(source string) string {
+	var b strings.Builder
+	var quote, prev rune
+	space := false
+	for i, r := range source {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case strings.HasPrefix(source[i:], "--") || strings.HasPrefix(source[i:], "/*"):
+			return source
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		case !unicode.IsDigit(prev):
+			// The e of 1e5 is only an exponent in lower case.
+			r = unicode.ToLower(r)
+		}
+
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		prev = r
+		b.WriteRune(r)
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(b.String(), ";"), " ")
+}
@@ -0,0 +1,79 @@
+package gosql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepared_Execute(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table users (id int, name text)", ScriptOptions{})
+	assert.Nil(t, err)
+
+	ctx := context.Background()
+	insert, err := Prepare(ctx, "insert into users values ($1, $2)")
+	assert.Nil(t, err)
+	for i, name := range []string{"alice", "bob"} {
+		r, err := insert.Execute(ctx, mb, i+1, name)
+		assert.Nil(t, err)
+		assert.Equal(t, "INSERT 0 1", r.Tag)
+	}
+
+	slct, err := Prepare(ctx, "select name from users where id = ?")
+	assert.Nil(t, err)
+	r, err := slct.Execute(ctx, mb, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "bob", r.Results.Rows[0][0].AsText())
+
+	_, err = slct.Execute(ctx, mb)
+	assert.ErrorIs(t, err, ErrUnboundParameter)
+
+	_, err = Prepare(ctx, "select 1 from users; select 2 from users")
+	assert.Equal(t, ErrMultipleStatements, err)
+}
+
+func TestStatementCache(t *testing.T) {
+	ctx := context.Background()
+	c := NewStatementCache(2)
+
+	a, err := c.Prepare(ctx, "select * from a")
+	assert.Nil(t, err)
+	same, err := c.Prepare(ctx, "SELECT *\n\tFROM A;")
+	assert.Nil(t, err)
+	assert.True(t, a == same)
+
+	_, err = c.Prepare(ctx, "select * from b")
+	assert.Nil(t, err)
+	// a was used more recently than b, so c pushes b out.
+	_, err = c.Prepare(ctx, "select * from a")
+	assert.Nil(t, err)
+	_, err = c.Prepare(ctx, "select * from c")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, c.Len())
+
+	again, err := c.Prepare(ctx, "select * from a")
+	assert.Nil(t, err)
+	assert.True(t, a == again)
+
+	_, err = c.Prepare(ctx, "select * from")
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestNormalize(t *testing.T) {
+	for _, test := range []struct {
+		source     string
+		normalized string
+	}{
+		{"select * from a", "select * from a"},
+		{"  SELECT  *\n FROM A ;  ", "select * from a"},
+		{"select 'A  B' from \"T  x\"", "select 'A  B' from \"T  x\""},
+		{"select 'it''s  A' from a", "select 'it''s  A' from a"},
+		{"select 1E5 from a", "select 1E5 from a"},
+		{"select 1 from a -- A\nwhere b", "select 1 from a -- A\nwhere b"},
+	} {
+		assert.Equal(t, test.normalized, normalize(test.source), test.source)
+	}
+}
@@ -0,0 +1,1094 @@
+// Package gosql.v1 describes running statements on a gosql backend from
+// another process. A session lives for as long as the client keeps its id,
+// and its transaction with it, the same as a connection to the PostgreSQL
+// server.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: rpc/gosql.proto
+
+package rpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// OpenSessionRequest logs in as user with password, which the server
+// requires once the backend has users.
+type OpenSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Password      string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OpenSessionRequest) Reset() {
+	*x = OpenSessionRequest{}
+	mi := &file_rpc_gosql_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OpenSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OpenSessionRequest) ProtoMessage() {}
+
+func (x *OpenSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gosql_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OpenSessionRequest.ProtoReflect.Descriptor instead.
+func (*OpenSessionRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_gosql_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *OpenSessionRequest) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *OpenSessionRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+type OpenSessionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OpenSessionResponse) Reset() {
+	*x = OpenSessionResponse{}
+	mi := &file_rpc_gosql_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OpenSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OpenSessionResponse) ProtoMessage() {}
+
+func (x *OpenSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gosql_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OpenSessionResponse.ProtoReflect.Descriptor instead.
+func (*OpenSessionResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_gosql_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *OpenSessionResponse) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type CloseSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CloseSessionRequest) Reset() {
+	*x = CloseSessionRequest{}
+	mi := &file_rpc_gosql_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloseSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloseSessionRequest) ProtoMessage() {}
+
+func (x *CloseSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gosql_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloseSessionRequest.ProtoReflect.Descriptor instead.
+func (*CloseSessionRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_gosql_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CloseSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type CloseSessionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CloseSessionResponse) Reset() {
+	*x = CloseSessionResponse{}
+	mi := &file_rpc_gosql_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloseSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloseSessionResponse) ProtoMessage() {}
+
+func (x *CloseSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gosql_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloseSessionResponse.ProtoReflect.Descriptor instead.
+func (*CloseSessionResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_gosql_proto_rawDescGZIP(), []int{3}
+}
+
+// Value is a parameter or a cell. Dates and timestamps are sent as text in
+// the format gosql prints them in, JSON as its text and bytea as bytes.
+// A Value with no kind set is NULL, as is one with null set.
+type Value struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Kind:
+	//
+	//	*Value_Null
+	//	*Value_Int
+	//	*Value_Float
+	//	*Value_Bool
+	//	*Value_Text
+	//	*Value_Bytes
+	Kind          isValue_Kind `protobuf_oneof:"kind"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Value) Reset() {
+	*x = Value{}
+	mi := &file_rpc_gosql_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Value) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Value) ProtoMessage() {}
+
+func (x *Value) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gosql_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Value.ProtoReflect.Descriptor instead.
+func (*Value) Descriptor() ([]byte, []int) {
+	return file_rpc_gosql_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Value) GetKind() isValue_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return nil
+}
+
+func (x *Value) GetNull() bool {
+	if x != nil {
+		if x, ok := x.Kind.(*Value_Null); ok {
+			return x.Null
+		}
+	}
+	return false
+}
+
+func (x *Value) GetInt() int64 {
+	if x != nil {
+		if x, ok := x.Kind.(*Value_Int); ok {
+			return x.Int
+		}
+	}
+	return 0
+}
+
+func (x *Value) GetFloat() float64 {
+	if x != nil {
+		if x, ok := x.Kind.(*Value_Float); ok {
+			return x.Float
+		}
+	}
+	return 0
+}
+
+func (x *Value) GetBool() bool {
+	if x != nil {
+		if x, ok := x.Kind.(*Value_Bool); ok {
+			return x.Bool
+		}
+	}
+	return false
+}
+
+func (x *Value) GetText() string {
+	if x != nil {
+		if x, ok := x.Kind.(*Value_Text); ok {
+			return x.Text
+		}
+	}
+	return ""
+}
+
+func (x *Value) GetBytes() []byte {
+	if x != nil {
+		if x, ok := x.Kind.(*Value_Bytes); ok {
+			return x.Bytes
+		}
+	}
+	return nil
+}
+
+type isValue_Kind interface {
+	isValue_Kind()
+}
+
+type Value_Null struct {
+	Null bool `protobuf:"varint,1,opt,name=null,proto3,oneof"`
+}
+
+type Value_Int struct {
+	Int int64 `protobuf:"varint,2,opt,name=int,proto3,oneof"`
+}
+
+type Value_Float struct {
+	Float float64 `protobuf:"fixed64,3,opt,name=float,proto3,oneof"`
+}
+
+type Value_Bool struct {
+	Bool bool `protobuf:"varint,4,opt,name=bool,proto3,oneof"`
+}
+
+type Value_Text struct {
+	Text string `protobuf:"bytes,5,opt,name=text,proto3,oneof"`
+}
+
+type Value_Bytes struct {
+	Bytes []byte `protobuf:"bytes,6,opt,name=bytes,proto3,oneof"`
+}
+
+func (*Value_Null) isValue_Kind() {}
+
+func (*Value_Int) isValue_Kind() {}
+
+func (*Value_Float) isValue_Kind() {}
+
+func (*Value_Bool) isValue_Kind() {}
+
+func (*Value_Text) isValue_Kind() {}
+
+func (*Value_Bytes) isValue_Kind() {}
+
+type ExecuteRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	SessionId string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Sql       string                 `protobuf:"bytes,2,opt,name=sql,proto3" json:"sql,omitempty"`
+	// Params are bound to the ? and $N parameters of sql, which must then
+	// hold a single statement.
+	Params        []*Value `protobuf:"bytes,3,rep,name=params,proto3" json:"params,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecuteRequest) Reset() {
+	*x = ExecuteRequest{}
+	mi := &file_rpc_gosql_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteRequest) ProtoMessage() {}
+
+func (x *ExecuteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gosql_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteRequest.ProtoReflect.Descriptor instead.
+func (*ExecuteRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_gosql_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ExecuteRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ExecuteRequest) GetSql() string {
+	if x != nil {
+		return x.Sql
+	}
+	return ""
+}
+
+func (x *ExecuteRequest) GetParams() []*Value {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+type Column struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Type names the column type the way CREATE TABLE does, like "int" or
+	// "text".
+	Type          string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Column) Reset() {
+	*x = Column{}
+	mi := &file_rpc_gosql_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Column) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Column) ProtoMessage() {}
+
+func (x *Column) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gosql_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Column.ProtoReflect.Descriptor instead.
+func (*Column) Descriptor() ([]byte, []int) {
+	return file_rpc_gosql_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Column) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Column) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+type Row struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cells         []*Value               `protobuf:"bytes,1,rep,name=cells,proto3" json:"cells,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Row) Reset() {
+	*x = Row{}
+	mi := &file_rpc_gosql_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Row) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Row) ProtoMessage() {}
+
+func (x *Row) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gosql_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Row.ProtoReflect.Descriptor instead.
+func (*Row) Descriptor() ([]byte, []int) {
+	return file_rpc_gosql_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Row) GetCells() []*Value {
+	if x != nil {
+		return x.Cells
+	}
+	return nil
+}
+
+type StatementResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Tag names the command that completed, like "INSERT 0 2".
+	Tag     string    `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	Columns []*Column `protobuf:"bytes,2,rep,name=columns,proto3" json:"columns,omitempty"`
+	Rows    []*Row    `protobuf:"bytes,3,rep,name=rows,proto3" json:"rows,omitempty"`
+	// rows_affected counts the rows an INSERT, UPDATE, DELETE or COPY wrote
+	// or a SELECT returned.
+	RowsAffected int64 `protobuf:"varint,4,opt,name=rows_affected,json=rowsAffected,proto3" json:"rows_affected,omitempty"`
+	// last_insert_id is the last value an INSERT took from the sequence of
+	// an auto-increment column, or 0 when it took none.
+	LastInsertId  int64    `protobuf:"varint,5,opt,name=last_insert_id,json=lastInsertId,proto3" json:"last_insert_id,omitempty"`
+	Warnings      []string `protobuf:"bytes,6,rep,name=warnings,proto3" json:"warnings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatementResult) Reset() {
+	*x = StatementResult{}
+	mi := &file_rpc_gosql_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatementResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatementResult) ProtoMessage() {}
+
+func (x *StatementResult) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gosql_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatementResult.ProtoReflect.Descriptor instead.
+func (*StatementResult) Descriptor() ([]byte, []int) {
+	return file_rpc_gosql_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StatementResult) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *StatementResult) GetColumns() []*Column {
+	if x != nil {
+		return x.Columns
+	}
+	return nil
+}
+
+func (x *StatementResult) GetRows() []*Row {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+func (x *StatementResult) GetRowsAffected() int64 {
+	if x != nil {
+		return x.RowsAffected
+	}
+	return 0
+}
+
+func (x *StatementResult) GetLastInsertId() int64 {
+	if x != nil {
+		return x.LastInsertId
+	}
+	return 0
+}
+
+func (x *StatementResult) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+type Error struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Message  string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Sqlstate string                 `protobuf:"bytes,2,opt,name=sqlstate,proto3" json:"sqlstate,omitempty"`
+	// Position is the 1-based character position of the token the error is
+	// about, or 0 when it is about none.
+	Position      int32  `protobuf:"varint,3,opt,name=position,proto3" json:"position,omitempty"`
+	Hint          string `protobuf:"bytes,4,opt,name=hint,proto3" json:"hint,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	mi := &file_rpc_gosql_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gosql_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_rpc_gosql_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Error) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Error) GetSqlstate() string {
+	if x != nil {
+		return x.Sqlstate
+	}
+	return ""
+}
+
+func (x *Error) GetPosition() int32 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *Error) GetHint() string {
+	if x != nil {
+		return x.Hint
+	}
+	return ""
+}
+
+type ExecuteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*StatementResult     `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	Error         *Error                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecuteResponse) Reset() {
+	*x = ExecuteResponse{}
+	mi := &file_rpc_gosql_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteResponse) ProtoMessage() {}
+
+func (x *ExecuteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gosql_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteResponse.ProtoReflect.Descriptor instead.
+func (*ExecuteResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_gosql_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ExecuteResponse) GetResults() []*StatementResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *ExecuteResponse) GetError() *Error {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type QueryRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	SessionId string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Sql       string                 `protobuf:"bytes,2,opt,name=sql,proto3" json:"sql,omitempty"`
+	Params    []*Value               `protobuf:"bytes,3,rep,name=params,proto3" json:"params,omitempty"`
+	// BatchSize is how many rows a response holds at most, or 0 for the
+	// server to choose.
+	BatchSize     int32 `protobuf:"varint,4,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryRequest) Reset() {
+	*x = QueryRequest{}
+	mi := &file_rpc_gosql_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryRequest) ProtoMessage() {}
+
+func (x *QueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gosql_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryRequest.ProtoReflect.Descriptor instead.
+func (*QueryRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_gosql_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *QueryRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetSql() string {
+	if x != nil {
+		return x.Sql
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetParams() []*Value {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetBatchSize() int32 {
+	if x != nil {
+		return x.BatchSize
+	}
+	return 0
+}
+
+// QueryResponse holds the columns in the first response of the stream and
+// rows in every response. A failing query ends the stream with an error
+// status carrying the Error.
+type QueryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Columns       []*Column              `protobuf:"bytes,1,rep,name=columns,proto3" json:"columns,omitempty"`
+	Rows          []*Row                 `protobuf:"bytes,2,rep,name=rows,proto3" json:"rows,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryResponse) Reset() {
+	*x = QueryResponse{}
+	mi := &file_rpc_gosql_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryResponse) ProtoMessage() {}
+
+func (x *QueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gosql_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryResponse.ProtoReflect.Descriptor instead.
+func (*QueryResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_gosql_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *QueryResponse) GetColumns() []*Column {
+	if x != nil {
+		return x.Columns
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetRows() []*Row {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+type TransactionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransactionRequest) Reset() {
+	*x = TransactionRequest{}
+	mi := &file_rpc_gosql_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionRequest) ProtoMessage() {}
+
+func (x *TransactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gosql_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionRequest.ProtoReflect.Descriptor instead.
+func (*TransactionRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_gosql_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *TransactionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type TransactionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransactionResponse) Reset() {
+	*x = TransactionResponse{}
+	mi := &file_rpc_gosql_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionResponse) ProtoMessage() {}
+
+func (x *TransactionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gosql_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionResponse.ProtoReflect.Descriptor instead.
+func (*TransactionResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_gosql_proto_rawDescGZIP(), []int{14}
+}
+
+var File_rpc_gosql_proto protoreflect.FileDescriptor
+
+const file_rpc_gosql_proto_rawDesc = "" +
+	"\n" +
+	"\x0frpc/gosql.proto\x12\bgosql.v1\"D\n" +
+	"\x12OpenSessionRequest\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\"4\n" +
+	"\x13OpenSessionResponse\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"4\n" +
+	"\x13CloseSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"\x16\n" +
+	"\x14CloseSessionResponse\"\x95\x01\n" +
+	"\x05Value\x12\x14\n" +
+	"\x04null\x18\x01 \x01(\bH\x00R\x04null\x12\x12\n" +
+	"\x03int\x18\x02 \x01(\x03H\x00R\x03int\x12\x16\n" +
+	"\x05float\x18\x03 \x01(\x01H\x00R\x05float\x12\x14\n" +
+	"\x04bool\x18\x04 \x01(\bH\x00R\x04bool\x12\x14\n" +
+	"\x04text\x18\x05 \x01(\tH\x00R\x04text\x12\x16\n" +
+	"\x05bytes\x18\x06 \x01(\fH\x00R\x05bytesB\x06\n" +
+	"\x04kind\"j\n" +
+	"\x0eExecuteRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x10\n" +
+	"\x03sql\x18\x02 \x01(\tR\x03sql\x12'\n" +
+	"\x06params\x18\x03 \x03(\v2\x0f.gosql.v1.ValueR\x06params\"0\n" +
+	"\x06Column\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\",\n" +
+	"\x03Row\x12%\n" +
+	"\x05cells\x18\x01 \x03(\v2\x0f.gosql.v1.ValueR\x05cells\"\xd9\x01\n" +
+	"\x0fStatementResult\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\x12*\n" +
+	"\acolumns\x18\x02 \x03(\v2\x10.gosql.v1.ColumnR\acolumns\x12!\n" +
+	"\x04rows\x18\x03 \x03(\v2\r.gosql.v1.RowR\x04rows\x12#\n" +
+	"\rrows_affected\x18\x04 \x01(\x03R\frowsAffected\x12$\n" +
+	"\x0elast_insert_id\x18\x05 \x01(\x03R\flastInsertId\x12\x1a\n" +
+	"\bwarnings\x18\x06 \x03(\tR\bwarnings\"m\n" +
+	"\x05Error\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\x12\x1a\n" +
+	"\bsqlstate\x18\x02 \x01(\tR\bsqlstate\x12\x1a\n" +
+	"\bposition\x18\x03 \x01(\x05R\bposition\x12\x12\n" +
+	"\x04hint\x18\x04 \x01(\tR\x04hint\"m\n" +
+	"\x0fExecuteResponse\x123\n" +
+	"\aresults\x18\x01 \x03(\v2\x19.gosql.v1.StatementResultR\aresults\x12%\n" +
+	"\x05error\x18\x02 \x01(\v2\x0f.gosql.v1.ErrorR\x05error\"\x87\x01\n" +
+	"\fQueryRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x10\n" +
+	"\x03sql\x18\x02 \x01(\tR\x03sql\x12'\n" +
+	"\x06params\x18\x03 \x03(\v2\x0f.gosql.v1.ValueR\x06params\x12\x1d\n" +
+	"\n" +
+	"batch_size\x18\x04 \x01(\x05R\tbatchSize\"^\n" +
+	"\rQueryResponse\x12*\n" +
+	"\acolumns\x18\x01 \x03(\v2\x10.gosql.v1.ColumnR\acolumns\x12!\n" +
+	"\x04rows\x18\x02 \x03(\v2\r.gosql.v1.RowR\x04rows\"3\n" +
+	"\x12TransactionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"\x15\n" +
+	"\x13TransactionResponse2\xf4\x03\n" +
+	"\x05Gosql\x12J\n" +
+	"\vOpenSession\x12\x1c.gosql.v1.OpenSessionRequest\x1a\x1d.gosql.v1.OpenSessionResponse\x12M\n" +
+	"\fCloseSession\x12\x1d.gosql.v1.CloseSessionRequest\x1a\x1e.gosql.v1.CloseSessionResponse\x12>\n" +
+	"\aExecute\x12\x18.gosql.v1.ExecuteRequest\x1a\x19.gosql.v1.ExecuteResponse\x12:\n" +
+	"\x05Query\x12\x16.gosql.v1.QueryRequest\x1a\x17.gosql.v1.QueryResponse0\x01\x12D\n" +
+	"\x05Begin\x12\x1c.gosql.v1.TransactionRequest\x1a\x1d.gosql.v1.TransactionResponse\x12E\n" +
+	"\x06Commit\x12\x1c.gosql.v1.TransactionRequest\x1a\x1d.gosql.v1.TransactionResponse\x12G\n" +
+	"\bRollback\x12\x1c.gosql.v1.TransactionRequest\x1a\x1d.gosql.v1.TransactionResponseB$Z\"github.com/piaoranyc/gosql/rpc;rpcb\x06proto3"
+
+var (
+	file_rpc_gosql_proto_rawDescOnce sync.Once
+	file_rpc_gosql_proto_rawDescData []byte
+)
+
+func file_rpc_gosql_proto_rawDescGZIP() []byte {
+	file_rpc_gosql_proto_rawDescOnce.Do(func() {
+		file_rpc_gosql_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_rpc_gosql_proto_rawDesc), len(file_rpc_gosql_proto_rawDesc)))
+	})
+	return file_rpc_gosql_proto_rawDescData
+}
+
+var file_rpc_gosql_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_rpc_gosql_proto_goTypes = []any{
+	(*OpenSessionRequest)(nil),   // 0: gosql.v1.OpenSessionRequest
+	(*OpenSessionResponse)(nil),  // 1: gosql.v1.OpenSessionResponse
+	(*CloseSessionRequest)(nil),  // 2: gosql.v1.CloseSessionRequest
+	(*CloseSessionResponse)(nil), // 3: gosql.v1.CloseSessionResponse
+	(*Value)(nil),                // 4: gosql.v1.Value
+	(*ExecuteRequest)(nil),       // 5: gosql.v1.ExecuteRequest
+	(*Column)(nil),               // 6: gosql.v1.Column
+	(*Row)(nil),                  // 7: gosql.v1.Row
+	(*StatementResult)(nil),      // 8: gosql.v1.StatementResult
+	(*Error)(nil),                // 9: gosql.v1.Error
+	(*ExecuteResponse)(nil),      // 10: gosql.v1.ExecuteResponse
+	(*QueryRequest)(nil),         // 11: gosql.v1.QueryRequest
+	(*QueryResponse)(nil),        // 12: gosql.v1.QueryResponse
+	(*TransactionRequest)(nil),   // 13: gosql.v1.TransactionRequest
+	(*TransactionResponse)(nil),  // 14: gosql.v1.TransactionResponse
+}
+var file_rpc_gosql_proto_depIdxs = []int32{
+	4,  // 0: gosql.v1.ExecuteRequest.params:type_name -> gosql.v1.Value
+	4,  // 1: gosql.v1.Row.cells:type_name -> gosql.v1.Value
+	6,  // 2: gosql.v1.StatementResult.columns:type_name -> gosql.v1.Column
+	7,  // 3: gosql.v1.StatementResult.rows:type_name -> gosql.v1.Row
+	8,  // 4: gosql.v1.ExecuteResponse.results:type_name -> gosql.v1.StatementResult
+	9,  // 5: gosql.v1.ExecuteResponse.error:type_name -> gosql.v1.Error
+	4,  // 6: gosql.v1.QueryRequest.params:type_name -> gosql.v1.Value
+	6,  // 7: gosql.v1.QueryResponse.columns:type_name -> gosql.v1.Column
+	7,  // 8: gosql.v1.QueryResponse.rows:type_name -> gosql.v1.Row
+	0,  // 9: gosql.v1.Gosql.OpenSession:input_type -> gosql.v1.OpenSessionRequest
+	2,  // 10: gosql.v1.Gosql.CloseSession:input_type -> gosql.v1.CloseSessionRequest
+	5,  // 11: gosql.v1.Gosql.Execute:input_type -> gosql.v1.ExecuteRequest
+	11, // 12: gosql.v1.Gosql.Query:input_type -> gosql.v1.QueryRequest
+	13, // 13: gosql.v1.Gosql.Begin:input_type -> gosql.v1.TransactionRequest
+	13, // 14: gosql.v1.Gosql.Commit:input_type -> gosql.v1.TransactionRequest
+	13, // 15: gosql.v1.Gosql.Rollback:input_type -> gosql.v1.TransactionRequest
+	1,  // 16: gosql.v1.Gosql.OpenSession:output_type -> gosql.v1.OpenSessionResponse
+	3,  // 17: gosql.v1.Gosql.CloseSession:output_type -> gosql.v1.CloseSessionResponse
+	10, // 18: gosql.v1.Gosql.Execute:output_type -> gosql.v1.ExecuteResponse
+	12, // 19: gosql.v1.Gosql.Query:output_type -> gosql.v1.QueryResponse
+	14, // 20: gosql.v1.Gosql.Begin:output_type -> gosql.v1.TransactionResponse
+	14, // 21: gosql.v1.Gosql.Commit:output_type -> gosql.v1.TransactionResponse
+	14, // 22: gosql.v1.Gosql.Rollback:output_type -> gosql.v1.TransactionResponse
+	16, // [16:23] is the sub-list for method output_type
+	9,  // [9:16] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_rpc_gosql_proto_init() }
+func file_rpc_gosql_proto_init() {
+	if File_rpc_gosql_proto != nil {
+		return
+	}
+	file_rpc_gosql_proto_msgTypes[4].OneofWrappers = []any{
+		(*Value_Null)(nil),
+		(*Value_Int)(nil),
+		(*Value_Float)(nil),
+		(*Value_Bool)(nil),
+		(*Value_Text)(nil),
+		(*Value_Bytes)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_rpc_gosql_proto_rawDesc), len(file_rpc_gosql_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_rpc_gosql_proto_goTypes,
+		DependencyIndexes: file_rpc_gosql_proto_depIdxs,
+		MessageInfos:      file_rpc_gosql_proto_msgTypes,
+	}.Build()
+	File_rpc_gosql_proto = out.File
+	file_rpc_gosql_proto_goTypes = nil
+	file_rpc_gosql_proto_depIdxs = nil
+}
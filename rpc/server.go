@@ -0,0 +1,367 @@
+// Package rpc serves a gosql backend over gRPC, as the Gosql service of
+// gosql.proto describes, and holds the client generated from it. The Go
+// code of the service is generated with
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//		--go-grpc_out=. --go-grpc_opt=paths=source_relative rpc/gosql.proto
+//
+// from the root of the repository.
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	"github.com/piaoranyc/gosql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultBatchSize is how many rows a response to Query holds when the
+// request leaves it to the server.
+const defaultBatchSize = 100
+
+// Server is the Gosql service for a backend, which grpc.Server's
+// RegisterService takes through RegisterGosqlServer. Every session a
+// client opens is one of the backend, logged in as the client's user once
+// the backend has users, and runs the calls made with its id one at a
+// time.
+type Server struct {
+	UnimplementedGosqlServer
+
+	backend *gosql.MemoryBackend
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// session is a session of the backend that a client holds by id. mu is
+// held by the call using it.
+type session struct {
+	mu sync.Mutex
+	s  *gosql.Session
+}
+
+func NewServer(backend *gosql.MemoryBackend) *Server {
+	return &Server{backend: backend, sessions: map[string]*session{}}
+}
+
+func (srv *Server) OpenSession(ctx context.Context, req *OpenSessionRequest) (*OpenSessionResponse, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	id := hex.EncodeToString(b[:])
+
+	var s *gosql.Session
+	if !srv.backend.HasUsers() {
+		s = srv.backend.NewSession()
+	} else {
+		var err error
+		if s, err = srv.backend.Authenticate(req.User, req.Password); err != nil {
+			return nil, status.Error(codes.Unauthenticated, "password authentication failed")
+		}
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.sessions[id] = &session{s: s}
+	return &OpenSessionResponse{SessionId: id}, nil
+}
+
+func (srv *Server) CloseSession(ctx context.Context, req *CloseSessionRequest) (*CloseSessionResponse, error) {
+	srv.mu.Lock()
+	sess, ok := srv.sessions[req.SessionId]
+	delete(srv.sessions, req.SessionId)
+	srv.mu.Unlock()
+	if !ok {
+		return nil, noSession(req.SessionId)
+	}
+
+	// A call still using the session finishes first.
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if err := sess.s.Close(); err != nil {
+		return nil, statusError("", err)
+	}
+	return &CloseSessionResponse{}, nil
+}
+
+// session returns the session called id, locked, and the function that
+// unlocks it.
+func (srv *Server) session(id string) (*gosql.Session, func(), error) {
+	srv.mu.Lock()
+	sess, ok := srv.sessions[id]
+	srv.mu.Unlock()
+	if !ok {
+		return nil, nil, noSession(id)
+	}
+	sess.mu.Lock()
+	return sess.s, sess.mu.Unlock, nil
+}
+
+func noSession(id string) error {
+	return status.Errorf(codes.NotFound, "session %q does not exist", id)
+}
+
+// Execute answers with the results of the statements that ran, and the
+// error of the one that failed, if any, in the response rather than as
+// the status of the call.
+func (srv *Server) Execute(ctx context.Context, req *ExecuteRequest) (*ExecuteResponse, error) {
+	s, unlock, err := srv.session(req.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var results []*gosql.StatementResult
+	if len(req.Params) == 0 {
+		results, err = gosql.ExecuteScriptContext(ctx, s, req.Sql, gosql.ScriptOptions{})
+	} else {
+		var p *gosql.Prepared
+		if p, err = gosql.Prepare(ctx, req.Sql); err == nil {
+			var result *gosql.StatementResult
+			if result, err = p.Execute(ctx, s, params(req.Params)...); result != nil {
+				results = append(results, result)
+			}
+		}
+	}
+
+	resp := &ExecuteResponse{}
+	for _, result := range results {
+		if result.Err == nil {
+			resp.Results = append(resp.Results, newStatementResult(result))
+		}
+	}
+	if err != nil {
+		resp.Error = newError(req.Sql, err)
+	}
+	return resp, nil
+}
+
+// Query reads the rows of a SELECT only as it sends them, within the
+// statement_timeout of the session.
+func (srv *Server) Query(req *QueryRequest, stream Gosql_QueryServer) error {
+	s, unlock, err := srv.session(req.SessionId)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	ctx := stream.Context()
+	if timeout := s.StatementTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, gosql.ErrStatementTimeout)
+		defer cancel()
+	}
+	err = query(ctx, s, req, stream)
+	if errors.Is(err, context.DeadlineExceeded) && context.Cause(ctx) == gosql.ErrStatementTimeout {
+		err = gosql.ErrStatementTimeout
+	}
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			// The stream failed to send.
+			return err
+		}
+		return statusError(req.Sql, err)
+	}
+	return nil
+}
+
+func query(ctx context.Context, s *gosql.Session, req *QueryRequest, stream Gosql_QueryServer) error {
+	p, err := gosql.Prepare(ctx, req.Sql)
+	if err != nil {
+		return err
+	}
+	stmt, err := gosql.Bind(p.Statement(), params(req.Params)...)
+	if err != nil {
+		return err
+	}
+	rows, err := gosql.QueryContext(ctx, s, stmt)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	batchSize := int(req.BatchSize)
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	columns := rows.Columns()
+	resp := &QueryResponse{Columns: newColumns(columns)}
+	sent := false
+	for rows.Next() {
+		resp.Rows = append(resp.Rows, newRow(rows.Row(), columns))
+		if len(resp.Rows) == batchSize {
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+			resp, sent = &QueryResponse{}, true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(resp.Rows) > 0 || !sent {
+		return stream.Send(resp)
+	}
+	return nil
+}
+
+func (srv *Server) Begin(ctx context.Context, req *TransactionRequest) (*TransactionResponse, error) {
+	return srv.transaction(req, (*gosql.Session).Begin)
+}
+
+func (srv *Server) Commit(ctx context.Context, req *TransactionRequest) (*TransactionResponse, error) {
+	return srv.transaction(req, (*gosql.Session).Commit)
+}
+
+func (srv *Server) Rollback(ctx context.Context, req *TransactionRequest) (*TransactionResponse, error) {
+	return srv.transaction(req, (*gosql.Session).Rollback)
+}
+
+// transaction runs f, which begins or ends a transaction, on the session
+// of req.
+func (srv *Server) transaction(req *TransactionRequest, f func(*gosql.Session) error) (*TransactionResponse, error) {
+	s, unlock, err := srv.session(req.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := f(s); err != nil {
+		return nil, statusError("", err)
+	}
+	return &TransactionResponse{}, nil
+}
+
+// statusError is the status a call fails with for err, which a statement
+// of source ran into, carrying it as an Error in its details.
+func statusError(source string, err error) error {
+	code := codes.InvalidArgument
+	switch {
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, gosql.ErrStatementTimeout):
+		code = codes.DeadlineExceeded
+	case errors.Is(err, gosql.ErrPermissionDenied):
+		code = codes.PermissionDenied
+	case errors.Is(err, gosql.ErrTransactionActive), errors.Is(err, gosql.ErrNoTransaction):
+		code = codes.FailedPrecondition
+	}
+	st := status.New(code, err.Error())
+	if detailed, derr := st.WithDetails(newError(source, err)); derr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+func newError(source string, err error) *Error {
+	e := &Error{Message: err.Error(), Sqlstate: gosql.SQLState(err)}
+	var ge *gosql.Error
+	if errors.As(err, &ge) {
+		e.Position = int32(ge.Loc.Offset(source))
+		e.Hint = ge.Hint
+	}
+	return e
+}
+
+func newStatementResult(result *gosql.StatementResult) *StatementResult {
+	sr := &StatementResult{
+		Tag:          result.Tag,
+		RowsAffected: result.RowsAffected,
+		LastInsertId: result.LastInsertID,
+		Warnings:     result.Warnings,
+	}
+	if result.Results != nil {
+		sr.Columns = newColumns(result.Results.Columns)
+		for _, row := range result.Results.Rows {
+			sr.Rows = append(sr.Rows, newRow(row, result.Results.Columns))
+		}
+	}
+	return sr
+}
+
+func newColumns(columns []gosql.ResultColumn) []*Column {
+	cols := make([]*Column, len(columns))
+	for i, col := range columns {
+		cols[i] = &Column{Name: col.Name, Type: typeName(col.Type)}
+	}
+	return cols
+}
+
+func newRow(row []gosql.Cell, columns []gosql.ResultColumn) *Row {
+	r := &Row{Cells: make([]*Value, len(row))}
+	for i, cell := range row {
+		r.Cells[i] = newValue(cell, columns[i].Type)
+	}
+	return r
+}
+
+// newValue is the Value of cell, of type ct.
+func newValue(cell gosql.Cell, ct gosql.ColumnType) *Value {
+	if cell.IsNull() {
+		return &Value{Kind: &Value_Null{Null: true}}
+	}
+	switch ct {
+	case gosql.IntType, gosql.BigIntType:
+		return &Value{Kind: &Value_Int{Int: cell.AsInt()}}
+	case gosql.FloatType:
+		return &Value{Kind: &Value_Float{Float: cell.AsFloat()}}
+	case gosql.BoolType:
+		return &Value{Kind: &Value_Bool{Bool: cell.AsBool()}}
+	case gosql.DateType:
+		return &Value{Kind: &Value_Text{Text: gosql.FormatDate(cell.AsTime())}}
+	case gosql.TimestampType:
+		return &Value{Kind: &Value_Text{Text: gosql.FormatTimestamp(cell.AsTime())}}
+	case gosql.ByteaType:
+		return &Value{Kind: &Value_Bytes{Bytes: []byte(cell.AsText())}}
+	default:
+		return &Value{Kind: &Value_Text{Text: cell.AsText()}}
+	}
+}
+
+// params converts values to the arguments Bind takes.
+func params(values []*Value) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		switch kind := v.GetKind().(type) {
+		case *Value_Int:
+			args[i] = kind.Int
+		case *Value_Float:
+			args[i] = kind.Float
+		case *Value_Bool:
+			args[i] = kind.Bool
+		case *Value_Text:
+			args[i] = kind.Text
+		case *Value_Bytes:
+			args[i] = kind.Bytes
+		}
+	}
+	return args
+}
+
+// typeName names ct the way CREATE TABLE does.
+func typeName(ct gosql.ColumnType) string {
+	switch ct {
+	case gosql.IntType:
+		return "int"
+	case gosql.BigIntType:
+		return "bigint"
+	case gosql.FloatType:
+		return "float"
+	case gosql.BoolType:
+		return "boolean"
+	case gosql.DateType:
+		return "date"
+	case gosql.TimestampType:
+		return "timestamp"
+	case gosql.JSONType:
+		return "json"
+	case gosql.ByteaType:
+		return "bytea"
+	default:
+		return "text"
+	}
+}
@@ -0,0 +1,200 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/piaoranyc/gosql"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial serves backend in memory and returns a client of it.
+func dial(t *testing.T, backend *gosql.MemoryBackend) GosqlClient {
+	l := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	RegisterGosqlServer(gs, NewServer(backend))
+	go gs.Serve(l)
+	t.Cleanup(gs.Stop)
+
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return l.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+	t.Cleanup(func() { cc.Close() })
+	return NewGosqlClient(cc)
+}
+
+func openSession(t *testing.T, client GosqlClient) string {
+	resp, err := client.OpenSession(context.Background(), &OpenSessionRequest{})
+	assert.Nil(t, err)
+	return resp.SessionId
+}
+
+func text(s string) *Value {
+	return &Value{Kind: &Value_Text{Text: s}}
+}
+
+func integer(i int64) *Value {
+	return &Value{Kind: &Value_Int{Int: i}}
+}
+
+func TestServer_Execute(t *testing.T) {
+	ctx := context.Background()
+	client := dial(t, gosql.NewMemoryBackend())
+	id := openSession(t, client)
+
+	resp, err := client.Execute(ctx, &ExecuteRequest{SessionId: id, Sql: `create table items (id serial primary key, name text, data bytea, added date);
+insert into items (name, data, added) values ('pen', '\x0001'::bytea, date '2024-05-01'), ('ink', null, null);
+select id, name, data, added from items order by id;
+insert into items values (1, 'again', null, null);
+select 1 from items`})
+	assert.Nil(t, err)
+	if !assert.Len(t, resp.Results, 3) {
+		return
+	}
+	assert.Equal(t, "CREATE TABLE", resp.Results[0].Tag)
+	assert.Equal(t, int64(2), resp.Results[1].LastInsertId)
+	sel := resp.Results[2]
+	assert.Equal(t, "SELECT 2", sel.Tag)
+	assert.Equal(t, []string{"int", "text", "bytea", "date"}, []string{sel.Columns[0].Type, sel.Columns[1].Type, sel.Columns[2].Type, sel.Columns[3].Type})
+	assert.Equal(t, int64(1), sel.Rows[0].Cells[0].GetInt())
+	assert.Equal(t, "pen", sel.Rows[0].Cells[1].GetText())
+	assert.Equal(t, []byte{0, 1}, sel.Rows[0].Cells[2].GetBytes())
+	assert.Equal(t, "2024-05-01", sel.Rows[0].Cells[3].GetText())
+	assert.True(t, sel.Rows[1].Cells[2].GetNull())
+	assert.Equal(t, "23505", resp.Error.Sqlstate)
+
+	resp, err = client.Execute(ctx, &ExecuteRequest{SessionId: id, Sql: "select name from items where data = $1 or id = $2",
+		Params: []*Value{{Kind: &Value_Bytes{Bytes: []byte{0, 1}}}, integer(2)}})
+	assert.Nil(t, err)
+	assert.Nil(t, resp.Error)
+	assert.Len(t, resp.Results[0].Rows, 2)
+
+	resp, err = client.Execute(ctx, &ExecuteRequest{SessionId: id, Sql: "select nosuch from items"})
+	assert.Nil(t, err)
+	assert.Equal(t, "42703", resp.Error.Sqlstate)
+	assert.Equal(t, int32(8), resp.Error.Position)
+
+	_, err = client.Execute(ctx, &ExecuteRequest{SessionId: "nope", Sql: "select 1 from items"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_Query(t *testing.T) {
+	ctx := context.Background()
+	client := dial(t, gosql.NewMemoryBackend())
+	id := openSession(t, client)
+	_, err := client.Execute(ctx, &ExecuteRequest{SessionId: id, Sql: "create table t (id int, name text);" +
+		"insert into t values (1, 'a'), (2, 'b'), (3, 'c'), (4, 'd'), (5, 'e')"})
+	assert.Nil(t, err)
+
+	query := func(req *QueryRequest) ([]*QueryResponse, error) {
+		req.SessionId = id
+		stream, err := client.Query(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		var responses []*QueryResponse
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				return responses, nil
+			}
+			if err != nil {
+				return responses, err
+			}
+			responses = append(responses, resp)
+		}
+	}
+
+	responses, err := query(&QueryRequest{Sql: "select id, name from t where id > $1 order by id", Params: []*Value{integer(0)}, BatchSize: 2})
+	assert.Nil(t, err)
+	if assert.Len(t, responses, 3) {
+		assert.Equal(t, "name", responses[0].Columns[1].Name)
+		assert.Empty(t, responses[1].Columns)
+		assert.Equal(t, []int{2, 2, 1}, []int{len(responses[0].Rows), len(responses[1].Rows), len(responses[2].Rows)})
+		assert.Equal(t, "e", responses[2].Rows[0].Cells[1].GetText())
+	}
+
+	// An empty result still sends its columns.
+	responses, err = query(&QueryRequest{Sql: "select name from t where name = $1", Params: []*Value{text("z")}})
+	assert.Nil(t, err)
+	if assert.Len(t, responses, 1) {
+		assert.Len(t, responses[0].Columns, 1)
+		assert.Empty(t, responses[0].Rows)
+	}
+
+	responses, err = query(&QueryRequest{Sql: "delete from t where id = 5 returning name"})
+	assert.Nil(t, err)
+	assert.Equal(t, "e", responses[0].Rows[0].Cells[0].GetText())
+
+	_, err = query(&QueryRequest{Sql: "select nosuch from t"})
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	if assert.Len(t, st.Details(), 1) {
+		assert.Equal(t, "42703", st.Details()[0].(*Error).Sqlstate)
+	}
+	_, err = query(&QueryRequest{Sql: "select 1 from t; select 2 from t"})
+	assert.NotNil(t, err)
+}
+
+func TestServer_transactions(t *testing.T) {
+	ctx := context.Background()
+	client := dial(t, gosql.NewMemoryBackend())
+	id, other := openSession(t, client), openSession(t, client)
+	_, err := client.Execute(ctx, &ExecuteRequest{SessionId: id, Sql: "create table t (id int)"})
+	assert.Nil(t, err)
+
+	count := func(session string) int64 {
+		resp, err := client.Execute(ctx, &ExecuteRequest{SessionId: session, Sql: "select count(*) from t"})
+		assert.Nil(t, err)
+		return resp.Results[0].Rows[0].Cells[0].GetInt()
+	}
+
+	_, err = client.Begin(ctx, &TransactionRequest{SessionId: id})
+	assert.Nil(t, err)
+	_, err = client.Begin(ctx, &TransactionRequest{SessionId: id})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = client.Execute(ctx, &ExecuteRequest{SessionId: id, Sql: "insert into t values (1)"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count(id))
+	assert.Equal(t, int64(0), count(other))
+	_, err = client.Commit(ctx, &TransactionRequest{SessionId: id})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count(other))
+
+	// Closing a session rolls back its transaction.
+	_, err = client.Begin(ctx, &TransactionRequest{SessionId: id})
+	assert.Nil(t, err)
+	_, err = client.Execute(ctx, &ExecuteRequest{SessionId: id, Sql: "insert into t values (2)"})
+	assert.Nil(t, err)
+	_, err = client.CloseSession(ctx, &CloseSessionRequest{SessionId: id})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count(other))
+	_, err = client.Rollback(ctx, &TransactionRequest{SessionId: id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_users(t *testing.T) {
+	ctx := context.Background()
+	backend := gosql.NewMemoryBackend()
+	_, err := gosql.ExecuteScript(backend, "create table t (id int); create user clerk password 'secret'", gosql.ScriptOptions{})
+	assert.Nil(t, err)
+	client := dial(t, backend)
+
+	_, err = client.OpenSession(ctx, &OpenSessionRequest{User: "clerk", Password: "wrong"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	resp, err := client.OpenSession(ctx, &OpenSessionRequest{User: "clerk", Password: "secret"})
+	assert.Nil(t, err)
+
+	stream, err := client.Query(ctx, &QueryRequest{SessionId: resp.SessionId, Sql: "select id from t"})
+	assert.Nil(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,318 @@
+package gosql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Executor is a backend that can describe its tables, so statements can
+// be validated before they run on it. Both MemoryBackend and Session are
+// executors.
+type Executor interface {
+	Backend
+	Schema() Schema
+}
+
+// StatementResult is the outcome of running one statement.
+type StatementResult struct {
+	Statement *Statement
+	// Tag names the command that completed the way PostgreSQL does, like
+	// "INSERT 0 2" or "SELECT 5".
+	Tag string
+	// Results holds the rows of a query or of a RETURNING clause, with the
+	// name and type of each column, and is nil for statements that return
+	// none.
+	Results *Results
+	// RowsAffected counts the rows an INSERT, UPDATE, DELETE or COPY
+	// wrote or a SELECT returned, the number its Tag ends with. It is 0
+	// for other statements.
+	RowsAffected int64
+	// LastInsertID is the last value an INSERT took from the sequence of
+	// an auto-increment column, or 0 when it took none.
+	LastInsertID int64
+	// Warnings holds notices about a statement that succeeded all the
+	// same, like a table DROP TABLE IF EXISTS did not find.
+	Warnings []string
+	Err      error
+	// Metrics measures how the statement ran, when it succeeded.
+	Metrics StatementMetrics
+}
+
+// resultKey is the context key under which executeStatement passes the
+// result of the running statement to the executor, for it to add the
+// warnings and insert ids it has.
+type resultKey struct{}
+
+// warn adds a warning to the result of the statement ctx belongs to, if
+// anyone is keeping it.
+func warn(ctx context.Context, format string, args ...interface{}) {
+	if r, ok := ctx.Value(resultKey{}).(*StatementResult); ok {
+		r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+	}
+}
+
+// insertedID records that the statement ctx belongs to took id from the
+// sequence of an auto-increment column.
+func insertedID(ctx context.Context, id int64) {
+	if r, ok := ctx.Value(resultKey{}).(*StatementResult); ok {
+		r.LastInsertID = id
+	}
+}
+
+// ScriptOptions controls how ExecuteScript handles failing statements.
+type ScriptOptions struct {
+	// ContinueOnError runs the statements after one that fails instead
+	// of stopping at it.
+	ContinueOnError bool
+	// Dialect is the flavor of SQL the script is written in, or nil for
+	// the one Parse takes.
+	Dialect *Dialect
+}
+
+// Execute validates stmt against the schema of ex and runs it.
+func Execute(ex Executor, stmt *Statement) (*StatementResult, error) {
+	return ExecuteContext(context.Background(), ex, stmt)
+}
+
+// ExecuteContext is Execute for a statement that is canceled when ctx is
+// done or when it runs longer than the statement_timeout of ex. It then
+// fails with the error of ctx or with ErrStatementTimeout.
+func ExecuteContext(ctx context.Context, ex Executor, stmt *Statement) (*StatementResult, error) {
+	return executeMeasured(ctx, ex, stmt, 0)
+}
+
+// QueryContext runs stmt on ex as ExecuteContext does and returns a cursor
+// over the rows it returns, which is empty for a statement that returns
+// none. The rows of a SELECT are only worked out as the cursor reads them,
+// so its statement_timeout does not apply; ctx can still cancel it.
+func QueryContext(ctx context.Context, ex Executor, stmt *Statement) (*Rows, error) {
+	if stmt.Kind != SelectKind {
+		r, err := ExecuteContext(ctx, ex, stmt)
+		if err != nil {
+			return nil, err
+		}
+		if r.Results == nil {
+			return (&Results{}).Cursor(), nil
+		}
+		return r.Results.Cursor(), nil
+	}
+	if err := authorize(ex, stmt); err != nil {
+		return nil, err
+	}
+	if err := Validate(stmt, ex.Schema()); err != nil {
+		return nil, err
+	}
+	return ex.Query(ctx, stmt.SelectStatement)
+}
+
+// executeMeasured is ExecuteContext for a statement that took parseTime
+// to parse. It records the metrics of the statement with ex, when ex
+// keeps them.
+func executeMeasured(ctx context.Context, ex Executor, stmt *Statement, parseTime time.Duration) (*StatementResult, error) {
+	m := &StatementMetrics{ParseTime: parseTime}
+	start := time.Now()
+	r, err := validateAndExecute(context.WithValue(ctx, metricsKey{}, m), ex, stmt)
+	m.ExecutionTime = time.Since(start)
+	if r != nil {
+		if r.Results != nil {
+			m.RowsReturned = int64(len(r.Results.Rows))
+		}
+		r.Metrics = *m
+	}
+	if rec, ok := ex.(metricsRecorder); ok {
+		rec.recordStatement(stmt, *m, err)
+	}
+	return r, err
+}
+
+func validateAndExecute(ctx context.Context, ex Executor, stmt *Statement) (*StatementResult, error) {
+	if err := authorize(ex, stmt); err != nil {
+		return nil, err
+	}
+	if err := Validate(stmt, ex.Schema()); err != nil {
+		return nil, err
+	}
+
+	if t, ok := ex.(timeouter); ok && t.StatementTimeout() > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, t.StatementTimeout(), ErrStatementTimeout)
+		defer cancel()
+	}
+	r, err := executeStatement(ctx, ex, stmt)
+	if errors.Is(err, context.DeadlineExceeded) && context.Cause(ctx) == ErrStatementTimeout {
+		err = ErrStatementTimeout
+	}
+	return r, err
+}
+
+func executeStatement(ctx context.Context, ex Executor, stmt *Statement) (*StatementResult, error) {
+	r := StatementResult{Statement: stmt}
+	ctx = context.WithValue(ctx, resultKey{}, &r)
+	var n int
+	var err error
+	switch stmt.Kind {
+	case CreateTableKind:
+		err = ex.CreateTable(ctx, stmt.CreateTableStatement)
+		r.Tag = "CREATE TABLE"
+	case DropTableKind:
+		err = ex.DropTable(ctx, stmt.DropTableStatement)
+		r.Tag = "DROP TABLE"
+	case AlterTableKind:
+		err = ex.AlterTable(ctx, stmt.AlterTableStatement)
+		r.Tag = "ALTER TABLE"
+	case CreateIndexKind:
+		err = ex.CreateIndex(ctx, stmt.CreateIndexStatement)
+		r.Tag = "CREATE INDEX"
+	case CreateViewKind:
+		err = ex.CreateView(ctx, stmt.CreateViewStatement)
+		r.Tag = "CREATE VIEW"
+	case DropViewKind:
+		err = ex.DropView(ctx, stmt.DropViewStatement)
+		r.Tag = "DROP VIEW"
+	case InsertKind:
+		n, r.Results, err = ex.Insert(ctx, stmt.InsertStatement)
+		r.Tag = "INSERT 0 " + strconv.Itoa(n)
+	case UpdateKind:
+		n, r.Results, err = ex.Update(ctx, stmt.UpdateStatement)
+		r.Tag = "UPDATE " + strconv.Itoa(n)
+	case DeleteKind:
+		n, r.Results, err = ex.Delete(ctx, stmt.DeleteStatement)
+		r.Tag = "DELETE " + strconv.Itoa(n)
+	case SelectKind:
+		r.Results, err = ex.Select(ctx, stmt.SelectStatement)
+		if err == nil {
+			n = len(r.Results.Rows)
+			r.Tag = "SELECT " + strconv.Itoa(n)
+		}
+	case CopyKind:
+		if stmt.CopyStatement.To {
+			n, err = copyTo(ctx, ex, stmt.CopyStatement)
+		} else {
+			n, err = copyFrom(ctx, ex, stmt.CopyStatement)
+		}
+		r.Tag = "COPY " + strconv.Itoa(n)
+	case ExplainKind:
+		r.Results, err = ex.Explain(ctx, stmt.ExplainStatement)
+		r.Tag = "EXPLAIN"
+	case ShowTablesKind:
+		_, database, _ := scriptSettings(ex)
+		r.Results = showTables(ex.Schema(), database, tempSchemaOf(ex))
+		r.Tag = "SHOW"
+	case DescribeKind:
+		r.Results, err = describe(ex.Schema(), stmt.DescribeStatement)
+		r.Tag = "DESCRIBE"
+	case SetKind:
+		err = ex.Set(stmt.SetStatement)
+		r.Tag = "SET"
+	case ShowKind:
+		r.Results, err = show(ex, stmt.ShowStatement)
+		r.Tag = "SHOW"
+	case CreateDatabaseKind:
+		err = ex.CreateDatabase(ctx, stmt.CreateDatabaseStatement)
+		r.Tag = "CREATE DATABASE"
+	case DropDatabaseKind:
+		err = ex.DropDatabase(ctx, stmt.DropDatabaseStatement)
+		r.Tag = "DROP DATABASE"
+	case CreateUserKind:
+		err = executeUserStatement(ctx, ex, stmt)
+		r.Tag = "CREATE ROLE"
+	case DropUserKind:
+		err = executeUserStatement(ctx, ex, stmt)
+		r.Tag = "DROP ROLE"
+	case GrantKind:
+		err = executeUserStatement(ctx, ex, stmt)
+		r.Tag = "GRANT"
+	case RevokeKind:
+		err = executeUserStatement(ctx, ex, stmt)
+		r.Tag = "REVOKE"
+	case UseKind:
+		err = ex.Set(&SetStatement{Name: &Token{Value: "database", Kind: IdentifierKind}, Value: stmt.UseStatement.Name})
+		r.Tag = "USE"
+	case BeginKind:
+		err = ex.Begin()
+		r.Tag = "BEGIN"
+	case CommitKind:
+		err = ex.Commit()
+		r.Tag = "COMMIT"
+	case RollbackKind:
+		err = ex.Rollback()
+		r.Tag = "ROLLBACK"
+	case SavepointKind:
+		err = ex.Savepoint(stmt.SavepointStatement.Name.Value)
+		r.Tag = "SAVEPOINT"
+	case RollbackToSavepointKind:
+		err = ex.RollbackToSavepoint(stmt.SavepointStatement.Name.Value)
+		r.Tag = "ROLLBACK"
+	case ReleaseSavepointKind:
+		err = ex.ReleaseSavepoint(stmt.SavepointStatement.Name.Value)
+		r.Tag = "RELEASE"
+	default:
+		err = ErrInvalidOperator
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.RowsAffected = int64(n)
+	return &r, nil
+}
+
+// ExecuteScript parses source as semicolon-separated statements and runs
+// them in order, returning a result for each statement it ran. A failing
+// statement gets a result holding its error. Nothing runs when source
+// does not parse. The error returned is the parse error or that of the
+// first statement that failed.
+func ExecuteScript(ex Executor, source string, opts ScriptOptions) ([]*StatementResult, error) {
+	return ExecuteScriptContext(context.Background(), ex, source, opts)
+}
+
+// ExecuteScriptContext is ExecuteScript with each statement run by
+// ExecuteContext. A statement that fails because ctx is done stops the
+// script even with ContinueOnError.
+func ExecuteScriptContext(ctx context.Context, ex Executor, source string, opts ScriptOptions) ([]*StatementResult, error) {
+	// case_sensitive is read once, as the whole script is parsed before
+	// any of it runs, so setting it takes effect from the next script.
+	caseSensitive, _, _ := scriptSettings(ex)
+	lexers := lexers
+	if opts.Dialect != nil || caseSensitive {
+		cfg := DefaultLexConfig()
+		if opts.Dialect != nil {
+			cfg = opts.Dialect.LexConfig
+		}
+		cfg.CaseSensitive = cfg.CaseSensitive || caseSensitive
+		lexers = cfg.lexers()
+	}
+	start := time.Now()
+	ast, err := parseWith(ctx, source, lexers)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Dialect != nil {
+		opts.Dialect.placeNulls(ast)
+	}
+	parseTime := time.Since(start)
+
+	var results []*StatementResult
+	var first error
+	for _, stmt := range ast.Statements {
+		// The database and search_path are read before each statement, so
+		// a USE or SET of them applies to the rest of the script.
+		_, database, path := scriptSettings(ex)
+		qualifyNames(stmt, ex.Schema(), database, path, tempSchemaOf(ex))
+		r, err := executeMeasured(ctx, ex, stmt, parseTime)
+		parseTime = 0
+		if err != nil {
+			r = &StatementResult{Statement: stmt, Err: err}
+			if first == nil {
+				first = err
+			}
+		}
+		results = append(results, r)
+		if err != nil && (!opts.ContinueOnError || ctx.Err() != nil) {
+			break
+		}
+	}
+	return results, first
+}
@@ -0,0 +1,116 @@
+package gosql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteScript(t *testing.T) {
+	mb := NewMemoryBackend()
+	results, err := ExecuteScript(mb, `
+create table users (id int primary key, name text);
+insert into users values (1, 'alice'), (2, 'bob') returning id;
+select name from users order by id;
+insert into users values (1, 'again');
+update users set name = 'carol' where id = 2;`, ScriptOptions{})
+	assert.ErrorIs(t, err, ErrViolatesPrimaryKey)
+	assert.Equal(t, 4, len(results))
+	assert.Equal(t, "CREATE TABLE", results[0].Tag)
+	assert.Equal(t, "INSERT 0 2", results[1].Tag)
+	assert.Equal(t, 2, len(results[1].Results.Rows))
+	assert.Equal(t, "SELECT 2", results[2].Tag)
+	assert.Equal(t, "alice", results[2].Results.Rows[0][0].AsText())
+	assert.Equal(t, InsertKind, results[3].Statement.Kind)
+	assert.ErrorIs(t, results[3].Err, ErrViolatesPrimaryKey)
+
+	results, err = ExecuteScript(mb, `
+delete from nope;
+update users set name = 'carol' where id = 2;
+delete from users where id = 1`, ScriptOptions{ContinueOnError: true})
+	assert.ErrorIs(t, err, ErrTableDoesNotExist)
+	assert.Equal(t, 3, len(results))
+	assert.ErrorIs(t, results[0].Err, ErrTableDoesNotExist)
+	assert.Equal(t, "UPDATE 1", results[1].Tag)
+	assert.Equal(t, "DELETE 1", results[2].Tag)
+
+	// Nothing runs when the script does not parse.
+	results, err = ExecuteScript(mb, "delete from users; delete from", ScriptOptions{})
+	assert.Equal(t, SyntaxError, Code(err))
+	assert.Nil(t, results)
+
+	session := mb.NewSession()
+	results, err = ExecuteScript(session, "begin; delete from users; rollback; select id from users", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"BEGIN", "DELETE 1", "ROLLBACK", "SELECT 1"}, []string{results[0].Tag, results[1].Tag, results[2].Tag, results[3].Tag})
+}
+
+func TestExecuteScript_resultMetadata(t *testing.T) {
+	mb := NewMemoryBackend()
+	results, err := ExecuteScript(mb, `create table users (id serial primary key, name text);
+insert into users (name) values ('alice'), ('bob');
+insert into users values (10, 'carol');
+update users set name = upper(name) where id < 10;
+select id, name from users;
+drop table if exists missing;
+create table if not exists users (id int);
+drop view if exists missing;
+drop user if exists nobody`, ScriptOptions{})
+	assert.Nil(t, err)
+
+	var affected, ids []int64
+	for _, r := range results[:5] {
+		affected = append(affected, r.RowsAffected)
+		ids = append(ids, r.LastInsertID)
+	}
+	assert.Equal(t, []int64{0, 2, 1, 2, 3}, affected)
+	// Only the statements that took values from the sequence have an id.
+	assert.Equal(t, []int64{0, 2, 0, 0, 0}, ids)
+	assert.Equal(t, []ResultColumn{{Type: IntType, Name: "id"}, {Type: TextType, Name: "name"}}, results[4].Results.Columns)
+
+	assert.Nil(t, results[4].Warnings)
+	assert.Equal(t, []string{"table missing does not exist, skipping"}, results[5].Warnings)
+	assert.Equal(t, []string{"table users already exists, skipping"}, results[6].Warnings)
+	assert.Equal(t, []string{"view missing does not exist, skipping"}, results[7].Warnings)
+	assert.Equal(t, []string{"user nobody does not exist, skipping"}, results[8].Warnings)
+}
+
+func TestQueryContext(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table users (id int primary key, name text);"+
+		"insert into users values (1, 'alice'), (2, 'bob');"+
+		"create user clerk password 'secret'", ScriptOptions{})
+	assert.Nil(t, err)
+
+	query := func(ex Executor, source string) (*Results, error) {
+		ast, err := Parse(source)
+		if !assert.Nil(t, err, source) {
+			return nil, err
+		}
+		rows, err := QueryContext(context.Background(), ex, ast.Statements[0])
+		if err != nil {
+			return nil, err
+		}
+		return rows.All()
+	}
+
+	results, err := query(mb, "select name from users order by id desc")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{MemoryCell("bob")}, {MemoryCell("alice")}}, results.Rows)
+	results, err = query(mb, "delete from users where id = 2 returning name")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{MemoryCell("bob")}}, results.Rows)
+	results, err = query(mb, "update users set name = 'ann'")
+	assert.Nil(t, err)
+	assert.Empty(t, results.Rows)
+
+	// Queries are validated and checked against the privileges of the
+	// session's user like any other statement.
+	_, err = query(mb, "select nope from users")
+	assert.ErrorIs(t, err, ErrColumnDoesNotExist)
+	clerk, err := mb.Authenticate("clerk", "secret")
+	assert.Nil(t, err)
+	_, err = query(clerk, "select name from users")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+}
@@ -0,0 +1,62 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoIncrement(t *testing.T) {
+	mb := NewMemoryBackend()
+	results, err := ExecuteScript(mb, "create table users (id serial primary key, name text);"+
+		"insert into users (name) values ('alice'), ('bob');"+
+		"insert into users values (10, 'carol');"+
+		"insert into users values (null, 'dave') returning id", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(11)}}, results[3].Results.Rows)
+	assert.Equal(t, int64(11), mb.LastInsertID())
+
+	results, err = ExecuteScript(mb, "select id, name from users order by id", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{
+		{intCell(1), MemoryCell("alice")},
+		{intCell(2), MemoryCell("bob")},
+		{intCell(10), MemoryCell("carol")},
+		{intCell(11), MemoryCell("dave")},
+	}, results[0].Results.Rows)
+
+	// Rolling back does not give values back to the sequence.
+	results, err = ExecuteScript(mb, "begin;"+
+		"insert into users (name) values ('erin');"+
+		"rollback;"+
+		"insert into users (name) values ('frank') returning id", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(13)}}, results[3].Results.Rows)
+
+	// An added column numbers the rows already there.
+	results, err = ExecuteScript(mb, "create table t (name text);"+
+		"insert into t values ('a'), ('b');"+
+		"alter table t add column n bigint auto_increment;"+
+		"insert into t (name) values ('c');"+
+		"select n from t order by n", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{bigIntCell(1)}, {bigIntCell(2)}, {bigIntCell(3)}}, results[4].Results.Rows)
+
+	_, err = ExecuteScript(mb, "alter table t add column m int auto_increment", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrMultipleAutoIncrement)
+}
+
+func TestParse_autoIncrement(t *testing.T) {
+	ast, err := Parse("create table t (id bigserial, name text)")
+	assert.Nil(t, err)
+	col := ast.Statements[0].CreateTableStatement.Cols[0]
+	assert.Equal(t, "bigint", col.Datatype.Value)
+	assert.True(t, col.hasConstraint(NotNullConstraint))
+	assert.True(t, col.hasConstraint(AutoIncrementConstraint))
+	assert.Equal(t, "CREATE TABLE t (\n  id BIGINT NOT NULL AUTO_INCREMENT,\n  name TEXT\n)", Format(ast.Statements[0]))
+
+	_, err = Parse("create table t (id bigserial, n int auto_increment unique)")
+	assert.NotNil(t, err)
+	_, err = Parse("create table t (name text auto_increment)")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/piaoranyc/gosql"
+)
+
+// queryRequest is the body of a request to /query. Params, when given,
+// are bound to the ? and $N parameters of Query, which must then hold a
+// single statement.
+type queryRequest struct {
+	Query  string        `json:"query"`
+	Params []interface{} `json:"params"`
+}
+
+type queryResponse struct {
+	Results []*queryResult `json:"results"`
+	Error   *queryError    `json:"error,omitempty"`
+}
+
+// queryResult is the outcome of one statement. Columns and Rows are null
+// for statements that return no rows.
+type queryResult struct {
+	Command      string          `json:"command"`
+	RowsAffected int64           `json:"rowsAffected"`
+	LastInsertID int64           `json:"lastInsertId,omitempty"`
+	Warnings     []string        `json:"warnings,omitempty"`
+	Columns      []queryColumn   `json:"columns"`
+	Rows         [][]interface{} `json:"rows"`
+}
+
+type queryColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type queryError struct {
+	Message string `json:"message"`
+	Code    string `json:"code"`
+	// Position is the 1-based character position in the query of the
+	// token the error is about, or 0 when it is about none.
+	Position int    `json:"position,omitempty"`
+	Hint     string `json:"hint,omitempty"`
+}
+
+// HTTPHandler serves the backend over HTTP, for clients that have no
+// PostgreSQL driver. Its one endpoint, POST /query, takes a JSON object
+// like {"query": "select * from t where id = $1", "params": [1]} and runs
+// the statements of the query, answering with the command tag, rows
+// affected, columns and rows of each of them in order, along with any
+// warnings and the id an INSERT took from an auto-increment column. Rows
+// hold numbers, booleans, strings, JSON values and null, with dates and
+// timestamps as strings.
+//
+// When a statement fails, those after it do not run, and the answer has
+// status 400 and the error with its SQLSTATE next to the results of the
+// statements before it. Every request is a session of its own, so a
+// transaction lasts no longer than a request. Once the backend has users,
+// requests log in with HTTP basic authentication.
+func (s *Server) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/query", s.serveQuery)
+	return mux
+}
+
+func (s *Server) serveQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req queryRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxMessageLength))
+	dec.UseNumber()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session, ok := s.session(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="gosql"`)
+		http.Error(w, "password authentication failed", http.StatusUnauthorized)
+		return
+	}
+	defer session.Close()
+
+	var results []*gosql.StatementResult
+	var err error
+	if req.Params == nil {
+		results, err = gosql.ExecuteScriptContext(r.Context(), session, req.Query, gosql.ScriptOptions{})
+	} else {
+		var result *gosql.StatementResult
+		result, err = executeWithParams(r, session, req)
+		if result != nil {
+			results = append(results, result)
+		}
+	}
+
+	resp := queryResponse{Results: []*queryResult{}}
+	for _, result := range results {
+		if result.Err == nil {
+			resp.Results = append(resp.Results, newQueryResult(result))
+		}
+	}
+	status := http.StatusOK
+	if err != nil {
+		status = http.StatusBadRequest
+		resp.Error = newQueryError(req.Query, err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	// The status is sent, so a client that went away is all that can fail.
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// session starts the session of r, logging in with its basic
+// authentication when the backend has users. It reports false when that
+// fails.
+func (s *Server) session(r *http.Request) (*gosql.Session, bool) {
+	if !s.backend.HasUsers() {
+		return s.backend.NewSession(), true
+	}
+	user, password, _ := r.BasicAuth()
+	session, err := s.backend.Authenticate(user, password)
+	return session, err == nil
+}
+
+// executeWithParams prepares the single statement of req and runs it with
+// its params.
+func executeWithParams(r *http.Request, session *gosql.Session, req queryRequest) (*gosql.StatementResult, error) {
+	p, err := gosql.Prepare(r.Context(), req.Query)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, len(req.Params))
+	for i, param := range req.Params {
+		args[i] = paramValue(param)
+	}
+	return p.Execute(r.Context(), session, args...)
+}
+
+// paramValue converts a JSON value to an argument Bind takes: numbers to
+// int64 when they are whole and to float64 otherwise. Arrays and objects
+// are passed as JSON text.
+func paramValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}, map[string]interface{}:
+		text, _ := json.Marshal(v)
+		return string(text)
+	}
+	return v
+}
+
+func newQueryResult(result *gosql.StatementResult) *queryResult {
+	qr := &queryResult{
+		Command:      result.Tag,
+		RowsAffected: result.RowsAffected,
+		LastInsertID: result.LastInsertID,
+		Warnings:     result.Warnings,
+	}
+	if result.Results == nil {
+		return qr
+	}
+	qr.Columns = []queryColumn{}
+	for _, col := range result.Results.Columns {
+		qr.Columns = append(qr.Columns, queryColumn{Name: col.Name, Type: typeName(col.Type)})
+	}
+	qr.Rows = [][]interface{}{}
+	for _, row := range result.Results.Rows {
+		values := make([]interface{}, len(row))
+		for i, cell := range row {
+			values[i] = jsonValue(cell, result.Results.Columns[i].Type)
+		}
+		qr.Rows = append(qr.Rows, values)
+	}
+	return qr
+}
+
+// jsonValue returns the value of cell as encoding/json should write it.
+func jsonValue(cell gosql.Cell, ct gosql.ColumnType) interface{} {
+	if cell.IsNull() {
+		return nil
+	}
+	switch ct {
+	case gosql.IntType, gosql.BigIntType:
+		return cell.AsInt()
+	case gosql.FloatType:
+		// JSON has no numbers for NaN and the infinities.
+		if f := cell.AsFloat(); !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+		return gosql.FormatFloat(cell.AsFloat())
+	case gosql.BoolType:
+		return cell.AsBool()
+	case gosql.JSONType:
+		return json.RawMessage(cell.AsText())
+	default:
+		return textValue(cell, ct)
+	}
+}
+
+// typeName names ct the way CREATE TABLE does.
+func typeName(ct gosql.ColumnType) string {
+	switch ct {
+	case gosql.IntType:
+		return "int"
+	case gosql.BigIntType:
+		return "bigint"
+	case gosql.FloatType:
+		return "float"
+	case gosql.BoolType:
+		return "boolean"
+	case gosql.DateType:
+		return "date"
+	case gosql.TimestampType:
+		return "timestamp"
+	case gosql.JSONType:
+		return "json"
+	case gosql.ByteaType:
+		return "bytea"
+	default:
+		return "text"
+	}
+}
+
+func newQueryError(source string, err error) *queryError {
+	qe := &queryError{Message: err.Error(), Code: gosql.SQLState(err)}
+	var e *gosql.Error
+	if errors.As(err, &e) {
+		qe.Position = e.Loc.Offset(source)
+		qe.Hint = e.Hint
+	}
+	return qe
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/piaoranyc/gosql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_HTTPHandler(t *testing.T) {
+	ts := httptest.NewServer(New(gosql.NewMemoryBackend()).HTTPHandler())
+	defer ts.Close()
+
+	post := func(body string) (int, string) {
+		resp, err := http.Post(ts.URL+"/query", "application/json", strings.NewReader(body))
+		assert.Nil(t, err)
+		defer resp.Body.Close()
+		var b strings.Builder
+		_, _ = io.Copy(&b, resp.Body)
+		return resp.StatusCode, b.String()
+	}
+
+	status, body := post(`{"query": "create table users (id int, name text, score float, doc json, born date); insert into users values (1, 'alice', 1.5, json '{\"a\": [1]}', date '2000-01-02'), (2, null, null, null, null)"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"results": [
+		{"command": "CREATE TABLE", "rowsAffected": 0, "columns": null, "rows": null},
+		{"command": "INSERT 0 2", "rowsAffected": 2, "columns": null, "rows": null}
+	]}`, body)
+
+	status, body = post(`{"query": "select id, name, score, doc, born from users where id >= $1 and name = $2 order by id", "params": [1, "alice"]}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"results": [{
+		"command": "SELECT 1",
+		"rowsAffected": 1,
+		"columns": [
+			{"name": "id", "type": "int"},
+			{"name": "name", "type": "text"},
+			{"name": "score", "type": "float"},
+			{"name": "doc", "type": "json"},
+			{"name": "born", "type": "date"}
+		],
+		"rows": [[1, "alice", 1.5, {"a": [1]}, "2000-01-02"]]
+	}]}`, body)
+
+	status, body = post(`{"query": "select name from users where id = 2; select nope from users; select 1 from users"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.JSONEq(t, `{
+		"results": [{"command": "SELECT 1", "rowsAffected": 1, "columns": [{"name": "name", "type": "text"}], "rows": [[null]]}],
+		"error": {"message": "Column does not exist: nope at 0:44", "code": "42703", "position": 45}
+	}`, body)
+
+	status, body = post(`{"query": "create table tags (id serial, name text); insert into tags (name) values ('a'), ('b'); drop table if exists missing"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"results": [
+		{"command": "CREATE TABLE", "rowsAffected": 0, "columns": null, "rows": null},
+		{"command": "INSERT 0 2", "rowsAffected": 2, "lastInsertId": 2, "columns": null, "rows": null},
+		{"command": "DROP TABLE", "rowsAffected": 0, "warnings": ["table missing does not exist, skipping"], "columns": null, "rows": null}
+	]}`, body)
+
+	status, body = post(`{"query": "select id from users; select id from users", "params": []}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, `"code":"`)
+
+	status, _ = post(`{"query": `)
+	assert.Equal(t, http.StatusBadRequest, status)
+	resp, err := http.Get(ts.URL + "/query")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestServer_HTTPHandler_authentication(t *testing.T) {
+	backend := gosql.NewMemoryBackend()
+	_, err := gosql.ExecuteScript(backend, "create table items (id int); create user clerk password 'secret'; grant select on items to clerk", gosql.ScriptOptions{})
+	assert.Nil(t, err)
+	ts := httptest.NewServer(New(backend).HTTPHandler())
+	defer ts.Close()
+
+	post := func(user, password, query string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/query", strings.NewReader(`{"query": "`+query+`"}`))
+		assert.Nil(t, err)
+		if user != "" {
+			req.SetBasicAuth(user, password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := post("", "", "select * from items")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, `Basic realm="gosql"`, resp.Header.Get("WWW-Authenticate"))
+	assert.Equal(t, http.StatusUnauthorized, post("clerk", "wrong", "select * from items").StatusCode)
+	assert.Equal(t, http.StatusOK, post("clerk", "secret", "select * from items").StatusCode)
+	assert.Equal(t, http.StatusBadRequest, post("clerk", "secret", "delete from items").StatusCode)
+}
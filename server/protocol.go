@@ -0,0 +1,97 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	// protocolVersion is version 3.0 of the protocol, the one modern
+	// clients speak.
+	protocolVersion   = 196608
+	sslRequestCode    = 80877103
+	gssEncRequestCode = 80877104
+	cancelRequestCode = 80877102
+
+	// authenticationCleartextPassword asks the client for its password.
+	authenticationCleartextPassword = 3
+
+	// maxMessageLength bounds the messages a client may send.
+	maxMessageLength = 1 << 24
+)
+
+var errMessageLength = errors.New("invalid message length")
+
+// readStartupMessage reads the untyped message a client opens with and
+// returns its body.
+func (cn *conn) readStartupMessage() ([]byte, error) {
+	return cn.readBody()
+}
+
+// readMessage reads a typed message and returns its type and body.
+func (cn *conn) readMessage() (byte, []byte, error) {
+	typ, err := cn.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	body, err := cn.readBody()
+	return typ, body, err
+}
+
+// readBody reads a length, which counts itself, and that many bytes.
+func (cn *conn) readBody() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(cn.r, header[:]); err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint32(header[:]))
+	if length < 4 || length > maxMessageLength {
+		return nil, errMessageLength
+	}
+
+	body := make([]byte, length-4)
+	if _, err := io.ReadFull(cn.r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// writeMessage buffers a message of type typ. Errors surface when the
+// buffer is flushed.
+func (cn *conn) writeMessage(typ byte, body []byte) {
+	_ = cn.w.WriteByte(typ)
+	_, _ = cn.w.Write(int32Bytes(len(body) + 4))
+	_, _ = cn.w.Write(body)
+}
+
+func readInt32(b []byte) int {
+	return int(int32(binary.BigEndian.Uint32(b)))
+}
+
+// readString returns the NUL-terminated string at the start of b and what
+// follows it.
+func readString(b []byte) (string, []byte) {
+	i := bytes.IndexByte(b, 0)
+	if i == -1 {
+		return string(b), nil
+	}
+	return string(b[:i]), b[i+1:]
+}
+
+func int32Bytes(i int) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(int32(i)))
+	return b
+}
+
+func int16Bytes(i int) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, uint16(int16(i)))
+	return b
+}
+
+func cString(s string) []byte {
+	return append([]byte(s), 0)
+}
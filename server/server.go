@@ -0,0 +1,392 @@
+// Package server lets PostgreSQL clients such as psql and lib/pq use a
+// gosql backend over TCP. It speaks the parts of the version 3 wire
+// protocol needed for the simple query flow: startup, with cleartext
+// password authentication once the backend has users, queries, row
+// descriptions, data rows and errors. Every connection is a session of its
+// own, with its own transaction.
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+
+	"github.com/piaoranyc/gosql"
+)
+
+type Server struct {
+	backend *gosql.MemoryBackend
+	// Logger receives connection errors. Nothing is logged when it is nil.
+	Logger *log.Logger
+}
+
+func New(backend *gosql.MemoryBackend) *Server {
+	return &Server{backend: backend}
+}
+
+// ListenAndServe accepts connections on the TCP address addr.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l until it fails, handling each in its own
+// goroutine.
+func (s *Server) Serve(l net.Listener) error {
+	defer l.Close()
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := s.serveConn(c); err != nil && !errors.Is(err, io.EOF) && s.Logger != nil {
+				s.Logger.Printf("%s: %s", c.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+type conn struct {
+	r       *bufio.Reader
+	w       *bufio.Writer
+	session *gosql.Session
+}
+
+func (s *Server) serveConn(c net.Conn) error {
+	defer c.Close()
+
+	cn := &conn{
+		r: bufio.NewReader(c),
+		w: bufio.NewWriter(c),
+	}
+	ok, err := cn.startup(s.backend)
+	if err != nil || !ok {
+		return err
+	}
+	defer cn.session.Close()
+
+	// The extended query protocol is not supported. After the error for
+	// its first message, the rest are skipped up to the Sync that ends
+	// them, as PostgreSQL does after any error in that protocol.
+	skipping := false
+	for {
+		typ, body, err := cn.readMessage()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case typ == 'X':
+			return nil
+		case typ == 'S':
+			skipping = false
+			cn.readyForQuery()
+		case skipping:
+		case typ == 'Q':
+			query, _ := readString(body)
+			cn.query(query)
+			cn.readyForQuery()
+		default:
+			cn.error(fmt.Errorf("unsupported message type %q", typ), "0A000", 0)
+			skipping = true
+		}
+		if err := cn.w.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// startup reads the startup message, turning down a request for SSL first
+// if there is one, and lets the client in, starting its session. When the
+// backend has users, the client logs in as the user of its startup message
+// with a cleartext password. It reports false when the client only wanted
+// to cancel a query, which is not supported, or failed to log in.
+func (cn *conn) startup(backend *gosql.MemoryBackend) (bool, error) {
+	var body []byte
+	for {
+		var err error
+		body, err = cn.readStartupMessage()
+		if err != nil {
+			return false, err
+		}
+		if len(body) < 4 {
+			return false, errors.New("startup message too short")
+		}
+
+		switch version := readInt32(body); version {
+		case sslRequestCode, gssEncRequestCode:
+			if err := cn.w.WriteByte('N'); err != nil {
+				return false, err
+			}
+			if err := cn.w.Flush(); err != nil {
+				return false, err
+			}
+			continue
+		case cancelRequestCode:
+			return false, nil
+		case protocolVersion:
+		default:
+			return false, fmt.Errorf("unsupported protocol version %d", version)
+		}
+		break
+	}
+
+	if !backend.HasUsers() {
+		cn.session = backend.NewSession()
+	} else {
+		session, err := cn.authenticate(backend, startupParams(body[4:])["user"])
+		if err != nil {
+			return false, err
+		}
+		if session == nil {
+			return false, cn.w.Flush()
+		}
+		cn.session = session
+	}
+
+	cn.writeMessage('R', int32Bytes(0))
+	for _, p := range [][2]string{
+		{"server_version", "9.6.0"},
+		{"server_encoding", "UTF8"},
+		{"client_encoding", "UTF8"},
+		{"DateStyle", "ISO, MDY"},
+		{"integer_datetimes", "on"},
+		{"standard_conforming_strings", "on"},
+	} {
+		cn.writeMessage('S', append(cString(p[0]), cString(p[1])...))
+	}
+	cn.readyForQuery()
+	return true, cn.w.Flush()
+}
+
+// startupParams returns the name and value pairs that follow the protocol
+// version in a startup message.
+func startupParams(b []byte) map[string]string {
+	params := map[string]string{}
+	for len(b) > 0 && b[0] != 0 {
+		var name, value string
+		name, b = readString(b)
+		value, b = readString(b)
+		params[name] = value
+	}
+	return params
+}
+
+// authenticate asks the client for the password of user and logs it in. It
+// returns a nil session, having sent the client a fatal error, when the
+// password is wrong.
+func (cn *conn) authenticate(backend *gosql.MemoryBackend, user string) (*gosql.Session, error) {
+	cn.writeMessage('R', int32Bytes(authenticationCleartextPassword))
+	if err := cn.w.Flush(); err != nil {
+		return nil, err
+	}
+	typ, body, err := cn.readMessage()
+	if err != nil {
+		return nil, err
+	}
+	if typ != 'p' {
+		return nil, fmt.Errorf("expected a password message, got %q", typ)
+	}
+	password, _ := readString(body)
+	session, err := backend.Authenticate(user, password)
+	if err != nil {
+		cn.errorResponse("FATAL", fmt.Errorf("password authentication failed for user %q", user), gosql.SQLState(err), 0)
+		return nil, nil
+	}
+	return session, nil
+}
+
+func (cn *conn) readyForQuery() {
+	status := byte('I')
+	if cn.session.InTransaction() {
+		status = 'T'
+	}
+	cn.writeMessage('Z', []byte{status})
+}
+
+// query runs each statement of a simple query as a script, so that the
+// settings of the session like its database apply, stopping at the first
+// error.
+func (cn *conn) query(source string) {
+	results, err := gosql.ExecuteScript(cn.session, source, gosql.ScriptOptions{})
+	if len(results) == 0 && err == nil {
+		cn.writeMessage('I', nil)
+		return
+	}
+
+	for _, r := range results {
+		if r.Err != nil {
+			break
+		}
+		cn.sendResult(r)
+	}
+	if err != nil {
+		cn.queryError(source, err)
+	}
+}
+
+// sendResult sends a notice for each warning of r, the rows of r if it
+// returns any and then the tag that names the command that completed.
+func (cn *conn) sendResult(r *gosql.StatementResult) {
+	for _, warning := range r.Warnings {
+		cn.notice(warning)
+	}
+	if r.Results != nil {
+		cn.results(r.Results)
+	}
+	cn.writeMessage('C', cString(r.Tag))
+}
+
+// notice sends a notice response, which clients show without failing the
+// query.
+func (cn *conn) notice(message string) {
+	var body []byte
+	body = append(body, 'S')
+	body = append(body, cString("NOTICE")...)
+	body = append(body, 'V')
+	body = append(body, cString("NOTICE")...)
+	body = append(body, 'C')
+	body = append(body, cString("00000")...)
+	body = append(body, 'M')
+	body = append(body, cString(message)...)
+	body = append(body, 0)
+	cn.writeMessage('N', body)
+}
+
+// results sends the row description and then a data row for each row, with
+// every value in text format.
+func (cn *conn) results(results *gosql.Results) {
+	desc := int16Bytes(len(results.Columns))
+	for _, col := range results.Columns {
+		oid, size := typeOID(col.Type)
+		desc = append(desc, cString(col.Name)...)
+		desc = append(desc, int32Bytes(0)...) // table
+		desc = append(desc, int16Bytes(0)...) // column number
+		desc = append(desc, int32Bytes(oid)...)
+		desc = append(desc, int16Bytes(size)...)
+		desc = append(desc, int32Bytes(-1)...) // type modifier
+		desc = append(desc, int16Bytes(0)...)  // text format
+	}
+	cn.writeMessage('T', desc)
+
+	for _, result := range results.Rows {
+		row := int16Bytes(len(result))
+		for i, cell := range result {
+			if cell.IsNull() {
+				row = append(row, int32Bytes(-1)...)
+				continue
+			}
+			value := textValue(cell, results.Columns[i].Type)
+			row = append(row, int32Bytes(len(value))...)
+			row = append(row, value...)
+		}
+		cn.writeMessage('D', row)
+	}
+}
+
+// Type OIDs from PostgreSQL's pg_type catalog.
+const (
+	boolOID      = 16
+	byteaOID     = 17
+	int8OID      = 20
+	int4OID      = 23
+	textOID      = 25
+	jsonOID      = 114
+	float8OID    = 701
+	dateOID      = 1082
+	timestampOID = 1114
+)
+
+func typeOID(ct gosql.ColumnType) (oid int, size int) {
+	switch ct {
+	case gosql.IntType:
+		return int4OID, 4
+	case gosql.BigIntType:
+		return int8OID, 8
+	case gosql.FloatType:
+		return float8OID, 8
+	case gosql.BoolType:
+		return boolOID, 1
+	case gosql.DateType:
+		return dateOID, 4
+	case gosql.TimestampType:
+		return timestampOID, 8
+	case gosql.JSONType:
+		return jsonOID, -1
+	case gosql.ByteaType:
+		return byteaOID, -1
+	default:
+		return textOID, -1
+	}
+}
+
+func textValue(cell gosql.Cell, ct gosql.ColumnType) string {
+	switch ct {
+	case gosql.IntType, gosql.BigIntType:
+		return strconv.FormatInt(cell.AsInt(), 10)
+	case gosql.FloatType:
+		return gosql.FormatFloat(cell.AsFloat())
+	case gosql.BoolType:
+		if cell.AsBool() {
+			return "t"
+		}
+		return "f"
+	case gosql.DateType:
+		return gosql.FormatDate(cell.AsTime())
+	case gosql.TimestampType:
+		return gosql.FormatTimestamp(cell.AsTime())
+	case gosql.ByteaType:
+		return gosql.FormatBytes([]byte(cell.AsText()))
+	default:
+		return cell.AsText()
+	}
+}
+
+// queryError reports err with the SQLSTATE PostgreSQL uses for the same
+// failure and, when err points into source, the position of the offending
+// token so clients can show it.
+func (cn *conn) queryError(source string, err error) {
+	position := 0
+	var e *gosql.Error
+	if errors.As(err, &e) {
+		position = e.Loc.Offset(source)
+	}
+	cn.error(err, gosql.SQLState(err), position)
+}
+
+// error sends an error response. position is left out when it is 0.
+func (cn *conn) error(err error, code string, position int) {
+	cn.errorResponse("ERROR", err, code, position)
+}
+
+// errorResponse sends err with the given severity, such as ERROR or FATAL.
+func (cn *conn) errorResponse(severity string, err error, code string, position int) {
+	var body []byte
+	body = append(body, 'S')
+	body = append(body, cString(severity)...)
+	body = append(body, 'V')
+	body = append(body, cString(severity)...)
+	body = append(body, 'C')
+	body = append(body, cString(code)...)
+	body = append(body, 'M')
+	body = append(body, cString(err.Error())...)
+	if position > 0 {
+		body = append(body, 'P')
+		body = append(body, cString(strconv.Itoa(position))...)
+	}
+	var e *gosql.Error
+	if errors.As(err, &e) && e.Hint != "" {
+		body = append(body, 'H')
+		body = append(body, cString(e.Hint)...)
+	}
+	body = append(body, 0)
+	cn.writeMessage('E', body)
+}
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"testing"
+
+	"github.com/piaoranyc/gosql"
+	"github.com/stretchr/testify/assert"
+)
+
+// client is just enough of a PostgreSQL client to drive the server.
+type client struct {
+	conn
+	c net.Conn
+}
+
+type message struct {
+	typ  byte
+	body []byte
+}
+
+func dial(t *testing.T, addr string) *client {
+	cl := connect(t, addr, "test")
+	messages := cl.readUntilReady(t)
+	assert.Equal(t, byte('R'), messages[0].typ)
+	assert.Equal(t, 0, readInt32(messages[0].body))
+	return cl
+}
+
+// connect sends the startup message for user, leaving the answer unread.
+func connect(t *testing.T, addr, user string) *client {
+	c, err := net.Dial("tcp", addr)
+	assert.Nil(t, err)
+	cl := &client{conn: conn{r: bufio.NewReader(c), w: bufio.NewWriter(c)}, c: c}
+
+	// Ask for SSL first, as psql does.
+	_, _ = cl.w.Write(append(int32Bytes(8), int32Bytes(sslRequestCode)...))
+	assert.Nil(t, cl.w.Flush())
+	answer, err := cl.r.ReadByte()
+	assert.Nil(t, err)
+	assert.Equal(t, byte('N'), answer)
+
+	body := int32Bytes(protocolVersion)
+	body = append(body, cString("user")...)
+	body = append(body, cString(user)...)
+	body = append(body, 0)
+	_, _ = cl.w.Write(append(int32Bytes(len(body)+4), body...))
+	assert.Nil(t, cl.w.Flush())
+	return cl
+}
+
+func (cl *client) readUntilReady(t *testing.T) []message {
+	var messages []message
+	for {
+		typ, body, err := cl.readMessage()
+		assert.Nil(t, err)
+		messages = append(messages, message{typ, body})
+		if typ == 'Z' {
+			return messages
+		}
+	}
+}
+
+func (cl *client) query(t *testing.T, query string) []message {
+	cl.writeMessage('Q', cString(query))
+	assert.Nil(t, cl.w.Flush())
+	return cl.readUntilReady(t)
+}
+
+// dataRows returns the values of each data row among messages, with nil
+// for NULL.
+func dataRows(messages []message) [][]interface{} {
+	var rows [][]interface{}
+	for _, m := range messages {
+		if m.typ != 'D' {
+			continue
+		}
+		var row []interface{}
+		b := m.body[2:]
+		for len(b) > 0 {
+			n := readInt32(b)
+			b = b[4:]
+			if n == -1 {
+				row = append(row, nil)
+				continue
+			}
+			row = append(row, string(b[:n]))
+			b = b[n:]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func tags(messages []message) []string {
+	var tags []string
+	for _, m := range messages {
+		if m.typ == 'C' {
+			tag, _ := readString(m.body)
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func errorFields(messages []message) map[byte]string {
+	for _, m := range messages {
+		if m.typ != 'E' {
+			continue
+		}
+		fields := map[byte]string{}
+		b := m.body
+		for len(b) > 1 {
+			value, rest := readString(b[1:])
+			fields[b[0]] = value
+			b = rest
+		}
+		return fields
+	}
+	return nil
+}
+
+func TestServer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go New(gosql.NewMemoryBackend()).Serve(l)
+
+	a := dial(t, l.Addr().String())
+	defer a.c.Close()
+	b := dial(t, l.Addr().String())
+	defer b.c.Close()
+
+	messages := a.query(t, "create table users (id int primary key, name text, admin boolean);"+
+		"insert into users values (1, 'alice', true);"+
+		"insert into users values (2, NULL, false);")
+	assert.Equal(t, []string{"CREATE TABLE", "INSERT 0 1", "INSERT 0 1"}, tags(messages))
+	assert.Equal(t, []byte{'I'}, messages[len(messages)-1].body)
+
+	messages = b.query(t, "select id, name, admin from users order by id")
+	assert.Equal(t, byte('T'), messages[0].typ)
+	desc := messages[0].body
+	assert.Equal(t, 3, int(desc[1]))
+	name, rest := readString(desc[2:])
+	assert.Equal(t, "id", name)
+	assert.Equal(t, int4OID, readInt32(rest[6:]))
+	assert.Equal(t, [][]interface{}{{"1", "alice", "t"}, {"2", nil, "f"}}, dataRows(messages))
+	assert.Equal(t, []string{"SELECT 2"}, tags(messages))
+
+	// Each connection has its own transaction.
+	messages = a.query(t, "begin; delete from users where id = 2")
+	assert.Equal(t, []string{"BEGIN", "DELETE 1"}, tags(messages))
+	assert.Equal(t, []byte{'T'}, messages[len(messages)-1].body)
+	messages = b.query(t, "select id from users")
+	assert.Equal(t, 2, len(dataRows(messages)))
+	messages = a.query(t, "commit")
+	assert.Equal(t, []byte{'I'}, messages[len(messages)-1].body)
+	messages = b.query(t, "select id from users")
+	assert.Equal(t, 1, len(dataRows(messages)))
+
+	messages = a.query(t, "insert into users values (1, 'again', false)")
+	assert.Equal(t, "23505", errorFields(messages)['C'])
+	assert.Equal(t, "ERROR", errorFields(messages)['S'])
+
+	messages = a.query(t, "select nope from users")
+	assert.Equal(t, "42703", errorFields(messages)['C'])
+	assert.Equal(t, "8", errorFields(messages)['P'])
+
+	messages = a.query(t, "select 'ééé', nope from users")
+	assert.Equal(t, "42703", errorFields(messages)['C'])
+	assert.Equal(t, "15", errorFields(messages)['P'])
+
+	messages = a.query(t, "select from")
+	assert.Equal(t, "42601", errorFields(messages)['C'])
+
+	messages = a.query(t, "selct 1")
+	assert.Equal(t, "1", errorFields(messages)['P'])
+	assert.Equal(t, "Did you mean SELECT?", errorFields(messages)['H'])
+
+	messages = a.query(t, "")
+	assert.Equal(t, byte('I'), messages[0].typ)
+
+	messages = a.query(t, "drop table if exists missing")
+	assert.Equal(t, byte('N'), messages[0].typ)
+	assert.Equal(t, []string{"DROP TABLE"}, tags(messages))
+
+	// Messages of the extended protocol are refused up to the next Sync.
+	a.writeMessage('P', append(cString(""), cString("select 1")...))
+	a.writeMessage('B', nil)
+	a.writeMessage('S', nil)
+	assert.Nil(t, a.w.Flush())
+	messages = a.readUntilReady(t)
+	assert.Equal(t, 2, len(messages))
+	assert.Equal(t, "0A000", errorFields(messages)['C'])
+}
+
+func TestServer_authentication(t *testing.T) {
+	backend := gosql.NewMemoryBackend()
+	_, err := gosql.ExecuteScript(backend, "create table items (id int); create user clerk password 'secret'; grant select on items to clerk", gosql.ScriptOptions{})
+	assert.Nil(t, err)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go New(backend).Serve(l)
+
+	login := func(password string) (*client, []message) {
+		cl := connect(t, l.Addr().String(), "clerk")
+		typ, body, err := cl.readMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, byte('R'), typ)
+		assert.Equal(t, authenticationCleartextPassword, readInt32(body))
+		cl.writeMessage('p', cString(password))
+		assert.Nil(t, cl.w.Flush())
+
+		var messages []message
+		for {
+			typ, body, err := cl.readMessage()
+			if err != nil {
+				return cl, messages
+			}
+			messages = append(messages, message{typ, body})
+			if typ == 'Z' {
+				return cl, messages
+			}
+		}
+	}
+
+	cl, messages := login("wrong")
+	cl.c.Close()
+	assert.Equal(t, "FATAL", errorFields(messages)['S'])
+	assert.Equal(t, "28P01", errorFields(messages)['C'])
+
+	cl, messages = login("secret")
+	defer cl.c.Close()
+	assert.Equal(t, byte('R'), messages[0].typ)
+	assert.Equal(t, 0, readInt32(messages[0].body))
+	assert.Equal(t, []string{"SELECT 0"}, tags(cl.query(t, "select * from items")))
+	assert.Equal(t, "42501", errorFields(cl.query(t, "insert into items values (1)"))['C'])
+}
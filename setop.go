@@ -0,0 +1,216 @@
+package gosql
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// setCursor runs slct, which combines two queries with UNION, INTERSECT
+// or EXCEPT. The combined rows are then sorted and cut down by the ORDER
+// BY, OFFSET and LIMIT of slct, which need all of them up front. Under
+// EXPLAIN ANALYZE, stages and combined are the operators of its plan, as
+// plan.stages returns them, which are measured.
+func (mb *MemoryBackend) setCursor(ctx context.Context, snap *txSnapshot, slct *SelectStatement, stages map[string]*planNode, combined *planNode) (*Rows, error) {
+	start := time.Now()
+	results, err := mb.combine(ctx, snap, slct.Set, combined)
+	if err != nil {
+		return nil, err
+	}
+	if combined != nil {
+		rows := len(results.Rows)
+		if combined.operator == "Append" {
+			// UNION keeps every row of both queries before any are
+			// dropped as duplicates.
+			rows = combined.children[0].actual.rows + combined.children[1].actual.rows
+		}
+		combined.record(start, rows)
+		stages["Unique"].record(start, len(results.Rows))
+	}
+
+	t := resultsTable(results, "")
+	rows := t.rows
+	if len(slct.OrderBy) > 0 {
+		orderBy, err := outputOrderBy(slct.OrderBy, t.outputs([]*SelectItem{{Asterisk: true}}))
+		if err != nil {
+			return nil, err
+		}
+		rows, err = t.sort(ctx, rows, orderBy)
+		if err != nil {
+			return nil, err
+		}
+		stages["Sort"].record(start, len(rows))
+	}
+
+	results.Rows = make([][]Cell, len(rows))
+	for i, row := range rows {
+		results.Rows[i] = make([]Cell, len(row))
+		for j, cell := range row {
+			results.Rows[i][j] = cell
+		}
+	}
+	results.Rows, err = t.limit(results.Rows, slct)
+	if err != nil {
+		return nil, err
+	}
+	stages["Limit"].record(start, len(results.Rows))
+	return results.Cursor(), nil
+}
+
+// combine runs both queries of set and combines their rows. Each column
+// takes the common type of the two it combines and the name the left
+// query gives it. Rows are told apart as DISTINCT tells them apart, so
+// NULLs count as equal to each other. Under EXPLAIN ANALYZE, node is the
+// operator that combines them, whose children become the plans the two
+// queries ran.
+func (mb *MemoryBackend) combine(ctx context.Context, snap *txSnapshot, set *SetOperation, node *planNode) (*Results, error) {
+	leftCtx, rightCtx := ctx, ctx
+	var arms [2]*analysis
+	if node != nil {
+		arms = [2]*analysis{{}, {}}
+		leftCtx = context.WithValue(ctx, analyzeKey{}, arms[0])
+		rightCtx = context.WithValue(ctx, analyzeKey{}, arms[1])
+	}
+	left, err := mb.query(leftCtx, snap, set.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := mb.query(rightCtx, snap, set.Right)
+	if err != nil {
+		return nil, err
+	}
+	if node != nil {
+		node.children = []*planNode{arms[0].root, arms[1].root}
+	}
+	if len(left.Columns) != len(right.Columns) {
+		return nil, ErrSetOperationColumns
+	}
+
+	columns := make([]ResultColumn, len(left.Columns))
+	for i, col := range left.Columns {
+		ct, ok := commonType(col.Type, right.Columns[i].Type)
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot combine %s with %s", ErrTypeMismatch, col.Type, right.Columns[i].Type)
+		}
+		columns[i] = ResultColumn{Type: ct, Name: col.Name}
+	}
+	leftRows, err := convertRows(left, columns)
+	if err != nil {
+		return nil, err
+	}
+	rightRows, err := convertRows(right, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	// unmatched counts the rows of the right query that no row of the
+	// left one has matched yet.
+	unmatched := map[string]int{}
+	for i, row := range rightRows {
+		if err := canceled(ctx, i); err != nil {
+			return nil, err
+		}
+		unmatched[rowKey(row, columns)]++
+	}
+
+	rows := [][]Cell{}
+	switch keyword(set.Op.Value) {
+	case UnionKeyword:
+		rows = append(leftRows, rightRows...)
+		if !set.All {
+			rows = distinct(rows, columns)
+		}
+	case IntersectKeyword:
+		for i, row := range leftRows {
+			if err := canceled(ctx, i); err != nil {
+				return nil, err
+			}
+			key := rowKey(row, columns)
+			if unmatched[key] == 0 {
+				continue
+			}
+			rows = append(rows, row)
+			if set.All {
+				unmatched[key]--
+			} else {
+				unmatched[key] = 0
+			}
+		}
+	case ExceptKeyword:
+		seen := map[string]bool{}
+		for i, row := range leftRows {
+			if err := canceled(ctx, i); err != nil {
+				return nil, err
+			}
+			key := rowKey(row, columns)
+			if unmatched[key] > 0 {
+				if set.All {
+					unmatched[key]--
+				}
+				continue
+			}
+			if !set.All {
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	return &Results{Columns: columns, Rows: rows}, nil
+}
+
+// convertRows converts the rows of results to the types of columns.
+func convertRows(results *Results, columns []ResultColumn) ([][]Cell, error) {
+	rows := make([][]Cell, len(results.Rows))
+	for i, row := range results.Rows {
+		rows[i] = make([]Cell, len(row))
+		for j, cell := range row {
+			converted, err := convertCell(cell.(MemoryCell), results.Columns[j].Type, columns[j].Type)
+			if err != nil {
+				return nil, err
+			}
+			rows[i][j] = converted
+		}
+	}
+	return rows, nil
+}
+
+// planSet plans combining the rows of the queries of set. Matching rows
+// up takes a step for each row of either.
+func (mb *MemoryBackend) planSet(snap *txSnapshot, set *SetOperation) (*planNode, error) {
+	left, err := mb.plan(snap, set.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := mb.plan(snap, set.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	children := []*planNode{left, right}
+	cost := left.cost + right.cost
+	var operator string
+	var rows int
+	switch keyword(set.Op.Value) {
+	case UnionKeyword:
+		node := &planNode{operator: "Append", rows: left.rows + right.rows, cost: cost, children: children}
+		if set.All {
+			return node, nil
+		}
+		return &planNode{operator: "Unique", rows: node.rows, cost: node.cost + float64(node.rows), children: []*planNode{node}}, nil
+	case IntersectKeyword:
+		operator, rows = "SetOp Intersect", left.rows
+		if right.rows < rows {
+			rows = right.rows
+		}
+	default:
+		operator, rows = "SetOp Except", left.rows
+	}
+	if set.All {
+		operator += " All"
+	}
+	return &planNode{operator: operator, rows: rows, cost: cost + float64(left.rows+right.rows), children: children}, nil
+}
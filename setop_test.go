@@ -0,0 +1,103 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetOperation(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table a (x int, y text);"+
+		"create table b (x bigint, y text);"+
+		"insert into a values (1, 'one');"+
+		"insert into a values (2, 'two');"+
+		"insert into a values (2, 'two');"+
+		"insert into a values (3, null);"+
+		"insert into b values (2, 'two');"+
+		"insert into b values (3, null);"+
+		"insert into b values (4, 'four')", ScriptOptions{})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		query string
+		rows  [][]Cell
+	}{
+		{
+			"select x from a union select x from b order by x",
+			[][]Cell{{bigIntCell(1)}, {bigIntCell(2)}, {bigIntCell(3)}, {bigIntCell(4)}},
+		},
+		{
+			"select x from a union all select x from b order by x desc limit 3",
+			[][]Cell{{bigIntCell(4)}, {bigIntCell(3)}, {bigIntCell(3)}},
+		},
+		{
+			"select x, y from a intersect select x, y from b order by x",
+			[][]Cell{{bigIntCell(2), MemoryCell("two")}, {bigIntCell(3), nullCell}},
+		},
+		{
+			"select x from a intersect all select x from a where x = 2",
+			[][]Cell{{intCell(2)}, {intCell(2)}},
+		},
+		{
+			"select x from a except select x from b",
+			[][]Cell{{bigIntCell(1)}},
+		},
+		{
+			"select x from a except all select x from b order by x",
+			[][]Cell{{bigIntCell(1)}, {bigIntCell(2)}},
+		},
+		{
+			// INTERSECT binds tighter than UNION.
+			"select x from a where x = 1 union select x from a intersect select x from b order by x",
+			[][]Cell{{bigIntCell(1)}, {bigIntCell(2)}, {bigIntCell(3)}},
+		},
+		{
+			"select x from b where x in (select x from a union select 4 from a) order by x",
+			[][]Cell{{bigIntCell(2)}, {bigIntCell(3)}, {bigIntCell(4)}},
+		},
+	}
+
+	for _, test := range tests {
+		results, err := ExecuteScript(mb, test.query, ScriptOptions{})
+		assert.Nil(t, err, test.query)
+		if err == nil {
+			assert.Equal(t, test.rows, results[0].Results.Rows, test.query)
+		}
+	}
+
+	results, err := ExecuteScript(mb, "select y as name from a union select y from b", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{Type: TextType, Name: "name"}}, results[0].Results.Columns)
+
+	results, err = ExecuteScript(mb, "explain select x from a union select x from b", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, MemoryCell("Unique  (cost=21.00 rows=7)"), results[0].Results.Rows[0][0])
+	assert.Equal(t, MemoryCell("  ->  Append  (cost=14.00 rows=7)"), results[0].Results.Rows[1][0])
+
+	_, err = ExecuteScript(mb, "select x, y from a union select x from b", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrSetOperationColumns)
+	_, err = ExecuteScript(mb, "select x from a union select y from b", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+	_, err = ExecuteScript(mb, "select x from a union select x from b order by a.x", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrColumnDoesNotExist)
+}
+
+func TestParse_setOperation(t *testing.T) {
+	ast, err := Parse("select a from t union all select b from u intersect select c from v order by a limit 1")
+	assert.Nil(t, err)
+
+	slct := ast.Statements[0].SelectStatement
+	assert.Equal(t, "union", slct.Set.Op.Value)
+	assert.True(t, slct.Set.All)
+	assert.Equal(t, "t", slct.Set.Left.From.Value)
+	assert.Equal(t, "intersect", slct.Set.Right.Set.Op.Value)
+	assert.Equal(t, 1, len(slct.OrderBy))
+	assert.NotNil(t, slct.Limit)
+	assert.Nil(t, slct.Set.Right.Set.Right.OrderBy)
+
+	assert.Equal(t, "SELECT a\nFROM t\nUNION ALL\nSELECT b\nFROM u\nINTERSECT\nSELECT c\nFROM v\nORDER BY a\nLIMIT 1", Format(ast.Statements[0]))
+
+	_, err = Parse("select a from t union")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,281 @@
+package gosql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// setting is a setting of a session, which SET changes and SHOW reads.
+type setting struct {
+	// set parses value, which may be DEFAULT, and stores it in s.
+	set func(s *Session, value *Token) error
+	// show returns the value s has, as text that set takes back.
+	show func(s *Session) string
+}
+
+// OutputFormats are the values output_format takes, the ways a client
+// such as the gosql shell may print results.
+var OutputFormats = []string{"table", "csv", "json", "vertical"}
+
+// defaultSearchPath is the search_path of a session that has not set it.
+var defaultSearchPath = []string{"public"}
+
+var settings = map[string]setting{
+	// statement_timeout limits how long ExecuteContext lets each statement
+	// run before canceling it with ErrStatementTimeout. It is given in
+	// milliseconds or as a duration like '5s', and 0 or DEFAULT turns it
+	// off.
+	"statement_timeout": {
+		set: func(s *Session, value *Token) error {
+			timeout, err := parseTimeout(value)
+			if err == nil {
+				s.timeout = timeout
+			}
+			return err
+		},
+		show: func(s *Session) string {
+			if s.timeout == 0 {
+				return "0"
+			}
+			return s.timeout.String()
+		},
+	},
+	// work_mem limits the memory a hash join may hash rows in before
+	// failing with ErrWorkMemExceeded, and past which ORDER BY sorts on
+	// disk. It is given in kilobytes or as a size like '64MB'.
+	"work_mem": {
+		set: func(s *Session, value *Token) error {
+			n, err := parseMemory(value)
+			if err == nil {
+				s.workMem = n
+			}
+			return err
+		},
+		show: func(s *Session) string {
+			return formatMemory(s.workMem)
+		},
+	},
+	// output_format is how the client should print results, one of
+	// OutputFormats. The backend itself only keeps it.
+	"output_format": {
+		set: func(s *Session, value *Token) error {
+			if isDefault(value) {
+				s.outputFormat = OutputFormats[0]
+				return nil
+			}
+			for _, format := range OutputFormats {
+				if strings.EqualFold(value.Value, format) {
+					s.outputFormat = format
+					return nil
+				}
+			}
+			return fmt.Errorf("%w: %s", ErrInvalidSettingValue, value.Value)
+		},
+		show: func(s *Session) string {
+			return s.outputFormat
+		},
+	},
+	// case_sensitive keeps the case of names that are not quoted in the
+	// scripts the session runs after the one setting it, instead of
+	// folding them to lower case. It is on or off.
+	"case_sensitive": {
+		set: func(s *Session, value *Token) error {
+			on, err := parseOnOff(value)
+			if err == nil {
+				s.caseSensitive = on
+			}
+			return err
+		},
+		show: func(s *Session) string {
+			if s.caseSensitive {
+				return "on"
+			}
+			return "off"
+		},
+	},
+	// search_path lists the schemas, public and information_schema, that
+	// the tables queries read are looked for in, in order. Putting
+	// information_schema first lets queries name its views without the
+	// schema.
+	"search_path": {
+		set: func(s *Session, value *Token) error {
+			path, err := parseSearchPath(value)
+			if err == nil {
+				s.searchPath = path
+			}
+			return err
+		},
+		show: func(s *Session) string {
+			return strings.Join(s.searchPath, ", ")
+		},
+	},
+	// log_min_duration_statement is how long a statement of the session
+	// has to run for to count as slow and go in the slow query log, given
+	// like statement_timeout. '-1' or DEFAULT leaves every statement out,
+	// while 0 logs them all.
+	"log_min_duration_statement": {
+		set: func(s *Session, value *Token) error {
+			if isDefault(value) || value.Value == "-1" {
+				s.slowQuery = -1
+				return nil
+			}
+			threshold, err := parseTimeout(value)
+			if err == nil {
+				s.slowQuery = threshold
+			}
+			return err
+		},
+		show: func(s *Session) string {
+			if s.slowQuery < 0 {
+				return "-1"
+			}
+			return s.slowQuery.String()
+		},
+	},
+	// database is the database that the scripts the session runs make and
+	// look up tables and views in, which USE sets too.
+	"database": {
+		set: func(s *Session, value *Token) error {
+			if isDefault(value) {
+				s.database = defaultDatabase
+				return nil
+			}
+			s.mb.mu.RLock()
+			ok := s.mb.hasDatabase(value.Value)
+			s.mb.mu.RUnlock()
+			if !ok {
+				return fmt.Errorf("%w: %s", ErrDatabaseDoesNotExist, value.Value)
+			}
+			s.database = value.Value
+			return nil
+		},
+		show: func(s *Session) string {
+			return s.database
+		},
+	},
+}
+
+// Set changes a setting of the session, one of statement_timeout,
+// work_mem, output_format, case_sensitive, search_path,
+// log_min_duration_statement and database.
+func (s *Session) Set(set *SetStatement) error {
+	st, ok := settings[set.Name.Value]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSetting, set.Name.Value)
+	}
+	return st.set(s, set.Value)
+}
+
+// Show returns the value of the setting called name as text.
+func (s *Session) Show(name string) (string, error) {
+	st, ok := settings[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownSetting, name)
+	}
+	return st.show(s), nil
+}
+
+func (mb *MemoryBackend) Set(set *SetStatement) error {
+	return mb.session.Set(set)
+}
+
+func (mb *MemoryBackend) Show(name string) (string, error) {
+	return mb.session.Show(name)
+}
+
+// show runs SHOW, whose result is the value of the setting it names, or
+// the name and value of every setting for SHOW ALL.
+func show(ex Executor, shw *ShowStatement) (*Results, error) {
+	if shw.Name != nil {
+		value, err := ex.Show(shw.Name.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &Results{
+			Columns: []ResultColumn{{Type: TextType, Name: shw.Name.Value}},
+			Rows:    [][]Cell{{MemoryCell(value)}},
+		}, nil
+	}
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	results := &Results{Columns: []ResultColumn{{Type: TextType, Name: "name"}, {Type: TextType, Name: "setting"}}}
+	for _, name := range names {
+		value, err := ex.Show(name)
+		if err != nil {
+			return nil, err
+		}
+		results.Rows = append(results.Rows, []Cell{MemoryCell(name), MemoryCell(value)})
+	}
+	return results, nil
+}
+
+// isDefault reports whether value is DEFAULT, which sets a setting back
+// to what a new session has.
+func isDefault(value *Token) bool {
+	return value.Kind == KeywordKind && keyword(value.Value) == DefaultKeyword
+}
+
+// parseOnOff reads the value of a setting that is on or off, which may
+// also be given as true or false, or DEFAULT for off.
+func parseOnOff(value *Token) (bool, error) {
+	if isDefault(value) {
+		return false, nil
+	}
+	switch strings.ToLower(value.Value) {
+	case "on", "true", "1":
+		return true, nil
+	case "off", "false", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("%w: %s", ErrInvalidSettingValue, value.Value)
+}
+
+// parseSearchPath reads a search path: a schema, or a string listing them
+// separated by commas, or DEFAULT for public alone.
+func parseSearchPath(value *Token) ([]string, error) {
+	if isDefault(value) {
+		return defaultSearchPath, nil
+	}
+	var path []string
+	for _, schema := range strings.Split(value.Value, ",") {
+		schema = strings.ToLower(strings.TrimSpace(schema))
+		if schema != "public" && schema != "information_schema" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSettingValue, value.Value)
+		}
+		path = append(path, schema)
+	}
+	return path, nil
+}
+
+// formatMemory writes n bytes, a whole number of kilobytes, in the largest
+// unit that holds a whole number of them, as parseMemory reads it.
+func formatMemory(n int64) string {
+	for _, unit := range []string{"GB", "MB"} {
+		if size := memoryUnits[unit]; n%size == 0 {
+			return fmt.Sprintf("%d%s", n/size, unit)
+		}
+	}
+	return fmt.Sprintf("%dkB", n/memoryUnits["kB"])
+}
+
+// scriptSettings are the settings of ex that change how a script it runs
+// is read: whether names keep their case, the database and the search
+// path.
+func scriptSettings(ex Executor) (caseSensitive bool, database string, path []string) {
+	database, path = defaultDatabase, defaultSearchPath
+	if on, err := ex.Show("case_sensitive"); err == nil {
+		caseSensitive = on == "on"
+	}
+	if value, err := ex.Show("database"); err == nil {
+		database = value
+	}
+	if value, err := ex.Show("search_path"); err == nil {
+		path = strings.Split(value, ", ")
+	}
+	return caseSensitive, database, path
+}
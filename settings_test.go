@@ -0,0 +1,85 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession_Show(t *testing.T) {
+	mb := NewMemoryBackend()
+	for _, test := range []struct {
+		source   string
+		expected string
+		err      error
+	}{
+		{"show statement_timeout", "0", nil},
+		{"set statement_timeout = '1500ms'; show statement_timeout", "1.5s", nil},
+		{"show work_mem", "64MB", nil},
+		{"set work_mem = 1536; show work_mem", "1536kB", nil},
+		{"set work_mem = '2GB'; show work_mem", "2GB", nil},
+		{"set work_mem = default; show work_mem", "64MB", nil},
+		{"show output_format", "table", nil},
+		{"set output_format = 'CSV'; show output_format", "csv", nil},
+		{"set output_format = xml", "", ErrInvalidSettingValue},
+		{"show case_sensitive", "off", nil},
+		{"set case_sensitive = true; show case_sensitive", "on", nil},
+		{"set case_sensitive = default; show case_sensitive", "off", nil},
+		{"set case_sensitive = 'maybe'", "", ErrInvalidSettingValue},
+		{"show search_path", "public", nil},
+		{"set search_path = 'information_schema, public'; show search_path", "information_schema, public", nil},
+		{"set search_path = private", "", ErrInvalidSettingValue},
+		{"set search_path = default; show search_path", "public", nil},
+		{"show datestyle", "", ErrUnknownSetting},
+	} {
+		results, err := ExecuteScript(mb, test.source, ScriptOptions{})
+		if test.err != nil {
+			assert.ErrorIs(t, err, test.err, test.source)
+			continue
+		}
+		assert.Nil(t, err, test.source)
+		shw := results[len(results)-1]
+		assert.Equal(t, "SHOW", shw.Tag, test.source)
+		assert.Equal(t, test.expected, shw.Results.Rows[0][0].AsText(), test.source)
+	}
+
+	results, err := ExecuteScript(mb, "show all", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "name", results[0].Results.Columns[0].Name)
+	assert.Len(t, results[0].Results.Rows, len(settings))
+	assert.Equal(t, "case_sensitive", results[0].Results.Rows[0][0].AsText())
+
+	// Sessions have settings of their own.
+	s := mb.NewSession()
+	_, err = ExecuteScript(s, "set output_format = json", ScriptOptions{})
+	assert.Nil(t, err)
+	format, err := mb.Show("output_format")
+	assert.Nil(t, err)
+	assert.Equal(t, "csv", format)
+}
+
+func TestSession_searchPath(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table users (id int)", ScriptOptions{})
+	assert.Nil(t, err)
+
+	_, err = ExecuteScript(mb, "select table_name from tables", ScriptOptions{})
+	assert.NotNil(t, err)
+
+	results, err := ExecuteScript(mb, "set search_path = 'information_schema, public'; select table_name from tables where table_name = 'users'; select * from users", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "users", results[1].Results.Rows[0][0].AsText())
+	assert.Equal(t, "SELECT 0", results[2].Tag)
+}
+
+func TestSession_caseSensitive(t *testing.T) {
+	mb := NewMemoryBackend()
+	// The setting is read before the script runs, so it is not yet on for
+	// the table created along with it.
+	_, err := ExecuteScript(mb, "set case_sensitive = on; create table Users (id int)", ScriptOptions{})
+	assert.Nil(t, err)
+	_, err = ExecuteScript(mb, "create table Users (ID int); select ID from Users", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, mb.Schema(), "users")
+	assert.Contains(t, mb.Schema(), "Users")
+}
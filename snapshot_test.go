@@ -0,0 +1,91 @@
+package gosql
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBackend_WriteSnapshot(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, `create table users (id int primary key auto_increment, name text not null, joined date);
+insert into users (name, joined) values ('alice', date '2024-01-02'), ('bob', null);
+create index users_name on users (name);
+create view names as select name from users`, ScriptOptions{})
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	assert.Nil(t, mb.WriteSnapshot(context.Background(), &buf))
+	assert.Equal(t, snapshotMagic, buf.String()[:len(snapshotMagic)])
+
+	loaded, err := LoadSnapshot(bytes.NewReader(buf.Bytes()))
+	assert.Nil(t, err)
+	for _, query := range []string{"select * from users", "select * from names"} {
+		want, err := ExecuteScript(mb, query, ScriptOptions{})
+		assert.Nil(t, err)
+		got, err := ExecuteScript(loaded, query, ScriptOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, want[0].Results, got[0].Results, query)
+	}
+	results, err := ExecuteScript(loaded, "insert into users (name) values ('carol') returning id", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), results[0].Results.Rows[0][0].AsInt())
+	assert.NotNil(t, loaded.tables["users"].indexOn(1))
+
+	// A flipped bit in the payload fails the checksum.
+	corrupt := append([]byte{}, buf.Bytes()...)
+	corrupt[len(corrupt)-5] ^= 1
+	_, err = LoadSnapshot(bytes.NewReader(corrupt))
+	assert.ErrorIs(t, err, ErrCorruptSnapshot)
+	assert.Equal(t, "XX001", SQLState(err))
+
+	// So does one cut short.
+	_, err = LoadSnapshot(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	assert.ErrorIs(t, err, ErrCorruptSnapshot)
+
+	// A later version is refused.
+	newer := append([]byte{}, buf.Bytes()...)
+	binary.BigEndian.PutUint32(newer[len(snapshotMagic):], snapshotVersion+1)
+	_, err = LoadSnapshot(bytes.NewReader(newer))
+	assert.ErrorIs(t, err, ErrUnsupportedSnapshot)
+
+	// Snapshots from before the format was versioned still load.
+	var legacy bytes.Buffer
+	assert.Nil(t, gob.NewEncoder(&legacy).Encode(mb.storedSnapshot()))
+	loaded, err = LoadSnapshot(&legacy)
+	assert.Nil(t, err)
+	assert.Len(t, loaded.tables["users"].versions, 2)
+}
+
+func TestDiskBackend_checkpoint(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDiskBackend(dir)
+	assert.Nil(t, err)
+	_, err = ExecuteScript(db, "create table t (id int); insert into t values (1), (2)", ScriptOptions{})
+	assert.Nil(t, err)
+
+	wal, err := os.Stat(filepath.Join(dir, walFile))
+	assert.Nil(t, err)
+	assert.NotZero(t, wal.Size())
+	assert.Nil(t, db.Checkpoint())
+	wal, err = os.Stat(filepath.Join(dir, walFile))
+	assert.Nil(t, err)
+	assert.Zero(t, wal.Size())
+	assert.Nil(t, db.Close())
+
+	// A damaged snapshot stops the database from opening rather than
+	// losing rows.
+	path := filepath.Join(dir, snapshotFile)
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	data[len(data)-1] ^= 1
+	assert.Nil(t, os.WriteFile(path, data, 0o644))
+	_, err = NewDiskBackend(dir)
+	assert.ErrorIs(t, err, ErrCorruptSnapshot)
+}
@@ -0,0 +1,283 @@
+package sqllogictest
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/piaoranyc/gosql"
+)
+
+// Result is how the records of a script fared. Control lines and records
+// meant for other engines do not count as passed.
+type Result struct {
+	Passed, Skipped int
+	Failures        []*Failure
+}
+
+// Failure is a record that did not do what its script expected.
+type Failure struct {
+	Script string
+	Line   int
+	SQL    string
+	Reason string
+}
+
+func (f *Failure) Error() string {
+	return fmt.Sprintf("%s:%d: %s", f.Script, f.Line, f.Reason)
+}
+
+// Add adds the counts and failures of other to r, to report on several
+// scripts at once.
+func (r *Result) Add(other *Result) {
+	r.Passed += other.Passed
+	r.Skipped += other.Skipped
+	r.Failures = append(r.Failures, other.Failures...)
+}
+
+// Conformance returns the share of the records run that passed, from 0 to
+// 1, or 1 when none ran.
+func (r *Result) Conformance() float64 {
+	run := r.Passed + len(r.Failures)
+	if run == 0 {
+		return 1
+	}
+	return float64(r.Passed) / float64(run)
+}
+
+func (r *Result) String() string {
+	return fmt.Sprintf("%d passed, %d failed, %d skipped (%.1f%%)", r.Passed, len(r.Failures), r.Skipped, 100*r.Conformance())
+}
+
+// hashPattern matches the results of a query given as their hash.
+var hashPattern = regexp.MustCompile(`^\d+ values hashing to [0-9a-f]{32}$`)
+
+// Run runs the records of script on ex, which starts out as the script
+// expects, usually empty. A failing record does not stop the script.
+func Run(ctx context.Context, ex gosql.Executor, script *Script) (*Result, error) {
+	r := &runner{ex: ex, labels: map[string]string{}}
+	result := &Result{}
+	for _, record := range script.Records {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if record.skipped() {
+			if record.Kind == StatementRecord || record.Kind == QueryRecord {
+				result.Skipped++
+			}
+			continue
+		}
+
+		var reason string
+		switch record.Kind {
+		case StatementRecord:
+			reason = r.statement(ctx, record)
+		case QueryRecord:
+			reason = r.query(ctx, record)
+		case HashThresholdRecord:
+			r.threshold = record.Threshold
+			continue
+		case HaltRecord:
+			return result, nil
+		}
+		if reason == "" {
+			result.Passed++
+			continue
+		}
+		result.Failures = append(result.Failures, &Failure{Script: script.Name, Line: record.Line, SQL: record.SQL, Reason: reason})
+	}
+	return result, nil
+}
+
+// RunFile parses the script in the file at path and runs it on ex.
+func RunFile(ctx context.Context, ex gosql.Executor, path string) (*Result, error) {
+	script, err := ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Run(ctx, ex, script)
+}
+
+type runner struct {
+	ex        gosql.Executor
+	threshold int
+	// labels maps the labels of the queries run so far to the hashes of
+	// their results.
+	labels map[string]string
+}
+
+// statement runs record and returns why it failed, or "" when it did what
+// it was expected to.
+func (r *runner) statement(ctx context.Context, record *Record) string {
+	_, err := gosql.ExecuteScriptContext(ctx, r.ex, record.SQL, gosql.ScriptOptions{})
+	switch {
+	case err != nil && !record.ExpectError:
+		return "unexpected error: " + err.Error()
+	case err == nil && record.ExpectError:
+		return "expected an error"
+	case err != nil && record.ErrorPattern != nil && !record.ErrorPattern.MatchString(err.Error()):
+		return fmt.Sprintf("expected an error matching %q, got: %s", record.ErrorPattern, err)
+	}
+	return ""
+}
+
+// query runs record and returns why its results differ from those
+// expected, or "" when they do not.
+func (r *runner) query(ctx context.Context, record *Record) string {
+	results, err := gosql.ExecuteScriptContext(ctx, r.ex, record.SQL, gosql.ScriptOptions{})
+	if err != nil {
+		return "unexpected error: " + err.Error()
+	}
+	if len(results) == 0 || results[len(results)-1].Results == nil {
+		return "expected a query"
+	}
+	last := results[len(results)-1].Results
+	if len(last.Columns) != len(record.Types) {
+		return fmt.Sprintf("expected %d columns, got %d", len(record.Types), len(last.Columns))
+	}
+
+	rows := make([][]string, len(last.Rows))
+	for i, row := range last.Rows {
+		for j, cell := range row {
+			rows[i] = append(rows[i], formatValue(cell, last.Columns[j].Type, record.Types[j]))
+		}
+	}
+	if record.SortMode == RowSort {
+		sort.SliceStable(rows, func(i, j int) bool {
+			for k := range rows[i] {
+				if rows[i][k] != rows[j][k] {
+					return rows[i][k] < rows[j][k]
+				}
+			}
+			return false
+		})
+	}
+	var values []string
+	for _, row := range rows {
+		values = append(values, row...)
+	}
+	if record.SortMode == ValueSort {
+		sort.Strings(values)
+	}
+
+	hash := hashValues(values)
+	if record.Label != "" {
+		if other, ok := r.labels[record.Label]; ok && other != hash {
+			return fmt.Sprintf("results differ from the earlier query labeled %s", record.Label)
+		}
+		r.labels[record.Label] = hash
+	}
+
+	if len(record.Expected) == 1 {
+		if hashPattern.MatchString(record.Expected[0]) {
+			if got := fmt.Sprintf("%d values hashing to %s", len(values), hash); got != record.Expected[0] {
+				return fmt.Sprintf("expected %s, got %s", record.Expected[0], got)
+			}
+			return ""
+		}
+	}
+	if !equal(values, record.Expected) {
+		if r.threshold > 0 && len(values) > r.threshold {
+			return fmt.Sprintf("expected %d values, got %d values hashing to %s", len(record.Expected), len(values), hash)
+		}
+		return fmt.Sprintf("expected:\n%s\ngot:\n%s", strings.Join(record.Expected, "\n"), strings.Join(values, "\n"))
+	}
+	return ""
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// hashValues hashes values the way sqllogictest does: the MD5 of each of
+// them followed by a line break.
+func hashValues(values []string) string {
+	h := md5.New()
+	for _, v := range values {
+		h.Write([]byte(v))
+		h.Write([]byte("\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// formatValue writes cell, of type ct, as sqllogictest writes a value of
+// a column of type t: NULL for nulls, an integer for I, a number with
+// three decimals for R and text for T, with an empty one written as
+// (empty) and each byte that is not printable ASCII as @.
+func formatValue(cell gosql.Cell, ct gosql.ColumnType, t byte) string {
+	if cell.IsNull() {
+		return "NULL"
+	}
+	switch t {
+	case 'I':
+		switch ct {
+		case gosql.IntType, gosql.BigIntType:
+			return strconv.FormatInt(cell.AsInt(), 10)
+		case gosql.FloatType:
+			return strconv.FormatInt(int64(math.Trunc(cell.AsFloat())), 10)
+		case gosql.BoolType:
+			if cell.AsBool() {
+				return "1"
+			}
+			return "0"
+		}
+		n, _ := strconv.ParseInt(strings.TrimSpace(text(cell, ct)), 10, 64)
+		return strconv.FormatInt(n, 10)
+	case 'R':
+		var f float64
+		switch ct {
+		case gosql.IntType, gosql.BigIntType:
+			f = float64(cell.AsInt())
+		case gosql.FloatType:
+			f = cell.AsFloat()
+		default:
+			f, _ = strconv.ParseFloat(strings.TrimSpace(text(cell, ct)), 64)
+		}
+		return strconv.FormatFloat(f, 'f', 3, 64)
+	}
+
+	s := text(cell, ct)
+	if s == "" {
+		return "(empty)"
+	}
+	b := []byte(s)
+	for i, c := range b {
+		if c < ' ' || c > '~' {
+			b[i] = '@'
+		}
+	}
+	return string(b)
+}
+
+// text is the text of cell, of type ct.
+func text(cell gosql.Cell, ct gosql.ColumnType) string {
+	switch ct {
+	case gosql.IntType, gosql.BigIntType:
+		return strconv.FormatInt(cell.AsInt(), 10)
+	case gosql.FloatType:
+		return gosql.FormatFloat(cell.AsFloat())
+	case gosql.BoolType:
+		return strconv.FormatBool(cell.AsBool())
+	case gosql.DateType:
+		return gosql.FormatDate(cell.AsTime())
+	case gosql.TimestampType:
+		return gosql.FormatTimestamp(cell.AsTime())
+	case gosql.ByteaType:
+		return gosql.FormatBytes([]byte(cell.AsText()))
+	}
+	return cell.AsText()
+}
@@ -0,0 +1,273 @@
+// Package sqllogictest runs scripts in the format of SQLite's sqllogictest
+// against a gosql database and reports how many of their records it gets
+// right. A script is a series of records separated by blank lines:
+//
+//	statement ok
+//	CREATE TABLE t1(a INT, b TEXT)
+//
+//	query IT rowsort
+//	SELECT a, b FROM t1
+//	----
+//	1
+//	one
+//
+// A statement is expected to succeed, or to fail with statement error. A
+// query lists the types of its columns, I for integers, R for reals and T
+// for text, and an optional sort mode, nosort, rowsort or valuesort, and
+// label. Below ---- come the values it returns, one per line or those of
+// a row separated by tabs, or "N values hashing to MD5" for large results.
+// Queries that share a label must return the same values. skipif and
+// onlyif lines before a record name the engines it is meant for, gosql
+// being this one, hash-threshold sets how many values a result may have
+// before the scripts give its hash instead, and halt ends the script.
+package sqllogictest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Engine is the name skipif and onlyif lines call gosql by.
+const Engine = "gosql"
+
+// RecordKind says what a record of a script is.
+type RecordKind uint
+
+const (
+	StatementRecord RecordKind = iota
+	QueryRecord
+	HashThresholdRecord
+	HaltRecord
+)
+
+// Condition is a skipif or onlyif line, which runs the record it comes
+// before only on engines other than Engine, or only on Engine.
+type Condition struct {
+	Skip   bool
+	Engine string
+}
+
+// Record is one statement, query or control line of a script, found at
+// Line of it. SQL is the statement or query to run.
+//
+// A statement is expected to fail when ExpectError is set, with a message
+// matching ErrorPattern if there is one. A query returns values of the
+// Types of its columns, which SortMode sorts before they are compared
+// with Expected, and Label names the results it must share with the other
+// queries of the script that have the same label. Threshold is the value
+// of a hash-threshold line.
+type Record struct {
+	Kind       RecordKind
+	Line       int
+	Conditions []Condition
+	SQL        string
+
+	ExpectError  bool
+	ErrorPattern *regexp.Regexp
+
+	Types    string
+	SortMode string
+	Label    string
+	Expected []string
+
+	Threshold int
+}
+
+// Script is a parsed sqllogictest file.
+type Script struct {
+	Name    string
+	Records []*Record
+}
+
+// The sort modes of a query.
+const (
+	NoSort    = "nosort"
+	RowSort   = "rowsort"
+	ValueSort = "valuesort"
+)
+
+// resultsSeparator ends the SQL of a query and starts its results.
+const resultsSeparator = "----"
+
+// ParseFile parses the script in the file at path.
+func ParseFile(path string) (*Script, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(path, f)
+}
+
+// Parse parses the script read from r. name is what errors and failures
+// call the script.
+func Parse(name string, r io.Reader) (*Script, error) {
+	p := &parser{name: name, scanner: bufio.NewScanner(r)}
+	p.scanner.Buffer(nil, 1<<24)
+	script := &Script{Name: name}
+	var conditions []Condition
+	for {
+		line, ok := p.next()
+		if !ok {
+			break
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		record := &Record{Line: p.line}
+		switch fields[0] {
+		case "skipif", "onlyif":
+			if len(fields) < 2 {
+				return nil, p.errorf("%s needs an engine", fields[0])
+			}
+			conditions = append(conditions, Condition{Skip: fields[0] == "skipif", Engine: fields[1]})
+			continue
+		case "statement":
+			if err := p.statement(record, fields); err != nil {
+				return nil, err
+			}
+		case "query":
+			if err := p.query(record, fields); err != nil {
+				return nil, err
+			}
+		case "hash-threshold":
+			n, err := strconv.Atoi(strings.Join(fields[1:], ""))
+			if err != nil || n < 0 {
+				return nil, p.errorf("invalid hash-threshold %q", line)
+			}
+			record.Kind, record.Threshold = HashThresholdRecord, n
+		case "halt":
+			record.Kind = HaltRecord
+		default:
+			return nil, p.errorf("unknown record %q", fields[0])
+		}
+		record.Conditions, conditions = conditions, nil
+		script.Records = append(script.Records, record)
+	}
+	if err := p.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return script, nil
+}
+
+type parser struct {
+	name    string
+	scanner *bufio.Scanner
+	line    int
+}
+
+// next returns the next line, without its line break.
+func (p *parser) next() (string, bool) {
+	if !p.scanner.Scan() {
+		return "", false
+	}
+	p.line++
+	return strings.TrimRight(p.scanner.Text(), "\r"), true
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s:%d: %s", p.name, p.line, fmt.Sprintf(format, args...))
+}
+
+// block reads lines up to a blank one or the end of the script. It stops
+// early at stop, which it consumes, when stop is not empty, and reports
+// whether it did.
+func (p *parser) block(stop string) ([]string, bool) {
+	var lines []string
+	for {
+		line, ok := p.next()
+		if !ok || strings.TrimSpace(line) == "" {
+			return lines, false
+		}
+		if stop != "" && line == stop {
+			return lines, true
+		}
+		lines = append(lines, line)
+	}
+}
+
+// statement parses a statement ok or statement error record, whose
+// header line has fields.
+func (p *parser) statement(record *Record, fields []string) error {
+	record.Kind = StatementRecord
+	if len(fields) < 2 {
+		return p.errorf("statement needs ok or error")
+	}
+	switch fields[1] {
+	case "ok":
+	case "error":
+		record.ExpectError = true
+		if len(fields) > 2 {
+			pattern, err := regexp.Compile(strings.Join(fields[2:], " "))
+			if err != nil {
+				return p.errorf("invalid error pattern: %s", err)
+			}
+			record.ErrorPattern = pattern
+		}
+	default:
+		return p.errorf("statement needs ok or error, got %q", fields[1])
+	}
+	lines, _ := p.block("")
+	record.SQL = strings.Join(lines, "\n")
+	if record.SQL == "" {
+		return p.errorf("statement has no SQL")
+	}
+	return nil
+}
+
+// query parses a query record, whose header line has fields.
+func (p *parser) query(record *Record, fields []string) error {
+	record.Kind = QueryRecord
+	if len(fields) < 2 {
+		return p.errorf("query needs the types of its columns")
+	}
+	record.Types = fields[1]
+	for _, t := range record.Types {
+		if t != 'I' && t != 'R' && t != 'T' {
+			return p.errorf("unknown column type %q", t)
+		}
+	}
+	record.SortMode = NoSort
+	if len(fields) > 2 {
+		switch fields[2] {
+		case NoSort, RowSort, ValueSort:
+			record.SortMode = fields[2]
+		default:
+			return p.errorf("unknown sort mode %q", fields[2])
+		}
+	}
+	if len(fields) > 3 {
+		record.Label = fields[3]
+	}
+
+	lines, results := p.block(resultsSeparator)
+	record.SQL = strings.Join(lines, "\n")
+	if record.SQL == "" {
+		return p.errorf("query has no SQL")
+	}
+	if results {
+		lines, _ = p.block("")
+		for _, line := range lines {
+			record.Expected = append(record.Expected, strings.Split(line, "\t")...)
+		}
+	}
+	return nil
+}
+
+// skipped reports whether the conditions of record keep it from running
+// on Engine.
+func (record *Record) skipped() bool {
+	for _, c := range record.Conditions {
+		if c.Skip == (c.Engine == Engine) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package sqllogictest
+
+import (
+	"context"
+	"encoding/binary"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/piaoranyc/gosql"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSuite runs every script in testdata on a database of its own. New
+// scripts there are run without any change here.
+func TestSuite(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.test"))
+	assert.Nil(t, err)
+	assert.NotEmpty(t, paths)
+	for _, path := range paths {
+		result, err := RunFile(context.Background(), gosql.NewMemoryBackend(), path)
+		if !assert.Nil(t, err, path) {
+			continue
+		}
+		for _, f := range result.Failures {
+			t.Error(f)
+		}
+		assert.True(t, result.Passed > 0, path)
+	}
+}
+
+func TestParse(t *testing.T) {
+	script, err := Parse("t.test", strings.NewReader(`# a comment
+statement ok
+CREATE TABLE t (a INT,
+  b TEXT)
+
+onlyif gosql
+skipif sqlite
+query IT rowsort label-1
+SELECT a, b
+FROM t
+----
+1	x
+2
+y
+
+statement error nope.*
+SELECT 1
+
+hash-threshold 10
+halt
+`))
+	assert.Nil(t, err)
+	if !assert.Len(t, script.Records, 5) {
+		return
+	}
+	assert.Equal(t, &Record{Kind: StatementRecord, Line: 2, SQL: "CREATE TABLE t (a INT,\n  b TEXT)"}, script.Records[0])
+
+	query := script.Records[1]
+	assert.Equal(t, QueryRecord, query.Kind)
+	assert.Equal(t, 8, query.Line)
+	assert.Equal(t, []Condition{{Engine: "gosql"}, {Skip: true, Engine: "sqlite"}}, query.Conditions)
+	assert.Equal(t, "IT", query.Types)
+	assert.Equal(t, RowSort, query.SortMode)
+	assert.Equal(t, "label-1", query.Label)
+	assert.Equal(t, "SELECT a, b\nFROM t", query.SQL)
+	assert.Equal(t, []string{"1", "x", "2", "y"}, query.Expected)
+	assert.False(t, query.skipped())
+
+	assert.True(t, script.Records[2].ExpectError)
+	assert.Equal(t, "nope.*", script.Records[2].ErrorPattern.String())
+	assert.Equal(t, 10, script.Records[3].Threshold)
+	assert.Equal(t, HaltRecord, script.Records[4].Kind)
+
+	for _, source := range []string{
+		"statement maybe\nSELECT 1",
+		"statement ok\n",
+		"query X\nSELECT 1",
+		"query I sometimes\nSELECT 1",
+		"hash-threshold many",
+		"explode",
+		"skipif",
+	} {
+		_, err := Parse("t.test", strings.NewReader(source))
+		assert.NotNil(t, err, source)
+	}
+}
+
+func TestRun(t *testing.T) {
+	script, err := Parse("t.test", strings.NewReader(`statement ok
+CREATE TABLE t (a INT, b TEXT)
+
+statement ok
+INSERT INTO t VALUES (2, 'b'), (1, 'a')
+
+query IT nosort
+SELECT a, b FROM t
+----
+1
+a
+2
+b
+
+statement error
+CREATE TABLE t (a INT)
+
+statement ok
+INSERT INTO t VALUES ('not a number', 1)
+
+query I nosort
+SELECT a FROM t WHERE a = 1
+----
+1
+
+query I nosort twice
+SELECT a FROM t WHERE a = 1
+
+query I nosort twice
+SELECT a FROM t WHERE a = 2
+
+statement error no such thing
+SELECT a FROM missing
+
+query II nosort
+SELECT a FROM t
+
+query I nosort
+-- nothing to run
+
+skipif gosql
+statement ok
+SELECT nothing
+
+halt
+
+statement ok
+SELECT nothing
+`))
+	assert.Nil(t, err)
+	result, err := Run(context.Background(), gosql.NewMemoryBackend(), script)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, result.Passed)
+	assert.Equal(t, 1, result.Skipped)
+	var lines []int
+	for _, f := range result.Failures {
+		lines = append(lines, f.Line)
+	}
+	assert.Equal(t, []int{7, 18, 26, 29, 32, 35, 38}, lines)
+	assert.Equal(t, "expected a query", result.Failures[6].Reason)
+	assert.Equal(t, "t.test:7: expected:\n1\na\n2\nb\ngot:\n2\nb\n1\na", result.Failures[0].Error())
+	assert.Equal(t, "4 passed, 7 failed, 1 skipped (36.4%)", result.String())
+
+	total := &Result{}
+	total.Add(result)
+	total.Add(&Result{Passed: 17})
+	assert.Equal(t, 0.75, total.Conformance())
+}
+
+func intCell(i int32) gosql.MemoryCell {
+	cell := make(gosql.MemoryCell, 4)
+	binary.BigEndian.PutUint32(cell, uint32(i))
+	return cell
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		cell gosql.Cell
+		ct   gosql.ColumnType
+		t    byte
+		text string
+	}{
+		{gosql.MemoryCell(nil), gosql.IntType, 'I', "NULL"},
+		{intCell(-7), gosql.IntType, 'I', "-7"},
+		{intCell(-7), gosql.IntType, 'R', "-7.000"},
+		{intCell(-7), gosql.IntType, 'T', "-7"},
+		{gosql.MemoryCell("12abc"), gosql.TextType, 'I', "0"},
+		{gosql.MemoryCell(" 12 "), gosql.TextType, 'R', "12.000"},
+		{gosql.MemoryCell(""), gosql.TextType, 'T', "(empty)"},
+		{gosql.MemoryCell("a\nb"), gosql.TextType, 'T', "a@b"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.text, formatValue(test.cell, test.ct, test.t), test.text)
+	}
+	assert.Equal(t, "d41d8cd98f00b204e9800998ecf8427e", hashValues(nil))
+}
@@ -0,0 +1,95 @@
+package gosql
+
+import "strings"
+
+// tempSchemaPrefix starts the names of the schemas holding the temporary
+// tables of each session, as in pg_temp_3.staging. Scripts name their own
+// session's as pg_temp, and find its tables ahead of any other.
+const tempSchemaPrefix = "pg_temp_"
+
+// isTemporary reports whether the table or view stored as name belongs to
+// a session, which drops it when it closes.
+func isTemporary(name string) bool {
+	return strings.HasPrefix(name, tempSchemaPrefix)
+}
+
+// tempSchemaOf returns the temporary schema of the session ex runs
+// statements in, or "" when it has none.
+func tempSchemaOf(ex Executor) string {
+	if s, ok := ex.(interface{ tempSchema() string }); ok {
+		return s.tempSchema()
+	}
+	return ""
+}
+
+func (mb *MemoryBackend) tempSchema() string {
+	return mb.session.tempSchema()
+}
+
+func (s *Session) tempSchema() string {
+	return s.temp
+}
+
+// temporaryTable returns crt with its name in the temporary schema of the
+// session, which scripts have already put it in.
+func (s *Session) temporaryTable(crt *CreateTableStatement) (*CreateTableStatement, error) {
+	name := crt.Name.Value
+	if strings.HasPrefix(name, s.temp+".") {
+		return crt, nil
+	}
+	if strings.Contains(name, ".") {
+		return nil, ErrTemporarySchema
+	}
+
+	temp := *crt
+	temp.Name = &Token{Value: s.temp + "." + name, Kind: IdentifierKind, Loc: crt.Name.Loc}
+	return &temp, nil
+}
+
+// Close ends the session. A transaction still open rolls back, and the
+// temporary tables and views of the session are dropped.
+func (s *Session) Close() error {
+	if s.tx != nil {
+		if err := s.Rollback(); err != nil {
+			return err
+		}
+	}
+
+	s.mb.mu.Lock()
+	defer s.mb.mu.Unlock()
+
+	var objects []string
+	for name := range s.mb.tables {
+		if databaseOf(name) == s.temp {
+			objects = append(objects, name)
+		}
+	}
+	for name := range s.mb.views {
+		if databaseOf(name) == s.temp {
+			objects = append(objects, name)
+		}
+	}
+	return s.drop(objects)
+}
+
+// drop drops the tables and views stored as objects that are still there
+// in a transaction of its own. The caller holds s.mb.mu.
+func (s *Session) drop(objects []string) error {
+	var left []string
+	for _, name := range objects {
+		if s.mb.exists(name) {
+			left = append(left, name)
+		}
+	}
+	if len(left) == 0 {
+		return nil
+	}
+
+	s.mb.schemaVersion++
+	tx := s.mb.begin()
+	err := s.mb.dropObjects(tx, left)
+	if endErr := s.mb.end(tx, err == nil); err == nil {
+		err = endErr
+	}
+	return err
+}
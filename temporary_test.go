@@ -0,0 +1,97 @@
+package gosql
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession_temporaryTables(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table items (id int primary key, name text); insert into items values (1, 'stored')", ScriptOptions{})
+	assert.Nil(t, err)
+
+	// A temporary table hides the stored one of the same name, and only
+	// its session sees it.
+	s := mb.NewSession()
+	results, err := ExecuteScript(s, `create temporary table items (id int primary key, name text);
+insert into items values (1, 'staged');
+select name from items;
+select name from main.items;
+create view staged as select name from items;
+show tables`, ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "staged", results[2].Results.Rows[0][0].AsText())
+	assert.Equal(t, "stored", results[3].Results.Rows[0][0].AsText())
+	assert.Equal(t, [][]Cell{{MemoryCell("items")}, {MemoryCell("staged")}, {MemoryCell("items")}}, results[5].Results.Rows)
+
+	other := mb.NewSession()
+	results, err = ExecuteScript(other, "select name from items", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "stored", results[0].Results.Rows[0][0].AsText())
+	_, err = ExecuteScript(other, "select * from staged", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrTableDoesNotExist)
+
+	for _, test := range []struct {
+		source string
+		err    error
+	}{
+		{"create temporary table shop.t (id int)", ErrTemporarySchema},
+		{"create table orders (item int references pg_temp.items (id))", ErrTemporaryReference},
+	} {
+		_, err := ExecuteScript(s, test.source, ScriptOptions{})
+		assert.ErrorIs(t, err, test.err, test.source)
+	}
+
+	// Closing the session drops its tables and the views reading them.
+	assert.Nil(t, s.Close())
+	assert.Len(t, mb.Schema(), 1)
+	results, err = ExecuteScript(mb, "select name from items", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "stored", results[0].Results.Rows[0][0].AsText())
+}
+
+func TestSession_onCommitDrop(t *testing.T) {
+	mb := NewMemoryBackend()
+	s := mb.NewSession()
+	results, err := ExecuteScript(s, `begin;
+create temp table staging (id int) on commit drop;
+insert into staging values (1), (2);
+select count(*) from staging;
+commit;
+show tables`, ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), results[3].Results.Rows[0][0].AsInt())
+	assert.Empty(t, results[5].Results.Rows)
+
+	// Outside a transaction the table is gone as soon as it is made.
+	_, err = ExecuteScript(s, "create temp table staging (id int) on commit drop; select * from staging", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrTableDoesNotExist)
+}
+
+func TestDiskBackend_temporaryTables(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDiskBackend(dir)
+	assert.Nil(t, err)
+	_, err = ExecuteScript(db, `create table totals (n int);
+create temporary table staging (n int);
+insert into staging values (1), (2);
+update staging set n = n * 10`, ScriptOptions{})
+	assert.Nil(t, err)
+
+	// Rows copied from a temporary table could not be replayed.
+	_, err = ExecuteScript(db, "insert into totals select sum(n) from staging", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrTemporaryReference)
+
+	var dump bytes.Buffer
+	assert.Nil(t, db.DumpSQL(context.Background(), &dump))
+	assert.NotContains(t, dump.String(), "staging")
+	assert.Nil(t, db.Close())
+
+	db, err = NewDiskBackend(dir)
+	assert.Nil(t, err)
+	assert.Len(t, db.Schema(), 1)
+	assert.Nil(t, db.Close())
+}
@@ -0,0 +1,60 @@
+package gosql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// checkInterval is how many rows a scan, join or sort handles between
+// looks at whether its statement has been canceled.
+const checkInterval = 1024
+
+// canceled returns the error of ctx once it is done. Callers pass a count
+// of the rows handled so far and ctx is only looked at every
+// checkInterval rows.
+func canceled(ctx context.Context, n int) error {
+	if n%checkInterval != 0 {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// timeouter is an executor whose statements are limited by a statement
+// timeout.
+type timeouter interface {
+	StatementTimeout() time.Duration
+}
+
+// StatementTimeout returns the statement_timeout of the session, or 0
+// when statements may run as long as they take.
+func (s *Session) StatementTimeout() time.Duration {
+	return s.timeout
+}
+
+func (mb *MemoryBackend) StatementTimeout() time.Duration {
+	return mb.session.StatementTimeout()
+}
+
+// parseTimeout reads the value of a timeout setting: a number of
+// milliseconds, a string holding one or a duration, or DEFAULT for none.
+func parseTimeout(value *Token) (time.Duration, error) {
+	if isDefault(value) {
+		return 0, nil
+	}
+
+	var timeout time.Duration
+	if ms, err := strconv.ParseInt(value.Value, 10, 64); err == nil {
+		timeout = time.Duration(ms) * time.Millisecond
+	} else if d, err := time.ParseDuration(value.Value); err == nil && value.Kind == StringKind {
+		timeout = d
+	} else {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidSettingValue, value.Value)
+	}
+
+	if timeout < 0 {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidSettingValue, value.Value)
+	}
+	return timeout, nil
+}
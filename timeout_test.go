@@ -0,0 +1,142 @@
+package gosql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// numbers creates a table n holding the integers from 1 to count.
+func numbers(t *testing.T, ex Executor, count int) {
+	var values []string
+	for i := 1; i <= count; i++ {
+		values = append(values, fmt.Sprintf("(%d)", i))
+	}
+	_, err := ExecuteScript(ex, "create table n (i int); insert into n values "+strings.Join(values, ", "), ScriptOptions{})
+	assert.Nil(t, err)
+}
+
+func TestExecuteContext_Canceled(t *testing.T) {
+	mb := NewMemoryBackend()
+	numbers(t, mb, 3*checkInterval)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, source := range []string{
+		"select * from n",
+		"select * from n where i > 5",
+		"select * from n order by i desc",
+		"select i, count(*) from n group by i",
+		"select * from n as a join n as b on a.i = b.i",
+		"update n set i = i + 1",
+		"delete from n",
+		"insert into n select i from n",
+	} {
+		_, err := ExecuteScriptContext(ctx, mb, source, ScriptOptions{})
+		assert.Equal(t, context.Canceled, err, source)
+		assert.Equal(t, CanceledError, Code(err), source)
+		assert.Equal(t, "57014", SQLState(err), source)
+	}
+
+	// The canceled changes left nothing behind.
+	results, err := ExecuteScript(mb, "select count(*), sum(i) from n", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3*checkInterval), results[0].Results.Rows[0][0].AsInt())
+	assert.Equal(t, int64(3*checkInterval*(3*checkInterval+1)/2), results[0].Results.Rows[0][1].AsInt())
+}
+
+func TestExecuteScriptContext_stops(t *testing.T) {
+	mb := NewMemoryBackend()
+	numbers(t, mb, checkInterval)
+
+	release, err := mb.locks.acquire(context.Background(), nil, []string{"n"})
+	assert.Nil(t, err)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	results, err := ExecuteScriptContext(ctx, mb, "select * from n; select * from n", ScriptOptions{ContinueOnError: true})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 1, len(results))
+}
+
+func TestSortOrder_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	orderBy := []*OrderByClause{{Exp: &Expression{Kind: LiteralKind, Literal: &Token{Kind: NumericKind, Value: "1"}}}}
+	_, err := sortOrder(ctx, 10, orderBy, func(i int, exp *Expression) (MemoryCell, ColumnType, error) {
+		return intCell(int32(i)), IntType, nil
+	})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestSession_StatementTimeout(t *testing.T) {
+	mb := NewMemoryBackend()
+	numbers(t, mb, 1)
+	s := mb.NewSession()
+
+	_, err := ExecuteScript(s, "set statement_timeout = 20", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 20*time.Millisecond, s.StatementTimeout())
+
+	// A statement waiting for a lock is canceled once it runs out of time.
+	release, err := mb.locks.acquire(context.Background(), nil, []string{"n"})
+	assert.Nil(t, err)
+	_, err = ExecuteScript(s, "select * from n", ScriptOptions{})
+	assert.Equal(t, ErrStatementTimeout, err)
+	assert.Equal(t, "57014", SQLState(err))
+	release()
+
+	results, err := ExecuteScript(s, "select * from n", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results[0].Results.Rows))
+
+	// The setting belongs to the session.
+	assert.Equal(t, time.Duration(0), mb.StatementTimeout())
+
+	_, err = ExecuteScript(s, "set statement_timeout to '1.5s'", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1500*time.Millisecond, s.StatementTimeout())
+	_, err = ExecuteScript(s, "set statement_timeout = default", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), s.StatementTimeout())
+
+	// The caller's own deadline is reported as such.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	release, err = mb.locks.acquire(context.Background(), nil, []string{"n"})
+	assert.Nil(t, err)
+	_, err = ExecuteScriptContext(ctx, s, "set statement_timeout = 1000; select * from n", ScriptOptions{})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	release()
+}
+
+func TestSession_Set(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	for _, test := range []struct {
+		source string
+		err    error
+	}{
+		{"set statement_timeout = 100", nil},
+		{"set statement_timeout = '250ms'", nil},
+		{"set statement_timeout = '100'", nil},
+		{"set statement_timeout = 0", nil},
+		{"set statement_timeout = '-1'", ErrInvalidSettingValue},
+		{"set statement_timeout = 1.5", ErrInvalidSettingValue},
+		{"set statement_timeout = 'soon'", ErrInvalidSettingValue},
+		{"set datestyle = 'iso'", ErrUnknownSetting},
+	} {
+		results, err := ExecuteScript(mb, test.source, ScriptOptions{})
+		if test.err == nil {
+			assert.Nil(t, err, test.source)
+			assert.Equal(t, "SET", results[0].Tag, test.source)
+		} else {
+			assert.ErrorIs(t, err, test.err, test.source)
+		}
+	}
+}
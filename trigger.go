@@ -0,0 +1,135 @@
+package gosql
+
+// TriggerEvent is a change to a row that fires triggers.
+type TriggerEvent uint
+
+const (
+	InsertEvent TriggerEvent = iota
+	UpdateEvent
+	DeleteEvent
+)
+
+// TriggerTiming is whether a trigger fires before or after the change.
+type TriggerTiming uint
+
+const (
+	// BeforeTrigger fires before the row is checked against the
+	// constraints of its table and changed, and can prevent the change.
+	BeforeTrigger TriggerTiming = iota
+	// AfterTrigger fires once the statement has changed every row,
+	// including those deleted by cascading foreign keys.
+	AfterTrigger
+)
+
+// Trigger is a Go callback that INSERT, UPDATE and DELETE call for each
+// row of a table they change, including rows COPY adds and rows deleted by
+// ON DELETE CASCADE.
+type Trigger struct {
+	Timing TriggerTiming
+	// Events are the changes the trigger fires for.
+	Events []TriggerEvent
+	// Fire is called with each change in turn. An error makes the
+	// statement fail, undoing the changes it has made so far. Fire runs
+	// while the statement holds its locks, so it must not run statements
+	// against the backend itself.
+	Fire func(change *RowChange) error
+}
+
+// RowChange describes a row that a statement changes.
+type RowChange struct {
+	Table string
+	Event TriggerEvent
+	// Columns names the cells of Old and New.
+	Columns []string
+	// Old holds the row before an UPDATE or DELETE and New the row after
+	// an INSERT or UPDATE, with the other nil. Their cells are those of
+	// the stored row, which must not be changed.
+	Old, New []Cell
+}
+
+// namedTrigger is a trigger as a table holds it.
+type namedTrigger struct {
+	name string
+	Trigger
+}
+
+// CreateTrigger adds trigger to the table called table under name, which
+// must not be taken by another trigger of the table. Triggers fire in the
+// order they were created. They are not stored in snapshots, backups or
+// on disk, and dropping the table drops them.
+func (mb *MemoryBackend) CreateTrigger(table, name string, trigger Trigger) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	t, ok := mb.tables[table]
+	if !ok {
+		return ErrTableDoesNotExist
+	}
+	for _, trg := range t.triggers {
+		if trg.name == name {
+			return ErrTriggerAlreadyExists
+		}
+	}
+	// Statements may be reading the old slice, so it is copied.
+	n := len(t.triggers)
+	t.triggers = append(t.triggers[:n:n], &namedTrigger{name, trigger})
+	return nil
+}
+
+// DropTrigger drops the trigger of the table called table called name.
+func (mb *MemoryBackend) DropTrigger(table, name string) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	t, ok := mb.tables[table]
+	if !ok {
+		return ErrTableDoesNotExist
+	}
+	for i, trg := range t.triggers {
+		if trg.name == name {
+			kept := append([]*namedTrigger{}, t.triggers[:i]...)
+			t.triggers = append(kept, t.triggers[i+1:]...)
+			return nil
+		}
+	}
+	return ErrTriggerDoesNotExist
+}
+
+// fireTriggers calls the triggers of t with timing for event on the row
+// changing from old to new, stopping at the first that fails.
+func (t *table) fireTriggers(timing TriggerTiming, event TriggerEvent, old, new []MemoryCell) error {
+	var change *RowChange
+	for _, trg := range t.triggers {
+		if trg.Timing != timing || !trg.firesFor(event) {
+			continue
+		}
+		if change == nil {
+			change = &RowChange{Table: t.name, Event: event, Columns: t.columns, Old: rowCells(old), New: rowCells(new)}
+		}
+		if err := trg.Fire(change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (trg *namedTrigger) firesFor(event TriggerEvent) bool {
+	for _, e := range trg.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// rowCells returns row as cells, or nil for no row.
+func rowCells(row []MemoryCell) []Cell {
+	if row == nil {
+		return nil
+	}
+	cells := make([]Cell, len(row))
+	for i, cell := range row {
+		cells[i] = cell
+	}
+	return cells
+}
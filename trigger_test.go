@@ -0,0 +1,74 @@
+package gosql
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBackend_triggers(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, `create table customers (id int primary key, name text);
+create table orders (id int primary key, customer int references customers (id) on delete cascade, total int);
+insert into customers values (1, 'ann'), (2, 'bob')`, ScriptOptions{})
+	assert.Nil(t, err)
+
+	var audit []string
+	record := func(change *RowChange) error {
+		row := change.New
+		if row == nil {
+			row = change.Old
+		}
+		audit = append(audit, change.Table+" "+[]string{"insert", "update", "delete"}[change.Event]+" "+strconv.FormatInt(row[0].AsInt(), 10))
+		return nil
+	}
+	all := []TriggerEvent{InsertEvent, UpdateEvent, DeleteEvent}
+	assert.Nil(t, mb.CreateTrigger("orders", "audit", Trigger{Timing: AfterTrigger, Events: all, Fire: record}))
+	assert.ErrorIs(t, mb.CreateTrigger("orders", "audit", Trigger{Timing: AfterTrigger, Events: all, Fire: record}), ErrTriggerAlreadyExists)
+	assert.ErrorIs(t, mb.CreateTrigger("nowhere", "audit", Trigger{Fire: record}), ErrTableDoesNotExist)
+
+	// A before trigger can reject a change, which undoes the statement.
+	errTooBig := errors.New("total too big")
+	assert.Nil(t, mb.CreateTrigger("orders", "limit", Trigger{
+		Timing: BeforeTrigger,
+		Events: []TriggerEvent{InsertEvent, UpdateEvent},
+		Fire: func(change *RowChange) error {
+			if change.New[2].AsInt() > 100 {
+				return errTooBig
+			}
+			return nil
+		},
+	}))
+
+	_, err = ExecuteScript(mb, `insert into orders values (1, 1, 10), (2, 1, 20), (3, 2, 30);
+update orders set total = total + 1 where id = 2;
+delete from customers where id = 1`, ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"orders insert 1", "orders insert 2", "orders insert 3",
+		"orders update 2",
+		"orders delete 1", "orders delete 2",
+	}, audit)
+
+	audit = nil
+	_, err = ExecuteScript(mb, "insert into orders values (4, 2, 5), (5, 2, 500)", ScriptOptions{})
+	assert.ErrorIs(t, err, errTooBig)
+	assert.Nil(t, audit)
+	results, err := ExecuteScript(mb, "select count(*) from orders", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), results[0].Results.Rows[0][0].AsInt())
+
+	// Rows added in a batch fire them too.
+	_, err = mb.InsertRows(context.Background(), "orders", []string{"id", "customer", "total"}, [][]Cell{{intCell(6), intCell(2), intCell(1)}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"orders insert 6"}, audit)
+
+	assert.Nil(t, mb.DropTrigger("orders", "audit"))
+	assert.ErrorIs(t, mb.DropTrigger("orders", "audit"), ErrTriggerDoesNotExist)
+	_, err = ExecuteScript(mb, "delete from orders", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, audit, 1)
+}
@@ -0,0 +1,135 @@
+package gosql
+
+import "context"
+
+// excludedTable is the name an ON CONFLICT DO UPDATE clause reads the row
+// that was to be inserted by.
+const excludedTable = "excluded"
+
+// replaceExcluded rewrites the references to excluded columns in exp with
+// what value returns for the index of the column they name in columns.
+func replaceExcluded(exp *Expression, columns []string, value func(i int) *Expression) (*Expression, error) {
+	return rewriteExpression(exp, func(exp *Expression) (*Expression, error) {
+		if exp.Kind != ColumnReferenceKind || exp.Column.Table.Value != excludedTable {
+			return nil, nil
+		}
+		for i, name := range columns {
+			if name == exp.Column.Column.Value {
+				return value(i), nil
+			}
+		}
+		return nil, validationError(ErrColumnDoesNotExist, exp.Column.Column)
+	})
+}
+
+// validateOnConflict checks the ON CONFLICT clause of an INSERT into t. Its
+// target must be a unique column, and DO UPDATE is checked like an UPDATE
+// of t, with the excluded columns taking the types of the columns of t.
+func (s Schema) validateOnConflict(t *CreateTableStatement, c *OnConflict) error {
+	if c.Target != nil {
+		col := findColumn(t, c.Target.Value)
+		if col == nil {
+			return validationError(ErrColumnDoesNotExist, c.Target)
+		}
+		if !col.hasConstraint(PrimaryKeyConstraint) && !col.hasConstraint(UniqueConstraint) {
+			return validationError(ErrNoConflictTarget, c.Target)
+		}
+	}
+	if !c.DoUpdate {
+		return nil
+	}
+
+	columns := columnNames(t)
+	typed := func(i int) *Expression {
+		return cellExpression(nil, columnType(t.Cols[i]))
+	}
+	set := make([]*Assignment, len(c.Set))
+	for i, assignment := range c.Set {
+		value, err := replaceExcluded(assignment.Value, columns, typed)
+		if err != nil {
+			return err
+		}
+		set[i] = &Assignment{Column: assignment.Column, Value: value}
+	}
+	where, err := replaceExcluded(c.Where, columns, typed)
+	if err != nil {
+		return err
+	}
+	return s.validateAssignments([]*CreateTableStatement{t}, t, set, where)
+}
+
+// conflicting returns the version of t that row conflicts with under c,
+// or nil when it conflicts with none. Like UPDATE, it fails with
+// ErrSerializationFailure when that version was made or changed by a
+// transaction tx cannot see.
+func (mb *MemoryBackend) conflicting(tx *transaction, t *table, c *OnConflict, row []MemoryCell) (*rowVersion, error) {
+	for i, cell := range row {
+		if cell.IsNull() {
+			continue
+		}
+		if c.Target != nil && t.columns[i] != c.Target.Value || c.Target == nil && !t.unique[i] {
+			continue
+		}
+		for _, v := range mb.holding(tx, t, i, cell) {
+			if !tx.snapshot.visible(v) || v.xmax != 0 {
+				return nil, ErrSerializationFailure
+			}
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+// upsert applies c to the version existing that row conflicts with, and
+// returns the row it is updated to, or nil for DO NOTHING and when the
+// WHERE of c does not hold for it. written holds the versions the
+// statement has stored so far, to which it adds the new one.
+func (mb *MemoryBackend) upsert(ctx context.Context, tx *transaction, t *table, c *OnConflict, existing *rowVersion, row []MemoryCell, written map[*rowVersion]bool) ([]MemoryCell, error) {
+	if !c.DoUpdate {
+		return nil, nil
+	}
+	if written[existing] {
+		return nil, ErrConflictAffectedTwice
+	}
+
+	excluded := func(i int) *Expression {
+		return cellExpression(row[i], t.columnTypes[i])
+	}
+	if c.Where != nil {
+		where, err := replaceExcluded(c.Where, t.columns, excluded)
+		if err != nil {
+			return nil, err
+		}
+		cell, ct, err := t.evaluateExpression(existing.cells, where)
+		if err != nil {
+			return nil, err
+		}
+		if !compatible(ct, BoolType) {
+			return nil, ErrInvalidCondition
+		}
+		if !cell.AsBool() {
+			return nil, nil
+		}
+	}
+
+	set := make([]*Assignment, len(c.Set))
+	indexes := make([]int, len(c.Set))
+	for i, assignment := range c.Set {
+		value, err := replaceExcluded(assignment.Value, t.columns, excluded)
+		if err != nil {
+			return nil, err
+		}
+		set[i] = &Assignment{Column: assignment.Column, Value: value}
+		if indexes[i] = t.columnIndex(assignment.Column.Value); indexes[i] == -1 {
+			return nil, ErrColumnDoesNotExist
+		}
+	}
+	rows, err := mb.replace(ctx, tx, t, []*rowVersion{existing}, func(old []MemoryCell) ([]MemoryCell, error) {
+		return t.applyAssignments(old, set, indexes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	written[t.versions[len(t.versions)-1]] = true
+	return rows[0], nil
+}
@@ -0,0 +1,1110 @@
+package gosql
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Schema maps table names to their definitions.
+type Schema map[string]*CreateTableStatement
+
+func (mb *MemoryBackend) Schema() Schema {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+
+	return mb.schema()
+}
+
+// schema is Schema for callers that already hold mb.mu.
+func (mb *MemoryBackend) schema() Schema {
+	schema := Schema{}
+	for name, t := range mb.tables {
+		crt := CreateTableStatement{
+			Name: &Token{Value: name, Kind: IdentifierKind},
+		}
+		for i, col := range t.columns {
+			cd := ColumnDefinition{
+				Name:     &Token{Value: col, Kind: IdentifierKind},
+				Datatype: &Token{Value: string(columnTypeKeyword(t.columnTypes[i])), Kind: KeywordKind},
+			}
+			if t.lengths[i] > 0 {
+				cd.Datatype.Value = string(VarcharKeyword)
+				cd.Length = &Token{Value: strconv.Itoa(t.lengths[i]), Kind: NumericKind}
+			}
+			if t.primaryKey == i {
+				cd.Constraints = append(cd.Constraints, &ColumnConstraint{Kind: PrimaryKeyConstraint})
+			} else if t.notNull[i] {
+				cd.Constraints = append(cd.Constraints, &ColumnConstraint{Kind: NotNullConstraint})
+			}
+			if t.unique[i] && t.primaryKey != i {
+				cd.Constraints = append(cd.Constraints, &ColumnConstraint{Kind: UniqueConstraint})
+			}
+			if t.defaults[i] != nil {
+				cd.Constraints = append(cd.Constraints, &ColumnConstraint{Kind: DefaultConstraint, Default: t.defaults[i]})
+			}
+			if t.autoIncrement[i] {
+				cd.Constraints = append(cd.Constraints, &ColumnConstraint{Kind: AutoIncrementConstraint})
+			}
+			if ref := t.references[i]; ref != nil {
+				cd.Constraints = append(cd.Constraints, &ColumnConstraint{Kind: ReferencesConstraint, References: &ForeignKey{
+					Table:    &Token{Value: ref.table, Kind: IdentifierKind},
+					Column:   &Token{Value: ref.column, Kind: IdentifierKind},
+					OnDelete: ref.onDelete,
+				}})
+			}
+			if c := t.collation(i); c != nil {
+				cd.Constraints = append(cd.Constraints, &ColumnConstraint{Kind: CollateConstraint, Collation: &Token{Value: c.name, Kind: IdentifierKind}})
+			}
+			crt.Cols = append(crt.Cols, &cd)
+		}
+		crt.Checks = t.checks
+		if t.columnar {
+			crt.Layout = &Token{Value: ColumnLayout, Kind: StringKind}
+		}
+		schema[name] = &crt
+	}
+	mb.addViews(schema)
+	return schema
+}
+
+func columnTypeKeyword(ct ColumnType) keyword {
+	switch ct {
+	case IntType:
+		return IntKeyword
+	case BigIntType:
+		return BigintKeyword
+	case FloatType:
+		return FloatKeyword
+	case BoolType:
+		return BooleanKeyword
+	case DateType:
+		return DateKeyword
+	case TimestampType:
+		return TimestampKeyword
+	case JSONType:
+		return JsonKeyword
+	case ByteaType:
+		return ByteaKeyword
+	default:
+		return TextKeyword
+	}
+}
+
+func validationError(err error, t *Token) error {
+	return tokenError(Code(err), err, t, fmt.Sprintf("%s: %s", err, t))
+}
+
+// Validate checks that every table and column stmt references exists in
+// schema and that the operands of comparisons and inserted values have
+// compatible types. Errors wrap the matching backend error and point at the
+// offending token.
+func Validate(stmt *Statement, schema Schema) error {
+	switch stmt.Kind {
+	case SelectKind:
+		return schema.validateSelect(stmt.SelectStatement)
+	case ExplainKind:
+		return schema.validateSelect(stmt.ExplainStatement.Select)
+	case InsertKind:
+		return schema.validateInsert(stmt.InsertStatement)
+	case UpdateKind:
+		return schema.validateUpdate(stmt.UpdateStatement)
+	case DeleteKind:
+		return schema.validateDelete(stmt.DeleteStatement)
+	case CreateIndexKind:
+		t, err := schema.table(stmt.CreateIndexStatement.Table)
+		if err != nil {
+			return err
+		}
+		if findColumn(t, stmt.CreateIndexStatement.Column.Value) == nil {
+			return validationError(ErrColumnDoesNotExist, stmt.CreateIndexStatement.Column)
+		}
+	case DropTableKind:
+		if !stmt.DropTableStatement.IfExists {
+			_, err := schema.table(stmt.DropTableStatement.Name)
+			return err
+		}
+	case AlterTableKind:
+		return schema.validateAlterTable(stmt.AlterTableStatement)
+	case DescribeKind:
+		_, err := schema.source(stmt.DescribeStatement.Table)
+		return err
+	case CopyKind:
+		t, err := schema.table(stmt.CopyStatement.Table)
+		if err != nil {
+			return err
+		}
+		_, err = columnList(t, stmt.CopyStatement.Columns)
+		return err
+	case CreateViewKind:
+		return schema.validateView(stmt.CreateViewStatement)
+	case DropViewKind:
+		crt, ok := schema[stmt.DropViewStatement.Name.Value]
+		switch {
+		case ok && crt.View == nil:
+			return validationError(ErrWrongObjectType, stmt.DropViewStatement.Name)
+		case !ok && !stmt.DropViewStatement.IfExists:
+			return validationError(ErrTableDoesNotExist, stmt.DropViewStatement.Name)
+		}
+	case CreateTableKind:
+		if _, ok := schema[stmt.CreateTableStatement.Name.Value]; ok && !stmt.CreateTableStatement.IfNotExists {
+			return validationError(ErrTableAlreadyExists, stmt.CreateTableStatement.Name)
+		}
+		for _, col := range stmt.CreateTableStatement.Cols {
+			if err := schema.validateDefault(col); err != nil {
+				return err
+			}
+			if err := validateCollation(col); err != nil {
+				return err
+			}
+			if err := schema.validateForeignKey(stmt.CreateTableStatement, col); err != nil {
+				return err
+			}
+		}
+		return schema.validateChecks(stmt.CreateTableStatement, stmt.CreateTableStatement.checks())
+	}
+	return nil
+}
+
+func (s Schema) validateAlterTable(alt *AlterTableStatement) error {
+	t, err := s.table(alt.Table)
+	if err != nil {
+		return err
+	}
+
+	if alt.Action == AddColumnAction {
+		if findColumn(t, alt.Add.Name.Value) != nil {
+			return validationError(ErrDuplicateColumn, alt.Add.Name)
+		}
+		if err := s.validateForeignKey(t, alt.Add); err != nil {
+			return err
+		}
+		if err := s.validateDefault(alt.Add); err != nil {
+			return err
+		}
+		if err := validateCollation(alt.Add); err != nil {
+			return err
+		}
+		added := *t
+		added.Cols = append(t.Cols[:len(t.Cols):len(t.Cols)], alt.Add)
+		return s.validateChecks(&added, alt.Add.checks())
+	}
+
+	if findColumn(t, alt.Column.Value) == nil {
+		return validationError(ErrColumnDoesNotExist, alt.Column)
+	}
+	if alt.Action == RenameColumnAction && findColumn(t, alt.To.Value) != nil {
+		return validationError(ErrDuplicateColumn, alt.To)
+	}
+	return nil
+}
+
+// validateForeignKey checks that the REFERENCES of col, a column of t,
+// names the primary key or a unique column of a table, which may be t
+// itself, and that the types of the two can be compared.
+func (s Schema) validateForeignKey(t *CreateTableStatement, col *ColumnDefinition) error {
+	fk := col.foreignKey()
+	if fk == nil {
+		return nil
+	}
+	parent := t
+	if fk.Table.Value != t.Name.Value {
+		var err error
+		if parent, err = s.table(fk.Table); err != nil {
+			return err
+		}
+	}
+
+	var ref *ColumnDefinition
+	if fk.Column == nil {
+		for _, c := range parent.Cols {
+			if c.hasConstraint(PrimaryKeyConstraint) {
+				ref = c
+			}
+		}
+		if ref == nil {
+			return validationError(ErrInvalidForeignKey, fk.Table)
+		}
+	} else if ref = findColumn(parent, fk.Column.Value); ref == nil {
+		return validationError(ErrColumnDoesNotExist, fk.Column)
+	} else if !ref.hasConstraint(PrimaryKeyConstraint) && !ref.hasConstraint(UniqueConstraint) {
+		return validationError(ErrInvalidForeignKey, fk.Column)
+	}
+
+	if !compatible(columnType(col), columnType(ref)) {
+		return validationError(ErrTypeMismatch, fk.Table)
+	}
+	return nil
+}
+
+// validateChecks checks that each of checks, CHECK constraints of t, is a
+// condition on the columns of t.
+func (s Schema) validateChecks(t *CreateTableStatement, checks []*Expression) error {
+	scope := []*CreateTableStatement{t}
+	for _, check := range checks {
+		ct, err := s.expressionType(scope, check)
+		if err != nil {
+			return err
+		}
+		if !compatible(ct, BoolType) {
+			return validationError(ErrInvalidCondition, firstToken(check))
+		}
+	}
+	return nil
+}
+
+// validateDefault checks that the DEFAULT of col, which cannot refer to any
+// column, fits its type.
+func (s Schema) validateDefault(col *ColumnDefinition) error {
+	exp := col.defaultValue()
+	if exp == nil {
+		return nil
+	}
+	ct, err := s.expressionType(nil, exp)
+	if err != nil {
+		return err
+	}
+	if !assignable(ct, columnType(col)) {
+		return validationError(ErrInvalidDatatype, firstToken(exp))
+	}
+	return nil
+}
+
+func (s Schema) validateUpdate(updt *UpdateStatement) error {
+	t, err := s.table(updt.Table)
+	if err != nil {
+		return err
+	}
+
+	scope := []*CreateTableStatement{t}
+	if err := s.validateAssignments(scope, t, updt.Set, updt.Where); err != nil {
+		return err
+	}
+	return s.validateReturning(scope, updt.Returning)
+}
+
+// validateAssignments checks the SET and WHERE of a statement that updates
+// the rows of t.
+func (s Schema) validateAssignments(scope []*CreateTableStatement, t *CreateTableStatement, set []*Assignment, where *Expression) error {
+	seen := map[string]bool{}
+	for _, assignment := range set {
+		name := assignment.Column
+		if seen[name.Value] {
+			return validationError(ErrDuplicateColumn, name)
+		}
+		seen[name.Value] = true
+
+		col := findColumn(t, name.Value)
+		if col == nil {
+			return validationError(ErrColumnDoesNotExist, name)
+		}
+
+		ct, err := s.expressionType(scope, assignment.Value)
+		if err != nil {
+			return err
+		}
+		if !assignable(ct, columnType(col)) {
+			return validationError(ErrInvalidDatatype, firstToken(assignment.Value))
+		}
+	}
+
+	if where != nil {
+		ct, err := s.expressionType(scope, where)
+		if err != nil {
+			return err
+		}
+		if !compatible(ct, BoolType) {
+			return validationError(ErrInvalidCondition, firstToken(where))
+		}
+	}
+	return nil
+}
+
+func (s Schema) validateDelete(dlt *DeleteStatement) error {
+	t, err := s.table(dlt.From)
+	if err != nil {
+		return err
+	}
+
+	scope := []*CreateTableStatement{t}
+	if dlt.Where != nil {
+		ct, err := s.expressionType(scope, dlt.Where)
+		if err != nil {
+			return err
+		}
+		if !compatible(ct, BoolType) {
+			return validationError(ErrInvalidCondition, firstToken(dlt.Where))
+		}
+	}
+
+	return s.validateReturning(scope, dlt.Returning)
+}
+
+// table is the definition of the stored table called name, which
+// statements that change rows or the table itself need.
+func (s Schema) table(name *Token) (*CreateTableStatement, error) {
+	t, ok := s[name.Value]
+	if !ok {
+		return nil, validationError(ErrTableDoesNotExist, name)
+	}
+	if t.View != nil {
+		return nil, validationError(ErrWrongObjectType, name)
+	}
+	return t, nil
+}
+
+// source is the definition of a table a query reads from, which may be
+// a view or one of the information_schema views as well as a stored table.
+func (s Schema) source(name *Token) (*CreateTableStatement, error) {
+	if view, ok := informationSchema[name.Value]; ok {
+		return view.definition, nil
+	}
+	t, ok := s[name.Value]
+	if !ok {
+		return nil, validationError(ErrTableDoesNotExist, name)
+	}
+	return t, nil
+}
+
+// aliased returns t renamed to as, so that qualified column references
+// have to use the alias. It returns t itself when as is nil.
+func aliased(t *CreateTableStatement, as *Token) *CreateTableStatement {
+	if as == nil {
+		return t
+	}
+	view := *t
+	view.Name = as
+	return &view
+}
+
+// validateView checks that the name of crt is free and that its query is
+// valid and returns columns with distinct names, which queries through
+// the view tell apart by name.
+func (s Schema) validateView(crt *CreateViewStatement) error {
+	if _, ok := s[crt.Name.Value]; ok {
+		return validationError(ErrTableAlreadyExists, crt.Name)
+	}
+	t, err := s.selectResult(crt.Select)
+	if err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, col := range t.Cols {
+		if seen[col.Name.Value] {
+			return validationError(ErrDuplicateColumn, col.Name)
+		}
+		seen[col.Name.Value] = true
+	}
+	return nil
+}
+
+func (s Schema) validateSelect(slct *SelectStatement) error {
+	_, err := s.selectResult(slct)
+	return err
+}
+
+// commonTable describes the rows of the subquery slct as a table, called
+// name when it is a common table expression.
+func (s Schema) commonTable(name *Token, slct *SelectStatement) (*CreateTableStatement, error) {
+	t, err := s.selectResult(slct)
+	if err != nil || name == nil {
+		return t, err
+	}
+	t.Name = name
+	return t, nil
+}
+
+// selectResult validates slct and describes the rows it returns as a
+// table, which is how the query around a subquery sees it. The common
+// table expressions of its WITH clause are validated whether it reads
+// them or not, and must have different names.
+func (s Schema) selectResult(slct *SelectStatement) (*CreateTableStatement, error) {
+	seen := map[string]bool{}
+	for _, cte := range slct.With {
+		if seen[cte.Name.Value] {
+			return nil, validationError(ErrDuplicateAlias, cte.Name)
+		}
+		seen[cte.Name.Value] = true
+		if _, err := s.selectResult(cte.Select); err != nil {
+			return nil, err
+		}
+	}
+
+	if slct.Set != nil {
+		return s.setResult(slct)
+	}
+
+	var t *CreateTableStatement
+	var err error
+	if slct.FromSelect != nil {
+		t, err = s.commonTable(slct.From, slct.FromSelect)
+	} else {
+		t, err = s.source(slct.From)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	scope := []*CreateTableStatement{aliased(t, slct.FromAs)}
+	for _, j := range slct.Join {
+		var t *CreateTableStatement
+		var err error
+		if j.Select != nil {
+			t, err = s.commonTable(j.Table, j.Select)
+		} else {
+			t, err = s.source(j.Table)
+		}
+		if err != nil {
+			return nil, err
+		}
+		t = aliased(t, j.As)
+		// Qualified references could not tell apart two tables known by
+		// the same name.
+		for _, other := range scope {
+			if other.Name.Value == t.Name.Value {
+				return nil, validationError(ErrDuplicateAlias, t.Name)
+			}
+		}
+		scope = append(scope, t)
+
+		ct, err := s.expressionType(scope, j.On)
+		if err != nil {
+			return nil, err
+		}
+		if !compatible(ct, BoolType) {
+			return nil, validationError(ErrInvalidCondition, firstToken(j.On))
+		}
+	}
+
+	if err := validateHints(scope, slct.Hints); err != nil {
+		return nil, err
+	}
+
+	result := &CreateTableStatement{}
+	for _, item := range slct.Item {
+		if item.Asterisk {
+			for _, t := range scope {
+				for _, col := range t.Cols {
+					result.Cols = append(result.Cols, &ColumnDefinition{Name: col.Name, Datatype: col.Datatype})
+				}
+			}
+			continue
+		}
+
+		ct, err := s.expressionType(scope, item.Exp)
+		if err != nil {
+			return nil, err
+		}
+		name := item.As
+		if name == nil {
+			name = resultName(item.Exp)
+		}
+		result.Cols = append(result.Cols, &ColumnDefinition{
+			Name:     name,
+			Datatype: &Token{Value: string(columnTypeKeyword(ct)), Kind: KeywordKind},
+		})
+	}
+
+	if slct.Where != nil {
+		ct, err := s.expressionType(scope, slct.Where)
+		if err != nil {
+			return nil, err
+		}
+		if !compatible(ct, BoolType) {
+			return nil, validationError(ErrInvalidCondition, firstToken(slct.Where))
+		}
+	}
+
+	for _, exp := range slct.GroupBy {
+		if _, err := s.expressionType(scope, exp); err != nil {
+			return nil, err
+		}
+	}
+
+	if slct.Having != nil {
+		ct, err := s.expressionType(scope, slct.Having)
+		if err != nil {
+			return nil, err
+		}
+		if !compatible(ct, BoolType) {
+			return nil, validationError(ErrInvalidCondition, firstToken(slct.Having))
+		}
+	}
+
+	if err := s.validateTail(scope, scopeOutputs(scope, slct.Item), slct); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// scopeOutputs returns items with each asterisk expanded into references
+// to the columns of the tables in scope.
+func scopeOutputs(scope []*CreateTableStatement, items []*SelectItem) []*SelectItem {
+	var outputs []*SelectItem
+	for _, item := range items {
+		if !item.Asterisk {
+			outputs = append(outputs, item)
+			continue
+		}
+		for _, t := range scope {
+			for _, col := range t.Cols {
+				ref := &ColumnReference{Table: t.Name, Column: col.Name}
+				outputs = append(outputs, &SelectItem{Exp: &Expression{Kind: ColumnReferenceKind, Column: ref}})
+			}
+		}
+	}
+	return outputs
+}
+
+// setResult validates a query that combines two others, whose rows must
+// have as many columns as each other and of types that can be compared.
+// The combined rows take the column names of the left query.
+func (s Schema) setResult(slct *SelectStatement) (*CreateTableStatement, error) {
+	left, err := s.selectResult(slct.Set.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := s.selectResult(slct.Set.Right)
+	if err != nil {
+		return nil, err
+	}
+	if len(left.Cols) != len(right.Cols) {
+		return nil, validationError(ErrSetOperationColumns, slct.Set.Op)
+	}
+
+	// The combined rows belong to no table, so ORDER BY can only name
+	// their columns unqualified.
+	result := &CreateTableStatement{Name: &Token{Kind: IdentifierKind}}
+	for i, col := range left.Cols {
+		ct, ok := commonType(columnType(col), columnType(right.Cols[i]))
+		if !ok {
+			return nil, validationError(ErrTypeMismatch, slct.Set.Op)
+		}
+		result.Cols = append(result.Cols, &ColumnDefinition{
+			Name:     col.Name,
+			Datatype: &Token{Value: string(columnTypeKeyword(ct)), Kind: KeywordKind},
+		})
+	}
+
+	scope := []*CreateTableStatement{result}
+	if err := s.validateTail(scope, scopeOutputs(scope, []*SelectItem{{Asterisk: true}}), slct); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// validateTail validates the ORDER BY of slct against scope, or against
+// outputs, the columns of its result, for the keys that refer to them,
+// and its LIMIT and OFFSET.
+func (s Schema) validateTail(scope []*CreateTableStatement, outputs []*SelectItem, slct *SelectStatement) error {
+	orderBy, err := outputOrderBy(slct.OrderBy, outputs)
+	if err != nil {
+		return err
+	}
+	for _, clause := range orderBy {
+		if _, err := s.expressionType(scope, clause.Exp); err != nil {
+			return err
+		}
+	}
+
+	// LIMIT and OFFSET are evaluated once, so they cannot see any columns.
+	for _, count := range []*Expression{slct.Limit, slct.Offset} {
+		if count == nil {
+			continue
+		}
+		ct, err := s.expressionType(nil, count)
+		if err != nil {
+			return err
+		}
+		if !implicit(ct, BigIntType) {
+			return validationError(ErrInvalidLimit, firstToken(count))
+		}
+	}
+	return nil
+}
+
+// outputOrderBy returns orderBy with each key that refers to a column of
+// the result replaced by the expression of that column, which sorts the
+// rows the same way. outputs are the items of the select list with any
+// asterisk expanded. A key refers to a column by its position, counting
+// from 1, or by its alias, which as in PostgreSQL takes precedence over
+// the columns of the tables read.
+func outputOrderBy(orderBy []*OrderByClause, outputs []*SelectItem) ([]*OrderByClause, error) {
+	var resolved []*OrderByClause
+	for _, clause := range orderBy {
+		exp, err := outputReference(clause.Exp, outputs)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, &OrderByClause{Exp: exp, Desc: clause.Desc, Nulls: clause.Nulls})
+	}
+	return resolved, nil
+}
+
+// outputReference returns the expression of the column of the result exp
+// refers to, or exp itself when it refers to none. An alias that several
+// columns with different expressions have is ambiguous.
+func outputReference(exp *Expression, outputs []*SelectItem) (*Expression, error) {
+	if exp.Kind != LiteralKind {
+		return exp, nil
+	}
+	switch exp.Literal.Kind {
+	case NumericKind:
+		n, err := strconv.Atoi(exp.Literal.Value)
+		if err != nil {
+			return exp, nil
+		}
+		if n < 1 || n > len(outputs) {
+			return nil, validationError(ErrOrderByPosition, exp.Literal)
+		}
+		return outputs[n-1].Exp, nil
+	case IdentifierKind:
+		var found *Expression
+		for _, item := range outputs {
+			if item.As == nil || item.As.Value != exp.Literal.Value {
+				continue
+			}
+			if found != nil && formatSQLExpression(found) != formatSQLExpression(item.Exp) {
+				return nil, validationError(ErrAmbiguousColumn, exp.Literal)
+			}
+			found = item.Exp
+		}
+		if found != nil {
+			return found, nil
+		}
+	}
+	return exp, nil
+}
+
+func (s Schema) validateInsert(inst *InsertStatement) error {
+	t, err := s.table(inst.Table)
+	if err != nil {
+		return err
+	}
+
+	scope := []*CreateTableStatement{t}
+	cols, err := columnList(t, inst.Columns)
+	if err != nil {
+		return err
+	}
+
+	if inst.Select != nil {
+		result, err := s.selectResult(inst.Select)
+		if err != nil {
+			return err
+		}
+		if len(result.Cols) != len(cols) {
+			return validationError(ErrMissingValues, inst.Table)
+		}
+		for i, col := range result.Cols {
+			if !assignable(columnType(col), columnType(cols[i])) {
+				return validationError(ErrInvalidDatatype, col.Name)
+			}
+		}
+	}
+
+	for _, values := range inst.Values {
+		if len(values) != len(cols) {
+			return validationError(ErrMissingValues, inst.Table)
+		}
+
+		for i, value := range values {
+			ct, err := s.expressionType(scope, value)
+			if err != nil {
+				return err
+			}
+			if !assignable(ct, columnType(cols[i])) {
+				return validationError(ErrInvalidDatatype, firstToken(value))
+			}
+		}
+	}
+
+	if inst.OnConflict != nil {
+		if err := s.validateOnConflict(t, inst.OnConflict); err != nil {
+			return err
+		}
+	}
+	return s.validateReturning(scope, inst.Returning)
+}
+
+// validateReturning checks the items of a RETURNING clause against the
+// table the statement writes.
+func (s Schema) validateReturning(scope []*CreateTableStatement, items []*SelectItem) error {
+	for _, item := range items {
+		if item.Asterisk {
+			continue
+		}
+		if _, err := s.expressionType(scope, item.Exp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columnList looks up the columns names lists in t, or returns every
+// column of t when names is nil.
+func columnList(t *CreateTableStatement, names []*Token) ([]*ColumnDefinition, error) {
+	if names == nil {
+		return t.Cols, nil
+	}
+
+	var cols []*ColumnDefinition
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name.Value] {
+			return nil, validationError(ErrDuplicateColumn, name)
+		}
+		seen[name.Value] = true
+
+		col := findColumn(t, name.Value)
+		if col == nil {
+			return nil, validationError(ErrColumnDoesNotExist, name)
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+func findColumn(t *CreateTableStatement, name string) *ColumnDefinition {
+	for _, col := range t.Cols {
+		if col.Name.Value == name {
+			return col
+		}
+	}
+	return nil
+}
+
+func columnType(cd *ColumnDefinition) ColumnType {
+	switch keyword(cd.Datatype.Value) {
+	case IntKeyword, IntegerKeyword:
+		return IntType
+	case BigintKeyword:
+		return BigIntType
+	case FloatKeyword, RealKeyword:
+		return FloatType
+	case BoolKeyword, BooleanKeyword:
+		return BoolType
+	case DateKeyword:
+		return DateType
+	case TimestampKeyword:
+		return TimestampType
+	case JsonKeyword:
+		return JSONType
+	case ByteaKeyword, BlobKeyword:
+		return ByteaType
+	default:
+		return TextType
+	}
+}
+
+// firstToken returns the leftmost token of exp for error reporting.
+func firstToken(exp *Expression) *Token {
+	switch exp.Kind {
+	case BinaryKind:
+		return firstToken(exp.Binary.Left)
+	case UnaryKind:
+		return exp.Unary.Op
+	case IsNullKind:
+		return firstToken(exp.IsNull.Operand)
+	case InKind:
+		return firstToken(exp.In.Left)
+	case BetweenKind:
+		return firstToken(exp.Between.Left)
+	case FunctionKind:
+		return exp.Function.Name
+	case ColumnReferenceKind:
+		return exp.Column.Table
+	case CastKind:
+		return firstToken(exp.Cast.Operand)
+	case CaseKind:
+		return exp.Case.Case
+	}
+	return exp.Literal
+}
+
+// resultName is the name of the result column for a select item without
+// an alias.
+func resultName(exp *Expression) *Token {
+	switch exp.Kind {
+	case ColumnReferenceKind:
+		return exp.Column.Column
+	case FunctionKind:
+		return exp.Function.Name
+	case CastKind:
+		// PostgreSQL names a cast of a column after the column and any
+		// other cast after the type.
+		if name := resultName(exp.Cast.Operand); name.Value != "?column?" {
+			return name
+		}
+		return exp.Cast.Type
+	case CaseKind:
+		return &Token{Value: "case", Kind: IdentifierKind, Loc: exp.Case.Case.Loc}
+	case LiteralKind:
+		if exp.Literal.Kind == IdentifierKind {
+			return exp.Literal
+		}
+	}
+	return &Token{Value: "?column?", Kind: IdentifierKind, Loc: firstToken(exp).Loc}
+}
+
+// expressionType infers the type exp evaluates to against the columns of the
+// tables in scope, mirroring the rules the memory backend applies at
+// execution time.
+func (s Schema) expressionType(scope []*CreateTableStatement, exp *Expression) (ColumnType, error) {
+	switch exp.Kind {
+	case ColumnReferenceKind:
+		for _, t := range scope {
+			if t.Name.Value != exp.Column.Table.Value {
+				continue
+			}
+			if col := findColumn(t, exp.Column.Column.Value); col != nil {
+				return columnType(col), nil
+			}
+		}
+		return 0, validationError(ErrColumnDoesNotExist, exp.Column.Column)
+	case LiteralKind:
+		switch exp.Literal.Kind {
+		case IdentifierKind:
+			var found *ColumnDefinition
+			for _, t := range scope {
+				for _, col := range t.Cols {
+					if col.Name.Value != exp.Literal.Value {
+						continue
+					}
+					if found != nil {
+						return 0, validationError(ErrAmbiguousColumn, exp.Literal)
+					}
+					found = col
+				}
+			}
+			if found != nil {
+				return columnType(found), nil
+			}
+			return 0, validationError(ErrColumnDoesNotExist, exp.Literal)
+		case NumericKind:
+			_, ct, err := numericLiteral(exp.Literal.Value)
+			if err != nil {
+				return 0, validationError(err, exp.Literal)
+			}
+			return ct, nil
+		case StringKind:
+			return TextType, nil
+		case BytesKind:
+			return ByteaType, nil
+		case BoolKind:
+			return BoolType, nil
+		case ParameterKind:
+			return 0, validationError(ErrUnboundParameter, exp.Literal)
+		case KeywordKind:
+			if keyword(exp.Literal.Value) == NullKeyword {
+				return NullType, nil
+			}
+		}
+	case BinaryKind:
+		lt, err := s.expressionType(scope, exp.Binary.Left)
+		if err != nil {
+			return 0, err
+		}
+		rt, err := s.expressionType(scope, exp.Binary.Right)
+		if err != nil {
+			return 0, err
+		}
+
+		want, result := lt, BoolType
+		switch op := exp.Binary.Op.Value; op {
+		case string(AndKeyword), string(OrKeyword):
+			want = BoolType
+		case string(LikeKeyword):
+			want = TextType
+		case string(ConcatSymbol):
+			// Values of any type can be concatenated.
+			return concatType(lt, rt), nil
+		case string(ArrowSymbol), string(ArrowTextSymbol):
+			ct, ok := jsonOperatorType(Symbol(op), lt, rt)
+			if !ok {
+				return 0, validationError(ErrTypeMismatch, exp.Binary.Op)
+			}
+			return ct, nil
+		case string(PlusSymbol), string(MinusSymbol), string(AsteriskSymbol), string(SlashSymbol), string(PercentSymbol):
+			ct, ok := arithmeticType(Symbol(op), lt, rt)
+			if !ok {
+				return 0, validationError(ErrTypeMismatch, exp.Binary.Op)
+			}
+			return ct, nil
+		}
+		if !compatible(lt, want) || !compatible(rt, want) || !compatible(lt, rt) {
+			return 0, validationError(ErrTypeMismatch, exp.Binary.Op)
+		}
+		return result, nil
+	case IsNullKind:
+		if _, err := s.expressionType(scope, exp.IsNull.Operand); err != nil {
+			return 0, err
+		}
+		return BoolType, nil
+	case UnaryKind:
+		ct, err := s.expressionType(scope, exp.Unary.Operand)
+		if err != nil {
+			return 0, err
+		}
+		if exp.Unary.Op.Value == string(MinusSymbol) {
+			ct, ok := arithmeticType(MinusSymbol, IntType, ct)
+			if !ok {
+				return 0, validationError(ErrTypeMismatch, exp.Unary.Op)
+			}
+			return ct, nil
+		}
+		if !compatible(ct, BoolType) {
+			return 0, validationError(ErrTypeMismatch, exp.Unary.Op)
+		}
+		return BoolType, nil
+	case InKind:
+		lt, err := s.expressionType(scope, exp.In.Left)
+		if err != nil {
+			return 0, err
+		}
+		if exp.In.Select != nil {
+			sub, err := s.selectResult(exp.In.Select)
+			if err != nil {
+				return 0, err
+			}
+			if len(sub.Cols) != 1 {
+				return 0, validationError(ErrSubqueryColumns, firstToken(exp.In.Left))
+			}
+			if !compatible(columnType(sub.Cols[0]), lt) {
+				return 0, validationError(ErrTypeMismatch, firstToken(exp.In.Left))
+			}
+		}
+		for _, item := range exp.In.List {
+			ct, err := s.expressionType(scope, item)
+			if err != nil {
+				return 0, err
+			}
+			if !compatible(ct, lt) {
+				return 0, validationError(ErrTypeMismatch, firstToken(item))
+			}
+		}
+		return BoolType, nil
+	case BetweenKind:
+		lt, err := s.expressionType(scope, exp.Between.Left)
+		if err != nil {
+			return 0, err
+		}
+		for _, bound := range []*Expression{exp.Between.Low, exp.Between.High} {
+			ct, err := s.expressionType(scope, bound)
+			if err != nil {
+				return 0, err
+			}
+			if !compatible(ct, lt) {
+				return 0, validationError(ErrTypeMismatch, firstToken(bound))
+			}
+		}
+		return BoolType, nil
+	case FunctionKind:
+		name := exp.Function.Name.Value
+		var types []ColumnType
+		for _, arg := range exp.Function.Args {
+			argType, err := s.expressionType(scope, arg)
+			if err != nil {
+				return 0, err
+			}
+			types = append(types, argType)
+		}
+		if over := exp.Function.Over; over != nil {
+			for _, e := range over.PartitionBy {
+				if _, err := s.expressionType(scope, e); err != nil {
+					return 0, err
+				}
+			}
+			for _, o := range over.OrderBy {
+				if _, err := s.expressionType(scope, o.Exp); err != nil {
+					return 0, err
+				}
+			}
+			switch {
+			case windowFunctions[name] && (len(types) > 0 || exp.Function.Asterisk):
+				return 0, validationError(ErrInvalidArguments, exp.Function.Name)
+			case windowFunctions[name]:
+				return BigIntType, nil
+			case !aggregateFunctions[name]:
+				return 0, validationError(ErrNotWindowFunction, exp.Function.Name)
+			}
+		} else if windowFunctions[name] {
+			return 0, validationError(ErrOverRequired, exp.Function.Name)
+		}
+		if aggregateFunctions[name] {
+			switch {
+			case name == "avg":
+				return FloatType, nil
+			case name == "count" || len(types) == 0:
+				return IntType, nil
+			case name == "sum" && !isNumeric(types[0]):
+				return 0, validationError(ErrTypeMismatch, exp.Function.Name)
+			}
+			// sum, min and max return a value of their argument's type.
+			return types[0], nil
+		}
+
+		fn, ok := lookupFunction(name)
+		if !ok {
+			return 0, validationError(ErrFunctionNotFound, exp.Function.Name)
+		}
+		if exp.Function.Asterisk {
+			return 0, validationError(ErrInvalidArguments, exp.Function.Name)
+		}
+		_, ct, err := fn.resolve(name, types)
+		if err != nil {
+			return 0, tokenError(Code(err), err, exp.Function.Name, err.Error())
+		}
+		return ct, nil
+	case CastKind:
+		from, err := s.expressionType(scope, exp.Cast.Operand)
+		if err != nil {
+			return 0, err
+		}
+		to, err := datatype(exp.Cast.Type)
+		if err != nil {
+			return 0, validationError(err, exp.Cast.Type)
+		}
+		if !castable(from, to) {
+			return 0, tokenError(TypeMismatchError, ErrInvalidCast, exp.Cast.Type, fmt.Sprintf("%s %s to %s", ErrInvalidCast, columnTypeKeyword(from), columnTypeKeyword(to)))
+		}
+		return to, nil
+	case CaseKind:
+		// Each WHEN is a condition, or in the simple form a value compared
+		// with the operand.
+		want := BoolType
+		if exp.Case.Operand != nil {
+			ct, err := s.expressionType(scope, exp.Case.Operand)
+			if err != nil {
+				return 0, err
+			}
+			want = ct
+		}
+
+		for _, when := range exp.Case.Whens {
+			ct, err := s.expressionType(scope, when.When)
+			if err != nil {
+				return 0, err
+			}
+			if !compatible(ct, want) {
+				return 0, validationError(ErrTypeMismatch, firstToken(when.When))
+			}
+		}
+
+		// The value takes the type the results have in common.
+		result := NullType
+		for _, r := range exp.Case.results() {
+			ct, err := s.expressionType(scope, r)
+			if err != nil {
+				return 0, err
+			}
+			var ok bool
+			result, ok = commonType(result, ct)
+			if !ok {
+				return 0, validationError(ErrTypeMismatch, firstToken(r))
+			}
+		}
+		return result, nil
+	}
+
+	return 0, validationError(ErrInvalidDatatype, firstToken(exp))
+}
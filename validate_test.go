@@ -0,0 +1,398 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table t (id int, name text)")
+	assert.Nil(t, err)
+	schema := mb.Schema()
+
+	tests := []struct {
+		source string
+		err    error
+		msg    string
+	}{
+		{
+			source: "select id, name from t where id = 1 and name like 'a%' order by name",
+		},
+		{
+			source: "insert into t values (1, 'a')",
+		},
+		{
+			source: "insert into t values (1, 'a'), (2, 3)",
+			err:    ErrInvalidDatatype,
+			msg:    "Invalid datatype: 3 at 0:35",
+		},
+		{
+			source: "insert into t (id) values (1), (2, 'b')",
+			err:    ErrMissingValues,
+			msg:    "Missing values: t at 0:12",
+		},
+		{
+			source: "insert into t (name, id) select name, id from t where id > 1",
+		},
+		{
+			source: "insert into t select id from t",
+			err:    ErrMissingValues,
+			msg:    "Missing values: t at 0:12",
+		},
+		{
+			source: "insert into t (name) select id from t",
+			err:    ErrInvalidDatatype,
+			msg:    "Invalid datatype: id at 0:28",
+		},
+		{
+			source: "insert into t (id) select nope from t",
+			err:    ErrColumnDoesNotExist,
+			msg:    "Column does not exist: nope at 0:26",
+		},
+		{
+			source: "select nonexistent from t",
+			err:    ErrColumnDoesNotExist,
+			msg:    "Column does not exist: nonexistent at 0:7",
+		},
+		{
+			source: "select * from t where name = 5",
+			err:    ErrTypeMismatch,
+			msg:    "Type mismatch: = at 0:27",
+		},
+		{
+			source: "select upper(name) from t where length(name) > 2 and coalesce(id, 0) = 1",
+		},
+		{
+			source: "select nope(name) from t",
+			err:    ErrFunctionNotFound,
+			msg:    "Function does not exist: nope at 0:7",
+		},
+		{
+			source: "select substr(name) from t",
+			err:    ErrInvalidArguments,
+			msg:    "Invalid function arguments: substr takes 2 to 3 arguments, got 1 at 0:7",
+		},
+		{
+			source: "select * from t where upper(id) = 'A'",
+			err:    ErrTypeMismatch,
+			msg:    "Type mismatch: argument 1 of upper must be TextType, got IntType at 0:22",
+		},
+		{
+			source: "select * from t where not id > 1",
+		},
+		{
+			source: "insert into t values (3000000000, 'a')",
+		},
+		{
+			source: "insert into t values (1.5, 'a')",
+			err:    ErrInvalidDatatype,
+			msg:    "Invalid datatype: 1.5 at 0:22",
+		},
+		{
+			source: "select id / 2.0 from t where id in (1, 2.5) and id * 1e2 > 3000000000",
+		},
+		{
+			source: "select id % 1.5 from t",
+			err:    ErrTypeMismatch,
+			msg:    "Type mismatch: % at 0:10",
+		},
+		{
+			source: "select * from t limit 1.5",
+			err:    ErrInvalidLimit,
+			msg:    "LIMIT and OFFSET must be non-negative integers: 1.5 at 0:22",
+		},
+		{
+			source: "select name || id from t where name not like 'a' || id",
+		},
+		{
+			source: "select * from t where (name || 'a') + 1 = 2",
+			err:    ErrTypeMismatch,
+			msg:    "Type mismatch: + at 0:36",
+		},
+		{
+			source: "select id * 2 + 1 as next, -id from t where id % 2 = 0",
+		},
+		{
+			source: "select name - 1 from t",
+			err:    ErrTypeMismatch,
+			msg:    "Type mismatch: - at 0:12",
+		},
+		{
+			source: "select * from t where -name = 1",
+			err:    ErrTypeMismatch,
+			msg:    "Type mismatch: - at 0:22",
+		},
+		{
+			source: "select * from t where not name",
+			err:    ErrTypeMismatch,
+			msg:    "Type mismatch: not at 0:22",
+		},
+		{
+			source: "update t set name = 'b' where id = 1",
+		},
+		{
+			source: "update t set name = 1",
+			err:    ErrInvalidDatatype,
+			msg:    "Invalid datatype: 1 at 0:20",
+		},
+		{
+			source: "update t set age = 1",
+			err:    ErrColumnDoesNotExist,
+			msg:    "Column does not exist: age at 0:13",
+		},
+		{
+			source: "delete from t where name = 'b'",
+		},
+		{
+			source: "delete from t where name",
+			err:    ErrInvalidCondition,
+			msg:    "Condition must be a boolean: name at 0:20",
+		},
+		{
+			source: "create table if not exists t (id int)",
+		},
+		{
+			source: "drop table if exists u",
+		},
+		{
+			source: "drop table u",
+			err:    ErrTableDoesNotExist,
+			msg:    "Table does not exist: u at 0:11",
+		},
+		{
+			source: "select * from t where id in (1, 'a')",
+			err:    ErrTypeMismatch,
+			msg:    "Type mismatch: 'a' at 0:32",
+		},
+		{
+			source: "select * from t where age = 1",
+			err:    ErrColumnDoesNotExist,
+			msg:    "Column does not exist: age at 0:22",
+		},
+		{
+			source: "select * from t where id",
+			err:    ErrInvalidCondition,
+			msg:    "Condition must be a boolean: id at 0:22",
+		},
+		{
+			source: "select * from u",
+			err:    ErrTableDoesNotExist,
+			msg:    "Table does not exist: u at 0:14",
+		},
+		{
+			source: "insert into t values ('a', 'b')",
+			err:    ErrInvalidDatatype,
+			msg:    "Invalid datatype: 'a' at 0:22",
+		},
+		{
+			source: "insert into t values (1, 'a') returning nope",
+			err:    ErrColumnDoesNotExist,
+			msg:    "Column does not exist: nope at 0:40",
+		},
+		{
+			source: "select * from t order by id limit 10 offset 2",
+		},
+		{
+			source: "select name, max(name) from t group by name having max(id) > 1",
+		},
+		{
+			source: "select name from t group by name having max(name)",
+			err:    ErrInvalidCondition,
+			msg:    "Condition must be a boolean: max at 0:40",
+		},
+		{
+			source: "select s.n from (select name as n from t) s where s.n in (select name from t)",
+		},
+		{
+			source: "select * from t where id in (select id, name from t)",
+			err:    ErrSubqueryColumns,
+			msg:    "Subquery must return exactly one column: id at 0:22",
+		},
+		{
+			source: "select * from t where id in (select name from t)",
+			err:    ErrTypeMismatch,
+			msg:    "Type mismatch: id at 0:22",
+		},
+		{
+			source: "select s.id from (select name from t) s",
+			err:    ErrColumnDoesNotExist,
+			msg:    "Column does not exist: id at 0:9",
+		},
+		{
+			source: "select * from t limit 'a'",
+			err:    ErrInvalidLimit,
+			msg:    "LIMIT and OFFSET must be non-negative integers: 'a' at 0:22",
+		},
+		{
+			source: "select * from t offset id",
+			err:    ErrColumnDoesNotExist,
+			msg:    "Column does not exist: id at 0:23",
+		},
+		{
+			source: "alter table t add column id int",
+			err:    ErrDuplicateColumn,
+			msg:    "Column specified more than once: id at 0:25",
+		},
+		{
+			source: "alter table t drop column nope",
+			err:    ErrColumnDoesNotExist,
+			msg:    "Column does not exist: nope at 0:26",
+		},
+		{
+			source: "alter table t rename id to name",
+			err:    ErrDuplicateColumn,
+			msg:    "Column specified more than once: name at 0:27",
+		},
+		{
+			source: "alter table t rename id to key_id",
+		},
+		{
+			source: "alter table t add column n int default id",
+			err:    ErrColumnDoesNotExist,
+			msg:    "Column does not exist: id at 0:39",
+		},
+		{
+			source: "create table u (n int default 'x')",
+			err:    ErrInvalidDatatype,
+			msg:    "Invalid datatype: 'x' at 0:30",
+		},
+		{
+			source: "create table u (b bool default 1 = 2)",
+		},
+		{
+			source: "insert into t values ('7'::int, cast(nope as text))",
+			err:    ErrColumnDoesNotExist,
+			msg:    "Column does not exist: nope at 0:37",
+		},
+		{
+			source: "update t set id = cast(1.5 as int), name = id::text where name::bool",
+		},
+		{
+			source: "select * from t where date '2024-01-31' + id > now() and extract(month from now()) = id",
+		},
+		{
+			source: "select * from t where date_trunc('day', name) = now()",
+			err:    ErrTypeMismatch,
+			msg:    "Type mismatch: argument 2 of date_trunc must be TimestampType, got TextType at 0:22",
+		},
+		{
+			source: "select * from t where (id = 1)::float > 0",
+			err:    ErrInvalidCast,
+			msg:    "Cannot cast boolean to float at 0:32",
+		},
+		{
+			source: "insert into t values (1.5::real, 'a')",
+			err:    ErrInvalidDatatype,
+			msg:    "Invalid datatype: 1.5 at 0:22",
+		},
+		{
+			source: "explain select nope from t",
+			err:    ErrColumnDoesNotExist,
+			msg:    "Column does not exist: nope at 0:15",
+		},
+		{
+			source: "create table t (id int)",
+			err:    ErrTableAlreadyExists,
+			msg:    "Table already exists: t at 0:13",
+		},
+	}
+
+	for _, test := range tests {
+		ast, err := Parse(test.source)
+		assert.Nil(t, err, test.source)
+
+		err = Validate(ast.Statements[0], schema)
+		if test.err == nil {
+			assert.Nil(t, err, test.source)
+			continue
+		}
+		assert.ErrorIs(t, err, test.err, test.source)
+		assert.EqualError(t, err, test.msg, test.source)
+	}
+}
+
+func TestValidate_join(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table users (id int, name text); create table orders (user_id int, total int)")
+	assert.Nil(t, err)
+	schema := mb.Schema()
+
+	ast, err := Parse("select users.name, total from users join orders on users.id = orders.user_id")
+	assert.Nil(t, err)
+	assert.Nil(t, Validate(ast.Statements[0], schema))
+
+	ast, err = Parse("select users.total from users join orders on users.id = orders.user_id")
+	assert.Nil(t, err)
+	assert.EqualError(t, Validate(ast.Statements[0], schema), "Column does not exist: total at 0:13")
+
+	ast, err = Parse("select u.name, o.total from users u join orders o on u.id = o.user_id")
+	assert.Nil(t, err)
+	assert.Nil(t, Validate(ast.Statements[0], schema))
+
+	ast, err = Parse("select users.name from users u")
+	assert.Nil(t, err)
+	assert.EqualError(t, Validate(ast.Statements[0], schema), "Column does not exist: name at 0:13")
+}
+
+func TestValidate_ambiguousColumn(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table users (id int, name text); create table orders (id int, user_id int)")
+	assert.Nil(t, err)
+	schema := mb.Schema()
+
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{"select id from users join orders on users.id = user_id", "Column reference is ambiguous: id at 0:7"},
+		{"select name from users u join users v on u.id = v.id", "Column reference is ambiguous: name at 0:7"},
+		{"select 1 from users u join orders u on u.id = user_id", "Table name specified more than once: u at 0:34"},
+		{"select u.id, v.name from users u join users v on u.id = v.id", ""},
+	}
+	for _, test := range tests {
+		ast, err := Parse(test.source)
+		assert.Nil(t, err)
+		err = Validate(ast.Statements[0], schema)
+		if test.err == "" {
+			assert.Nil(t, err, test.source)
+		} else {
+			assert.EqualError(t, err, test.err, test.source)
+		}
+	}
+
+	// The backend finds the ambiguity too when the query is not validated.
+	_, err = execute(t, mb, "insert into users values (1, 'a'); insert into orders values (2, 1);"+
+		"select id from users join orders on users.id = orders.user_id")
+	assert.ErrorIs(t, err, ErrAmbiguousColumn)
+}
+
+func TestValidate_onConflict(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := execute(t, mb, "create table users (id int primary key, name text, age int)")
+	assert.Nil(t, err)
+	schema := mb.Schema()
+
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{"insert into users values (1, 'a', 2) on conflict (id) do update set age = users.age + excluded.age where excluded.name <> name", ""},
+		{"insert into users values (1, 'a', 2) on conflict do nothing", ""},
+		{"insert into users values (1, 'a', 2) on conflict (name) do nothing", "No unique constraint matching the ON CONFLICT specification: name at 0:50"},
+		{"insert into users values (1, 'a', 2) on conflict (nope) do nothing", "Column does not exist: nope at 0:50"},
+		{"insert into users values (1, 'a', 2) on conflict (id) do update set age = excluded.nope", "Column does not exist: nope at 0:83"},
+		{"insert into users values (1, 'a', 2) on conflict (id) do update set age = excluded.name", "Invalid datatype"},
+	}
+	for _, test := range tests {
+		ast, err := Parse(test.source)
+		assert.Nil(t, err)
+		err = Validate(ast.Statements[0], schema)
+		if test.err == "" {
+			assert.Nil(t, err, test.source)
+		} else {
+			assert.ErrorContains(t, err, test.err, test.source)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package gosql
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// numericRank orders the numeric types by the values they can hold, so
+// that the narrower of two numbers can be widened to the other's type. It
+// is 0 for types that are not numbers.
+func numericRank(ct ColumnType) int {
+	switch ct {
+	case IntType:
+		return 1
+	case BigIntType:
+		return 2
+	case FloatType:
+		return 3
+	}
+	return 0
+}
+
+func isNumeric(ct ColumnType) bool {
+	return numericRank(ct) > 0
+}
+
+func isInteger(ct ColumnType) bool {
+	return ct == IntType || ct == BigIntType
+}
+
+// commonType is the type two values are converted to before they are
+// compared or combined. NULL takes the type of the other side and the
+// narrower of two numbers is widened, so int and float give float. A date
+// widens to the timestamp of its midnight. Other types only go with
+// themselves.
+func commonType(a, b ColumnType) (ColumnType, bool) {
+	switch {
+	case a == b || b == NullType:
+		return a, true
+	case a == NullType:
+		return b, true
+	case isNumeric(a) && isNumeric(b):
+		if numericRank(a) > numericRank(b) {
+			return a, true
+		}
+		return b, true
+	case isTemporal(a) && isTemporal(b):
+		return TimestampType, true
+	}
+	return 0, false
+}
+
+// implicit reports whether a value of type from is converted to type to
+// without being asked to, which only happens when nothing is lost.
+func implicit(from, to ColumnType) bool {
+	ct, ok := commonType(from, to)
+	return ok && (ct == to || to == NullType)
+}
+
+// assignable reports whether a value of type from can be stored in a
+// column of type to. Integers also narrow as long as the value fits and
+// timestamps drop their time of day, but a float has to be cast to be
+// stored in an integer column.
+func assignable(from, to ColumnType) bool {
+	return implicit(from, to) || isInteger(from) && isInteger(to) || from == TimestampType && to == DateType
+}
+
+// convertCell converts cell from type from to type to, which must be a
+// conversion assignable allows. Integers that do not fit in an int are
+// ErrIntegerOutOfRange.
+func convertCell(cell MemoryCell, from, to ColumnType) (MemoryCell, error) {
+	if cell.IsNull() || from == to || from == NullType || to == NullType {
+		return cell, nil
+	}
+
+	switch to {
+	case IntType:
+		i := cell.AsInt()
+		if i < math.MinInt32 || i > math.MaxInt32 {
+			return nil, ErrIntegerOutOfRange
+		}
+		return intCell(int32(i)), nil
+	case BigIntType:
+		return bigIntCell(cell.AsInt()), nil
+	case FloatType:
+		return floatCell(float64(cell.AsInt())), nil
+	case DateType:
+		return dateCell(cell.AsTime()), nil
+	case TimestampType:
+		return bigIntCell(cell.AsInt() * microsecondsPerDay), nil
+	}
+	return nil, ErrInvalidDatatype
+}
+
+// castable reports whether CAST can convert a value of type from to type
+// to. Anything can be cast to and from text, numbers to each other, dates
+// and timestamps to each other, and integers to and from booleans. Only
+// NULL can be cast from NullType.
+func castable(from, to ColumnType) bool {
+	switch {
+	case from == to || from == NullType || from == TextType || to == TextType:
+		return true
+	case isNumeric(from) && isNumeric(to), isTemporal(from) && isTemporal(to):
+		return true
+	case from == BoolType:
+		return isInteger(to)
+	case to == BoolType:
+		return isInteger(from)
+	}
+	return false
+}
+
+// castCell converts cell from type from to type to for CAST. Unlike
+// convertCell it narrows floats to integers, rounding halves to even, and
+// parses text, reporting ErrInvalidTextRepresentation when the text does
+// not hold a value of type to.
+func castCell(cell MemoryCell, from, to ColumnType) (MemoryCell, error) {
+	if !castable(from, to) {
+		return nil, fmt.Errorf("%w %s to %s", ErrInvalidCast, columnTypeKeyword(from), columnTypeKeyword(to))
+	}
+	if cell.IsNull() || from == to || from == NullType {
+		return cell, nil
+	}
+
+	switch {
+	case to == TextType:
+		return MemoryCell(cellText(cell, from)), nil
+	case from == TextType:
+		return parseCell(cell.AsText(), to)
+	case from == BoolType:
+		if cell.AsBool() {
+			return convertCell(intCell(1), IntType, to)
+		}
+		return convertCell(intCell(0), IntType, to)
+	case to == BoolType:
+		return boolCell(cell.AsInt() != 0), nil
+	case from == FloatType && isInteger(to):
+		f := math.RoundToEven(cell.AsFloat())
+		if !(f >= math.MinInt64 && f < math.MaxInt64) {
+			return nil, ErrIntegerOutOfRange
+		}
+		return convertCell(bigIntCell(int64(f)), BigIntType, to)
+	}
+	return convertCell(cell, from, to)
+}
+
+// parseCell reads a value of type ct from text the way PostgreSQL reads
+// input, ignoring surrounding whitespace.
+func parseCell(s string, ct ColumnType) (MemoryCell, error) {
+	s = strings.TrimSpace(s)
+	invalid := fmt.Errorf("%w for type %s: %q", ErrInvalidTextRepresentation, columnTypeKeyword(ct), s)
+
+	switch ct {
+	case IntType, BigIntType:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+				return nil, ErrIntegerOutOfRange
+			}
+			return nil, invalid
+		}
+		return convertCell(bigIntCell(i), BigIntType, ct)
+	case FloatType:
+		// ParseFloat also reads hexadecimal and underscores, which are
+		// not SQL.
+		if strings.ContainsAny(s, "xX_") {
+			return nil, invalid
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+				return nil, ErrValueOutOfRange
+			}
+			return nil, invalid
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, ErrValueOutOfRange
+		}
+		return floatCell(f), nil
+	case BoolType:
+		switch strings.ToLower(s) {
+		case "t", "true", "y", "yes", "on", "1":
+			return boolCell(true), nil
+		case "f", "false", "n", "no", "off", "0":
+			return boolCell(false), nil
+		}
+		return nil, invalid
+	case DateType, TimestampType:
+		t, ok := parseTime(s)
+		if !ok {
+			return nil, invalid
+		}
+		if !timeInRange(t) {
+			return nil, ErrValueOutOfRange
+		}
+		if ct == DateType {
+			return dateCell(t), nil
+		}
+		return timestampCell(t), nil
+	case JSONType:
+		return parseJSON(s)
+	case ByteaType:
+		cell, ok := parseBytes(s)
+		if !ok {
+			return nil, invalid
+		}
+		return cell, nil
+	}
+	return MemoryCell(s), nil
+}
+
+// numericLiteral parses the text of a number. Integers are ints when they
+// fit and bigints otherwise, and numbers with a fraction or an exponent are
+// floats.
+func numericLiteral(s string) (MemoryCell, ColumnType, error) {
+	if strings.ContainsAny(s, ".e") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+				return nil, 0, ErrValueOutOfRange
+			}
+			return nil, 0, ErrInvalidDatatype
+		}
+		return floatCell(f), FloatType, nil
+	}
+
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+			return nil, 0, ErrIntegerOutOfRange
+		}
+		return nil, 0, ErrInvalidDatatype
+	}
+	if i < math.MinInt32 || i > math.MaxInt32 {
+		return bigIntCell(i), BigIntType, nil
+	}
+	return intCell(int32(i)), IntType, nil
+}
+
+// floatLiteral formats f as the text of a number that numericLiteral reads
+// back as a float.
+func floatLiteral(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// FormatFloat formats f the way PostgreSQL prints double precision values:
+// in as few digits as it takes to read back the same value, switching to
+// an exponent for very large and very small numbers.
+func FormatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	exp, err := strconv.Atoi(s[strings.IndexByte(s, 'e')+1:])
+	if err == nil && exp >= -4 && exp < 15 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return s
+}
+
+// cellText is the text of cell as || and concat see it.
+func cellText(cell MemoryCell, ct ColumnType) string {
+	switch ct {
+	case IntType, BigIntType:
+		return strconv.FormatInt(cell.AsInt(), 10)
+	case FloatType:
+		return FormatFloat(cell.AsFloat())
+	case BoolType:
+		return strconv.FormatBool(cell.AsBool())
+	case DateType:
+		return FormatDate(cell.AsTime())
+	case TimestampType:
+		return FormatTimestamp(cell.AsTime())
+	case ByteaType:
+		return FormatBytes(cell)
+	}
+	return cell.AsText()
+}
+
+// arithmeticType is the type of op applied to values of types lt and rt,
+// which must be numbers. % only works on integers. Dates move by a whole
+// number of days, and subtracting two dates gives the days between them.
+func arithmeticType(op Symbol, lt, rt ColumnType) (ColumnType, bool) {
+	if lt == DateType || rt == DateType {
+		days := func(ct ColumnType) bool { return isInteger(ct) || ct == NullType }
+		switch {
+		case lt == DateType && rt == DateType:
+			return IntType, op == MinusSymbol
+		case lt == DateType && days(rt):
+			return DateType, op == PlusSymbol || op == MinusSymbol
+		case days(lt) && rt == DateType:
+			return DateType, op == PlusSymbol
+		}
+		return 0, false
+	}
+
+	ct, ok := commonType(lt, rt)
+	switch {
+	case !ok:
+		return 0, false
+	case ct == NullType:
+		return IntType, true
+	case ct == FloatType && op == PercentSymbol:
+		return 0, false
+	}
+	return ct, isNumeric(ct)
+}
+
+// integerArithmetic applies op to two integers, reporting false when the
+// result does not fit in an int64.
+func integerArithmetic(op Symbol, l, r int64) (int64, bool, error) {
+	switch op {
+	case PlusSymbol:
+		result := l + r
+		return result, (result > l) == (r > 0), nil
+	case MinusSymbol:
+		result := l - r
+		return result, (result < l) == (r > 0), nil
+	case AsteriskSymbol:
+		if l == 0 || r == 0 {
+			return 0, true, nil
+		}
+		result := l * r
+		return result, result/r == l && !(r == -1 && l == math.MinInt64), nil
+	case SlashSymbol, PercentSymbol:
+		if r == 0 {
+			return 0, false, ErrDivisionByZero
+		}
+		if op == PercentSymbol {
+			return l % r, true, nil
+		}
+		return l / r, !(l == math.MinInt64 && r == -1), nil
+	}
+	return 0, false, ErrInvalidOperator
+}
+
+// floatArithmetic applies op to two floats. Results too large to hold are
+// an error rather than infinity.
+func floatArithmetic(op Symbol, l, r float64) (float64, error) {
+	var result float64
+	switch op {
+	case PlusSymbol:
+		result = l + r
+	case MinusSymbol:
+		result = l - r
+	case AsteriskSymbol:
+		result = l * r
+	case SlashSymbol:
+		if r == 0 {
+			return 0, ErrDivisionByZero
+		}
+		result = l / r
+	default:
+		return 0, ErrInvalidOperator
+	}
+	if math.IsInf(result, 0) {
+		return 0, ErrValueOutOfRange
+	}
+	return result, nil
+}
@@ -0,0 +1,122 @@
+package gosql
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommonType(t *testing.T) {
+	tests := []struct {
+		a, b   ColumnType
+		common ColumnType
+		ok     bool
+	}{
+		{IntType, IntType, IntType, true},
+		{IntType, BigIntType, BigIntType, true},
+		{FloatType, IntType, FloatType, true},
+		{NullType, BigIntType, BigIntType, true},
+		{TextType, NullType, TextType, true},
+		{TextType, IntType, 0, false},
+		{BoolType, FloatType, 0, false},
+	}
+	for _, test := range tests {
+		common, ok := commonType(test.a, test.b)
+		assert.Equal(t, test.ok, ok, test)
+		assert.Equal(t, test.common, common, test)
+	}
+
+	assert.True(t, implicit(IntType, FloatType))
+	assert.False(t, implicit(FloatType, IntType))
+	assert.True(t, assignable(BigIntType, IntType))
+	assert.False(t, assignable(FloatType, BigIntType))
+}
+
+func TestCastCell(t *testing.T) {
+	tests := []struct {
+		cell     MemoryCell
+		from, to ColumnType
+		result   MemoryCell
+		err      error
+	}{
+		{intCell(5), IntType, TextType, MemoryCell("5"), nil},
+		{floatCell(0.5), FloatType, TextType, MemoryCell("0.5"), nil},
+		{boolCell(false), BoolType, TextType, MemoryCell("false"), nil},
+		{MemoryCell(" -12 "), TextType, BigIntType, bigIntCell(-12), nil},
+		{MemoryCell("1.5"), TextType, IntType, nil, ErrInvalidTextRepresentation},
+		{MemoryCell("2e1"), TextType, FloatType, floatCell(20), nil},
+		{MemoryCell("1e400"), TextType, FloatType, nil, ErrValueOutOfRange},
+		{MemoryCell("inf"), TextType, FloatType, nil, ErrValueOutOfRange},
+		{MemoryCell("Off"), TextType, BoolType, boolCell(false), nil},
+		{floatCell(0.5), FloatType, IntType, intCell(0), nil},
+		{floatCell(1.5), FloatType, BigIntType, bigIntCell(2), nil},
+		{floatCell(9.3e18), FloatType, BigIntType, nil, ErrIntegerOutOfRange},
+		{bigIntCell(1 << 40), BigIntType, IntType, nil, ErrIntegerOutOfRange},
+		{intCell(-1), IntType, BoolType, boolCell(true), nil},
+		{boolCell(true), BoolType, BigIntType, bigIntCell(1), nil},
+		{boolCell(true), BoolType, FloatType, nil, ErrInvalidCast},
+		{nullCell, TextType, IntType, nullCell, nil},
+		{nullCell, NullType, BoolType, nullCell, nil},
+	}
+	for _, test := range tests {
+		result, err := castCell(test.cell, test.from, test.to)
+		assert.ErrorIs(t, err, test.err, test)
+		assert.Equal(t, test.result, result, test)
+	}
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []struct {
+		op     Symbol
+		l, r   int64
+		result int64
+		ok     bool
+	}{
+		{PlusSymbol, math.MaxInt64 - 1, 1, math.MaxInt64, true},
+		{PlusSymbol, math.MaxInt64, 1, 0, false},
+		{PlusSymbol, math.MinInt64, -1, 0, false},
+		{MinusSymbol, math.MinInt64 + 1, 1, math.MinInt64, true},
+		{MinusSymbol, 0, math.MinInt64, 0, false},
+		{AsteriskSymbol, 1 << 31, 1 << 31, 1 << 62, true},
+		{AsteriskSymbol, 1 << 32, 1 << 31, 0, false},
+		{AsteriskSymbol, math.MinInt64, -1, 0, false},
+		{SlashSymbol, math.MinInt64, -1, 0, false},
+		{SlashSymbol, -7, 2, -3, true},
+		{PercentSymbol, -7, 2, -1, true},
+	}
+	for _, test := range tests {
+		result, ok, err := integerArithmetic(test.op, test.l, test.r)
+		assert.Nil(t, err)
+		assert.Equal(t, test.ok, ok, test)
+		if test.ok {
+			assert.Equal(t, test.result, result, test)
+		}
+	}
+
+	_, _, err := integerArithmetic(PercentSymbol, 1, 0)
+	assert.Equal(t, ErrDivisionByZero, err)
+}
+
+func TestFormatFloat(t *testing.T) {
+	tests := []struct {
+		f    float64
+		text string
+	}{
+		{0, "0"},
+		{1.5, "1.5"},
+		{-0.25, "-0.25"},
+		{1000000, "1000000"},
+		{0.0001, "0.0001"},
+		{0.00001, "1e-05"},
+		{123456789012345, "123456789012345"},
+		{1e15, "1e+15"},
+		{math.Nextafter(0.3, 1), "0.30000000000000004"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.text, FormatFloat(test.f))
+	}
+
+	assert.Equal(t, "2.0", floatLiteral(2))
+	assert.Equal(t, "1e+21", floatLiteral(1e21))
+}
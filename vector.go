@@ -0,0 +1,386 @@
+package gosql
+
+import (
+	"bytes"
+	"context"
+	"math"
+)
+
+// batchSize is how many rows filters and projections evaluate at once.
+const batchSize = 1024
+
+// vectorExpression is an expression compiled to compute its value for a
+// whole batch of rows at once, as a columnVector with an entry for each
+// row. Walking the expression once per batch rather than once per row,
+// and working on unpacked values in tight loops, saves most of the cost
+// of evaluating simple conditions and arithmetic over many rows.
+//
+// Only column references, literals, arithmetic, comparisons, AND, OR, NOT
+// and IS NULL compile. Other expressions are evaluated a row at a time by
+// evaluateExpression, which also reports the errors: when a batch fails,
+// its rows are evaluated again one at a time to find the first error.
+type vectorExpression struct {
+	ct ColumnType
+	// column is the column a bare column reference reads, or -1.
+	column int
+	eval   func(rows [][]MemoryCell) (*columnVector, error)
+}
+
+// compileVector compiles exp against the columns of t, returning nil when
+// exp has a part that does not compile or whose types do not go together.
+func (t *table) compileVector(exp *Expression) *vectorExpression {
+	switch exp.Kind {
+	case LiteralKind, ColumnReferenceKind:
+		i, err := t.resolveColumn(exp)
+		if err != nil {
+			return nil
+		}
+		if i == -1 {
+			cell, ct, err := literalToCell(exp.Literal)
+			if err != nil {
+				return nil
+			}
+			return constantVector(cell, ct)
+		}
+		return columnVectorExpression(i, t.columnTypes[i])
+	case BinaryKind:
+		return t.compileBinaryVector(exp.Binary)
+	case UnaryKind:
+		operand := t.compileVector(exp.Unary.Operand)
+		if operand == nil {
+			return nil
+		}
+		switch exp.Unary.Op.Value {
+		case string(NotKeyword):
+			return notVector(operand)
+		case string(MinusSymbol):
+			return arithmeticVector(MinusSymbol, constantVector(intCell(0), IntType), operand)
+		}
+	case IsNullKind:
+		operand := t.compileVector(exp.IsNull.Operand)
+		if operand == nil {
+			return nil
+		}
+		return isNullVector(operand, exp.IsNull.Not)
+	}
+	return nil
+}
+
+func (t *table) compileBinaryVector(bexp *BinaryExpression) *vectorExpression {
+	left := t.compileVector(bexp.Left)
+	if left == nil {
+		return nil
+	}
+	right := t.compileVector(bexp.Right)
+	if right == nil {
+		return nil
+	}
+
+	op := bexp.Op
+	switch op.Kind {
+	case KeywordKind:
+		switch keyword(op.Value) {
+		case AndKeyword, OrKeyword:
+			return logicalVector(keyword(op.Value) == AndKeyword, left, right)
+		}
+	case SymbolKind:
+		switch Symbol(op.Value) {
+		case PlusSymbol, MinusSymbol, AsteriskSymbol, SlashSymbol, PercentSymbol:
+			return arithmeticVector(Symbol(op.Value), left, right)
+		case EqSymbol, NeqSymbol, BangEqSymbol, LtSymbol, LteSymbol, GtSymbol, GteSymbol:
+			// Text with a collation compares by its keys, which the
+			// vectors do not hold.
+			if t.collationOf(bexp.Left) != nil || t.collationOf(bexp.Right) != nil {
+				return nil
+			}
+			return comparisonVector(Symbol(op.Value), left, right)
+		}
+	}
+	return nil
+}
+
+func constantVector(cell MemoryCell, ct ColumnType) *vectorExpression {
+	return &vectorExpression{ct: ct, column: -1, eval: func(rows [][]MemoryCell) (*columnVector, error) {
+		cv := newColumnVector(ct)
+		for range rows {
+			cv.append(cell)
+		}
+		return cv, nil
+	}}
+}
+
+func columnVectorExpression(i int, ct ColumnType) *vectorExpression {
+	return &vectorExpression{ct: ct, column: i, eval: func(rows [][]MemoryCell) (*columnVector, error) {
+		cv := newColumnVector(ct)
+		for _, row := range rows {
+			cv.append(row[i])
+		}
+		return cv, nil
+	}}
+}
+
+// evalBoth evaluates two operands over rows.
+func evalBoth(left, right *vectorExpression, rows [][]MemoryCell) (*columnVector, *columnVector, error) {
+	l, err := left.eval(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := right.eval(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, r, nil
+}
+
+// arithmeticVector is arithmetic on numbers. Dates do not compile.
+func arithmeticVector(op Symbol, left, right *vectorExpression) *vectorExpression {
+	ct, ok := arithmeticType(op, left.ct, right.ct)
+	if !ok || !isNumeric(ct) || left.ct != NullType && !isNumeric(left.ct) || right.ct != NullType && !isNumeric(right.ct) {
+		return nil
+	}
+
+	return &vectorExpression{ct: ct, column: -1, eval: func(rows [][]MemoryCell) (*columnVector, error) {
+		l, r, err := evalBoth(left, right, rows)
+		if err != nil {
+			return nil, err
+		}
+		out := makeColumnVector(ct, len(rows))
+		for k := range rows {
+			if l.nulls[k] || r.nulls[k] {
+				out.nulls[k] = true
+				continue
+			}
+			if ct == FloatType {
+				if out.floats[k], err = floatArithmetic(op, l.float(k), r.float(k)); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			result, ok, err := integerArithmetic(op, l.ints[k], r.ints[k])
+			if err != nil {
+				return nil, err
+			}
+			if !ok || ct == IntType && (result < math.MinInt32 || result > math.MaxInt32) {
+				return nil, ErrIntegerOutOfRange
+			}
+			out.ints[k] = result
+		}
+		return out, nil
+	}}
+}
+
+// comparisonVector compares numbers with each other, or values of the
+// same other type.
+func comparisonVector(op Symbol, left, right *vectorExpression) *vectorExpression {
+	ct, ok := commonType(left.ct, right.ct)
+	if !ok || !isNumeric(ct) && left.ct != right.ct && left.ct != NullType && right.ct != NullType {
+		return nil
+	}
+
+	return &vectorExpression{ct: BoolType, column: -1, eval: func(rows [][]MemoryCell) (*columnVector, error) {
+		l, r, err := evalBoth(left, right, rows)
+		if err != nil {
+			return nil, err
+		}
+		out := makeColumnVector(BoolType, len(rows))
+		for k := range rows {
+			if l.nulls[k] || r.nulls[k] {
+				out.nulls[k] = true
+				continue
+			}
+
+			var c int
+			equal := false
+			switch {
+			case isInteger(ct):
+				c = compareInts(l.ints[k], r.ints[k])
+				equal = c == 0
+			case ct == FloatType:
+				// Equal floats are equal bytes, as in cells.
+				lf, rf := l.float(k), r.float(k)
+				c = compareFloats(lf, rf)
+				equal = lf == rf || math.Float64bits(lf) == math.Float64bits(rf)
+			case ct == BoolType:
+				c = compareBools(l.bools[k], r.bools[k])
+				equal = c == 0
+			default:
+				c = compareCells(l.cells[k], r.cells[k], ct)
+				equal = bytes.Equal(l.cells[k], r.cells[k])
+			}
+
+			switch op {
+			case EqSymbol:
+				out.bools[k] = equal
+			case NeqSymbol, BangEqSymbol:
+				out.bools[k] = !equal
+			case LtSymbol:
+				out.bools[k] = c < 0
+			case LteSymbol:
+				out.bools[k] = c <= 0
+			case GtSymbol:
+				out.bools[k] = c > 0
+			case GteSymbol:
+				out.bools[k] = c >= 0
+			}
+		}
+		return out, nil
+	}}
+}
+
+// logicalVector is AND, when and is set, or OR, with the three-valued
+// logic of evaluateBinaryExpression.
+func logicalVector(and bool, left, right *vectorExpression) *vectorExpression {
+	if !compatible(left.ct, BoolType) || !compatible(right.ct, BoolType) {
+		return nil
+	}
+
+	return &vectorExpression{ct: BoolType, column: -1, eval: func(rows [][]MemoryCell) (*columnVector, error) {
+		l, r, err := evalBoth(left, right, rows)
+		if err != nil {
+			return nil, err
+		}
+		out := makeColumnVector(BoolType, len(rows))
+		for k := range rows {
+			// A NULL side is unknown, so it only decides the result when
+			// the other side does not.
+			lk, rk := !l.nulls[k] && l.bools[k], !r.nulls[k] && r.bools[k]
+			if and {
+				lFalse, rFalse := !l.nulls[k] && !l.bools[k], !r.nulls[k] && !r.bools[k]
+				out.bools[k] = lk && rk
+				out.nulls[k] = !lFalse && !rFalse && (l.nulls[k] || r.nulls[k])
+			} else {
+				out.bools[k] = lk || rk
+				out.nulls[k] = !lk && !rk && (l.nulls[k] || r.nulls[k])
+			}
+		}
+		return out, nil
+	}}
+}
+
+func notVector(operand *vectorExpression) *vectorExpression {
+	if !compatible(operand.ct, BoolType) {
+		return nil
+	}
+
+	return &vectorExpression{ct: BoolType, column: -1, eval: func(rows [][]MemoryCell) (*columnVector, error) {
+		v, err := operand.eval(rows)
+		if err != nil {
+			return nil, err
+		}
+		out := makeColumnVector(BoolType, len(rows))
+		for k := range rows {
+			out.nulls[k] = v.nulls[k]
+			out.bools[k] = !v.nulls[k] && !v.bools[k]
+		}
+		return out, nil
+	}}
+}
+
+func isNullVector(operand *vectorExpression, not bool) *vectorExpression {
+	return &vectorExpression{ct: BoolType, column: -1, eval: func(rows [][]MemoryCell) (*columnVector, error) {
+		v, err := operand.eval(rows)
+		if err != nil {
+			return nil, err
+		}
+		out := makeColumnVector(BoolType, len(rows))
+		for k := range rows {
+			out.bools[k] = v.nulls[k] != not
+		}
+		return out, nil
+	}}
+}
+
+// filterBatches is filter for a condition that compiled to cond.
+func (t *table) filterBatches(ctx context.Context, where *Expression, cond *vectorExpression) ([][]MemoryCell, error) {
+	var rows [][]MemoryCell
+	for start := 0; start < len(t.rows); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := start + batchSize
+		if end > len(t.rows) {
+			end = len(t.rows)
+		}
+		batch := t.rows[start:end]
+
+		matches, err := cond.eval(batch)
+		if err != nil {
+			kept, err := t.filterRows(ctx, batch, where)
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, kept...)
+			continue
+		}
+		for k, row := range batch {
+			if !matches.nulls[k] && matches.bools[k] {
+				rows = append(rows, row)
+			}
+		}
+	}
+	return rows, nil
+}
+
+// compileItems compiles the select items for projectBatch. It returns nil
+// when one of them does not compile, or when each is * or a bare column,
+// which projecting a row at a time already only copies.
+func (t *table) compileItems(items []*SelectItem) []*vectorExpression {
+	compiled := make([]*vectorExpression, len(items))
+	computed := false
+	for i, item := range items {
+		if item.Asterisk {
+			continue
+		}
+		if compiled[i] = t.compileVector(item.Exp); compiled[i] == nil {
+			return nil
+		}
+		computed = computed || compiled[i].column == -1
+	}
+	if !computed {
+		return nil
+	}
+	return compiled
+}
+
+// batchLength is how many rows to project at once for a query that still
+// skips skip rows and then wants want rows, or any number when want is
+// negative. Only the rows a query returns are projected a row at a time,
+// so batches do not go past them, except for DISTINCT, which may drop
+// any row.
+func batchLength(distinct bool, skip, want int) int {
+	if distinct || want < 0 || skip+want >= batchSize {
+		return batchSize
+	}
+	return skip + want
+}
+
+// projectBatch is project for a batch of rows, with the items compiled by
+// compileItems.
+func (t *table) projectBatch(items []*SelectItem, compiled []*vectorExpression, rows [][]MemoryCell) ([][]Cell, error) {
+	results := make([][]Cell, len(rows))
+	for i, item := range items {
+		if item.Asterisk {
+			for k, row := range rows {
+				for _, cell := range row {
+					results[k] = append(results[k], cell)
+				}
+			}
+			continue
+		}
+		if col := compiled[i].column; col != -1 {
+			for k, row := range rows {
+				results[k] = append(results[k], row[col])
+			}
+			continue
+		}
+
+		values, err := compiled[i].eval(rows)
+		if err != nil {
+			return nil, err
+		}
+		for k := range rows {
+			results[k] = append(results[k], values.cell(k))
+		}
+	}
+	return results, nil
+}
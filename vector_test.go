@@ -0,0 +1,109 @@
+package gosql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVectorExpression(t *testing.T) {
+	mb := NewMemoryBackend()
+	script := "create table t (n int, b bigint, f float, s text, ok bool);"
+	for i := 0; i < 3000; i++ {
+		values := fmt.Sprintf("(%d, %d, %d.25, '%c', %t)", i%11-5, i*1000000, i%7, 'a'+i%5, i%3 == 0)
+		if i%13 == 0 {
+			values = "(null, null, null, null, null)"
+		}
+		script += "insert into t values " + values + ";"
+	}
+	_, err := ExecuteScript(mb, script, ScriptOptions{})
+	assert.Nil(t, err)
+	view := mb.tables["t"].visibleTo(mb.snapshot())
+
+	// Each expression compiles and gives what evaluating it a row at a
+	// time gives.
+	expressions := []string{
+		"n + 1", "n * b", "b - n", "n % 3", "n / 2", "f * n", "f / 2", "-n", "-f",
+		"n > 2", "n <= f", "b = 5000000", "f <> 3.25", "s < 'c'", "s = 'b'", "ok = true", "n = null",
+		"n > 0 and ok", "n > 0 or ok", "not ok", "n is null", "s is not null",
+		"ok and null", "null or ok", "n + null", "(n + 1) * 2 > b / 1000000 or not (s >= 'd')",
+	}
+	for _, source := range expressions {
+		exp := mustParseExpression(t, source)
+		compiled := view.compileVector(exp)
+		if !assert.NotNil(t, compiled, source) {
+			continue
+		}
+		values, err := compiled.eval(view.rows)
+		if !assert.Nil(t, err, source) {
+			continue
+		}
+		for k, row := range view.rows {
+			cell, ct, err := view.evaluateExpression(row, exp)
+			assert.Nil(t, err, source)
+			assert.Equal(t, ct, compiled.ct, source)
+			if !assert.Equal(t, cell, values.cell(k), "%s at row %d", source, k) {
+				break
+			}
+		}
+	}
+
+	// These are left to evaluateExpression.
+	for _, source := range []string{"n in (1, 2)", "abs(n)", "s || 'x'", "n > s", "cast(n as text)"} {
+		assert.Nil(t, view.compileVector(mustParseExpression(t, source)), source)
+	}
+
+	tests := []struct {
+		query string
+		rows  int
+		err   error
+	}{
+		{"select n from t where n > 2 and ok", 251, nil},
+		{"select n + 1, s from t where f > 3 or s = 'a'", 1817, nil},
+		{"select 10 / (n + 5) from t where n is not null limit 2", 2, nil},
+		{"select 10 / (n + 5) from t where n is not null", 0, ErrDivisionByZero},
+		{"select n from t where 10 / (n + 5) > 1", 0, ErrDivisionByZero},
+		{"select n * 1000000000 from t limit 1", 1, nil},
+		{"select n * 1000000000 from t", 0, ErrIntegerOutOfRange},
+	}
+	for _, test := range tests {
+		results, err := ExecuteScript(mb, test.query, ScriptOptions{})
+		if test.err != nil {
+			assert.ErrorIs(t, err, test.err, test.query)
+			continue
+		}
+		if assert.Nil(t, err, test.query) {
+			assert.Equal(t, test.rows, len(results[0].Results.Rows), test.query)
+		}
+	}
+}
+
+func mustParseExpression(t *testing.T, source string) *Expression {
+	ast, err := Parse("select " + source + " from t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ast.Statements[0].SelectStatement.Item[0].Exp
+}
+
+func BenchmarkFilter(b *testing.B) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table t (n int, f float)", ScriptOptions{})
+	assert.Nil(b, err)
+	rows := make([][]Cell, 100000)
+	for i := range rows {
+		rows[i] = []Cell{intCell(int32(i % 1000)), floatCell(float64(i) / 3)}
+	}
+	_, err = mb.InsertRows(context.Background(), "t", nil, rows)
+	assert.Nil(b, err)
+	ast, err := Parse("select n * 2 + 1, f from t where n % 7 = 3 and f > 100")
+	assert.Nil(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := mb.Select(context.Background(), ast.Statements[0].SelectStatement)
+		assert.Nil(b, err)
+	}
+}
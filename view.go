@@ -0,0 +1,209 @@
+package gosql
+
+import (
+	"context"
+	"sort"
+)
+
+func (mb *MemoryBackend) CreateView(ctx context.Context, crt *CreateViewStatement) error {
+	return mb.session.CreateView(ctx, crt)
+}
+
+func (mb *MemoryBackend) DropView(ctx context.Context, drp *DropViewStatement) error {
+	return mb.session.DropView(ctx, drp)
+}
+
+func (s *Session) CreateView(ctx context.Context, crt *CreateViewStatement) error {
+	return s.alter(ctx, func(tx *transaction) error {
+		return s.mb.createView(tx, crt)
+	})
+}
+
+func (s *Session) DropView(ctx context.Context, drp *DropViewStatement) error {
+	return s.alter(ctx, func(tx *transaction) error {
+		if drp.IfExists && !s.mb.exists(drp.Name.Value) {
+			warn(ctx, "view %s does not exist, skipping", drp.Name.Value)
+		}
+		return s.mb.dropView(tx, drp)
+	})
+}
+
+// exists reports whether a table or a view is called name.
+func (mb *MemoryBackend) exists(name string) bool {
+	_, table := mb.tables[name]
+	_, view := mb.views[name]
+	return table || view
+}
+
+// createView stores the query of crt once it has checked that the query
+// is valid against the tables as they are.
+func (mb *MemoryBackend) createView(tx *transaction, crt *CreateViewStatement) error {
+	if mb.exists(crt.Name.Value) {
+		return ErrTableAlreadyExists
+	}
+	if !isTemporary(crt.Name.Value) {
+		if !mb.hasDatabase(databaseOf(crt.Name.Value)) {
+			return ErrDatabaseDoesNotExist
+		}
+		for _, name := range readTables(crt.Select, mb.views) {
+			if isTemporary(name) {
+				return ErrTemporaryReference
+			}
+		}
+	}
+	if err := mb.schema().validateView(crt); err != nil {
+		return err
+	}
+
+	mb.views[crt.Name.Value] = crt.Select
+	tx.undo = append(tx.undo, func() {
+		delete(mb.views, crt.Name.Value)
+	})
+	return nil
+}
+
+func (mb *MemoryBackend) dropView(tx *transaction, drp *DropViewStatement) error {
+	slct, ok := mb.views[drp.Name.Value]
+	if !ok {
+		if _, ok := mb.tables[drp.Name.Value]; ok {
+			return ErrWrongObjectType
+		}
+		if drp.IfExists {
+			return nil
+		}
+		return ErrTableDoesNotExist
+	}
+	if err := mb.checkDependents(drp.Name.Value); err != nil {
+		return err
+	}
+
+	delete(mb.views, drp.Name.Value)
+	tx.undo = append(tx.undo, func() {
+		mb.views[drp.Name.Value] = slct
+	})
+	return nil
+}
+
+// checkDependents fails with ErrDependentObjects when a view reads from
+// the table or view called name, or a foreign key of another table
+// references it. Views and foreign keys are kept valid this way instead of
+// failing once what they read has gone.
+func (mb *MemoryBackend) checkDependents(name string) error {
+	if mb.referenced(name) {
+		return ErrDependentObjects
+	}
+	for _, slct := range mb.views {
+		for _, read := range readTables(slct, nil) {
+			if read == name {
+				return ErrDependentObjects
+			}
+		}
+	}
+	return nil
+}
+
+// checkColumnDependents fails with ErrDependentObjects when a view or a
+// foreign key reads the column called column of the table called name,
+// which may then not be dropped or renamed. Views are matched by the
+// names they use, so one that reads the table and uses the name for a
+// column of another table counts too, as does one that selects every
+// column with an asterisk.
+func (mb *MemoryBackend) checkColumnDependents(name, column string) error {
+	if mb.columnReferenced(name, column) {
+		return ErrDependentObjects
+	}
+	for _, slct := range mb.views {
+		reads := false
+		for _, read := range readTables(slct, nil) {
+			reads = reads || read == name
+		}
+		if reads && usesColumn(slct, column) {
+			return ErrDependentObjects
+		}
+	}
+	return nil
+}
+
+// usesColumn reports whether slct names a column called column anywhere,
+// or selects every column of a table with an asterisk.
+func usesColumn(slct *SelectStatement, column string) bool {
+	found := false
+	Inspect(slct, func(node Node) bool {
+		switch n := node.(type) {
+		case *SelectItem:
+			found = found || n.Asterisk
+		case *Expression:
+			switch {
+			case n.Kind == LiteralKind && n.Literal.Kind == IdentifierKind:
+				found = found || n.Literal.Value == column
+			case n.Kind == ColumnReferenceKind:
+				found = found || n.Column.Column.Value == column
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// addViews adds the views to schema, each described by the columns its
+// query returns. A view may read from others, which are added first.
+func (mb *MemoryBackend) addViews(schema Schema) {
+	var add func(name string)
+	add = func(name string) {
+		if _, ok := schema[name]; ok {
+			return
+		}
+		slct := mb.views[name]
+		for _, read := range readTables(slct, nil) {
+			if _, ok := mb.views[read]; ok {
+				add(read)
+			}
+		}
+
+		// The query was valid when the view was created and what it reads
+		// cannot have changed since, so this does not fail.
+		crt, err := schema.selectResult(slct)
+		if err != nil {
+			return
+		}
+		crt.Name = &Token{Value: name, Kind: IdentifierKind}
+		crt.View = slct
+		schema[name] = crt
+	}
+
+	names := make([]string, 0, len(mb.views))
+	for name := range mb.views {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add(name)
+	}
+}
+
+// subqueryRelation prepares slct, the query of the view or common table
+// expression called name or a subquery with that alias, for a query that
+// names it as.
+func (mb *MemoryBackend) subqueryRelation(snap *txSnapshot, slct *SelectStatement, name, as *Token) (*relation, error) {
+	sub, err := mb.plan(snap, slct)
+	if err != nil {
+		return nil, err
+	}
+
+	rel := &relation{name: name.Value, target: name.Value}
+	if as != nil {
+		rel.name = as.Value
+		rel.target += " " + as.Value
+	}
+	alias := &Token{Value: rel.name, Kind: IdentifierKind}
+	rel.source = &planNode{
+		operator: "Subquery Scan on " + rel.target,
+		rows:     sub.rows,
+		cost:     sub.cost,
+		children: []*planNode{sub},
+		run: func(ctx context.Context) (*table, error) {
+			return mb.subquery(ctx, snap, slct, alias)
+		},
+	}
+	return rel, nil
+}
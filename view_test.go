@@ -0,0 +1,98 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestView(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table users (id int, name text, age int);"+
+		"insert into users values (1, 'alice', 30);"+
+		"insert into users values (2, 'bob', 17);"+
+		"insert into users values (3, 'carol', 45);"+
+		"create view adults as select id, name from users where age >= 18;"+
+		"create view names as select name from adults", ScriptOptions{})
+	assert.Nil(t, err)
+
+	results, err := ExecuteScript(mb, "select name from adults where id > 1", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{MemoryCell("carol")}}, results[0].Results.Rows)
+
+	// Views read the rows as they are when the query runs.
+	results, err = ExecuteScript(mb, "insert into users values (4, 'dave', 50);"+
+		"select n.name from names n order by n.name", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{MemoryCell("alice")}, {MemoryCell("carol")}, {MemoryCell("dave")}}, results[1].Results.Rows)
+
+	results, err = ExecuteScript(mb, "select u.age from adults a join users u on a.id = u.id where a.name = 'alice'", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{intCell(30)}}, results[0].Results.Rows)
+
+	results, err = ExecuteScript(mb, "explain select name from adults", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, MemoryCell("  ->  Subquery Scan on adults  (cost=10.00 rows=2)"), results[0].Results.Rows[2][0])
+
+	results, err = ExecuteScript(mb, "select table_name from information_schema.tables where table_type = 'VIEW' and table_schema = 'public'", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]Cell{{MemoryCell("adults")}, {MemoryCell("names")}}, results[0].Results.Rows)
+
+	_, err = ExecuteScript(mb, "insert into adults values (5, 'erin')", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrWrongObjectType)
+	_, err = ExecuteScript(mb, "drop table adults", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrWrongObjectType)
+	_, err = ExecuteScript(mb, "create view users as select id from users", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrTableAlreadyExists)
+	_, err = ExecuteScript(mb, "create view broken as select nope from users", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrColumnDoesNotExist)
+
+	// What a view reads cannot go away from under it.
+	_, err = ExecuteScript(mb, "drop view adults", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrDependentObjects)
+	_, err = ExecuteScript(mb, "alter table users drop column age", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrDependentObjects)
+	_, err = ExecuteScript(mb, "alter table users add column note text;"+
+		"alter table users rename column note to remark;"+
+		"alter table users drop column remark", ScriptOptions{})
+	assert.Nil(t, err)
+	_, err = ExecuteScript(mb, "create view everyone as select * from users;"+
+		"alter table users add column note text;"+
+		"alter table users drop column note", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrDependentObjects)
+	_, err = ExecuteScript(mb, "drop view everyone", ScriptOptions{})
+	assert.Nil(t, err)
+
+	results, err = ExecuteScript(mb, "drop view names; drop view adults; drop view if exists adults", ScriptOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "DROP VIEW", results[0].Tag)
+	_, err = ExecuteScript(mb, "select name from adults", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrTableDoesNotExist)
+	_, err = ExecuteScript(mb, "drop table users", ScriptOptions{})
+	assert.Nil(t, err)
+}
+
+func TestView_rollback(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table t (x int);"+
+		"begin;"+
+		"create view v as select x from t;"+
+		"rollback", ScriptOptions{})
+	assert.Nil(t, err)
+
+	_, err = ExecuteScript(mb, "select x from v", ScriptOptions{})
+	assert.ErrorIs(t, err, ErrTableDoesNotExist)
+}
+
+func TestParse_view(t *testing.T) {
+	ast, err := Parse("create view v as select a from t; drop view if exists v")
+	assert.Nil(t, err)
+	assert.Equal(t, CreateViewKind, ast.Statements[0].Kind)
+	assert.Equal(t, "v", ast.Statements[0].CreateViewStatement.Name.Value)
+	assert.Equal(t, "t", ast.Statements[0].CreateViewStatement.Select.From.Value)
+	assert.Equal(t, DropViewKind, ast.Statements[1].Kind)
+	assert.True(t, ast.Statements[1].DropViewStatement.IfExists)
+
+	_, err = Parse("create view v select a from t")
+	assert.NotNil(t, err)
+}
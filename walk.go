@@ -0,0 +1,521 @@
+package gosql
+
+// Node is any part of a parsed script, from the *Ast down to its tokens.
+type Node interface {
+	// Children returns the nodes directly below this one in source
+	// order, leaving out parts that are absent.
+	Children() []Node
+}
+
+// Visitor has its Visit method called for each node Walk reaches. When it
+// returns a visitor w, Walk visits the children of node with w and then
+// calls w.Visit(nil). Returning nil skips the children.
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses node depth first in source order. Nodes are pointers, so
+// a visitor may change a node in place, for example replacing *exp with
+// another expression, and Walk then descends into the new children.
+func Walk(node Node, v Visitor) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	for _, child := range node.Children() {
+		Walk(child, v)
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if node != nil && f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses node like Walk, calling f for each node. Returning
+// false from f skips the children of that node.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(node, inspector(f))
+}
+
+// Extent returns the byte offsets of the source text of node, from the start
+// of its first token to the end of its last, so that a tool can rewrite
+// source[offset:end]. Punctuation the AST does not keep, such as a closing
+// parenthesis, is only covered when a later token of node follows it. A
+// statement gives its own extent, which does cover them. ok is false when
+// node has no lexed tokens, as when it was made up by hand.
+func Extent(node Node) (offset, end uint, ok bool) {
+	if stmt, isStmt := node.(*Statement); isStmt && stmt.EndOffset > 0 {
+		return stmt.Offset, stmt.EndOffset, true
+	}
+	Inspect(node, func(n Node) bool {
+		t, isToken := n.(*Token)
+		if !isToken || t.EndOffset == 0 {
+			return true
+		}
+		if !ok || t.Offset < offset {
+			offset = t.Offset
+		}
+		if t.EndOffset > end {
+			end = t.EndOffset
+		}
+		ok = true
+		return true
+	})
+	return offset, end, ok
+}
+
+func (t *Token) Children() []Node {
+	return nil
+}
+
+func (a *Ast) Children() []Node {
+	var nodes []Node
+	for _, stmt := range a.Statements {
+		nodes = append(nodes, stmt)
+	}
+	return nodes
+}
+
+func (stmt *Statement) Children() []Node {
+	switch stmt.Kind {
+	case SelectKind:
+		return []Node{stmt.SelectStatement}
+	case InsertKind:
+		return []Node{stmt.InsertStatement}
+	case CreateTableKind:
+		return []Node{stmt.CreateTableStatement}
+	case UpdateKind:
+		return []Node{stmt.UpdateStatement}
+	case DeleteKind:
+		return []Node{stmt.DeleteStatement}
+	case DropTableKind:
+		return []Node{stmt.DropTableStatement}
+	case CreateIndexKind:
+		return []Node{stmt.CreateIndexStatement}
+	case ExplainKind:
+		return []Node{stmt.ExplainStatement}
+	case AlterTableKind:
+		return []Node{stmt.AlterTableStatement}
+	case CopyKind:
+		return []Node{stmt.CopyStatement}
+	case DescribeKind:
+		return []Node{stmt.DescribeStatement}
+	case SetKind:
+		return []Node{stmt.SetStatement}
+	case ShowKind:
+		return []Node{stmt.ShowStatement}
+	case CreateDatabaseKind:
+		return []Node{stmt.CreateDatabaseStatement}
+	case DropDatabaseKind:
+		return []Node{stmt.DropDatabaseStatement}
+	case UseKind:
+		return []Node{stmt.UseStatement}
+	case CreateUserKind:
+		return []Node{stmt.CreateUserStatement}
+	case DropUserKind:
+		return []Node{stmt.DropUserStatement}
+	case GrantKind, RevokeKind:
+		return []Node{stmt.GrantStatement}
+	case SavepointKind, ReleaseSavepointKind, RollbackToSavepointKind:
+		return []Node{stmt.SavepointStatement}
+	case CreateViewKind:
+		return []Node{stmt.CreateViewStatement}
+	case DropViewKind:
+		return []Node{stmt.DropViewStatement}
+	}
+	return nil
+}
+
+func (exp *Expression) Children() []Node {
+	switch exp.Kind {
+	case LiteralKind:
+		return []Node{exp.Literal}
+	case ColumnReferenceKind:
+		return []Node{exp.Column}
+	case BinaryKind:
+		return []Node{exp.Binary}
+	case UnaryKind:
+		return []Node{exp.Unary}
+	case IsNullKind:
+		return []Node{exp.IsNull}
+	case InKind:
+		return []Node{exp.In}
+	case BetweenKind:
+		return []Node{exp.Between}
+	case FunctionKind:
+		return []Node{exp.Function}
+	case CastKind:
+		return []Node{exp.Cast}
+	case CaseKind:
+		return []Node{exp.Case}
+	}
+	return nil
+}
+
+func (b *BinaryExpression) Children() []Node {
+	return []Node{b.Left, b.Op, b.Right}
+}
+
+func (u *UnaryExpression) Children() []Node {
+	return []Node{u.Op, u.Operand}
+}
+
+func (is *IsNullExpression) Children() []Node {
+	return []Node{is.Operand}
+}
+
+func (in *InExpression) Children() []Node {
+	nodes := []Node{in.Left}
+	for _, exp := range in.List {
+		nodes = append(nodes, exp)
+	}
+	if in.Select != nil {
+		nodes = append(nodes, in.Select)
+	}
+	return nodes
+}
+
+func (b *BetweenExpression) Children() []Node {
+	return []Node{b.Left, b.Low, b.High}
+}
+
+func (fn *FunctionExpression) Children() []Node {
+	nodes := []Node{fn.Name}
+	for _, arg := range fn.Args {
+		nodes = append(nodes, arg)
+	}
+	if fn.Over != nil {
+		nodes = append(nodes, fn.Over)
+	}
+	return nodes
+}
+
+func (w *Window) Children() []Node {
+	var nodes []Node
+	for _, exp := range w.PartitionBy {
+		nodes = append(nodes, exp)
+	}
+	for _, key := range w.OrderBy {
+		nodes = append(nodes, key)
+	}
+	return nodes
+}
+
+func (c *CastExpression) Children() []Node {
+	return []Node{c.Operand, c.Type}
+}
+
+func (c *CaseExpression) Children() []Node {
+	nodes := []Node{c.Case}
+	if c.Operand != nil {
+		nodes = append(nodes, c.Operand)
+	}
+	for _, when := range c.Whens {
+		nodes = append(nodes, when)
+	}
+	if c.Else != nil {
+		nodes = append(nodes, c.Else)
+	}
+	return nodes
+}
+
+func (w *WhenClause) Children() []Node {
+	return []Node{w.When, w.Then}
+}
+
+func (c *ColumnReference) Children() []Node {
+	return []Node{c.Table, c.Column}
+}
+
+func (si *SelectItem) Children() []Node {
+	var nodes []Node
+	if si.Exp != nil {
+		nodes = append(nodes, si.Exp)
+	}
+	if si.As != nil {
+		nodes = append(nodes, si.As)
+	}
+	return nodes
+}
+
+func (o *OrderByClause) Children() []Node {
+	return []Node{o.Exp}
+}
+
+func (j *JoinClause) Children() []Node {
+	nodes := []Node{j.Table}
+	if j.As != nil {
+		nodes = append(nodes, j.As)
+	}
+	return append(nodes, j.On)
+}
+
+func (h *Hint) Children() []Node {
+	nodes := []Node{h.Name}
+	for _, arg := range h.Args {
+		nodes = append(nodes, arg)
+	}
+	return nodes
+}
+
+func (cte *CommonTableExpression) Children() []Node {
+	return []Node{cte.Name, cte.Select}
+}
+
+// Children of a query include its common table expressions once, in its
+// WITH clause, and not where it reads from them.
+func (slct *SelectStatement) Children() []Node {
+	var nodes []Node
+	for _, cte := range slct.With {
+		nodes = append(nodes, cte)
+	}
+	for _, hint := range slct.Hints {
+		nodes = append(nodes, hint)
+	}
+	for _, item := range slct.Item {
+		nodes = append(nodes, item)
+	}
+	if slct.From != nil {
+		nodes = append(nodes, slct.From)
+	} else if slct.FromSelect != nil {
+		nodes = append(nodes, slct.FromSelect)
+	}
+	if slct.FromAs != nil {
+		nodes = append(nodes, slct.FromAs)
+	}
+	for _, join := range slct.Join {
+		nodes = append(nodes, join)
+	}
+	if slct.Where != nil {
+		nodes = append(nodes, slct.Where)
+	}
+	for _, exp := range slct.GroupBy {
+		nodes = append(nodes, exp)
+	}
+	if slct.Having != nil {
+		nodes = append(nodes, slct.Having)
+	}
+	if slct.Set != nil {
+		nodes = append(nodes, slct.Set)
+	}
+	for _, key := range slct.OrderBy {
+		nodes = append(nodes, key)
+	}
+	if slct.Limit != nil {
+		nodes = append(nodes, slct.Limit)
+	}
+	if slct.Offset != nil {
+		nodes = append(nodes, slct.Offset)
+	}
+	return nodes
+}
+
+func (set *SetOperation) Children() []Node {
+	return []Node{set.Left, set.Op, set.Right}
+}
+
+func (e *ExplainStatement) Children() []Node {
+	return []Node{e.Select}
+}
+
+func (inst *InsertStatement) Children() []Node {
+	nodes := []Node{inst.Table}
+	for _, col := range inst.Columns {
+		nodes = append(nodes, col)
+	}
+	for _, row := range inst.Values {
+		for _, exp := range row {
+			nodes = append(nodes, exp)
+		}
+	}
+	if inst.Select != nil {
+		nodes = append(nodes, inst.Select)
+	}
+	if inst.OnConflict != nil {
+		nodes = append(nodes, inst.OnConflict)
+	}
+	for _, item := range inst.Returning {
+		nodes = append(nodes, item)
+	}
+	return nodes
+}
+
+func (c *ColumnConstraint) Children() []Node {
+	switch {
+	case c.Default != nil:
+		return []Node{c.Default}
+	case c.Check != nil:
+		return []Node{c.Check}
+	case c.Collation != nil:
+		return []Node{c.Collation}
+	}
+	return nil
+}
+
+func (cd *ColumnDefinition) Children() []Node {
+	nodes := []Node{cd.Name, cd.Datatype}
+	if cd.Length != nil {
+		nodes = append(nodes, cd.Length)
+	}
+	for _, c := range cd.Constraints {
+		nodes = append(nodes, c)
+	}
+	return nodes
+}
+
+func (crt *CreateTableStatement) Children() []Node {
+	nodes := []Node{crt.Name}
+	for _, col := range crt.Cols {
+		nodes = append(nodes, col)
+	}
+	for _, check := range crt.Checks {
+		nodes = append(nodes, check)
+	}
+	if crt.Layout != nil {
+		nodes = append(nodes, crt.Layout)
+	}
+	return nodes
+}
+
+func (crt *CreateIndexStatement) Children() []Node {
+	return []Node{crt.Name, crt.Table, crt.Column}
+}
+
+func (drp *DropTableStatement) Children() []Node {
+	return []Node{drp.Name}
+}
+
+func (alt *AlterTableStatement) Children() []Node {
+	nodes := []Node{alt.Table}
+	if alt.Add != nil {
+		nodes = append(nodes, alt.Add)
+	}
+	if alt.Column != nil {
+		nodes = append(nodes, alt.Column)
+	}
+	if alt.To != nil {
+		nodes = append(nodes, alt.To)
+	}
+	return nodes
+}
+
+func (a *Assignment) Children() []Node {
+	return []Node{a.Column, a.Value}
+}
+
+func (c *OnConflict) Children() []Node {
+	var nodes []Node
+	if c.Target != nil {
+		nodes = append(nodes, c.Target)
+	}
+	for _, a := range c.Set {
+		nodes = append(nodes, a)
+	}
+	if c.Where != nil {
+		nodes = append(nodes, c.Where)
+	}
+	return nodes
+}
+
+func (updt *UpdateStatement) Children() []Node {
+	nodes := []Node{updt.Table}
+	for _, a := range updt.Set {
+		nodes = append(nodes, a)
+	}
+	if updt.Where != nil {
+		nodes = append(nodes, updt.Where)
+	}
+	for _, item := range updt.Returning {
+		nodes = append(nodes, item)
+	}
+	return nodes
+}
+
+func (dlt *DeleteStatement) Children() []Node {
+	nodes := []Node{dlt.From}
+	if dlt.Where != nil {
+		nodes = append(nodes, dlt.Where)
+	}
+	for _, item := range dlt.Returning {
+		nodes = append(nodes, item)
+	}
+	return nodes
+}
+
+func (desc *DescribeStatement) Children() []Node {
+	return []Node{desc.Table}
+}
+
+func (set *SetStatement) Children() []Node {
+	return []Node{set.Name, set.Value}
+}
+
+func (shw *ShowStatement) Children() []Node {
+	if shw.Name == nil {
+		return nil
+	}
+	return []Node{shw.Name}
+}
+
+func (crt *CreateDatabaseStatement) Children() []Node {
+	return []Node{crt.Name}
+}
+
+func (drp *DropDatabaseStatement) Children() []Node {
+	return []Node{drp.Name}
+}
+
+func (use *UseStatement) Children() []Node {
+	return []Node{use.Name}
+}
+
+func (crt *CreateUserStatement) Children() []Node {
+	if crt.Password == nil {
+		return []Node{crt.Name}
+	}
+	return []Node{crt.Name, crt.Password}
+}
+
+func (drp *DropUserStatement) Children() []Node {
+	return []Node{drp.Name}
+}
+
+func (sp *SavepointStatement) Children() []Node {
+	return []Node{sp.Name}
+}
+
+func (grant *GrantStatement) Children() []Node {
+	var nodes []Node
+	for _, p := range grant.Privileges {
+		nodes = append(nodes, p)
+	}
+	return append(nodes, grant.Table, grant.User)
+}
+
+func (crt *CreateViewStatement) Children() []Node {
+	return []Node{crt.Name, crt.Select}
+}
+
+func (drp *DropViewStatement) Children() []Node {
+	return []Node{drp.Name}
+}
+
+func (cp *CopyStatement) Children() []Node {
+	nodes := []Node{cp.Table}
+	for _, col := range cp.Columns {
+		nodes = append(nodes, col)
+	}
+	nodes = append(nodes, cp.File)
+	if cp.Delimiter != nil {
+		nodes = append(nodes, cp.Delimiter)
+	}
+	if cp.Null != nil {
+		nodes = append(nodes, cp.Null)
+	}
+	return nodes
+}
@@ -0,0 +1,124 @@
+package gosql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// depthVisitor records each node it visits with its depth.
+type depthVisitor struct {
+	depth  int
+	visits *[]int
+}
+
+func (v depthVisitor) Visit(node Node) Visitor {
+	if node == nil {
+		return nil
+	}
+	*v.visits = append(*v.visits, v.depth)
+	return depthVisitor{depth: v.depth + 1, visits: v.visits}
+}
+
+func TestWalk(t *testing.T) {
+	ast, err := Parse("delete from users where id = 1")
+	assert.Nil(t, err)
+
+	var visits []int
+	Walk(ast, depthVisitor{visits: &visits})
+	// Ast, Statement, DeleteStatement, users, Expression, BinaryExpression,
+	// then the operands and operator.
+	assert.Equal(t, []int{0, 1, 2, 3, 3, 4, 5, 6, 5, 5, 6}, visits)
+}
+
+func TestInspect(t *testing.T) {
+	ast, err := Parse(`select u.name, count(*) from users u
+join orders o on u.id = o.user_id
+where u.id in (select id from admins) and o.total between 1 and 2
+group by u.name order by u.name`)
+	assert.Nil(t, err)
+
+	var tables []string
+	var functions []string
+	Inspect(ast, func(node Node) bool {
+		switch n := node.(type) {
+		case *SelectStatement:
+			if n.From != nil {
+				tables = append(tables, n.From.Value)
+			}
+		case *JoinClause:
+			tables = append(tables, n.Table.Value)
+		case *FunctionExpression:
+			functions = append(functions, n.Name.Value)
+		}
+		return true
+	})
+	assert.Equal(t, []string{"users", "orders", "admins"}, tables)
+	assert.Equal(t, []string{"count"}, functions)
+
+	// Returning false skips the subquery.
+	var skipped []string
+	Inspect(ast, func(node Node) bool {
+		if slct, ok := node.(*SelectStatement); ok {
+			skipped = append(skipped, slct.From.Value)
+		}
+		_, in := node.(*InExpression)
+		return !in
+	})
+	assert.Equal(t, []string{"users"}, skipped)
+}
+
+func TestWalk_rewrite(t *testing.T) {
+	ast, err := Parse("update users set name = upper(name) where id = 1 or id is null")
+	assert.Nil(t, err)
+
+	// Replace each IS NULL test with false and rename the id column.
+	Inspect(ast, func(node Node) bool {
+		if exp, ok := node.(*Expression); ok && exp.Kind == IsNullKind {
+			*exp = Expression{Kind: LiteralKind, Literal: &Token{Kind: BoolKind, Value: "false"}}
+		}
+		if t, ok := node.(*Token); ok && t.Kind == IdentifierKind && t.Value == "id" {
+			t.Value = "user_id"
+		}
+		return true
+	})
+	assert.Equal(t, "UPDATE users\nSET name = upper(name)\nWHERE user_id = 1 OR FALSE", Format(ast.Statements[0]))
+}
+
+func TestExtent(t *testing.T) {
+	source := "select id, Name -- the name\nfrom users where id > 1;\n\nDELETE FROM users"
+	ast, err := Parse(source)
+	assert.Nil(t, err)
+
+	var spans []string
+	for _, stmt := range ast.Statements {
+		offset, end, ok := Extent(stmt)
+		assert.True(t, ok)
+		spans = append(spans, source[offset:end])
+	}
+	assert.Equal(t, []string{"select id, Name -- the name\nfrom users where id > 1", "DELETE FROM users"}, spans)
+	assert.Equal(t, Location{Line: 3, Col: 0}, ast.Statements[1].Loc)
+	assert.Equal(t, Location{Line: 3, Col: 17}, ast.Statements[1].End)
+
+	offset, end, ok := Extent(ast.Statements[0].SelectStatement.Where)
+	assert.True(t, ok)
+	assert.Equal(t, "id > 1", source[offset:end])
+
+	// Renaming the id column in place keeps the rest of the text as it was.
+	var rewritten strings.Builder
+	var last uint
+	Inspect(ast, func(node Node) bool {
+		if t, ok := node.(*Token); ok && t.Kind == IdentifierKind && t.Value == "id" {
+			rewritten.WriteString(source[last:t.Offset])
+			rewritten.WriteString("user_id")
+			last = t.EndOffset
+		}
+		return true
+	})
+	rewritten.WriteString(source[last:])
+	assert.Equal(t, "select user_id, Name -- the name\nfrom users where user_id > 1;\n\nDELETE FROM users", rewritten.String())
+
+	_, _, ok = Extent(&Expression{Kind: LiteralKind, Literal: &Token{Kind: NumericKind, Value: "1"}})
+	assert.False(t, ok)
+}
@@ -0,0 +1,262 @@
+package gosql
+
+import (
+	"context"
+)
+
+// hasWindow reports whether the items or ORDER BY of slct call a function
+// with OVER.
+func hasWindow(slct *SelectStatement) bool {
+	return len(windowCalls(slct)) > 0
+}
+
+// windowCalls returns the function calls with OVER in the items and ORDER
+// BY of slct, in the order they appear.
+func windowCalls(slct *SelectStatement) []*Expression {
+	var calls []*Expression
+	find := func(exp *Expression) {
+		_, _ = rewriteExpression(exp, func(exp *Expression) (*Expression, error) {
+			if exp.Kind == FunctionKind && exp.Function.Over != nil {
+				calls = append(calls, exp)
+				return exp, nil
+			}
+			return nil, nil
+		})
+	}
+	for _, item := range slct.Item {
+		if !item.Asterisk {
+			find(item.Exp)
+		}
+	}
+	for _, clause := range slct.OrderBy {
+		find(clause.Exp)
+	}
+	return calls
+}
+
+// window computes the result rows of a query calling window functions.
+// Each call is first computed for every row over the rows of its
+// partition, and the items and ORDER BY are then evaluated with the calls
+// replaced by their values.
+func (t *table) window(ctx context.Context, slct *SelectStatement, rows [][]MemoryCell) (*Results, error) {
+	values := map[*Expression][]*Expression{}
+	types := map[*Expression]ColumnType{}
+	for _, call := range windowCalls(slct) {
+		v, ct, err := t.windowValues(ctx, call.Function, rows)
+		if err != nil {
+			return nil, err
+		}
+		values[call], types[call] = v, ct
+	}
+
+	// resolve replaces the calls in exp by their values for row i, or by a
+	// NULL of their type when i is -1.
+	resolve := func(exp *Expression, i int) (*Expression, error) {
+		return rewriteExpression(exp, func(exp *Expression) (*Expression, error) {
+			v, ok := values[exp]
+			switch {
+			case !ok:
+				return nil, nil
+			case i == -1:
+				return cellExpression(nullCell, types[exp]), nil
+			}
+			return v[i], nil
+		})
+	}
+	resolveItems := func(i int) ([]*SelectItem, error) {
+		items := make([]*SelectItem, len(slct.Item))
+		for j, item := range slct.Item {
+			resolved := *item
+			var err error
+			if resolved.Exp, err = resolve(item.Exp, i); err != nil {
+				return nil, err
+			}
+			items[j] = &resolved
+		}
+		return items, nil
+	}
+
+	items, err := resolveItems(-1)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := t.projection(items)
+	if err != nil {
+		return nil, err
+	}
+
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	if len(slct.OrderBy) > 0 {
+		order, err = sortOrder(ctx, len(rows), slct.OrderBy, func(i int, exp *Expression) (MemoryCell, ColumnType, error) {
+			resolved, err := resolve(exp, i)
+			if err != nil {
+				return nil, 0, err
+			}
+			return t.evaluateKey(rows[i], resolved)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results := [][]Cell{}
+	for n, i := range order {
+		if err := canceled(ctx, n); err != nil {
+			return nil, err
+		}
+		items, err := resolveItems(i)
+		if err != nil {
+			return nil, err
+		}
+		result, err := t.project(items, rows[i])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return &Results{
+		Columns: columns,
+		Rows:    results,
+	}, nil
+}
+
+// windowValues computes the window function call fn for each of rows,
+// returning its values as literals in the order of rows. The rows are
+// split by PARTITION BY and each partition sorted by the ORDER BY of the
+// window. Rows with the same ORDER BY values are peers: they rank the
+// same, and an aggregate function covers the rows of the partition up to
+// the last of their peers. Without ORDER BY every row of a partition is a
+// peer of the others, so aggregates cover the whole partition.
+func (t *table) windowValues(ctx context.Context, fn *FunctionExpression, rows [][]MemoryCell) ([]*Expression, ColumnType, error) {
+	name := fn.Name.Value
+	var ct ColumnType
+	switch {
+	case windowFunctions[name]:
+		if len(fn.Args) > 0 || fn.Asterisk {
+			return nil, 0, ErrInvalidArguments
+		}
+		ct = BigIntType
+	case aggregateFunctions[name]:
+		var err error
+		if _, ct, err = t.evaluateAggregate(fn, nil); err != nil {
+			return nil, 0, err
+		}
+	default:
+		return nil, 0, ErrNotWindowFunction
+	}
+
+	partitions, err := t.partitionRows(ctx, fn.Over.PartitionBy, rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	values := make([]*Expression, len(rows))
+	for _, partition := range partitions {
+		order, err := sortOrder(ctx, len(partition), fn.Over.OrderBy, func(i int, exp *Expression) (MemoryCell, ColumnType, error) {
+			return t.evaluateKey(rows[partition[i]], exp)
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		sorted := make([]int, len(order))
+		for i, o := range order {
+			sorted[i] = partition[o]
+		}
+		keys, err := t.peerKeys(fn.Over.OrderBy, rows, sorted)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		frame := make([][]MemoryCell, 0, len(sorted))
+		groups := 0
+		for start, end := 0, 0; start < len(sorted); start = end {
+			for end = start + 1; end < len(sorted) && keys[end] == keys[start]; end++ {
+			}
+			groups++
+			for _, i := range sorted[start:end] {
+				frame = append(frame, rows[i])
+			}
+
+			var aggregate *Expression
+			if aggregateFunctions[name] {
+				cell, ct, err := t.evaluateAggregate(fn, frame)
+				if err != nil {
+					return nil, 0, err
+				}
+				aggregate = cellExpression(cell, ct)
+			}
+			for j := start; j < end; j++ {
+				switch name {
+				case "row_number":
+					values[sorted[j]] = cellExpression(bigIntCell(int64(j+1)), ct)
+				case "rank":
+					values[sorted[j]] = cellExpression(bigIntCell(int64(start+1)), ct)
+				case "dense_rank":
+					values[sorted[j]] = cellExpression(bigIntCell(int64(groups)), ct)
+				default:
+					values[sorted[j]] = aggregate
+				}
+			}
+		}
+	}
+	return values, ct, nil
+}
+
+// partitionRows splits the positions of rows by their PARTITION BY values,
+// keeping the partitions in the order they first appear.
+func (t *table) partitionRows(ctx context.Context, partitionBy []*Expression, rows [][]MemoryCell) ([][]int, error) {
+	var partitions [][]int
+	seen := map[string]int{}
+	for i, row := range rows {
+		if err := canceled(ctx, i); err != nil {
+			return nil, err
+		}
+		key, err := t.expressionsKey(partitionBy, row)
+		if err != nil {
+			return nil, err
+		}
+		if p, ok := seen[key]; ok {
+			partitions[p] = append(partitions[p], i)
+			continue
+		}
+		seen[key] = len(partitions)
+		partitions = append(partitions, []int{i})
+	}
+	return partitions, nil
+}
+
+// peerKeys returns the key of the ORDER BY values of each row in sorted,
+// which is the same for peers.
+func (t *table) peerKeys(orderBy []*OrderByClause, rows [][]MemoryCell, sorted []int) ([]string, error) {
+	exps := make([]*Expression, len(orderBy))
+	for i, clause := range orderBy {
+		exps[i] = clause.Exp
+	}
+	keys := make([]string, len(sorted))
+	for i, row := range sorted {
+		key, err := t.expressionsKey(exps, rows[row])
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+	return keys, nil
+}
+
+// expressionsKey evaluates exps for row and encodes the values with rowKey.
+func (t *table) expressionsKey(exps []*Expression, row []MemoryCell) (string, error) {
+	values := make([]Cell, len(exps))
+	types := make([]ResultColumn, len(exps))
+	for i, exp := range exps {
+		cell, ct, err := t.evaluateKey(row, exp)
+		if err != nil {
+			return "", err
+		}
+		values[i], types[i].Type = cell, ct
+	}
+	return rowKey(values, types), nil
+}
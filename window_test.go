@@ -0,0 +1,89 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowFunctions(t *testing.T) {
+	mb := NewMemoryBackend()
+	_, err := ExecuteScript(mb, "create table sales (id int, region text, amount int);"+
+		"insert into sales values (1, 'east', 10);"+
+		"insert into sales values (2, 'west', 5);"+
+		"insert into sales values (3, 'east', 20);"+
+		"insert into sales values (4, 'east', 20);"+
+		"insert into sales values (5, 'west', 15)", ScriptOptions{})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		query string
+		rows  [][]Cell
+	}{
+		{
+			"select id, row_number() over (order by amount desc, id) from sales order by id",
+			[][]Cell{{intCell(1), bigIntCell(4)}, {intCell(2), bigIntCell(5)}, {intCell(3), bigIntCell(1)}, {intCell(4), bigIntCell(2)}, {intCell(5), bigIntCell(3)}},
+		},
+		{
+			// Peers rank the same.
+			"select id, rank() over (partition by region order by amount), dense_rank() over (order by amount) from sales order by id",
+			[][]Cell{{intCell(1), bigIntCell(1), bigIntCell(2)}, {intCell(2), bigIntCell(1), bigIntCell(1)}, {intCell(3), bigIntCell(2), bigIntCell(4)}, {intCell(4), bigIntCell(2), bigIntCell(4)}, {intCell(5), bigIntCell(2), bigIntCell(3)}},
+		},
+		{
+			// A running sum covers the rows up to the last peer.
+			"select id, sum(amount) over (partition by region order by amount) as running from sales order by id",
+			[][]Cell{{intCell(1), intCell(10)}, {intCell(2), intCell(5)}, {intCell(3), intCell(50)}, {intCell(4), intCell(50)}, {intCell(5), intCell(20)}},
+		},
+		{
+			"select region, avg(amount) over (partition by region), count(*) over () from sales where id < 4 order by id",
+			[][]Cell{{MemoryCell("east"), floatCell(15), intCell(3)}, {MemoryCell("west"), floatCell(5), intCell(3)}, {MemoryCell("east"), floatCell(15), intCell(3)}},
+		},
+		{
+			// The values can be used in expressions and to sort by.
+			"select id from sales order by row_number() over (order by id) * -1 limit 2",
+			[][]Cell{{intCell(5)}, {intCell(4)}},
+		},
+		{
+			"select distinct region, sum(amount) over (partition by region) from sales order by region",
+			[][]Cell{{MemoryCell("east"), intCell(50)}, {MemoryCell("west"), intCell(20)}},
+		},
+	}
+	for _, test := range tests {
+		results, err := ExecuteScript(mb, test.query, ScriptOptions{})
+		assert.Nil(t, err, test.query)
+		if err == nil {
+			assert.Equal(t, test.rows, results[0].Results.Rows, test.query)
+		}
+	}
+
+	for _, test := range []struct {
+		query string
+		err   error
+	}{
+		{"select row_number() from sales", ErrOverRequired},
+		{"select lower(region) over () from sales", ErrNotWindowFunction},
+		{"select id from sales where rank() over (order by id) = 1", ErrWindowNotAllowed},
+		{"select region, row_number() over () from sales group by region", ErrWindowNotAllowed},
+		{"select rank(id) over () from sales", ErrInvalidArguments},
+	} {
+		_, err := ExecuteScript(mb, test.query, ScriptOptions{})
+		assert.ErrorIs(t, err, test.err, test.query)
+	}
+}
+
+func TestParse_window(t *testing.T) {
+	ast, err := Parse("select rank() over (partition by a, b order by c desc), sum(x) over () from t")
+	assert.Nil(t, err)
+
+	over := ast.Statements[0].SelectStatement.Item[0].Exp.Function.Over
+	assert.Len(t, over.PartitionBy, 2)
+	assert.Len(t, over.OrderBy, 1)
+	assert.True(t, over.OrderBy[0].Desc)
+	assert.Equal(t, `SELECT rank() OVER (PARTITION BY a, b ORDER BY c DESC), sum(x) OVER ()
+FROM t`, Format(ast.Statements[0]))
+
+	_, err = Parse("select rank() over partition by a from t")
+	assert.EqualError(t, err, "Expected left paren, got partition at 0:19")
+	_, err = Parse("select rank() over (order a) from t")
+	assert.EqualError(t, err, "Expected BY, got a at 0:26")
+}
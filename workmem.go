@@ -0,0 +1,71 @@
+package gosql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultWorkMem is the work_mem of a session that has not set it.
+const defaultWorkMem = 64 << 20
+
+// memoryUnits are the units work_mem may be given in, as in PostgreSQL.
+// A bare number counts kilobytes.
+var memoryUnits = map[string]int64{
+	"":   1 << 10,
+	"kB": 1 << 10,
+	"MB": 1 << 20,
+	"GB": 1 << 30,
+}
+
+// parseMemory reads the value of a memory setting in bytes: a number of
+// kilobytes, a string holding one or a size like '64MB', or DEFAULT for
+// defaultWorkMem.
+func parseMemory(value *Token) (int64, error) {
+	if isDefault(value) {
+		return defaultWorkMem, nil
+	}
+
+	number := strings.TrimRight(value.Value, "kMGB")
+	unit, ok := memoryUnits[value.Value[len(number):]]
+	n, err := strconv.ParseInt(strings.TrimSpace(number), 10, 64)
+	if !ok || err != nil || n <= 0 || n > (1<<62)/unit || unit != memoryUnits[""] && value.Kind != StringKind {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidSettingValue, value.Value)
+	}
+	return n * unit, nil
+}
+
+// WorkMem returns the work_mem of the session, the bytes a hash join may
+// use for the rows it hashes and a sort for the keys it holds in memory.
+func (s *Session) WorkMem() int64 {
+	return s.workMem
+}
+
+type workMemKey struct{}
+
+// withSettings returns ctx carrying the settings of the session that the
+// operators of its statements read, and the time the statement sees.
+func (s *Session) withSettings(ctx context.Context) context.Context {
+	ctx = withTime(ctx, s.statementTime(ctx))
+	return context.WithValue(ctx, workMemKey{}, s.workMem)
+}
+
+// workMem returns the work_mem of the session running the statement ctx
+// belongs to.
+func workMem(ctx context.Context) int64 {
+	if n, ok := ctx.Value(workMemKey{}).(int64); ok {
+		return n
+	}
+	return defaultWorkMem
+}
+
+// rowBytes estimates the memory row takes: its cells and the slice
+// headers that hold them.
+func rowBytes(row []MemoryCell) int64 {
+	n := int64(24 * (len(row) + 1))
+	for _, cell := range row {
+		n += int64(len(cell))
+	}
+	return n
+}